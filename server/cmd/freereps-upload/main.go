@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/claude/freereps/internal/ingest"
 	"github.com/claude/freereps/internal/upload"
 )
 
@@ -20,17 +21,30 @@ func main() {
 	serverURL := flag.String("server", "", "FreeReps server URL (e.g. https://freereps.tail1234.ts.net)")
 	dryRun := flag.Bool("dry-run", false, "parse and convert but don't send to server")
 	version := flag.Bool("version", false, "print version and exit")
+	gzipBody := flag.Bool("gzip", false, "gzip-compress ingest payloads (requires server support)")
+	timeout := flag.Duration("timeout", 60*time.Second, "per-request timeout for server calls")
+	retries := flag.Int("retries", 2, "retries for failed server calls (connection errors and 5xx)")
 
 	// File mode flags
 	autoSyncPath := flag.String("path", "", "path to AutoSync directory (file mode)")
 	batchSize := flag.Int("batch-size", 2000, "data points per metric payload (file mode)")
+	hrTolerance := flag.Duration("hr-tolerance", 0, "match heart rate samples up to this long before/after a workout, e.g. 2m (file mode)")
+	earliest := flag.String("earliest", ingest.DefaultTimeBounds.Earliest.Format("2006-01-02"), "skip metric points before this date, yyyy-MM-dd; match the server's ingest.earliest_time (file mode)")
+	maxFuture := flag.Duration("max-future", ingest.DefaultTimeBounds.MaxFuture, "skip metric points more than this far in the future, 0 = no limit; match the server's ingest.max_future (file mode)")
+	maxWorkoutAttempts := flag.Int("max-workout-attempts", upload.DefaultMaxWorkoutAttempts, "runs that re-send a workout the server doesn't confirm before giving up on it, 0 = never give up (file mode)")
+
+	// FIT mode flags
+	fitPath := flag.String("fit", "", "Garmin FIT file or directory of .fit files (FIT mode)")
 
 	// TCP mode flags
 	haeHost := flag.String("hae-host", "", "HAE TCP server IP address (TCP mode)")
 	haePort := flag.Int("hae-port", 9000, "HAE TCP server port")
+	haeToken := flag.String("hae-token", os.Getenv("FREEREPS_HAE_TOKEN"), "HAE TCP server auth token (TCP mode, default $FREEREPS_HAE_TOKEN)")
 	startDate := flag.String("start", "", "start date for backfill (yyyy-MM-dd, default: 1 year ago)")
 	endDate := flag.String("end", "", "end date (yyyy-MM-dd, default: today)")
 	chunkDays := flag.Int("chunk-days", 1, "days per query chunk (TCP mode)")
+	metricChunks := flag.String("metric-chunk-days", "", "per-metric chunk overrides, e.g. heart_rate=1,weight_body_mass=30 (TCP mode)")
+	requestDelay := flag.Duration("request-delay", 0, "pause between HAE queries, e.g. 500ms (TCP mode)")
 	flag.Parse()
 
 	if *version {
@@ -41,10 +55,11 @@ func main() {
 	log := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
 
 	// Mode selection
-	if *haeHost == "" && *autoSyncPath == "" {
+	if *haeHost == "" && *autoSyncPath == "" && *fitPath == "" {
 		fmt.Fprintf(os.Stderr, "Usage:\n")
-		fmt.Fprintf(os.Stderr, "  TCP mode:  freereps-upload -hae-host <IP> -server <URL> [-start yyyy-MM-dd] [-end yyyy-MM-dd] [-chunk-days N]\n")
-		fmt.Fprintf(os.Stderr, "  File mode: freereps-upload -path <AutoSync dir> -server <URL> [-batch-size N]\n\n")
+		fmt.Fprintf(os.Stderr, "  TCP mode:  freereps-upload -hae-host <IP> -server <URL> [-start yyyy-MM-dd] [-end yyyy-MM-dd] [-chunk-days N] [-metric-chunk-days m=N,...] [-request-delay D] [-hae-token T]\n")
+		fmt.Fprintf(os.Stderr, "  File mode: freereps-upload -path <AutoSync dir> -server <URL> [-batch-size N] [-hr-tolerance D] [-earliest yyyy-MM-dd] [-max-future D]\n")
+		fmt.Fprintf(os.Stderr, "  FIT mode:  freereps-upload -fit <file or dir> -server <URL>\n\n")
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
@@ -75,7 +90,11 @@ func main() {
 	// Create client (nil in dry-run mode)
 	var client *upload.Client
 	if !*dryRun {
-		client = upload.NewClient(*serverURL)
+		client = upload.NewClientWithOptions(*serverURL, upload.ClientOptions{
+			Timeout:  *timeout,
+			Attempts: *retries + 1,
+		})
+		client.SetGzip(*gzipBody)
 	}
 
 	if *dryRun {
@@ -86,8 +105,20 @@ func main() {
 		// TCP server mode
 		start, end := parseDateRange(*startDate, *endDate, state, log)
 
+		overrides, err := upload.ParseMetricChunkDays(*metricChunks)
+		if err != nil {
+			log.Error("invalid -metric-chunk-days", "error", err)
+			os.Exit(1)
+		}
+		opts := upload.TCPOptions{
+			ChunkDays:       *chunkDays,
+			MetricChunkDays: overrides,
+			RequestDelay:    *requestDelay,
+			Token:           *haeToken,
+		}
+
 		uploader := upload.New(client, state, "", *dryRun, 0, log)
-		stats, err := uploader.RunTCP(*haeHost, *haePort, start, end, *chunkDays)
+		stats, err := uploader.RunTCP(*haeHost, *haePort, start, end, opts)
 		if err != nil {
 			log.Error("TCP upload failed", "error", err)
 			printTCPStats(stats)
@@ -96,6 +127,18 @@ func main() {
 
 		printTCPStats(stats)
 		log.Info("TCP upload complete")
+	} else if *fitPath != "" {
+		// FIT mode
+		uploader := upload.New(client, state, "", *dryRun, 0, log)
+		stats, err := uploader.RunFIT(*fitPath)
+		if err != nil {
+			log.Error("FIT upload failed", "error", err)
+			printFITStats(stats)
+			os.Exit(1)
+		}
+
+		printFITStats(stats)
+		log.Info("FIT upload complete")
 	} else {
 		// File mode
 		autoSync := upload.ResolveAutoSync(*autoSyncPath)
@@ -106,7 +149,16 @@ func main() {
 		}
 		log.Info("using AutoSync directory", "path", autoSync)
 
+		earliestTime, err := time.Parse("2006-01-02", *earliest)
+		if err != nil {
+			log.Error("invalid -earliest, want yyyy-MM-dd", "value", *earliest)
+			os.Exit(1)
+		}
+
 		uploader := upload.New(client, state, autoSync, *dryRun, *batchSize, log)
+		uploader.SetTimeBounds(ingest.TimeBounds{Earliest: earliestTime, MaxFuture: *maxFuture})
+		uploader.SetHRTolerance(*hrTolerance)
+		uploader.SetMaxWorkoutAttempts(*maxWorkoutAttempts)
 		stats, err := uploader.Run()
 		if err != nil {
 			log.Error("upload failed", "error", err)
@@ -170,6 +222,16 @@ func printTCPStats(stats *upload.Stats) {
 	fmt.Println()
 }
 
+func printFITStats(stats *upload.Stats) {
+	fmt.Println()
+	fmt.Println("=== FIT Upload Summary ===")
+	fmt.Printf("  Files total:      %d\n", stats.FilesTotal)
+	fmt.Printf("  Files uploaded:   %d\n", stats.FilesUploaded)
+	fmt.Printf("  Files skipped:    %d (already uploaded)\n", stats.FilesSkipped)
+	fmt.Printf("  Files errored:    %d\n", stats.FilesErrored)
+	fmt.Println()
+}
+
 func printFileStats(stats *upload.Stats) {
 	fmt.Println()
 	fmt.Println("=== Upload Summary ===")
@@ -179,8 +241,20 @@ func printFileStats(stats *upload.Stats) {
 	fmt.Printf("  Files errored:    %d\n", stats.FilesErrored)
 	fmt.Println()
 	fmt.Printf("  Metric points:    %d\n", stats.MetricPointsSent)
+	if stats.MetricPointsSkipped > 0 {
+		fmt.Printf("  Points skipped:   %d (malformed)\n", stats.MetricPointsSkipped)
+	}
+	if stats.MetricPointsOutOfRange > 0 {
+		fmt.Printf("  Points rejected:  %d (timestamp out of range)\n", stats.MetricPointsOutOfRange)
+	}
 	fmt.Printf("  Sleep stages:     %d\n", stats.SleepStagesSent)
 	fmt.Printf("  Workouts:         %d\n", stats.WorkoutsSent)
+	if stats.WorkoutsUnconfirmed > 0 {
+		fmt.Printf("  Workouts pending: %d (not confirmed stored; retried next run)\n", stats.WorkoutsUnconfirmed)
+	}
+	if stats.WorkoutsAbandoned > 0 {
+		fmt.Printf("  Workouts dropped: %d (never confirmed stored; no longer retried)\n", stats.WorkoutsAbandoned)
+	}
 	fmt.Printf("  Route points:     %d\n", stats.RoutePointsSent)
 	fmt.Printf("  HR correlated:    %d\n", stats.HRPointsCorrelated)
 
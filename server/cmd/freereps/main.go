@@ -16,7 +16,9 @@ import (
 	freereps "github.com/claude/freereps"
 	"github.com/claude/freereps/internal/config"
 	"github.com/claude/freereps/internal/demo"
+	"github.com/claude/freereps/internal/ingest"
 	"github.com/claude/freereps/internal/ingest/alpha"
+	"github.com/claude/freereps/internal/ingest/fit"
 	"github.com/claude/freereps/internal/ingest/health"
 	freerepsmcp "github.com/claude/freereps/internal/mcp"
 	"github.com/claude/freereps/internal/oura"
@@ -34,6 +36,8 @@ func main() {
 	migrateOnly := flag.Bool("migrate-only", false, "run migrations and exit")
 	mcpMode := flag.Bool("mcp", false, "run as MCP server over stdio (for Claude Code integration)")
 	demoMode := flag.Bool("demo", false, "seed database with demo data for testing")
+	downsample := flag.Bool("downsample", false, "apply retention policies once and exit")
+	dryRun := flag.Bool("dry-run", false, "with -downsample, report affected rows without changing anything")
 	flag.Parse()
 
 	// In MCP stdio mode, logs go to stderr to keep stdout clean for JSON-RPC.
@@ -41,15 +45,15 @@ func main() {
 	if *mcpMode {
 		logOutput = os.Stderr
 	}
-	log := slog.New(slog.NewTextHandler(logOutput, &slog.HandlerOptions{Level: slog.LevelInfo}))
-	log.Info("FreeReps starting", "version", Version)
-
 	// Load config
 	cfg, err := config.Load(*configPath)
 	if err != nil {
-		log.Error("failed to load config", "error", err)
+		config.LogConfig{}.NewLogger(logOutput).Error("failed to load config", "error", err)
 		os.Exit(1)
 	}
+	log := cfg.Log.NewLogger(logOutput)
+	slog.SetDefault(log)
+	log.Info("FreeReps starting", "version", Version)
 
 	// Run migrations (skip in MCP stdio mode — DB is managed by the server)
 	dsn := cfg.Database.DSN()
@@ -68,15 +72,59 @@ func main() {
 
 	// Connect database
 	ctx := context.Background()
-	db, err := storage.New(ctx, dsn)
+	db, err := storage.New(ctx, dsn, storage.PoolConfig{
+		MaxConns:          cfg.Database.MaxConns,
+		MinConns:          cfg.Database.MinConns,
+		MaxConnLifetime:   cfg.Database.MaxConnLifetime,
+		HealthCheckPeriod: cfg.Database.HealthCheckPeriod,
+	})
 	if err != nil {
 		log.Error("failed to connect database", "error", err)
 		os.Exit(1)
 	}
 	defer db.Close()
 	db.SetSourcePriority(cfg.SourcePriority)
+	db.SetAllowlistCacheTTL(cfg.Ingest.AllowlistCacheTTL)
+	db.SetSleepTimezone(cfg.Ingest.Timezone)
+	db.SetDayStartHour(cfg.Ingest.DayStartHour)
+	db.SetAlphaLinkWindow(cfg.Ingest.AlphaLinkWindow)
+	db.SetSleepDebtPolicy(cfg.Sleep.TargetHours, cfg.Sleep.MissingNightsAsZero)
+	db.SetSleepDayBoundary(storage.SleepDayBoundary(cfg.Sleep.DayBoundary))
+	db.SetRIRBands(rirBands(cfg.Training.RIRBands))
+	db.SetStrengthCalories(cfg.Training.StrengthMET, cfg.Training.DefaultBodyweightKg)
+	db.SetVolumeLandmarks(volumeLandmarks(cfg.Training.VolumeLandmarks))
+	db.SetWorkoutTypes(cfg.Training.WorkoutTypes)
+	db.SetIllnessThresholds(storage.IllnessThresholds(cfg.Illness))
+	db.SetMinCorrelationPoints(cfg.Correlation.MinPoints)
+	if err := db.SetDerivedMetrics(cfg.DerivedMetrics); err != nil {
+		log.Error("invalid derived metric", "error", err)
+		os.Exit(1)
+	}
 	log.Info("database connected")
 
+	if seeds := allowlistSeeds(cfg.Ingest.AllowlistSeed); len(seeds) > 0 {
+		if err := db.SeedAllowlist(ctx, seeds); err != nil {
+			log.Error("allowlist seed failed", "error", err)
+			os.Exit(1)
+		}
+		log.Info("allowlist seeded", "metrics", len(seeds))
+	}
+
+	policies := retentionPolicies(cfg.Retention)
+	if *downsample {
+		reports, err := db.RunRetention(ctx, policies, time.Now(), *dryRun)
+		for _, r := range reports {
+			log.Info("retention", "metric", r.Metric, "resolution", r.Resolution,
+				"from", r.From, "until", r.Until, "raw_rows", r.RawRows,
+				"buckets", r.Buckets, "deleted", r.Deleted, "dry_run", r.DryRun)
+		}
+		if err != nil {
+			log.Error("retention failed", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Backfill sleep sessions from stages (idempotent — ON CONFLICT DO NOTHING)
 	if err := db.BackfillSleepSessions(ctx, log); err != nil {
 		log.Warn("sleep session backfill failed", "error", err)
@@ -106,17 +154,29 @@ func main() {
 	}
 
 	// Create providers
+	unitNormalizer := ingest.NewUnitNormalizer(cfg.Ingest.CanonicalUnits)
 	healthProvider := health.NewProvider(db, log)
+	healthProvider.SetUnitNormalizer(unitNormalizer)
+	healthProvider.SetTimeBounds(ingest.TimeBounds{
+		Earliest:  cfg.Ingest.EarliestTime,
+		MaxFuture: cfg.Ingest.MaxFuture,
+	})
+	healthProvider.SetRepeatCollapse(cfg.Ingest.CollapseRepeats)
+	healthProvider.SetSegmentMergeGap(cfg.Ingest.WorkoutMergeGap)
 	alphaProvider := alpha.NewProvider(db, log)
+	alphaProvider.SetLinkWindow(cfg.Ingest.AlphaLinkWindow)
+	fitProvider := fit.NewProvider(db, log)
+	fitProvider.SetRepeatCollapse(cfg.Ingest.CollapseRepeats)
 
 	// Create server
 	server.Version = Version
-	srv := server.New(db, healthProvider, alphaProvider, log)
+	srv := server.New(db, healthProvider, alphaProvider, fitProvider, log)
 
 	// Start Oura sync (always runs; no-ops if no users have Oura tokens)
 	ouraClient := oura.NewClient()
 	tokenMgr := oura.NewTokenManager(db)
 	ouraSyncer := oura.NewSyncer(ouraClient, tokenMgr, db, cfg.Oura, log)
+	ouraSyncer.SetUnitNormalizer(unitNormalizer)
 
 	syncCtx, syncCancel := context.WithCancel(ctx)
 	defer syncCancel()
@@ -125,6 +185,17 @@ func main() {
 	srv.SetOura(tokenMgr, ouraSyncer)
 	log.Info("oura sync started", "interval", cfg.Oura.SyncInterval)
 
+	srv.SetRetentionPolicies(policies)
+	srv.SetMaxImportChunks(cfg.HAE.MaxChunks)
+	srv.SetImportStallTimeout(cfg.HAE.StallTimeout)
+	srv.SetMaxRawRows(cfg.Server.MaxRawRows)
+	srv.SetQueryTimeouts(cfg.Server.QueryTimeout, cfg.Server.HeavyQueryTimeout)
+	srv.SetMigrations(dsn, "migrations")
+	if cfg.Retention.Interval > 0 && len(policies) > 0 {
+		go runRetention(syncCtx, db, policies, cfg.Retention.Interval, log)
+		log.Info("retention started", "interval", cfg.Retention.Interval, "policies", len(policies))
+	}
+
 	// Mount MCP SSE server
 	mcpSrv := freerepsmcp.New(db, Version, log)
 	srv.SetMCP(mcpSrv)
@@ -175,7 +246,15 @@ func main() {
 		log.Info("server starting", "addr", addr, "mode", "dev (no tailscale)")
 	}
 
-	httpSrv := &http.Server{Handler: srv}
+	srv.SetMaxBodyBytes(cfg.Server.MaxBodyMB << 20)
+	httpSrv := &http.Server{
+		Handler:           srv,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    1 << 20,
+	}
 
 	go func() {
 		if err := httpSrv.Serve(listener); err != nil && err != http.ErrServerClosed {
@@ -197,3 +276,79 @@ func main() {
 	}
 	log.Info("server stopped")
 }
+
+// allowlistSeeds converts configured allowlist entries to storage seeds.
+func allowlistSeeds(entries []config.AllowlistEntry) []storage.AllowlistSeed {
+	seeds := make([]storage.AllowlistSeed, 0, len(entries))
+	for _, e := range entries {
+		seeds = append(seeds, storage.AllowlistSeed{
+			MetricName:   e.Metric,
+			Category:     e.Category,
+			Enabled:      e.IsEnabled(),
+			DisplayLabel: e.DisplayLabel,
+			DisplayUnit:  e.DisplayUnit,
+			IsCumulative: e.Cumulative,
+		})
+	}
+	return seeds
+}
+
+// retentionPolicies converts configured policies to storage policies.
+func retentionPolicies(cfg config.RetentionConfig) []storage.RetentionPolicy {
+	policies := make([]storage.RetentionPolicy, 0, len(cfg.Policies))
+	for _, p := range cfg.Policies {
+		policies = append(policies, storage.RetentionPolicy{
+			Metric:     p.Metric,
+			OlderThan:  time.Duration(p.OlderThanDays) * 24 * time.Hour,
+			Resolution: p.Resolution,
+			DeleteRaw:  p.DeleteRaw,
+		})
+	}
+	return policies
+}
+
+// rirBands converts configured RIR bands; nil keeps the storage defaults.
+func rirBands(cfg []config.RIRBandConfig) []storage.RIRBandDef {
+	if len(cfg) == 0 {
+		return nil
+	}
+	bands := make([]storage.RIRBandDef, 0, len(cfg))
+	for _, b := range cfg {
+		bands = append(bands, storage.RIRBandDef{
+			Name:     b.Name,
+			RIRRange: b.Range,
+			MaxRIR:   b.MaxRIR,
+			Failure:  b.Failure,
+		})
+	}
+	return bands
+}
+
+// volumeLandmarks converts configured landmark overrides.
+func volumeLandmarks(cfg map[string]config.VolumeLandmarkConfig) map[string]storage.VolumeLandmark {
+	out := make(map[string]storage.VolumeLandmark, len(cfg))
+	for group, l := range cfg {
+		out[group] = storage.VolumeLandmark{MEV: l.MEV, MRV: l.MRV}
+	}
+	return out
+}
+
+// runRetention applies the retention policies every interval until ctx is done.
+func runRetention(ctx context.Context, db *storage.DB, policies []storage.RetentionPolicy, interval time.Duration, log *slog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reports, err := db.RunRetention(ctx, policies, time.Now(), false)
+			if err != nil {
+				log.Error("retention failed", "error", err)
+			}
+			for _, r := range reports {
+				log.Info("retention applied", "metric", r.Metric, "buckets", r.Buckets, "deleted", r.Deleted)
+			}
+		}
+	}
+}
@@ -2,25 +2,96 @@ package config
 
 import (
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/claude/freereps/internal/storage"
 	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Server         ServerConfig    `yaml:"server"`
-	Database       DatabaseConfig  `yaml:"database"`
-	Tailscale      TailscaleConfig `yaml:"tailscale"`
-	Oura           OuraConfig      `yaml:"oura"`
-	SourcePriority []string        `yaml:"source_priority"`
+	Server         ServerConfig      `yaml:"server"`
+	Log            LogConfig         `yaml:"log"`
+	Database       DatabaseConfig    `yaml:"database"`
+	Tailscale      TailscaleConfig   `yaml:"tailscale"`
+	Oura           OuraConfig        `yaml:"oura"`
+	Ingest         IngestConfig      `yaml:"ingest"`
+	Retention      RetentionConfig   `yaml:"retention"`
+	Sleep          SleepConfig       `yaml:"sleep"`
+	Training       TrainingConfig    `yaml:"training"`
+	HAE            HAEConfig         `yaml:"hae"`
+	Illness        IllnessConfig     `yaml:"illness"`
+	Correlation    CorrelationConfig `yaml:"correlation"`
+	SourcePriority []string          `yaml:"source_priority"`
+
+	// DerivedMetrics maps a derived metric name to an arithmetic expression
+	// over other metrics, e.g. "active_energy + basal_energy_burned".
+	DerivedMetrics map[string]string `yaml:"derived_metrics"`
 }
 
 type ServerConfig struct {
 	Host string `yaml:"host"`
 	Port int    `yaml:"port"`
+
+	// MaxBodyMB caps ingest/import request bodies (after gzip decoding).
+	// Requests over the limit get 413. 0 disables the limit.
+	MaxBodyMB int64 `yaml:"max_body_mb"`
+
+	// MaxRawRows caps how many rows GET /api/v1/metrics returns unaggregated;
+	// larger ranges are bucketed and flagged as downsampled.
+	MaxRawRows int `yaml:"max_raw_rows"`
+
+	ReadHeaderTimeout time.Duration `yaml:"-"`
+	ReadTimeout       time.Duration `yaml:"-"`
+	WriteTimeout      time.Duration `yaml:"-"` // 0 = none; SSE and MCP streams outlive any fixed deadline
+	IdleTimeout       time.Duration `yaml:"-"`
+
+	// QueryTimeout bounds the database work of read endpoints; heavy
+	// aggregations (correlation, training summary) get HeavyQueryTimeout.
+	// Requests over the limit get 504. 0 = unbounded.
+	QueryTimeout      time.Duration `yaml:"-"`
+	HeavyQueryTimeout time.Duration `yaml:"-"`
+
+	// Raw* fields are the YAML representations; parsed by Load.
+	RawReadHeaderTimeout string `yaml:"read_header_timeout"`
+	RawReadTimeout       string `yaml:"read_timeout"`
+	RawWriteTimeout      string `yaml:"write_timeout"`
+	RawIdleTimeout       string `yaml:"idle_timeout"`
+	RawQueryTimeout      string `yaml:"query_timeout"`
+	RawHeavyQueryTimeout string `yaml:"heavy_query_timeout"`
+}
+
+// LogConfig selects the minimum log level and the output format.
+type LogConfig struct {
+	Level  string `yaml:"level"`  // debug, info, warn, or error
+	Format string `yaml:"format"` // text or json
+}
+
+// logLevels maps the accepted level names to slog levels.
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// NewLogger builds a logger writing to w at the configured level and format.
+// Unset fields fall back to info and text.
+func (l LogConfig) NewLogger(w io.Writer) *slog.Logger {
+	level, ok := logLevels[strings.ToLower(l.Level)]
+	if !ok {
+		level = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	if strings.EqualFold(l.Format, "json") {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
 }
 
 type DatabaseConfig struct {
@@ -30,6 +101,16 @@ type DatabaseConfig struct {
 	User     string `yaml:"user"`
 	Password string `yaml:"password"`
 	SSLMode  string `yaml:"sslmode"`
+
+	// Connection pool sizing.
+	MaxConns          int32         `yaml:"max_conns"`
+	MinConns          int32         `yaml:"min_conns"` // connections kept open while idle
+	MaxConnLifetime   time.Duration `yaml:"-"`
+	HealthCheckPeriod time.Duration `yaml:"-"`
+
+	// Raw YAML representations; parsed into the durations above by Load.
+	RawMaxConnLifetime   string `yaml:"max_conn_lifetime"`
+	RawHealthCheckPeriod string `yaml:"health_check_period"`
 }
 
 type TailscaleConfig struct {
@@ -48,6 +129,166 @@ type OuraConfig struct {
 	RawSyncInterval string `yaml:"sync_interval"`
 }
 
+// IngestConfig holds settings that affect how incoming data is processed.
+type IngestConfig struct {
+	AllowlistCacheTTL time.Duration  `yaml:"-"`
+	EarliestTime      time.Time      `yaml:"-"` // data points before this are rejected
+	MaxFuture         time.Duration  `yaml:"-"` // data points after now+MaxFuture are rejected
+	Timezone          *time.Location `yaml:"-"` // local zone used to assign sleep nights to dates
+	AlphaLinkWindow   time.Duration  `yaml:"-"` // max start-time gap when linking Alpha sessions to workouts
+	WorkoutMergeGap   time.Duration  `yaml:"-"` // join same-type workouts separated by less than this (0 = off)
+
+	// DayStartHour shifts daily buckets and daily sums so a day runs from
+	// this hour to the same hour next day (0 = midnight). Late-night samples
+	// before it count toward the previous day.
+	DayStartHour int `yaml:"day_start_hour"`
+
+	// CanonicalUnits overrides or extends the built-in metric → unit table
+	// used to normalize incoming values (e.g. weight_body_mass: kg).
+	CanonicalUnits map[string]string `yaml:"canonical_units"`
+
+	// CollapseRepeats opts metrics into dropping a point identical in value
+	// and source to the one before it, at most this long after it.
+	// Cumulative metrics are rejected.
+	CollapseRepeats map[string]time.Duration `yaml:"-"`
+
+	// AllowlistSeed entries are added to the metric allowlist at startup, so
+	// new metrics can be enabled without a migration. Metrics already in the
+	// allowlist are left alone. Entries from
+	// AllowlistSeedFile (a YAML list of the same shape, relative paths resolved
+	// against the config file) are applied first; inline entries win.
+	AllowlistSeed     []AllowlistEntry `yaml:"allowlist_seed"`
+	AllowlistSeedFile string           `yaml:"allowlist_seed_file"`
+
+	// Raw* fields are the YAML representations; parsed by Load.
+	RawAllowlistCacheTTL string            `yaml:"allowlist_cache_ttl"`
+	RawEarliestTime      string            `yaml:"earliest_time"`
+	RawMaxFuture         string            `yaml:"max_future"`
+	RawTimezone          string            `yaml:"timezone"`
+	RawAlphaLinkWindow   string            `yaml:"alpha_link_window"`
+	RawWorkoutMergeGap   string            `yaml:"workout_merge_gap"`
+	RawCollapseRepeats   map[string]string `yaml:"collapse_repeats"`
+}
+
+// AllowlistEntry seeds one metric_allowlist row. Enabled defaults to true.
+type AllowlistEntry struct {
+	Metric       string `yaml:"metric"`
+	Category     string `yaml:"category"`
+	Enabled      *bool  `yaml:"enabled"`
+	DisplayLabel string `yaml:"display_label"`
+	DisplayUnit  string `yaml:"display_unit"`
+	Cumulative   bool   `yaml:"cumulative"`
+}
+
+// IsEnabled reports whether the entry enables its metric (true when unset).
+func (e AllowlistEntry) IsEnabled() bool {
+	return e.Enabled == nil || *e.Enabled
+}
+
+// SleepConfig holds defaults for sleep analysis.
+type SleepConfig struct {
+	TargetHours float64 `yaml:"target_hours"` // nightly need used for sleep debt
+	// MissingNightsAsZero counts nights without any session as zero sleep in
+	// sleep debt. Off by default: a night without data usually means the
+	// device wasn't worn, not that the user didn't sleep.
+	MissingNightsAsZero bool `yaml:"missing_nights_as_zero"`
+	// DayBoundary assigns nights to days: "midnight" files a night under its
+	// wake-up date, "noon" under the noon-to-noon sleep day it starts in, so
+	// a 1am bedtime counts toward the evening before.
+	DayBoundary string `yaml:"day_boundary"`
+}
+
+// TrainingConfig holds settings for strength-training analysis.
+type TrainingConfig struct {
+	// RIRBands replaces the built-in RIR intensity bands when non-empty.
+	// Bands are listed from hardest to easiest; every band but the last sets
+	// max_rir, and the last one takes all remaining tracked sets.
+	RIRBands []RIRBandConfig `yaml:"rir_bands"`
+
+	// Strength session calories are estimated as
+	// strength_met × bodyweight (kg) × session hours, using the latest
+	// weight_body_mass sample or DefaultBodyweightKg. 0 disables the estimate.
+	StrengthMET         float64 `yaml:"strength_met"`
+	DefaultBodyweightKg float64 `yaml:"default_bodyweight_kg"`
+
+	// VolumeLandmarks overrides the built-in weekly working-set landmarks per
+	// muscle group (e.g. "chest"); groups not listed keep their defaults.
+	VolumeLandmarks map[string]VolumeLandmarkConfig `yaml:"volume_landmarks"`
+
+	// WorkoutTypes maps raw workout names onto the canonical types training
+	// summaries group by, overriding or extending the built-in mapping.
+	WorkoutTypes map[string]string `yaml:"workout_types"`
+}
+
+// VolumeLandmarkConfig is a muscle group's minimum effective (MEV) and
+// maximum recoverable (MRV) weekly working sets.
+type VolumeLandmarkConfig struct {
+	MEV int `yaml:"mev"`
+	MRV int `yaml:"mrv"`
+}
+
+// RIRBandConfig defines one RIR band. Failure bands count toward the
+// failure rate.
+type RIRBandConfig struct {
+	Name    string   `yaml:"name"`
+	Range   string   `yaml:"range"`
+	MaxRIR  *float64 `yaml:"max_rir"`
+	Failure bool     `yaml:"failure"`
+}
+
+// HAEConfig limits imports pulled from a Health Auto Export TCP server,
+// which is easily overwhelmed by large backfills.
+type HAEConfig struct {
+	// MaxChunks caps how many date chunks one import may request; each chunk
+	// costs one request per metric plus one for workouts.
+	MaxChunks int `yaml:"max_chunks"`
+
+	// StallTimeout ends an import as errored when no chunk starts within
+	// it, e.g. because HAE stopped answering mid-read. 0 disables the
+	// watchdog.
+	StallTimeout time.Duration `yaml:"-"`
+
+	// RawStallTimeout is the YAML representation; parsed by Load.
+	RawStallTimeout string `yaml:"stall_timeout"`
+}
+
+// IllnessConfig holds the thresholds for illness-onset detection. Each vital
+// is compared against its mean over the trailing BaselineDays.
+type IllnessConfig struct {
+	BaselineDays     int     `yaml:"baseline_days"`
+	MinBaselineDays  int     `yaml:"min_baseline_days"`   // days with data needed before a vital is judged
+	RestingHRRiseBPM float64 `yaml:"resting_hr_rise_bpm"` // resting HR above baseline
+	HRVDropPct       float64 `yaml:"hrv_drop_pct"`        // HRV below baseline, percent
+	RespRateRise     float64 `yaml:"resp_rate_rise"`      // breaths/min above baseline
+	WristTempRiseC   float64 `yaml:"wrist_temp_rise_c"`   // sleeping wrist temperature above baseline
+	MinSignals       int     `yaml:"min_signals"`         // triggered vitals needed to flag likely illness
+}
+
+// CorrelationConfig controls when a correlation coefficient is reported.
+type CorrelationConfig struct {
+	// MinPoints is the fewest paired buckets a coefficient is computed from;
+	// with fewer, pearson_r is null. Very small samples give r = ±1 by chance.
+	MinPoints int `yaml:"min_points"`
+}
+
+// RetentionConfig controls downsampling of old high-frequency metrics.
+type RetentionConfig struct {
+	Interval time.Duration           `yaml:"-"` // 0 = only run on demand (-downsample or the admin endpoint)
+	Policies []RetentionPolicyConfig `yaml:"policies"`
+
+	// RawInterval is the YAML representation; parsed into Interval by Load.
+	RawInterval string `yaml:"interval"`
+}
+
+// RetentionPolicyConfig rolls up one metric's samples older than OlderThanDays
+// into Resolution buckets ("1 hour" or "1 day").
+type RetentionPolicyConfig struct {
+	Metric        string `yaml:"metric"`
+	OlderThanDays int    `yaml:"older_than_days"`
+	Resolution    string `yaml:"resolution"`
+	DeleteRaw     bool   `yaml:"delete_raw"`
+}
+
 // DSN returns a PostgreSQL connection string.
 func (d DatabaseConfig) DSN() string {
 	sslmode := d.SSLMode
@@ -62,11 +303,33 @@ func (d DatabaseConfig) DSN() string {
 // Env vars use the prefix FREEREPS_ and underscore-separated paths:
 //
 //	FREEREPS_SERVER_HOST, FREEREPS_SERVER_PORT,
+//	FREEREPS_LOG_LEVEL, FREEREPS_LOG_FORMAT,
 //	FREEREPS_DB_HOST, FREEREPS_DB_PORT, FREEREPS_DB_NAME,
 //	FREEREPS_DB_USER, FREEREPS_DB_PASSWORD, FREEREPS_DB_SSLMODE,
+//	FREEREPS_DB_MAX_CONNS,
 //	FREEREPS_TS_ENABLED, FREEREPS_TS_HOSTNAME, FREEREPS_TS_STATE_DIR
 func Load(path string) (*Config, error) {
 	cfg := &Config{
+		Server: ServerConfig{
+			MaxBodyMB:            1024,
+			MaxRawRows:           50000,
+			RawReadHeaderTimeout: "10s",
+			RawReadTimeout:       "10m",
+			RawWriteTimeout:      "0s",
+			RawIdleTimeout:       "2m",
+			RawQueryTimeout:      "30s",
+			RawHeavyQueryTimeout: "2m",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Database: DatabaseConfig{
+			MaxConns:             16,
+			MinConns:             2,
+			RawMaxConnLifetime:   "1h",
+			RawHealthCheckPeriod: "1m",
+		},
 		Tailscale: TailscaleConfig{
 			Enabled:  true,
 			Hostname: "freereps",
@@ -76,7 +339,38 @@ func Load(path string) (*Config, error) {
 			RawSyncInterval: "30m",
 			BackfillDays:    90,
 		},
-		SourcePriority: []string{"Oura", ""},
+		Ingest: IngestConfig{
+			RawAllowlistCacheTTL: "60s",
+			RawEarliestTime:      "2014-01-01",
+			RawMaxFuture:         "24h",
+			RawTimezone:          "UTC",
+			RawAlphaLinkWindow:   "2h",
+		},
+		Sleep: SleepConfig{
+			TargetHours: 8,
+			DayBoundary: "midnight",
+		},
+		Training: TrainingConfig{
+			StrengthMET:         5,
+			DefaultBodyweightKg: 75,
+		},
+		HAE: HAEConfig{
+			MaxChunks:       260,
+			RawStallTimeout: "10m",
+		},
+		Illness: IllnessConfig{
+			BaselineDays:     28,
+			MinBaselineDays:  14,
+			RestingHRRiseBPM: 5,
+			HRVDropPct:       20,
+			RespRateRise:     1,
+			WristTempRiseC:   0.5,
+			MinSignals:       2,
+		},
+		Correlation: CorrelationConfig{
+			MinPoints: 10,
+		},
+		SourcePriority: []string{"Oura", "Apple Watch", "iPhone", ""},
 	}
 
 	data, err := os.ReadFile(path)
@@ -89,6 +383,32 @@ func Load(path string) (*Config, error) {
 
 	applyEnvOverrides(cfg)
 
+	// Parse HTTP server timeouts and pool durations.
+	for _, t := range []struct {
+		name string
+		raw  string
+		dst  *time.Duration
+	}{
+		{"server.read_header_timeout", cfg.Server.RawReadHeaderTimeout, &cfg.Server.ReadHeaderTimeout},
+		{"server.read_timeout", cfg.Server.RawReadTimeout, &cfg.Server.ReadTimeout},
+		{"server.write_timeout", cfg.Server.RawWriteTimeout, &cfg.Server.WriteTimeout},
+		{"server.idle_timeout", cfg.Server.RawIdleTimeout, &cfg.Server.IdleTimeout},
+		{"server.query_timeout", cfg.Server.RawQueryTimeout, &cfg.Server.QueryTimeout},
+		{"server.heavy_query_timeout", cfg.Server.RawHeavyQueryTimeout, &cfg.Server.HeavyQueryTimeout},
+		{"database.max_conn_lifetime", cfg.Database.RawMaxConnLifetime, &cfg.Database.MaxConnLifetime},
+		{"database.health_check_period", cfg.Database.RawHealthCheckPeriod, &cfg.Database.HealthCheckPeriod},
+		{"hae.stall_timeout", cfg.HAE.RawStallTimeout, &cfg.HAE.StallTimeout},
+	} {
+		if t.raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(t.raw)
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", t.name, err)
+		}
+		*t.dst = d
+	}
+
 	// Parse Oura sync interval.
 	if cfg.Oura.RawSyncInterval != "" {
 		d, err := time.ParseDuration(cfg.Oura.RawSyncInterval)
@@ -98,6 +418,76 @@ func Load(path string) (*Config, error) {
 		cfg.Oura.SyncInterval = d
 	}
 
+	if cfg.Ingest.RawAllowlistCacheTTL != "" {
+		d, err := time.ParseDuration(cfg.Ingest.RawAllowlistCacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ingest.allowlist_cache_ttl: %w", err)
+		}
+		cfg.Ingest.AllowlistCacheTTL = d
+	}
+	if cfg.Ingest.RawEarliestTime != "" {
+		t, err := time.Parse("2006-01-02", cfg.Ingest.RawEarliestTime)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ingest.earliest_time: %w", err)
+		}
+		cfg.Ingest.EarliestTime = t
+	}
+	if cfg.Ingest.RawMaxFuture != "" {
+		d, err := time.ParseDuration(cfg.Ingest.RawMaxFuture)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ingest.max_future: %w", err)
+		}
+		cfg.Ingest.MaxFuture = d
+	}
+	if cfg.Ingest.RawTimezone != "" {
+		loc, err := time.LoadLocation(cfg.Ingest.RawTimezone)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ingest.timezone: %w", err)
+		}
+		cfg.Ingest.Timezone = loc
+	}
+	if cfg.Ingest.RawAlphaLinkWindow != "" {
+		d, err := time.ParseDuration(cfg.Ingest.RawAlphaLinkWindow)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ingest.alpha_link_window: %w", err)
+		}
+		cfg.Ingest.AlphaLinkWindow = d
+	}
+	if cfg.Ingest.RawWorkoutMergeGap != "" {
+		d, err := time.ParseDuration(cfg.Ingest.RawWorkoutMergeGap)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ingest.workout_merge_gap: %w", err)
+		}
+		cfg.Ingest.WorkoutMergeGap = d
+	}
+	for metric, raw := range cfg.Ingest.RawCollapseRepeats {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ingest.collapse_repeats.%s: %w", metric, err)
+		}
+		if cfg.Ingest.CollapseRepeats == nil {
+			cfg.Ingest.CollapseRepeats = make(map[string]time.Duration)
+		}
+		cfg.Ingest.CollapseRepeats[metric] = d
+	}
+	if f := cfg.Ingest.AllowlistSeedFile; f != "" {
+		if !filepath.IsAbs(f) {
+			f = filepath.Join(filepath.Dir(path), f)
+		}
+		seed, err := loadAllowlistSeed(f)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Ingest.AllowlistSeed = mergeAllowlistSeed(seed, cfg.Ingest.AllowlistSeed)
+	}
+	if cfg.Retention.RawInterval != "" {
+		d, err := time.ParseDuration(cfg.Retention.RawInterval)
+		if err != nil {
+			return nil, fmt.Errorf("parsing retention.interval: %w", err)
+		}
+		cfg.Retention.Interval = d
+	}
+
 	if err := cfg.validate(); err != nil {
 		return nil, fmt.Errorf("config validation: %w", err)
 	}
@@ -105,6 +495,37 @@ func Load(path string) (*Config, error) {
 	return cfg, nil
 }
 
+// loadAllowlistSeed reads a YAML list of allowlist entries.
+func loadAllowlistSeed(path string) ([]AllowlistEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading allowlist seed file: %w", err)
+	}
+	var entries []AllowlistEntry
+	if err := yaml.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parsing allowlist seed file: %w", err)
+	}
+	return entries, nil
+}
+
+// mergeAllowlistSeed combines seed lists, with later lists replacing earlier
+// entries for the same metric. Order of first appearance is kept.
+func mergeAllowlistSeed(lists ...[]AllowlistEntry) []AllowlistEntry {
+	var out []AllowlistEntry
+	index := make(map[string]int)
+	for _, list := range lists {
+		for _, e := range list {
+			if i, ok := index[e.Metric]; ok {
+				out[i] = e
+				continue
+			}
+			index[e.Metric] = len(out)
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
 func applyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("FREEREPS_SERVER_HOST"); v != "" {
 		cfg.Server.Host = v
@@ -114,6 +535,12 @@ func applyEnvOverrides(cfg *Config) {
 			cfg.Server.Port = port
 		}
 	}
+	if v := os.Getenv("FREEREPS_LOG_LEVEL"); v != "" {
+		cfg.Log.Level = v
+	}
+	if v := os.Getenv("FREEREPS_LOG_FORMAT"); v != "" {
+		cfg.Log.Format = v
+	}
 	if v := os.Getenv("FREEREPS_DB_HOST"); v != "" {
 		cfg.Database.Host = v
 	}
@@ -134,6 +561,11 @@ func applyEnvOverrides(cfg *Config) {
 	if v := os.Getenv("FREEREPS_DB_SSLMODE"); v != "" {
 		cfg.Database.SSLMode = v
 	}
+	if v := os.Getenv("FREEREPS_DB_MAX_CONNS"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 32); err == nil {
+			cfg.Database.MaxConns = int32(n)
+		}
+	}
 	if v := os.Getenv("FREEREPS_TS_ENABLED"); v != "" {
 		cfg.Tailscale.Enabled = strings.EqualFold(v, "true") || v == "1"
 	}
@@ -149,6 +581,12 @@ func (c *Config) validate() error {
 	if !c.Tailscale.Enabled && c.Server.Port == 0 {
 		return fmt.Errorf("server.port is required when tailscale is disabled")
 	}
+	if _, ok := logLevels[strings.ToLower(c.Log.Level)]; !ok {
+		return fmt.Errorf("log.level must be debug, info, warn, or error")
+	}
+	if f := strings.ToLower(c.Log.Format); f != "text" && f != "json" {
+		return fmt.Errorf("log.format must be text or json")
+	}
 	if c.Database.Host == "" {
 		return fmt.Errorf("database.host is required")
 	}
@@ -161,5 +599,130 @@ func (c *Config) validate() error {
 	if c.Database.User == "" {
 		return fmt.Errorf("database.user is required")
 	}
+	if c.Database.MaxConns <= 0 || c.Database.MinConns < 0 || c.Database.MinConns > c.Database.MaxConns {
+		return fmt.Errorf("database: need 0 <= min_conns <= max_conns and max_conns > 0")
+	}
+	if c.Database.MaxConnLifetime <= 0 || c.Database.HealthCheckPeriod <= 0 {
+		return fmt.Errorf("database.max_conn_lifetime and database.health_check_period must be positive")
+	}
+	if c.Sleep.TargetHours <= 0 || c.Sleep.TargetHours > 24 {
+		return fmt.Errorf("sleep.target_hours must be between 0 and 24")
+	}
+	if c.Sleep.DayBoundary != "midnight" && c.Sleep.DayBoundary != "noon" {
+		return fmt.Errorf("sleep.day_boundary must be \"midnight\" or \"noon\"")
+	}
+	cumulative := map[string]bool{}
+	for _, e := range c.Ingest.AllowlistSeed {
+		cumulative[e.Metric] = e.Cumulative
+	}
+	for metric, d := range c.Ingest.CollapseRepeats {
+		if d <= 0 {
+			return fmt.Errorf("ingest.collapse_repeats.%s must be positive", metric)
+		}
+		// Each repeated sample of a summed metric is a real increment, so
+		// dropping one would undercount the total.
+		if cumulative[metric] || storage.IsCumulativeMetric(metric) {
+			return fmt.Errorf("ingest.collapse_repeats.%s: cumulative metrics cannot be collapsed", metric)
+		}
+	}
+	if c.Ingest.DayStartHour < 0 || c.Ingest.DayStartHour > 23 {
+		return fmt.Errorf("ingest.day_start_hour must be between 0 and 23")
+	}
+	if c.Ingest.AlphaLinkWindow < 0 {
+		return fmt.Errorf("ingest.alpha_link_window must not be negative")
+	}
+	if c.Ingest.WorkoutMergeGap < 0 {
+		return fmt.Errorf("ingest.workout_merge_gap must not be negative")
+	}
+	for i, e := range c.Ingest.AllowlistSeed {
+		if e.Metric == "" || e.Category == "" {
+			return fmt.Errorf("ingest.allowlist_seed[%d]: metric and category are required", i)
+		}
+	}
+	if c.Training.StrengthMET < 0 || c.Training.StrengthMET > 20 {
+		return fmt.Errorf("training.strength_met must be between 0 and 20")
+	}
+	if c.Training.DefaultBodyweightKg <= 0 {
+		return fmt.Errorf("training.default_bodyweight_kg must be positive")
+	}
+	for group, l := range c.Training.VolumeLandmarks {
+		if l.MEV < 0 || l.MRV < l.MEV {
+			return fmt.Errorf("training.volume_landmarks.%s: need 0 <= mev <= mrv", group)
+		}
+	}
+	if c.Server.MaxRawRows <= 0 {
+		return fmt.Errorf("server.max_raw_rows must be positive")
+	}
+	if c.Server.QueryTimeout < 0 || c.Server.HeavyQueryTimeout < 0 {
+		return fmt.Errorf("server query timeouts must not be negative")
+	}
+	if c.HAE.MaxChunks <= 0 {
+		return fmt.Errorf("hae.max_chunks must be positive")
+	}
+	if c.HAE.StallTimeout < 0 {
+		return fmt.Errorf("hae.stall_timeout must not be negative")
+	}
+	if il := c.Illness; il.BaselineDays <= 0 || il.MinBaselineDays <= 0 || il.MinBaselineDays > il.BaselineDays {
+		return fmt.Errorf("illness: need 0 < min_baseline_days <= baseline_days")
+	}
+	if il := c.Illness; il.RestingHRRiseBPM <= 0 || il.HRVDropPct <= 0 || il.HRVDropPct >= 100 || il.RespRateRise <= 0 || il.WristTempRiseC <= 0 {
+		return fmt.Errorf("illness thresholds must be positive (hrv_drop_pct below 100)")
+	}
+	if c.Illness.MinSignals < 1 || c.Illness.MinSignals > 4 {
+		return fmt.Errorf("illness.min_signals must be between 1 and 4")
+	}
+	if c.Correlation.MinPoints < 3 {
+		return fmt.Errorf("correlation.min_points must be at least 3")
+	}
+	if err := validateRIRBands(c.Training.RIRBands); err != nil {
+		return err
+	}
+	for name, expr := range c.DerivedMetrics {
+		if name == "" || strings.TrimSpace(expr) == "" {
+			return fmt.Errorf("derived_metrics: %q needs a name and an expression", name)
+		}
+	}
+	seen := make(map[string]bool)
+	for i, p := range c.Retention.Policies {
+		if p.Metric == "" {
+			return fmt.Errorf("retention.policies[%d].metric is required", i)
+		}
+		if seen[p.Metric] {
+			return fmt.Errorf("retention.policies[%d]: duplicate policy for %s", i, p.Metric)
+		}
+		seen[p.Metric] = true
+		if p.OlderThanDays <= 0 {
+			return fmt.Errorf("retention.policies[%d].older_than_days must be positive", i)
+		}
+		if p.Resolution != "1 hour" && p.Resolution != "1 day" {
+			return fmt.Errorf("retention.policies[%d].resolution must be \"1 hour\" or \"1 day\"", i)
+		}
+	}
+	return nil
+}
+
+// validateRIRBands checks bands are uniquely named, ascending, and closed by
+// exactly one open-ended band.
+func validateRIRBands(bands []RIRBandConfig) error {
+	names := make(map[string]bool)
+	for i, b := range bands {
+		if b.Name == "" || b.Name == "untracked" {
+			return fmt.Errorf("training.rir_bands[%d].name is required and must not be \"untracked\"", i)
+		}
+		if names[b.Name] {
+			return fmt.Errorf("training.rir_bands[%d]: duplicate band %s", i, b.Name)
+		}
+		names[b.Name] = true
+
+		last := i == len(bands)-1
+		switch {
+		case last && b.MaxRIR != nil:
+			return fmt.Errorf("training.rir_bands[%d]: the last band must omit max_rir", i)
+		case !last && b.MaxRIR == nil:
+			return fmt.Errorf("training.rir_bands[%d].max_rir is required", i)
+		case i > 0 && !last && *b.MaxRIR <= *bands[i-1].MaxRIR:
+			return fmt.Errorf("training.rir_bands[%d].max_rir must be greater than the previous band's", i)
+		}
+	}
 	return nil
 }
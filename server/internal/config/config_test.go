@@ -1,9 +1,13 @@
 package config
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 const validYAML = `
@@ -226,21 +230,17 @@ func TestOuraDefaults(t *testing.T) {
 	}
 }
 
-// TestSourcePriorityDefault verifies the default source priority list ensures
-// Oura data is preferred over HealthKit when both sources overlap.
+// TestSourcePriorityDefault verifies the default source priority list prefers
+// Oura over HealthKit when both sources overlap, and the Apple Watch over the
+// iPhone within HealthKit.
 func TestSourcePriorityDefault(t *testing.T) {
 	cfg, err := Load(writeTemp(t, validYAML))
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if len(cfg.SourcePriority) != 2 {
-		t.Fatalf("source_priority length = %d, want 2", len(cfg.SourcePriority))
-	}
-	if cfg.SourcePriority[0] != "Oura" {
-		t.Errorf("source_priority[0] = %q, want %q", cfg.SourcePriority[0], "Oura")
-	}
-	if cfg.SourcePriority[1] != "" {
-		t.Errorf("source_priority[1] = %q, want empty string", cfg.SourcePriority[1])
+	want := []string{"Oura", "Apple Watch", "iPhone", ""}
+	if !reflect.DeepEqual(cfg.SourcePriority, want) {
+		t.Errorf("source_priority = %q, want %q", cfg.SourcePriority, want)
 	}
 }
 
@@ -271,3 +271,609 @@ oura:
 		t.Errorf("oura.backfill_days = %d, want 30", cfg.Oura.BackfillDays)
 	}
 }
+
+// TestIngestAllowlistCacheTTL verifies the allowlist cache TTL defaults to 60s
+// and can be overridden (including "0s" to disable caching).
+func TestIngestAllowlistCacheTTL(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Ingest.AllowlistCacheTTL != 60*time.Second {
+		t.Errorf("ingest.allowlist_cache_ttl = %v, want 60s", cfg.Ingest.AllowlistCacheTTL)
+	}
+
+	cfg, err = Load(writeTemp(t, validYAML+"ingest:\n  allowlist_cache_ttl: \"0s\"\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Ingest.AllowlistCacheTTL != 0 {
+		t.Errorf("ingest.allowlist_cache_ttl = %v, want 0", cfg.Ingest.AllowlistCacheTTL)
+	}
+
+	if _, err := Load(writeTemp(t, validYAML+"ingest:\n  allowlist_cache_ttl: \"soon\"\n")); err == nil {
+		t.Error("expected error for invalid duration")
+	}
+}
+
+// TestIngestTimeBounds verifies the ingest timestamp bounds default to
+// 2014-01-01 / 24h and accept overrides from YAML.
+func TestIngestTimeBounds(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC); !cfg.Ingest.EarliestTime.Equal(want) {
+		t.Errorf("ingest.earliest_time = %v, want %v", cfg.Ingest.EarliestTime, want)
+	}
+	if cfg.Ingest.MaxFuture != 24*time.Hour {
+		t.Errorf("ingest.max_future = %v, want 24h", cfg.Ingest.MaxFuture)
+	}
+
+	cfg, err = Load(writeTemp(t, validYAML+"ingest:\n  earliest_time: \"2010-06-01\"\n  max_future: \"2h\"\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Ingest.EarliestTime.Year() != 2010 || cfg.Ingest.MaxFuture != 2*time.Hour {
+		t.Errorf("got earliest=%v max_future=%v, want 2010-06-01 / 2h", cfg.Ingest.EarliestTime, cfg.Ingest.MaxFuture)
+	}
+
+	if _, err := Load(writeTemp(t, validYAML+"ingest:\n  earliest_time: \"June 2010\"\n")); err == nil {
+		t.Error("expected error for invalid earliest_time")
+	}
+}
+
+// TestIngestAlphaLinkWindow verifies the Alpha session link window defaults
+// to 2h and rejects negative or unparseable values.
+func TestIngestAlphaLinkWindow(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Ingest.AlphaLinkWindow != 2*time.Hour {
+		t.Errorf("ingest.alpha_link_window = %v, want 2h", cfg.Ingest.AlphaLinkWindow)
+	}
+
+	cfg, err = Load(writeTemp(t, validYAML+"ingest:\n  alpha_link_window: \"45m\"\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Ingest.AlphaLinkWindow != 45*time.Minute {
+		t.Errorf("ingest.alpha_link_window = %v, want 45m", cfg.Ingest.AlphaLinkWindow)
+	}
+
+	for _, v := range []string{"-1h", "later"} {
+		if _, err := Load(writeTemp(t, validYAML+"ingest:\n  alpha_link_window: \""+v+"\"\n")); err == nil {
+			t.Errorf("expected error for alpha_link_window %q", v)
+		}
+	}
+}
+
+// TestIngestDayStartHour verifies the day start hour defaults to midnight,
+// accepts an hour of the day, and rejects values outside 0–23.
+func TestIngestDayStartHour(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Ingest.DayStartHour != 0 {
+		t.Errorf("ingest.day_start_hour = %d, want 0", cfg.Ingest.DayStartHour)
+	}
+	cfg, err = Load(writeTemp(t, validYAML+"ingest:\n  day_start_hour: 4\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Ingest.DayStartHour != 4 {
+		t.Errorf("ingest.day_start_hour = %d, want 4", cfg.Ingest.DayStartHour)
+	}
+	for _, h := range []string{"-1", "24"} {
+		if _, err := Load(writeTemp(t, validYAML+"ingest:\n  day_start_hour: "+h+"\n")); err == nil {
+			t.Errorf("expected error for day_start_hour %s", h)
+		}
+	}
+}
+
+// TestIngestTimezone verifies the sleep-dating zone defaults to UTC, accepts
+// IANA names, and rejects unknown zones at startup rather than at backfill.
+func TestIngestTimezone(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Ingest.Timezone != time.UTC {
+		t.Errorf("ingest.timezone = %v, want UTC", cfg.Ingest.Timezone)
+	}
+
+	cfg, err = Load(writeTemp(t, validYAML+"ingest:\n  timezone: \"Europe/Berlin\"\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Ingest.Timezone.String() != "Europe/Berlin" {
+		t.Errorf("ingest.timezone = %v, want Europe/Berlin", cfg.Ingest.Timezone)
+	}
+
+	if _, err := Load(writeTemp(t, validYAML+"ingest:\n  timezone: \"Mars/Olympus\"\n")); err == nil {
+		t.Error("expected error for unknown timezone")
+	}
+}
+
+// TestServerLimitsDefaults verifies HTTP timeouts and the body cap have safe
+// defaults (no write timeout, so SSE streams survive) and can be overridden.
+func TestServerLimitsDefaults(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Server.ReadHeaderTimeout != 10*time.Second || cfg.Server.ReadTimeout != 10*time.Minute ||
+		cfg.Server.WriteTimeout != 0 || cfg.Server.IdleTimeout != 2*time.Minute {
+		t.Errorf("timeouts = %+v", cfg.Server)
+	}
+	if cfg.Server.MaxBodyMB != 1024 {
+		t.Errorf("server.max_body_mb = %d, want 1024", cfg.Server.MaxBodyMB)
+	}
+	if cfg.Server.MaxRawRows != 50000 {
+		t.Errorf("server.max_raw_rows = %d, want 50000", cfg.Server.MaxRawRows)
+	}
+	if cfg.Server.QueryTimeout != 30*time.Second || cfg.Server.HeavyQueryTimeout != 2*time.Minute {
+		t.Errorf("query timeouts = %v / %v, want 30s / 2m", cfg.Server.QueryTimeout, cfg.Server.HeavyQueryTimeout)
+	}
+
+	yaml := `
+server:
+  port: 8080
+  max_body_mb: 50
+  read_timeout: "30s"
+database:
+  host: "localhost"
+  port: 5432
+  name: "freereps"
+  user: "freereps"
+tailscale:
+  enabled: false
+`
+	cfg, err = Load(writeTemp(t, yaml))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Server.MaxBodyMB != 50 || cfg.Server.ReadTimeout != 30*time.Second {
+		t.Errorf("got max_body_mb=%d read_timeout=%v, want 50 / 30s", cfg.Server.MaxBodyMB, cfg.Server.ReadTimeout)
+	}
+
+	if _, err := Load(writeTemp(t, strings.Replace(yaml, `"30s"`, `"soon"`, 1))); err == nil {
+		t.Error("expected error for invalid read_timeout")
+	}
+	if _, err := Load(writeTemp(t, strings.Replace(yaml, "max_body_mb: 50", "max_raw_rows: -1", 1))); err == nil {
+		t.Error("expected error for negative max_raw_rows")
+	}
+	if _, err := Load(writeTemp(t, strings.Replace(yaml, `read_timeout: "30s"`, `query_timeout: "-1s"`, 1))); err == nil {
+		t.Error("expected error for negative query_timeout")
+	}
+}
+
+// TestRetentionPolicies verifies retention is off by default and that malformed
+// policies are rejected at load time rather than failing mid-downsample.
+func TestRetentionPolicies(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Retention.Interval != 0 || len(cfg.Retention.Policies) != 0 {
+		t.Errorf("retention = %+v, want disabled", cfg.Retention)
+	}
+
+	policy := "retention:\n  interval: \"24h\"\n  policies:\n    - metric: \"heart_rate\"\n      older_than_days: 90\n      resolution: \"1 hour\"\n      delete_raw: true\n"
+	cfg, err = Load(writeTemp(t, validYAML+policy))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Retention.Interval != 24*time.Hour {
+		t.Errorf("retention.interval = %v, want 24h", cfg.Retention.Interval)
+	}
+	want := RetentionPolicyConfig{Metric: "heart_rate", OlderThanDays: 90, Resolution: "1 hour", DeleteRaw: true}
+	if len(cfg.Retention.Policies) != 1 || cfg.Retention.Policies[0] != want {
+		t.Errorf("retention.policies = %+v, want [%+v]", cfg.Retention.Policies, want)
+	}
+
+	for _, bad := range []string{
+		strings.Replace(policy, `"1 hour"`, `"1 week"`, 1),
+		strings.Replace(policy, "older_than_days: 90", "older_than_days: 0", 1),
+		strings.Replace(policy, `metric: "heart_rate"`, `metric: ""`, 1),
+		policy + "    - metric: \"heart_rate\"\n      older_than_days: 30\n      resolution: \"1 day\"\n",
+		strings.Replace(policy, `"24h"`, `"daily"`, 1),
+	} {
+		if _, err := Load(writeTemp(t, validYAML+bad)); err == nil {
+			t.Errorf("expected error for:\n%s", bad)
+		}
+	}
+}
+
+// TestAllowlistSeed verifies seed entries load from both a seed file and the
+// inline list, that inline entries override the file, and that enabled
+// defaults to true so listing a metric is enough to allow it.
+func TestAllowlistSeed(t *testing.T) {
+	dir := t.TempDir()
+	seed := `
+- metric: "time_in_daylight"
+  category: "activity"
+  display_label: "Daylight"
+  display_unit: "min"
+  cumulative: true
+- metric: "cycling_cadence"
+  category: "fitness"
+`
+	if err := os.WriteFile(filepath.Join(dir, "allowlist.yaml"), []byte(seed), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfgPath := filepath.Join(dir, "config.yaml")
+	yml := validYAML + `ingest:
+  allowlist_seed_file: "allowlist.yaml"
+  allowlist_seed:
+    - metric: "cycling_cadence"
+      category: "fitness"
+      enabled: false
+`
+	if err := os.WriteFile(cfgPath, []byte(yml), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := Load(cfgPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := cfg.Ingest.AllowlistSeed
+	if len(got) != 2 {
+		t.Fatalf("allowlist_seed = %+v, want 2 entries", got)
+	}
+	if got[0].Metric != "time_in_daylight" || !got[0].IsEnabled() || !got[0].Cumulative || got[0].DisplayUnit != "min" {
+		t.Errorf("seed file entry = %+v", got[0])
+	}
+	if got[1].Metric != "cycling_cadence" || got[1].IsEnabled() {
+		t.Errorf("inline entry should override the file and disable cycling_cadence: %+v", got[1])
+	}
+
+	if _, err := Load(writeTemp(t, validYAML+"ingest:\n  allowlist_seed:\n    - metric: \"x\"\n")); err == nil {
+		t.Error("expected error for entry without category")
+	}
+	if _, err := Load(writeTemp(t, validYAML+"ingest:\n  allowlist_seed_file: \"/nonexistent/seed.yaml\"\n")); err == nil {
+		t.Error("expected error for missing seed file")
+	}
+}
+
+// TestSleepDefaults verifies the sleep debt target defaults to 8h with
+// missing nights skipped, and that impossible targets are rejected.
+func TestSleepDefaults(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Sleep.TargetHours != 8 || cfg.Sleep.MissingNightsAsZero {
+		t.Errorf("sleep = %+v, want 8h target, missing nights skipped", cfg.Sleep)
+	}
+	if _, err := Load(writeTemp(t, validYAML+"sleep:\n  target_hours: 25\n")); err == nil {
+		t.Error("expected error for target_hours > 24")
+	}
+}
+
+// TestSleepDayBoundary verifies nights default to the midnight boundary,
+// that noon can be selected, and that other values are rejected.
+func TestSleepDayBoundary(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Sleep.DayBoundary != "midnight" {
+		t.Errorf("day_boundary = %q, want midnight", cfg.Sleep.DayBoundary)
+	}
+	cfg, err = Load(writeTemp(t, validYAML+"sleep:\n  day_boundary: noon\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Sleep.DayBoundary != "noon" {
+		t.Errorf("day_boundary = %q, want noon", cfg.Sleep.DayBoundary)
+	}
+	if _, err := Load(writeTemp(t, validYAML+"sleep:\n  day_boundary: \"18:00\"\n")); err == nil {
+		t.Error("expected error for unknown day_boundary")
+	}
+}
+
+// TestTrainingRIRBands verifies custom RIR bands load in order and that
+// misordered or unterminated band lists are rejected at startup.
+func TestTrainingRIRBands(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Training.RIRBands) != 0 {
+		t.Errorf("rir_bands = %v, want empty (built-in defaults)", cfg.Training.RIRBands)
+	}
+
+	custom := `training:
+  rir_bands:
+    - {name: hard, range: "0-1", max_rir: 1, failure: true}
+    - {name: working, range: "1.5-3", max_rir: 3}
+    - {name: easy, range: ">3"}
+`
+	cfg, err = Load(writeTemp(t, validYAML+custom))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	bands := cfg.Training.RIRBands
+	if len(bands) != 3 || bands[0].Name != "hard" || !bands[0].Failure || *bands[1].MaxRIR != 3 || bands[2].MaxRIR != nil {
+		t.Errorf("rir_bands = %+v", bands)
+	}
+
+	for name, yaml := range map[string]string{
+		"descending":   "training:\n  rir_bands:\n    - {name: a, max_rir: 2}\n    - {name: b, max_rir: 1}\n    - {name: c}\n",
+		"no catch-all": "training:\n  rir_bands:\n    - {name: a, max_rir: 1}\n",
+		"open middle":  "training:\n  rir_bands:\n    - {name: a}\n    - {name: b}\n",
+		"reserved":     "training:\n  rir_bands:\n    - {name: untracked}\n",
+		"duplicate":    "training:\n  rir_bands:\n    - {name: a, max_rir: 1}\n    - {name: a}\n",
+	} {
+		if _, err := Load(writeTemp(t, validYAML+yaml)); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+}
+
+// TestTrainingStrengthCalories verifies the calorie estimate defaults and
+// that an explicit 0 MET (disabled) is accepted while nonsense is rejected.
+func TestTrainingStrengthCalories(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Training.StrengthMET != 5 || cfg.Training.DefaultBodyweightKg != 75 {
+		t.Errorf("got met=%v bodyweight=%v, want 5 / 75", cfg.Training.StrengthMET, cfg.Training.DefaultBodyweightKg)
+	}
+
+	cfg, err = Load(writeTemp(t, validYAML+"training:\n  strength_met: 0\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Training.StrengthMET != 0 {
+		t.Errorf("strength_met = %v, want 0", cfg.Training.StrengthMET)
+	}
+
+	for _, yaml := range []string{
+		"training:\n  strength_met: -1\n",
+		"training:\n  default_bodyweight_kg: 0\n",
+	} {
+		if _, err := Load(writeTemp(t, validYAML+yaml)); err == nil {
+			t.Errorf("expected error for %q", yaml)
+		}
+	}
+}
+
+// TestHAEMaxChunks verifies the HAE import chunk cap defaults to 260 and
+// must be positive.
+func TestHAEMaxChunks(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.HAE.MaxChunks != 260 {
+		t.Errorf("hae.max_chunks = %d, want 260", cfg.HAE.MaxChunks)
+	}
+	if _, err := Load(writeTemp(t, validYAML+"hae:\n  max_chunks: 0\n")); err == nil {
+		t.Error("expected error for max_chunks 0")
+	}
+}
+
+// TestCollapseRepeatsConfig verifies per-metric collapse windows are parsed
+// and that unparseable or non-positive windows and cumulative metrics, built
+// in or seeded, are rejected.
+func TestCollapseRepeatsConfig(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML+"ingest:\n  collapse_repeats:\n    heart_rate: \"5s\"\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.Ingest.CollapseRepeats["heart_rate"]; got != 5*time.Second {
+		t.Errorf("collapse_repeats.heart_rate = %v, want 5s", got)
+	}
+	for _, bad := range []string{"soon", "0s", "-1s"} {
+		if _, err := Load(writeTemp(t, validYAML+"ingest:\n  collapse_repeats:\n    heart_rate: \""+bad+"\"\n")); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+	if _, err := Load(writeTemp(t, validYAML+"ingest:\n  collapse_repeats:\n    step_count: \"5s\"\n")); err == nil {
+		t.Error("step_count: expected error for a cumulative metric")
+	}
+	seeded := "ingest:\n  allowlist_seed:\n    - metric: \"time_in_daylight\"\n      category: \"activity\"\n      cumulative: true\n  collapse_repeats:\n    time_in_daylight: \"5s\"\n"
+	if _, err := Load(writeTemp(t, validYAML+seeded)); err == nil {
+		t.Error("time_in_daylight: expected error for a metric seeded as cumulative")
+	}
+}
+
+// TestHAEStallTimeout verifies the import stall watchdog defaults to ten
+// minutes, can be disabled with 0, and rejects unparseable durations.
+func TestHAEStallTimeout(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.HAE.StallTimeout != 10*time.Minute {
+		t.Errorf("hae.stall_timeout = %v, want 10m", cfg.HAE.StallTimeout)
+	}
+	cfg, err = Load(writeTemp(t, validYAML+"hae:\n  stall_timeout: \"0\"\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.HAE.StallTimeout != 0 {
+		t.Errorf("hae.stall_timeout = %v, want 0", cfg.HAE.StallTimeout)
+	}
+	if _, err := Load(writeTemp(t, validYAML+"hae:\n  stall_timeout: \"soon\"\n")); err == nil {
+		t.Error("expected error for unparseable stall_timeout")
+	}
+}
+
+// TestIllnessConfig verifies the illness threshold defaults and that an
+// inconsistent baseline window is rejected.
+func TestIllnessConfig(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML+"illness:\n  hrv_drop_pct: 25\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Illness.HRVDropPct != 25 || cfg.Illness.BaselineDays != 28 || cfg.Illness.MinSignals != 2 {
+		t.Errorf("illness = %+v", cfg.Illness)
+	}
+	if _, err := Load(writeTemp(t, validYAML+"illness:\n  min_baseline_days: 40\n")); err == nil {
+		t.Error("expected error for min_baseline_days above baseline_days")
+	}
+	if _, err := Load(writeTemp(t, validYAML+"illness:\n  min_signals: 5\n")); err == nil {
+		t.Error("expected error for min_signals 5")
+	}
+}
+
+// TestCorrelationConfig verifies the minimum correlation points default to
+// 10, can be overridden, and may not drop below 3.
+func TestCorrelationConfig(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Correlation.MinPoints != 10 {
+		t.Errorf("correlation.min_points = %d, want 10", cfg.Correlation.MinPoints)
+	}
+	cfg, err = Load(writeTemp(t, validYAML+"correlation:\n  min_points: 20\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Correlation.MinPoints != 20 {
+		t.Errorf("correlation.min_points = %d, want 20", cfg.Correlation.MinPoints)
+	}
+	if _, err := Load(writeTemp(t, validYAML+"correlation:\n  min_points: 2\n")); err == nil {
+		t.Error("expected error for min_points 2")
+	}
+}
+
+// TestDerivedMetricsConfig verifies derived metric definitions are loaded and
+// that an empty expression is rejected.
+func TestDerivedMetricsConfig(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML+"derived_metrics:\n  total_energy: \"active_energy + basal_energy_burned\"\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.DerivedMetrics["total_energy"]; got != "active_energy + basal_energy_burned" {
+		t.Errorf("derived_metrics.total_energy = %q", got)
+	}
+	if _, err := Load(writeTemp(t, validYAML+"derived_metrics:\n  total_energy: \" \"\n")); err == nil {
+		t.Error("expected error for empty expression")
+	}
+}
+
+// TestVolumeLandmarks verifies landmark overrides are loaded and that an MRV
+// below the MEV is rejected.
+func TestVolumeLandmarks(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML+"training:\n  volume_landmarks:\n    chest: {mev: 10, mrv: 20}\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if l := cfg.Training.VolumeLandmarks["chest"]; l.MEV != 10 || l.MRV != 20 {
+		t.Errorf("chest landmarks = %+v, want 10/20", l)
+	}
+	if _, err := Load(writeTemp(t, validYAML+"training:\n  volume_landmarks:\n    chest: {mev: 12, mrv: 8}\n")); err == nil {
+		t.Error("expected error for mrv below mev")
+	}
+}
+
+// TestLogConfig verifies the configured level filters records, the format
+// selects JSON output, FREEREPS_LOG_LEVEL overrides the file, and unknown
+// values are rejected.
+func TestLogConfig(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML+"log:\n  level: warn\n  format: json\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	log := cfg.Log.NewLogger(&buf)
+	log.Info("dropped")
+	log.Warn("kept")
+	out := buf.String()
+	if strings.Contains(out, "dropped") || !strings.Contains(out, `"msg":"kept"`) {
+		t.Errorf("log output = %q, want only the warning as JSON", out)
+	}
+
+	t.Setenv("FREEREPS_LOG_LEVEL", "debug")
+	cfg, err = Load(writeTemp(t, validYAML+"log:\n  level: warn\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	buf.Reset()
+	cfg.Log.NewLogger(&buf).Debug("verbose")
+	if !strings.Contains(buf.String(), "level=DEBUG") {
+		t.Errorf("env override: output = %q, want a text debug record", buf.String())
+	}
+
+	t.Setenv("FREEREPS_LOG_LEVEL", "")
+	for _, bad := range []string{"log:\n  level: trace\n", "log:\n  format: xml\n"} {
+		if _, err := Load(writeTemp(t, validYAML+bad)); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+}
+
+// TestDatabasePoolConfig verifies pool sizing defaults, YAML overrides, the
+// FREEREPS_DB_MAX_CONNS override, and that min_conns above max_conns is
+// rejected.
+func TestDatabasePoolConfig(t *testing.T) {
+	withPool := func(lines string) string {
+		return strings.Replace(validYAML, "  sslmode: \"disable\"\n", "  sslmode: \"disable\"\n"+lines, 1)
+	}
+
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if db := cfg.Database; db.MaxConns != 16 || db.MinConns != 2 || db.MaxConnLifetime != time.Hour || db.HealthCheckPeriod != time.Minute {
+		t.Errorf("defaults = %+v", db)
+	}
+
+	cfg, err = Load(writeTemp(t, withPool("  max_conns: 32\n  min_conns: 4\n  max_conn_lifetime: \"30m\"\n  health_check_period: \"10s\"\n")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if db := cfg.Database; db.MaxConns != 32 || db.MinConns != 4 || db.MaxConnLifetime != 30*time.Minute || db.HealthCheckPeriod != 10*time.Second {
+		t.Errorf("overrides = %+v", db)
+	}
+
+	t.Setenv("FREEREPS_DB_MAX_CONNS", "8")
+	cfg, err = Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Database.MaxConns != 8 {
+		t.Errorf("env max_conns = %d, want 8", cfg.Database.MaxConns)
+	}
+	t.Setenv("FREEREPS_DB_MAX_CONNS", "")
+
+	if _, err := Load(writeTemp(t, withPool("  max_conns: 2\n  min_conns: 4\n"))); err == nil {
+		t.Error("expected error for min_conns above max_conns")
+	}
+	if _, err := Load(writeTemp(t, withPool("  max_conn_lifetime: \"soon\"\n"))); err == nil {
+		t.Error("expected error for unparseable max_conn_lifetime")
+	}
+}
+
+// TestWorkoutMergeGap verifies segment merging is off by default and that
+// negative or unparseable gaps are rejected.
+func TestWorkoutMergeGap(t *testing.T) {
+	cfg, err := Load(writeTemp(t, validYAML))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Ingest.WorkoutMergeGap != 0 {
+		t.Errorf("default workout_merge_gap = %v, want 0", cfg.Ingest.WorkoutMergeGap)
+	}
+	cfg, err = Load(writeTemp(t, validYAML+"ingest:\n  workout_merge_gap: \"2m\"\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Ingest.WorkoutMergeGap != 2*time.Minute {
+		t.Errorf("workout_merge_gap = %v, want 2m", cfg.Ingest.WorkoutMergeGap)
+	}
+	for _, bad := range []string{"later", "-1m"} {
+		if _, err := Load(writeTemp(t, validYAML+"ingest:\n  workout_merge_gap: \""+bad+"\"\n")); err == nil {
+			t.Errorf("%s: expected error", bad)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package alpha
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// SessionsFromRows regroups workout_sets rows into sessions, the inverse of
+// sessionRows. Rows must be ordered by session, then exercise number, with
+// warmups before working sets (as QueryWorkoutSets returns them).
+func SessionsFromRows(rows []models.WorkoutSetRow) []models.AlphaSession {
+	var sessions []models.AlphaSession
+	for _, r := range rows {
+		n := len(sessions)
+		if n == 0 || sessions[n-1].Name != r.SessionName || !sessions[n-1].Date.Equal(r.SessionDate) ||
+			sessions[n-1].Duration != r.SessionDuration {
+			sessions = append(sessions, models.AlphaSession{Name: r.SessionName, Date: r.SessionDate, Duration: r.SessionDuration,
+				Notes: r.SessionNotes})
+			n++
+		}
+		s := &sessions[n-1]
+
+		e := len(s.Exercises)
+		if e == 0 || s.Exercises[e-1].Number != r.ExerciseNumber {
+			s.Exercises = append(s.Exercises, models.AlphaExercise{
+				Number:     r.ExerciseNumber,
+				Name:       r.ExerciseName,
+				Equipment:  r.Equipment,
+				TargetReps: r.TargetReps,
+			})
+			e++
+		}
+		s.Exercises[e-1].Sets = append(s.Exercises[e-1].Sets, models.AlphaSet{
+			Number:           r.SetNumber,
+			WeightKg:         r.WeightKg,
+			IsBodyweightPlus: r.IsBodyweightPlus,
+			Reps:             r.Reps,
+			RIR:              r.RIR,
+			IsWarmup:         r.IsWarmup,
+			RestSeconds:      r.RestSeconds,
+		})
+	}
+	return sessions
+}
+
+// Write renders sessions in the Alpha Progression CSV export format that
+// Parse reads: a session header, then per exercise a header (with warmups in
+// a second column), the column header, and one row per working set. Session
+// notes follow the session header, and a REST column is added to exercises
+// with recorded rest times. Sessions are separated by blank lines. Exercise modifiers such as dropsets aren't
+// stored, so they aren't written back.
+func Write(w io.Writer, sessions []models.AlphaSession) error {
+	bw := bufio.NewWriter(w)
+	for i, s := range sessions {
+		if i > 0 {
+			bw.WriteString("\n")
+		}
+		fmt.Fprintf(bw, "%q;%q;%q\n", s.Name, s.Date.Local().Format("2006-01-02 15:04")+" h", s.Duration)
+		if s.Notes != "" {
+			fmt.Fprintf(bw, "\"Notes: %s\"\n", strings.ReplaceAll(s.Notes, `"`, `""`))
+		}
+		for _, ex := range s.Exercises {
+			var warmups, working []models.AlphaSet
+			hasRest := false
+			for _, set := range ex.Sets {
+				if set.IsWarmup {
+					warmups = append(warmups, set)
+				} else {
+					working = append(working, set)
+					hasRest = hasRest || set.RestSeconds != nil
+				}
+			}
+
+			header := fmt.Sprintf("%d. %s", ex.Number, ex.Name)
+			if ex.Equipment != "" {
+				header += " · " + ex.Equipment
+			}
+			fmt.Fprintf(bw, "%q", fmt.Sprintf("%s · %d reps", header, ex.TargetReps))
+			if len(warmups) > 0 {
+				parts := make([]string, len(warmups))
+				for j, wu := range warmups {
+					parts[j] = fmt.Sprintf("WU%d · %s kg · %d reps", wu.Number, formatWeight(wu), wu.Reps)
+				}
+				fmt.Fprintf(bw, ";%q", strings.Join(parts, "<br>"))
+			}
+			if !hasRest {
+				bw.WriteString("\n#;KG;REPS;RIR\n")
+				for _, set := range working {
+					fmt.Fprintf(bw, "%d;%s;%d;%s\n", set.Number, formatWeight(set), set.Reps, formatEuropeanFloat(set.RIR))
+				}
+				continue
+			}
+			bw.WriteString("\n#;KG;REPS;RIR;REST\n")
+			for _, set := range working {
+				fmt.Fprintf(bw, "%d;%s;%d;%s;%s\n", set.Number, formatWeight(set), set.Reps, formatEuropeanFloat(set.RIR), formatRest(set.RestSeconds))
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// formatWeight is the inverse of parseWeight: "+35" for bodyweight-plus,
+// European decimals otherwise.
+func formatWeight(set models.AlphaSet) string {
+	if set.IsBodyweightPlus {
+		return "+" + formatEuropeanFloat(set.WeightKg)
+	}
+	return formatEuropeanFloat(set.WeightKg)
+}
+
+// formatRest is the inverse of parseRest: "2:30" for 150 seconds, empty for
+// nil.
+func formatRest(secs *int) string {
+	if secs == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d:%02d", *secs/60, *secs%60)
+}
+
+// formatEuropeanFloat is the inverse of parseEuropeanFloat: 102.5 -> "102,5",
+// 115 -> "115".
+func formatEuropeanFloat(f float64) string {
+	return strings.ReplaceAll(strconv.FormatFloat(f, 'f', -1, 64), ".", ",")
+}
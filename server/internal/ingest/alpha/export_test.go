@@ -0,0 +1,99 @@
+package alpha
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// TestExportRoundTrip verifies the sample export survives parse → stored
+// rows → export → parse unchanged, so exported CSVs can be re-imported into
+// Alpha Progression or FreeReps.
+func TestExportRoundTrip(t *testing.T) {
+	sessions, err := Parse(strings.NewReader(sampleCSV))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	var out strings.Builder
+	if err := Write(&out, SessionsFromRows(sessionRows(sessions, 1))); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	again, err := Parse(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatalf("re-parse error: %v\n%s", err, out.String())
+	}
+	if !reflect.DeepEqual(again, sessions) {
+		t.Errorf("round trip changed sessions:\n got %+v\nwant %+v\nexport:\n%s", again, sessions, out.String())
+	}
+	if again[0].Key() != sessions[0].Key() {
+		t.Error("session key changed; a re-import would duplicate sets")
+	}
+}
+
+// TestExportRoundTripNotesAndRest verifies session notes and rest times
+// survive export and re-import.
+func TestExportRoundTripNotesAndRest(t *testing.T) {
+	sessions, err := Parse(strings.NewReader(notesAndRestCSV))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+
+	var out strings.Builder
+	if err := Write(&out, SessionsFromRows(sessionRows(sessions, 1))); err != nil {
+		t.Fatalf("write error: %v", err)
+	}
+	again, err := Parse(strings.NewReader(out.String()))
+	if err != nil {
+		t.Fatalf("re-parse error: %v\n%s", err, out.String())
+	}
+	if !reflect.DeepEqual(again, sessions) {
+		t.Errorf("round trip changed sessions:\n got %+v\nwant %+v\nexport:\n%s", again, sessions, out.String())
+	}
+}
+
+// TestWriteFormat verifies the exact line shapes: session header, exercise
+// header with warmups, European decimals, and bodyweight-plus weights.
+func TestWriteFormat(t *testing.T) {
+	sessions, err := Parse(strings.NewReader(`"Push";"2026-02-17 5:04 h";"1:12 hr"
+"1. Dips · Bodyweight · 8 reps";"WU1 · +0 kg · 5 reps"
+#;KG;REPS;RIR
+1;+12,5;8;0,5
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	if err := Write(&out, sessions); err != nil {
+		t.Fatal(err)
+	}
+	want := `"Push";"2026-02-17 05:04 h";"1:12 hr"
+"1. Dips · Bodyweight · 8 reps";"WU1 · +0 kg · 5 reps"
+#;KG;REPS;RIR
+1;+12,5;8;0,5
+`
+	if out.String() != want {
+		t.Errorf("export =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+// TestSessionsFromRowsGroupsBySession verifies rows split into sessions and
+// exercises at each change of session or exercise number.
+func TestSessionsFromRowsGroupsBySession(t *testing.T) {
+	sessions, err := Parse(strings.NewReader(sampleCSV))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := SessionsFromRows(sessionRows(sessions, 1))
+	if len(got) != 2 || len(got[0].Exercises) != 6 || len(got[1].Exercises) != 1 {
+		t.Fatalf("got %d sessions", len(got))
+	}
+	if sets := got[0].Exercises[0].Sets; len(sets) != 5 || !sets[0].IsWarmup || sets[2].IsWarmup {
+		t.Errorf("hack squat sets = %+v", sets)
+	}
+	if got := SessionsFromRows([]models.WorkoutSetRow(nil)); got != nil {
+		t.Errorf("no rows = %+v", got)
+	}
+}
@@ -21,14 +21,18 @@ var (
 	// Group 1: exercise number, Group 2: name+equipment, Group 3: target reps, Group 4: modifiers, Group 5: warmups
 	exerciseHeaderRe = regexp.MustCompile(`^"(\d+)\.\s+(.+?)\s+·\s+(\d+)\s+reps([^"]*)"(?:;"(.+)")?$`)
 
-	// setDataRe matches: 1;115;8;1
-	setDataRe = regexp.MustCompile(`^(\d+);(.+);(\d+);(.+)$`)
+	// setDataRe matches: 1;115;8;1 with an optional rest column: 1;115;8;1;2:30
+	setDataRe = regexp.MustCompile(`^(\d+);([^;]+);(\d+);([^;]+)(?:;([^;]*))?$`)
 
 	// warmupRe matches: WU1 · 37,5 kg · 9 reps
 	warmupRe = regexp.MustCompile(`WU(\d+)\s+·\s+(.+?)\s+kg\s+·\s+(\d+)\s+reps`)
 
-	// columnHeaderRe matches: #;KG;REPS;RIR
-	columnHeaderRe = regexp.MustCompile(`^#;KG;REPS;RIR$`)
+	// columnHeaderRe matches: #;KG;REPS;RIR[;REST]
+	columnHeaderRe = regexp.MustCompile(`^#;KG;REPS;RIR(;REST)?$`)
+
+	// notesRe matches the start of a session note: "Notes: text..., possibly
+	// continuing on following lines until the closing quote.
+	notesRe = regexp.MustCompile(`(?i)^"notes?:\s*(.*)$`)
 )
 
 // Parse reads an Alpha Progression CSV export and returns parsed sessions.
@@ -38,8 +42,20 @@ func Parse(r io.Reader) ([]models.AlphaSession, error) {
 	var sessions []models.AlphaSession
 	var current *models.AlphaSession
 	var currentExercise *models.AlphaExercise
+	var notes []string
+	inNotes := false // inside a quoted note spanning several lines
 
 	for scanner.Scan() {
+		if inNotes {
+			text, closed := strings.CutSuffix(strings.TrimSpace(scanner.Text()), `"`)
+			notes = append(notes, text)
+			if closed {
+				current.Notes = joinNotes(current.Notes, notes)
+				notes, inNotes = nil, false
+			}
+			continue
+		}
+
 		// Normalize tab-delimited exports to semicolons so all regexes work.
 		line := strings.ReplaceAll(strings.TrimSpace(scanner.Text()), "\t", ";")
 
@@ -82,6 +98,21 @@ func Parse(r io.Reader) ([]models.AlphaSession, error) {
 			continue
 		}
 
+		// Try session note
+		if m := notesRe.FindStringSubmatch(line); m != nil && current != nil {
+			// Notes are free text, so undo the tab normalization above.
+			text := notesRe.FindStringSubmatch(strings.TrimSpace(scanner.Text()))[1]
+			text, closed := strings.CutSuffix(text, `"`)
+			notes = []string{text}
+			if closed {
+				current.Notes = joinNotes(current.Notes, notes)
+				notes = nil
+			} else {
+				inNotes = true
+			}
+			continue
+		}
+
 		// Try exercise header
 		if m := exerciseHeaderRe.FindStringSubmatch(line); m != nil {
 			if current == nil {
@@ -127,6 +158,7 @@ func Parse(r io.Reader) ([]models.AlphaSession, error) {
 				Reps:             reps,
 				RIR:              rir,
 				IsWarmup:         false,
+				RestSeconds:      parseRest(m[5]),
 			})
 			continue
 		}
@@ -138,6 +170,9 @@ func Parse(r io.Reader) ([]models.AlphaSession, error) {
 	}
 
 	// Flush remaining
+	if inNotes {
+		current.Notes = joinNotes(current.Notes, notes)
+	}
 	if current != nil {
 		if currentExercise != nil {
 			current.Exercises = append(current.Exercises, *currentExercise)
@@ -148,6 +183,40 @@ func Parse(r io.Reader) ([]models.AlphaSession, error) {
 	return sessions, scanner.Err()
 }
 
+// joinNotes appends note lines to a session's existing notes, one per line.
+func joinNotes(existing string, lines []string) string {
+	text := strings.TrimSpace(strings.ReplaceAll(strings.Join(lines, "\n"), `""`, `"`))
+	if existing == "" || text == "" {
+		return existing + text
+	}
+	return existing + "\n" + text
+}
+
+// parseRest parses a rest column value: "2:30" (minutes:seconds), "90s", or
+// "90" (seconds). Returns nil for an empty or unparseable value.
+func parseRest(s string) *int {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "s")
+	if s == "" {
+		return nil
+	}
+	var secs int
+	if m, sec, ok := strings.Cut(s, ":"); ok {
+		mins, err1 := strconv.Atoi(m)
+		ss, err2 := strconv.Atoi(sec)
+		if err1 != nil || err2 != nil || mins < 0 || ss < 0 || ss >= 60 {
+			return nil
+		}
+		secs = mins*60 + ss
+	} else {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return nil
+		}
+		secs = n
+	}
+	return &secs
+}
+
 // parseSessionDate parses "2026-02-19 4:54" into a time.Time.
 func parseSessionDate(s string) (time.Time, error) {
 	// Try both formats: "2026-02-19 4:54" and "2026-02-19 16:54"
@@ -285,3 +285,82 @@ func TestSplitExerciseNameEquipment(t *testing.T) {
 		t.Errorf("equip = %q", equip)
 	}
 }
+
+const notesAndRestCSV = `"Pull · Day 3";"2026-02-21 6:10 h";"0:58 hr"
+"Notes: Grip gave out on the last row set.
+Try straps next week, ""heavy"" day."
+"1. Barbell Rows · Barbell · 8 reps"
+#;KG;REPS;RIR;REST
+1;80;8;2;2:30
+2;80;8;1;90s
+3;80;7;0;
+"2. Face Pulls · Cable · 15 reps"
+#;KG;REPS;RIR
+1;20;15;2
+
+"Legs";"2026-02-23 6:00 h";"1:00 hr"
+"Notes: Short one."
+"1. Leg Press · Machine · 10 reps"
+#;KG;REPS;RIR;REST
+1;180;10;1;120
+`
+
+// TestParseNotesAndRest verifies a multi-line quoted session note is kept
+// with its line break and unescaped quotes, and that a REST column yields
+// per-set rest seconds in m:ss, "Ns" and plain-seconds form, left nil where
+// the column is blank or absent.
+func TestParseNotesAndRest(t *testing.T) {
+	i := func(n int) *int { return &n }
+	sessions, err := Parse(strings.NewReader(notesAndRestCSV))
+	if err != nil {
+		t.Fatalf("parse error: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("sessions = %d, want 2", len(sessions))
+	}
+
+	s1 := sessions[0]
+	wantNotes := "Grip gave out on the last row set.\nTry straps next week, \"heavy\" day."
+	if s1.Notes != wantNotes {
+		t.Errorf("notes = %q, want %q", s1.Notes, wantNotes)
+	}
+	if len(s1.Exercises) != 2 {
+		t.Fatalf("exercises = %d, want 2", len(s1.Exercises))
+	}
+	rows := s1.Exercises[0].Sets
+	if len(rows) != 3 || rows[0].RIR != 2 || rows[0].Reps != 8 {
+		t.Fatalf("row sets = %+v", rows)
+	}
+	for n, want := range []*int{i(150), i(90), nil} {
+		got := rows[n].RestSeconds
+		if (got == nil) != (want == nil) || (got != nil && *got != *want) {
+			t.Errorf("set %d rest = %v, want %v", n+1, got, want)
+		}
+	}
+	if rest := s1.Exercises[1].Sets[0].RestSeconds; rest != nil {
+		t.Errorf("face pull rest = %d, want nil without a REST column", *rest)
+	}
+
+	s2 := sessions[1]
+	if s2.Notes != "Short one." || len(s2.Exercises) != 1 {
+		t.Errorf("second session = %+v", s2)
+	}
+	if rest := s2.Exercises[0].Sets[0].RestSeconds; rest == nil || *rest != 120 {
+		t.Errorf("leg press rest = %v, want 120", rest)
+	}
+}
+
+// TestParseRest verifies accepted rest formats and that malformed values are
+// dropped rather than failing the import.
+func TestParseRest(t *testing.T) {
+	i := func(n int) *int { return &n }
+	for in, want := range map[string]*int{
+		"2:30": i(150), "0:45": i(45), "90s": i(90), "75": i(75),
+		"": nil, "1:75": nil, "abc": nil, "-5": nil,
+	} {
+		got := parseRest(in)
+		if (got == nil) != (want == nil) || (got != nil && *got != *want) {
+			t.Errorf("parseRest(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
@@ -5,21 +5,33 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"time"
 
 	"github.com/claude/freereps/internal/ingest"
 	"github.com/claude/freereps/internal/models"
 	"github.com/claude/freereps/internal/storage"
 )
 
+// DefaultLinkWindow is how far apart an Alpha session and a workout may start
+// and still be linked, matching the merged workout list.
+const DefaultLinkWindow = 2 * time.Hour
+
 // Provider processes Alpha Progression CSV exports.
 type Provider struct {
-	db  *storage.DB
-	log *slog.Logger
+	db         *storage.DB
+	log        *slog.Logger
+	linkWindow time.Duration
 }
 
 // NewProvider creates a new Alpha Progression ingest provider.
 func NewProvider(db *storage.DB, log *slog.Logger) *Provider {
-	return &Provider{db: db, log: log}
+	return &Provider{db: db, log: log, linkWindow: DefaultLinkWindow}
+}
+
+// SetLinkWindow sets the window used to link sessions to workouts. Zero
+// disables linking.
+func (p *Provider) SetLinkWindow(d time.Duration) {
+	p.linkWindow = d
 }
 
 // Ingest parses a CSV export and stores the workout set data.
@@ -30,13 +42,40 @@ func (p *Provider) Ingest(ctx context.Context, r io.Reader, userID int) (*ingest
 	}
 
 	result := &ingest.Result{}
-	var allRows []models.WorkoutSetRow
+	allRows := sessionRows(sessions, userID)
+
+	if len(allRows) > 0 {
+		inserted, err := p.db.InsertWorkoutSets(ctx, allRows)
+		if err != nil {
+			return nil, fmt.Errorf("inserting sets: %w", err)
+		}
+		result.SetsReceived = len(allRows)
+		result.SetsInserted = inserted
+		result.SetsSkipped = int64(len(allRows)) - inserted
+
+		// A missing link only hides the combined view, so don't fail the upload.
+		linked, err := p.db.LinkWorkoutSets(ctx, userID, p.linkWindow)
+		if err != nil {
+			p.log.Warn("linking alpha sessions to workouts failed", "error", err)
+		}
+		result.SetsLinked = linked
+	}
 
+	return result, nil
+}
+
+// sessionRows flattens parsed sessions into workout_sets rows, tagging each
+// with its session key so a re-uploaded export conflicts instead of
+// duplicating.
+func sessionRows(sessions []models.AlphaSession, userID int) []models.WorkoutSetRow {
+	var rows []models.WorkoutSetRow
 	for _, s := range sessions {
+		key := s.Key()
 		for _, ex := range s.Exercises {
 			for _, set := range ex.Sets {
-				allRows = append(allRows, models.WorkoutSetRow{
+				rows = append(rows, models.WorkoutSetRow{
 					UserID:           userID,
+					SessionKey:       key,
 					SessionName:      s.Name,
 					SessionDate:      s.Date,
 					SessionDuration:  s.Duration,
@@ -50,19 +89,11 @@ func (p *Provider) Ingest(ctx context.Context, r io.Reader, userID int) (*ingest
 					IsBodyweightPlus: set.IsBodyweightPlus,
 					Reps:             set.Reps,
 					RIR:              set.RIR,
+					SessionNotes:     s.Notes,
+					RestSeconds:      set.RestSeconds,
 				})
 			}
 		}
 	}
-
-	if len(allRows) > 0 {
-		inserted, err := p.db.InsertWorkoutSets(ctx, allRows)
-		if err != nil {
-			return nil, fmt.Errorf("inserting sets: %w", err)
-		}
-		result.SetsReceived = len(allRows)
-		result.SetsInserted = inserted
-	}
-
-	return result, nil
+	return rows
 }
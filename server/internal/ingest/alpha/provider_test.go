@@ -0,0 +1,47 @@
+package alpha
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestSessionRowsReingest verifies ingesting the same export twice produces
+// identical unique keys, so every set of the second upload conflicts with
+// the first instead of being stored again.
+func TestSessionRowsReingest(t *testing.T) {
+	type setKey struct {
+		session  string
+		exercise int
+		set      int
+		warmup   bool
+	}
+	keysOf := func() map[setKey]bool {
+		sessions, err := Parse(strings.NewReader(sampleCSV))
+		if err != nil {
+			t.Fatalf("parse error: %v", err)
+		}
+		rows := sessionRows(sessions, 1)
+		keys := make(map[setKey]bool, len(rows))
+		for _, r := range rows {
+			k := setKey{r.SessionKey, r.ExerciseNumber, r.SetNumber, r.IsWarmup}
+			if keys[k] {
+				t.Fatalf("duplicate key within one export: %+v", k)
+			}
+			keys[k] = true
+		}
+		return keys
+	}
+
+	first, second := keysOf(), keysOf()
+	if len(first) == 0 {
+		t.Fatal("no rows built from sample CSV")
+	}
+	if len(first) != len(second) {
+		t.Fatalf("rows = %d then %d", len(first), len(second))
+	}
+	for k := range second {
+		if !first[k] {
+			t.Errorf("re-ingested set %+v would not conflict with the first upload", k)
+		}
+	}
+}
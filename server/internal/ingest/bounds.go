@@ -0,0 +1,38 @@
+package ingest
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeOutOfRange is returned for data points whose timestamp falls outside
+// the accepted TimeBounds.
+var ErrTimeOutOfRange = errors.New("timestamp out of accepted range")
+
+// TimeBounds limits the timestamps accepted on ingest. Buggy sources
+// occasionally emit far-future or pre-HealthKit dates, which would otherwise
+// corrupt min/max time queries.
+type TimeBounds struct {
+	Earliest  time.Time     // points before this are rejected
+	MaxFuture time.Duration // points later than now+MaxFuture are rejected
+}
+
+// DefaultTimeBounds rejects anything before 2014 (HealthKit's release) or
+// more than a day in the future.
+var DefaultTimeBounds = TimeBounds{
+	Earliest:  time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC),
+	MaxFuture: 24 * time.Hour,
+}
+
+// Check returns ErrTimeOutOfRange if t falls outside the bounds.
+// A zero Earliest or MaxFuture disables that side of the check.
+func (b TimeBounds) Check(t time.Time) error {
+	if !b.Earliest.IsZero() && t.Before(b.Earliest) {
+		return fmt.Errorf("%w: %s is before %s", ErrTimeOutOfRange, t.Format(time.RFC3339), b.Earliest.Format("2006-01-02"))
+	}
+	if b.MaxFuture > 0 && t.After(time.Now().Add(b.MaxFuture)) {
+		return fmt.Errorf("%w: %s is in the future", ErrTimeOutOfRange, t.Format(time.RFC3339))
+	}
+	return nil
+}
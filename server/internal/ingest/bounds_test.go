@@ -0,0 +1,34 @@
+package ingest
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestTimeBoundsCheck verifies the accepted window: pre-2014 and far-future
+// timestamps are rejected, recent ones pass, and zero bounds disable a side.
+func TestTimeBoundsCheck(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		bounds TimeBounds
+		t      time.Time
+		reject bool
+	}{
+		{"recent", DefaultTimeBounds, now.Add(-time.Hour), false},
+		{"slightly ahead (timezone skew)", DefaultTimeBounds, now.Add(6 * time.Hour), false},
+		{"year 3000", DefaultTimeBounds, time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC), true},
+		{"before 2014", DefaultTimeBounds, time.Date(2013, 12, 31, 0, 0, 0, 0, time.UTC), true},
+		{"zero time", DefaultTimeBounds, time.Time{}, true},
+		{"no bounds", TimeBounds{}, time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.bounds.Check(tt.t)
+			if tt.reject != errors.Is(err, ErrTimeOutOfRange) {
+				t.Errorf("Check(%v) = %v, reject=%v", tt.t, err, tt.reject)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ type Format string
 
 const (
 	FormatAlpha   Format = "alpha_progression_csv"
+	FormatFIT     Format = "garmin_fit"
 	FormatUnknown Format = "unknown"
 )
 
@@ -19,6 +20,7 @@ type detector func(head []byte) Format
 
 // detectors is an ordered list of format detectors. The first match wins.
 var detectors = []detector{
+	detectFIT,
 	detectAlpha,
 }
 
@@ -51,6 +53,15 @@ var (
 	alphaExerciseRe = regexp.MustCompile(`"\d+\.\s+.+\s+·\s+\d+\s+reps`)
 )
 
+// detectFIT matches the FIT file header: a 12- or 14-byte header whose
+// bytes 8-11 hold the ".FIT" signature.
+func detectFIT(head []byte) Format {
+	if len(head) >= 12 && (head[0] == 12 || head[0] == 14) && string(head[8:12]) == ".FIT" {
+		return FormatFIT
+	}
+	return FormatUnknown
+}
+
 func detectAlpha(head []byte) Format {
 	if alphaSessionRe.Match(head) {
 		return FormatAlpha
@@ -61,3 +61,15 @@ func TestDetectEmpty(t *testing.T) {
 		t.Errorf("DetectFormat(empty) = %q, want %q", got, FormatUnknown)
 	}
 }
+
+// TestDetectFIT verifies detection of a FIT file by its header signature.
+func TestDetectFIT(t *testing.T) {
+	header := []byte{14, 0x20, 0x8b, 0x08, 0x10, 0, 0, 0, '.', 'F', 'I', 'T', 0, 0}
+	if got := DetectFormat(header); got != FormatFIT {
+		t.Errorf("DetectFormat(FIT header) = %q, want %q", got, FormatFIT)
+	}
+	header[0] = 40
+	if got := DetectFormat(header); got != FormatUnknown {
+		t.Errorf("DetectFormat(bad header size) = %q, want %q", got, FormatUnknown)
+	}
+}
@@ -0,0 +1,205 @@
+// Package fit imports Garmin FIT files: activity sessions with their GPS
+// track and heart rate, and the daily resting heart rate and stress samples
+// found in monitoring files.
+package fit
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"time"
+
+	"github.com/muktihari/fit/decoder"
+	"github.com/muktihari/fit/profile/basetype"
+	"github.com/muktihari/fit/profile/mesgdef"
+	"github.com/muktihari/fit/profile/typedef"
+	"github.com/muktihari/fit/profile/untyped/mesgnum"
+)
+
+// File holds what FreeReps keeps from one FIT file (or a chain of them).
+// Optional values are nil when the device didn't record them.
+type File struct {
+	SerialNumber uint32
+	Sessions     []Session
+	Records      []Record
+	RestingHR    []Sample // daily resting heart rate, bpm
+	Stress       []Sample // stress score, 0–100
+}
+
+// Session is one activity in the file; multisport files have several.
+type Session struct {
+	Start       time.Time
+	Elapsed     time.Duration // includes pauses
+	Sport       string        // FIT sport name, e.g. "running"
+	SubSport    string        // FIT sub-sport name, e.g. "treadmill"; "" when generic
+	DistanceM   *float64
+	Calories    *float64
+	AvgHR       *float64
+	MaxHR       *float64
+	MinHR       *float64
+	AscentM     *float64
+	DescentM    *float64
+	AvgTempC    *float64
+	PoolLengthM *float64
+	Strokes     *int
+	Lengths     *int
+}
+
+// End returns when the session stopped.
+func (s Session) End() time.Time {
+	return s.Start.Add(s.Elapsed)
+}
+
+// Record is one sample of the activity stream. Latitude and Longitude are
+// both set or both nil.
+type Record struct {
+	Time      time.Time
+	HeartRate *float64
+	Latitude  *float64
+	Longitude *float64
+	AltitudeM *float64
+	SpeedMPS  *float64
+	PowerW    *float64
+}
+
+// Sample is a timestamped value from a monitoring file.
+type Sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// Parse decodes a FIT file, following chained files, and keeps the messages
+// FreeReps stores. Files with none of them parse to an empty File.
+func Parse(r io.Reader) (*File, error) {
+	f := &File{}
+	dec := decoder.New(r)
+	for dec.Next() {
+		data, err := dec.Decode()
+		if err != nil {
+			return nil, fmt.Errorf("decoding FIT: %w", err)
+		}
+		for i := range data.Messages {
+			mesg := &data.Messages[i]
+			switch mesg.Num {
+			case mesgnum.FileId:
+				if id := mesgdef.NewFileId(mesg); id.SerialNumber != basetype.Uint32zInvalid {
+					f.SerialNumber = id.SerialNumber
+				}
+			case mesgnum.Session:
+				if s, ok := session(mesgdef.NewSession(mesg)); ok {
+					f.Sessions = append(f.Sessions, s)
+				}
+			case mesgnum.Record:
+				if rec, ok := record(mesgdef.NewRecord(mesg)); ok {
+					f.Records = append(f.Records, rec)
+				}
+			case mesgnum.MonitoringHrData:
+				m := mesgdef.NewMonitoringHrData(mesg)
+				if !m.Timestamp.IsZero() && m.CurrentDayRestingHeartRate != basetype.Uint8Invalid && m.CurrentDayRestingHeartRate > 0 {
+					f.RestingHR = append(f.RestingHR, Sample{Time: m.Timestamp, Value: float64(m.CurrentDayRestingHeartRate)})
+				}
+			case mesgnum.StressLevel:
+				// Negative values mark off-wrist or too-active periods.
+				m := mesgdef.NewStressLevel(mesg)
+				if !m.StressLevelTime.IsZero() && m.StressLevelValue >= 0 && m.StressLevelValue <= 100 {
+					f.Stress = append(f.Stress, Sample{Time: m.StressLevelTime, Value: float64(m.StressLevelValue)})
+				}
+			}
+		}
+	}
+	return f, nil
+}
+
+// session converts a session message, skipping ones without a start time.
+func session(m *mesgdef.Session) (Session, bool) {
+	if m.StartTime.IsZero() {
+		return Session{}, false
+	}
+	s := Session{Start: m.StartTime}
+	if v := m.TotalElapsedTimeScaled(); valid(v) {
+		s.Elapsed = time.Duration(v * float64(time.Second))
+	} else if !m.Timestamp.IsZero() {
+		s.Elapsed = m.Timestamp.Sub(m.StartTime)
+	}
+	if m.Sport != typedef.SportInvalid {
+		s.Sport = m.Sport.String()
+	}
+	if m.SubSport != typedef.SubSportInvalid && m.SubSport != typedef.SubSportGeneric {
+		s.SubSport = m.SubSport.String()
+	}
+	s.DistanceM = scaled(m.TotalDistanceScaled())
+	s.PoolLengthM = scaled(m.PoolLengthScaled())
+	if m.TotalCalories != basetype.Uint16Invalid {
+		s.Calories = ptr(float64(m.TotalCalories))
+	}
+	s.AvgHR = u8(m.AvgHeartRate)
+	s.MaxHR = u8(m.MaxHeartRate)
+	s.MinHR = u8(m.MinHeartRate)
+	if m.TotalAscent != basetype.Uint16Invalid {
+		s.AscentM = ptr(float64(m.TotalAscent))
+	}
+	if m.TotalDescent != basetype.Uint16Invalid {
+		s.DescentM = ptr(float64(m.TotalDescent))
+	}
+	if m.AvgTemperature != basetype.Sint8Invalid {
+		s.AvgTempC = ptr(float64(m.AvgTemperature))
+	}
+	if m.NumLengths != basetype.Uint16Invalid {
+		n := int(m.NumLengths)
+		s.Lengths = &n
+	}
+	if m.Sport == typedef.SportSwimming && m.TotalCycles != basetype.Uint32Invalid {
+		n := int(m.TotalCycles)
+		s.Strokes = &n
+	}
+	return s, true
+}
+
+// record converts a record message, skipping ones without a timestamp.
+// Enhanced altitude and speed are preferred over the 16-bit fields.
+func record(m *mesgdef.Record) (Record, bool) {
+	if m.Timestamp.IsZero() {
+		return Record{}, false
+	}
+	r := Record{Time: m.Timestamp, HeartRate: u8(m.HeartRate)}
+	lat, lon := m.PositionLatDegrees(), m.PositionLongDegrees()
+	if valid(lat) && valid(lon) {
+		r.Latitude, r.Longitude = &lat, &lon
+	}
+	r.AltitudeM = scaled(m.EnhancedAltitudeScaled())
+	if r.AltitudeM == nil {
+		r.AltitudeM = scaled(m.AltitudeScaled())
+	}
+	r.SpeedMPS = scaled(m.EnhancedSpeedScaled())
+	if r.SpeedMPS == nil {
+		r.SpeedMPS = scaled(m.SpeedScaled())
+	}
+	if m.Power != basetype.Uint16Invalid {
+		r.PowerW = ptr(float64(m.Power))
+	}
+	return r, true
+}
+
+// valid reports whether a scaled value is set; the decoder returns NaN for
+// fields the device left invalid.
+func valid(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func scaled(v float64) *float64 {
+	if !valid(v) {
+		return nil
+	}
+	return &v
+}
+
+func u8(v uint8) *float64 {
+	if v == basetype.Uint8Invalid {
+		return nil
+	}
+	return ptr(float64(v))
+}
+
+func ptr(v float64) *float64 {
+	return &v
+}
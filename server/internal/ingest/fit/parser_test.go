@@ -0,0 +1,65 @@
+package fit
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+// TestParseFixture verifies that a small activity file decodes to its
+// session, records, resting heart rate, and valid stress samples.
+func TestParseFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/activity.fit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := Parse(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	start := time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC)
+	if f.SerialNumber != 3412345678 {
+		t.Errorf("serial = %d", f.SerialNumber)
+	}
+	if len(f.Sessions) != 1 {
+		t.Fatalf("sessions = %d, want 1", len(f.Sessions))
+	}
+	s := f.Sessions[0]
+	if !s.Start.Equal(start) || s.Elapsed != 3*time.Minute || s.Sport != "running" || s.SubSport != "" {
+		t.Errorf("session = %+v", s)
+	}
+	if s.DistanceM == nil || *s.DistanceM != 500 || s.Calories == nil || *s.Calories != 40 {
+		t.Errorf("distance/calories = %v/%v", s.DistanceM, s.Calories)
+	}
+	if s.AvgHR == nil || *s.AvgHR != 128 || s.MaxHR == nil || *s.MaxHR != 137 || s.MinHR != nil {
+		t.Errorf("HR avg/max/min = %v/%v/%v", s.AvgHR, s.MaxHR, s.MinHR)
+	}
+
+	if len(f.Records) != 18 {
+		t.Fatalf("records = %d, want 18", len(f.Records))
+	}
+	first, last := f.Records[0], f.Records[17]
+	if first.HeartRate == nil || *first.HeartRate != 120 || first.Latitude == nil || first.AltitudeM == nil || *first.AltitudeM != 34 {
+		t.Errorf("first record = %+v", first)
+	}
+	if last.Latitude != nil || last.Longitude != nil || last.SpeedMPS != nil {
+		t.Errorf("last record has position: %+v", last)
+	}
+
+	if len(f.RestingHR) != 1 || f.RestingHR[0].Value != 52 {
+		t.Errorf("resting HR = %+v", f.RestingHR)
+	}
+	// The -1 (off-wrist) stress sample is dropped.
+	if len(f.Stress) != 1 || f.Stress[0].Value != 35 || !f.Stress[0].Time.Equal(start.Add(-time.Hour)) {
+		t.Errorf("stress = %+v", f.Stress)
+	}
+}
+
+// TestParseInvalid verifies non-FIT input is rejected.
+func TestParseInvalid(t *testing.T) {
+	if _, err := Parse(bytes.NewReader([]byte("not a fit file at all"))); err == nil {
+		t.Error("expected error for non-FIT input")
+	}
+}
@@ -0,0 +1,308 @@
+package fit
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/claude/freereps/internal/ingest"
+	"github.com/claude/freereps/internal/models"
+	"github.com/claude/freereps/internal/storage"
+	"github.com/google/uuid"
+)
+
+// Source is stored on every workout and metric imported from a FIT file.
+const Source = "Garmin"
+
+// Metric names for monitoring data. Resting heart rate shares the name Apple
+// Health and Oura use so the series merge under source priority.
+const (
+	MetricRestingHR = "resting_heart_rate"
+	MetricStress    = "garmin_stress_level"
+)
+
+// workoutNamespace seeds the deterministic workout IDs, so re-importing a
+// file conflicts instead of duplicating.
+var workoutNamespace = uuid.MustParse("8ba7b810-9dad-11d1-80b4-00c04fd430c8")
+
+// Provider processes Garmin FIT files.
+type Provider struct {
+	db  *storage.DB
+	log *slog.Logger
+
+	// collapse maps metrics opted into ingest.CollapseRepeats to their window.
+	collapse map[string]time.Duration
+}
+
+// NewProvider creates a new FIT ingest provider.
+func NewProvider(db *storage.DB, log *slog.Logger) *Provider {
+	return &Provider{db: db, log: log}
+}
+
+// SetRepeatCollapse opts monitoring metrics into dropping repeated
+// identical samples; see ingest.CollapseRepeats.
+func (p *Provider) SetRepeatCollapse(windows map[string]time.Duration) {
+	p.collapse = windows
+}
+
+// workout is one session converted to rows for the workout tables.
+type workout struct {
+	row   models.WorkoutRow
+	hr    []models.WorkoutHRRow
+	route []models.WorkoutRouteRow
+	power []models.WorkoutPowerRow
+}
+
+// Ingest parses a FIT file and stores its workouts, their heart rate, route
+// and power, and any resting heart rate and stress samples.
+func (p *Provider) Ingest(ctx context.Context, r io.Reader, userID int) (*ingest.Result, error) {
+	f, err := Parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ingest.Result{}
+	for _, w := range workouts(f, userID) {
+		result.WorkoutsReceived++
+		inserted, err := p.db.InsertWorkout(ctx, w.row)
+		if err != nil {
+			return result, fmt.Errorf("inserting workout %s: %w", w.row.ID, err)
+		}
+		if inserted {
+			result.WorkoutsInserted++
+		}
+		if len(w.hr) > 0 {
+			n, err := p.db.InsertWorkoutHeartRate(ctx, w.hr)
+			if err != nil {
+				return result, fmt.Errorf("inserting workout HR: %w", err)
+			}
+			result.WorkoutHRPoints += n
+		}
+		if len(w.route) > 0 {
+			n, err := p.db.InsertWorkoutRoutes(ctx, w.route)
+			if err != nil {
+				return result, fmt.Errorf("inserting workout routes: %w", err)
+			}
+			result.WorkoutRoutePoints += n
+			if n > 0 {
+				if _, err := p.db.UpdateMovingTime(ctx, w.row.ID, userID); err != nil {
+					p.log.Warn("computing moving time", "workout", w.row.ID, "error", err)
+				}
+			}
+		}
+		if len(w.power) > 0 {
+			n, err := p.db.InsertWorkoutPower(ctx, w.power)
+			if err != nil {
+				return result, fmt.Errorf("inserting workout power: %w", err)
+			}
+			result.WorkoutPowerPoints += n
+		}
+	}
+
+	rows := metricRows(f, userID)
+	result.MetricsReceived = len(rows)
+	rows, result.MetricsCollapsed = ingest.CollapseRepeats(rows, p.collapse)
+	if len(rows) > 0 {
+		inserted, err := p.db.InsertHealthMetrics(ctx, rows)
+		if err != nil {
+			return result, fmt.Errorf("inserting metrics: %w", err)
+		}
+		result.MetricsInserted = inserted
+		result.MetricsSkipped = int64(len(rows)) - inserted
+	}
+	return result, nil
+}
+
+// WorkoutID derives a stable workout ID from the device serial number and
+// the session start.
+func WorkoutID(serial uint32, start time.Time) uuid.UUID {
+	return uuid.NewSHA1(workoutNamespace, []byte(fmt.Sprintf("fit:%d:%s", serial, start.UTC().Format(time.RFC3339))))
+}
+
+// workouts converts each session to workout rows. Records belong to the
+// latest session starting at or before them; records before the first
+// session belong to it.
+func workouts(f *File, userID int) []workout {
+	sessions := append([]Session(nil), f.Sessions...)
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Start.Before(sessions[j].Start) })
+
+	out := make([]workout, len(sessions))
+	perSession := make([][]Record, len(sessions))
+	for _, rec := range f.Records {
+		i := sort.Search(len(sessions), func(i int) bool { return sessions[i].Start.After(rec.Time) }) - 1
+		if i < 0 {
+			i = 0
+		}
+		if len(sessions) > 0 {
+			perSession[i] = append(perSession[i], rec)
+		}
+	}
+
+	for i, s := range sessions {
+		id := WorkoutID(f.SerialNumber, s.Start)
+		row := models.WorkoutRow{
+			ID:            id,
+			UserID:        userID,
+			Name:          workoutName(s.Sport, s.SubSport),
+			Source:        Source,
+			StartTime:     s.Start,
+			EndTime:       s.End(),
+			DurationSec:   s.Elapsed.Seconds(),
+			AvgHeartRate:  s.AvgHR,
+			MaxHeartRate:  s.MaxHR,
+			MinHeartRate:  s.MinHR,
+			ElevationUp:   s.AscentM,
+			ElevationDown: s.DescentM,
+		}
+		if isIndoor(s.SubSport) {
+			indoor := true
+			row.IsIndoor = &indoor
+		}
+		if s.Calories != nil {
+			row.ActiveEnergyBurned, row.ActiveEnergyUnits = s.Calories, "kcal"
+		}
+		if s.DistanceM != nil {
+			row.Distance, row.DistanceUnits = s.DistanceM, "m"
+		}
+		if s.AvgTempC != nil {
+			row.Temperature, row.TemperatureUnits = s.AvgTempC, "degC"
+		}
+		if s.Sport == "swimming" {
+			row.PoolLength, row.TotalStrokes, row.LapCount = s.PoolLengthM, s.Strokes, s.Lengths
+		}
+		out[i] = workout{
+			row:   row,
+			hr:    hrRows(perSession[i], id, userID),
+			route: routeRows(perSession[i], id, userID),
+			power: powerRows(perSession[i], id, userID),
+		}
+	}
+	return out
+}
+
+// hrRows folds per-second heart rate records into per-minute min/avg/max
+// rows, matching the granularity of Apple Health workout heart rate.
+func hrRows(records []Record, workoutID uuid.UUID, userID int) []models.WorkoutHRRow {
+	type acc struct {
+		min, max, sum float64
+		n             int
+	}
+	byMinute := map[time.Time]*acc{}
+	var minutes []time.Time
+	for _, r := range records {
+		if r.HeartRate == nil {
+			continue
+		}
+		m := r.Time.Truncate(time.Minute)
+		a := byMinute[m]
+		if a == nil {
+			a = &acc{min: *r.HeartRate, max: *r.HeartRate}
+			byMinute[m] = a
+			minutes = append(minutes, m)
+		}
+		a.min = min(a.min, *r.HeartRate)
+		a.max = max(a.max, *r.HeartRate)
+		a.sum += *r.HeartRate
+		a.n++
+	}
+	sort.Slice(minutes, func(i, j int) bool { return minutes[i].Before(minutes[j]) })
+
+	rows := make([]models.WorkoutHRRow, len(minutes))
+	for i, m := range minutes {
+		a := byMinute[m]
+		lo, avg, hi := a.min, a.sum/float64(a.n), a.max
+		rows[i] = models.WorkoutHRRow{
+			Time: m, WorkoutID: workoutID, UserID: userID,
+			MinBPM: &lo, AvgBPM: &avg, MaxBPM: &hi, Source: Source,
+		}
+	}
+	return rows
+}
+
+// routeRows keeps the records with a GPS position.
+func routeRows(records []Record, workoutID uuid.UUID, userID int) []models.WorkoutRouteRow {
+	var rows []models.WorkoutRouteRow
+	for _, r := range records {
+		if r.Latitude == nil || r.Longitude == nil {
+			continue
+		}
+		rows = append(rows, models.WorkoutRouteRow{
+			Time:      r.Time,
+			WorkoutID: workoutID,
+			UserID:    userID,
+			Latitude:  *r.Latitude,
+			Longitude: *r.Longitude,
+			Altitude:  r.AltitudeM,
+			Speed:     r.SpeedMPS,
+		})
+	}
+	return rows
+}
+
+// powerRows keeps the records with a power reading; workouts recorded
+// without a power meter get none.
+func powerRows(records []Record, workoutID uuid.UUID, userID int) []models.WorkoutPowerRow {
+	var rows []models.WorkoutPowerRow
+	for _, r := range records {
+		if r.PowerW == nil {
+			continue
+		}
+		rows = append(rows, models.WorkoutPowerRow{
+			Time: r.Time, WorkoutID: workoutID, UserID: userID, Watts: *r.PowerW, Source: Source,
+		})
+	}
+	return rows
+}
+
+// metricRows converts monitoring samples to health_metrics rows.
+func metricRows(f *File, userID int) []models.HealthMetricRow {
+	rows := make([]models.HealthMetricRow, 0, len(f.RestingHR)+len(f.Stress))
+	add := func(name, units string, samples []Sample) {
+		for _, s := range samples {
+			v := s.Value
+			rows = append(rows, models.HealthMetricRow{
+				Time: s.Time, UserID: userID, MetricName: name, Source: Source, Units: units, Qty: &v,
+			})
+		}
+	}
+	add(MetricRestingHR, "bpm", f.RestingHR)
+	add(MetricStress, "score", f.Stress)
+	return rows
+}
+
+// workoutName maps a FIT sport to a canonical workout name. Training
+// sessions are named by their sub-sport (e.g. strength_training); sports
+// without a mapping are title-cased ("open_water" → "Open Water").
+func workoutName(sport, subSport string) string {
+	name := sport
+	if (sport == "training" || sport == "") && subSport != "" {
+		name = subSport
+	}
+	if name == "" {
+		return "Other"
+	}
+	if n := ingest.NormalizeWorkoutName(name); n != name {
+		return n
+	}
+	words := strings.Split(name, "_")
+	for i, w := range words {
+		if w != "" {
+			words[i] = strings.ToUpper(w[:1]) + w[1:]
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// isIndoor reports whether a sub-sport is an indoor activity.
+func isIndoor(subSport string) bool {
+	switch subSport {
+	case "treadmill", "spin", "virtual_activity", "indoor_cycling", "indoor_rowing",
+		"indoor_running", "indoor_walking", "indoor_skiing", "indoor_climbing":
+		return true
+	}
+	return false
+}
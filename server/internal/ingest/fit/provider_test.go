@@ -0,0 +1,133 @@
+package fit
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWorkoutName verifies sports map to canonical names, training sessions
+// use their sub-sport, and unmapped names are title-cased.
+func TestWorkoutName(t *testing.T) {
+	tests := []struct {
+		sport, subSport, want string
+	}{
+		{"running", "", "Running"},
+		{"cycling", "indoor_cycling", "Cycling"},
+		{"training", "strength_training", "Strength Training"},
+		{"training", "", "Training"},
+		{"stand_up_paddleboarding", "", "Stand Up Paddleboarding"},
+		{"", "", "Other"},
+	}
+	for _, tt := range tests {
+		if got := workoutName(tt.sport, tt.subSport); got != tt.want {
+			t.Errorf("workoutName(%q, %q) = %q, want %q", tt.sport, tt.subSport, got, tt.want)
+		}
+	}
+}
+
+// TestWorkoutID verifies IDs are stable per device and start time, so a
+// re-imported file dedups against the first import.
+func TestWorkoutID(t *testing.T) {
+	start := time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC)
+	if WorkoutID(1, start) != WorkoutID(1, start.In(time.FixedZone("CEST", 7200))) {
+		t.Error("same instant in another zone gave a different ID")
+	}
+	if WorkoutID(1, start) == WorkoutID(2, start) || WorkoutID(1, start) == WorkoutID(1, start.Add(time.Second)) {
+		t.Error("different device or start gave the same ID")
+	}
+}
+
+// TestWorkouts verifies records are split between sessions by start time
+// and that heart rate folds into per-minute rows.
+func TestWorkouts(t *testing.T) {
+	start := time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC)
+	hr := func(v float64) *float64 { return &v }
+	lat, lon := 52.5, 13.4
+	f := &File{
+		SerialNumber: 7,
+		// Out of order on purpose: a multisport file's transition.
+		Sessions: []Session{
+			{Start: start.Add(2 * time.Minute), Elapsed: time.Minute, Sport: "cycling", SubSport: "indoor_cycling"},
+			{Start: start, Elapsed: 2 * time.Minute, Sport: "running"},
+		},
+		Records: []Record{
+			{Time: start.Add(-5 * time.Second), HeartRate: hr(90)},
+			{Time: start.Add(10 * time.Second), HeartRate: hr(100), Latitude: &lat, Longitude: &lon},
+			{Time: start.Add(50 * time.Second), HeartRate: hr(110)},
+			{Time: start.Add(70 * time.Second), HeartRate: hr(130)},
+			{Time: start.Add(2 * time.Minute), HeartRate: hr(140)},
+			{Time: start.Add(150 * time.Second)},
+		},
+	}
+
+	ws := workouts(f, 1)
+	if len(ws) != 2 {
+		t.Fatalf("workouts = %d, want 2", len(ws))
+	}
+	run, ride := ws[0], ws[1]
+	if run.row.Name != "Running" || run.row.ID != WorkoutID(7, start) || run.row.IsIndoor != nil {
+		t.Errorf("run = %+v", run.row)
+	}
+	if ride.row.Name != "Cycling" || ride.row.IsIndoor == nil || !*ride.row.IsIndoor {
+		t.Errorf("ride = %+v", ride.row)
+	}
+
+	// The record before the first session joins it: minute 05:59 and
+	// minutes 06:00 (100, 110) and 06:01 (130).
+	if len(run.hr) != 3 {
+		t.Fatalf("run HR rows = %d, want 3", len(run.hr))
+	}
+	m := run.hr[1]
+	if !m.Time.Equal(start) || *m.MinBPM != 100 || *m.AvgBPM != 105 || *m.MaxBPM != 110 {
+		t.Errorf("06:00 row = %v %v/%v/%v", m.Time, *m.MinBPM, *m.AvgBPM, *m.MaxBPM)
+	}
+	if len(run.route) != 1 || run.route[0].Latitude != lat {
+		t.Errorf("run route = %+v", run.route)
+	}
+	if len(ride.hr) != 1 || *ride.hr[0].AvgBPM != 140 || len(ride.route) != 0 {
+		t.Errorf("ride HR = %d rows, route = %d", len(ride.hr), len(ride.route))
+	}
+}
+
+// TestMetricRows verifies monitoring samples map to resting HR and stress
+// metrics from the Garmin source.
+func TestMetricRows(t *testing.T) {
+	day := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	rows := metricRows(&File{
+		RestingHR: []Sample{{Time: day, Value: 52}},
+		Stress:    []Sample{{Time: day.Add(time.Hour), Value: 35}},
+	}, 1)
+	if len(rows) != 2 {
+		t.Fatalf("rows = %d, want 2", len(rows))
+	}
+	if rows[0].MetricName != MetricRestingHR || *rows[0].Qty != 52 || rows[0].Units != "bpm" || rows[0].Source != Source {
+		t.Errorf("resting HR row = %+v", rows[0])
+	}
+	if rows[1].MetricName != MetricStress || *rows[1].Qty != 35 {
+		t.Errorf("stress row = %+v", rows[1])
+	}
+}
+
+// TestPowerRows verifies only records with a power reading become power
+// rows, so sessions without a power meter store none.
+func TestPowerRows(t *testing.T) {
+	start := time.Date(2025, 6, 1, 6, 0, 0, 0, time.UTC)
+	w := func(v float64) *float64 { return &v }
+	f := &File{
+		Sessions: []Session{{Start: start, Elapsed: time.Minute, Sport: "cycling"}},
+		Records: []Record{
+			{Time: start, PowerW: w(180)},
+			{Time: start.Add(time.Second)},
+			{Time: start.Add(2 * time.Second), PowerW: w(220)},
+		},
+	}
+	ws := workouts(f, 1)
+	if len(ws[0].power) != 2 || ws[0].power[1].Watts != 220 || ws[0].power[0].WorkoutID != ws[0].row.ID {
+		t.Errorf("power rows = %+v", ws[0].power)
+	}
+
+	f.Records = []Record{{Time: start}}
+	if ws := workouts(f, 1); ws[0].power != nil {
+		t.Errorf("power rows without power = %+v", ws[0].power)
+	}
+}
@@ -3,8 +3,10 @@ package health
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"strings"
 	"time"
 
@@ -16,13 +18,43 @@ import (
 
 // Provider processes health data REST API payloads.
 type Provider struct {
-	db  *storage.DB
-	log *slog.Logger
+	db     *storage.DB
+	log    *slog.Logger
+	bounds ingest.TimeBounds
+	units  *ingest.UnitNormalizer
+
+	// collapse maps metrics opted into ingest.CollapseRepeats to their window.
+	collapse map[string]time.Duration
+	// mergeGap joins workout segments (see MergeSegments); 0 disables.
+	mergeGap time.Duration
 }
 
 // NewProvider creates a new health ingest provider.
 func NewProvider(db *storage.DB, log *slog.Logger) *Provider {
-	return &Provider{db: db, log: log}
+	return &Provider{db: db, log: log, bounds: ingest.DefaultTimeBounds, units: ingest.NewUnitNormalizer(nil)}
+}
+
+// SetUnitNormalizer replaces the unit normalizer applied to metric rows.
+func (p *Provider) SetUnitNormalizer(n *ingest.UnitNormalizer) {
+	p.units = n
+}
+
+// SetTimeBounds configures the accepted timestamp range for metric data points.
+func (p *Provider) SetTimeBounds(b ingest.TimeBounds) {
+	p.bounds = b
+}
+
+// SetRepeatCollapse opts metrics into dropping repeated identical points
+// (see ingest.CollapseRepeats), keyed by metric name with the window to
+// compare within.
+func (p *Provider) SetRepeatCollapse(windows map[string]time.Duration) {
+	p.collapse = windows
+}
+
+// SetSegmentMergeGap merges workouts of the same type that start less than
+// gap after the previous one ended (see MergeSegments). Zero disables.
+func (p *Provider) SetSegmentMergeGap(gap time.Duration) {
+	p.mergeGap = gap
 }
 
 // Ingest processes a health data JSON payload and stores accepted data.
@@ -38,7 +70,10 @@ func (p *Provider) Ingest(ctx context.Context, payload *models.HealthPayload, us
 
 	// Process workouts
 	if len(payload.Data.Workouts) > 0 {
-		if err := p.processWorkouts(ctx, payload.Data.Workouts, userID, result); err != nil {
+		workouts, merged := MergeSegments(payload.Data.Workouts, p.mergeGap)
+		result.WorkoutsMerged = merged
+		result.WorkoutsReceived += merged // absorbed segments were received too
+		if err := p.processWorkouts(ctx, workouts, userID, result); err != nil {
 			return result, fmt.Errorf("processing workouts: %w", err)
 		}
 	}
@@ -92,6 +127,13 @@ func (p *Provider) Ingest(ctx context.Context, payload *models.HealthPayload, us
 		}
 	}
 
+	// Process symptoms
+	if len(payload.Data.Symptoms) > 0 {
+		if err := p.processSymptoms(ctx, payload.Data.Symptoms, userID, result); err != nil {
+			return result, fmt.Errorf("processing symptoms: %w", err)
+		}
+	}
+
 	// Build message for rejected metrics
 	if len(result.RejectedNames) > 0 {
 		result.Message = fmt.Sprintf(
@@ -104,60 +146,163 @@ func (p *Provider) Ingest(ctx context.Context, payload *models.HealthPayload, us
 }
 
 func (p *Provider) processMetrics(ctx context.Context, metrics []models.HealthMetric, userID int, result *ingest.Result) error {
+	aliases, err := p.db.MetricAliases(ctx)
+	if err != nil {
+		return fmt.Errorf("loading metric aliases: %w", err)
+	}
+	allowed := func(name string) (bool, error) { return p.db.IsMetricAllowed(ctx, name) }
+
+	healthRows, sleep, err := p.acceptMetrics(metrics, aliases, allowed, userID, result)
+	if err != nil {
+		return err
+	}
+
+	// Rejections are bookkeeping for GET /api/v1/allowlist/rejected; failing
+	// to record them shouldn't fail the ingest.
+	if err := p.db.RecordRejectedMetrics(ctx, userID, result.RejectedPoints); err != nil {
+		p.log.Warn("recording rejected metrics", "error", err)
+	}
+
+	// Handle sleep_analysis separately
+	for _, m := range sleep {
+		if err := p.processSleep(ctx, m, userID, result); err != nil {
+			return fmt.Errorf("processing sleep: %w", err)
+		}
+	}
+
+	// Batch insert health metrics
+	if len(healthRows) > 0 {
+		inserted, err := p.db.InsertHealthMetrics(ctx, healthRows)
+		if err != nil {
+			return fmt.Errorf("inserting health metrics: %w", err)
+		}
+		result.MetricsInserted = inserted
+		result.MetricsSkipped = int64(len(healthRows)) - inserted
+	}
+
+	return nil
+}
+
+// acceptMetrics resolves aliases, rejects metrics allowed reports as off the
+// allowlist, and converts the rest to rows. sleep_analysis is returned
+// unconverted since it is stored as sleep sessions and stages. Nothing is
+// written.
+func (p *Provider) acceptMetrics(metrics []models.HealthMetric, aliases map[string]string, allowed func(name string) (bool, error), userID int, result *ingest.Result) ([]models.HealthMetricRow, []models.HealthMetric, error) {
 	var healthRows []models.HealthMetricRow
+	var sleep []models.HealthMetric
 	rejectedSet := map[string]bool{}
 
-	for _, m := range metrics {
+	for _, m := range canonicalMetrics(metrics, aliases) {
 		// Check allowlist
-		allowed, err := p.db.IsMetricAllowed(ctx, m.Name)
+		ok, err := allowed(m.Name)
 		if err != nil {
-			return fmt.Errorf("checking allowlist for %s: %w", m.Name, err)
+			return nil, nil, fmt.Errorf("checking allowlist for %s: %w", m.Name, err)
 		}
-		if !allowed {
+		if !ok {
 			if !rejectedSet[m.Name] {
 				result.RejectedNames = append(result.RejectedNames, m.Name)
 				rejectedSet[m.Name] = true
 			}
+			if result.RejectedPoints == nil {
+				result.RejectedPoints = map[string]int{}
+			}
+			result.RejectedPoints[m.Name] += len(m.Data)
 			result.MetricsRejected += len(m.Data)
 			continue
 		}
 
-		// Handle sleep_analysis separately
 		if m.Name == "sleep_analysis" {
-			if err := p.processSleep(ctx, m, userID, result); err != nil {
-				return fmt.Errorf("processing sleep: %w", err)
-			}
+			sleep = append(sleep, m)
 			continue
 		}
 
-		// Detect metric shape and convert to rows
-		for _, raw := range m.Data {
-			result.MetricsReceived++
+		healthRows = append(healthRows, p.convertMetricRows(m, userID, result)...)
+	}
+	healthRows, result.MetricsCollapsed = ingest.CollapseRepeats(healthRows, p.collapse)
+	return healthRows, sleep, nil
+}
 
-			row, err := convertMetricDataPoint(m.Name, m.Units, raw, userID)
-			if err != nil {
-				p.log.Warn("skipping data point", "metric", m.Name, "error", err)
+// Validate runs a payload through alias resolution, the allowlist, and row
+// conversion without writing anything. MetricsAccepted counts the points that
+// would be stored; duplicates of existing rows aren't detected, so it is an
+// upper bound on MetricsInserted.
+func (p *Provider) Validate(ctx context.Context, payload *models.HealthPayload, userID int) (*ingest.Result, error) {
+	aliases, err := p.db.MetricAliases(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("loading metric aliases: %w", err)
+	}
+	allowed := func(name string) (bool, error) { return p.db.IsMetricAllowed(ctx, name) }
+	return p.validate(payload, aliases, allowed, userID)
+}
+
+// validate is Validate with the alias map and allowlist lookup supplied.
+func (p *Provider) validate(payload *models.HealthPayload, aliases map[string]string, allowed func(name string) (bool, error), userID int) (*ingest.Result, error) {
+	result := &ingest.Result{DryRun: true}
+
+	rows, sleep, err := p.acceptMetrics(payload.Data.Metrics, aliases, allowed, userID, result)
+	if err != nil {
+		return result, fmt.Errorf("validating metrics: %w", err)
+	}
+	result.MetricsAccepted = len(rows)
+	for _, m := range sleep {
+		result.MetricsReceived += len(m.Data)
+		result.MetricsAccepted += len(m.Data)
+	}
+	result.WorkoutsReceived = len(payload.Data.Workouts)
+
+	if len(result.RejectedNames) > 0 {
+		result.Message = fmt.Sprintf(
+			"These metrics would be rejected because they are not in the allowlist: %v. "+
+				"Check GET /api/v1/allowlist for the full list.",
+			result.RejectedNames)
+	}
+	return result, nil
+}
+
+// canonicalMetrics renames metrics sent under an alias to their canonical
+// allowlist name, so renamed HAE metrics land in the existing series.
+func canonicalMetrics(metrics []models.HealthMetric, aliases map[string]string) []models.HealthMetric {
+	out := make([]models.HealthMetric, len(metrics))
+	for i, m := range metrics {
+		m.Name = storage.CanonicalMetricName(aliases, m.Name)
+		out[i] = m
+	}
+	return out
+}
+
+// convertMetricRows converts every data point of a metric to rows, counting
+// received points and rejecting those with out-of-range timestamps.
+func (p *Provider) convertMetricRows(m models.HealthMetric, userID int, result *ingest.Result) []models.HealthMetricRow {
+	var rows []models.HealthMetricRow
+	for _, raw := range m.Data {
+		result.MetricsReceived++
+
+		row, err := convertMetricDataPoint(m.Name, m.Units, raw, userID, p.bounds)
+		if err != nil {
+			if errors.Is(err, ingest.ErrTimeOutOfRange) {
+				result.MetricsOutOfRange++
+				p.log.Warn("rejecting out-of-range data point", "metric", m.Name, "error", err)
 				continue
 			}
-			healthRows = append(healthRows, *row)
+			p.log.Warn("skipping data point", "metric", m.Name, "error", err)
+			continue
+		}
+		if _, err := p.units.Normalize(row); err != nil {
+			p.log.Warn("storing metric in received unit", "metric", m.Name, "error", err)
 		}
+		rows = append(rows, *row)
 	}
-
-	// Batch insert health metrics
-	if len(healthRows) > 0 {
-		inserted, err := p.db.InsertHealthMetrics(ctx, healthRows)
-		if err != nil {
-			return fmt.Errorf("inserting health metrics: %w", err)
+	if DailyAggregated(rows) {
+		for i := range rows {
+			rows[i].AggLevel = models.AggLevelDay
 		}
-		result.MetricsInserted = inserted
-		result.MetricsSkipped = int64(len(healthRows)) - inserted
 	}
-
-	return nil
+	return rows
 }
 
 // convertMetricDataPoint detects the shape of a metric data point and converts it to a HealthMetricRow.
-func convertMetricDataPoint(name, units string, raw json.RawMessage, userID int) (*models.HealthMetricRow, error) {
+// Points timestamped outside bounds are rejected with ingest.ErrTimeOutOfRange.
+func convertMetricDataPoint(name, units string, raw json.RawMessage, userID int, bounds ingest.TimeBounds) (*models.HealthMetricRow, error) {
 	row := &models.HealthMetricRow{
 		UserID:     userID,
 		MetricName: name,
@@ -219,6 +364,10 @@ func convertMetricDataPoint(name, units string, raw json.RawMessage, userID int)
 		}
 	}
 
+	if err := bounds.Check(row.Time); err != nil {
+		return nil, err
+	}
+
 	return row, nil
 }
 
@@ -301,6 +450,54 @@ func (p *Provider) processSleep(ctx context.Context, m models.HealthMetric, user
 	return nil
 }
 
+// workoutBulkKeys are workout JSON keys stored in their own tables and
+// therefore dropped from raw_json.
+var workoutBulkKeys = []string{"heartRateData", "heartRateRecovery", "route", "stepCount"}
+
+// workoutRawJSON returns the JSON stored in workouts.raw_json: the original
+// payload (so unmodeled fields like weather survive) minus the bulky arrays
+// kept in workout_heart_rate / workout_routes / workout_cadence.
+func workoutRawJSON(w models.HealthWorkout) []byte {
+	src := []byte(w.RawJSON)
+	if len(src) == 0 {
+		src, _ = json.Marshal(w)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(src, &fields); err != nil {
+		return src
+	}
+	for _, k := range workoutBulkKeys {
+		delete(fields, k)
+	}
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return src
+	}
+	return out
+}
+
+// applySwimFields copies pool length (in metres) and stroke count from a swim
+// workout into row. HAE doesn't send a lap count, so it is derived from
+// distance / pool length when both are known.
+func applySwimFields(w models.HealthWorkout, row *models.WorkoutRow) {
+	if w.LapLength != nil && w.LapLength.Qty > 0 {
+		if m, ok := ingest.ConvertUnit(w.LapLength.Qty, w.LapLength.Units, "m"); ok {
+			row.PoolLength = &m
+		}
+	}
+	if w.StrokeCount != nil && w.StrokeCount.Qty > 0 {
+		strokes := int(math.Round(w.StrokeCount.Qty))
+		row.TotalStrokes = &strokes
+	}
+	if row.PoolLength != nil && w.Distance != nil {
+		if dist, ok := ingest.ConvertUnit(w.Distance.Qty, w.Distance.Units, "m"); ok {
+			if laps := int(math.Round(dist / *row.PoolLength)); laps > 0 {
+				row.LapCount = &laps
+			}
+		}
+	}
+}
+
 func (p *Provider) processWorkouts(ctx context.Context, workouts []models.HealthWorkout, userID int, result *ingest.Result) error {
 	for _, w := range workouts {
 		result.WorkoutsReceived++
@@ -311,8 +508,7 @@ func (p *Provider) processWorkouts(ctx context.Context, workouts []models.Health
 			continue
 		}
 
-		// Marshal the full workout as raw_json for fields we don't explicitly model
-		rawJSON, _ := json.Marshal(w)
+		rawJSON := workoutRawJSON(w)
 
 		row := models.WorkoutRow{
 			ID:          workoutID,
@@ -345,6 +541,17 @@ func (p *Provider) processWorkouts(ctx context.Context, workouts []models.Health
 		if w.ElevationDown != nil {
 			row.ElevationDown = &w.ElevationDown.Qty
 		}
+		if w.Temperature != nil {
+			row.Temperature = &w.Temperature.Qty
+			row.TemperatureUnits = w.Temperature.Units
+		}
+		if w.Humidity != nil {
+			row.Humidity = &w.Humidity.Qty
+		}
+
+		if ingest.IsSwimWorkout(row.Name) {
+			applySwimFields(w, &row)
+		}
 
 		// Extract HR summary
 		if w.HeartRate != nil {
@@ -360,7 +567,12 @@ func (p *Provider) processWorkouts(ctx context.Context, workouts []models.Health
 			}
 		}
 
-		inserted, err := p.db.InsertWorkout(ctx, row)
+		var inserted bool
+		if absorbed := absorbedSegmentIDs(w); len(absorbed) > 0 {
+			inserted, err = p.db.UpsertMergedWorkout(ctx, row, absorbed)
+		} else {
+			inserted, err = p.db.InsertWorkout(ctx, row)
+		}
 		if err != nil {
 			return fmt.Errorf("inserting workout %s: %w", w.ID, err)
 		}
@@ -369,53 +581,109 @@ func (p *Provider) processWorkouts(ctx context.Context, workouts []models.Health
 		}
 
 		// Insert HR time-series (ON CONFLICT DO NOTHING — safe for backfill)
-		if len(w.HeartRateData) > 0 {
-			hrRows := make([]models.WorkoutHRRow, len(w.HeartRateData))
-			for i, hr := range w.HeartRateData {
-				hrRows[i] = models.WorkoutHRRow{
-					Time:      hr.Date.Time,
-					WorkoutID: workoutID,
-					UserID:    userID,
-					MinBPM:    &hr.Min,
-					AvgBPM:    &hr.Avg,
-					MaxBPM:    &hr.Max,
-					Source:    hr.Source,
-				}
-			}
+		if hrRows := workoutHRRows(w.HeartRateData, workoutID, userID); len(hrRows) > 0 {
 			n, err := p.db.InsertWorkoutHeartRate(ctx, hrRows)
 			if err != nil {
 				return fmt.Errorf("inserting workout HR: %w", err)
 			}
 			result.WorkoutHRPoints += n
 		}
+		if recRows := workoutHRRows(w.HeartRateRecovery, workoutID, userID); len(recRows) > 0 {
+			n, err := p.db.InsertWorkoutHRRecovery(ctx, recRows)
+			if err != nil {
+				return fmt.Errorf("inserting workout HR recovery: %w", err)
+			}
+			result.WorkoutHRRecoveryPoints += n
+		}
 
-		// Insert route data
-		if len(w.Route) > 0 {
-			routeRows := make([]models.WorkoutRouteRow, len(w.Route))
-			for i, rp := range w.Route {
-				routeRows[i] = models.WorkoutRouteRow{
-					Time:               rp.Timestamp.Time,
-					WorkoutID:          workoutID,
-					UserID:             userID,
-					Latitude:           rp.Latitude,
-					Longitude:          rp.Longitude,
-					Altitude:           &rp.Altitude,
-					Speed:              &rp.Speed,
-					Course:             &rp.Course,
-					HorizontalAccuracy: &rp.HorizontalAccuracy,
-					VerticalAccuracy:   &rp.VerticalAccuracy,
-				}
+		if cadRows := workoutCadenceRows(w.StepCount, workoutID, userID); len(cadRows) > 0 {
+			n, err := p.db.InsertWorkoutCadence(ctx, cadRows)
+			if err != nil {
+				return fmt.Errorf("inserting workout cadence: %w", err)
 			}
+			result.WorkoutCadencePoints += n
+		}
+
+		// Insert route data
+		if routeRows := workoutRouteRows(w.Route, workoutID, userID); len(routeRows) > 0 {
 			n, err := p.db.InsertWorkoutRoutes(ctx, routeRows)
 			if err != nil {
 				return fmt.Errorf("inserting workout routes: %w", err)
 			}
 			result.WorkoutRoutePoints += n
+			if n > 0 {
+				if _, err := p.db.UpdateMovingTime(ctx, workoutID, userID); err != nil {
+					p.log.Warn("computing moving time", "workout", workoutID, "error", err)
+				}
+			}
 		}
+		result.WorkoutIDs = append(result.WorkoutIDs, segmentIDs(w)...)
 	}
 	return nil
 }
 
+// workoutHRRows converts HAE workout HR points (in-workout or recovery) into
+// rows for the workout HR tables.
+func workoutHRRows(points []models.WorkoutHRPoint, workoutID uuid.UUID, userID int) []models.WorkoutHRRow {
+	rows := make([]models.WorkoutHRRow, len(points))
+	for i, hr := range points {
+		rows[i] = models.WorkoutHRRow{
+			Time:      hr.Date.Time,
+			WorkoutID: workoutID,
+			UserID:    userID,
+			MinBPM:    &hr.Min,
+			AvgBPM:    &hr.Avg,
+			MaxBPM:    &hr.Max,
+			Source:    hr.Source,
+		}
+	}
+	return rows
+}
+
+// workoutRouteRows converts HAE route points into workout_routes rows.
+func workoutRouteRows(points []models.RoutePoint, workoutID uuid.UUID, userID int) []models.WorkoutRouteRow {
+	rows := make([]models.WorkoutRouteRow, len(points))
+	for i, rp := range points {
+		rows[i] = models.WorkoutRouteRow{
+			Time:               rp.Timestamp.Time,
+			WorkoutID:          workoutID,
+			UserID:             userID,
+			Latitude:           rp.Latitude,
+			Longitude:          rp.Longitude,
+			Altitude:           &rp.Altitude,
+			Speed:              &rp.Speed,
+			Course:             &rp.Course,
+			HorizontalAccuracy: &rp.HorizontalAccuracy,
+			VerticalAccuracy:   &rp.VerticalAccuracy,
+			CourseAccuracy:     &rp.CourseAccuracy,
+			SpeedAccuracy:      &rp.SpeedAccuracy,
+		}
+	}
+	return rows
+}
+
+// workoutCadenceRows converts HAE in-workout step counts into cadence rows.
+// Each sample's cadence is its count divided by the interval it covers (see
+// storage.CadenceIntervals).
+func workoutCadenceRows(points []models.WorkoutQtyPoint, workoutID uuid.UUID, userID int) []models.WorkoutCadenceRow {
+	times := make([]time.Time, len(points))
+	for i, p := range points {
+		times[i] = p.Date.Time
+	}
+	intervals := storage.CadenceIntervals(times)
+	rows := make([]models.WorkoutCadenceRow, len(points))
+	for i, p := range points {
+		rows[i] = models.WorkoutCadenceRow{
+			Time:      p.Date.Time,
+			WorkoutID: workoutID,
+			UserID:    userID,
+			SPM:       p.Qty / intervals[i].Minutes(),
+			Source:    p.Source,
+		}
+	}
+	return rows
+}
+
 func (p *Provider) processECGRecordings(ctx context.Context, recordings []models.ECGRecording, userID int, result *ingest.Result) error {
 	for _, rec := range recordings {
 		id, err := uuid.Parse(rec.ID)
@@ -639,6 +907,46 @@ func (p *Provider) processStateOfMind(ctx context.Context, records []models.Stat
 	return nil
 }
 
+func (p *Provider) processSymptoms(ctx context.Context, symptoms []models.Symptom, userID int, result *ingest.Result) error {
+	rows := symptomRows(symptoms, userID)
+	if skipped := len(symptoms) - len(rows); skipped > 0 {
+		p.log.Warn("skipping symptoms without a name", "count", skipped)
+	}
+	if len(rows) > 0 {
+		inserted, err := p.db.InsertSymptoms(ctx, rows)
+		if err != nil {
+			return fmt.Errorf("inserting symptoms: %w", err)
+		}
+		result.SymptomsInserted = inserted
+	}
+	return nil
+}
+
+// symptomRows converts HAE symptoms to rows, dropping unnamed entries.
+func symptomRows(symptoms []models.Symptom, userID int) []models.SymptomRow {
+	var rows []models.SymptomRow
+	for _, s := range symptoms {
+		name := strings.TrimSpace(s.Name)
+		if name == "" {
+			continue
+		}
+		row := models.SymptomRow{
+			UserID:      userID,
+			Name:        name,
+			Severity:    s.Severity,
+			StartTime:   s.Start.Time,
+			UserEntered: s.UserEntered,
+			Source:      s.Source,
+		}
+		if s.End != nil {
+			t := s.End.Time
+			row.EndTime = &t
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
 func (p *Provider) processCategorySamples(ctx context.Context, samples []models.CategorySample, userID int, result *ingest.Result) error {
 	var rows []models.CategorySampleRow
 	for _, cs := range samples {
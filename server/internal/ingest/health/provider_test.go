@@ -1 +1,401 @@
 package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/ingest"
+	"github.com/claude/freereps/internal/models"
+	"github.com/google/uuid"
+)
+
+// TestConvertMetricRowsCountsOutOfRange verifies that a year-3000 data point is
+// counted in MetricsOutOfRange and excluded from the rows sent to storage,
+// while valid points in the same metric are kept.
+func TestConvertMetricRowsCountsOutOfRange(t *testing.T) {
+	p := &Provider{log: slog.Default(), bounds: ingest.DefaultTimeBounds}
+	m := models.HealthMetric{
+		Name:  "step_count",
+		Units: "count",
+		Data: []json.RawMessage{
+			json.RawMessage(`{"date":"2025-01-01 08:00:00 +0000","qty":100}`),
+			json.RawMessage(`{"date":"3000-01-01 08:00:00 +0000","qty":200}`),
+		},
+	}
+	result := &ingest.Result{}
+
+	rows := p.convertMetricRows(m, 1, result)
+
+	if len(rows) != 1 || *rows[0].Qty != 100 {
+		t.Fatalf("rows = %+v, want only the 2025 point", rows)
+	}
+	if result.MetricsReceived != 2 {
+		t.Errorf("MetricsReceived = %d, want 2", result.MetricsReceived)
+	}
+	if result.MetricsOutOfRange != 1 {
+		t.Errorf("MetricsOutOfRange = %d, want 1", result.MetricsOutOfRange)
+	}
+}
+
+// TestCanonicalMetricsAliasIngest verifies a metric sent under an alias is
+// converted into rows for its canonical metric, leaving other names alone.
+func TestCanonicalMetricsAliasIngest(t *testing.T) {
+	p := &Provider{log: slog.Default(), bounds: ingest.DefaultTimeBounds, units: ingest.NewUnitNormalizer(nil)}
+	aliases := map[string]string{"walking_heart_rate": "walking_heart_rate_average"}
+	metrics := canonicalMetrics([]models.HealthMetric{
+		{Name: "walking_heart_rate", Units: "count/min", Data: []json.RawMessage{
+			json.RawMessage(`{"date":"2025-01-01 08:00:00 +0000","qty":98}`),
+		}},
+		{Name: "step_count", Units: "count"},
+	}, aliases)
+
+	if metrics[1].Name != "step_count" {
+		t.Errorf("unaliased name = %q, want step_count", metrics[1].Name)
+	}
+	rows := p.convertMetricRows(metrics[0], 1, &ingest.Result{})
+	if len(rows) != 1 || rows[0].MetricName != "walking_heart_rate_average" {
+		t.Fatalf("rows = %+v, want one walking_heart_rate_average row", rows)
+	}
+}
+
+// TestConvertMetricRowsNormalizesUnits verifies the health provider applies
+// unit normalization before rows reach storage.
+func TestConvertMetricRowsNormalizesUnits(t *testing.T) {
+	p := &Provider{log: slog.Default(), bounds: ingest.DefaultTimeBounds, units: ingest.NewUnitNormalizer(nil)}
+	m := models.HealthMetric{
+		Name:  "weight_body_mass",
+		Units: "lb",
+		Data:  []json.RawMessage{json.RawMessage(`{"date":"2025-01-01 08:00:00 +0000","qty":220.462}`)},
+	}
+
+	rows := p.convertMetricRows(m, 1, &ingest.Result{})
+	if len(rows) != 1 {
+		t.Fatalf("rows = %d, want 1", len(rows))
+	}
+	if rows[0].Units != "kg" || rows[0].RawUnits != "lb" {
+		t.Errorf("units = %q raw = %q, want kg / lb", rows[0].Units, rows[0].RawUnits)
+	}
+	if q := *rows[0].Qty; q < 99.99 || q > 100.01 {
+		t.Errorf("qty = %v, want ~100", q)
+	}
+}
+
+// TestWorkoutRawJSONDropsBulkArrays verifies raw_json keeps unmodeled fields
+// from the original payload but not the HR/route arrays, which already live
+// in their own tables and would bloat every workout row.
+func TestWorkoutRawJSONDropsBulkArrays(t *testing.T) {
+	var w models.HealthWorkout
+	payload := `{"id":"550e8400-e29b-41d4-a716-446655440000","name":"Outdoor Walk",
+		"weather":"Sunny","heartRateData":[{"date":"2024-02-06 07:00:00 -0800","Avg":110}],
+		"route":[{"latitude":1,"longitude":2}]}`
+	if err := json.Unmarshal([]byte(payload), &w); err != nil {
+		t.Fatal(err)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(workoutRawJSON(w), &fields); err != nil {
+		t.Fatalf("raw json not an object: %v", err)
+	}
+	if string(fields["weather"]) != `"Sunny"` {
+		t.Errorf("weather = %s, want \"Sunny\"", fields["weather"])
+	}
+	for _, k := range []string{"heartRateData", "route"} {
+		if _, ok := fields[k]; ok {
+			t.Errorf("%s should be stripped from raw_json", k)
+		}
+	}
+}
+
+// TestWorkoutHRRecoveryRows verifies heartRateRecovery points from an HAE
+// workout become recovery rows tied to the workout and user.
+func TestWorkoutHRRecoveryRows(t *testing.T) {
+	var w models.HealthWorkout
+	payload := `{"id":"550e8400-e29b-41d4-a716-446655440000","name":"Outdoor Run",
+		"heartRateRecovery":[
+			{"date":"2024-02-06 07:30:00 -0800","Min":150,"Avg":155,"Max":160,"units":"count/min","source":"Apple Watch"},
+			{"date":"2024-02-06 07:31:00 -0800","Min":128,"Avg":132,"Max":136,"units":"count/min","source":"Apple Watch"}]}`
+	if err := json.Unmarshal([]byte(payload), &w); err != nil {
+		t.Fatal(err)
+	}
+
+	id := uuid.MustParse(w.ID)
+	rows := workoutHRRows(w.HeartRateRecovery, id, 7)
+	if len(rows) != 2 {
+		t.Fatalf("rows = %d, want 2", len(rows))
+	}
+	r := rows[1]
+	if r.WorkoutID != id || r.UserID != 7 || r.Source != "Apple Watch" {
+		t.Errorf("row = %+v, want workout %s, user 7, Apple Watch", r, id)
+	}
+	if *r.AvgBPM != 132 || *r.MinBPM != 128 || *r.MaxBPM != 136 {
+		t.Errorf("bpm = %v/%v/%v, want 128/132/136", *r.MinBPM, *r.AvgBPM, *r.MaxBPM)
+	}
+	if want := time.Date(2024, 2, 6, 15, 31, 0, 0, time.UTC); !r.Time.Equal(want) {
+		t.Errorf("time = %v, want %v", r.Time, want)
+	}
+	// Rows mustn't alias the loop variable.
+	if *rows[0].AvgBPM != 155 {
+		t.Errorf("first row avg = %v, want 155", *rows[0].AvgBPM)
+	}
+}
+
+// TestWorkoutCadenceRows verifies a run's in-workout step counts become
+// steps-per-minute rows, using the sample spacing as the interval and
+// reusing it across pauses and for the last sample.
+func TestWorkoutCadenceRows(t *testing.T) {
+	var w models.HealthWorkout
+	payload := `{"id":"550e8400-e29b-41d4-a716-446655440000","name":"Outdoor Run",
+		"stepCount":[
+			{"date":"2024-02-06 07:00:00 -0800","qty":164,"units":"count","source":"Apple Watch"},
+			{"date":"2024-02-06 07:01:00 -0800","qty":170,"units":"count","source":"Apple Watch"},
+			{"date":"2024-02-06 07:01:30 -0800","qty":87,"units":"count","source":"Apple Watch"},
+			{"date":"2024-02-06 07:10:00 -0800","qty":88,"units":"count","source":"Apple Watch"}]}`
+	if err := json.Unmarshal([]byte(payload), &w); err != nil {
+		t.Fatal(err)
+	}
+
+	id := uuid.MustParse(w.ID)
+	rows := workoutCadenceRows(w.StepCount, id, 7)
+	want := []float64{164, 340, 174, 176}
+	if len(rows) != len(want) {
+		t.Fatalf("rows = %d, want %d", len(rows), len(want))
+	}
+	for i, spm := range want {
+		if rows[i].SPM != spm {
+			t.Errorf("row %d spm = %v, want %v", i, rows[i].SPM, spm)
+		}
+	}
+	r := rows[0]
+	if r.WorkoutID != id || r.UserID != 7 || r.Source != "Apple Watch" {
+		t.Errorf("row = %+v, want workout %s, user 7, Apple Watch", r, id)
+	}
+	if want := time.Date(2024, 2, 6, 15, 0, 0, 0, time.UTC); !r.Time.Equal(want) {
+		t.Errorf("time = %v, want %v", r.Time, want)
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(workoutRawJSON(w), &fields); err != nil {
+		t.Fatalf("raw json not an object: %v", err)
+	}
+	if _, ok := fields["stepCount"]; ok {
+		t.Error("stepCount should be stripped from raw_json")
+	}
+	if rows := workoutCadenceRows(nil, id, 7); len(rows) != 0 {
+		t.Errorf("no step data gave %d rows, want 0", len(rows))
+	}
+}
+
+// TestWorkoutRouteRowsKeepAccuracy verifies course and speed accuracy from an
+// HAE route point are carried into the stored row.
+func TestWorkoutRouteRowsKeepAccuracy(t *testing.T) {
+	var w models.HealthWorkout
+	payload := `{"id":"550e8400-e29b-41d4-a716-446655440000","name":"Outdoor Run",
+		"route":[{"latitude":48.1,"longitude":11.5,"course":90,"courseAccuracy":12.5,
+			"speed":3.5,"speedAccuracy":0.4,"timestamp":"2024-02-06 07:30:00 -0800"}]}`
+	if err := json.Unmarshal([]byte(payload), &w); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := workoutRouteRows(w.Route, uuid.MustParse(w.ID), 1)
+	if len(rows) != 1 {
+		t.Fatalf("rows = %d, want 1", len(rows))
+	}
+	if *rows[0].CourseAccuracy != 12.5 || *rows[0].SpeedAccuracy != 0.4 {
+		t.Errorf("accuracies = %v/%v, want 12.5/0.4", *rows[0].CourseAccuracy, *rows[0].SpeedAccuracy)
+	}
+}
+
+// TestSymptomRows verifies an HAE symptoms array becomes symptom rows and
+// entries without a name are dropped.
+func TestSymptomRows(t *testing.T) {
+	var p models.HealthPayload
+	payload := `{"data":{"symptoms":[
+		{"name":"Headache","severity":"Mild","start":"2024-02-06 09:00:00 -0800","end":"2024-02-06 11:00:00 -0800","userEntered":true,"source":"Health"},
+		{"name":" ","start":"2024-02-06 10:00:00 -0800"}]}}`
+	if err := json.Unmarshal([]byte(payload), &p); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := symptomRows(p.Data.Symptoms, 3)
+	if len(rows) != 1 {
+		t.Fatalf("rows = %d, want 1", len(rows))
+	}
+	r := rows[0]
+	if r.UserID != 3 || r.Name != "Headache" || r.Severity != "Mild" || !r.UserEntered || r.Source != "Health" {
+		t.Errorf("row = %+v", r)
+	}
+	if want := time.Date(2024, 2, 6, 17, 0, 0, 0, time.UTC); !r.StartTime.Equal(want) {
+		t.Errorf("start = %v, want %v", r.StartTime, want)
+	}
+	if r.EndTime == nil || r.EndTime.Sub(r.StartTime) != 2*time.Hour {
+		t.Errorf("end = %v, want start + 2h", r.EndTime)
+	}
+}
+
+// TestApplySwimFields verifies a yard pool is stored in metres and the lap
+// count is derived from distance, since HAE only sends the lap length.
+func TestApplySwimFields(t *testing.T) {
+	w := models.HealthWorkout{
+		Distance:    &models.Quantity{Qty: 1000, Units: "yd"},
+		LapLength:   &models.Quantity{Qty: 25, Units: "yd"},
+		StrokeCount: &models.Quantity{Qty: 812, Units: "count"},
+	}
+	var row models.WorkoutRow
+	applySwimFields(w, &row)
+
+	if row.PoolLength == nil || *row.PoolLength < 22.85 || *row.PoolLength > 22.87 {
+		t.Errorf("pool_length = %v, want ~22.86 m", row.PoolLength)
+	}
+	if row.LapCount == nil || *row.LapCount != 40 {
+		t.Errorf("lap_count = %v, want 40", row.LapCount)
+	}
+	if row.TotalStrokes == nil || *row.TotalStrokes != 812 {
+		t.Errorf("total_strokes = %v, want 812", row.TotalStrokes)
+	}
+}
+
+// TestValidateWritesNothing verifies a dry run counts accepted and rejected
+// points without touching storage; the provider has no DB, so any write
+// would panic.
+func TestValidateWritesNothing(t *testing.T) {
+	p := &Provider{log: slog.Default(), bounds: ingest.DefaultTimeBounds, units: ingest.NewUnitNormalizer(nil)}
+	point := json.RawMessage(`{"date":"2025-01-01 08:00:00 +0000","qty":100}`)
+	var payload models.HealthPayload
+	payload.Data.Metrics = []models.HealthMetric{
+		{Name: "step_count", Units: "count", Data: []json.RawMessage{point, point}},
+		{Name: "walking_hr", Units: "count/min", Data: []json.RawMessage{point}},
+		{Name: "mystery_metric", Units: "count", Data: []json.RawMessage{point}},
+		{Name: "sleep_analysis", Data: []json.RawMessage{json.RawMessage(`{"date":"2025-01-01","totalSleep":7}`)}},
+	}
+	payload.Data.Workouts = []models.HealthWorkout{{}}
+	aliases := map[string]string{"walking_hr": "walking_heart_rate_average"}
+	allowed := func(name string) (bool, error) { return name != "mystery_metric", nil }
+
+	result, err := p.validate(&payload, aliases, allowed, 1)
+	if err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if !result.DryRun || result.MetricsInserted != 0 {
+		t.Errorf("result = %+v, want a dry run with nothing inserted", result)
+	}
+	if result.MetricsReceived != 4 || result.MetricsAccepted != 4 {
+		t.Errorf("received/accepted = %d/%d, want 4/4", result.MetricsReceived, result.MetricsAccepted)
+	}
+	if result.MetricsRejected != 1 || len(result.RejectedNames) != 1 || result.RejectedNames[0] != "mystery_metric" {
+		t.Errorf("rejected = %d %v, want 1 [mystery_metric]", result.MetricsRejected, result.RejectedNames)
+	}
+	if result.WorkoutsReceived != 1 {
+		t.Errorf("WorkoutsReceived = %d, want 1", result.WorkoutsReceived)
+	}
+}
+
+// TestAcceptMetricsCollapsesRepeats verifies opted-in metrics drop repeated
+// identical points and count them, while other metrics keep every point.
+func TestAcceptMetricsCollapsesRepeats(t *testing.T) {
+	p := &Provider{log: slog.Default(), bounds: ingest.DefaultTimeBounds, units: ingest.NewUnitNormalizer(nil),
+		collapse: map[string]time.Duration{"heart_rate": 5 * time.Second}}
+	var points []json.RawMessage
+	for sec := range 4 {
+		points = append(points, json.RawMessage(fmt.Sprintf(`{"date":"2025-01-01 08:00:0%d +0000","qty":60,"source":"Watch"}`, sec)))
+	}
+	metrics := []models.HealthMetric{
+		{Name: "heart_rate", Units: "count/min", Data: points},
+		{Name: "step_count", Units: "count", Data: points},
+	}
+	allowed := func(string) (bool, error) { return true, nil }
+
+	result := &ingest.Result{}
+	rows, _, err := p.acceptMetrics(metrics, nil, allowed, 1, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hr := 0
+	for _, r := range rows {
+		if r.MetricName == "heart_rate" {
+			hr++
+		}
+	}
+	if hr != 1 || len(rows) != 5 || result.MetricsCollapsed != 3 {
+		t.Errorf("heart_rate rows = %d, total = %d, collapsed = %d; want 1, 5, 3", hr, len(rows), result.MetricsCollapsed)
+	}
+}
+
+// TestAcceptMetricsMarksDailyAggregates verifies active_energy sent with
+// "Summarize Data" on is stored as daily rows while raw samples of the same
+// day, and hourly summaries starting at midnight, stay raw, so aggregation
+// can skip the daily total instead of adding it to the samples.
+func TestAcceptMetricsMarksDailyAggregates(t *testing.T) {
+	p := &Provider{log: slog.Default(), bounds: ingest.DefaultTimeBounds, units: ingest.NewUnitNormalizer(nil)}
+	allowed := func(string) (bool, error) { return true, nil }
+	payloads := map[string][]string{
+		"raw": {
+			`{"date":"2025-01-01 08:00:00 -0500","qty":1.5,"source_uuid":"7f1c2a4e-5b6d-4c8e-9f0a-1b2c3d4e5f60"}`,
+			`{"date":"2025-01-01 08:01:00 -0500","qty":2.5,"source_uuid":"8a2d3b5f-6c7e-4d9f-a01b-2c3d4e5f6071"}`,
+		},
+		"daily": {
+			`{"date":"2025-01-01 00:00:00 -0500","qty":640}`,
+			`{"date":"2025-01-02 00:00:00 -0500","qty":512}`,
+		},
+		"hourly": {
+			`{"date":"2025-01-01 00:00:00 -0500","qty":12}`,
+			`{"date":"2025-01-01 01:00:00 -0500","qty":9}`,
+		},
+	}
+	want := map[string]string{"raw": "", "daily": models.AggLevelDay, "hourly": ""}
+
+	for name, points := range payloads {
+		var data []json.RawMessage
+		for _, pt := range points {
+			data = append(data, json.RawMessage(pt))
+		}
+		metrics := []models.HealthMetric{{Name: "active_energy", Units: "kcal", Data: data}}
+		rows, _, err := p.acceptMetrics(metrics, nil, allowed, 1, &ingest.Result{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != len(points) {
+			t.Fatalf("%s: got %d rows, want %d", name, len(rows), len(points))
+		}
+		for _, r := range rows {
+			if r.AggLevel != want[name] {
+				t.Errorf("%s: row at %s has agg level %q, want %q", name, r.Time, r.AggLevel, want[name])
+			}
+		}
+	}
+}
+
+// TestAcceptMetricsRejectedPoints verifies a metric off the allowlist is
+// reported with its dropped point count for recording, and that once enabled
+// the same payload is accepted with nothing left to record.
+func TestAcceptMetricsRejectedPoints(t *testing.T) {
+	p := &Provider{log: slog.Default(), bounds: ingest.DefaultTimeBounds, units: ingest.NewUnitNormalizer(nil)}
+	enabled := map[string]bool{"step_count": true}
+	allowed := func(name string) (bool, error) { return enabled[name], nil }
+	point := json.RawMessage(`{"date":"2025-01-01 08:00:00 +0000","qty":3}`)
+	metrics := []models.HealthMetric{
+		{Name: "step_count", Units: "count", Data: []json.RawMessage{point}},
+		{Name: "walking_asymmetry_percentage", Units: "%", Data: []json.RawMessage{point, point}},
+	}
+
+	result := &ingest.Result{}
+	rows, _, err := p.acceptMetrics(metrics, nil, allowed, 1, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || result.RejectedPoints["walking_asymmetry_percentage"] != 2 || len(result.RejectedPoints) != 1 {
+		t.Errorf("rows = %d, rejected = %v; want 1 row and 2 walking_asymmetry_percentage points", len(rows), result.RejectedPoints)
+	}
+
+	enabled["walking_asymmetry_percentage"] = true
+	result = &ingest.Result{}
+	rows, _, err = p.acceptMetrics(metrics, nil, allowed, 1, result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || result.RejectedPoints != nil || result.MetricsRejected != 0 {
+		t.Errorf("after enabling: rows = %d, rejected = %v; want 3 rows and none rejected", len(rows), result.RejectedPoints)
+	}
+}
@@ -0,0 +1,183 @@
+package health
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/claude/freereps/internal/ingest"
+	"github.com/claude/freereps/internal/models"
+	"github.com/google/uuid"
+)
+
+// MergeSegments joins workouts of the same (normalized) type that start less
+// than gap after the previous one ended, which is how Apple records a
+// paused-and-resumed activity as several files. Durations, energy, distance,
+// elevation and strokes are summed, HR summaries combined (average weighted
+// by duration), and HR and route samples concatenated. The merged workout
+// keeps the first segment's ID, so re-importing the same payload is
+// idempotent, and lists every segment ID under "mergedSegments" in its raw
+// JSON; it is stored with storage.UpsertMergedWorkout, which replaces a first
+// segment synced earlier on its own and drops absorbed segments' rows. Segments with mismatched units aren't merged. Returns the workouts in
+// start order and the number of segments absorbed. A gap ≤ 0 disables
+// merging.
+func MergeSegments(workouts []models.HealthWorkout, gap time.Duration) ([]models.HealthWorkout, int) {
+	if gap <= 0 || len(workouts) < 2 {
+		return workouts, 0
+	}
+	sorted := append([]models.HealthWorkout(nil), workouts...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start.Before(sorted[j].Start.Time) })
+
+	var out []models.HealthWorkout
+	segments := map[int][]string{} // index in out → merged segment IDs
+	merged := 0
+	for _, w := range sorted {
+		// Look back for the latest workout of the same type; other types
+		// may be interleaved (e.g. a walk logged during a run's pause).
+		idx := -1
+		for i := len(out) - 1; i >= 0; i-- {
+			if ingest.NormalizeWorkoutName(out[i].Name) == ingest.NormalizeWorkoutName(w.Name) {
+				idx = i
+				break
+			}
+		}
+		if idx >= 0 {
+			prev := &out[idx]
+			pause := w.Start.Sub(prev.End.Time)
+			if pause >= 0 && pause < gap && unitsMatch(*prev, w) {
+				if segments[idx] == nil {
+					segments[idx] = []string{prev.ID}
+				}
+				segments[idx] = append(segments[idx], w.ID)
+				mergeWorkout(prev, w)
+				merged++
+				continue
+			}
+		}
+		out = append(out, w)
+	}
+	for i, ids := range segments {
+		out[i].RawJSON = withMergedSegments(out[i], ids)
+	}
+	return out, merged
+}
+
+// unitsMatch reports whether every summed quantity present on both workouts
+// uses the same unit.
+func unitsMatch(a, b models.HealthWorkout) bool {
+	pairs := [][2]*models.Quantity{
+		{a.ActiveEnergyBurned, b.ActiveEnergyBurned}, {a.TotalEnergy, b.TotalEnergy},
+		{a.Distance, b.Distance}, {a.ElevationUp, b.ElevationUp}, {a.ElevationDown, b.ElevationDown},
+	}
+	for _, p := range pairs {
+		if p[0] != nil && p[1] != nil && p[0].Units != p[1].Units {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeWorkout folds segment next into w.
+func mergeWorkout(w *models.HealthWorkout, next models.HealthWorkout) {
+	prevDuration := w.Duration
+	w.End = next.End
+	w.Duration += next.Duration
+
+	w.ActiveEnergyBurned = sumQty(w.ActiveEnergyBurned, next.ActiveEnergyBurned)
+	w.TotalEnergy = sumQty(w.TotalEnergy, next.TotalEnergy)
+	w.Distance = sumQty(w.Distance, next.Distance)
+	w.ElevationUp = sumQty(w.ElevationUp, next.ElevationUp)
+	w.ElevationDown = sumQty(w.ElevationDown, next.ElevationDown)
+	w.StrokeCount = sumQty(w.StrokeCount, next.StrokeCount)
+
+	switch {
+	case w.HeartRate != nil && next.HeartRate != nil:
+		hr := *w.HeartRate
+		hr.Min.Qty = min(hr.Min.Qty, next.HeartRate.Min.Qty)
+		hr.Max.Qty = max(hr.Max.Qty, next.HeartRate.Max.Qty)
+		if total := prevDuration + next.Duration; total > 0 {
+			hr.Avg.Qty = (hr.Avg.Qty*prevDuration + next.HeartRate.Avg.Qty*next.Duration) / total
+		}
+		w.HeartRate = &hr
+	case w.HeartRate == nil:
+		w.HeartRate = next.HeartRate
+	}
+	if w.AvgHR != nil && next.AvgHR != nil && prevDuration+next.Duration > 0 {
+		avg := *w.AvgHR
+		avg.Qty = (avg.Qty*prevDuration + next.AvgHR.Qty*next.Duration) / (prevDuration + next.Duration)
+		w.AvgHR = &avg
+	} else if w.AvgHR == nil {
+		w.AvgHR = next.AvgHR
+	}
+	if w.MaxHR == nil || (next.MaxHR != nil && next.MaxHR.Qty > w.MaxHR.Qty) {
+		w.MaxHR = next.MaxHR
+	}
+
+	w.HeartRateData = append(w.HeartRateData, next.HeartRateData...)
+	w.Route = append(w.Route, next.Route...)
+	w.StepCount = append(w.StepCount, next.StepCount...)
+	// Recovery is measured after the activity ends, i.e. after the last segment.
+	if len(next.HeartRateRecovery) > 0 {
+		w.HeartRateRecovery = next.HeartRateRecovery
+	}
+}
+
+// sumQty adds two optional quantities of the same unit.
+func sumQty(a, b *models.Quantity) *models.Quantity {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	}
+	return &models.Quantity{Qty: a.Qty + b.Qty, Units: a.Units}
+}
+
+// withMergedSegments returns w's raw JSON with the merged segment IDs added.
+// Summary fields in the raw JSON stay those of the first segment; the stored
+// columns hold the merged values.
+func withMergedSegments(w models.HealthWorkout, ids []string) json.RawMessage {
+	raw := w.RawJSON
+	if len(raw) == 0 {
+		raw, _ = json.Marshal(w)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return raw
+	}
+	b, err := json.Marshal(ids)
+	if err != nil {
+		return raw
+	}
+	fields["mergedSegments"] = b
+	out, err := json.Marshal(fields)
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+// segmentIDs returns the IDs of the segments merged into w, as recorded by
+// withMergedSegments, or just w's ID for an unmerged workout.
+func segmentIDs(w models.HealthWorkout) []string {
+	var fields struct {
+		MergedSegments []string `json:"mergedSegments"`
+	}
+	if len(w.RawJSON) > 0 && json.Unmarshal(w.RawJSON, &fields) == nil && len(fields.MergedSegments) > 0 {
+		return fields.MergedSegments
+	}
+	return []string{w.ID}
+}
+
+// absorbedSegmentIDs returns the IDs of the segments merged into w after its
+// first one, which is the ID w is stored under. Unparseable IDs are skipped.
+func absorbedSegmentIDs(w models.HealthWorkout) []uuid.UUID {
+	ids := segmentIDs(w)
+	var out []uuid.UUID
+	for _, id := range ids[1:] {
+		if u, err := uuid.Parse(id); err == nil {
+			out = append(out, u)
+		}
+	}
+	return out
+}
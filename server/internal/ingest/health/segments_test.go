@@ -0,0 +1,142 @@
+package health
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+	"github.com/google/uuid"
+)
+
+// TestMergeSegments verifies two adjacent running segments become one
+// workout with summed duration, energy and distance, combined HR, and
+// concatenated samples, while a run after a longer gap and a walk in between
+// stay separate.
+func TestMergeSegments(t *testing.T) {
+	t0 := time.Date(2025, 5, 3, 7, 0, 0, 0, time.UTC)
+	ht := func(d time.Duration) models.HealthTime { return models.HealthTime{Time: t0.Add(d)} }
+	q := func(v float64, u string) *models.Quantity { return &models.Quantity{Qty: v, Units: u} }
+	run := func(id string, start, end time.Duration, kcal, km, avgHR, maxHR float64) models.HealthWorkout {
+		return models.HealthWorkout{
+			ID: id, Name: "Outdoor Run", Start: ht(start), End: ht(end), Duration: (end - start).Seconds(),
+			ActiveEnergyBurned: q(kcal, "kcal"), Distance: q(km, "km"),
+			HeartRate: &models.HeartRateSummary{
+				Min: models.Quantity{Qty: avgHR - 30}, Avg: models.Quantity{Qty: avgHR}, Max: models.Quantity{Qty: maxHR},
+			},
+			HeartRateData: []models.WorkoutHRPoint{{Date: ht(start), Avg: avgHR}},
+			Route:         []models.RoutePoint{{Timestamp: ht(start)}},
+			RawJSON:       json.RawMessage(`{"id":"` + id + `"}`),
+		}
+	}
+	workouts := []models.HealthWorkout{
+		run("b", 21*time.Minute, 41*time.Minute, 200, 4, 160, 178),
+		run("a", 0, 20*time.Minute, 180, 3.5, 150, 170),
+		{ID: "w", Name: "Walking", Start: ht(41 * time.Minute), End: ht(50 * time.Minute), Duration: 540},
+		run("c", 3*time.Hour, 3*time.Hour+10*time.Minute, 90, 2, 140, 150),
+	}
+
+	got, merged := MergeSegments(workouts, 2*time.Minute)
+	if merged != 1 || len(got) != 3 {
+		t.Fatalf("got %d workouts, %d merged; want 3 and 1", len(got), merged)
+	}
+	m := got[0]
+	if m.ID != "a" || !m.End.Equal(t0.Add(41*time.Minute)) || m.Duration != 2400 {
+		t.Errorf("merged = id %s, end %s, duration %v; want a, 07:41, 2400", m.ID, m.End, m.Duration)
+	}
+	if m.ActiveEnergyBurned.Qty != 380 || m.Distance.Qty != 7.5 || m.Distance.Units != "km" {
+		t.Errorf("energy = %v, distance = %v; want 380 kcal, 7.5 km", m.ActiveEnergyBurned, m.Distance)
+	}
+	if m.HeartRate.Avg.Qty != 155 || m.HeartRate.Max.Qty != 178 || m.HeartRate.Min.Qty != 120 {
+		t.Errorf("HR = %+v, want avg 155, max 178, min 120", m.HeartRate)
+	}
+	if len(m.HeartRateData) != 2 || len(m.Route) != 2 {
+		t.Errorf("HR points = %d, route points = %d; want 2 each", len(m.HeartRateData), len(m.Route))
+	}
+	var raw struct {
+		ID       string   `json:"id"`
+		Segments []string `json:"mergedSegments"`
+	}
+	if err := json.Unmarshal(m.RawJSON, &raw); err != nil || raw.ID != "a" || len(raw.Segments) != 2 || raw.Segments[1] != "b" {
+		t.Errorf("raw JSON = %s, want segments [a b]", m.RawJSON)
+	}
+	if got[1].ID != "w" || got[2].ID != "c" {
+		t.Errorf("unmerged = %s, %s; want w, c", got[1].ID, got[2].ID)
+	}
+
+	if _, merged := MergeSegments(workouts, 0); merged != 0 {
+		t.Errorf("merged %d with merging off", merged)
+	}
+}
+
+// TestMergeSegmentsUnitMismatch verifies segments reporting distance in
+// different units are left apart rather than summed wrongly.
+func TestMergeSegmentsUnitMismatch(t *testing.T) {
+	t0 := time.Date(2025, 5, 3, 7, 0, 0, 0, time.UTC)
+	a := models.HealthWorkout{ID: "a", Name: "Running", Start: models.HealthTime{Time: t0}, End: models.HealthTime{Time: t0.Add(time.Minute)},
+		Distance: &models.Quantity{Qty: 1, Units: "km"}}
+	b := models.HealthWorkout{ID: "b", Name: "Running", Start: models.HealthTime{Time: t0.Add(90 * time.Second)}, End: models.HealthTime{Time: t0.Add(3 * time.Minute)},
+		Distance: &models.Quantity{Qty: 1, Units: "mi"}}
+	if got, merged := MergeSegments([]models.HealthWorkout{a, b}, time.Minute); merged != 0 || len(got) != 2 {
+		t.Errorf("got %d workouts, %d merged; want 2 and 0", len(got), merged)
+	}
+}
+
+// TestSegmentIDs verifies a merged workout confirms every segment it absorbed
+// and an unmerged one only itself.
+func TestSegmentIDs(t *testing.T) {
+	t0 := time.Date(2025, 5, 3, 7, 0, 0, 0, time.UTC)
+	seg := func(id string, start time.Duration) models.HealthWorkout {
+		return models.HealthWorkout{
+			ID: id, Name: "Outdoor Run",
+			Start: models.HealthTime{Time: t0.Add(start)}, End: models.HealthTime{Time: t0.Add(start + 10*time.Minute)},
+			RawJSON: json.RawMessage(`{"id":"` + id + `"}`),
+		}
+	}
+	got, _ := MergeSegments([]models.HealthWorkout{seg("a", 0), seg("b", 11*time.Minute), seg("c", 2*time.Hour)}, 2*time.Minute)
+	if len(got) != 2 {
+		t.Fatalf("got %d workouts, want 2", len(got))
+	}
+	if ids := segmentIDs(got[0]); len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Errorf("merged IDs = %v, want [a b]", ids)
+	}
+	if ids := segmentIDs(got[1]); len(ids) != 1 || ids[0] != "c" {
+		t.Errorf("unmerged IDs = %v, want [c]", ids)
+	}
+	if ids := segmentIDs(models.HealthWorkout{ID: "d"}); len(ids) != 1 || ids[0] != "d" {
+		t.Errorf("IDs without raw JSON = %v, want [d]", ids)
+	}
+}
+
+// TestAbsorbedSegmentIDsAfterPartialSync covers segment 1 being synced on its
+// own and a later payload holding both segments: the merged workout keeps
+// segment 1's ID, so it must overwrite that row rather than be skipped as a
+// duplicate, and segment 2 is reported for deletion in case it was stored
+// separately too. Unmerged workouts absorb nothing.
+func TestAbsorbedSegmentIDsAfterPartialSync(t *testing.T) {
+	t0 := time.Date(2025, 5, 3, 7, 0, 0, 0, time.UTC)
+	first, second := uuid.New(), uuid.New()
+	seg := func(id uuid.UUID, start time.Duration, km float64) models.HealthWorkout {
+		return models.HealthWorkout{
+			ID: id.String(), Name: "Outdoor Run",
+			Start: models.HealthTime{Time: t0.Add(start)}, End: models.HealthTime{Time: t0.Add(start + 10*time.Minute)},
+			Duration: 600, Distance: &models.Quantity{Qty: km, Units: "km"},
+			RawJSON: json.RawMessage(`{"id":"` + id.String() + `"}`),
+		}
+	}
+
+	// First sync: segment 1 alone is an ordinary workout.
+	alone, _ := MergeSegments([]models.HealthWorkout{seg(first, 0, 2)}, 2*time.Minute)
+	if got := absorbedSegmentIDs(alone[0]); len(got) != 0 {
+		t.Errorf("lone segment absorbs %v", got)
+	}
+
+	// Second sync: both segments arrive and merge under segment 1's ID.
+	both, _ := MergeSegments([]models.HealthWorkout{seg(second, 11*time.Minute, 1.5), seg(first, 0, 2)}, 2*time.Minute)
+	if len(both) != 1 || both[0].ID != first.String() || both[0].Distance.Qty != 3.5 {
+		t.Fatalf("merged = %+v, want one workout under the first segment with 3.5 km", both)
+	}
+	if got := absorbedSegmentIDs(both[0]); len(got) != 1 || got[0] != second {
+		t.Errorf("absorbed = %v, want [%s]", got, second)
+	}
+}
@@ -1,6 +1,10 @@
 package health
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/claude/freereps/internal/models"
+)
 
 // MetricShape describes the data point structure for a metric.
 type MetricShape int
@@ -46,3 +50,30 @@ func DetectSleepFormat(raw json.RawMessage) SleepFormat {
 	}
 	return SleepFormatAggregated // fallback
 }
+
+// DailyAggregated reports whether a metric's converted points are daily
+// aggregates, as sent with "Summarize Data" on: at least two points, every
+// one stamped at local midnight on a distinct day, and none carrying a
+// sample UUID. Hourly summaries and raw samples have points at other times.
+// A single midnight point could be a raw sample, so it is left raw.
+func DailyAggregated(rows []models.HealthMetricRow) bool {
+	if len(rows) < 2 {
+		return false
+	}
+	days := make(map[string]bool, len(rows))
+	for _, r := range rows {
+		if r.SourceUUID != nil {
+			return false
+		}
+		h, m, s := r.Time.Clock()
+		if h != 0 || m != 0 || s != 0 || r.Time.Nanosecond() != 0 {
+			return false
+		}
+		day := r.Time.Format("2006-01-02")
+		if days[day] {
+			return false
+		}
+		days[day] = true
+	}
+	return true
+}
@@ -2,7 +2,13 @@ package health
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/ingest"
+	"github.com/claude/freereps/internal/models"
+	"github.com/google/uuid"
 )
 
 // TestDetectMetricShapeHeartRate verifies that heart_rate is detected as Min/Avg/Max shape.
@@ -50,7 +56,7 @@ func TestDetectSleepFormatUnaggregated(t *testing.T) {
 // TestConvertMetricQty verifies conversion of a standard qty metric data point.
 func TestConvertMetricQty(t *testing.T) {
 	raw := json.RawMessage(`{"date":"2024-02-06 14:30:00 -0800","qty":58}`)
-	row, err := convertMetricDataPoint("resting_heart_rate", "bpm", raw, 1)
+	row, err := convertMetricDataPoint("resting_heart_rate", "bpm", raw, 1, ingest.DefaultTimeBounds)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -65,7 +71,7 @@ func TestConvertMetricQty(t *testing.T) {
 // TestConvertMetricMinAvgMax verifies conversion of heart rate (Min/Avg/Max) data.
 func TestConvertMetricMinAvgMax(t *testing.T) {
 	raw := json.RawMessage(`{"date":"2024-02-06 14:30:00 -0800","Min":65,"Avg":72,"Max":85}`)
-	row, err := convertMetricDataPoint("heart_rate", "bpm", raw, 1)
+	row, err := convertMetricDataPoint("heart_rate", "bpm", raw, 1, ingest.DefaultTimeBounds)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -88,7 +94,7 @@ func TestConvertMetricMinAvgMax(t *testing.T) {
 // This happens when the iOS app's aggregation fails and falls back to per-sample sync.
 func TestConvertMetricMinAvgMaxFallbackFromQty(t *testing.T) {
 	raw := json.RawMessage(`{"date":"2024-02-06 14:30:00 -0800","qty":72,"source_uuid":"abc-123"}`)
-	row, err := convertMetricDataPoint("heart_rate", "bpm", raw, 1)
+	row, err := convertMetricDataPoint("heart_rate", "bpm", raw, 1, ingest.DefaultTimeBounds)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -106,7 +112,7 @@ func TestConvertMetricMinAvgMaxFallbackFromQty(t *testing.T) {
 // TestConvertMetricBloodPressure verifies conversion of blood pressure data.
 func TestConvertMetricBloodPressure(t *testing.T) {
 	raw := json.RawMessage(`{"date":"2024-02-06 14:30:00 -0800","systolic":120,"diastolic":80}`)
-	row, err := convertMetricDataPoint("blood_pressure", "mmHg", raw, 1)
+	row, err := convertMetricDataPoint("blood_pressure", "mmHg", raw, 1, ingest.DefaultTimeBounds)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -117,3 +123,55 @@ func TestConvertMetricBloodPressure(t *testing.T) {
 		t.Errorf("diastolic = %v, want 80", row.Diastolic)
 	}
 }
+
+// TestConvertMetricRejectsFutureTimestamp verifies that a year-3000 timestamp
+// from a buggy source is rejected with ErrTimeOutOfRange instead of stored.
+func TestConvertMetricRejectsFutureTimestamp(t *testing.T) {
+	raw := json.RawMessage(`{"date":"3000-01-01 00:00:00 +0000","qty":58}`)
+	row, err := convertMetricDataPoint("resting_heart_rate", "bpm", raw, 1, ingest.DefaultTimeBounds)
+	if !errors.Is(err, ingest.ErrTimeOutOfRange) {
+		t.Fatalf("err = %v, want ErrTimeOutOfRange", err)
+	}
+	if row != nil {
+		t.Errorf("row = %+v, want nil", row)
+	}
+}
+
+// TestConvertMetricRejectsPreHealthKitTimestamp verifies that timestamps before
+// the configured earliest date (default 2014) are rejected.
+func TestConvertMetricRejectsPreHealthKitTimestamp(t *testing.T) {
+	raw := json.RawMessage(`{"date":"2001-01-01 00:00:00 +0000","Min":60,"Avg":60,"Max":60}`)
+	if _, err := convertMetricDataPoint("heart_rate", "bpm", raw, 1, ingest.DefaultTimeBounds); !errors.Is(err, ingest.ErrTimeOutOfRange) {
+		t.Fatalf("err = %v, want ErrTimeOutOfRange", err)
+	}
+}
+
+// TestDailyAggregated verifies only two or more midnight points on distinct
+// days without sample UUIDs are treated as daily aggregates.
+func TestDailyAggregated(t *testing.T) {
+	loc := time.FixedZone("", -5*3600)
+	midnight := func(day int) models.HealthMetricRow {
+		return models.HealthMetricRow{Time: time.Date(2025, 1, day, 0, 0, 0, 0, loc)}
+	}
+	withUUID := midnight(3)
+	id := uuid.New()
+	withUUID.SourceUUID = &id
+
+	tests := []struct {
+		name string
+		rows []models.HealthMetricRow
+		want bool
+	}{
+		{"empty", nil, false},
+		{"single midnight point", []models.HealthMetricRow{midnight(1)}, false},
+		{"daily", []models.HealthMetricRow{midnight(1), midnight(2)}, true},
+		{"same day twice", []models.HealthMetricRow{midnight(1), midnight(1)}, false},
+		{"sample uuid", []models.HealthMetricRow{midnight(1), withUUID}, false},
+		{"not midnight", []models.HealthMetricRow{midnight(1), {Time: time.Date(2025, 1, 2, 0, 0, 30, 0, loc)}}, false},
+	}
+	for _, tt := range tests {
+		if got := DailyAggregated(tt.rows); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -7,25 +7,45 @@ type Result struct {
 	MetricsSkipped  int64    `json:"metrics_skipped"`
 	MetricsRejected int      `json:"metrics_rejected"`
 	RejectedNames   []string `json:"rejected_names,omitempty"`
+	// RejectedPoints counts dropped data points per rejected metric name.
+	RejectedPoints map[string]int `json:"-"`
 
-	SleepSessionsInserted int `json:"sleep_sessions_inserted,omitempty"`
+	MetricsOutOfRange int `json:"metrics_out_of_range,omitempty"`
+	MetricsCollapsed  int `json:"metrics_collapsed,omitempty"` // repeats dropped by CollapseRepeats
+
+	SleepSessionsInserted int   `json:"sleep_sessions_inserted,omitempty"`
 	SleepStagesInserted   int64 `json:"sleep_stages_inserted,omitempty"`
 
-	WorkoutsReceived int   `json:"workouts_received,omitempty"`
-	WorkoutsInserted int   `json:"workouts_inserted,omitempty"`
-	WorkoutHRPoints  int64 `json:"workout_hr_points,omitempty"`
-	WorkoutRoutePoints int64 `json:"workout_route_points,omitempty"`
+	WorkoutsReceived        int   `json:"workouts_received,omitempty"`
+	WorkoutsInserted        int   `json:"workouts_inserted,omitempty"`
+	WorkoutsMerged          int   `json:"workouts_merged,omitempty"` // segments joined by health.MergeSegments
+	WorkoutHRPoints         int64 `json:"workout_hr_points,omitempty"`
+	WorkoutHRRecoveryPoints int64 `json:"workout_hr_recovery_points,omitempty"`
+	WorkoutRoutePoints      int64 `json:"workout_route_points,omitempty"`
+	WorkoutPowerPoints      int64 `json:"workout_power_points,omitempty"`
+	WorkoutCadencePoints    int64 `json:"workout_cadence_points,omitempty"`
+	// WorkoutIDs lists the workouts now stored (inserted or already present),
+	// including segments merged into another, so clients can confirm each.
+	WorkoutIDs []string `json:"workout_ids,omitempty"`
 
 	SetsReceived int   `json:"sets_received"`
 	SetsInserted int64 `json:"sets_inserted"`
+	SetsSkipped  int64 `json:"sets_skipped"`
+	SetsLinked   int64 `json:"sets_linked,omitempty"`
+
+	ECGRecordingsInserted       int   `json:"ecg_recordings_inserted,omitempty"`
+	AudiogramsInserted          int   `json:"audiograms_inserted,omitempty"`
+	ActivitySummariesInserted   int64 `json:"activity_summaries_inserted,omitempty"`
+	MedicationsInserted         int   `json:"medications_inserted,omitempty"`
+	VisionPrescriptionsInserted int   `json:"vision_prescriptions_inserted,omitempty"`
+	StateOfMindInserted         int64 `json:"state_of_mind_inserted,omitempty"`
+	CategorySamplesInserted     int64 `json:"category_samples_inserted,omitempty"`
+	SymptomsInserted            int64 `json:"symptoms_inserted,omitempty"`
 
-	ECGRecordingsInserted    int   `json:"ecg_recordings_inserted,omitempty"`
-	AudiogramsInserted       int   `json:"audiograms_inserted,omitempty"`
-	ActivitySummariesInserted int64 `json:"activity_summaries_inserted,omitempty"`
-	MedicationsInserted      int   `json:"medications_inserted,omitempty"`
-	VisionPrescriptionsInserted int `json:"vision_prescriptions_inserted,omitempty"`
-	StateOfMindInserted      int64 `json:"state_of_mind_inserted,omitempty"`
-	CategorySamplesInserted  int64 `json:"category_samples_inserted,omitempty"`
+	// DryRun marks a validation result; nothing was written and
+	// MetricsAccepted counts the points that would have been stored.
+	DryRun          bool `json:"dry_run,omitempty"`
+	MetricsAccepted int  `json:"metrics_accepted,omitempty"`
 
 	Message string `json:"message,omitempty"`
 }
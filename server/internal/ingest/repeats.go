@@ -0,0 +1,70 @@
+package ingest
+
+import (
+	"sort"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// CollapseRepeats drops metric rows that repeat the row before them: same
+// metric, source, units and values, at most the metric's window later. Only
+// metrics listed in windows are collapsed. A run of identical
+// readings keeps only its first row, since each is compared with its
+// predecessor rather than the last kept row. Rows are compared in time order
+// per metric and source; kept rows stay in their input order. Returns the
+// kept rows and how many were dropped.
+func CollapseRepeats(rows []models.HealthMetricRow, windows map[string]time.Duration) ([]models.HealthMetricRow, int) {
+	if len(windows) == 0 || len(rows) < 2 {
+		return rows, 0
+	}
+
+	type key struct{ metric, source string }
+	groups := map[key][]int{}
+	for i, r := range rows {
+		if windows[r.MetricName] <= 0 {
+			continue
+		}
+		k := key{r.MetricName, r.Source}
+		groups[k] = append(groups[k], i)
+	}
+
+	drop := make([]bool, len(rows))
+	dropped := 0
+	for k, idx := range groups {
+		window := windows[k.metric]
+		sort.SliceStable(idx, func(a, b int) bool { return rows[idx[a]].Time.Before(rows[idx[b]].Time) })
+		for j := 1; j < len(idx); j++ {
+			prev, cur := rows[idx[j-1]], rows[idx[j]]
+			if cur.Time.Sub(prev.Time) <= window && sameReading(prev, cur) {
+				drop[idx[j]] = true
+				dropped++
+			}
+		}
+	}
+	if dropped == 0 {
+		return rows, 0
+	}
+
+	kept := make([]models.HealthMetricRow, 0, len(rows)-dropped)
+	for i, r := range rows {
+		if !drop[i] {
+			kept = append(kept, r)
+		}
+	}
+	return kept, dropped
+}
+
+// sameReading reports whether two rows carry the same units and values.
+func sameReading(a, b models.HealthMetricRow) bool {
+	return a.Units == b.Units &&
+		samePtr(a.Qty, b.Qty) && samePtr(a.MinVal, b.MinVal) && samePtr(a.AvgVal, b.AvgVal) &&
+		samePtr(a.MaxVal, b.MaxVal) && samePtr(a.Systolic, b.Systolic) && samePtr(a.Diastolic, b.Diastolic)
+}
+
+func samePtr(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
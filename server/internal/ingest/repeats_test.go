@@ -0,0 +1,47 @@
+package ingest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// TestCollapseRepeats verifies a run of identical readings one second apart
+// collapses to its first row, while changed values, other sources, gaps
+// beyond the window, and metrics not opted in are kept.
+func TestCollapseRepeats(t *testing.T) {
+	base := time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC)
+	row := func(sec int, source string, v float64) models.HealthMetricRow {
+		return models.HealthMetricRow{
+			Time: base.Add(time.Duration(sec) * time.Second), MetricName: "heart_rate",
+			Source: source, Units: "count/min", Qty: &v,
+		}
+	}
+
+	windows := map[string]time.Duration{"heart_rate": 2 * time.Second}
+	run := []models.HealthMetricRow{row(0, "Watch", 60), row(1, "Watch", 60), row(2, "Watch", 60), row(3, "Watch", 60), row(4, "Watch", 60)}
+	kept, dropped := CollapseRepeats(run, windows)
+	if len(kept) != 1 || dropped != 4 || !kept[0].Time.Equal(base) {
+		t.Fatalf("run: kept %d (dropped %d), want only the first row", len(kept), dropped)
+	}
+
+	mixed := []models.HealthMetricRow{
+		row(2, "Watch", 60), // out of order: still the repeat of 0s
+		row(0, "Watch", 60),
+		row(1, "Phone", 60),  // other source
+		row(3, "Watch", 61),  // changed value
+		row(10, "Watch", 61), // beyond the window
+	}
+	kept, dropped = CollapseRepeats(mixed, windows)
+	if dropped != 1 || len(kept) != 4 {
+		t.Fatalf("mixed: kept %d (dropped %d), want 4 (1)", len(kept), dropped)
+	}
+	if !kept[0].Time.Equal(base) || kept[1].Source != "Phone" {
+		t.Errorf("mixed: kept rows out of input order: %+v", kept)
+	}
+
+	if kept, dropped := CollapseRepeats(run, map[string]time.Duration{"step_count": time.Minute}); len(kept) != len(run) || dropped != 0 {
+		t.Errorf("metric not opted in: dropped %d rows", dropped)
+	}
+}
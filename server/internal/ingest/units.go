@@ -0,0 +1,149 @@
+package ingest
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// DefaultCanonicalUnits maps metric names to the unit values are stored in.
+// These match the display units seeded in metric_allowlist so no display
+// conversion is needed. Metrics not listed are stored as received.
+var DefaultCanonicalUnits = map[string]string{
+	"weight_body_mass":                 "kg",
+	"lean_body_mass":                   "kg",
+	"height":                           "cm",
+	"body_temperature":                 "degC",
+	"basal_body_temperature":           "degC",
+	"apple_sleeping_wrist_temperature": "degC",
+	"active_energy":                    "kcal",
+	"basal_energy_burned":              "kcal",
+	"dietary_energy_consumed":          "kcal",
+	"distance_walking_running":         "km",
+	"distance_cycling":                 "km",
+	"distance_wheelchair":              "km",
+	"distance_downhill_snow_sports":    "km",
+	"distance_swimming":                "m",
+}
+
+// unitAliases folds the various spellings sources use onto one name.
+var unitAliases = map[string]string{
+	"°c":          "degC",
+	"degc":        "degC",
+	"°f":          "degF",
+	"degf":        "degF",
+	"lbs":         "lb",
+	"pound":       "lb",
+	"pounds":      "lb",
+	"kilogram":    "kg",
+	"kilograms":   "kg",
+	"cal":         "kcal",
+	"kilocalorie": "kcal",
+	"miles":       "mi",
+	"mile":        "mi",
+	"yards":       "yd",
+	"feet":        "ft",
+	"inches":      "in",
+}
+
+// canonicalUnitName returns the normalized spelling of a unit string.
+func canonicalUnitName(u string) string {
+	u = strings.TrimSpace(u)
+	if alias, ok := unitAliases[strings.ToLower(u)]; ok {
+		return alias
+	}
+	return u
+}
+
+// unitConversions maps "from→to" to a conversion function.
+var unitConversions = map[[2]string]func(float64) float64{
+	{"lb", "kg"}:     func(v float64) float64 { return v * 0.45359237 },
+	{"kg", "lb"}:     func(v float64) float64 { return v / 0.45359237 },
+	{"g", "kg"}:      func(v float64) float64 { return v / 1000 },
+	{"mi", "km"}:     func(v float64) float64 { return v * 1.609344 },
+	{"km", "mi"}:     func(v float64) float64 { return v / 1.609344 },
+	{"m", "km"}:      func(v float64) float64 { return v / 1000 },
+	{"km", "m"}:      func(v float64) float64 { return v * 1000 },
+	{"mi", "m"}:      func(v float64) float64 { return v * 1609.344 },
+	{"yd", "m"}:      func(v float64) float64 { return v * 0.9144 },
+	{"yd", "km"}:     func(v float64) float64 { return v * 0.0009144 },
+	{"ft", "m"}:      func(v float64) float64 { return v * 0.3048 },
+	{"m", "cm"}:      func(v float64) float64 { return v * 100 },
+	{"in", "cm"}:     func(v float64) float64 { return v * 2.54 },
+	{"ft", "cm"}:     func(v float64) float64 { return v * 30.48 },
+	{"kJ", "kcal"}:   func(v float64) float64 { return v / 4.184 },
+	{"kcal", "kJ"}:   func(v float64) float64 { return v * 4.184 },
+	{"degF", "degC"}: func(v float64) float64 { return (v - 32) * 5 / 9 },
+	{"degC", "degF"}: func(v float64) float64 { return v*9/5 + 32 },
+}
+
+// ConvertUnit converts v between two units, accepting any spelling in
+// unitAliases. Reports false when no conversion is known.
+func ConvertUnit(v float64, from, to string) (float64, bool) {
+	from, to = canonicalUnitName(from), canonicalUnitName(to)
+	if from == to {
+		return v, true
+	}
+	conv, ok := unitConversions[[2]string{from, to}]
+	if !ok {
+		return 0, false
+	}
+	return conv(v), true
+}
+
+// UnitNormalizer converts metric rows to a canonical unit per metric so that
+// aggregations stay meaningful when sources report in different units.
+// A nil *UnitNormalizer is valid and leaves rows untouched.
+type UnitNormalizer struct {
+	canonical map[string]string
+}
+
+// NewUnitNormalizer returns a normalizer using DefaultCanonicalUnits, with
+// entries in overrides replacing or extending the defaults.
+func NewUnitNormalizer(overrides map[string]string) *UnitNormalizer {
+	canonical := make(map[string]string, len(DefaultCanonicalUnits)+len(overrides))
+	for k, v := range DefaultCanonicalUnits {
+		canonical[k] = v
+	}
+	for k, v := range overrides {
+		canonical[k] = canonicalUnitName(v)
+	}
+	return &UnitNormalizer{canonical: canonical}
+}
+
+// Normalize converts row in place to its metric's canonical unit, recording
+// the original unit and qty in RawUnits/RawQty. Reports whether a conversion
+// happened. Returns an error (leaving the row unchanged) when the row's unit
+// differs from the canonical one and no conversion is known.
+func (n *UnitNormalizer) Normalize(row *models.HealthMetricRow) (bool, error) {
+	if n == nil {
+		return false, nil
+	}
+	target, ok := n.canonical[row.MetricName]
+	if !ok || row.Units == "" {
+		return false, nil
+	}
+	from := canonicalUnitName(row.Units)
+	if from == target {
+		return false, nil
+	}
+	conv, ok := unitConversions[[2]string{from, target}]
+	if !ok {
+		return false, fmt.Errorf("no conversion from %q to %q for %s", row.Units, target, row.MetricName)
+	}
+
+	row.RawUnits = row.Units
+	if row.Qty != nil {
+		raw := *row.Qty
+		row.RawQty = &raw
+	}
+	for _, v := range []**float64{&row.Qty, &row.MinVal, &row.AvgVal, &row.MaxVal} {
+		if *v != nil {
+			converted := conv(**v)
+			*v = &converted
+		}
+	}
+	row.Units = target
+	return true, nil
+}
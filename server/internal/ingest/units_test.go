@@ -0,0 +1,118 @@
+package ingest
+
+import (
+	"math"
+	"testing"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+func ptr(f float64) *float64 { return &f }
+
+// TestNormalizeLbToKg verifies that weight reported in pounds is stored in kg
+// with the original value and unit preserved for auditing.
+func TestNormalizeLbToKg(t *testing.T) {
+	n := NewUnitNormalizer(nil)
+	row := models.HealthMetricRow{MetricName: "weight_body_mass", Units: "lb", Qty: ptr(176.37)}
+
+	converted, err := n.Normalize(&row)
+	if err != nil || !converted {
+		t.Fatalf("Normalize = (%v, %v), want (true, nil)", converted, err)
+	}
+	if row.Units != "kg" {
+		t.Errorf("units = %q, want kg", row.Units)
+	}
+	if math.Abs(*row.Qty-80.0) > 0.01 {
+		t.Errorf("qty = %v, want ~80.0", *row.Qty)
+	}
+	if row.RawUnits != "lb" || row.RawQty == nil || *row.RawQty != 176.37 {
+		t.Errorf("raw = (%q, %v), want (lb, 176.37)", row.RawUnits, row.RawQty)
+	}
+}
+
+// TestNormalizeMiToKm verifies that distance in miles is converted to km so
+// daily sums across sources add up in one unit.
+func TestNormalizeMiToKm(t *testing.T) {
+	n := NewUnitNormalizer(nil)
+	row := models.HealthMetricRow{MetricName: "distance_walking_running", Units: "mi", Qty: ptr(3.1)}
+
+	if _, err := n.Normalize(&row); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if row.Units != "km" || math.Abs(*row.Qty-4.989) > 0.001 {
+		t.Errorf("got %v %s, want ~4.989 km", *row.Qty, row.Units)
+	}
+}
+
+// TestNormalizeCanonicalUnchanged verifies rows already in the canonical unit
+// (including alias spellings) are left alone with no raw values recorded.
+func TestNormalizeCanonicalUnchanged(t *testing.T) {
+	n := NewUnitNormalizer(nil)
+	for _, units := range []string{"kg", "kilograms"} {
+		row := models.HealthMetricRow{MetricName: "weight_body_mass", Units: units, Qty: ptr(80)}
+		converted, err := n.Normalize(&row)
+		if err != nil || converted {
+			t.Errorf("Normalize(%q) = (%v, %v), want (false, nil)", units, converted, err)
+		}
+		if *row.Qty != 80 || row.RawQty != nil {
+			t.Errorf("row modified for %q: %+v", units, row)
+		}
+	}
+}
+
+// TestNormalizeMinAvgMax verifies every value column is converted, not just qty.
+func TestNormalizeMinAvgMax(t *testing.T) {
+	n := NewUnitNormalizer(map[string]string{"body_temperature": "degC"})
+	row := models.HealthMetricRow{MetricName: "body_temperature", Units: "°F", MinVal: ptr(212), AvgVal: ptr(32), MaxVal: ptr(212)}
+
+	if _, err := n.Normalize(&row); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *row.MinVal != 100 || *row.AvgVal != 0 || *row.MaxVal != 100 {
+		t.Errorf("got min=%v avg=%v max=%v, want 100/0/100", *row.MinVal, *row.AvgVal, *row.MaxVal)
+	}
+}
+
+// TestNormalizeUnknownConversion verifies an unconvertible unit leaves the row
+// untouched and reports an error so ingest can log and store as received.
+func TestNormalizeUnknownConversion(t *testing.T) {
+	n := NewUnitNormalizer(nil)
+	row := models.HealthMetricRow{MetricName: "weight_body_mass", Units: "stone", Qty: ptr(12)}
+
+	if _, err := n.Normalize(&row); err == nil {
+		t.Fatal("expected error for unknown conversion")
+	}
+	if row.Units != "stone" || *row.Qty != 12 {
+		t.Errorf("row modified: %+v", row)
+	}
+}
+
+// TestNormalizeOverrideAndNil verifies config overrides replace defaults and
+// that a nil normalizer is a no-op.
+func TestNormalizeOverrideAndNil(t *testing.T) {
+	n := NewUnitNormalizer(map[string]string{"weight_body_mass": "pounds"})
+	row := models.HealthMetricRow{MetricName: "weight_body_mass", Units: "kg", Qty: ptr(1)}
+	if _, err := n.Normalize(&row); err != nil || row.Units != "lb" {
+		t.Errorf("override not applied: units=%q err=%v", row.Units, err)
+	}
+
+	var none *UnitNormalizer
+	row = models.HealthMetricRow{MetricName: "weight_body_mass", Units: "lb", Qty: ptr(1)}
+	if converted, err := none.Normalize(&row); converted || err != nil || row.Units != "lb" {
+		t.Errorf("nil normalizer modified row: %+v", row)
+	}
+}
+
+// TestConvertUnit verifies aliased spellings convert and identical units pass
+// through, while unknown pairs are reported rather than silently returned.
+func TestConvertUnit(t *testing.T) {
+	if v, ok := ConvertUnit(25, "yards", "m"); !ok || v < 22.85 || v > 22.87 {
+		t.Errorf("25 yards = %v (ok=%v), want ~22.86 m", v, ok)
+	}
+	if v, ok := ConvertUnit(50, "m", "m"); !ok || v != 50 {
+		t.Errorf("50 m → m = %v (ok=%v)", v, ok)
+	}
+	if _, ok := ConvertUnit(1, "kg", "m"); ok {
+		t.Error("kg → m should not convert")
+	}
+}
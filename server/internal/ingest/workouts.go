@@ -1,5 +1,7 @@
 package ingest
 
+import "strings"
+
 // workoutNameMap normalizes workout names from various sources to canonical English names.
 // Handles German translations (Apple Health), Indoor/Outdoor prefixes, and Oura lowercase names.
 // The is_indoor field on the workout already captures indoor/outdoor distinction.
@@ -64,3 +66,9 @@ func NormalizeWorkoutName(name string) string {
 	}
 	return name
 }
+
+// IsSwimWorkout reports whether a (normalized or raw) workout name is a swim.
+// Swim-only fields are parsed only for these so other workouts are unaffected.
+func IsSwimWorkout(name string) bool {
+	return strings.Contains(strings.ToLower(NormalizeWorkoutName(name)), "swim")
+}
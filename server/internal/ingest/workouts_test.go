@@ -39,3 +39,18 @@ func TestNormalizeWorkoutName(t *testing.T) {
 		}
 	}
 }
+
+// TestIsSwimWorkout verifies swim detection covers pool/open-water and
+// localized names, but not workouts that merely share letters.
+func TestIsSwimWorkout(t *testing.T) {
+	for _, name := range []string{"Swimming", "Pool Swim", "Open Water Swim", "Schwimmbad Schwimmen", "swimming"} {
+		if !IsSwimWorkout(name) {
+			t.Errorf("IsSwimWorkout(%q) = false, want true", name)
+		}
+	}
+	for _, name := range []string{"Running", "Rowing", "Walking"} {
+		if IsSwimWorkout(name) {
+			t.Errorf("IsSwimWorkout(%q) = true, want false", name)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/claude/freereps/internal/storage"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
@@ -24,7 +25,7 @@ func (h *handlers) dailySummary(ctx context.Context, req mcp.ReadResourceRequest
 		return nil, err
 	}
 
-	sums, err := h.ds.GetDailySums(ctx, uid, cumulativeMetrics)
+	sums, err := h.ds.GetDailySums(ctx, uid, cumulativeMetrics, storage.DailySumsLatest)
 	if err != nil {
 		h.log.Warn("daily_summary: daily sums failed", "error", err)
 	}
@@ -38,7 +39,7 @@ func (h *handlers) dailySummary(ctx context.Context, req mcp.ReadResourceRequest
 		h.log.Warn("daily_summary: sleep query failed", "error", err)
 	}
 
-	workouts, err := h.ds.QueryWorkouts(ctx, today, tomorrow, uid, "")
+	workouts, err := h.ds.QueryWorkouts(ctx, today, tomorrow, uid, storage.WorkoutFilter{})
 	if err != nil {
 		h.log.Warn("daily_summary: workout query failed", "error", err)
 	}
@@ -70,7 +71,7 @@ func (h *handlers) recentWorkouts(ctx context.Context, req mcp.ReadResourceReque
 	end := time.Now()
 	start := end.AddDate(0, 0, -14)
 
-	workouts, err := h.ds.QueryWorkouts(ctx, start, end, uid, "")
+	workouts, err := h.ds.QueryWorkouts(ctx, start, end, uid, storage.WorkoutFilter{})
 	if err != nil {
 		return nil, err
 	}
@@ -39,15 +39,37 @@ func New(ds *storage.DB, version string, log *slog.Logger) *server.MCPServer {
 	// Tools
 	s.AddTools(
 		server.ServerTool{Tool: toolGetHealthMetrics, Handler: h.getHealthMetrics},
+		server.ServerTool{Tool: toolGetMultipleMetrics, Handler: h.getMultipleMetrics},
 		server.ServerTool{Tool: toolGetMetricStats, Handler: h.getMetricStats},
 		server.ServerTool{Tool: toolGetCorrelation, Handler: h.getCorrelation},
+		server.ServerTool{Tool: toolFindCorrelationsWith, Handler: h.findCorrelationsWith},
 		server.ServerTool{Tool: toolGetSleepData, Handler: h.getSleepData},
+		server.ServerTool{Tool: toolGetSleepNight, Handler: h.getSleepNight},
+		server.ServerTool{Tool: toolGetSleepArchitecture, Handler: h.getSleepArchitecture},
+		server.ServerTool{Tool: toolGetSleepDebt, Handler: h.getSleepDebt},
 		server.ServerTool{Tool: toolGetWorkouts, Handler: h.getWorkouts},
 		server.ServerTool{Tool: toolGetWorkoutSets, Handler: h.getWorkoutSets},
+		server.ServerTool{Tool: toolGetExerciseReport, Handler: h.getExerciseReport},
+		server.ServerTool{Tool: toolGetActivityCalendar, Handler: h.getActivityCalendar},
+		server.ServerTool{Tool: toolGetWorkoutConditions, Handler: h.getWorkoutConditions},
+		server.ServerTool{Tool: toolGetWorkoutIntervals, Handler: h.getWorkoutIntervals},
+		server.ServerTool{Tool: toolGetWorkoutPower, Handler: h.getWorkoutPower},
+		server.ServerTool{Tool: toolGetWorkoutCadence, Handler: h.getWorkoutCadence},
+		server.ServerTool{Tool: toolGetPaceByTemperature, Handler: h.getPaceByTemperature},
+		server.ServerTool{Tool: toolGetSwimStats, Handler: h.getSwimStats},
+		server.ServerTool{Tool: toolGetDailySteps, Handler: h.getDailySteps},
+		server.ServerTool{Tool: toolGetDailySums, Handler: h.getDailySums},
+		server.ServerTool{Tool: toolGetMetricInfo, Handler: h.getMetricInfo},
+		server.ServerTool{Tool: toolGetDataQuality, Handler: h.getDataQuality},
 		server.ServerTool{Tool: toolListAvailableMetrics, Handler: h.listAvailableMetrics},
 		server.ServerTool{Tool: toolComparePeriods, Handler: h.comparePeriods},
 		server.ServerTool{Tool: toolGetTrainingSummary, Handler: h.getTrainingSummary},
 		server.ServerTool{Tool: toolGetTrainingIntensity, Handler: h.getTrainingIntensity},
+		server.ServerTool{Tool: toolGetIntensityTrend, Handler: h.getIntensityTrend},
+		server.ServerTool{Tool: toolGetMuscleGroupVolume, Handler: h.getMuscleGroupVolume},
+		server.ServerTool{Tool: toolDetectIllnessSignals, Handler: h.detectIllnessSignals},
+		server.ServerTool{Tool: toolGetWristTempDeviation, Handler: h.getWristTempDeviation},
+		server.ServerTool{Tool: toolGetMetricPercentileContext, Handler: h.getMetricPercentileContext},
 		server.ServerTool{Tool: toolGetSleepSummary, Handler: h.getSleepSummary},
 		server.ServerTool{Tool: toolGetECGRecordings, Handler: h.getECGRecordings},
 		server.ServerTool{Tool: toolGetAudiograms, Handler: h.getAudiograms},
@@ -56,6 +78,7 @@ func New(ds *storage.DB, version string, log *slog.Logger) *server.MCPServer {
 		server.ServerTool{Tool: toolGetVisionPrescriptions, Handler: h.getVisionPrescriptions},
 		server.ServerTool{Tool: toolGetStateOfMind, Handler: h.getStateOfMind},
 		server.ServerTool{Tool: toolGetCategorySamples, Handler: h.getCategorySamples},
+		server.ServerTool{Tool: toolGetSymptoms, Handler: h.getSymptoms},
 	)
 
 	// Resources
@@ -2,7 +2,10 @@ package mcp
 
 import (
 	"context"
+	"strings"
 	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
 )
 
 // TestUserIDFromContextDefault verifies the default user ID (1) when no value
@@ -62,3 +65,343 @@ func TestDefaultTimeRange(t *testing.T) {
 		t.Error("expected error for invalid date")
 	}
 }
+
+// TestGetMetricInfoRequiresMetric verifies the tool rejects a missing metric
+// as a tool error instead of querying for an empty name.
+func TestGetMetricInfoRequiresMetric(t *testing.T) {
+	h := &handlers{}
+	res, err := h.getMetricInfo(context.Background(), mcp.CallToolRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.IsError {
+		t.Error("expected tool error for missing metric")
+	}
+}
+
+// TestGetTrainingIntensityTopBounds verifies an out-of-range top is rejected
+// as a tool error before any query runs.
+func TestGetTrainingIntensityTopBounds(t *testing.T) {
+	h := &handlers{}
+	for _, top := range []float64{-1, 11} {
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = map[string]any{"top": top}
+		res, err := h.getTrainingIntensity(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !res.IsError {
+			t.Errorf("top=%v: expected tool error", top)
+		}
+	}
+}
+
+// TestGetMuscleGroupVolumeBadDate verifies an unparseable date is a tool
+// error rather than a silent fallback to the default four weeks.
+func TestGetMuscleGroupVolumeBadDate(t *testing.T) {
+	h := &handlers{}
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"end": "next week"}
+	res, err := h.getMuscleGroupVolume(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.IsError {
+		t.Error("expected tool error for invalid end")
+	}
+}
+
+// TestDetectIllnessSignalsBadDate verifies an unparseable date is a tool
+// error rather than silently checking today.
+func TestDetectIllnessSignalsBadDate(t *testing.T) {
+	h := &handlers{}
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"date": "yesterday-ish"}
+	res, err := h.detectIllnessSignals(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.IsError {
+		t.Error("expected tool error for invalid date")
+	}
+}
+
+// TestGetWristTempDeviationBadDate verifies an unparseable start date is a
+// tool error.
+func TestGetWristTempDeviationBadDate(t *testing.T) {
+	h := &handlers{}
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"start": "last month"}
+	res, err := h.getWristTempDeviation(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.IsError {
+		t.Error("expected tool error for invalid start")
+	}
+}
+
+// TestGetPaceByTemperatureBadArgs verifies an unparseable date or a
+// non-positive band width is a tool error.
+func TestGetPaceByTemperatureBadArgs(t *testing.T) {
+	h := &handlers{}
+	for _, args := range []map[string]any{
+		{"start": "last summer"},
+		{"band_c": 0},
+	} {
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := h.getPaceByTemperature(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !res.IsError {
+			t.Errorf("args %v: expected tool error", args)
+		}
+	}
+}
+
+// TestGetMetricPercentileContextBadDate verifies an unparseable end date is a
+// tool error.
+func TestGetMetricPercentileContextBadDate(t *testing.T) {
+	h := &handlers{}
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"end": "yesterday-ish"}
+	res, err := h.getMetricPercentileContext(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.IsError {
+		t.Error("expected tool error for invalid end")
+	}
+}
+
+// TestGetDataQualityBadArgs verifies a missing metric or an unparseable date
+// is a tool error.
+func TestGetDataQualityBadArgs(t *testing.T) {
+	h := &handlers{}
+	for _, args := range []map[string]any{
+		{},
+		{"metric": "heart_rate", "start": "a while ago"},
+	} {
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := h.getDataQuality(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !res.IsError {
+			t.Errorf("args %v: expected tool error", args)
+		}
+	}
+}
+
+// TestGetWorkoutIntervalsBadArgs verifies a malformed workout ID or a
+// negative threshold is a tool error.
+func TestGetWorkoutIntervalsBadArgs(t *testing.T) {
+	h := &handlers{}
+	for _, args := range []map[string]any{
+		{"workout_id": "not-a-uuid"},
+		{"workout_id": "6f1c2d1e-8a4b-4c1e-9f00-000000000001", "threshold_bpm": -5},
+	} {
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := h.getWorkoutIntervals(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !res.IsError {
+			t.Errorf("%v: expected tool error", args)
+		}
+	}
+}
+
+// TestGetSleepArchitectureBadArgs verifies a missing or malformed date is a
+// tool error.
+func TestGetSleepArchitectureBadArgs(t *testing.T) {
+	h := &handlers{}
+	for _, args := range []map[string]any{{}, {"date": "last night"}} {
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := h.getSleepArchitecture(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !res.IsError {
+			t.Errorf("%v: expected tool error", args)
+		}
+	}
+}
+
+// TestGetWorkoutPowerBadArgs verifies a malformed workout ID is a tool error.
+func TestGetWorkoutPowerBadArgs(t *testing.T) {
+	h := &handlers{}
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"workout_id": "not-a-uuid"}
+	res, err := h.getWorkoutPower(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.IsError {
+		t.Error("expected tool error")
+	}
+}
+
+// TestGetWorkoutCadenceBadArgs verifies a malformed workout ID is a tool error.
+func TestGetWorkoutCadenceBadArgs(t *testing.T) {
+	h := &handlers{}
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"workout_id": "not-a-uuid"}
+	res, err := h.getWorkoutCadence(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.IsError {
+		t.Error("expected tool error")
+	}
+}
+
+// TestGetSymptomsBadArgs verifies an unparseable date or a negative lag is a
+// tool error.
+func TestGetSymptomsBadArgs(t *testing.T) {
+	h := &handlers{}
+	for _, args := range []map[string]any{
+		{"start": "last tuesday"},
+		{"correlate_metric": "sleep_analysis", "lag_days": -1},
+	} {
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := h.getSymptoms(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !res.IsError {
+			t.Errorf("%v: expected tool error", args)
+		}
+	}
+}
+
+// TestFindCorrelationsWithBadArgs verifies a missing anchor or unparseable
+// date is a tool error.
+func TestFindCorrelationsWithBadArgs(t *testing.T) {
+	h := &handlers{}
+	for _, args := range []map[string]any{
+		{},
+		{"anchor": "heart_rate_variability", "end": "someday"},
+	} {
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := h.findCorrelationsWith(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !res.IsError {
+			t.Errorf("%v: expected tool error", args)
+		}
+	}
+}
+
+// TestPartialResult verifies a multi-query tool still returns the parts that
+// succeeded, flags the failed ones as warnings, and only errors when nothing
+// could be returned.
+func TestPartialResult(t *testing.T) {
+	res := partialResult(map[string]any{"sessions": []int{1}}, nil, 2)
+	if res.IsError || res.StructuredContent.(map[string]any)["warnings"] != nil {
+		t.Errorf("clean result should have no warnings: %+v", res)
+	}
+
+	res = partialResult(map[string]any{"sessions": []int{1}, "stages": nil}, []string{"stages unavailable: boom"}, 2)
+	if res.IsError {
+		t.Fatal("partial failure should not be a tool error")
+	}
+	warnings, _ := res.StructuredContent.(map[string]any)["warnings"].([]string)
+	if len(warnings) != 1 || warnings[0] != "stages unavailable: boom" {
+		t.Errorf("warnings = %v", warnings)
+	}
+
+	res = partialResult(map[string]any{}, []string{"a failed", "b failed"}, 2)
+	if !res.IsError {
+		t.Error("expected tool error when every part failed")
+	}
+}
+
+// TestGetIntensityTrendBadDate verifies an unparseable date is reported as a
+// tool error rather than silently falling back to the default range.
+func TestGetIntensityTrendBadDate(t *testing.T) {
+	h := &handlers{}
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"start": "last spring"}
+	res, err := h.getIntensityTrend(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.IsError {
+		t.Error("expected tool error for invalid start")
+	}
+}
+
+// TestGetExerciseReportBadArgs verifies a missing exercise or unparseable date
+// is reported as a tool error before any query runs.
+func TestGetExerciseReportBadArgs(t *testing.T) {
+	h := &handlers{}
+	for _, args := range []map[string]any{
+		{"start": "2025-01-01"},
+		{"exercise": "squat", "start": "last spring"},
+	} {
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = args
+		res, err := h.getExerciseReport(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !res.IsError {
+			t.Errorf("%v: expected tool error", args)
+		}
+	}
+}
+
+// TestGetWorkoutsBadTag verifies an invalid tag filter is reported as a tool
+// error before any query runs.
+func TestGetWorkoutsBadTag(t *testing.T) {
+	h := &handlers{}
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"tag": "two words"}
+	res, err := h.getWorkouts(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.IsError {
+		t.Error("expected tool error for invalid tag")
+	}
+}
+
+// TestGetDailySumsBadDay verifies an unknown day selector is a tool error.
+func TestGetDailySumsBadDay(t *testing.T) {
+	h := &handlers{}
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"day": "yesterday"}
+	res, err := h.getDailySums(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !res.IsError {
+		t.Error("expected tool error for invalid day")
+	}
+}
+
+// TestGetMultipleMetricsBadArgs verifies an empty or oversized metric list is
+// a tool error before any query runs.
+func TestGetMultipleMetricsBadArgs(t *testing.T) {
+	h := &handlers{}
+	for _, metrics := range []string{"", " , ", strings.Repeat("m,", 51)} {
+		req := mcp.CallToolRequest{}
+		req.Params.Arguments = map[string]any{"metrics": metrics}
+		res, err := h.getMultipleMetrics(context.Background(), req)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !res.IsError {
+			t.Errorf("metrics %q: expected tool error", metrics)
+		}
+	}
+}
@@ -2,8 +2,11 @@ package mcp
 
 import (
 	"context"
+	"strings"
 	"time"
 
+	"github.com/claude/freereps/internal/storage"
+	"github.com/google/uuid"
 	"github.com/mark3labs/mcp-go/mcp"
 )
 
@@ -45,6 +48,33 @@ func parseFlexTime(s string) (time.Time, error) {
 	return time.Time{}, err
 }
 
+// optionalFloat returns a numeric tool argument, or nil when it wasn't passed
+// (GetFloat can't distinguish an explicit 0 from absence).
+func optionalFloat(req mcp.CallToolRequest, key string) *float64 {
+	if _, ok := req.GetArguments()[key]; !ok {
+		return nil
+	}
+	v := req.GetFloat(key, 0)
+	return &v
+}
+
+// partialResult builds a tool result from whatever parts of a multi-query
+// tool succeeded. Each failed part is listed under "warnings" so the caller
+// knows the data is incomplete; only when every part failed is it an error.
+func partialResult(data map[string]any, warnings []string, parts int) *mcp.CallToolResult {
+	if len(warnings) >= parts {
+		return mcp.NewToolResultError("query failed: " + strings.Join(warnings, "; "))
+	}
+	if len(warnings) > 0 {
+		data["warnings"] = warnings
+	}
+	result, err := mcp.NewToolResultJSON(data)
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed")
+	}
+	return result
+}
+
 // --- Tool definitions ---
 
 var toolGetHealthMetrics = mcp.NewTool("get_health_metrics",
@@ -55,15 +85,23 @@ var toolGetHealthMetrics = mcp.NewTool("get_health_metrics",
 	mcp.WithString("bucket", mcp.Description("Time bucket size (e.g. '1 hour', '1 day', '1 week', '1 month'). Defaults to '1 day'."), mcp.Enum("1 hour", "1 day", "1 week", "1 month")),
 )
 
+var toolGetMultipleMetrics = mcp.NewTool("get_multiple_metrics",
+	mcp.WithDescription("Retrieve time-bucketed data for several metrics in one call, keyed by metric name. Same points as get_health_metrics; prefer this when a report needs more than one metric. Metrics with no data in the range return an empty array."),
+	mcp.WithString("metrics", mcp.Required(), mcp.Description("Comma-separated metric names (e.g. 'resting_heart_rate,heart_rate_variability,step_count'). At most 50.")),
+	mcp.WithString("start", mcp.Description("Start date (ISO 8601 or YYYY-MM-DD). Defaults to 7 days ago.")),
+	mcp.WithString("end", mcp.Description("End date (ISO 8601 or YYYY-MM-DD). Defaults to now.")),
+	mcp.WithString("bucket", mcp.Description("Time bucket size. Defaults to '1 day'."), mcp.Enum("1 hour", "1 day", "1 week", "1 month")),
+)
+
 var toolGetMetricStats = mcp.NewTool("get_metric_stats",
-	mcp.WithDescription("Get aggregate statistics (avg, min, max, stddev, count) for a metric over a time range."),
+	mcp.WithDescription("Get aggregate statistics (avg, min, max, stddev, count) for a metric over a time range. The 'basis' field explains the values: 'min_avg_max' (e.g. heart_rate) means avg/stddev are over per-sample averages while min/max are the true observed extremes."),
 	mcp.WithString("metric", mcp.Required(), mcp.Description("Metric name")),
 	mcp.WithString("start", mcp.Description("Start date. Defaults to 7 days ago.")),
 	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
 )
 
 var toolGetCorrelation = mcp.NewTool("get_correlation",
-	mcp.WithDescription("Compute Pearson correlation between two health metrics. Returns time-aligned data points and the correlation coefficient."),
+	mcp.WithDescription("Compute Pearson correlation between two health metrics. Returns time-aligned data points, the correlation coefficient, and its two-tailed p-value. pearson_r is null with fewer paired buckets than the configured minimum (default 10). Treat low_confidence results (p >= 0.05 or no coefficient) as inconclusive."),
 	mcp.WithString("x", mcp.Required(), mcp.Description("X-axis metric name")),
 	mcp.WithString("y", mcp.Required(), mcp.Description("Y-axis metric name")),
 	mcp.WithString("start", mcp.Description("Start date. Defaults to 7 days ago.")),
@@ -71,10 +109,41 @@ var toolGetCorrelation = mcp.NewTool("get_correlation",
 	mcp.WithString("bucket", mcp.Description("Time bucket for alignment. Defaults to '1 day'."), mcp.Enum("1 hour", "1 day", "1 week", "1 month")),
 )
 
+var toolFindCorrelationsWith = mcp.NewTool("find_correlations_with",
+	mcp.WithDescription("Find which metrics correlate most with one anchor metric (e.g. 'what correlates with my HRV'). Returns each candidate's Pearson r against the anchor, strongest (by absolute value) first. Each result carries a p-value and a low_confidence flag (p >= 0.05). Candidates with fewer shared time buckets than the configured minimum (default 10) are skipped."),
+	mcp.WithString("anchor", mcp.Required(), mcp.Description("Anchor metric name, e.g. 'heart_rate_variability'")),
+	mcp.WithString("candidates", mcp.Description("Comma-separated metric names to test. Defaults to all available metrics.")),
+	mcp.WithString("start", mcp.Description("Start date. Defaults to 90 days ago.")),
+	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
+	mcp.WithString("bucket", mcp.Description("Time bucket for alignment. Defaults to '1 day'."), mcp.Enum("1 hour", "1 day", "1 week", "1 month")),
+	mcp.WithNumber("limit", mcp.Description("Return at most this many correlations. Defaults to 10.")),
+)
+
 var toolGetSleepData = mcp.NewTool("get_sleep_data",
-	mcp.WithDescription("Retrieve sleep sessions and individual sleep stages. Sessions include total sleep, stage durations (core/deep/REM), and timing. Stages are individual segments with start/end times."),
+	mcp.WithDescription("Retrieve sleep sessions, and optionally individual sleep stages. Sessions include total sleep, stage durations (core/deep/REM), and timing. Stages are individual segments with start/end times; they are large over long ranges, so they are only returned with include_stages."),
 	mcp.WithString("start", mcp.Description("Start date. Defaults to 7 days ago.")),
 	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
+	mcp.WithBoolean("include_stages", mcp.Description("Also return every stage segment in the range. Defaults to false.")),
+)
+
+// sleepNightDating explains which date a night is filed under; see
+// storage.sleepNightDate.
+const sleepNightDating = " A night is filed under the local date of its sleep midpoint shifted 12h forward, which is normally the wake-up date; with sleep.day_boundary: noon the midpoint is shifted 12h back instead, giving the evening the noon-to-noon sleep day began, so an early-morning bedtime or afternoon nap stays with the day before."
+
+var toolGetSleepNight = mcp.NewTool("get_sleep_night",
+	mcp.WithDescription("One night's sleep as a hypnogram: the session summary plus every stage segment (start, end, stage, duration) in order."+sleepNightDating),
+	mcp.WithString("date", mcp.Required(), mcp.Description("Date the night is filed under (YYYY-MM-DD)")),
+)
+
+var toolGetSleepArchitecture = mcp.NewTool("get_sleep_architecture",
+	mcp.WithDescription("How one night moved between sleep stages: sleep onset and final wake times, onset latency (minutes from getting into bed to first sleep), number of awakenings and wake after sleep onset (WASO, minutes), REM cycle count, longest uninterrupted deep sleep block (minutes), and stage transitions."+sleepNightDating),
+	mcp.WithString("date", mcp.Required(), mcp.Description("Date the night is filed under (YYYY-MM-DD)")),
+)
+
+var toolGetSleepDebt = mcp.NewTool("get_sleep_debt",
+	mcp.WithDescription("Sleep debt over the 14 nights ending on a date: cumulative shortfall against a nightly target, average sleep, and per-night detail. Nights without data are reported as missing and left out of the totals unless the server counts them as zero."+sleepNightDating),
+	mcp.WithString("end", mcp.Description("Last night of the window (YYYY-MM-DD, dated as above). Defaults to today.")),
+	mcp.WithNumber("target_hours", mcp.Description("Nightly sleep need in hours. Defaults to the server's configured target (8h unless changed).")),
 )
 
 var toolGetWorkouts = mcp.NewTool("get_workouts",
@@ -82,6 +151,19 @@ var toolGetWorkouts = mcp.NewTool("get_workouts",
 	mcp.WithString("start", mcp.Description("Start date. Defaults to 7 days ago.")),
 	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
 	mcp.WithString("type", mcp.Description("Filter by workout type (e.g. 'Traditional Strength Training', 'Running')")),
+	mcp.WithString("tag", mcp.Description("Only workouts carrying this user-defined tag (e.g. 'race')")),
+	mcp.WithNumber("min_duration_sec", mcp.Description("Only workouts at least this long (seconds)")),
+	mcp.WithNumber("max_duration_sec", mcp.Description("Only workouts at most this long (seconds)")),
+	mcp.WithNumber("min_distance_km", mcp.Description("Only workouts covering at least this distance (km)")),
+	mcp.WithNumber("max_distance_km", mcp.Description("Only workouts covering at most this distance (km)")),
+	mcp.WithNumber("min_energy_kcal", mcp.Description("Only workouts burning at least this much active energy (kcal)")),
+	mcp.WithNumber("max_energy_kcal", mcp.Description("Only workouts burning at most this much active energy (kcal)")),
+)
+
+var toolGetActivityCalendar = mcp.NewTool("get_activity_calendar",
+	mcp.WithDescription("Per-day workout count, active minutes, and active calories (kcal) for every day in a range, including rest days with zeros. Useful for spotting consistency, streaks, and gaps."),
+	mcp.WithString("start", mcp.Description("Start date. Defaults to 30 days ago.")),
+	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
 )
 
 var toolGetWorkoutSets = mcp.NewTool("get_workout_sets",
@@ -91,8 +173,74 @@ var toolGetWorkoutSets = mcp.NewTool("get_workout_sets",
 	mcp.WithString("exercise", mcp.Description("Filter by exercise name (partial match, e.g. 'bench press')")),
 )
 
+var toolGetExerciseReport = mcp.NewTool("get_exercise_report",
+	mcp.WithDescription("Per-session volume and intensity for a strength exercise: working sets, reps, tonnage (kg), max weight, average RIR, and estimated 1RM (Epley, counting reps in reserve). Warm-ups are excluded. Sessions are sorted oldest first."),
+	mcp.WithString("exercise", mcp.Required(), mcp.Description("Exercise name (partial match, e.g. 'bench press')")),
+	mcp.WithString("start", mcp.Description("Start date. Defaults to 90 days ago.")),
+	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
+)
+
+var toolGetWorkoutConditions = mcp.NewTool("get_workout_conditions",
+	mcp.WithDescription("Environmental conditions for a single workout: temperature, humidity, indoor/outdoor, location, and elevation, plus any unmodeled fields Health Auto Export recorded."),
+	mcp.WithString("workout_id", mcp.Required(), mcp.Description("Workout UUID (from get_workouts)")),
+)
+
+var toolGetWorkoutPower = mcp.NewTool("get_workout_power",
+	mcp.WithDescription("Power meter summary for one cycling workout: average, maximum and normalized power (watts), and intensity factor (normalized power / FTP) when the user profile has an FTP. Returns null data for workouts recorded without power."),
+	mcp.WithString("workout_id", mcp.Required(), mcp.Description("Workout UUID (from get_workouts)")),
+)
+
+var toolGetWorkoutCadence = mcp.NewTool("get_workout_cadence",
+	mcp.WithDescription("Running cadence for one workout: average and maximum steps per minute and the time and share of moving time in each 10 spm band. Derived from in-workout step counts (Health Auto Export 'Include Workout Metrics'); returns null data for workouts without them."),
+	mcp.WithString("workout_id", mcp.Required(), mcp.Description("Workout UUID (from get_workouts)")),
+)
+
+var toolGetPaceByTemperature = mcp.NewTool("get_pace_by_temperature",
+	mcp.WithDescription("Average pace per temperature band for workouts that recorded both distance and weather, to see how heat or cold affects performance. Fahrenheit readings and non-km distances are converted; workouts without a temperature are left out."),
+	mcp.WithString("start", mcp.Description("Start date. Defaults to 1 year ago.")),
+	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
+	mcp.WithString("type", mcp.Description("Filter by workout type (e.g. 'Running', 'Hiking')")),
+	mcp.WithNumber("band_c", mcp.Description("Width of each temperature band in °C. Defaults to 5.")),
+)
+
+var toolGetWorkoutIntervals = mcp.NewTool("get_workout_intervals",
+	mcp.WithDescription("Splits of an interval workout: segments the heart-rate stream into alternating high- and low-effort intervals with each one's duration, average/max HR, and average speed (m/s, when the workout has a route)."),
+	mcp.WithString("workout_id", mcp.Required(), mcp.Description("Workout UUID (from get_workouts)")),
+	mcp.WithNumber("threshold_bpm", mcp.Description("HR separating high from low effort. Defaults to the midpoint of the workout's lowest and highest HR.")),
+	mcp.WithNumber("min_duration_sec", mcp.Description("Shorter segments are merged into their neighbors. Defaults to 30.")),
+)
+
+var toolGetSwimStats = mcp.NewTool("get_swim_stats",
+	mcp.WithDescription("Swimming summary: total distance (m), pace per 100m, and SWOLF (seconds + strokes per length) when lap and stroke counts are available. Includes per-swim detail."),
+	mcp.WithString("start", mcp.Description("Start date. Defaults to 7 days ago.")),
+	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
+)
+
+var toolGetDailySteps = mcp.NewTool("get_daily_steps",
+	mcp.WithDescription("Daily step totals with iPhone/Apple Watch overlap removed: per hour the highest-reporting source is kept, then hours are summed per day. Prefer this over summing step_count from get_health_metrics."),
+	mcp.WithString("start", mcp.Description("Start date. Defaults to 7 days ago.")),
+	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
+)
+
+var toolGetDailySums = mcp.NewTool("get_daily_sums",
+	mcp.WithDescription("Totals of cumulative metrics (steps, active energy, exercise minutes, distance, flights) for one day. day='latest' (default) sums the most recent day with data; day='today' sums the current day so far, which may be partial or empty."),
+	mcp.WithString("day", mcp.Description("'latest' or 'today'. Defaults to 'latest'.")),
+)
+
+var toolGetMetricInfo = mcp.NewTool("get_metric_info",
+	mcp.WithDescription("Check whether a metric has data before querying it: earliest and latest timestamps, sample count, units, and sources. has_data is false when nothing is recorded."),
+	mcp.WithString("metric", mcp.Required(), mcp.Description("Metric name (e.g. 'heart_rate', 'weight_body_mass')")),
+)
+
+var toolGetDataQuality = mcp.NewTool("get_data_quality",
+	mcp.WithDescription("How completely a metric is recorded over a range: share of days with data, longest gap in days, sample count, sources, and a 0-100 quality score. Check this before drawing conclusions from a trend; caveat anything built on a low score."),
+	mcp.WithString("metric", mcp.Required(), mcp.Description("Metric name (e.g. 'heart_rate_variability')")),
+	mcp.WithString("start", mcp.Description("Start date. Defaults to 30 days ago.")),
+	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
+)
+
 var toolListAvailableMetrics = mcp.NewTool("list_available_metrics",
-	mcp.WithDescription("List all available health metrics with their categories and enabled status."),
+	mcp.WithDescription("List all available health metrics with their categories, enabled status, and human-readable display_label/display_unit. Use the display label when presenting a metric to the user and metric_name when calling other tools."),
 )
 
 var toolGetTrainingSummary = mcp.NewTool("get_training_summary",
@@ -102,11 +250,42 @@ var toolGetTrainingSummary = mcp.NewTool("get_training_summary",
 	mcp.WithString("bucket", mcp.Description("Aggregation period. Defaults to '1 month'."), mcp.Enum("1 week", "1 month")),
 )
 
+var toolGetIntensityTrend = mcp.NewTool("get_intensity_trend",
+	mcp.WithDescription("Average RIR and failure rate (share of tracked working sets at RIR <= 1) per week or month, to see whether strength training is getting harder over time. Periods without RIR-tracked sets return nulls."),
+	mcp.WithString("start", mcp.Description("Start date. Defaults to 6 months ago.")),
+	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
+	mcp.WithString("bucket", mcp.Description("Aggregation period. Defaults to '1 month'."), mcp.Enum("1 week", "1 month")),
+)
+
+var toolGetMuscleGroupVolume = mcp.NewTool("get_muscle_group_volume",
+	mcp.WithDescription("Average weekly working sets per muscle group, rated against volume landmarks: below_mev (add sets), productive, or above_mrv (cut sets). Exercises are assigned to their primary muscle group by name; unrecognized ones are grouped as 'other'."),
+	mcp.WithString("start", mcp.Description("Start date. Defaults to 4 weeks ago.")),
+	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
+)
+
+var toolDetectIllnessSignals = mcp.NewTool("detect_illness_signals",
+	mcp.WithDescription("Early illness check for one day: compares resting heart rate, HRV, respiratory rate, and sleeping wrist temperature against their trailing baselines. Status is likely_illness when enough vitals move the wrong way together, watch for a single one, normal, or insufficient_data when baselines are too short."),
+	mcp.WithString("date", mcp.Description("Day to check. Defaults to today.")),
+)
+
+var toolGetMetricPercentileContext = mcp.NewTool("get_metric_percentile_context",
+	mcp.WithDescription("Compare the user's average resting heart rate, HRV (SDNN), VO2 max, and sleep duration against published population norms for their age and sex. Each metric is labeled below, within, or above the typical range, with the range and its citation; the label is 'unknown' when no profile (birth date and sex, set via PUT /api/v1/profile) is saved or there is no data. Norms describe typical adults, not clinical thresholds."),
+	mcp.WithString("start", mcp.Description("Start date. Defaults to 30 days ago.")),
+	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
+)
+
+var toolGetWristTempDeviation = mcp.NewTool("get_wrist_temp_deviation",
+	mcp.WithDescription("Nightly sleeping wrist temperature as deviation (°C) from the user's baseline, the mean of the previous 30 recorded nights. Raw wrist temperatures vary by person; the deviation is what's meaningful. Nights before 30 nights of history return null baseline and deviation."),
+	mcp.WithString("start", mcp.Description("Start date. Defaults to 90 days ago.")),
+	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
+)
+
 var toolGetTrainingIntensity = mcp.NewTool("get_training_intensity",
 	mcp.WithDescription("RIR distribution, failure rate, per-exercise stats, and optional exercise progression. Returns intensity analysis for strength training."),
 	mcp.WithString("start", mcp.Description("Start date. Defaults to 90 days ago.")),
 	mcp.WithString("end", mcp.Description("End date. Defaults to now.")),
 	mcp.WithString("exercise", mcp.Description("Filter by exercise name (partial match). When set, includes session-by-session progression.")),
+	mcp.WithNumber("top", mcp.Description("Without an exercise filter, include session-by-session progression for this many top exercises by volume (max 10).")),
 )
 
 var toolGetSleepSummary = mcp.NewTool("get_sleep_summary",
@@ -168,6 +347,15 @@ var toolGetCategorySamples = mcp.NewTool("get_category_samples",
 	mcp.WithString("type", mcp.Description("Filter by category sample type (e.g. 'sleepAnalysis', 'menstrualFlow')")),
 )
 
+var toolGetSymptoms = mcp.NewTool("get_symptoms",
+	mcp.WithDescription("Logged symptoms (headache, fatigue, ...) with severity by date range. With correlate_metric, also compares that metric's daily average on days each symptom was logged against the other days, e.g. headache days vs sleep."),
+	mcp.WithString("start", mcp.Description("Start date (ISO 8601 or YYYY-MM-DD). Defaults to 7 days ago.")),
+	mcp.WithString("end", mcp.Description("End date (ISO 8601 or YYYY-MM-DD). Defaults to now.")),
+	mcp.WithString("name", mcp.Description("Only this symptom (case-insensitive, e.g. 'Headache')")),
+	mcp.WithString("correlate_metric", mcp.Description("Metric to align symptom days against (e.g. 'sleep_analysis', 'heart_rate_variability')")),
+	mcp.WithNumber("lag_days", mcp.Description("Compare each day with the metric this many days earlier (e.g. 1 = previous day). Defaults to 0.")),
+)
+
 // --- Tool handlers ---
 
 func (h *handlers) getHealthMetrics(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -197,6 +385,39 @@ func (h *handlers) getHealthMetrics(ctx context.Context, req mcp.CallToolRequest
 	return result, nil
 }
 
+func (h *handlers) getMultipleMetrics(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	var metrics []string
+	for _, m := range strings.Split(req.GetString("metrics", ""), ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			metrics = append(metrics, m)
+		}
+	}
+	if len(metrics) == 0 {
+		return mcp.NewToolResultError("metrics parameter is required"), nil
+	}
+	if len(metrics) > storage.MaxBulkMetrics {
+		return mcp.NewToolResultError("at most 50 metrics per call"), nil
+	}
+
+	start, end, err := defaultTimeRange(req.GetString("start", ""), req.GetString("end", ""))
+	if err != nil {
+		return mcp.NewToolResultError("invalid date format: " + err.Error()), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	series, err := h.ds.GetMultipleTimeSeries(ctx, metrics, start, end, req.GetString("bucket", "1 day"), uid)
+	if err != nil {
+		h.log.Error("mcp get_multiple_metrics", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": series})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
 func (h *handlers) getMetricStats(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	metric, err := req.RequireString("metric")
 	if err != nil {
@@ -222,6 +443,58 @@ func (h *handlers) getMetricStats(ctx context.Context, req mcp.CallToolRequest)
 	return result, nil
 }
 
+func (h *handlers) getMetricInfo(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	metric, err := req.RequireString("metric")
+	if err != nil {
+		return mcp.NewToolResultError("metric parameter is required"), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	info, err := h.ds.GetMetricInfo(ctx, metric, uid)
+	if err != nil {
+		h.log.Error("mcp get_metric_info", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(info)
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getDataQuality(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	metric, err := req.RequireString("metric")
+	if err != nil {
+		return mcp.NewToolResultError("metric parameter is required"), nil
+	}
+	end := time.Now()
+	if v := req.GetString("end", ""); v != "" {
+		if end, err = parseFlexTime(v); err != nil {
+			return mcp.NewToolResultError("invalid end date: " + err.Error()), nil
+		}
+	}
+	start := end.AddDate(0, 0, -30)
+	if v := req.GetString("start", ""); v != "" {
+		if start, err = parseFlexTime(v); err != nil {
+			return mcp.NewToolResultError("invalid start date: " + err.Error()), nil
+		}
+	}
+
+	uid := UserIDFromContext(ctx)
+	q, err := h.ds.GetDataQuality(ctx, metric, start, end, uid)
+	if err != nil {
+		h.log.Error("mcp get_data_quality", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": q})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
 func (h *handlers) getCorrelation(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	xMetric, err := req.RequireString("x")
 	if err != nil {
@@ -253,6 +526,53 @@ func (h *handlers) getCorrelation(ctx context.Context, req mcp.CallToolRequest)
 	return result, nil
 }
 
+func (h *handlers) findCorrelationsWith(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	anchor, err := req.RequireString("anchor")
+	if err != nil {
+		return mcp.NewToolResultError("anchor parameter is required"), nil
+	}
+
+	end := time.Now()
+	if v := req.GetString("end", ""); v != "" {
+		if end, err = parseFlexTime(v); err != nil {
+			return mcp.NewToolResultError("invalid end date: " + err.Error()), nil
+		}
+	}
+	start := end.AddDate(0, 0, -90)
+	if v := req.GetString("start", ""); v != "" {
+		if start, err = parseFlexTime(v); err != nil {
+			return mcp.NewToolResultError("invalid start date: " + err.Error()), nil
+		}
+	}
+
+	var candidates []string
+	for _, c := range strings.Split(req.GetString("candidates", ""), ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			candidates = append(candidates, c)
+		}
+	}
+	limit := int(req.GetFloat("limit", 10))
+	if limit <= 0 {
+		limit = 10
+	}
+
+	uid := UserIDFromContext(ctx)
+	corrs, err := h.ds.GetCorrelationsAgainst(ctx, anchor, candidates, start, end, req.GetString("bucket", "1 day"), uid)
+	if err != nil {
+		h.log.Error("mcp find_correlations_with", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+	if len(corrs) > limit {
+		corrs = corrs[:limit]
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": corrs})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
 func (h *handlers) getSleepData(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	start, end, err := defaultTimeRange(req.GetString("start", ""), req.GetString("end", ""))
 	if err != nil {
@@ -260,23 +580,103 @@ func (h *handlers) getSleepData(ctx context.Context, req mcp.CallToolRequest) (*
 	}
 
 	uid := UserIDFromContext(ctx)
+	var warnings []string
 
 	sessions, err := h.ds.QuerySleepSessions(ctx, start, end, uid)
 	if err != nil {
 		h.log.Error("mcp get_sleep_data sessions", "error", err)
-		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+		warnings = append(warnings, "sessions unavailable: "+err.Error())
+	}
+	data := map[string]any{"sessions": sessions}
+	if !req.GetBool("include_stages", false) {
+		return partialResult(data, warnings, 1), nil
 	}
 
 	stages, err := h.ds.QuerySleepStages(ctx, start, end, uid)
 	if err != nil {
 		h.log.Error("mcp get_sleep_data stages", "error", err)
+		warnings = append(warnings, "stages unavailable: "+err.Error())
+	}
+	data["stages"] = stages
+	return partialResult(data, warnings, 2), nil
+}
+
+func (h *handlers) getSleepNight(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	dateStr, err := req.RequireString("date")
+	if err != nil {
+		return mcp.NewToolResultError("date parameter is required"), nil
+	}
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return mcp.NewToolResultError("invalid date, expected YYYY-MM-DD"), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	night, err := h.ds.GetSleepNight(ctx, date, uid)
+	if err != nil {
+		h.log.Error("mcp get_sleep_night", "error", err)
 		return mcp.NewToolResultError("query failed: " + err.Error()), nil
 	}
+	if night == nil {
+		return mcp.NewToolResultText("No sleep recorded for " + dateStr), nil
+	}
 
-	result, err := mcp.NewToolResultJSON(map[string]any{
-		"sessions": sessions,
-		"stages":   stages,
-	})
+	result, err := mcp.NewToolResultJSON(night)
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getSleepArchitecture(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	dateStr, err := req.RequireString("date")
+	if err != nil {
+		return mcp.NewToolResultError("date parameter is required"), nil
+	}
+	date, err := time.Parse("2006-01-02", dateStr)
+	if err != nil {
+		return mcp.NewToolResultError("invalid date, expected YYYY-MM-DD"), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	arch, err := h.ds.GetSleepArchitecture(ctx, date, uid)
+	if err != nil {
+		h.log.Error("mcp get_sleep_architecture", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+	if arch == nil {
+		return mcp.NewToolResultText("No sleep stages recorded for " + dateStr), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(arch)
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getSleepDebt(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	end := time.Now().UTC()
+	if v := req.GetString("end", ""); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return mcp.NewToolResultError("invalid end, expected YYYY-MM-DD"), nil
+		}
+		end = t
+	}
+	target := req.GetFloat("target_hours", 0)
+	if target < 0 || target > 24 {
+		return mcp.NewToolResultError("target_hours must be between 0 and 24"), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	debt, err := h.ds.GetSleepDebt(ctx, end, uid, target)
+	if err != nil {
+		h.log.Error("mcp get_sleep_debt", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(debt)
 	if err != nil {
 		return mcp.NewToolResultError("serialization failed"), nil
 	}
@@ -289,10 +689,26 @@ func (h *handlers) getWorkouts(ctx context.Context, req mcp.CallToolRequest) (*m
 		return mcp.NewToolResultError("invalid date format: " + err.Error()), nil
 	}
 
-	nameFilter := req.GetString("type", "")
+	tag := req.GetString("tag", "")
+	if tag != "" {
+		if tag, err = storage.NormalizeTag(tag); err != nil {
+			return mcp.NewToolResultError("invalid tag: " + err.Error()), nil
+		}
+	}
+
+	filter := storage.WorkoutFilter{
+		Name:           req.GetString("type", ""),
+		Tag:            tag,
+		MinDurationSec: optionalFloat(req, "min_duration_sec"),
+		MaxDurationSec: optionalFloat(req, "max_duration_sec"),
+		MinDistanceKm:  optionalFloat(req, "min_distance_km"),
+		MaxDistanceKm:  optionalFloat(req, "max_distance_km"),
+		MinEnergyKcal:  optionalFloat(req, "min_energy_kcal"),
+		MaxEnergyKcal:  optionalFloat(req, "max_energy_kcal"),
+	}
 	uid := UserIDFromContext(ctx)
 
-	workouts, err := h.ds.QueryWorkouts(ctx, start, end, uid, nameFilter)
+	workouts, err := h.ds.QueryWorkouts(ctx, start, end, uid, filter)
 	if err != nil {
 		h.log.Error("mcp get_workouts", "error", err)
 		return mcp.NewToolResultError("query failed: " + err.Error()), nil
@@ -327,6 +743,252 @@ func (h *handlers) getWorkoutSets(ctx context.Context, req mcp.CallToolRequest)
 	return result, nil
 }
 
+func (h *handlers) getExerciseReport(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	exercise := req.GetString("exercise", "")
+	if exercise == "" {
+		return mcp.NewToolResultError("exercise is required"), nil
+	}
+	start, end, err := defaultTimeRange(req.GetString("start", ""), req.GetString("end", ""))
+	if err != nil {
+		return mcp.NewToolResultError("invalid date format: " + err.Error()), nil
+	}
+	if req.GetString("start", "") == "" {
+		start = end.AddDate(0, 0, -90)
+	}
+
+	uid := UserIDFromContext(ctx)
+
+	report, err := h.ds.GetExerciseReport(ctx, exercise, start, end, uid)
+	if err != nil {
+		h.log.Error("mcp get_exercise_report", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": report})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getActivityCalendar(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	start, end, err := defaultTimeRange(req.GetString("start", ""), req.GetString("end", ""))
+	if err != nil {
+		return mcp.NewToolResultError("invalid date format: " + err.Error()), nil
+	}
+	if req.GetString("start", "") == "" {
+		start = end.AddDate(0, 0, -30)
+	}
+	if err := storage.ValidateCalendarRange(start, end); err != nil {
+		return mcp.NewToolResultError("invalid range: " + err.Error()), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	days, err := h.ds.GetActivityCalendar(ctx, start, end, uid)
+	if err != nil {
+		h.log.Error("mcp get_activity_calendar", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": days})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getWorkoutIntervals(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, err := uuid.Parse(req.GetString("workout_id", ""))
+	if err != nil {
+		return mcp.NewToolResultError("invalid workout_id: " + err.Error()), nil
+	}
+	opts := storage.IntervalOptions{
+		ThresholdBPM: req.GetFloat("threshold_bpm", 0),
+		MinDuration:  time.Duration(req.GetFloat("min_duration_sec", 0) * float64(time.Second)),
+	}
+	if opts.ThresholdBPM < 0 || opts.MinDuration < 0 {
+		return mcp.NewToolResultError("threshold_bpm and min_duration_sec must not be negative"), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	intervals, err := h.ds.DetectIntervals(ctx, id, uid, opts)
+	if err != nil {
+		h.log.Error("mcp get_workout_intervals", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": intervals})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getWorkoutConditions(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, err := uuid.Parse(req.GetString("workout_id", ""))
+	if err != nil {
+		return mcp.NewToolResultError("invalid workout_id: " + err.Error()), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	w, err := h.ds.GetWorkout(ctx, id, uid)
+	if err != nil {
+		h.log.Error("mcp get_workout_conditions", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": map[string]any{
+		"workout_id":        w.ID,
+		"name":              w.Name,
+		"start_time":        w.StartTime,
+		"location":          w.Location,
+		"is_indoor":         w.IsIndoor,
+		"temperature":       w.Temperature,
+		"temperature_units": w.TemperatureUnits,
+		"humidity":          w.Humidity,
+		"elevation_up":      w.ElevationUp,
+		"elevation_down":    w.ElevationDown,
+		"extra":             storage.WorkoutRawExtras(w.RawJSON, nil),
+	}})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getWorkoutPower(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, err := uuid.Parse(req.GetString("workout_id", ""))
+	if err != nil {
+		return mcp.NewToolResultError("invalid workout_id: " + err.Error()), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	stats, err := h.ds.GetWorkoutPowerStats(ctx, id, uid)
+	if err != nil {
+		h.log.Error("mcp get_workout_power", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": stats})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getWorkoutCadence(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, err := uuid.Parse(req.GetString("workout_id", ""))
+	if err != nil {
+		return mcp.NewToolResultError("invalid workout_id: " + err.Error()), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	stats, err := h.ds.GetWorkoutCadence(ctx, id, uid)
+	if err != nil {
+		h.log.Error("mcp get_workout_cadence", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": stats})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getPaceByTemperature(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	end := time.Now()
+	var err error
+	if v := req.GetString("end", ""); v != "" {
+		if end, err = parseFlexTime(v); err != nil {
+			return mcp.NewToolResultError("invalid end date: " + err.Error()), nil
+		}
+	}
+	start := end.AddDate(-1, 0, 0)
+	if v := req.GetString("start", ""); v != "" {
+		if start, err = parseFlexTime(v); err != nil {
+			return mcp.NewToolResultError("invalid start date: " + err.Error()), nil
+		}
+	}
+	band := req.GetFloat("band_c", storage.DefaultTemperatureBandC)
+	if band <= 0 {
+		return mcp.NewToolResultError("band_c must be positive"), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	bands, err := h.ds.GetPaceByTemperature(ctx, start, end, req.GetString("type", ""), band, uid)
+	if err != nil {
+		h.log.Error("mcp get_pace_by_temperature", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": bands})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getSwimStats(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	start, end, err := defaultTimeRange(req.GetString("start", ""), req.GetString("end", ""))
+	if err != nil {
+		return mcp.NewToolResultError("invalid date format: " + err.Error()), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	stats, err := h.ds.GetSwimStats(ctx, start, end, uid)
+	if err != nil {
+		h.log.Error("mcp get_swim_stats", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": stats})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getDailySteps(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	start, end, err := defaultTimeRange(req.GetString("start", ""), req.GetString("end", ""))
+	if err != nil {
+		return mcp.NewToolResultError("invalid date format: " + err.Error()), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	steps, err := h.ds.GetDailySteps(ctx, start, end, uid)
+	if err != nil {
+		h.log.Error("mcp get_daily_steps", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": steps})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getDailySums(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	day := req.GetString("day", storage.DailySumsLatest)
+	if !storage.ValidDailySumsDay(day) {
+		return mcp.NewToolResultError("day must be 'latest' or 'today'"), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	sums, err := h.ds.GetDailySums(ctx, uid, cumulativeMetrics, day)
+	if err != nil {
+		h.log.Error("mcp get_daily_sums", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"day": day, "data": sums})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
 func (h *handlers) listAvailableMetrics(ctx context.Context, _ mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	metrics, err := h.ds.GetAllowedMetrics(ctx)
 	if err != nil {
@@ -382,28 +1044,25 @@ func (h *handlers) comparePeriods(ctx context.Context, req mcp.CallToolRequest)
 	}
 
 	uid := UserIDFromContext(ctx)
+	var warnings []string
 
 	statsA, err := h.ds.GetMetricStats(ctx, metric, aStart, aEnd, uid)
 	if err != nil {
 		h.log.Error("mcp compare_periods A", "error", err)
-		return mcp.NewToolResultError("query failed for period A: " + err.Error()), nil
+		warnings = append(warnings, "period A unavailable: "+err.Error())
 	}
 
 	statsB, err := h.ds.GetMetricStats(ctx, metric, bStart, bEnd, uid)
 	if err != nil {
 		h.log.Error("mcp compare_periods B", "error", err)
-		return mcp.NewToolResultError("query failed for period B: " + err.Error()), nil
+		warnings = append(warnings, "period B unavailable: "+err.Error())
 	}
 
-	result, err := mcp.NewToolResultJSON(map[string]any{
+	return partialResult(map[string]any{
 		"metric":   metric,
 		"period_a": statsA,
 		"period_b": statsB,
-	})
-	if err != nil {
-		return mcp.NewToolResultError("serialization failed"), nil
-	}
-	return result, nil
+	}, warnings, 2), nil
 }
 
 func (h *handlers) getTrainingSummary(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -474,8 +1133,12 @@ func (h *handlers) getTrainingIntensity(ctx context.Context, req mcp.CallToolReq
 
 	uid := UserIDFromContext(ctx)
 	exerciseFilter := req.GetString("exercise", "")
+	top := int(req.GetFloat("top", 0))
+	if top < 0 || top > storage.MaxIntensityTop {
+		return mcp.NewToolResultError("top must be between 0 and 10"), nil
+	}
 
-	intensity, err := h.ds.GetTrainingIntensity(ctx, start, end, uid, exerciseFilter)
+	intensity, err := h.ds.GetTrainingIntensity(ctx, start, end, uid, exerciseFilter, top)
 	if err != nil {
 		h.log.Error("mcp get_training_intensity", "error", err)
 		return mcp.NewToolResultError("query failed: " + err.Error()), nil
@@ -488,6 +1151,157 @@ func (h *handlers) getTrainingIntensity(ctx context.Context, req mcp.CallToolReq
 	return result, nil
 }
 
+func (h *handlers) getIntensityTrend(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	endStr := req.GetString("end", "")
+	startStr := req.GetString("start", "")
+
+	var start, end time.Time
+	var err error
+
+	if endStr != "" {
+		end, err = parseFlexTime(endStr)
+		if err != nil {
+			return mcp.NewToolResultError("invalid end date: " + err.Error()), nil
+		}
+	} else {
+		end = time.Now()
+	}
+
+	if startStr != "" {
+		start, err = parseFlexTime(startStr)
+		if err != nil {
+			return mcp.NewToolResultError("invalid start date: " + err.Error()), nil
+		}
+	} else {
+		start = end.AddDate(0, -6, 0)
+	}
+
+	bucket := req.GetString("bucket", "1 month")
+	uid := UserIDFromContext(ctx)
+
+	trend, err := h.ds.GetIntensityTrend(ctx, start, end, bucket, uid)
+	if err != nil {
+		h.log.Error("mcp get_intensity_trend", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": trend})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getMuscleGroupVolume(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	end := time.Now()
+	var err error
+	if v := req.GetString("end", ""); v != "" {
+		if end, err = parseFlexTime(v); err != nil {
+			return mcp.NewToolResultError("invalid end date: " + err.Error()), nil
+		}
+	}
+	start := end.AddDate(0, 0, -28)
+	if v := req.GetString("start", ""); v != "" {
+		if start, err = parseFlexTime(v); err != nil {
+			return mcp.NewToolResultError("invalid start date: " + err.Error()), nil
+		}
+	}
+
+	uid := UserIDFromContext(ctx)
+	groups, err := h.ds.GetMuscleGroupVolume(ctx, start, end, uid)
+	if err != nil {
+		h.log.Error("mcp get_muscle_group_volume", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": groups})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) detectIllnessSignals(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	date := time.Now()
+	if v := req.GetString("date", ""); v != "" {
+		var err error
+		if date, err = parseFlexTime(v); err != nil {
+			return mcp.NewToolResultError("invalid date: " + err.Error()), nil
+		}
+	}
+
+	uid := UserIDFromContext(ctx)
+	assessment, err := h.ds.DetectIllnessSignals(ctx, date, uid)
+	if err != nil {
+		h.log.Error("mcp detect_illness_signals", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": assessment})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getMetricPercentileContext(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	end := time.Now()
+	var err error
+	if v := req.GetString("end", ""); v != "" {
+		if end, err = parseFlexTime(v); err != nil {
+			return mcp.NewToolResultError("invalid end date: " + err.Error()), nil
+		}
+	}
+	start := end.AddDate(0, 0, -30)
+	if v := req.GetString("start", ""); v != "" {
+		if start, err = parseFlexTime(v); err != nil {
+			return mcp.NewToolResultError("invalid start date: " + err.Error()), nil
+		}
+	}
+
+	uid := UserIDFromContext(ctx)
+	norms, err := h.ds.GetNormContext(ctx, start, end, uid)
+	if err != nil {
+		h.log.Error("mcp get_metric_percentile_context", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": norms})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
+func (h *handlers) getWristTempDeviation(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	end := time.Now()
+	var err error
+	if v := req.GetString("end", ""); v != "" {
+		if end, err = parseFlexTime(v); err != nil {
+			return mcp.NewToolResultError("invalid end date: " + err.Error()), nil
+		}
+	}
+	start := end.AddDate(0, 0, -90)
+	if v := req.GetString("start", ""); v != "" {
+		if start, err = parseFlexTime(v); err != nil {
+			return mcp.NewToolResultError("invalid start date: " + err.Error()), nil
+		}
+	}
+
+	uid := UserIDFromContext(ctx)
+	nights, err := h.ds.GetWristTempDeviation(ctx, start, end, uid)
+	if err != nil {
+		h.log.Error("mcp get_wrist_temp_deviation", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+
+	result, err := mcp.NewToolResultJSON(map[string]any{"data": nights})
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
+
 func (h *handlers) getSleepSummary(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	endStr := req.GetString("end", "")
 	startStr := req.GetString("start", "")
@@ -676,3 +1490,37 @@ func (h *handlers) getCategorySamples(ctx context.Context, req mcp.CallToolReque
 	}
 	return result, nil
 }
+
+func (h *handlers) getSymptoms(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	start, end, err := defaultTimeRange(req.GetString("start", ""), req.GetString("end", ""))
+	if err != nil {
+		return mcp.NewToolResultError("invalid date format: " + err.Error()), nil
+	}
+	lag := int(req.GetFloat("lag_days", 0))
+	if lag < 0 {
+		return mcp.NewToolResultError("lag_days must not be negative"), nil
+	}
+
+	uid := UserIDFromContext(ctx)
+	symptoms, err := h.ds.GetSymptoms(ctx, start, end, uid, req.GetString("name", ""))
+	if err != nil {
+		h.log.Error("mcp get_symptoms", "error", err)
+		return mcp.NewToolResultError("query failed: " + err.Error()), nil
+	}
+	data := map[string]any{"data": symptoms}
+
+	if metric := req.GetString("correlate_metric", ""); metric != "" {
+		correlations, err := h.ds.CorrelateSymptoms(ctx, start, end, metric, lag, uid)
+		if err != nil {
+			h.log.Error("mcp get_symptoms correlation", "error", err)
+			return mcp.NewToolResultError("query failed: " + err.Error()), nil
+		}
+		data["correlations"] = correlations
+	}
+
+	result, err := mcp.NewToolResultJSON(data)
+	if err != nil {
+		return mcp.NewToolResultError("serialization failed"), nil
+	}
+	return result, nil
+}
@@ -1,15 +1,29 @@
 package models
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
 
 // AlphaSession represents a parsed Alpha Progression workout session.
 type AlphaSession struct {
 	Name      string
 	Date      time.Time
 	Duration  string
+	Notes     string // free-text session note, empty if none
 	Exercises []AlphaExercise
 }
 
+// Key returns the session's deterministic identity: the hex SHA-256 of
+// "name|date|duration" with the date in UTC RFC 3339. Migration 000024
+// backfills workout_sets.session_key with the same formula, so keep them in
+// sync.
+func (s AlphaSession) Key() string {
+	sum := sha256.Sum256([]byte(s.Name + "|" + s.Date.UTC().Format(time.RFC3339) + "|" + s.Duration))
+	return hex.EncodeToString(sum[:])
+}
+
 // AlphaExercise represents a single exercise within a session.
 type AlphaExercise struct {
 	Number     int
@@ -27,4 +41,5 @@ type AlphaSet struct {
 	Reps             int
 	RIR              float64
 	IsWarmup         bool
+	RestSeconds      *int // rest before the next set, nil if not exported
 }
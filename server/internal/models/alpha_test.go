@@ -0,0 +1,32 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+// TestAlphaSessionKey verifies the key ignores the date's time zone but
+// changes with the session's name, date, or duration.
+func TestAlphaSessionKey(t *testing.T) {
+	date := time.Date(2026, 2, 19, 4, 54, 0, 0, time.UTC)
+	base := AlphaSession{Name: "Legs · Day 2", Date: date, Duration: "1:02 hr"}
+
+	berlin := base
+	berlin.Date = date.In(time.FixedZone("CET", 3600))
+	if base.Key() != berlin.Key() {
+		t.Error("same instant in another zone should give the same key")
+	}
+	if len(base.Key()) != 64 {
+		t.Errorf("key length = %d, want 64 hex chars", len(base.Key()))
+	}
+
+	for name, other := range map[string]AlphaSession{
+		"name":     {Name: "Push · Day 1", Date: date, Duration: "1:02 hr"},
+		"date":     {Name: base.Name, Date: date.Add(time.Minute), Duration: "1:02 hr"},
+		"duration": {Name: base.Name, Date: date, Duration: "1:03 hr"},
+	} {
+		if other.Key() == base.Key() {
+			t.Errorf("different %s gave the same key", name)
+		}
+	}
+}
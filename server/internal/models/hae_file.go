@@ -125,4 +125,6 @@ type HAEFileLocation struct {
 	Time      float64 `json:"time"`
 	HAcc      float64 `json:"hAcc"`
 	VAcc      float64 `json:"vAcc"`
+	CourseAcc float64 `json:"courseAcc"`
+	SpeedAcc  float64 `json:"speedAcc"`
 }
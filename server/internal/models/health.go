@@ -69,6 +69,7 @@ type HealthData struct {
 	VisionPrescriptions []VisionPrescription `json:"vision_prescriptions,omitempty"`
 	StateOfMind         []StateOfMind        `json:"state_of_mind,omitempty"`
 	CategorySamples     []CategorySample     `json:"category_samples,omitempty"`
+	Symptoms            []Symptom            `json:"symptoms,omitempty"`
 }
 
 // HealthMetric is a single metric entry with name, units, and data points.
@@ -145,6 +146,13 @@ type HealthWorkout struct {
 	ElevationUp        *Quantity `json:"elevationUp,omitempty"`
 	ElevationDown      *Quantity `json:"elevationDown,omitempty"`
 
+	Temperature *Quantity `json:"temperature,omitempty"`
+	Humidity    *Quantity `json:"humidity,omitempty"`
+
+	// Swim-only fields (pool swims report lap length; strokes for all swims).
+	LapLength   *Quantity `json:"lapLength,omitempty"`
+	StrokeCount *Quantity `json:"totalSwimmingStrokeCount,omitempty"`
+
 	HeartRate *HeartRateSummary `json:"heartRate,omitempty"`
 	AvgHR     *Quantity         `json:"avgHeartRate,omitempty"`
 	MaxHR     *Quantity         `json:"maxHeartRate,omitempty"`
@@ -153,10 +161,27 @@ type HealthWorkout struct {
 	HeartRateRecovery []WorkoutHRPoint `json:"heartRateRecovery,omitempty"`
 	Route             []RoutePoint     `json:"route,omitempty"`
 
+	// StepCount holds per-interval step counts recorded during the workout
+	// ("Include Workout Metrics"), from which running cadence is derived.
+	StepCount []WorkoutQtyPoint `json:"stepCount,omitempty"`
+
 	// Store original JSON for fields we don't explicitly model
 	RawJSON json.RawMessage `json:"-"`
 }
 
+// UnmarshalJSON decodes a workout and keeps the original bytes in RawJSON so
+// fields we don't model (weather, swim stroke counts, ...) aren't lost.
+func (w *HealthWorkout) UnmarshalJSON(data []byte) error {
+	type plain HealthWorkout
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	*w = HealthWorkout(p)
+	w.RawJSON = append(json.RawMessage(nil), data...)
+	return nil
+}
+
 // Quantity is the {"qty": N, "units": "..."} structure.
 type Quantity struct {
 	Qty   float64 `json:"qty"`
@@ -180,6 +205,14 @@ type WorkoutHRPoint struct {
 	Source string  `json:"source"`
 }
 
+// WorkoutQtyPoint is one sample of a workout metric time series.
+type WorkoutQtyPoint struct {
+	Date   HealthTime `json:"date"`
+	Qty    float64    `json:"qty"`
+	Units  string     `json:"units"`
+	Source string     `json:"source"`
+}
+
 // RoutePoint is a GPS point from a workout route.
 type RoutePoint struct {
 	Latitude           float64 `json:"latitude"`
@@ -274,3 +307,14 @@ type CategorySample struct {
 	EndDate    HealthTime `json:"end_date"`
 	Source     string  `json:"source,omitempty"`
 }
+
+// Symptom is a logged symptom from Health Auto Export, e.g. {"name":
+// "Headache", "severity": "Mild", "start": ..., "end": ...}.
+type Symptom struct {
+	Name        string      `json:"name"`
+	Severity    string      `json:"severity,omitempty"`
+	Start       HealthTime  `json:"start"`
+	End         *HealthTime `json:"end,omitempty"`
+	UserEntered bool        `json:"userEntered,omitempty"`
+	Source      string      `json:"source,omitempty"`
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 	"time"
 )
@@ -141,3 +142,25 @@ func TestHealthWorkoutUnmarshal(t *testing.T) {
 		t.Errorf("route = %v", w.Route)
 	}
 }
+
+// TestHealthWorkoutUnmarshalKeepsRaw verifies the original workout JSON is
+// retained so fields without a struct field (e.g. weather) aren't lost, and
+// that temperature/humidity parse as quantities.
+func TestHealthWorkoutUnmarshalKeepsRaw(t *testing.T) {
+	raw := `{"id":"550e8400-e29b-41d4-a716-446655440000","name":"Outdoor Run",
+		"temperature":{"qty":18.5,"units":"degC"},"humidity":{"qty":62,"units":"%"},
+		"weather":"Cloudy"}`
+	var w HealthWorkout
+	if err := json.Unmarshal([]byte(raw), &w); err != nil {
+		t.Fatalf("unmarshal error: %v", err)
+	}
+	if w.Temperature == nil || w.Temperature.Qty != 18.5 || w.Temperature.Units != "degC" {
+		t.Errorf("temperature = %v", w.Temperature)
+	}
+	if w.Humidity == nil || w.Humidity.Qty != 62 {
+		t.Errorf("humidity = %v", w.Humidity)
+	}
+	if !strings.Contains(string(w.RawJSON), `"weather":"Cloudy"`) {
+		t.Errorf("RawJSON = %s, want original payload", w.RawJSON)
+	}
+}
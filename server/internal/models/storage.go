@@ -20,8 +20,25 @@ type HealthMetricRow struct {
 	Systolic   *float64
 	Diastolic  *float64
 	SourceUUID *uuid.UUID
+
+	// RawUnits and RawQty preserve the as-received unit and value when
+	// ingest normalized the row to a canonical unit. Empty/nil otherwise.
+	RawUnits string
+	RawQty   *float64
+
+	// AggLevel is AggLevelDay for pre-aggregated daily points. Empty means
+	// AggLevelRaw.
+	AggLevel string
 }
 
+// Aggregation levels of health metric rows.
+const (
+	// AggLevelRaw marks an individual sample.
+	AggLevelRaw = "raw"
+	// AggLevelDay marks a daily total or average computed by the exporter.
+	AggLevelDay = "day"
+)
+
 // SleepSessionRow is a row ready for insertion into the sleep_sessions table.
 type SleepSessionRow struct {
 	UserID     int
@@ -70,11 +87,20 @@ type WorkoutRow struct {
 	MinHeartRate       *float64
 	ElevationUp        *float64
 	ElevationDown      *float64
+	Temperature        *float64
+	TemperatureUnits   string
+	Humidity           *float64
+	PoolLength         *float64 // metres
+	TotalStrokes       *int
+	LapCount           *int
+	MovingTimeSec      *float64 // nil without a GPS route
+	Notes              string
+	Tags               []string
 	RawJSON            []byte `json:"-"`
 	AlphaSessionName   string `json:"alpha_session_name,omitempty"`
 }
 
-// WorkoutHRRow is a row for the workout_heart_rate table.
+// WorkoutHRRow is a row for the workout_heart_rate and workout_hr_recovery tables.
 type WorkoutHRRow struct {
 	Time      time.Time
 	WorkoutID uuid.UUID
@@ -85,23 +111,45 @@ type WorkoutHRRow struct {
 	Source    string
 }
 
+// WorkoutPowerRow is a row for the workout_power table.
+type WorkoutPowerRow struct {
+	Time      time.Time
+	WorkoutID uuid.UUID
+	UserID    int
+	Watts     float64
+	Source    string
+}
+
+// WorkoutCadenceRow is a row for the workout_cadence table.
+type WorkoutCadenceRow struct {
+	Time      time.Time
+	WorkoutID uuid.UUID
+	UserID    int
+	SPM       float64
+	Source    string
+}
+
 // WorkoutRouteRow is a row for the workout_routes table.
 type WorkoutRouteRow struct {
-	Time                time.Time
-	WorkoutID           uuid.UUID
-	UserID              int
-	Latitude            float64
-	Longitude           float64
-	Altitude            *float64
-	Speed               *float64
-	Course              *float64
-	HorizontalAccuracy  *float64
-	VerticalAccuracy    *float64
+	Time               time.Time
+	WorkoutID          uuid.UUID
+	UserID             int
+	Latitude           float64
+	Longitude          float64
+	Altitude           *float64
+	Speed              *float64
+	Course             *float64
+	HorizontalAccuracy *float64
+	VerticalAccuracy   *float64
+	CourseAccuracy     *float64
+	SpeedAccuracy      *float64
 }
 
 // WorkoutSetRow is a row for the workout_sets table.
 type WorkoutSetRow struct {
 	UserID           int
+	SessionKey       string
+	WorkoutID        *uuid.UUID // linked Apple/Oura workout, nil if none matched
 	SessionName      string
 	SessionDate      time.Time
 	SessionDuration  string
@@ -115,6 +163,8 @@ type WorkoutSetRow struct {
 	IsBodyweightPlus bool
 	Reps             int
 	RIR              float64
+	SessionNotes     string
+	RestSeconds      *int
 }
 
 // ECGRecordingRow is a row for the ecg_recordings table.
@@ -197,3 +247,14 @@ type CategorySampleRow struct {
 	EndDate    time.Time
 	Source     string
 }
+
+// SymptomRow is a row for the symptoms table.
+type SymptomRow struct {
+	UserID      int
+	Name        string
+	Severity    string
+	StartTime   time.Time
+	EndTime     *time.Time
+	UserEntered bool
+	Source      string
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/claude/freereps/internal/config"
+	"github.com/claude/freereps/internal/ingest"
 	"github.com/claude/freereps/internal/models"
 	"github.com/claude/freereps/internal/storage"
 )
@@ -45,6 +46,7 @@ type Syncer struct {
 	db       *storage.DB
 	cfg      config.OuraConfig
 	log      *slog.Logger
+	units    *ingest.UnitNormalizer
 }
 
 // NewSyncer creates a new Oura sync orchestrator.
@@ -55,9 +57,15 @@ func NewSyncer(client *Client, tokenMgr *TokenManager, db *storage.DB, cfg confi
 		db:       db,
 		cfg:      cfg,
 		log:      log,
+		units:    ingest.NewUnitNormalizer(nil),
 	}
 }
 
+// SetUnitNormalizer replaces the unit normalizer applied before insert.
+func (s *Syncer) SetUnitNormalizer(n *ingest.UnitNormalizer) {
+	s.units = n
+}
+
 // Run starts the polling loop. Blocks until ctx is cancelled.
 func (s *Syncer) Run(ctx context.Context) {
 	// Sync immediately on startup.
@@ -209,6 +217,11 @@ func (s *Syncer) insertMetrics(ctx context.Context, rows []models.HealthMetricRo
 	if len(rows) == 0 {
 		return nil
 	}
+	for i := range rows {
+		if _, err := s.units.Normalize(&rows[i]); err != nil {
+			s.log.Warn("oura: storing metric in received unit", "error", err)
+		}
+	}
 	inserted, err := s.db.InsertHealthMetrics(ctx, rows)
 	if err != nil {
 		return err
@@ -0,0 +1,156 @@
+// Package report renders stored summaries as ready-to-send text digests.
+package report
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/claude/freereps/internal/storage"
+)
+
+// sparkBlocks are the unicode bars used for sparklines, lowest first.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// flatChangePct is the week-over-week change below which a trend is shown
+// as flat.
+const flatChangePct = 2.0
+
+// Sparkline draws one bar per value scaled between the series' min and max.
+// Missing values are drawn as a middle dot.
+func Sparkline(vals []*float64) string {
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range vals {
+		if v != nil {
+			lo, hi = math.Min(lo, *v), math.Max(hi, *v)
+		}
+	}
+	var b strings.Builder
+	for _, v := range vals {
+		switch {
+		case v == nil:
+			b.WriteRune('·')
+		case hi == lo:
+			b.WriteRune(sparkBlocks[len(sparkBlocks)/2])
+		default:
+			i := int((*v - lo) / (hi - lo) * float64(len(sparkBlocks)-1))
+			b.WriteRune(sparkBlocks[i])
+		}
+	}
+	return b.String()
+}
+
+// Trend describes the change from prev to cur as an arrow, percentage and
+// verdict, e.g. "↑ +12% better". A rise is better unless lowerIsBetter is
+// set, as for resting heart rate. Changes under flatChangePct are "→".
+// Returns "–" when either side is missing or prev is zero.
+func Trend(cur, prev *float64, lowerIsBetter bool) string {
+	if cur == nil || prev == nil || *prev == 0 {
+		return "–"
+	}
+	pct := (*cur - *prev) / math.Abs(*prev) * 100
+	if math.Abs(pct) < flatChangePct {
+		return "→"
+	}
+	verdict := "better"
+	if (pct > 0) == lowerIsBetter {
+		verdict = "worse"
+	}
+	if pct > 0 {
+		return fmt.Sprintf("↑ +%.0f%% %s", pct, verdict)
+	}
+	return fmt.Sprintf("↓ %.0f%% %s", pct, verdict)
+}
+
+// formatValue prints v with the precision suited to unit and thousands
+// separators, or "–" when v is nil.
+func formatValue(v *float64, unit string) string {
+	if v == nil {
+		return "–"
+	}
+	decimals := 0
+	if unit == "h" {
+		decimals = 1
+	}
+	s := strconv.FormatFloat(*v, 'f', decimals, 64)
+	intPart, frac, _ := strings.Cut(s, ".")
+	neg := strings.HasPrefix(intPart, "-")
+	intPart = strings.TrimPrefix(intPart, "-")
+	for i := len(intPart) - 3; i > 0; i -= 3 {
+		intPart = intPart[:i] + "," + intPart[i:]
+	}
+	if neg {
+		intPart = "-" + intPart
+	}
+	if frac != "" {
+		intPart += "." + frac
+	}
+	if unit == "steps" {
+		return intPart
+	}
+	return intPart + " " + unit
+}
+
+// metricRow holds one metric's rendered cells.
+type metricRow struct {
+	label, spark, avg, trend string
+}
+
+func metricRows(r *storage.WeeklyReport) []metricRow {
+	rows := make([]metricRow, 0, len(r.Metrics))
+	for _, m := range r.Metrics {
+		rows = append(rows, metricRow{
+			label: m.Label,
+			spark: Sparkline(m.Daily),
+			avg:   formatValue(m.Avg, m.Unit),
+			trend: Trend(m.Avg, m.PrevAvg, m.LowerIsBetter),
+		})
+	}
+	return rows
+}
+
+// workoutLines renders the workout totals with last week's in parentheses.
+func workoutLines(w storage.ReportWorkouts) []string {
+	f := func(v float64) *float64 { return &v }
+	return []string{
+		fmt.Sprintf("%d workouts (last week %d) %s", w.Count, w.PrevCount, Trend(f(float64(w.Count)), f(float64(w.PrevCount)), false)),
+		fmt.Sprintf("%s active (last week %s) %s", formatValue(f(w.Minutes), "min"), formatValue(f(w.PrevMinutes), "min"), Trend(f(w.Minutes), f(w.PrevMinutes), false)),
+		fmt.Sprintf("%s active energy (last week %s) %s", formatValue(f(w.ActiveKcal), "kcal"), formatValue(f(w.PrevActiveKcal), "kcal"), Trend(f(w.ActiveKcal), f(w.PrevActiveKcal), false)),
+	}
+}
+
+// Markdown renders the report as a markdown digest: a metric table with daily
+// sparklines and week-over-week trends, then workout totals.
+func Markdown(r *storage.WeeklyReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Weekly report: %s – %s\n\n", r.WeekStart, r.WeekEnd)
+	b.WriteString("| Metric | Daily | Average | vs last week |\n")
+	b.WriteString("|--------|-------|---------|--------------|\n")
+	for _, row := range metricRows(r) {
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", row.label, row.spark, row.avg, row.trend)
+	}
+	b.WriteString("\n## Workouts\n\n")
+	for _, line := range workoutLines(r.Workouts) {
+		fmt.Fprintf(&b, "- %s\n", line)
+	}
+	return b.String()
+}
+
+// HTML renders the same digest as a standalone HTML fragment for email.
+func HTML(r *storage.WeeklyReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<h1>Weekly report: %s – %s</h1>\n", html.EscapeString(r.WeekStart), html.EscapeString(r.WeekEnd))
+	b.WriteString("<table>\n<tr><th>Metric</th><th>Daily</th><th>Average</th><th>vs last week</th></tr>\n")
+	for _, row := range metricRows(r) {
+		fmt.Fprintf(&b, "<tr><td>%s</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+			html.EscapeString(row.label), row.spark, html.EscapeString(row.avg), row.trend)
+	}
+	b.WriteString("</table>\n<h2>Workouts</h2>\n<ul>\n")
+	for _, line := range workoutLines(r.Workouts) {
+		fmt.Fprintf(&b, "<li>%s</li>\n", html.EscapeString(line))
+	}
+	b.WriteString("</ul>\n")
+	return b.String()
+}
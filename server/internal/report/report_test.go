@@ -0,0 +1,88 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/claude/freereps/internal/storage"
+)
+
+func f(v float64) *float64 { return &v }
+
+// fixedReport is a week with a gap day, an improving metric, a metric
+// without data, and a lower-is-better metric that dropped.
+func fixedReport() *storage.WeeklyReport {
+	return &storage.WeeklyReport{
+		WeekStart: "2025-04-07",
+		WeekEnd:   "2025-04-13",
+		Metrics: []storage.ReportMetric{
+			{Name: "steps", Label: "Steps", Unit: "steps",
+				Daily: []*float64{f(4000), f(8000), nil, f(12000), f(6000), f(10000), f(8000)},
+				Avg:   f(8000), PrevAvg: f(7000)},
+			{Name: "sleep", Label: "Sleep", Unit: "h",
+				Daily: []*float64{f(7), f(7), f(7), f(7), f(7), f(7), f(7)},
+				Avg:   f(7.04), PrevAvg: f(7)},
+			{Name: "heart_rate_variability", Label: "HRV", Unit: "ms", Daily: make([]*float64, 7)},
+			{Name: "resting_heart_rate", Label: "Resting HR", Unit: "bpm",
+				Daily: []*float64{f(55), f(54), f(54), f(53), f(53), f(52), f(52)},
+				Avg:   f(53.3), PrevAvg: f(58), LowerIsBetter: true},
+		},
+		Workouts: storage.ReportWorkouts{Count: 4, PrevCount: 2, Minutes: 185, PrevMinutes: 160, ActiveKcal: 1420, PrevActiveKcal: 1500},
+	}
+}
+
+// TestMarkdown verifies the digest's title, metric rows with sparklines and
+// trends, and workout totals.
+func TestMarkdown(t *testing.T) {
+	md := Markdown(fixedReport())
+	for _, want := range []string{
+		"# Weekly report: 2025-04-07 – 2025-04-13",
+		"| Steps | ▁▄·█▂▆▄ | 8,000 | ↑ +14% better |",
+		"| Sleep | ▅▅▅▅▅▅▅ | 7.0 h | → |",
+		"| HRV | ······· | – | – |",
+		"| Resting HR | █▅▅▃▃▁▁ | 53 bpm | ↓ -8% better |",
+		"- 4 workouts (last week 2) ↑ +100% better",
+		"- 185 min active (last week 160 min) ↑ +16% better",
+		"- 1,420 kcal active energy (last week 1,500 kcal) ↓ -5% worse",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q\n%s", want, md)
+		}
+	}
+}
+
+// TestHTMLEscapes verifies the HTML digest escapes labels.
+func TestHTMLEscapes(t *testing.T) {
+	r := fixedReport()
+	r.Metrics[0].Label = "<b>Steps</b>"
+	out := HTML(r)
+	if strings.Contains(out, "<b>Steps</b>") || !strings.Contains(out, "&lt;b&gt;Steps&lt;/b&gt;") {
+		t.Errorf("label not escaped:\n%s", out)
+	}
+	if !strings.Contains(out, "<td>8,000</td>") {
+		t.Errorf("missing steps average:\n%s", out)
+	}
+}
+
+// TestTrend verifies arrows, the flat band, and that the verdict flips for
+// lower-is-better metrics.
+func TestTrend(t *testing.T) {
+	tests := []struct {
+		cur, prev     *float64
+		lowerIsBetter bool
+		want          string
+	}{
+		{f(110), f(100), false, "↑ +10% better"},
+		{f(90), f(100), false, "↓ -10% worse"},
+		{f(110), f(100), true, "↑ +10% worse"},
+		{f(90), f(100), true, "↓ -10% better"},
+		{f(101), f(100), false, "→"},
+		{nil, f(100), false, "–"},
+		{f(5), f(0), false, "–"},
+	}
+	for _, tt := range tests {
+		if got := Trend(tt.cur, tt.prev, tt.lowerIsBetter); got != tt.want {
+			t.Errorf("Trend(%v, %v, %v) = %q, want %q", tt.cur, tt.prev, tt.lowerIsBetter, got, tt.want)
+		}
+	}
+}
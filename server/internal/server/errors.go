@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Error codes in APIError.Code. Clients switch on these rather than on the
+// message text; each maps to one HTTP status.
+const (
+	CodeInvalidInput    = "invalid_input"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodePayloadTooLarge = "payload_too_large"
+	CodeUnprocessable   = "unprocessable"
+	CodeRateLimited     = "rate_limited"
+	CodeInternal        = "internal"
+	CodeUnavailable     = "unavailable"
+	CodeTimeout         = "timeout"
+)
+
+// codeStatus maps each error code to its HTTP status.
+var codeStatus = map[string]int{
+	CodeInvalidInput:    http.StatusBadRequest,
+	CodeForbidden:       http.StatusForbidden,
+	CodeNotFound:        http.StatusNotFound,
+	CodeConflict:        http.StatusConflict,
+	CodePayloadTooLarge: http.StatusRequestEntityTooLarge,
+	CodeUnprocessable:   http.StatusUnprocessableEntity,
+	CodeRateLimited:     http.StatusTooManyRequests,
+	CodeInternal:        http.StatusInternalServerError,
+	CodeUnavailable:     http.StatusServiceUnavailable,
+	CodeTimeout:         http.StatusGatewayTimeout,
+}
+
+// APIError is the body of every API error response. The message is kept
+// under "error" so clients reading the older {"error": "..."} shape keep
+// working; Details carries optional structured context.
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"error"`
+	Details any    `json:"details,omitempty"`
+}
+
+func (e *APIError) Error() string { return e.Code + ": " + e.Message }
+
+// Status returns the HTTP status for e's code, 500 for unknown codes.
+func (e *APIError) Status() int {
+	if s, ok := codeStatus[e.Code]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// codeForStatus returns the error code for an HTTP status. Statuses without
+// their own code fall back to invalid_input (4xx) or internal (5xx).
+func codeForStatus(status int) string {
+	for code, s := range codeStatus {
+		if s == status {
+			return code
+		}
+	}
+	if status >= 400 && status < 500 {
+		return CodeInvalidInput
+	}
+	return CodeInternal
+}
+
+// writeAPIError writes e with the status its code maps to.
+func writeAPIError(w http.ResponseWriter, e *APIError) {
+	writeJSON(w, e.Status(), e)
+}
+
+// writeError writes an APIError with msg, coded from status.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, &APIError{Code: codeForStatus(status), Message: msg})
+}
+
+// writeServerError reports a failed query: 504 when it ran past the request's
+// query timeout, otherwise 500 with the error message.
+func writeServerError(w http.ResponseWriter, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeError(w, http.StatusGatewayTimeout, "query timed out")
+		return
+	}
+	writeError(w, http.StatusInternalServerError, err.Error())
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestWriteErrorCodes verifies statuses map to their error codes, with
+// unlisted statuses falling back by class, and the message stays under
+// "error" for older clients.
+func TestWriteErrorCodes(t *testing.T) {
+	cases := []struct {
+		status int
+		code   string
+	}{
+		{http.StatusBadRequest, CodeInvalidInput},
+		{http.StatusNotFound, CodeNotFound},
+		{http.StatusTooManyRequests, CodeRateLimited},
+		{http.StatusGatewayTimeout, CodeTimeout},
+		{http.StatusMethodNotAllowed, CodeInvalidInput},
+		{http.StatusBadGateway, CodeInternal},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		writeError(rec, c.status, "boom")
+		if rec.Code != c.status {
+			t.Errorf("status = %d, want %d", rec.Code, c.status)
+		}
+		var body map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatal(err)
+		}
+		if body["code"] != c.code || body["error"] != "boom" {
+			t.Errorf("%d: body = %v, want code %q", c.status, body, c.code)
+		}
+	}
+}
+
+// TestWriteAPIErrorStatus verifies an APIError is written with the status
+// its code maps to, including details.
+func TestWriteAPIErrorStatus(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeAPIError(rec, &APIError{Code: CodeConflict, Message: "exists", Details: map[string]string{"id": "x"}})
+	if rec.Code != http.StatusConflict {
+		t.Errorf("status = %d, want 409", rec.Code)
+	}
+	var body APIError
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != CodeConflict || body.Message != "exists" || body.Details == nil {
+		t.Errorf("body = %+v", body)
+	}
+
+	if s := (&APIError{Code: "made_up"}).Status(); s != http.StatusInternalServerError {
+		t.Errorf("unknown code status = %d, want 500", s)
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/claude/freereps/internal/storage"
+)
+
+// notModified looks up when table's data last changed and answers the
+// request with 304 if the client's validators are still current. A failed
+// lookup only disables caching for this request; it never fails the request.
+func (s *Server) notModified(w http.ResponseWriter, r *http.Request, uid int, table storage.DataTable, start, end time.Time) bool {
+	latest, err := s.db.GetDataVersion(r.Context(), table, uid)
+	if err != nil {
+		s.reqLog(r).Warn("etag lookup failed", "table", table, "error", err)
+		return false
+	}
+	return checkNotModified(w, r, computeETag(uid, r, start, end, latest), latest)
+}
+
+// computeETag derives a strong ETag from the user, the request path and query,
+// the resolved time range and the version (last change time) of the
+// underlying data.
+// The range is truncated to the minute so open-ended ranges ("until now") still
+// produce stable tags for clients polling within the same minute.
+func computeETag(uid int, r *http.Request, start, end, latest time.Time) string {
+	key := fmt.Sprintf("%d|%s|%s|%d|%d|%d",
+		uid, r.URL.Path, r.URL.RawQuery,
+		start.Truncate(time.Minute).Unix(), end.Truncate(time.Minute).Unix(),
+		latest.UnixNano(),
+	)
+	sum := sha256.Sum256([]byte(key))
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// checkNotModified sets ETag and Last-Modified on the response and reports
+// whether the client's cached copy is still current. When it returns true a
+// 304 has already been written and the handler should return immediately.
+// If-None-Match takes precedence over If-Modified-Since (RFC 9110 §13.2.2).
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag string, latest time.Time) bool {
+	w.Header().Set("ETag", etag)
+	if !latest.IsZero() {
+		w.Header().Set("Last-Modified", latest.UTC().Format(http.TimeFormat))
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etagMatches(inm, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !latest.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err == nil && !latest.Truncate(time.Second).After(t) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag,
+// handling lists, weak validators and the "*" wildcard.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
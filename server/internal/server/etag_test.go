@@ -0,0 +1,104 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestComputeETagStability verifies the ETag is stable for identical inputs and
+// changes when the user, query or underlying data timestamp changes — the
+// properties 304 responses rely on.
+func TestComputeETagStability(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 7)
+	latest := time.Date(2025, 1, 7, 12, 0, 0, 0, time.UTC)
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/timeseries?metric=heart_rate", nil)
+
+	base := computeETag(1, req, start, end, latest)
+	if got := computeETag(1, req, start, end.Add(10*time.Second), latest); got != base {
+		t.Errorf("sub-minute end change altered ETag: %s vs %s", got, base)
+	}
+	if computeETag(2, req, start, end, latest) == base {
+		t.Error("different user produced same ETag")
+	}
+	if computeETag(1, req, start, end, latest.Add(time.Second)) == base {
+		t.Error("newer data produced same ETag")
+	}
+	other := httptest.NewRequest(http.MethodGet, "/api/v1/timeseries?metric=hrv", nil)
+	if computeETag(1, other, start, end, latest) == base {
+		t.Error("different query produced same ETag")
+	}
+}
+
+// TestCheckNotModified verifies conditional request handling: matching
+// If-None-Match or a fresh If-Modified-Since yields 304, anything else
+// lets the handler render a full response with validators set.
+func TestCheckNotModified(t *testing.T) {
+	latest := time.Date(2025, 1, 7, 12, 0, 0, 0, time.UTC)
+	etag := `"abc"`
+
+	tests := []struct {
+		name    string
+		headers map[string]string
+		want    bool
+	}{
+		{"no validators", nil, false},
+		{"etag match", map[string]string{"If-None-Match": `"abc"`}, true},
+		{"weak etag match in list", map[string]string{"If-None-Match": `"x", W/"abc"`}, true},
+		{"etag mismatch", map[string]string{"If-None-Match": `"old"`}, false},
+		{"etag mismatch wins over fresh date", map[string]string{
+			"If-None-Match":     `"old"`,
+			"If-Modified-Since": latest.Format(http.TimeFormat),
+		}, false},
+		{"not modified since", map[string]string{"If-Modified-Since": latest.Format(http.TimeFormat)}, true},
+		{"modified since", map[string]string{"If-Modified-Since": latest.Add(-time.Hour).Format(http.TimeFormat)}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+
+			got := checkNotModified(rec, req, etag, latest)
+			if got != tt.want {
+				t.Fatalf("checkNotModified = %v, want %v", got, tt.want)
+			}
+			if got && rec.Code != http.StatusNotModified {
+				t.Errorf("status = %d, want 304", rec.Code)
+			}
+			if rec.Header().Get("ETag") != etag {
+				t.Errorf("ETag header = %q, want %q", rec.Header().Get("ETag"), etag)
+			}
+			if rec.Header().Get("Last-Modified") == "" {
+				t.Error("Last-Modified header not set")
+			}
+		})
+	}
+}
+
+// TestSummaryBucket verifies the summary endpoints only accept buckets the
+// storage layer knows how to truncate, defaulting to monthly.
+func TestSummaryBucket(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   string
+		wantOK bool
+	}{
+		{"", "1 month", true},
+		{"bucket=1+week", "1 week", true},
+		{"bucket=1+month", "1 month", true},
+		{"bucket=1+day", "", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/sleep/summary?"+tt.query, nil)
+		got, ok := summaryBucket(req)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("summaryBucket(%q) = (%q, %v), want (%q, %v)", tt.query, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
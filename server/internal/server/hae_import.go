@@ -3,8 +3,11 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"sync"
 	"time"
 
@@ -12,22 +15,25 @@ import (
 	"github.com/claude/freereps/internal/models"
 	"github.com/claude/freereps/internal/storage"
 	"github.com/claude/freereps/internal/upload"
+	"github.com/go-chi/chi/v5"
 )
 
 // haeImportState tracks a running HAE TCP import.
 type haeImportState struct {
-	mu       sync.Mutex
-	running  bool
-	cancel   context.CancelFunc
-	doneCh   chan struct{} // closed when goroutine exits
-	step     int
-	total    int
-	metric   string  // current metric/phase being processed
-	chunk    string  // current chunk date range
-	done     bool
-	err      error
-	logID    int64   // import_logs row id
-	startedAt time.Time
+	mu         sync.Mutex
+	running    bool
+	cancel     context.CancelFunc
+	doneCh     chan struct{} // closed when goroutine exits
+	step       int
+	total      int
+	metric     string // current metric/phase being processed
+	chunk      string // current chunk date range
+	done       bool
+	err        error
+	logID      int64 // import_logs row id
+	startedAt  time.Time
+	progressAt time.Time // when the current step started; see watchImport
+	finishOnce sync.Once
 
 	// Result counters (accumulated from ingest.Result per chunk)
 	metricsReceived  int
@@ -36,8 +42,9 @@ type haeImportState struct {
 	workoutsInserted int
 	sleepSessions    int
 	bytesFetched     int64
-	haeHost          string
-	haePort          int
+
+	// req is the import's configuration, kept so the log can be re-run.
+	req haeImportRequest
 
 	// SSE subscribers
 	subs   map[chan sseEvent]struct{}
@@ -80,35 +87,155 @@ func (st *haeImportState) unsubscribe(ch chan sseEvent) {
 type haeImportRequest struct {
 	HAEHost   string `json:"hae_host"`
 	HAEPort   int    `json:"hae_port"`
-	Start     string `json:"start"`     // YYYY-MM-DD
-	End       string `json:"end"`       // YYYY-MM-DD
+	Start     string `json:"start"` // YYYY-MM-DD
+	End       string `json:"end"`   // YYYY-MM-DD
 	ChunkDays int    `json:"chunk_days"`
 	DryRun    bool   `json:"dry_run"`
+
+	// MetricChunkDays overrides chunk_days per metric name.
+	MetricChunkDays map[string]int `json:"metric_chunk_days,omitempty"`
+	// RequestDelayMs is waited between consecutive HAE queries.
+	RequestDelayMs int `json:"request_delay_ms,omitempty"`
+	// HAEToken authenticates against HAE servers that require a token. It is
+	// never written to the import log, so reruns must supply it again.
+	HAEToken string `json:"hae_token,omitempty"`
+}
+
+// tcpOptions returns the request's pacing as upload.TCPOptions.
+func (req *haeImportRequest) tcpOptions() upload.TCPOptions {
+	return upload.TCPOptions{
+		ChunkDays:       req.ChunkDays,
+		MetricChunkDays: req.MetricChunkDays,
+		RequestDelay:    time.Duration(req.RequestDelayMs) * time.Millisecond,
+		Token:           req.HAEToken,
+	}
 }
 
 func (s *Server) handleCheckHAE(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		HAEHost string `json:"hae_host"`
-		HAEPort int    `json:"hae_port"`
+		HAEHost  string `json:"hae_host"`
+		HAEPort  int    `json:"hae_port"`
+		HAEToken string `json:"hae_token"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
 		return
 	}
 	if req.HAEHost == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "hae_host is required"})
+		writeError(w, http.StatusBadRequest, "hae_host is required")
 		return
 	}
 	if req.HAEPort == 0 {
 		req.HAEPort = 9000
 	}
 
-	client := upload.NewHAEClient(req.HAEHost, req.HAEPort)
+	client := upload.NewHAEClient(req.HAEHost, req.HAEPort, req.HAEToken)
 	if err := client.Ping(); err != nil {
 		writeJSON(w, http.StatusOK, map[string]any{"reachable": false, "error": err.Error()})
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"reachable": true})
+
+	// Introspection is optional; servers without listTools still import fine.
+	info, err := client.ServerInfo()
+	if err != nil {
+		s.reqLog(r).Debug("HAE server introspection unavailable", "error", err)
+		writeJSON(w, http.StatusOK, map[string]any{"reachable": true, "introspection": false})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"reachable":     true,
+		"introspection": true,
+		"version":       info.Version,
+		"tools":         info.Tools,
+		"missing_tools": info.MissingTools(),
+	})
+}
+
+// errImportRunning is returned by launchHAEImport when another import is
+// still in progress.
+var errImportRunning = errors.New("an import is already running")
+
+// defaultMaxImportChunks is the chunk cap used until SetMaxImportChunks is called.
+const defaultMaxImportChunks = 260
+
+// normalize applies defaults, parses the request's date range, and rejects
+// ranges that are inverted, end after today, or would need more than
+// maxChunks chunks. The returned end is exclusive: the day after req.End.
+func (req *haeImportRequest) normalize(now time.Time, maxChunks int) (start, end time.Time, err error) {
+	if req.HAEHost == "" {
+		return time.Time{}, time.Time{}, errors.New("hae_host is required")
+	}
+	if req.HAEPort == 0 {
+		req.HAEPort = 9000
+	}
+	if req.ChunkDays == 0 {
+		req.ChunkDays = 7
+	}
+	if req.ChunkDays < 0 {
+		return time.Time{}, time.Time{}, errors.New("chunk_days must be positive")
+	}
+	if err := req.tcpOptions().Validate(); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	start, err = time.Parse("2006-01-02", req.Start)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start date (YYYY-MM-DD): %w", err)
+	}
+	end, err = time.Parse("2006-01-02", req.End)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end date (YYYY-MM-DD): %w", err)
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("start %s is after end %s", req.Start, req.End)
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if end.After(today) {
+		return time.Time{}, time.Time{}, fmt.Errorf("end %s is in the future; the latest allowed end is %s", req.End, today.Format("2006-01-02"))
+	}
+
+	// Make end date inclusive: advance to start of next day so queries
+	// cover the entire end date (YYYY-MM-DD 00:00 → YYYY-MM-DD+1 00:00).
+	end = end.AddDate(0, 0, 1)
+
+	// The smallest chunk size, default or override, sets the chunk count.
+	chunkDays := req.ChunkDays
+	for _, d := range req.MetricChunkDays {
+		chunkDays = min(chunkDays, d)
+	}
+	days := int(end.Sub(start).Hours() / 24)
+	chunks := upload.CountChunks(start, end, chunkDays)
+	if maxChunks > 0 && chunks > maxChunks {
+		return time.Time{}, time.Time{}, fmt.Errorf(
+			"range of %d days needs %d chunks of %d days, over the limit of %d; import a shorter range or raise chunk_days",
+			days, chunks, chunkDays, maxChunks)
+	}
+	return start, end, nil
+}
+
+// importMetadata is the import_logs metadata for an HAE TCP import. It holds
+// the full request so the import can be re-run from its log.
+func importMetadata(req haeImportRequest, bytesFetched int64) *json.RawMessage {
+	meta := map[string]any{
+		"hae_host":   req.HAEHost,
+		"hae_port":   req.HAEPort,
+		"start":      req.Start,
+		"end":        req.End,
+		"chunk_days": req.ChunkDays,
+		"dry_run":    req.DryRun,
+	}
+	if len(req.MetricChunkDays) > 0 {
+		meta["metric_chunk_days"] = req.MetricChunkDays
+	}
+	if req.RequestDelayMs > 0 {
+		meta["request_delay_ms"] = req.RequestDelayMs
+	}
+	if bytesFetched > 0 {
+		meta["bytes_fetched"] = bytesFetched
+	}
+	metaJSON, _ := json.Marshal(meta)
+	raw := json.RawMessage(metaJSON)
+	return &raw
 }
 
 func (s *Server) handleStartHAEImport(w http.ResponseWriter, r *http.Request) {
@@ -119,125 +246,184 @@ func (s *Server) handleStartHAEImport(w http.ResponseWriter, r *http.Request) {
 
 	var req haeImportRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	startDate, endDate, err := req.normalize(time.Now(), s.maxImportChunks)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	if req.HAEHost == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "hae_host is required"})
+	logID, totalSteps, err := s.launchHAEImport(r.Context(), uid, req, startDate, endDate)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
 		return
 	}
-	if req.HAEPort == 0 {
-		req.HAEPort = 9000
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"status":      "started",
+		"total_steps": totalSteps,
+		"log_id":      logID,
+	})
+}
+
+// handleRerunHAEImport starts a fresh import with the host, port, range, and
+// chunking stored in one of the user's earlier HAE TCP import logs.
+func (s *Server) handleRerunHAEImport(w http.ResponseWriter, r *http.Request) {
+	prevID, err := strconv.ParseInt(chi.URLParam(r, "logId"), 10, 64)
+	if err != nil || prevID <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid import log id")
+		return
 	}
-	if req.ChunkDays == 0 {
-		req.ChunkDays = 7
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
 	}
 
-	startDate, err := time.Parse("2006-01-02", req.Start)
+	prev, err := s.db.GetImportLog(r.Context(), prevID, uid)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid start date (YYYY-MM-DD): " + err.Error()})
+		writeServerError(w, err)
 		return
 	}
-	endDate, err := time.Parse("2006-01-02", req.End)
+	if prev == nil || prev.Source != "hae_tcp" {
+		writeError(w, http.StatusNotFound, "HAE TCP import log not found")
+		return
+	}
+
+	var req haeImportRequest
+	if prev.Metadata != nil {
+		_ = json.Unmarshal(*prev.Metadata, &req)
+	}
+	// The token isn't stored with the log; accept it in an optional body.
+	var body struct {
+		HAEToken string `json:"hae_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	req.HAEToken = body.HAEToken
+	startDate, endDate, err := req.normalize(time.Now(), s.maxImportChunks)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid end date (YYYY-MM-DD): " + err.Error()})
+		writeError(w, http.StatusUnprocessableEntity, "import log has no usable config: "+err.Error())
 		return
 	}
-	// Make end date inclusive: advance to start of next day so queries
-	// cover the entire end date (YYYY-MM-DD 00:00 → YYYY-MM-DD+1 00:00).
-	endDate = endDate.AddDate(0, 0, 1)
 
+	logID, totalSteps, err := s.launchHAEImport(r.Context(), uid, req, startDate, endDate)
+	if err != nil {
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"status":      "started",
+		"total_steps": totalSteps,
+		"log_id":      logID,
+		"rerun_of":    prevID,
+	})
+}
+
+// importWaitTimeout is how long a new import waits for the user's previous
+// one to exit (e.g. right after a cancel) before giving up.
+var importWaitTimeout = 5 * time.Second
+
+func (st *haeImportState) isRunning() bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.running
+}
+
+// userImport returns the user's current or most recent import, or nil.
+func (s *Server) userImport(uid int) *haeImportState {
 	s.importMu.Lock()
-	if s.activeImport != nil && s.activeImport.running {
-		// If context was already canceled, wait briefly for the goroutine to finish
-		prev := s.activeImport
+	defer s.importMu.Unlock()
+	return s.imports[uid]
+}
+
+// claimImport registers state as the user's active import. Imports of other
+// users don't interfere; if this user's previous import is still running it
+// waits up to importWaitTimeout for it to exit and otherwise returns
+// errImportRunning.
+func (s *Server) claimImport(uid int, state *haeImportState) error {
+	for {
+		s.importMu.Lock()
+		prev := s.imports[uid]
+		if prev == nil || !prev.isRunning() {
+			if s.imports == nil {
+				s.imports = make(map[int]*haeImportState)
+			}
+			s.imports[uid] = state
+			s.importMu.Unlock()
+			return nil
+		}
 		s.importMu.Unlock()
+
 		select {
 		case <-prev.doneCh:
-			// Goroutine finished, proceed to start a new import
-		case <-time.After(5 * time.Second):
-			writeJSON(w, http.StatusConflict, map[string]string{"error": "an import is already running"})
-			return
+			// Goroutine finished; claim again.
+		case <-time.After(importWaitTimeout):
+			return errImportRunning
 		}
-		s.importMu.Lock()
 	}
+}
 
-	// Calculate total steps
-	chunkDur := time.Duration(req.ChunkDays) * 24 * time.Hour
-	numChunks := 0
-	for cs := startDate; cs.Before(endDate); cs = cs.Add(chunkDur) {
-		numChunks++
-	}
-	totalSteps := len(upload.TCPMetrics)*numChunks + numChunks
+// launchHAEImport records a "running" import log and starts the import in the
+// background. Returns errImportRunning if the user's previous import doesn't
+// finish within importWaitTimeout.
+func (s *Server) launchHAEImport(ctx context.Context, uid int, req haeImportRequest, startDate, endDate time.Time) (int64, int, error) {
+	totalSteps := req.tcpOptions().TotalRequests(startDate, endDate)
 
-	ctx, cancel := context.WithCancel(context.Background())
+	importCtx, cancel := context.WithCancel(context.Background())
 	state := &haeImportState{
-		running:   true,
-		cancel:    cancel,
-		doneCh:    make(chan struct{}),
-		total:     totalSteps,
-		startedAt: time.Now(),
-		subs:      make(map[chan sseEvent]struct{}),
-		haeHost:   req.HAEHost,
-		haePort:   req.HAEPort,
+		running:    true,
+		cancel:     cancel,
+		doneCh:     make(chan struct{}),
+		total:      totalSteps,
+		startedAt:  time.Now(),
+		progressAt: time.Now(),
+		subs:       make(map[chan sseEvent]struct{}),
+		req:        req,
+	}
+	if err := s.claimImport(uid, state); err != nil {
+		cancel()
+		return 0, 0, err
 	}
 
 	// Create import log with "running" status
-	metaJSON, _ := json.Marshal(map[string]any{
-		"hae_host":   req.HAEHost,
-		"hae_port":   req.HAEPort,
-		"start":      req.Start,
-		"end":        req.End,
-		"chunk_days": req.ChunkDays,
-		"dry_run":    req.DryRun,
-	})
-	rawMeta := json.RawMessage(metaJSON)
-	logID, logErr := s.db.InsertImportLog(r.Context(), storage.ImportLog{
+	logID, logErr := s.db.InsertImportLog(ctx, storage.ImportLog{
 		UserID:   uid,
 		Source:   "hae_tcp",
 		Status:   "running",
-		Metadata: &rawMeta,
+		Metadata: importMetadata(req, 0),
 	})
 	if logErr != nil {
 		s.log.Error("failed to create import log", "error", logErr)
 	}
+	state.mu.Lock()
 	state.logID = logID
-
-	s.activeImport = state
-	s.importMu.Unlock()
+	state.mu.Unlock()
 
 	// Start background goroutine
-	go s.runHAEImport(ctx, state, uid, req, startDate, endDate)
+	go s.runHAEImport(importCtx, state, uid, req, startDate, endDate)
 
-	writeJSON(w, http.StatusAccepted, map[string]any{
-		"status":      "started",
-		"total_steps": totalSteps,
-		"log_id":      logID,
-	})
+	return logID, totalSteps, nil
 }
 
 func (s *Server) runHAEImport(ctx context.Context, state *haeImportState, userID int, req haeImportRequest, start, end time.Time) {
-	defer func() {
-		state.mu.Lock()
-		state.running = false
-		state.done = true
-		state.mu.Unlock()
-		close(state.doneCh)
-	}()
+	defer s.finishImport(state, userID)
+	if s.importStallTimeout > 0 {
+		go s.watchImport(ctx, state, userID)
+	}
 
-	haeClient := upload.NewHAEClient(req.HAEHost, req.HAEPort)
-	chunkDur := time.Duration(req.ChunkDays) * 24 * time.Hour
+	opts := req.tcpOptions()
+	haeClient := upload.NewHAEClient(req.HAEHost, req.HAEPort, opts.Token)
 	currentStep := 0
 
 	// Phase 1: Health metrics
 	for _, m := range upload.TCPMetrics {
+		chunkDur := time.Duration(opts.ChunkDaysFor(m.Name)) * 24 * time.Hour
 		for chunkStart := start; chunkStart.Before(end); chunkStart = chunkStart.Add(chunkDur) {
 			if ctx.Err() != nil {
-				state.mu.Lock()
-				state.err = fmt.Errorf("import canceled by user")
-				state.mu.Unlock()
-				s.finalizeImport(state, userID)
+				state.markCanceled()
 				return
 			}
 
@@ -250,6 +436,7 @@ func (s *Server) runHAEImport(ctx context.Context, state *haeImportState, userID
 			chunkRange := fmt.Sprintf("%s → %s", chunkStart.Format("2006-01-02"), chunkEnd.Format("2006-01-02"))
 			state.mu.Lock()
 			state.step = currentStep
+			state.progressAt = time.Now()
 			state.metric = m.Name
 			state.chunk = chunkRange
 			state.mu.Unlock()
@@ -265,6 +452,9 @@ func (s *Server) runHAEImport(ctx context.Context, state *haeImportState, userID
 				}),
 			})
 
+			if currentStep > 1 && !waitDelay(ctx, opts.RequestDelay) {
+				continue // canceled; handled at the top of the loop
+			}
 			result, err := haeClient.QueryMetricsWithRetry(chunkStart, chunkEnd, m.Name, m.Aggregate, s.log)
 			if err != nil {
 				s.log.Warn("HAE TCP query failed, skipping",
@@ -276,6 +466,11 @@ func (s *Server) runHAEImport(ctx context.Context, state *haeImportState, userID
 				continue
 			}
 
+			// A cancel or the stall watchdog may have ended the import while
+			// the query ran; its log is final, so don't ingest more.
+			if ctx.Err() != nil {
+				continue // handled at the top of the loop
+			}
 			if !req.DryRun {
 				ir, err := s.ingestRawHAEResult(ctx, result, userID)
 				if err != nil {
@@ -296,12 +491,10 @@ func (s *Server) runHAEImport(ctx context.Context, state *haeImportState, userID
 	}
 
 	// Phase 2: Workouts
+	chunkDur := time.Duration(opts.ChunkDays) * 24 * time.Hour
 	for chunkStart := start; chunkStart.Before(end); chunkStart = chunkStart.Add(chunkDur) {
 		if ctx.Err() != nil {
-			state.mu.Lock()
-			state.err = fmt.Errorf("import canceled by user")
-			state.mu.Unlock()
-			s.finalizeImport(state, userID)
+			state.markCanceled()
 			return
 		}
 
@@ -314,6 +507,7 @@ func (s *Server) runHAEImport(ctx context.Context, state *haeImportState, userID
 		chunkRange := fmt.Sprintf("%s → %s", chunkStart.Format("2006-01-02"), chunkEnd.Format("2006-01-02"))
 		state.mu.Lock()
 		state.step = currentStep
+		state.progressAt = time.Now()
 		state.metric = "workouts"
 		state.chunk = chunkRange
 		state.mu.Unlock()
@@ -329,6 +523,9 @@ func (s *Server) runHAEImport(ctx context.Context, state *haeImportState, userID
 			}),
 		})
 
+		if currentStep > 1 && !waitDelay(ctx, opts.RequestDelay) {
+			continue // canceled; handled at the top of the loop
+		}
 		result, err := haeClient.QueryWorkoutsWithRetry(chunkStart, chunkEnd, s.log)
 		if err != nil {
 			s.log.Warn("HAE TCP workout query failed", "chunk", chunkRange, "error", err)
@@ -339,6 +536,9 @@ func (s *Server) runHAEImport(ctx context.Context, state *haeImportState, userID
 			continue
 		}
 
+		if ctx.Err() != nil {
+			continue // ended while querying; handled at the top of the loop
+		}
 		if !req.DryRun {
 			ir, err := s.ingestRawHAEResult(ctx, result, userID)
 			if err != nil {
@@ -356,6 +556,13 @@ func (s *Server) runHAEImport(ctx context.Context, state *haeImportState, userID
 		state.mu.Unlock()
 	}
 
+	// The loops only check ctx before each chunk, so a cancel during the
+	// last one lands here.
+	if ctx.Err() != nil {
+		state.markCanceled()
+		return
+	}
+
 	// Backfill sleep sessions from newly imported stages
 	if err := s.db.BackfillSleepSessions(ctx, s.log); err != nil {
 		s.log.Warn("sleep session backfill after import failed", "error", err)
@@ -363,20 +570,105 @@ func (s *Server) runHAEImport(ctx context.Context, state *haeImportState, userID
 
 	s.db.InvalidateAllAvailableMetrics()
 
+	if ctx.Err() != nil {
+		state.markCanceled()
+		return
+	}
+
 	// Broadcast completion
-	state.broadcast(sseEvent{
-		Event: "complete",
-		Data: mustJSON(map[string]any{
-			"metrics_received":  state.metricsReceived,
-			"metrics_inserted":  state.metricsInserted,
-			"workouts_received": state.workoutsReceived,
-			"workouts_inserted": state.workoutsInserted,
-			"sleep_sessions":    state.sleepSessions,
-			"bytes_fetched":     state.bytesFetched,
-		}),
+	state.broadcast(sseEvent{Event: "complete", Data: mustJSON(state.counts())})
+}
+
+// counts returns the result counters accumulated so far, as sent with the
+// terminal "complete" and "cancelled" events.
+func (st *haeImportState) counts() map[string]any {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return map[string]any{
+		"metrics_received":  st.metricsReceived,
+		"metrics_inserted":  st.metricsInserted,
+		"workouts_received": st.workoutsReceived,
+		"workouts_inserted": st.workoutsInserted,
+		"sleep_sessions":    st.sleepSessions,
+		"bytes_fetched":     st.bytesFetched,
+	}
+}
+
+// markCanceled records a user cancel and sends subscribers a terminal
+// "cancelled" event with the partial counts. An import that already failed
+// (e.g. ended by the watchdog) keeps its error and sends nothing more.
+func (st *haeImportState) markCanceled() {
+	st.mu.Lock()
+	if st.err != nil {
+		st.mu.Unlock()
+		return
+	}
+	st.err = fmt.Errorf("import canceled by user")
+	st.mu.Unlock()
+	st.broadcast(sseEvent{Event: "cancelled", Data: mustJSON(st.counts())})
+}
+
+// finishImport finalizes the import log and releases the user's import. It
+// runs once, whether the import completes, is canceled, or stalls.
+func (s *Server) finishImport(state *haeImportState, userID int) {
+	state.finishOnce.Do(func() {
+		s.finalizeImport(state, userID)
+		state.mu.Lock()
+		state.running = false
+		state.done = true
+		state.mu.Unlock()
+		close(state.doneCh)
 	})
+}
 
-	s.finalizeImport(state, userID)
+// watchImport ends the import as errored once no new step has started for
+// importStallTimeout. HAE reads don't observe ctx, so a server that stops
+// answering mid-read would otherwise leave the import running and block the
+// user's next one; the watchdog cancels ctx and finishes the import itself.
+func (s *Server) watchImport(ctx context.Context, state *haeImportState, userID int) {
+	tick := time.NewTicker(max(s.importStallTimeout/10, 10*time.Millisecond))
+	defer tick.Stop()
+	for {
+		select {
+		case <-state.doneCh:
+			return
+		case <-ctx.Done():
+			return
+		case now := <-tick.C:
+			state.mu.Lock()
+			idle := now.Sub(state.progressAt)
+			if idle < s.importStallTimeout {
+				state.mu.Unlock()
+				continue
+			}
+			state.err = fmt.Errorf("import stalled: no progress for %s at step %d/%d (%s %s)",
+				idle.Round(time.Second), state.step, state.total, state.metric, state.chunk)
+			msg := state.err.Error()
+			state.mu.Unlock()
+
+			s.log.Warn("HAE TCP import stalled, ending it", "user_id", userID, "log_id", state.logID, "error", msg)
+			state.cancel()
+			state.broadcast(sseEvent{Event: "error", Data: mustJSON(map[string]any{"error": msg})})
+			s.finishImport(state, userID)
+			return
+		}
+	}
+}
+
+// waitDelay pauses for d between HAE queries. It returns false as soon as
+// ctx is canceled so a cancel doesn't wait out the delay.
+func waitDelay(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
 // ingestRawHAEResult parses a raw HAE JSON-RPC result and ingests it via the HAE provider.
@@ -410,13 +702,6 @@ func (s *Server) finalizeImport(state *haeImportState, userID int) {
 	ctx, cancel := contextWithTimeout()
 	defer cancel()
 
-	metaJSON, _ := json.Marshal(map[string]any{
-		"bytes_fetched": state.bytesFetched,
-		"hae_host":      state.haeHost,
-		"hae_port":      state.haePort,
-	})
-	rawMeta := json.RawMessage(metaJSON)
-
 	if err := s.db.UpdateImportLog(ctx, state.logID, storage.ImportLog{
 		Status:           status,
 		MetricsReceived:  state.metricsReceived,
@@ -426,23 +711,23 @@ func (s *Server) finalizeImport(state *haeImportState, userID int) {
 		SleepSessions:    state.sleepSessions,
 		DurationMs:       &durationMs,
 		ErrorMessage:     errMsg,
-		Metadata:         &rawMeta,
+		Metadata:         importMetadata(state.req, state.bytesFetched),
 	}); err != nil {
 		s.log.Error("failed to finalize import log", "log_id", state.logID, "error", err)
 	}
 }
 
 func (s *Server) handleCancelHAEImport(w http.ResponseWriter, r *http.Request) {
-	s.importMu.Lock()
-	if s.activeImport == nil || !s.activeImport.running {
-		s.importMu.Unlock()
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no import running"})
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+	state := s.userImport(uid)
+	if state == nil || !state.isRunning() {
+		writeError(w, http.StatusNotFound, "no import running")
 		return
 	}
-
-	state := s.activeImport
 	state.cancel()
-	s.importMu.Unlock()
 
 	// Wait briefly for goroutine to finish
 	select {
@@ -454,9 +739,11 @@ func (s *Server) handleCancelHAEImport(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleHAEImportStatus(w http.ResponseWriter, r *http.Request) {
-	s.importMu.Lock()
-	state := s.activeImport
-	s.importMu.Unlock()
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+	state := s.userImport(uid)
 
 	if state == nil {
 		writeJSON(w, http.StatusOK, map[string]any{
@@ -489,19 +776,24 @@ func (s *Server) handleHAEImportStatus(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, resp)
 }
 
-func (s *Server) handleHAEImportEvents(w http.ResponseWriter, r *http.Request) {
-	s.importMu.Lock()
-	state := s.activeImport
-	s.importMu.Unlock()
+// sseKeepaliveInterval is how often an idle event stream gets a comment line,
+// so proxies don't close it while HAE is slow to answer a chunk.
+var sseKeepaliveInterval = 15 * time.Second
 
-	if state == nil || !state.running {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no import running"})
+func (s *Server) handleHAEImportEvents(w http.ResponseWriter, r *http.Request) {
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+	state := s.userImport(uid)
+	if state == nil || !state.isRunning() {
+		writeError(w, http.StatusNotFound, "no import running")
 		return
 	}
 
 	flusher, ok := w.(http.Flusher)
 	if !ok {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "streaming not supported"})
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
 		return
 	}
 
@@ -515,18 +807,24 @@ func (s *Server) handleHAEImportEvents(w http.ResponseWriter, r *http.Request) {
 	// Send current status immediately
 	state.mu.Lock()
 	_, _ = fmt.Fprintf(w, "event: status\ndata: %s\n\n", mustJSON(map[string]any{
-		"step":  state.step,
-		"total": state.total,
+		"step":   state.step,
+		"total":  state.total,
 		"metric": state.metric,
 		"chunk":  state.chunk,
 	}))
 	state.mu.Unlock()
 	flusher.Flush()
 
+	keepalive := time.NewTicker(sseKeepaliveInterval)
+	defer keepalive.Stop()
+
 	for {
 		select {
 		case <-r.Context().Done():
 			return
+		case <-keepalive.C:
+			_, _ = fmt.Fprint(w, ": keepalive\n\n")
+			flusher.Flush()
 		case evt, ok := <-ch:
 			if !ok {
 				return
@@ -534,7 +832,7 @@ func (s *Server) handleHAEImportEvents(w http.ResponseWriter, r *http.Request) {
 			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", evt.Event, evt.Data)
 			flusher.Flush()
 
-			if evt.Event == "complete" || evt.Event == "error" {
+			if evt.Event == "complete" || evt.Event == "cancelled" || evt.Event == "error" {
 				return
 			}
 		}
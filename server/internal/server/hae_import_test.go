@@ -0,0 +1,471 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/upload"
+	"github.com/go-chi/chi/v5"
+)
+
+// TestHAEImportRequestNormalize verifies defaults are applied, the end date
+// becomes exclusive, and a missing host or bad date is rejected.
+func TestHAEImportRequestNormalize(t *testing.T) {
+	req := haeImportRequest{HAEHost: "iphone.local", Start: "2025-01-01", End: "2025-01-31"}
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	start, end, err := req.normalize(now, defaultMaxImportChunks)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if req.HAEPort != 9000 || req.ChunkDays != 7 {
+		t.Errorf("defaults = port %d, chunk %d; want 9000, 7", req.HAEPort, req.ChunkDays)
+	}
+	if !start.Equal(time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("range = %v – %v, want Jan 1 – Feb 1", start, end)
+	}
+
+	for _, bad := range []haeImportRequest{
+		{Start: "2025-01-01", End: "2025-01-31"},
+		{HAEHost: "iphone.local", Start: "Jan 1", End: "2025-01-31"},
+		{HAEHost: "iphone.local", Start: "2025-01-01"},
+	} {
+		if _, _, err := bad.normalize(now, defaultMaxImportChunks); err == nil {
+			t.Errorf("%+v: expected error", bad)
+		}
+	}
+}
+
+// TestHAEImportRequestRangeLimits verifies inverted ranges, ranges ending in
+// the future, and ranges needing too many chunks are rejected, while a range
+// ending today and one exactly at the chunk cap are accepted.
+func TestHAEImportRequestRangeLimits(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		req     haeImportRequest
+		max     int
+		wantErr string
+	}{
+		{"inverted", haeImportRequest{Start: "2025-05-10", End: "2025-05-01"}, 10, "is after end"},
+		{"future end", haeImportRequest{Start: "2025-05-01", End: "2025-06-02"}, 10, "in the future"},
+		{"too many chunks", haeImportRequest{Start: "2005-01-01", End: "2025-05-31"}, 260, "over the limit of 260"},
+		{"negative chunk", haeImportRequest{Start: "2025-05-01", End: "2025-05-31", ChunkDays: -1}, 10, "chunk_days"},
+		{"ends today", haeImportRequest{Start: "2025-05-26", End: "2025-06-01"}, 1, ""},
+		{"at the cap", haeImportRequest{Start: "2025-05-01", End: "2025-05-28", ChunkDays: 7}, 4, ""},
+		{"one over the cap", haeImportRequest{Start: "2025-05-01", End: "2025-05-29", ChunkDays: 7}, 4, "needs 5 chunks"},
+		{"override sets the cap", haeImportRequest{Start: "2025-05-01", End: "2025-05-28", ChunkDays: 7,
+			MetricChunkDays: map[string]int{"heart_rate": 1}}, 4, "needs 28 chunks of 1 days"},
+		{"unknown override", haeImportRequest{Start: "2025-05-01", End: "2025-05-28",
+			MetricChunkDays: map[string]int{"steps": 1}}, 10, "unknown metric"},
+		{"negative delay", haeImportRequest{Start: "2025-05-01", End: "2025-05-28", RequestDelayMs: -1}, 10, "delay"},
+	}
+	for _, tt := range tests {
+		tt.req.HAEHost = "iphone.local"
+		_, _, err := tt.req.normalize(now, tt.max)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: unexpected error: %v", tt.name, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: err = %v, want containing %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+// TestWaitDelay verifies the inter-request delay is waited out normally but
+// cut short by cancellation.
+func TestWaitDelay(t *testing.T) {
+	if !waitDelay(context.Background(), time.Millisecond) {
+		t.Error("uncanceled wait returned false")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	start := time.Now()
+	if waitDelay(ctx, time.Minute) {
+		t.Error("canceled wait returned true")
+	}
+	if time.Since(start) > time.Second {
+		t.Error("canceled wait did not return promptly")
+	}
+}
+
+// TestImportMetadataRoundTrip verifies the stored log metadata decodes back
+// into the request that produced it, which is what a re-run relies on.
+func TestImportMetadataRoundTrip(t *testing.T) {
+	orig := haeImportRequest{HAEHost: "10.0.0.5", HAEPort: 9100, Start: "2025-03-01", End: "2025-03-14", ChunkDays: 3, DryRun: true,
+		MetricChunkDays: map[string]int{"heart_rate": 1}, RequestDelayMs: 250}
+	meta := importMetadata(orig, 4096)
+
+	var got haeImportRequest
+	if err := json.Unmarshal(*meta, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, orig) {
+		t.Errorf("decoded %+v, want %+v", got, orig)
+	}
+
+	var raw map[string]any
+	_ = json.Unmarshal(*meta, &raw)
+	if raw["bytes_fetched"] != float64(4096) {
+		t.Errorf("bytes_fetched = %v, want 4096", raw["bytes_fetched"])
+	}
+}
+
+// TestHandleRerunHAEImportBadID verifies a malformed log id is rejected
+// before the log is looked up.
+func TestHandleRerunHAEImportBadID(t *testing.T) {
+	s := &Server{}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/import/hae-tcp/rerun/abc", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("logId", "abc")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+	s.handleRerunHAEImport(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+// TestHAEImportEventsKeepalive verifies an idle event stream gets keepalive
+// comments and that the handler returns once the client disconnects.
+func TestHAEImportEventsKeepalive(t *testing.T) {
+	orig := sseKeepaliveInterval
+	sseKeepaliveInterval = 5 * time.Millisecond
+	defer func() { sseKeepaliveInterval = orig }()
+
+	s := &Server{imports: map[int]*haeImportState{
+		1: {running: true, subs: make(map[chan sseEvent]struct{})},
+	}}
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), userIDKey, 1))
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/import/hae-tcp/events", nil).WithContext(ctx)
+	rec := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		s.handleHAEImportEvents(rec, req)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("handler did not return after client disconnect")
+	}
+	if n := strings.Count(rec.Body.String(), ": keepalive\n\n"); n < 2 {
+		t.Errorf("keepalives = %d, want several while idle; body:\n%s", n, rec.Body.String())
+	}
+}
+
+// TestClaimImportPerUser verifies imports for different users can run at the
+// same time while a second import for the same user is refused.
+func TestClaimImportPerUser(t *testing.T) {
+	orig := importWaitTimeout
+	importWaitTimeout = 10 * time.Millisecond
+	defer func() { importWaitTimeout = orig }()
+
+	s := &Server{}
+	newState := func() *haeImportState {
+		return &haeImportState{running: true, doneCh: make(chan struct{}), subs: make(map[chan sseEvent]struct{})}
+	}
+
+	errs := make(chan error, 2)
+	for _, uid := range []int{1, 2} {
+		go func() { errs <- s.claimImport(uid, newState()) }()
+	}
+	for range 2 {
+		if err := <-errs; err != nil {
+			t.Fatalf("concurrent imports for different users: %v", err)
+		}
+	}
+	if s.userImport(1) == s.userImport(2) {
+		t.Fatal("users share one import state")
+	}
+
+	if err := s.claimImport(1, newState()); err != errImportRunning {
+		t.Errorf("second import for user 1: err = %v, want errImportRunning", err)
+	}
+
+	// Once user 1's import finishes, they can start another.
+	first := s.userImport(1)
+	first.mu.Lock()
+	first.running = false
+	first.mu.Unlock()
+	close(first.doneCh)
+	if err := s.claimImport(1, newState()); err != nil {
+		t.Errorf("import after the previous finished: %v", err)
+	}
+}
+
+// TestHAEImportStatusScopedToUser verifies a user doesn't see another user's
+// running import.
+func TestHAEImportStatusScopedToUser(t *testing.T) {
+	s := &Server{imports: map[int]*haeImportState{
+		1: {running: true, total: 10, subs: make(map[chan sseEvent]struct{})},
+	}}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/import/hae-tcp/status", nil)
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, 2))
+	rec := httptest.NewRecorder()
+	s.handleHAEImportStatus(rec, req)
+
+	var resp map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp["running"] != false {
+		t.Errorf("user 2 status = %v, want not running", resp)
+	}
+}
+
+// startHAEStub serves every connection with response until the test ends,
+// covering both the ping dial and the listTools call.
+func startHAEStub(t *testing.T, response string) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() }) //nolint:errcheck
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 4096)
+			conn.SetReadDeadline(time.Now().Add(time.Second)) //nolint:errcheck
+			conn.Read(buf)                                    //nolint:errcheck
+			conn.Write([]byte(response))                      //nolint:errcheck
+			conn.Close()                                      //nolint:errcheck
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// TestHandleCheckHAE verifies the check reports tools, version and missing
+// tools when the server supports introspection, and falls back to plain
+// reachability when it doesn't.
+func TestHandleCheckHAE(t *testing.T) {
+	s := &Server{log: slog.New(slog.DiscardHandler)}
+	check := func(port int) map[string]any {
+		body := fmt.Sprintf(`{"hae_host":"127.0.0.1","hae_port":%d}`, port)
+		rec := httptest.NewRecorder()
+		s.handleCheckHAE(rec, httptest.NewRequest(http.MethodPost, "/api/v1/import/hae-tcp/check", strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		var got map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	got := check(startHAEStub(t, `{"jsonrpc":"2.0","id":1,"result":{"version":"8.0","tools":["health_metrics"]}}`))
+	if got["reachable"] != true || got["introspection"] != true || got["version"] != "8.0" {
+		t.Errorf("introspected check = %v", got)
+	}
+	if missing, _ := got["missing_tools"].([]any); len(missing) != 1 || missing[0] != "workouts" {
+		t.Errorf("missing_tools = %v, want [workouts]", got["missing_tools"])
+	}
+
+	got = check(startHAEStub(t, `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"Method not found"}}`))
+	if got["reachable"] != true || got["introspection"] != false {
+		t.Errorf("fallback check = %v, want reachable without introspection", got)
+	}
+	if _, ok := got["tools"]; ok {
+		t.Errorf("fallback check should not list tools: %v", got)
+	}
+}
+
+// TestImportStallWatchdog verifies an import whose HAE server stops answering
+// mid-chunk is ended as errored with the stall reason, and that the user can
+// start another import afterwards.
+func TestImportStallWatchdog(t *testing.T) {
+	// The stub accepts queries but never answers them.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	held := make(chan net.Conn, 16)
+	t.Cleanup(func() {
+		ln.Close() //nolint:errcheck
+		for {
+			select {
+			case c := <-held:
+				c.Close() //nolint:errcheck
+			default:
+				return
+			}
+		}
+	})
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			held <- conn
+		}
+	}()
+
+	s := &Server{log: slog.New(slog.DiscardHandler), importStallTimeout: 50 * time.Millisecond}
+	ctx, cancel := context.WithCancel(context.Background())
+	now := time.Now()
+	state := &haeImportState{
+		running: true, cancel: cancel, doneCh: make(chan struct{}), total: 2,
+		startedAt: now, progressAt: now, subs: make(map[chan sseEvent]struct{}),
+	}
+	if err := s.claimImport(1, state); err != nil {
+		t.Fatal(err)
+	}
+	events := state.subscribe()
+
+	req := haeImportRequest{HAEHost: "127.0.0.1", HAEPort: ln.Addr().(*net.TCPAddr).Port, ChunkDays: 1, DryRun: true}
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	go s.runHAEImport(ctx, state, 1, req, day, day.AddDate(0, 0, 1))
+
+	select {
+	case <-state.doneCh:
+	case <-time.After(2 * time.Second):
+		t.Fatal("watchdog did not end the stalled import")
+	}
+	if ctx.Err() == nil {
+		t.Error("import context was not canceled")
+	}
+	state.mu.Lock()
+	err = state.err
+	state.mu.Unlock()
+	if err == nil || !strings.Contains(err.Error(), "import stalled") || !strings.Contains(err.Error(), "step 1/2") {
+		t.Errorf("err = %v, want the stall reason at step 1/2", err)
+	}
+	// The step's progress event comes first; the stall error follows it.
+	var last sseEvent
+	for len(events) > 0 {
+		last = <-events
+	}
+	if last.Event != "error" || !strings.Contains(last.Data, "import stalled") {
+		t.Errorf("last event = %+v, want the stall error", last)
+	}
+
+	if state.isRunning() {
+		t.Error("stalled import still running")
+	}
+	next := &haeImportState{running: true, doneCh: make(chan struct{}), subs: make(map[chan sseEvent]struct{})}
+	if err := s.claimImport(1, next); err != nil {
+		t.Errorf("import after the stall: %v", err)
+	}
+}
+
+// TestHAEImportCancelEndsEvents verifies a canceled import sends subscribers
+// a terminal "cancelled" event with its partial counts and that the events
+// handler returns on it.
+func TestHAEImportCancelEndsEvents(t *testing.T) {
+	s := &Server{log: slog.New(slog.DiscardHandler)}
+	ctx, cancel := context.WithCancel(context.Background())
+	state := &haeImportState{
+		running: true, cancel: cancel, doneCh: make(chan struct{}), total: 2,
+		metricsInserted: 42, subs: make(map[chan sseEvent]struct{}),
+	}
+	if err := s.claimImport(1, state); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/import/hae-tcp/events", nil)
+	req = req.WithContext(context.WithValue(req.Context(), userIDKey, 1))
+	rec := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		s.handleHAEImportEvents(rec, req)
+		close(done)
+	}()
+	for deadline := time.Now().Add(time.Second); ; {
+		state.subsMu.Lock()
+		n := len(state.subs)
+		state.subsMu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("events handler did not subscribe")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	cancel()
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.runHAEImport(ctx, state, 1, haeImportRequest{HAEHost: "127.0.0.1", ChunkDays: 1, DryRun: true}, day, day.AddDate(0, 0, 1))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("events handler did not return after cancel")
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "event: cancelled\n") || !strings.Contains(body, `"metrics_inserted":42`) {
+		t.Errorf("body = %q, want a cancelled event with partial counts", body)
+	}
+	if state.isRunning() {
+		t.Error("canceled import still running")
+	}
+}
+
+// TestHAEImportCancelDuringLastChunk verifies a cancel that arrives while the
+// final workouts chunk is being queried ends the import as cancelled rather
+// than broadcasting "complete".
+func TestHAEImportCancelDuringLastChunk(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() }) //nolint:errcheck
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			buf := make([]byte, 4096)
+			conn.SetReadDeadline(time.Now().Add(time.Second)) //nolint:errcheck
+			n, _ := conn.Read(buf)
+			if strings.Contains(string(buf[:n]), `"name":"workouts"`) {
+				cancel()
+			}
+			conn.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"data":{}}}` + "\n")) //nolint:errcheck
+			conn.Close()                                                               //nolint:errcheck
+		}
+	}()
+
+	s := &Server{log: slog.New(slog.DiscardHandler)}
+	state := &haeImportState{
+		running: true, cancel: cancel, doneCh: make(chan struct{}), total: len(upload.TCPMetrics) + 1,
+		subs: make(map[chan sseEvent]struct{}),
+	}
+	events := make(chan sseEvent, 64)
+	state.subs[events] = struct{}{}
+
+	req := haeImportRequest{HAEHost: "127.0.0.1", HAEPort: ln.Addr().(*net.TCPAddr).Port, ChunkDays: 1, DryRun: true}
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.runHAEImport(ctx, state, 1, req, day, day.AddDate(0, 0, 1))
+
+	var last sseEvent
+	for len(events) > 0 {
+		if last = <-events; last.Event == "complete" {
+			t.Fatal("canceled import broadcast complete")
+		}
+	}
+	if last.Event != "cancelled" {
+		t.Errorf("last event = %+v, want cancelled", last)
+	}
+}
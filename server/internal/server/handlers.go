@@ -2,21 +2,66 @@ package server
 
 import (
 	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"math"
 	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/claude/freereps/internal/ingest"
+	"github.com/claude/freereps/internal/ingest/alpha"
 	"github.com/claude/freereps/internal/models"
 	"github.com/claude/freereps/internal/storage"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 )
 
-func (s *Server) handleVersion(w http.ResponseWriter, _ *http.Request) {
-	writeJSON(w, http.StatusOK, map[string]string{"version": Version})
+// versionInfo describes the running build: the ldflags version, the Go
+// toolchain, and the applied migration when the database is reachable.
+func (s *Server) versionInfo(ctx context.Context) map[string]any {
+	info := map[string]any{
+		"version":    Version,
+		"go_version": runtime.Version(),
+	}
+	if s.db != nil {
+		if v, dirty, err := s.db.SchemaVersion(ctx); err == nil {
+			info["migration_version"] = v
+			if dirty {
+				info["migration_dirty"] = true
+			}
+		}
+	}
+	return info
+}
+
+func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.versionInfo(r.Context()))
+}
+
+// handleHealthz reports liveness plus the build version, returning 503 when
+// the database doesn't answer a ping.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	resp := s.versionInfo(r.Context())
+	resp["status"] = "ok"
+	status := http.StatusOK
+	if s.db != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := s.db.Pool.Ping(ctx); err != nil {
+			resp["status"] = "unavailable"
+			resp["error"] = "database unreachable"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	writeJSON(w, status, resp)
 }
 
 func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
@@ -26,8 +71,15 @@ func (s *Server) handleMe(w http.ResponseWriter, r *http.Request) {
 
 // handleDashboardInit returns available metrics, latest metrics, and daily sums
 // in a single response. The three database queries run concurrently to minimize
-// latency compared to three separate HTTP requests.
+// latency compared to three separate HTTP requests. ?day= selects the daily
+// sums day (see dailySumsDay).
 func (s *Server) handleDashboardInit(w http.ResponseWriter, r *http.Request) {
+	day, err := dailySumsDay(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	uid, ok := mustUserID(w, r)
 	if !ok {
 		return
@@ -55,13 +107,13 @@ func (s *Server) handleDashboardInit(w http.ResponseWriter, r *http.Request) {
 	}()
 	go func() {
 		defer wg.Done()
-		sums, errSums = s.db.GetDailySums(ctx, uid, cumulativeMetrics)
+		sums, errSums = s.db.GetDailySums(ctx, uid, cumulativeMetrics, day)
 	}()
 	wg.Wait()
 
 	for _, err := range []error{errAvail, errLatest, errSums} {
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			writeServerError(w, err)
 			return
 		}
 	}
@@ -74,10 +126,87 @@ func (s *Server) handleDashboardInit(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// dashboardSleepNights and dashboardWorkoutDays bound the history included in
+// the combined dashboard response.
+const (
+	dashboardSleepNights = 7
+	dashboardWorkoutDays = 14
+)
+
+// handleDashboard returns everything the overview page renders — latest
+// metrics, today's cumulative sums, recent sleep and workouts, and data
+// freshness — in one round-trip. Queries run concurrently. ?day= selects the
+// daily sums day (see dailySumsDay).
+func (s *Server) handleDashboard(w http.ResponseWriter, r *http.Request) {
+	day, err := dailySumsDay(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ctx := r.Context()
+	now := time.Now().UTC()
+	var (
+		latest    []models.HealthMetricRow
+		sums      []storage.DailySum
+		sleep     []storage.SleepSessionResult
+		workouts  []models.WorkoutRow
+		freshness map[storage.DataTable]*time.Time
+		errs      [5]error
+		wg        sync.WaitGroup
+	)
+
+	wg.Add(5)
+	go func() {
+		defer wg.Done()
+		latest, errs[0] = s.db.GetLatestMetrics(ctx, uid)
+	}()
+	go func() {
+		defer wg.Done()
+		sums, errs[1] = s.db.GetDailySums(ctx, uid, cumulativeMetrics, day)
+	}()
+	go func() {
+		defer wg.Done()
+		sleep, errs[2] = s.db.QuerySleepSessions(ctx, now.AddDate(0, 0, -dashboardSleepNights), now, uid)
+	}()
+	go func() {
+		defer wg.Done()
+		workouts, errs[3] = s.db.QueryWorkoutsMerged(ctx, now.AddDate(0, 0, -dashboardWorkoutDays), now, uid, storage.WorkoutFilter{})
+	}()
+	go func() {
+		defer wg.Done()
+		freshness, errs[4] = s.db.GetDataFreshness(ctx, uid)
+	}()
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			s.reqLog(r).Error("dashboard query failed", "error", err)
+			writeServerError(w, err)
+			return
+		}
+	}
+
+	w.Header().Set("Cache-Control", "private, max-age=30")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"generated_at":    now,
+		"latest":          latest,
+		"daily_sums":      sums,
+		"sleep":           sleep,
+		"recent_workouts": workouts,
+		"freshness":       freshness,
+	})
+}
+
 func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
 	var payload models.HealthPayload
 	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+		writeBodyError(w, err, "invalid JSON: "+err.Error())
 		return
 	}
 
@@ -86,26 +215,37 @@ func (s *Server) handleIngest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// ?dry_run=true reports what the payload would produce without writing.
+	if r.URL.Query().Get("dry_run") == "true" {
+		result, err := s.health.Validate(r.Context(), &payload, uid)
+		if err != nil {
+			writeServerError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, result)
+		return
+	}
+
 	start := time.Now()
 	result, err := s.health.Ingest(r.Context(), &payload, uid)
 	durationMs := int(time.Since(start).Milliseconds())
 	if err != nil {
-		s.log.Error("ingest error", "error", err)
+		s.reqLog(r).Error("ingest error", "error", err)
 		if result != nil {
-			go s.logImport(uid, "hae_rest", result, err, durationMs)
+			s.logImport(uid, "hae_rest", result, err, durationMs)
 		}
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 
 	if result.SleepStagesInserted > 0 {
 		if err := s.db.BackfillSleepSessions(r.Context(), s.log); err != nil {
-			s.log.Warn("sleep session backfill after REST ingest failed", "error", err)
+			s.reqLog(r).Warn("sleep session backfill after REST ingest failed", "error", err)
 		}
 	}
 
 	s.db.InvalidateAllAvailableMetrics()
-	go s.logImport(uid, "hae_rest", result, nil, durationMs)
+	s.logImport(uid, "hae_rest", result, nil, durationMs)
 	writeJSON(w, http.StatusOK, result)
 }
 
@@ -119,105 +259,845 @@ func (s *Server) handleAlphaIngest(w http.ResponseWriter, r *http.Request) {
 	result, err := s.alpha.Ingest(r.Context(), r.Body, uid)
 	durationMs := int(time.Since(start).Milliseconds())
 	if err != nil {
-		s.log.Error("alpha ingest error", "error", err)
+		s.reqLog(r).Error("alpha ingest error", "error", err)
 		if result != nil {
-			go s.logImport(uid, "alpha", result, err, durationMs)
+			s.logImport(uid, "alpha", result, err, durationMs)
 		}
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeBodyError(w, err, err.Error())
+		return
+	}
+
+	s.db.InvalidateAllAvailableMetrics()
+	s.logImport(uid, "alpha", result, nil, durationMs)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleFITIngest imports a Garmin FIT file sent as the raw request body.
+func (s *Server) handleFITIngest(w http.ResponseWriter, r *http.Request) {
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	start := time.Now()
+	result, err := s.fit.Ingest(r.Context(), r.Body, uid)
+	durationMs := int(time.Since(start).Milliseconds())
+	if err != nil {
+		s.reqLog(r).Error("fit ingest error", "error", err)
+		if result != nil {
+			s.logImport(uid, "fit", result, err, durationMs)
+		}
+		writeBodyError(w, err, err.Error())
+		return
+	}
+
+	s.db.InvalidateAllAvailableMetrics()
+	s.logImport(uid, "fit", result, nil, durationMs)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleExportAlpha returns the user's strength sets in [start, end) as an
+// Alpha Progression CSV export, newest session first.
+func (s *Server) handleExportAlpha(w http.ResponseWriter, r *http.Request) {
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	rows, err := s.db.QueryWorkoutSets(r.Context(), start, end, uid, "")
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="alpha-export.csv"`)
+	if err := alpha.Write(w, alpha.SessionsFromRows(rows)); err != nil {
+		s.reqLog(r).Error("alpha export failed", "error", err)
+	}
+}
+
+// handleChanges lists the health metrics, workouts, and sleep sessions
+// created or updated after ?after= (RFC 3339 for the first poll, then the
+// previous poll's continue token), for clients that sync incrementally
+// instead of re-fetching whole ranges. Deleted rows are not listed.
+func (s *Server) handleChanges(w http.ResponseWriter, r *http.Request) {
+	after, err := parseAfter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if after == nil {
+		writeError(w, http.StatusBadRequest, "after parameter required")
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	changes, err := s.db.GetChanges(r.Context(), *after, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	setContinueToken(w, changes.NextAfter)
+	writeJSON(w, http.StatusOK, changes)
+}
+
+// continueTokenHeader carries the cursor for the next page of a paged
+// endpoint (raw metric export, changes, sleep stages). Clients pass it back
+// as ?after=; JSON responses also include it as next_after.
+const continueTokenHeader = "X-Continue-Token"
+
+// parseAfter reads the ?after= continue token, returning nil when unset.
+func parseAfter(r *http.Request) (*time.Time, error) {
+	v := r.URL.Query().Get("after")
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, v)
+	if err != nil {
+		return nil, errors.New("invalid after, expected a continue token")
+	}
+	return &t, nil
+}
+
+// setContinueToken sets the continue token header to t.
+func setContinueToken(w http.ResponseWriter, t time.Time) {
+	w.Header().Set(continueTokenHeader, t.UTC().Format(time.RFC3339Nano))
+}
+
+// maxExportPageRows caps the rows in one page of a raw metric export.
+const maxExportPageRows = 100000
+
+// exportFlushEvery is how many CSV rows are written between flushes.
+const exportFlushEvery = 1000
+
+// handleExportMetric streams a metric's raw rows in [start, end) as CSV, one
+// page at a time. A page holds at most ?limit= rows (default and cap
+// maxExportPageRows, plus any rows sharing the last timestamp). When more rows
+// remain, the continue token header carries the last timestamp of the page;
+// passing it back as ?after= returns the next page.
+func (s *Server) handleExportMetric(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	metric := q.Get("metric")
+	if metric == "" {
+		writeError(w, http.StatusBadRequest, "metric parameter required")
+		return
+	}
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit := maxExportPageRows
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxExportPageRows {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be 1-%d", maxExportPageRows))
+			return
+		}
+		limit = n
+	}
+	after, err := parseAfter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := s.db.PlanMetricExportPage(r.Context(), metric, start, end, after, limit, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.csv"`, metric))
+	if page.More {
+		setContinueToken(w, *page.Through)
+	}
+
+	flusher, _ := w.(http.Flusher)
+	cw := csv.NewWriter(w)
+	_ = cw.Write([]string{"time", "source", "units", "qty", "min", "avg", "max", "systolic", "diastolic"})
+	written := 0
+	_, err = s.db.StreamHealthMetrics(r.Context(), metric, start, end, after, page.Through, uid, func(m models.HealthMetricRow) error {
+		if err := cw.Write([]string{
+			m.Time.UTC().Format(time.RFC3339Nano), m.Source, m.Units,
+			csvFloat(m.Qty), csvFloat(m.MinVal), csvFloat(m.AvgVal), csvFloat(m.MaxVal),
+			csvFloat(m.Systolic), csvFloat(m.Diastolic),
+		}); err != nil {
+			return err
+		}
+		if written++; written%exportFlushEvery == 0 {
+			cw.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return cw.Error()
+	})
+	cw.Flush()
+	if err != nil {
+		// Headers are already sent; the truncated body is all we can signal.
+		s.reqLog(r).Error("metric export failed", "metric", metric, "error", err)
+	}
+}
+
+// csvFloat formats an optional value for CSV, empty when nil.
+func csvFloat(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
+
+func (s *Server) handleUnifiedImport(w http.ResponseWriter, r *http.Request) {
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeBodyError(w, err, "failed to read body")
+		return
+	}
+
+	format := ingest.DetectFormat(data)
+	start := time.Now()
+
+	var result *ingest.Result
+	switch format {
+	case ingest.FormatAlpha:
+		result, err = s.alpha.Ingest(r.Context(), bytes.NewReader(data), uid)
+	case ingest.FormatFIT:
+		result, err = s.fit.Ingest(r.Context(), bytes.NewReader(data), uid)
+	default:
+		writeAPIError(w, &APIError{
+			Code:    CodeUnprocessable,
+			Message: "unrecognized file format",
+			Details: map[string]any{"supported": []string{string(ingest.FormatAlpha), string(ingest.FormatFIT)}},
+		})
+		return
+	}
+	durationMs := int(time.Since(start).Milliseconds())
+	if err != nil {
+		s.reqLog(r).Error("unified import error", "format", format, "error", err)
+		if result != nil {
+			s.logImport(uid, "import_auto", result, err, durationMs)
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.db.InvalidateAllAvailableMetrics()
+	s.logImport(uid, "import_auto", result, nil, durationMs)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// cumulativeMetrics are metrics that should show daily totals instead of latest value.
+var cumulativeMetrics = []string{
+	"active_energy", "basal_energy_burned", "apple_exercise_time",
+	"step_count", "distance_walking_running", "distance_cycling",
+	"distance_swimming", "distance_wheelchair", "flights_climbed",
+	"apple_move_time", "apple_stand_time", "push_count",
+	"swimming_stroke_count", "distance_downhill_snow_sports",
+}
+
+// dailySumsDay reads ?day= for daily sums: "latest" (the default) sums the
+// most recent day with data, "today" the current day so far.
+func dailySumsDay(r *http.Request) (string, error) {
+	day := r.URL.Query().Get("day")
+	if day == "" {
+		return storage.DailySumsLatest, nil
+	}
+	if !storage.ValidDailySumsDay(day) {
+		return "", fmt.Errorf("day must be %q or %q", storage.DailySumsLatest, storage.DailySumsToday)
+	}
+	return day, nil
+}
+
+func (s *Server) handleLatestMetrics(w http.ResponseWriter, r *http.Request) {
+	day, err := dailySumsDay(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+	rows, err := s.db.GetLatestMetrics(r.Context(), uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+
+	// Get daily sums for cumulative metrics
+	sums, err := s.db.GetDailySums(r.Context(), uid, cumulativeMetrics, day)
+	if err != nil {
+		s.reqLog(r).Error("daily sums error", "error", err)
+		// Non-fatal: continue with latest values
+		writeJSON(w, http.StatusOK, rows)
+		return
+	}
+
+	// Build response with daily_sums field
+	w.Header().Set("Cache-Control", "private, max-age=60")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"latest":     rows,
+		"daily_sums": sums,
+	})
+}
+
+// defaultMaxRawRows is the raw row cap used until SetMaxRawRows is called.
+const defaultMaxRawRows = 50000
+
+func (s *Server) handleQueryMetrics(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		writeError(w, http.StatusBadRequest, "name parameter required")
+		return
+	}
+
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Ranges with more rows than the cap (a month of heart_rate is hundreds
+	// of thousands) come back bucketed instead, flagged as downsampled.
+	count, err := s.db.CountHealthMetrics(r.Context(), name, start, end, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if count > int64(s.maxRawRows) {
+		bucket := storage.DownsampleBucket(start, end, s.maxRawRows)
+		points, err := s.db.GetTimeSeries(r.Context(), name, start, end, bucket, uid)
+		if err != nil {
+			writeServerError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, metricsResponse(nil, points, bucket, count))
+		return
+	}
+
+	rows, err := s.db.QueryHealthMetrics(r.Context(), name, start, end, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, metricsResponse(rows, nil, "", count))
+}
+
+// metricsResponse is the GET /api/v1/metrics envelope. Its shape is the same
+// either way: raw rows fill "rows", while a range over the row cap fills
+// "points" with buckets of the given size and sets "downsampled".
+func metricsResponse(rows []models.HealthMetricRow, points []storage.TimeSeriesPoint, bucket string, rawRows int64) map[string]any {
+	if rows == nil {
+		rows = []models.HealthMetricRow{}
+	}
+	if points == nil {
+		points = []storage.TimeSeriesPoint{}
+	}
+	return map[string]any{
+		"downsampled": bucket != "",
+		"bucket":      bucket,
+		"raw_rows":    rawRows,
+		"rows":        rows,
+		"points":      points,
+	}
+}
+
+func (s *Server) handleQuerySleep(w http.ResponseWriter, r *http.Request) {
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	sessions, err := s.db.QuerySleepSessions(r.Context(), start, end, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"sessions": sessions,
+	})
+}
+
+// defaultSleepStagePage and maxSleepStagePage bound ?limit= on
+// GET /api/v1/sleep/stages.
+const (
+	defaultSleepStagePage = 1000
+	maxSleepStagePage     = 10000
+)
+
+// handleSleepStages returns raw sleep stages starting in the range, one page
+// at a time. ?stage=Deep,REM keeps only those stages. When more remain, the
+// continue token (header and next_after) carries the last start time of the
+// page; passing it back as ?after= returns the next page.
+func (s *Server) handleSleepStages(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	var stages []string
+	if v := q.Get("stage"); v != "" {
+		for _, st := range strings.Split(v, ",") {
+			if st = strings.TrimSpace(st); st == "" {
+				writeError(w, http.StatusBadRequest, "stage must be a comma-separated list of stage names")
+				return
+			}
+			stages = append(stages, st)
+		}
+	}
+	limit := defaultSleepStagePage
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxSleepStagePage {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be 1-%d", maxSleepStagePage))
+			return
+		}
+		limit = n
+	}
+	after, err := parseAfter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	page, err := s.db.QuerySleepStagePage(r.Context(), start, end, stages, after, limit, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if page.NextAfter != nil {
+		setContinueToken(w, *page.NextAfter)
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+// handleSleepNight returns one night's session and ordered stages for a
+// hypnogram. {date} (YYYY-MM-DD) is the date the night is filed under: its
+// wake-up date, or the evening's date under the noon sleep-day boundary.
+func (s *Server) handleSleepNight(w http.ResponseWriter, r *http.Request) {
+	date, err := time.Parse("2006-01-02", chi.URLParam(r, "date"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid date, expected YYYY-MM-DD")
+		return
+	}
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	night, err := s.db.GetSleepNight(r.Context(), date, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if night == nil {
+		writeError(w, http.StatusNotFound, "no sleep recorded for this night")
+		return
+	}
+	writeJSON(w, http.StatusOK, night)
+}
+
+// handleSleepArchitecture returns the stage transition analysis (onset
+// latency, awakenings, REM cycles, longest deep block) for one night.
+func (s *Server) handleSleepArchitecture(w http.ResponseWriter, r *http.Request) {
+	date, err := time.Parse("2006-01-02", chi.URLParam(r, "date"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid date, expected YYYY-MM-DD")
+		return
+	}
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	arch, err := s.db.GetSleepArchitecture(r.Context(), date, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if arch == nil {
+		writeError(w, http.StatusNotFound, "no sleep stages recorded for this night")
+		return
+	}
+	writeJSON(w, http.StatusOK, arch)
+}
+
+// handleSleepBackfill regroups all of the user's sleep stages into sessions.
+// Startup and post-import backfills only look at stages newer than the latest
+// session; this is the escape hatch for older gaps.
+func (s *Server) handleSleepBackfill(w http.ResponseWriter, r *http.Request) {
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := s.db.BackfillSleepSessionsFull(r.Context(), s.log, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"sessions_created": created})
+}
+
+// handleRecomputeMovingTime recomputes moving time from the stored GPS route
+// of every one of the user's workouts.
+func (s *Server) handleRecomputeMovingTime(w http.ResponseWriter, r *http.Request) {
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := s.db.RecomputeMovingTimes(r.Context(), uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]int{"workouts_updated": updated})
+}
+
+// handleRetention applies the configured retention policies now. With
+// ?dry_run=true nothing is written and the reports show what would change.
+// Restricted to the primary user, as retention rewrites every user's data.
+func (s *Server) handleRetention(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requirePrimaryUser(w, r); !ok {
+		return
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	if len(s.retentionPolicies) == 0 {
+		writeJSON(w, http.StatusOK, map[string]any{"dry_run": dryRun, "reports": []storage.DownsampleReport{}})
+		return
+	}
+
+	reports, err := s.db.RunRetention(r.Context(), s.retentionPolicies, time.Now(), dryRun)
+	if err != nil {
+		s.reqLog(r).Error("retention failed", "error", err)
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"dry_run": dryRun, "reports": reports})
+}
+
+func (s *Server) handleQueryWorkouts(w http.ResponseWriter, r *http.Request) {
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	filter, err := parseWorkoutFilter(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	system, err := parseUnitSystem(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+	if system, err = s.preferredUnits(r.Context(), uid, system); err != nil {
+		writeServerError(w, err)
+		return
+	}
+
+	workouts, err := s.db.QueryWorkoutsMerged(r.Context(), start, end, uid, filter)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, withUnits(workouts, system))
+}
+
+// parseWorkoutFilter reads the optional type, tag, and min_/max_ range params for
+// the workout list (duration_sec, distance_km, energy_kcal).
+func parseWorkoutFilter(r *http.Request) (storage.WorkoutFilter, error) {
+	q := r.URL.Query()
+	f := storage.WorkoutFilter{Name: q.Get("type")}
+	if tag := q.Get("tag"); tag != "" {
+		norm, err := storage.NormalizeTag(tag)
+		if err != nil {
+			return storage.WorkoutFilter{}, fmt.Errorf("invalid tag %q: %w", tag, err)
+		}
+		f.Tag = norm
+	}
+	params := []struct {
+		key string
+		dst **float64
+	}{
+		{"min_duration_sec", &f.MinDurationSec},
+		{"max_duration_sec", &f.MaxDurationSec},
+		{"min_distance_km", &f.MinDistanceKm},
+		{"max_distance_km", &f.MaxDistanceKm},
+		{"min_energy_kcal", &f.MinEnergyKcal},
+		{"max_energy_kcal", &f.MaxEnergyKcal},
+	}
+	for _, p := range params {
+		raw := q.Get(p.key)
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return storage.WorkoutFilter{}, fmt.Errorf("invalid %s: %q", p.key, raw)
+		}
+		*p.dst = &v
+	}
+	return f, nil
+}
+
+// handleUpdateWorkout applies a partial manual correction (name, location,
+// is_indoor, notes) to one of the user's workouts and returns the result.
+func (s *Server) handleUpdateWorkout(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid workout ID")
+		return
+	}
+	var u storage.WorkoutUpdate
+	if err := json.NewDecoder(r.Body).Decode(&u); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	if u.Empty() {
+		writeError(w, http.StatusBadRequest, "no updatable fields given (name, location, is_indoor, notes)")
+		return
+	}
+	if u.Name != nil && strings.TrimSpace(*u.Name) == "" {
+		writeError(w, http.StatusBadRequest, "name must not be empty")
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	found, err := s.db.UpdateWorkoutFields(r.Context(), workoutID, uid, u)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "workout not found")
+		return
+	}
+	detail, err := s.db.GetWorkout(r.Context(), workoutID, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, detail.WorkoutRow)
+}
+
+// handleAddWorkoutTag labels one of the user's workouts with {"tag": "..."}.
+func (s *Server) handleAddWorkoutTag(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid workout ID")
+		return
+	}
+	var body struct {
+		Tag string `json:"tag"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return
+	}
+	tag, err := storage.NormalizeTag(body.Tag)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	found, err := s.db.AddWorkoutTag(r.Context(), workoutID, uid, tag)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if !found {
+		writeError(w, http.StatusNotFound, "workout not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"workout_id": workoutID.String(), "tag": tag})
+}
+
+// handleRemoveWorkoutTag removes a tag from one of the user's workouts.
+func (s *Server) handleRemoveWorkoutTag(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid workout ID")
+		return
+	}
+	tag, err := storage.NormalizeTag(chi.URLParam(r, "tag"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	removed, err := s.db.RemoveWorkoutTag(r.Context(), workoutID, uid, tag)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if !removed {
+		writeError(w, http.StatusNotFound, "tag not found on workout")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (s *Server) handleGetWorkout(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	workoutID, err := uuid.Parse(idStr)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid workout ID")
+		return
+	}
+	system, err := parseUnitSystem(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	s.db.InvalidateAllAvailableMetrics()
-	go s.logImport(uid, "alpha", result, nil, durationMs)
-	writeJSON(w, http.StatusOK, result)
-}
-
-func (s *Server) handleUnifiedImport(w http.ResponseWriter, r *http.Request) {
 	uid, ok := mustUserID(w, r)
 	if !ok {
 		return
 	}
+	if system, err = s.preferredUnits(r.Context(), uid, system); err != nil {
+		writeServerError(w, err)
+		return
+	}
 
-	data, err := io.ReadAll(r.Body)
+	detail, err := s.db.GetWorkout(r.Context(), workoutID, uid)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "failed to read body"})
+		writeError(w, http.StatusNotFound, "workout not found")
 		return
 	}
 
-	format := ingest.DetectFormat(data)
-	start := time.Now()
-
-	switch format {
-	case ingest.FormatAlpha:
-		result, err := s.alpha.Ingest(r.Context(), bytes.NewReader(data), uid)
-		durationMs := int(time.Since(start).Milliseconds())
-		if err != nil {
-			s.log.Error("unified import (alpha) error", "error", err)
-			if result != nil {
-				go s.logImport(uid, "import_auto", result, err, durationMs)
-			}
-			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
-			return
+	// include=raw merges unmodeled raw_json fields (weather, swim strokes, ...)
+	// into the response; raw_fields=a,b restricts which ones.
+	if r.URL.Query().Get("include") == "raw" {
+		var only []string
+		if f := r.URL.Query().Get("raw_fields"); f != "" {
+			only = strings.Split(f, ",")
 		}
-		s.db.InvalidateAllAvailableMetrics()
-		go s.logImport(uid, "import_auto", result, nil, durationMs)
-		writeJSON(w, http.StatusOK, result)
-
-	default:
-		writeJSON(w, http.StatusUnprocessableEntity, map[string]any{
-			"error":     "unrecognized file format",
-			"supported": []string{"alpha_progression_csv"},
-		})
+		detail.Raw = storage.WorkoutRawExtras(detail.RawJSON, only)
 	}
+	writeJSON(w, http.StatusOK, detailWithUnits(detail, system))
 }
 
-// cumulativeMetrics are metrics that should show daily totals instead of latest value.
-var cumulativeMetrics = []string{
-	"active_energy", "basal_energy_burned", "apple_exercise_time",
-	"step_count", "distance_walking_running", "distance_cycling",
-	"distance_swimming", "distance_wheelchair", "flights_climbed",
-	"apple_move_time", "apple_stand_time", "push_count",
-	"swimming_stroke_count", "distance_downhill_snow_sports",
-}
-
-func (s *Server) handleLatestMetrics(w http.ResponseWriter, r *http.Request) {
-	uid, ok := mustUserID(w, r)
-	if !ok {
+// handleWorkoutIntervals splits a workout's HR stream into high/low effort
+// intervals. threshold_bpm and min_duration (seconds) override the defaults.
+func (s *Server) handleWorkoutIntervals(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid workout ID")
 		return
 	}
-	rows, err := s.db.GetLatestMetrics(r.Context(), uid)
+	opts, err := parseIntervalOptions(r)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Get daily sums for cumulative metrics
-	sums, err := s.db.GetDailySums(r.Context(), uid, cumulativeMetrics)
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	intervals, err := s.db.DetectIntervals(r.Context(), workoutID, uid, opts)
 	if err != nil {
-		s.log.Error("daily sums error", "error", err)
-		// Non-fatal: continue with latest values
-		writeJSON(w, http.StatusOK, rows)
+		writeError(w, http.StatusNotFound, "workout not found")
 		return
 	}
+	writeJSON(w, http.StatusOK, intervals)
+}
 
-	// Build response with daily_sums field
-	w.Header().Set("Cache-Control", "private, max-age=60")
-	writeJSON(w, http.StatusOK, map[string]any{
-		"latest":     rows,
-		"daily_sums": sums,
-	})
+// parseIntervalOptions reads the optional threshold_bpm and min_duration
+// (seconds) query parameters.
+func parseIntervalOptions(r *http.Request) (storage.IntervalOptions, error) {
+	var opts storage.IntervalOptions
+	q := r.URL.Query()
+	if raw := q.Get("threshold_bpm"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v <= 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return opts, fmt.Errorf("invalid threshold_bpm: %q", raw)
+		}
+		opts.ThresholdBPM = v
+	}
+	if raw := q.Get("min_duration"); raw != "" {
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || v < 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+			return opts, fmt.Errorf("invalid min_duration: %q", raw)
+		}
+		opts.MinDuration = time.Duration(v * float64(time.Second))
+	}
+	return opts, nil
 }
 
-func (s *Server) handleQueryMetrics(w http.ResponseWriter, r *http.Request) {
-	name := r.URL.Query().Get("name")
-	if name == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "name parameter required"})
+func (s *Server) handleMetricStats(w http.ResponseWriter, r *http.Request) {
+	metric := r.URL.Query().Get("metric")
+	if metric == "" {
+		writeError(w, http.StatusBadRequest, "metric parameter required")
 		return
 	}
 
 	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -226,95 +1106,134 @@ func (s *Server) handleQueryMetrics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	rows, err := s.db.QueryHealthMetrics(r.Context(), name, start, end, uid)
+	stats, err := s.db.GetMetricStats(r.Context(), metric, start, end, uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, rows)
+	writeJSON(w, http.StatusOK, stats)
 }
 
-func (s *Server) handleQuerySleep(w http.ResponseWriter, r *http.Request) {
-	uid, ok := mustUserID(w, r)
-	if !ok {
+// handleDataQuality reports a metric's daily coverage, longest gap, sources,
+// and 0-100 quality score over the range.
+func (s *Server) handleDataQuality(w http.ResponseWriter, r *http.Request) {
+	metric := r.URL.Query().Get("metric")
+	if metric == "" {
+		writeError(w, http.StatusBadRequest, "metric parameter required")
 		return
 	}
 
 	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	sessions, err := s.db.QuerySleepSessions(r.Context(), start, end, uid)
-	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
 		return
 	}
 
-	stages, err := s.db.QuerySleepStages(r.Context(), start, end, uid)
+	q, err := s.db.GetDataQuality(r.Context(), metric, start, end, uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
-
-	writeJSON(w, http.StatusOK, map[string]any{
-		"sessions": sessions,
-		"stages":   stages,
-	})
+	writeJSON(w, http.StatusOK, q)
 }
 
-func (s *Server) handleQueryWorkouts(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleDailySteps(w http.ResponseWriter, r *http.Request) {
 	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	nameFilter := r.URL.Query().Get("type")
 	uid, ok := mustUserID(w, r)
 	if !ok {
 		return
 	}
 
-	workouts, err := s.db.QueryWorkoutsMerged(r.Context(), start, end, uid, nameFilter)
+	if s.notModified(w, r, uid, storage.DataHealthMetrics, start, end) {
+		return
+	}
+
+	steps, err := s.db.GetDailySteps(r.Context(), start, end, uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, workouts)
+	writeJSON(w, http.StatusOK, steps)
 }
 
-func (s *Server) handleGetWorkout(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	workoutID, err := uuid.Parse(idStr)
+func (s *Server) handleTimeSeries(w http.ResponseWriter, r *http.Request) {
+	metric := r.URL.Query().Get("metric")
+	if metric == "" {
+		writeError(w, http.StatusBadRequest, "metric parameter required")
+		return
+	}
+
+	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid workout ID"})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	bucket := timeSeriesBucket(r.URL.Query().Get("agg"))
+
 	uid, ok := mustUserID(w, r)
 	if !ok {
 		return
 	}
 
-	detail, err := s.db.GetWorkout(r.Context(), workoutID, uid)
+	if s.notModified(w, r, uid, storage.DataHealthMetrics, start, end) {
+		return
+	}
+
+	points, err := s.db.GetTimeSeries(r.Context(), metric, start, end, bucket, uid)
 	if err != nil {
-		writeJSON(w, http.StatusNotFound, map[string]string{"error": "workout not found"})
+		writeServerError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, detail)
+	writeJSON(w, http.StatusOK, points)
 }
 
-func (s *Server) handleMetricStats(w http.ResponseWriter, r *http.Request) {
-	metric := r.URL.Query().Get("metric")
+// timeSeriesBucket maps the agg query parameter to a bucket interval,
+// defaulting to daily.
+func timeSeriesBucket(agg string) string {
+	switch agg {
+	case "hourly":
+		return "1 hour"
+	case "weekly":
+		return "1 week"
+	case "monthly":
+		return "1 month"
+	default:
+		return "1 day"
+	}
+}
+
+// handleAggregate returns one aggregate (?fn=avg|sum|min|max|count) of a
+// metric per bucket, chosen by ?agg= as for /timeseries (default daily).
+func (s *Server) handleAggregate(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	metric := q.Get("metric")
 	if metric == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "metric parameter required"})
+		writeError(w, http.StatusBadRequest, "metric parameter required")
 		return
 	}
-
+	fn := q.Get("fn")
+	if fn == "" {
+		fn = "avg"
+	}
+	if !storage.ValidAggregate(fn) {
+		writeError(w, http.StatusBadRequest, storage.ErrUnknownAggregate.Error())
+		return
+	}
+	bucket := timeSeriesBucket(q.Get("agg"))
 	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -323,64 +1242,76 @@ func (s *Server) handleMetricStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	stats, err := s.db.GetMetricStats(r.Context(), metric, start, end, uid)
+	if s.notModified(w, r, uid, storage.DataHealthMetrics, start, end) {
+		return
+	}
+
+	points, err := s.db.GetAggregate(r.Context(), metric, fn, start, end, bucket, uid)
+	if errors.Is(err, storage.ErrDerivedAggregate) {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, stats)
+	writeJSON(w, http.StatusOK, map[string]any{"metric": metric, "fn": fn, "bucket": bucket, "points": points})
 }
 
-func (s *Server) handleTimeSeries(w http.ResponseWriter, r *http.Request) {
-	metric := r.URL.Query().Get("metric")
-	if metric == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "metric parameter required"})
+// handleBulkTimeSeries returns time series for several metrics
+// (metrics=a,b,c) in one call, keyed by metric name. Metrics without data
+// in the range are present with an empty array.
+func (s *Server) handleBulkTimeSeries(w http.ResponseWriter, r *http.Request) {
+	var metrics []string
+	for _, m := range strings.Split(r.URL.Query().Get("metrics"), ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			metrics = append(metrics, m)
+		}
+	}
+	if len(metrics) == 0 {
+		writeError(w, http.StatusBadRequest, "metrics parameter required")
+		return
+	}
+	if len(metrics) > storage.MaxBulkMetrics {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d metrics per request", storage.MaxBulkMetrics))
 		return
 	}
 
 	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-
-	agg := r.URL.Query().Get("agg")
-	bucket := "1 day" // default
-	switch agg {
-	case "hourly":
-		bucket = "1 hour"
-	case "weekly":
-		bucket = "1 week"
-	case "monthly":
-		bucket = "1 month"
-	case "daily", "":
-		bucket = "1 day"
-	}
+	bucket := timeSeriesBucket(r.URL.Query().Get("agg"))
 
 	uid, ok := mustUserID(w, r)
 	if !ok {
 		return
 	}
 
-	points, err := s.db.GetTimeSeries(r.Context(), metric, start, end, bucket, uid)
+	if s.notModified(w, r, uid, storage.DataHealthMetrics, start, end) {
+		return
+	}
+
+	series, err := s.db.GetMultipleTimeSeries(r.Context(), metrics, start, end, bucket, uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
-	writeJSON(w, http.StatusOK, points)
+	writeJSON(w, http.StatusOK, series)
 }
 
 func (s *Server) handleCorrelation(w http.ResponseWriter, r *http.Request) {
 	xMetric := r.URL.Query().Get("x")
 	yMetric := r.URL.Query().Get("y")
 	if xMetric == "" || yMetric == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "x and y metric parameters required"})
+		writeError(w, http.StatusBadRequest, "x and y metric parameters required")
 		return
 	}
 
 	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -396,7 +1327,7 @@ func (s *Server) handleCorrelation(w http.ResponseWriter, r *http.Request) {
 
 	result, err := s.db.GetCorrelation(r.Context(), xMetric, yMetric, start, end, bucket, uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, result)
@@ -411,7 +1342,7 @@ func (s *Server) handleWorkoutSets(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 	workoutID, err := uuid.Parse(idStr)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid workout ID"})
+		writeError(w, http.StatusBadRequest, "invalid workout ID")
 		return
 	}
 
@@ -426,7 +1357,7 @@ func (s *Server) handleWorkoutSets(w http.ResponseWriter, r *http.Request) {
 		st, errS := time.Parse(time.RFC3339, startStr)
 		et, errE := time.Parse(time.RFC3339, endStr)
 		if errS != nil || errE != nil {
-			writeJSON(w, http.StatusNotFound, map[string]string{"error": "workout not found"})
+			writeError(w, http.StatusNotFound, "workout not found")
 			return
 		}
 		windowStart = st.Add(-2 * time.Hour)
@@ -438,21 +1369,206 @@ func (s *Server) handleWorkoutSets(w http.ResponseWriter, r *http.Request) {
 
 	sets, err := s.db.QueryWorkoutSets(r.Context(), windowStart, windowEnd, uid, "")
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, sets)
 }
 
+// handleWorkoutCombined returns a workout together with the Alpha Progression
+// sets linked to it at ingest. Sets is empty when no session was linked.
+func (s *Server) handleWorkoutCombined(w http.ResponseWriter, r *http.Request) {
+	workoutID, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid workout ID")
+		return
+	}
+	system, err := parseUnitSystem(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+	if system, err = s.preferredUnits(r.Context(), uid, system); err != nil {
+		writeServerError(w, err)
+		return
+	}
+
+	workout, err := s.db.GetWorkout(r.Context(), workoutID, uid)
+	if err != nil {
+		writeError(w, http.StatusNotFound, "workout not found")
+		return
+	}
+	sets, err := s.db.QueryLinkedWorkoutSets(r.Context(), workoutID, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if sets == nil {
+		sets = []models.WorkoutSetRow{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"workout": detailWithUnits(workout, system), "sets": sets})
+}
+
 func (s *Server) handleAllowlist(w http.ResponseWriter, r *http.Request) {
 	metrics, err := s.db.GetAllowedMetrics(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, metrics)
+}
+
+// handleUpdateMetricDisplay edits a metric's display label and unit, and
+// enables or disables its ingest. Omitted fields are unchanged; an empty
+// display_label restores the default name. Restricted to the primary user,
+// as the allowlist applies to everyone.
+func (s *Server) handleUpdateMetricDisplay(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		DisplayLabel *string `json:"display_label"`
+		DisplayUnit  *string `json:"display_unit"`
+		Enabled      *bool   `json:"enabled"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if body.DisplayLabel == nil && body.DisplayUnit == nil && body.Enabled == nil {
+		writeError(w, http.StatusBadRequest, "display_label, display_unit or enabled is required")
+		return
+	}
+	for _, v := range []*string{body.DisplayLabel, body.DisplayUnit} {
+		if v != nil && len(*v) > 64 {
+			writeError(w, http.StatusBadRequest, "display fields are limited to 64 characters")
+			return
+		}
+	}
+
+	if _, ok := s.requirePrimaryUser(w, r); !ok {
+		return
+	}
+	metric := chi.URLParam(r, "metric")
+	var err error
+	if body.Enabled != nil {
+		err = s.db.UpdateMetricAllowed(r.Context(), metric, *body.Enabled)
+	}
+	if err == nil && (body.DisplayLabel != nil || body.DisplayUnit != nil) {
+		err = s.db.UpdateMetricDisplay(r.Context(), metric, body.DisplayLabel, body.DisplayUnit)
+	}
+	if errors.Is(err, storage.ErrMetricNotAllowlisted) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "saved"})
+}
+
+// handleRejectedMetrics lists metric names ingest rejected for the caller
+// because they are not enabled in the allowlist. Restricted to the primary
+// user, who manages the allowlist.
+func (s *Server) handleRejectedMetrics(w http.ResponseWriter, r *http.Request) {
+	uid, ok := s.requirePrimaryUser(w, r)
+	if !ok {
+		return
+	}
+	metrics, err := s.db.ListRejectedMetrics(r.Context(), uid)
+	if err != nil {
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, metrics)
 }
 
+// handleAllowRejectedMetric enables a rejected metric in the allowlist so
+// later imports store it. The optional category defaults to
+// storage.DefaultRejectedCategory for metrics not yet in the allowlist, and
+// the optional is_cumulative to the metric's built-in aggregation. Restricted
+// to the primary user, as the allowlist applies to everyone.
+func (s *Server) handleAllowRejectedMetric(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Category     string `json:"category"`
+		IsCumulative *bool  `json:"is_cumulative"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON")
+			return
+		}
+	}
+	if len(body.Category) > 64 {
+		writeError(w, http.StatusBadRequest, "category is limited to 64 characters")
+		return
+	}
+	if body.Category == "" {
+		body.Category = storage.DefaultRejectedCategory
+	}
+
+	if _, ok := s.requirePrimaryUser(w, r); !ok {
+		return
+	}
+	metric := chi.URLParam(r, "metric")
+	if err := s.db.AllowRejectedMetric(r.Context(), metric, body.Category, body.IsCumulative); err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "allowed"})
+}
+
+// handleListMetricAliases returns every metric alias and its canonical name.
+func (s *Server) handleListMetricAliases(w http.ResponseWriter, r *http.Request) {
+	aliases, err := s.db.ListMetricAliases(r.Context())
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, aliases)
+}
+
+// handleUpsertMetricAlias maps an incoming metric name onto an allowlisted
+// metric so future ingests of the alias land in the canonical series.
+// Restricted to the primary user, as aliases apply to everyone's ingests.
+func (s *Server) handleUpsertMetricAlias(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Alias      string `json:"alias"`
+		MetricName string `json:"metric_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	if body.Alias == "" || body.MetricName == "" {
+		writeError(w, http.StatusBadRequest, "alias and metric_name are required")
+		return
+	}
+	if body.Alias == body.MetricName {
+		writeError(w, http.StatusBadRequest, "alias must differ from metric_name")
+		return
+	}
+	if _, ok := s.requirePrimaryUser(w, r); !ok {
+		return
+	}
+
+	err := s.db.UpsertMetricAlias(r.Context(), body.Alias, body.MetricName)
+	switch {
+	case errors.Is(err, storage.ErrMetricNotAllowlisted):
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, storage.ErrAliasIsMetric):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "saved"})
+}
+
 func (s *Server) handleAvailableMetrics(w http.ResponseWriter, r *http.Request) {
 	uid, ok := mustUserID(w, r)
 	if !ok {
@@ -460,7 +1576,7 @@ func (s *Server) handleAvailableMetrics(w http.ResponseWriter, r *http.Request)
 	}
 	metrics, err := s.db.GetAvailableMetrics(r.Context(), uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	w.Header().Set("Cache-Control", "private, max-age=60")
@@ -475,12 +1591,12 @@ func (s *Server) handleSaveMetricVisibility(w http.ResponseWriter, r *http.Reque
 
 	var body map[string]bool
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		writeError(w, http.StatusBadRequest, "invalid JSON")
 		return
 	}
 
 	if err := s.db.SaveMetricVisibility(r.Context(), uid, body); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	s.db.InvalidateAvailableMetrics(uid)
@@ -495,13 +1611,13 @@ func (s *Server) handleGetECGRecordings(w http.ResponseWriter, r *http.Request)
 
 	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	recordings, err := s.db.QueryECGRecordings(r.Context(), start, end, uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, recordings)
@@ -515,13 +1631,13 @@ func (s *Server) handleGetAudiograms(w http.ResponseWriter, r *http.Request) {
 
 	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	audiograms, err := s.db.QueryAudiograms(r.Context(), start, end, uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, audiograms)
@@ -535,13 +1651,13 @@ func (s *Server) handleGetActivitySummaries(w http.ResponseWriter, r *http.Reque
 
 	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	summaries, err := s.db.QueryActivitySummaries(r.Context(), start, end, uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, summaries)
@@ -555,13 +1671,13 @@ func (s *Server) handleGetMedications(w http.ResponseWriter, r *http.Request) {
 
 	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	medications, err := s.db.QueryMedications(r.Context(), start, end, uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, medications)
@@ -575,13 +1691,13 @@ func (s *Server) handleGetVisionPrescriptions(w http.ResponseWriter, r *http.Req
 
 	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	prescriptions, err := s.db.QueryVisionPrescriptions(r.Context(), start, end, uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, prescriptions)
@@ -595,13 +1711,13 @@ func (s *Server) handleGetStateOfMind(w http.ResponseWriter, r *http.Request) {
 
 	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	records, err := s.db.QueryStateOfMind(r.Context(), start, end, uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, records)
@@ -615,7 +1731,7 @@ func (s *Server) handleGetCategorySamples(w http.ResponseWriter, r *http.Request
 
 	start, end, err := parseTimeRange(r)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -623,22 +1739,62 @@ func (s *Server) handleGetCategorySamples(w http.ResponseWriter, r *http.Request
 
 	samples, err := s.db.QueryCategorySamples(r.Context(), start, end, uid, typeFilter)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, samples)
 }
 
+func (s *Server) handleGetSymptoms(w http.ResponseWriter, r *http.Request) {
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	symptoms, err := s.db.GetSymptoms(r.Context(), start, end, uid, r.URL.Query().Get("name"))
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, symptoms)
+}
+
 func writeJSON(w http.ResponseWriter, status int, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	_ = json.NewEncoder(w).Encode(v)
 }
 
+// parseTimeRange reads ?start= and ?end= as a half-open range [start, end),
+// the convention every storage range query follows. start and end accept
+// RFC 3339 or YYYY-MM-DD.
+//
+// ?end_inclusive= states whether the end value itself is included. When
+// true, a date-only end covers that whole day (the bound moves to the
+// following midnight) and an RFC 3339 end covers that instant (the bound
+// moves one microsecond later, the database's resolution). When false, the
+// end is the exclusive bound as given, so end=2025-03-01 stops at the start
+// of March 1st. Omitted, a date-only end is inclusive and an RFC 3339 end is
+// exclusive.
 func parseTimeRange(r *http.Request) (start, end time.Time, err error) {
 	startStr := r.URL.Query().Get("start")
 	endStr := r.URL.Query().Get("end")
 
+	var inclusive *bool
+	if v := r.URL.Query().Get("end_inclusive"); v != "" {
+		b, perr := strconv.ParseBool(v)
+		if perr != nil {
+			return time.Time{}, time.Time{}, errors.New("end_inclusive must be true or false")
+		}
+		inclusive = &b
+	}
+
 	if startStr == "" {
 		// Default: last 7 days
 		end = time.Now()
@@ -656,16 +1812,21 @@ func parseTimeRange(r *http.Request) (start, end time.Time, err error) {
 
 	if endStr == "" {
 		end = time.Now()
-	} else {
-		end, err = time.Parse(time.RFC3339, endStr)
-		if err != nil {
-			end, err = time.Parse("2006-01-02", endStr)
-			if err != nil {
-				return time.Time{}, time.Time{}, err
-			}
-			// End of day for date-only
-			end = end.Add(24 * time.Hour)
+		return
+	}
+	end, err = time.Parse(time.RFC3339, endStr)
+	if err == nil {
+		if inclusive != nil && *inclusive {
+			end = end.Add(time.Microsecond)
 		}
+		return
+	}
+	end, err = time.Parse("2006-01-02", endStr)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if inclusive == nil || *inclusive {
+		end = end.AddDate(0, 0, 1)
 	}
 	return
 }
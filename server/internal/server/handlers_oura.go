@@ -23,7 +23,7 @@ func (s *Server) handleOuraStatus(w http.ResponseWriter, r *http.Request) {
 
 	token, err := s.db.GetOuraToken(r.Context(), uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 
@@ -48,7 +48,7 @@ func (s *Server) handleOuraStatus(w http.ResponseWriter, r *http.Request) {
 
 		states, err := s.db.ListOuraSyncStates(r.Context(), uid)
 		if err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			writeServerError(w, err)
 			return
 		}
 		syncMap := make(map[string]string, len(states))
@@ -73,16 +73,16 @@ func (s *Server) handleOuraCredentials(w http.ResponseWriter, r *http.Request) {
 		ClientSecret string `json:"client_secret"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		writeError(w, http.StatusBadRequest, "invalid JSON")
 		return
 	}
 	if body.ClientID == "" || body.ClientSecret == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "client_id and client_secret are required"})
+		writeError(w, http.StatusBadRequest, "client_id and client_secret are required")
 		return
 	}
 
 	if err := s.db.UpsertOuraCredentials(r.Context(), uid, body.ClientID, body.ClientSecret); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 
@@ -99,7 +99,7 @@ func (s *Server) handleOuraAuthorize(w http.ResponseWriter, r *http.Request) {
 	// Generate CSRF state token and store in cookie.
 	stateBytes := make([]byte, 16)
 	if _, err := rand.Read(stateBytes); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "generating state"})
+		writeError(w, http.StatusInternalServerError, "generating state")
 		return
 	}
 	state := hex.EncodeToString(stateBytes)
@@ -116,7 +116,7 @@ func (s *Server) handleOuraAuthorize(w http.ResponseWriter, r *http.Request) {
 
 	url, err := s.ouraTokenMgr.AuthorizeURL(r.Context(), uid, ouraRedirectURI, state)
 	if err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"authorize_url": url})
@@ -162,7 +162,7 @@ func (s *Server) handleOuraCallback(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.ouraTokenMgr.ExchangeCode(r.Context(), code, ouraRedirectURI, uid); err != nil {
-		s.log.Error("oura code exchange failed", "error", err)
+		s.reqLog(r).Error("oura code exchange failed", "error", err)
 		http.Redirect(w, r, "/settings?tab=oura&error=exchange_failed", http.StatusFound)
 		return
 	}
@@ -194,7 +194,7 @@ func (s *Server) handleOuraDisconnect(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.ouraTokenMgr.Disconnect(r.Context(), uid); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/claude/freereps/internal/storage"
+)
+
+// handleGetProfile returns the user's profile, with null fields when none
+// has been saved.
+func (s *Server) handleGetProfile(w http.ResponseWriter, r *http.Request) {
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	p, err := s.db.GetUserProfile(r.Context(), uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if p == nil {
+		p = &storage.UserProfile{}
+	}
+	writeJSON(w, http.StatusOK, profileJSON(p))
+}
+
+// handleHRZones returns the user's estimated max heart rate, whether it came
+// from the profile age or observed heart rate data, and the zones derived
+// from it.
+func (s *Server) handleHRZones(w http.ResponseWriter, r *http.Request) {
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	est, err := s.db.GetEstimatedMaxHR(r.Context(), uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	if est == nil {
+		writeError(w, http.StatusNotFound, "no birth date or heart rate data to estimate max heart rate from")
+		return
+	}
+	writeJSON(w, http.StatusOK, est)
+}
+
+// handleUpsertProfile saves the user's birth date (YYYY-MM-DD), sex ("male"
+// or "female"), cycling FTP in watts, weekly volume landmarks per muscle
+// group, and preferred unit system. Omitted fields are cleared.
+func (s *Server) handleUpsertProfile(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		BirthDate       string                            `json:"birth_date"`
+		Sex             string                            `json:"sex"`
+		FTPWatts        *int                              `json:"ftp_watts"`
+		VolumeLandmarks map[string]storage.VolumeLandmark `json:"volume_landmarks"`
+		UnitSystem      string                            `json:"unit_system"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON")
+		return
+	}
+	var p storage.UserProfile
+	if body.BirthDate != "" {
+		t, err := time.Parse("2006-01-02", body.BirthDate)
+		if err != nil || t.After(time.Now()) {
+			writeError(w, http.StatusBadRequest, "birth_date must be a past date, YYYY-MM-DD")
+			return
+		}
+		p.BirthDate = &t
+	}
+	switch body.Sex {
+	case "", "male", "female":
+		p.Sex = body.Sex
+	default:
+		writeError(w, http.StatusBadRequest, "sex must be male or female")
+		return
+	}
+	if body.FTPWatts != nil && (*body.FTPWatts <= 0 || *body.FTPWatts > maxFTPWatts) {
+		writeError(w, http.StatusBadRequest, "ftp_watts must be between 1 and 2000")
+		return
+	}
+	p.FTPWatts = body.FTPWatts
+	for group, l := range body.VolumeLandmarks {
+		if group == "" || l.MEV < 0 || l.MEV > l.MRV {
+			writeError(w, http.StatusBadRequest, "volume_landmarks need a muscle group and 0 <= mev <= mrv")
+			return
+		}
+	}
+	p.VolumeLandmarks = body.VolumeLandmarks
+	switch body.UnitSystem {
+	case "", unitsMetric, unitsImperial:
+		p.UnitSystem = body.UnitSystem
+	default:
+		writeError(w, http.StatusBadRequest, "unit_system must be metric or imperial")
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+	if err := s.db.UpsertUserProfile(r.Context(), uid, p); err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, profileJSON(&p))
+}
+
+// maxFTPWatts bounds the accepted FTP; well above any human's.
+const maxFTPWatts = 2000
+
+// profileJSON renders a profile with the birth date as a plain date.
+func profileJSON(p *storage.UserProfile) map[string]any {
+	var birth any
+	if p.BirthDate != nil {
+		birth = p.BirthDate.Format("2006-01-02")
+	}
+	var sex any
+	if p.Sex != "" {
+		sex = p.Sex
+	}
+	var units any
+	if p.UnitSystem != "" {
+		units = p.UnitSystem
+	}
+	return map[string]any{"birth_date": birth, "sex": sex, "ftp_watts": p.FTPWatts, "volume_landmarks": p.VolumeLandmarks,
+		"unit_system": units}
+}
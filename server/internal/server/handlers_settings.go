@@ -2,12 +2,15 @@ package server
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/claude/freereps/internal/ingest"
 	"github.com/claude/freereps/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
 )
 
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
@@ -17,12 +20,172 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	}
 	stats, err := s.db.GetDataStats(r.Context(), uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, stats)
 }
 
+// isPrimaryUser reports whether uid is the tailnet owner, i.e. the user
+// GetPrimaryUser resolves tagged devices to. In dev mode no real login exists
+// yet, so the seeded local user counts as primary.
+func isPrimaryUser(ctx context.Context, db userStore, uid int, devMode bool) (bool, error) {
+	primaryID, _, err := db.GetPrimaryUser(ctx)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return devMode && uid == 1, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return uid == primaryID, nil
+}
+
+// requirePrimaryUser resolves the caller and writes 403 unless they are the
+// primary user. Returns false when the response has been written.
+func (s *Server) requirePrimaryUser(w http.ResponseWriter, r *http.Request) (int, bool) {
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return 0, false
+	}
+	primary, err := isPrimaryUser(r.Context(), s.db, uid, s.lc == nil)
+	if err != nil {
+		s.reqLog(r).Error("primary user lookup failed", "error", err)
+		writeServerError(w, err)
+		return 0, false
+	}
+	if !primary {
+		writeError(w, http.StatusForbidden, "admin endpoints are restricted to the primary user")
+		return 0, false
+	}
+	return uid, true
+}
+
+// handleAdminUsers lists every user who has logged in, with their row counts.
+func (s *Server) handleAdminUsers(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requirePrimaryUser(w, r); !ok {
+		return
+	}
+	users, err := s.db.GetUsers(r.Context())
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, users)
+}
+
+// handleDeleteUserData erases a user's stored data. Users may erase their own
+// data; erasing anyone else's requires the primary user. With
+// ?remove_user=true the user row is deleted as well.
+func (s *Server) handleDeleteUserData(w http.ResponseWriter, r *http.Request) {
+	target, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil || target <= 0 {
+		writeError(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	removeUser := r.URL.Query().Get("remove_user") == "true"
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+	if uid != target {
+		if _, ok := s.requirePrimaryUser(w, r); !ok {
+			return
+		}
+	}
+
+	deleted, err := s.db.DeleteUserData(r.Context(), target, removeUser)
+	if err != nil {
+		s.reqLog(r).Error("user data delete failed", "target_user", target, "error", err)
+		writeServerError(w, err)
+		return
+	}
+	s.reqLog(r).Info("user data deleted", "target_user", target, "remove_user", removeUser)
+	writeJSON(w, http.StatusOK, map[string]any{"user_id": target, "user_removed": removeUser, "deleted": deleted})
+}
+
+// handleMigrationStatus reports the applied schema version, whether the last
+// migration left it dirty, and the latest version available on disk.
+func (s *Server) handleMigrationStatus(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requirePrimaryUser(w, r); !ok {
+		return
+	}
+	s.writeMigrationStatus(w, r)
+}
+
+// handleRunMigrations applies any pending migrations, then reports the
+// resulting status as handleMigrationStatus does.
+func (s *Server) handleRunMigrations(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requirePrimaryUser(w, r); !ok {
+		return
+	}
+	if s.migrationDSN == "" {
+		writeError(w, http.StatusServiceUnavailable, "migrations are not configured")
+		return
+	}
+	if err := storage.RunMigrations(s.migrationDSN, s.migrationsPath); err != nil {
+		s.reqLog(r).Error("manual migration failed", "error", err)
+		writeServerError(w, err)
+		return
+	}
+	s.reqLog(r).Info("manual migration run")
+	s.writeMigrationStatus(w, r)
+}
+
+// writeMigrationStatus writes the current and latest schema versions.
+func (s *Server) writeMigrationStatus(w http.ResponseWriter, r *http.Request) {
+	version, dirty, err := s.db.SchemaVersion(r.Context())
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	resp := map[string]any{"version": version, "dirty": dirty}
+	if s.migrationsPath != "" {
+		latest, err := storage.LatestMigrationVersion(s.migrationsPath)
+		if err != nil {
+			writeServerError(w, err)
+			return
+		}
+		resp["latest"] = latest
+		resp["pending"] = latest > version
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleUnitConsistency reports, per metric, the units stored rows use and
+// how many rows use each.
+func (s *Server) handleUnitConsistency(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requirePrimaryUser(w, r); !ok {
+		return
+	}
+	reports, err := s.db.GetUnitConsistency(r.Context())
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reports)
+}
+
+// handleRepairUnits converts minority-unit metric rows to each metric's
+// majority unit. With ?dry_run=true nothing is written and the repairs show
+// what would change.
+func (s *Server) handleRepairUnits(w http.ResponseWriter, r *http.Request) {
+	if _, ok := s.requirePrimaryUser(w, r); !ok {
+		return
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	repairs, err := s.db.RepairMetricUnits(r.Context(), ingest.ConvertUnit, dryRun)
+	if err != nil {
+		s.reqLog(r).Error("unit repair failed", "error", err)
+		writeServerError(w, err)
+		return
+	}
+	if !dryRun {
+		s.reqLog(r).Info("metric units repaired", "repairs", len(repairs))
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"dry_run": dryRun, "repairs": repairs})
+}
+
 func (s *Server) handleImportLogs(w http.ResponseWriter, r *http.Request) {
 	uid, ok := mustUserID(w, r)
 	if !ok {
@@ -36,13 +199,15 @@ func (s *Server) handleImportLogs(w http.ResponseWriter, r *http.Request) {
 	}
 	logs, err := s.db.QueryImportLogs(r.Context(), uid, limit)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, logs)
 }
 
 // logImport records an import operation's result to the import_logs table.
+// Handlers call it before responding, so the entry is in place (and folded
+// into ETags, see storage.GetDataVersion) by the client's next request.
 func (s *Server) logImport(uid int, source string, result *ingest.Result, importErr error, durationMs int) {
 	status := "success"
 	var errMsg *string
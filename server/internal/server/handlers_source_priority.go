@@ -16,19 +16,19 @@ func (s *Server) handleGetSourcePriorities(w http.ResponseWriter, r *http.Reques
 
 	rules, err := s.db.GetSourcePriorities(r.Context(), uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 
 	sources, err := s.db.GetDistinctSources(r.Context(), uid)
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 
 	categories, err := s.db.GetAllowlistCategories(r.Context())
 	if err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 
@@ -52,16 +52,16 @@ func (s *Server) handleUpsertSourcePriority(w http.ResponseWriter, r *http.Reque
 		Sources  []string `json:"sources"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON"})
+		writeError(w, http.StatusBadRequest, "invalid JSON")
 		return
 	}
 	if body.Category == "" || len(body.Sources) == 0 {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "category and sources are required"})
+		writeError(w, http.StatusBadRequest, "category and sources are required")
 		return
 	}
 
 	if err := s.db.UpsertSourcePriority(r.Context(), uid, body.Category, body.Sources); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "saved"})
@@ -76,12 +76,12 @@ func (s *Server) handleDeleteSourcePriority(w http.ResponseWriter, r *http.Reque
 
 	category := chi.URLParam(r, "category")
 	if category == "" {
-		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "category is required"})
+		writeError(w, http.StatusBadRequest, "category is required")
 		return
 	}
 
 	if err := s.db.DeleteSourcePriority(r.Context(), uid, category); err != nil {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		writeServerError(w, err)
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
@@ -0,0 +1,350 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/claude/freereps/internal/report"
+	"github.com/claude/freereps/internal/storage"
+)
+
+// summaryBucket returns the validated bucket query parameter for period
+// summaries, defaulting to monthly.
+func summaryBucket(r *http.Request) (string, bool) {
+	switch b := r.URL.Query().Get("bucket"); b {
+	case "":
+		return "1 month", true
+	case "1 week", "1 month":
+		return b, true
+	default:
+		return "", false
+	}
+}
+
+func (s *Server) handleSleepSummary(w http.ResponseWriter, r *http.Request) {
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bucket, ok := summaryBucket(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "bucket must be '1 week' or '1 month'")
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if s.notModified(w, r, uid, storage.DataSleep, start, end) {
+		return
+	}
+
+	summary, err := s.db.GetSleepSummary(r.Context(), start, end, bucket, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+func (s *Server) handleTrainingSummary(w http.ResponseWriter, r *http.Request) {
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	bucket, ok := summaryBucket(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "bucket must be '1 week' or '1 month'")
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if s.notModified(w, r, uid, storage.DataTraining, start, end) {
+		return
+	}
+
+	summary, err := s.db.GetTrainingSummary(r.Context(), start, end, bucket, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// handleIntensityTrend returns average RIR and failure rate per week or
+// month. Without ?start= it covers the last six months.
+func (s *Server) handleIntensityTrend(w http.ResponseWriter, r *http.Request) {
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.URL.Query().Get("start") == "" {
+		start = end.AddDate(0, -6, 0)
+	}
+	bucket, ok := summaryBucket(r)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "bucket must be '1 week' or '1 month'")
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if s.notModified(w, r, uid, storage.DataTraining, start, end) {
+		return
+	}
+
+	trend, err := s.db.GetIntensityTrend(r.Context(), start, end, bucket, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, trend)
+}
+
+// maxExerciseResults caps GET /api/v1/training/exercises.
+const maxExerciseResults = 200
+
+// handleExercises returns the user's distinct exercise names with working
+// set counts, most trained first. ?search= keeps names containing every
+// word; ?limit= caps the list (default 20).
+func (s *Server) handleExercises(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := 20
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxExerciseResults {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be 1-%d", maxExerciseResults))
+			return
+		}
+		limit = n
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	exercises, err := s.db.ListExercises(r.Context(), q.Get("search"), limit, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, exercises)
+}
+
+// handleExerciseReport returns per-session tonnage, max weight, average RIR
+// and estimated 1RM for exercises matching ?exercise=. Without ?start= it
+// covers the last 90 days.
+func (s *Server) handleExerciseReport(w http.ResponseWriter, r *http.Request) {
+	exercise := r.URL.Query().Get("exercise")
+	if exercise == "" {
+		writeError(w, http.StatusBadRequest, "exercise is required")
+		return
+	}
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.URL.Query().Get("start") == "" {
+		start = end.AddDate(0, 0, -90)
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if s.notModified(w, r, uid, storage.DataTraining, start, end) {
+		return
+	}
+
+	report, err := s.db.GetExerciseReport(r.Context(), exercise, start, end, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleActivityCalendar returns one row per day with workout count, active
+// minutes, and calories for a GitHub-style calendar. Without ?start= it
+// covers the last year rather than parseTimeRange's 7-day default.
+func (s *Server) handleActivityCalendar(w http.ResponseWriter, r *http.Request) {
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.URL.Query().Get("start") == "" {
+		start = end.AddDate(-1, 0, 0)
+	}
+	if err := storage.ValidateCalendarRange(start, end); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if s.notModified(w, r, uid, storage.DataTraining, start, end) {
+		return
+	}
+
+	days, err := s.db.GetActivityCalendar(r.Context(), start, end, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, days)
+}
+
+// handleWorkoutTypes returns how workouts in the range split across
+// canonical workout types (default: last 90 days).
+func (s *Server) handleWorkoutTypes(w http.ResponseWriter, r *http.Request) {
+	start, end, err := parseTimeRange(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if r.URL.Query().Get("start") == "" {
+		start = end.AddDate(0, 0, -90)
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	types, err := s.db.GetWorkoutTypeDistribution(r.Context(), start, end, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, types)
+}
+
+// handleSleepDebt returns sleep debt over the 14 nights ending on ?end=
+// (YYYY-MM-DD, default today). ?target= overrides the configured nightly need.
+func (s *Server) handleSleepDebt(w http.ResponseWriter, r *http.Request) {
+	end := time.Now().UTC()
+	if v := r.URL.Query().Get("end"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid end, expected YYYY-MM-DD")
+			return
+		}
+		end = t
+	}
+	var target float64
+	if v := r.URL.Query().Get("target"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil || t <= 0 || t > 24 {
+			writeError(w, http.StatusBadRequest, "target must be hours between 0 and 24")
+			return
+		}
+		target = t
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	debt, err := s.db.GetSleepDebt(r.Context(), end, uid, target)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, debt)
+}
+
+// handleWeeklyReport returns the weekly digest for the seven days ending
+// end (inclusive, default yesterday) as JSON, or rendered with
+// format=markdown or format=html.
+func (s *Server) handleWeeklyReport(w http.ResponseWriter, r *http.Request) {
+	end := time.Now().UTC()
+	if v := r.URL.Query().Get("end"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid end, expected YYYY-MM-DD")
+			return
+		}
+		end = t.AddDate(0, 0, 1)
+	}
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "json", "markdown", "html":
+	default:
+		writeError(w, http.StatusBadRequest, "format must be json, markdown, or html")
+		return
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	rep, err := s.db.GetWeeklyReport(r.Context(), end, uid)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	switch format {
+	case "markdown":
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		_, _ = io.WriteString(w, report.Markdown(rep))
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = io.WriteString(w, report.HTML(rep))
+	default:
+		writeJSON(w, http.StatusOK, rep)
+	}
+}
+
+// handleBestEfforts returns all-time fastest efforts per workout type for the
+// requested distances (comma-separated metres, e.g. distances=1000,5000).
+func (s *Server) handleBestEfforts(w http.ResponseWriter, r *http.Request) {
+	distances := storage.DefaultBestEffortDistances
+	if raw := r.URL.Query().Get("distances"); raw != "" {
+		distances = nil
+		for _, part := range strings.Split(raw, ",") {
+			d, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil || d <= 0 {
+				writeError(w, http.StatusBadRequest, "distances must be positive numbers in metres")
+				return
+			}
+			distances = append(distances, d)
+		}
+	}
+
+	uid, ok := mustUserID(w, r)
+	if !ok {
+		return
+	}
+
+	efforts, err := s.db.ComputeBestEfforts(r.Context(), uid, distances)
+	if err != nil {
+		writeServerError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, efforts)
+}
@@ -5,7 +5,15 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+	"github.com/claude/freereps/internal/storage"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5"
 )
 
 // TestHandleVersion verifies the /api/v1/version endpoint returns the
@@ -34,6 +42,32 @@ func TestHandleVersion(t *testing.T) {
 	}
 }
 
+// TestHandleVersionBuildInfo verifies the version payload carries the Go
+// toolchain version, and that /healthz reports ok with the same build info.
+func TestHandleVersionBuildInfo(t *testing.T) {
+	s := &Server{}
+	for _, h := range []http.HandlerFunc{s.handleVersion, s.handleHealthz} {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want 200", rec.Code)
+		}
+		var resp map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if resp["go_version"] != runtime.Version() || resp["version"] != Version {
+			t.Errorf("build info = %v", resp)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleHealthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if !strings.Contains(rec.Body.String(), `"status":"ok"`) {
+		t.Errorf("healthz body = %s, want status ok", rec.Body.String())
+	}
+}
+
 // TestHandleMeDefault verifies the /api/v1/me endpoint returns the dev user
 // identity when no Tailscale middleware is active.
 func TestHandleMeDefault(t *testing.T) {
@@ -83,3 +117,611 @@ func TestHandleMeTailscaleUser(t *testing.T) {
 		t.Errorf("display_name = %q, want %q", info.DisplayName, "Alice")
 	}
 }
+
+// TestParseWorkoutFilter verifies workout list range params are parsed and
+// malformed numbers are rejected instead of being silently ignored.
+func TestParseWorkoutFilter(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/workouts?type=Running&tag=Race&min_distance_km=10&max_duration_sec=3600", nil)
+	f, err := parseWorkoutFilter(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if f.Name != "Running" || f.Tag != "race" || f.MinDistanceKm == nil || *f.MinDistanceKm != 10 || f.MaxDurationSec == nil || *f.MaxDurationSec != 3600 {
+		t.Errorf("filter = %+v", f)
+	}
+	if f.MinEnergyKcal != nil {
+		t.Errorf("min_energy_kcal = %v, want nil", *f.MinEnergyKcal)
+	}
+
+	for _, q := range []string{"min_distance_km=ten", "max_energy_kcal=NaN", "tag=a,b"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/workouts?"+q, nil)
+		if _, err := parseWorkoutFilter(req); err == nil {
+			t.Errorf("%s: expected error", q)
+		}
+	}
+}
+
+// TestParseIntervalOptions verifies interval overrides are parsed and
+// nonsensical values are rejected.
+func TestParseIntervalOptions(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/workouts/x/intervals?threshold_bpm=150&min_duration=45", nil)
+	opts, err := parseIntervalOptions(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.ThresholdBPM != 150 || opts.MinDuration != 45*time.Second {
+		t.Errorf("opts = %+v", opts)
+	}
+
+	for _, q := range []string{"threshold_bpm=0", "threshold_bpm=NaN", "min_duration=-1", "min_duration=soon"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/workouts/x/intervals?"+q, nil)
+		if _, err := parseIntervalOptions(req); err == nil {
+			t.Errorf("%s: expected error", q)
+		}
+	}
+}
+
+// TestHandleExportAlphaBadRange verifies a malformed date is rejected before
+// any sets are loaded.
+func TestHandleExportAlphaBadRange(t *testing.T) {
+	s := &Server{}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/export/alpha?start=last-week", nil)
+	rec := httptest.NewRecorder()
+	s.handleExportAlpha(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+// TestHandleBestEffortsBadDistances verifies malformed distance lists are
+// rejected before any route data is loaded.
+func TestHandleBestEffortsBadDistances(t *testing.T) {
+	s := &Server{}
+	for _, q := range []string{"distances=abc", "distances=1000,-5", "distances=1000,,5000"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/training/best-efforts?"+q, nil)
+		rec := httptest.NewRecorder()
+		s.handleBestEfforts(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+// TestHandleExercisesBadLimit verifies out-of-range limits are rejected
+// before exercises are queried.
+func TestHandleExercisesBadLimit(t *testing.T) {
+	s := &Server{}
+	for _, q := range []string{"limit=0", "limit=abc", "limit=201"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/training/exercises?search=press&"+q, nil)
+		rec := httptest.NewRecorder()
+		s.handleExercises(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+// TestHandleExerciseReportBadParams verifies a missing exercise or malformed
+// range is rejected before any sets are read.
+func TestHandleExerciseReportBadParams(t *testing.T) {
+	s := &Server{}
+	for _, q := range []string{"", "start=2025-01-01", "exercise=squat&start=yesterday"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/training/exercise-report?"+q, nil)
+		rec := httptest.NewRecorder()
+		s.handleExerciseReport(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+// TestHandleAggregateBadParams verifies a missing metric, an aggregate outside
+// the allowlist and a bad range are rejected before any data is read.
+func TestHandleAggregateBadParams(t *testing.T) {
+	s := &Server{}
+	for _, q := range []string{"", "metric=heart_rate&fn=median", "metric=heart_rate&fn=avg%3Bdrop", "metric=heart_rate&start=yesterday"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/aggregate?"+q, nil)
+		rec := httptest.NewRecorder()
+		s.handleAggregate(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+// TestHandleAllowRejectedMetricBadBody verifies malformed bodies and
+// oversized categories are rejected before the allowlist is touched.
+func TestHandleAllowRejectedMetricBadBody(t *testing.T) {
+	s := &Server{}
+	for _, body := range []string{"{", `{"category":"` + strings.Repeat("x", 65) + `"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/allowlist/rejected/body_temperature/allow", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.handleAllowRejectedMetric(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%.20s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+// TestHandleSleepStagesBadParams verifies malformed stage filters, limits and
+// cursors are rejected before any stages are read.
+func TestHandleSleepStagesBadParams(t *testing.T) {
+	s := &Server{}
+	for _, q := range []string{"stage=Deep,,REM", "limit=0", "limit=10001", "after=yesterday"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/sleep/stages?"+q, nil)
+		rec := httptest.NewRecorder()
+		s.handleSleepStages(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+// TestHandleWeeklyReportBadParams verifies an invalid end date or format is
+// rejected before the report is built.
+func TestHandleWeeklyReportBadParams(t *testing.T) {
+	s := &Server{}
+	for _, q := range []string{"end=last-week", "format=pdf"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/reports/weekly?"+q, nil)
+		rec := httptest.NewRecorder()
+		s.handleWeeklyReport(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+// TestHandleRetentionRequiresUser verifies retention, which rewrites every
+// user's data, never runs without an authenticated caller to check against
+// the primary user.
+func TestHandleRetentionRequiresUser(t *testing.T) {
+	s := &Server{retentionPolicies: []storage.RetentionPolicy{{Metric: "heart_rate"}}}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/retention?dry_run=true", nil)
+	rec := httptest.NewRecorder()
+
+	s.handleRetention(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+}
+
+// TestHandleDashboardRequiresUser verifies the combined dashboard never runs
+// its queries without an authenticated user to scope them to.
+func TestHandleDashboardRequiresUser(t *testing.T) {
+	s := &Server{}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/dashboard", nil)
+	rec := httptest.NewRecorder()
+
+	s.handleDashboard(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+}
+
+// TestHandleSleepNightBadDate verifies malformed dates are rejected before
+// any lookup.
+func TestHandleSleepNightBadDate(t *testing.T) {
+	s := &Server{}
+	for _, date := range []string{"yesterday", "2025-13-01", "2025-04-10T00:00:00Z"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/sleep/"+date, nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("date", date)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		s.handleSleepNight(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", date, rec.Code)
+		}
+	}
+}
+
+// TestHandleSleepArchitectureBadDate verifies malformed dates are rejected
+// before any lookup.
+func TestHandleSleepArchitectureBadDate(t *testing.T) {
+	s := &Server{}
+	for _, date := range []string{"yesterday", "2025-02-30"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/sleep/"+date+"/architecture", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("date", date)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		s.handleSleepArchitecture(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", date, rec.Code)
+		}
+	}
+}
+
+// TestHandleSleepDebtBadParams verifies invalid end dates and targets are
+// rejected with 400.
+func TestHandleSleepDebtBadParams(t *testing.T) {
+	s := &Server{}
+	for _, q := range []string{"end=May", "target=abc", "target=0", "target=30"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/sleep/debt?"+q, nil)
+		rec := httptest.NewRecorder()
+		s.handleSleepDebt(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+// TestHandleActivityCalendarBadRange verifies inverted and oversized ranges
+// are rejected before the day series is generated.
+func TestHandleActivityCalendarBadRange(t *testing.T) {
+	s := &Server{}
+	for _, q := range []string{"start=2025-06-01&end=2025-05-01", "start=2000-01-01&end=2025-01-01", "start=soon"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/training/calendar?"+q, nil)
+		rec := httptest.NewRecorder()
+		s.handleActivityCalendar(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+// TestHandleUpdateMetricDisplayValidation verifies empty and oversized edits
+// are rejected before touching the allowlist.
+func TestHandleUpdateMetricDisplayValidation(t *testing.T) {
+	s := &Server{}
+	long := `"` + strings.Repeat("x", 65) + `"`
+	for _, body := range []string{`{}`, `not json`, `{"display_label":` + long + `}`, `{"enabled":"yes"}`} {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/allowlist/heart_rate", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.handleUpdateMetricDisplay(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+// TestIsPrimaryUser verifies only the tailnet owner passes the admin check,
+// and that the seeded local user is accepted in dev mode only.
+func TestIsPrimaryUser(t *testing.T) {
+	ctx := context.Background()
+	owner := &mockUserStore{primaryID: 3, primaryLogin: "alice@example.com"}
+	noOwner := &mockUserStore{primaryErr: pgx.ErrNoRows}
+
+	tests := []struct {
+		name    string
+		store   *mockUserStore
+		uid     int
+		devMode bool
+		want    bool
+	}{
+		{"owner", owner, 3, false, true},
+		{"other user", owner, 4, false, false},
+		{"dev local user", noOwner, 1, true, true},
+		{"no owner in production", noOwner, 1, false, false},
+	}
+	for _, tt := range tests {
+		got, err := isPrimaryUser(ctx, tt.store, tt.uid, tt.devMode)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: isPrimaryUser = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestHandleDeleteUserDataBadID verifies a malformed user id is rejected
+// before any authorization check or delete runs.
+func TestHandleDeleteUserDataBadID(t *testing.T) {
+	s := &Server{}
+	for _, id := range []string{"abc", "0", "-2"} {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/admin/users/"+id+"/data", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		s.handleDeleteUserData(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("id %q: status = %d, want 400", id, rec.Code)
+		}
+	}
+}
+
+// TestHandleWorkoutCombinedBadID verifies a malformed workout ID is rejected
+// before the workout is looked up.
+func TestHandleWorkoutCombinedBadID(t *testing.T) {
+	s := &Server{}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/workouts/nope/combined", nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "nope")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	rec := httptest.NewRecorder()
+	s.handleWorkoutCombined(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+// TestHandleUpdateWorkoutBadRequest verifies a malformed ID, an empty patch,
+// and a blank name are rejected before anything is written.
+func TestHandleUpdateWorkoutBadRequest(t *testing.T) {
+	s := &Server{}
+	valid := "9f1c2d3e-0000-4000-8000-000000000001"
+	tests := []struct{ id, body string }{
+		{"nope", `{"name":"Run"}`},
+		{valid, `{}`},
+		{valid, `{"name":"  "}`},
+		{valid, `not json`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/workouts/"+tt.id, strings.NewReader(tt.body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", tt.id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+		s.handleUpdateWorkout(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("id %q body %q: status = %d, want 400", tt.id, tt.body, rec.Code)
+		}
+	}
+}
+
+// TestHandleWorkoutTagsBadRequest verifies malformed workout IDs and invalid
+// tags are rejected before any storage call on both add and remove.
+func TestHandleWorkoutTagsBadRequest(t *testing.T) {
+	s := &Server{}
+	valid := "9f1c2d3e-0000-4000-8000-000000000001"
+	route := func(req *http.Request, id, tag string) *http.Request {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		rctx.URLParams.Add("tag", tag)
+		return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	}
+
+	for _, tt := range []struct{ id, body string }{
+		{"nope", `{"tag":"race"}`},
+		{valid, `{"tag":""}`},
+		{valid, `{"tag":"two words"}`},
+	} {
+		req := route(httptest.NewRequest(http.MethodPost, "/api/v1/workouts/x/tags", strings.NewReader(tt.body)), tt.id, "")
+		rec := httptest.NewRecorder()
+		s.handleAddWorkoutTag(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("add id %q body %q: status = %d, want 400", tt.id, tt.body, rec.Code)
+		}
+	}
+
+	req := route(httptest.NewRequest(http.MethodDelete, "/api/v1/workouts/x/tags/x", nil), valid, "race!")
+	rec := httptest.NewRecorder()
+	s.handleRemoveWorkoutTag(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("remove bad tag: status = %d, want 400", rec.Code)
+	}
+}
+
+// TestHandleIntensityTrendBadBucket verifies unsupported buckets are rejected.
+func TestHandleIntensityTrendBadBucket(t *testing.T) {
+	s := &Server{}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/training/intensity-trend?bucket=1+day", nil)
+	rec := httptest.NewRecorder()
+	s.handleIntensityTrend(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+// TestHandleUpsertProfileBadBody verifies malformed JSON, an unparseable or
+// future birth date, an unknown sex, and an out-of-range FTP are rejected
+// before saving.
+func TestHandleUpsertProfileBadBody(t *testing.T) {
+	s := &Server{}
+	for _, body := range []string{
+		`{`,
+		`{"birth_date":"01/02/1990"}`,
+		`{"birth_date":"2999-01-01"}`,
+		`{"sex":"other"}`,
+		`{"ftp_watts":0}`,
+		`{"ftp_watts":5000}`,
+		`{"volume_landmarks":{"chest":{"mev":12,"mrv":8}}}`,
+		`{"volume_landmarks":{"chest":{"mev":-1,"mrv":8}}}`,
+		`{"unit_system":"furlongs"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/profile", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.handleUpsertProfile(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+// TestHandleUpsertMetricAliasBadBody verifies malformed JSON, missing fields,
+// and a self-alias are rejected before touching the database.
+func TestHandleUpsertMetricAliasBadBody(t *testing.T) {
+	s := &Server{}
+	for _, body := range []string{
+		`{`,
+		`{"alias":"vo2max"}`,
+		`{"alias":"vo2_max","metric_name":"vo2_max"}`,
+	} {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/metric-aliases", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		s.handleUpsertMetricAlias(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", body, rec.Code)
+		}
+	}
+}
+
+// TestHandleDataQualityBadParams verifies a missing metric or malformed start
+// is rejected before querying.
+func TestHandleDataQualityBadParams(t *testing.T) {
+	s := &Server{}
+	for _, q := range []string{"", "metric=heart_rate&start=yesterday"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/metrics/quality?"+q, nil)
+		rec := httptest.NewRecorder()
+		s.handleDataQuality(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+// TestHandleExportMetricBadRequest verifies a missing metric, an out-of-range
+// limit, and a malformed continue token are rejected before querying.
+func TestHandleExportMetricBadRequest(t *testing.T) {
+	s := &Server{}
+	for _, q := range []string{"", "metric=heart_rate&limit=0", "metric=heart_rate&limit=100001", "metric=heart_rate&after=yesterday"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/export/metrics?"+q, nil)
+		rec := httptest.NewRecorder()
+		s.handleExportMetric(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+// TestHandleChangesBadAfter verifies a missing or malformed after is
+// rejected before querying.
+func TestHandleChangesBadAfter(t *testing.T) {
+	s := &Server{}
+	for _, q := range []string{"", "after=2025-01-01", "after=yesterday"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/changes?"+q, nil)
+		rec := httptest.NewRecorder()
+		s.handleChanges(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+// TestParseTimeRangeHalfOpen verifies a date-only end extends to the next
+// midnight so the whole day is included, while an RFC 3339 end is used as
+// the exclusive bound as given.
+func TestParseTimeRangeHalfOpen(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/?start=2025-03-01&end=2025-03-01", nil)
+	start, end, err := parseTimeRange(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !start.Equal(time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)) || !end.Equal(time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("date-only range = [%v, %v), want all of March 1st", start, end)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/?start=2025-03-01T00:00:00Z&end=2025-03-01T12:00:00Z", nil)
+	_, end, err = parseTimeRange(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !end.Equal(time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("RFC 3339 end = %v, want 12:00 exactly", end)
+	}
+}
+
+// TestParseTimeRangeEndInclusive pins end_inclusive for date-only and
+// RFC 3339 ends: omitted keeps the per-format default, true includes the end
+// value, false excludes it, and anything else is an error.
+func TestParseTimeRangeEndInclusive(t *testing.T) {
+	day := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		end, inclusive string
+		want           time.Time
+	}{
+		{"2026-01-31", "", day.AddDate(0, 0, 1)},
+		{"2026-01-31", "true", day.AddDate(0, 0, 1)},
+		{"2026-01-31", "false", day},
+		{"2026-01-31T00:00:00Z", "", day},
+		{"2026-01-31T00:00:00Z", "false", day},
+		{"2026-01-31T00:00:00Z", "true", day.Add(time.Microsecond)},
+	}
+	for _, tt := range tests {
+		q := "/?start=2026-01-01&end=" + tt.end
+		if tt.inclusive != "" {
+			q += "&end_inclusive=" + tt.inclusive
+		}
+		_, end, err := parseTimeRange(httptest.NewRequest(http.MethodGet, q, nil))
+		if err != nil {
+			t.Errorf("%s: %v", q, err)
+			continue
+		}
+		if !end.Equal(tt.want) {
+			t.Errorf("%s: end = %v, want %v", q, end, tt.want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/?start=2026-01-01&end=2026-01-31&end_inclusive=maybe", nil)
+	if _, _, err := parseTimeRange(req); err == nil {
+		t.Error("expected error for end_inclusive=maybe")
+	}
+}
+
+// TestHandleLatestMetricsBadDay verifies an unknown daily sums day is
+// rejected before querying.
+func TestHandleLatestMetricsBadDay(t *testing.T) {
+	s := &Server{}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/metrics/latest?day=yesterday", nil)
+	rec := httptest.NewRecorder()
+	s.handleLatestMetrics(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
+
+// TestTimeSeriesBucket verifies agg values map to bucket intervals and
+// unknown values fall back to daily.
+func TestTimeSeriesBucket(t *testing.T) {
+	for agg, want := range map[string]string{
+		"hourly": "1 hour", "daily": "1 day", "weekly": "1 week", "monthly": "1 month", "": "1 day", "yearly": "1 day",
+	} {
+		if got := timeSeriesBucket(agg); got != want {
+			t.Errorf("timeSeriesBucket(%q) = %q, want %q", agg, got, want)
+		}
+	}
+}
+
+// TestHandleBulkTimeSeriesValidation verifies a missing or oversized metric
+// list and a bad range are rejected before querying.
+func TestHandleBulkTimeSeriesValidation(t *testing.T) {
+	s := &Server{}
+	tooMany := strings.TrimSuffix(strings.Repeat("m,", storage.MaxBulkMetrics+1), ",")
+	for _, q := range []string{
+		"",
+		"?metrics=,%20,",
+		"?metrics=" + tooMany,
+		"?metrics=heart_rate&start=nope",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/timeseries/bulk"+q, nil)
+		rec := httptest.NewRecorder()
+		s.handleBulkTimeSeries(rec, req)
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+// TestMetricsResponseShape verifies GET /api/v1/metrics answers with the same
+// envelope keys whether it returns raw rows or downsampled buckets, with the
+// unused list empty rather than null.
+func TestMetricsResponseShape(t *testing.T) {
+	v := 72.0
+	keys := func(resp map[string]any) map[string]json.RawMessage {
+		rec := httptest.NewRecorder()
+		writeJSON(rec, http.StatusOK, resp)
+		var got map[string]json.RawMessage
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	raw := keys(metricsResponse([]models.HealthMetricRow{{MetricName: "heart_rate", Qty: &v}}, nil, "", 1))
+	bucketed := keys(metricsResponse(nil, []storage.TimeSeriesPoint{{}}, "1 hour", 90000))
+	for _, k := range []string{"downsampled", "bucket", "raw_rows", "rows", "points"} {
+		if raw[k] == nil || bucketed[k] == nil {
+			t.Errorf("key %q missing: raw %s, bucketed %s", k, raw[k], bucketed[k])
+		}
+	}
+	if string(raw["downsampled"]) != "false" || string(raw["points"]) != "[]" || !strings.Contains(string(raw["rows"]), "heart_rate") {
+		t.Errorf("raw response = %v", raw)
+	}
+	if string(bucketed["downsampled"]) != "true" || string(bucketed["rows"]) != "[]" || string(bucketed["bucket"]) != `"1 hour"` {
+		t.Errorf("downsampled response = %v", bucketed)
+	}
+}
@@ -1,12 +1,16 @@
 package server
 
 import (
+	"compress/gzip"
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"tailscale.com/client/tailscale/apitype"
 )
 
@@ -24,10 +28,14 @@ type userStore interface {
 type contextKey int
 
 const (
-	userIDKey   contextKey = iota
-	userInfoKey            // stores UserInfo alongside userID
+	userIDKey    contextKey = iota
+	userInfoKey             // stores UserInfo alongside userID
+	requestIDKey            // correlation ID set by RequestID
 )
 
+// requestIDHeader carries the correlation ID in both directions.
+const requestIDHeader = "X-Request-ID"
+
 // UserInfo holds the authenticated user's identity details.
 type UserInfo struct {
 	Login       string `json:"login"`
@@ -49,7 +57,7 @@ func userIDFromContext(r *http.Request) (int, bool) {
 func mustUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
 	uid, ok := userIDFromContext(r)
 	if !ok {
-		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "no authenticated user in request context"})
+		writeError(w, http.StatusInternalServerError, "no authenticated user in request context")
 		return 0, false
 	}
 	return uid, true
@@ -74,7 +82,7 @@ func TailscaleIdentity(lc whoisClient, db userStore, log *slog.Logger) func(http
 			whois, err := lc.WhoIs(r.Context(), r.RemoteAddr)
 			if err != nil {
 				log.Error("tailscale whois failed", "remote", r.RemoteAddr, "error", err)
-				http.Error(w, `{"error":"identity lookup failed"}`, http.StatusInternalServerError)
+				writeError(w, http.StatusInternalServerError, "identity lookup failed")
 				return
 			}
 
@@ -86,7 +94,7 @@ func TailscaleIdentity(lc whoisClient, db userStore, log *slog.Logger) func(http
 				if err != nil {
 					log.Warn("tagged device access denied: no registered user yet",
 						"node", whois.Node.ComputedName)
-					http.Error(w, `{"error":"access denied: no registered user yet; log in from a personal device first"}`, http.StatusForbidden)
+					writeError(w, http.StatusForbidden, "access denied: no registered user yet; log in from a personal device first")
 					return
 				}
 				login = ownerLogin
@@ -101,7 +109,7 @@ func TailscaleIdentity(lc whoisClient, db userStore, log *slog.Logger) func(http
 				// Personal device — use WhoIs identity.
 				login = whois.UserProfile.LoginName
 				if login == "" {
-					http.Error(w, `{"error":"access denied: personal Tailscale login required"}`, http.StatusForbidden)
+					writeError(w, http.StatusForbidden, "access denied: personal Tailscale login required")
 					return
 				}
 				displayName = whois.UserProfile.DisplayName
@@ -115,7 +123,7 @@ func TailscaleIdentity(lc whoisClient, db userStore, log *slog.Logger) func(http
 			userID, err := db.GetOrCreateUser(r.Context(), login, displayName)
 			if err != nil {
 				log.Error("user resolution failed", "login", login, "error", err)
-				http.Error(w, `{"error":"user resolution failed"}`, http.StatusInternalServerError)
+				writeError(w, http.StatusInternalServerError, "user resolution failed")
 				return
 			}
 
@@ -159,6 +167,51 @@ func DevIdentity(next http.Handler) http.Handler {
 	})
 }
 
+// requestIDFromContext returns the request's correlation ID, or "" if the
+// RequestID middleware hasn't run.
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// validRequestID reports whether a client-supplied X-Request-ID is safe to
+// echo into headers and logs: short, printable ASCII without spaces.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, c := range id {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// RequestID returns middleware that assigns each request a correlation ID,
+// reusing a valid incoming X-Request-ID (e.g. from a proxy or the uploader)
+// and generating one otherwise. The ID is stored in the context and echoed
+// in the response header. Mount it before RequestLogging.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !validRequestID(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey, id)))
+	})
+}
+
+// reqLog returns the server logger tagged with the request's correlation ID,
+// for handler logs that should be traceable back to the request line.
+func (s *Server) reqLog(r *http.Request) *slog.Logger {
+	if id := requestIDFromContext(r.Context()); id != "" {
+		return s.log.With("request_id", id)
+	}
+	return s.log
+}
+
 // RequestLogging returns middleware that logs each request.
 func RequestLogging(log *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -171,17 +224,105 @@ func RequestLogging(log *slog.Logger) func(http.Handler) http.Handler {
 				"path", r.URL.Path,
 				"status", sw.status,
 				"duration", time.Since(start).String(),
+				"request_id", requestIDFromContext(r.Context()),
 			)
 		})
 	}
 }
 
+// GzipRequest returns middleware that transparently decompresses request bodies
+// sent with Content-Encoding: gzip. Uncompressed requests pass through untouched.
+// A body that doesn't start with a valid gzip header is rejected with 400;
+// corruption later in the stream surfaces as a read error in the handler.
+func GzipRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.EqualFold(strings.TrimSpace(r.Header.Get("Content-Encoding")), "gzip") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid gzip body: "+err.Error())
+			return
+		}
+		defer zr.Close() //nolint:errcheck
+
+		r.Body = zr
+		r.Header.Del("Content-Encoding")
+		r.Header.Del("Content-Length")
+		r.ContentLength = -1
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MaxBodySize returns middleware that caps request bodies at limit bytes.
+// Requests declaring a larger Content-Length are rejected with 413 up front;
+// otherwise the body is wrapped so reads past the limit fail with
+// *http.MaxBytesError (see writeBodyError). A limit <= 0 disables the check.
+// Mount it after GzipRequest so the decompressed size is what counts.
+func MaxBodySize(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				writeError(w, http.StatusRequestEntityTooLarge, "request body too large")
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Query timeouts used until SetQueryTimeouts is called.
+const (
+	defaultQueryTimeout      = 30 * time.Second
+	defaultHeavyQueryTimeout = 2 * time.Minute
+)
+
+// queryTimeout returns middleware that bounds the request context, and with
+// it every database call the handler makes, by the default query timeout or,
+// when heavy is set, the longer one. Handlers report the resulting deadline
+// errors as 504 via writeServerError.
+func (s *Server) queryTimeout(heavy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d := s.queryTimeoutDefault
+			if heavy {
+				d = s.queryTimeoutHeavy
+			}
+			if d <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// writeBodyError reports a failure reading or decoding the request body:
+// 413 when MaxBodySize cut it off, otherwise 400 with msg.
+func writeBodyError(w http.ResponseWriter, err error, msg string) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+		return
+	}
+	writeError(w, http.StatusBadRequest, msg)
+}
+
 // CORS adds permissive CORS headers for local development.
 func CORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Encoding, If-None-Match, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag, Last-Modified, X-Request-ID")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusNoContent)
 			return
@@ -1,12 +1,21 @@
 package server
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
 
 	"tailscale.com/client/tailscale/apitype"
 	"tailscale.com/tailcfg"
@@ -347,3 +356,255 @@ func TestTailscaleIdentityTaggedNodeNoOwner(t *testing.T) {
 		t.Errorf("status = %d, want 403", rec.Code)
 	}
 }
+
+// gzipBytes compresses data for use as a request body in gzip tests.
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatalf("gzip write: %v", err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("gzip close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// TestGzipRequestDecodesPayload verifies that a gzip-encoded ingest payload is
+// transparently decompressed so handlers decode the same JSON they would
+// receive uncompressed.
+func TestGzipRequestDecodesPayload(t *testing.T) {
+	raw := []byte(`{"data":{"metrics":[{"name":"step_count","units":"count","data":[{"date":"2025-01-01 08:00:00 +0000","qty":42}]}]}}`)
+
+	var got models.HealthPayload
+	handler := GzipRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if enc := r.Header.Get("Content-Encoding"); enc != "" {
+			t.Errorf("Content-Encoding = %q, want it stripped", enc)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ingest/", bytes.NewReader(gzipBytes(t, raw)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if len(got.Data.Metrics) != 1 || got.Data.Metrics[0].Name != "step_count" {
+		t.Errorf("metrics = %+v, want one step_count metric", got.Data.Metrics)
+	}
+}
+
+// TestGzipRequestPassthrough verifies that uncompressed requests are handed
+// to the next handler unchanged, so existing clients keep working.
+func TestGzipRequestPassthrough(t *testing.T) {
+	var body string
+	handler := GzipRequest(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatalf("read error: %v", err)
+		}
+		body = string(b)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"a":1}`))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if body != `{"a":1}` {
+		t.Errorf("body = %q, want passthrough", body)
+	}
+}
+
+// TestGzipRequestMalformed verifies that bodies claiming gzip encoding but
+// carrying garbage or a truncated stream are rejected with 400 rather than
+// reaching ingest with partial data.
+func TestGzipRequestMalformed(t *testing.T) {
+	valid := gzipBytes(t, []byte(`{"data":{"metrics":[]}}`))
+
+	tests := []struct {
+		name string
+		body []byte
+	}{
+		{"not gzip", []byte(`{"data":{}}`)},
+		{"truncated stream", valid[:len(valid)/2]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{}
+			handler := GzipRequest(http.HandlerFunc(s.handleIngest))
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/ingest/", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Encoding", "gzip")
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400", rec.Code)
+			}
+		})
+	}
+}
+
+// TestMaxBodySizeOversized verifies an ingest body over the limit is rejected
+// with 413, both when Content-Length announces it and when it only becomes
+// apparent while streaming (chunked, or after gzip decoding).
+func TestMaxBodySizeOversized(t *testing.T) {
+	s := &Server{maxBodyBytes: 64}
+	var reached bool
+	ingest := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		var payload models.HealthPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			writeBodyError(w, err, "invalid JSON")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	h := GzipRequest(s.limitBody(ingest))
+	big := []byte(`{"data":{"metrics":[` + strings.Repeat(`{"name":"step_count"},`, 20) + `]}}`)
+
+	// Announced length: rejected before the handler runs.
+	reached = false
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/ingest/", bytes.NewReader(big)))
+	if rec.Code != http.StatusRequestEntityTooLarge || reached {
+		t.Errorf("content-length: status = %d reached = %v, want 413 before handler", rec.Code, reached)
+	}
+
+	// Unknown length: cut off mid-read.
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ingest/", io.MultiReader(bytes.NewReader(big)))
+	req.ContentLength = -1
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("streamed: status = %d, want 413", rec.Code)
+	}
+
+	// Small compressed body that inflates past the limit.
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/ingest/", bytes.NewReader(gzipBytes(t, big)))
+	req.Header.Set("Content-Encoding", "gzip")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("gzip: status = %d, want 413", rec.Code)
+	}
+}
+
+// TestMaxBodySizeWithinLimit verifies bodies under the limit, and any body
+// when the limit is disabled, reach the handler untouched.
+func TestMaxBodySizeWithinLimit(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			writeBodyError(w, err, "read failed")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	for _, limit := range []int64{1024, 0} {
+		rec := httptest.NewRecorder()
+		MaxBodySize(limit)(ok).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"data":{}}`)))
+		if rec.Code != http.StatusOK {
+			t.Errorf("limit %d: status = %d, want 200", limit, rec.Code)
+		}
+	}
+}
+
+// TestRequestIDRoundTrip verifies a client's X-Request-ID is echoed back and
+// visible to handlers, so logs on both sides can be correlated.
+func TestRequestIDRoundTrip(t *testing.T) {
+	var seen string
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/ingest/", nil)
+	req.Header.Set("X-Request-ID", "upload-42")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if got := rec.Header().Get("X-Request-ID"); got != "upload-42" {
+		t.Errorf("response header = %q, want upload-42", got)
+	}
+	if seen != "upload-42" {
+		t.Errorf("context id = %q, want upload-42", seen)
+	}
+}
+
+// TestRequestIDGenerated verifies a missing or unsafe incoming ID is replaced
+// with a fresh one rather than echoed into headers and logs.
+func TestRequestIDGenerated(t *testing.T) {
+	h := RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, incoming := range []string{"", "has space", strings.Repeat("x", 200)} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if incoming != "" {
+			req.Header.Set("X-Request-ID", incoming)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		got := rec.Header().Get("X-Request-ID")
+		if got == "" || got == incoming {
+			t.Errorf("incoming %q: response id = %q, want a generated id", incoming, got)
+		}
+	}
+}
+
+// TestRequestLoggingIncludesRequestID verifies the access log line carries
+// the correlation ID when RequestID runs first.
+func TestRequestLoggingIncludesRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	log := slog.New(slog.NewJSONHandler(&buf, nil))
+	h := RequestID(RequestLogging(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+	req.Header.Set("X-Request-ID", "abc-123")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(buf.String(), `"request_id":"abc-123"`) {
+		t.Errorf("log line missing request_id: %s", buf.String())
+	}
+}
+
+// TestQueryTimeoutDeadline verifies a query still running at the query
+// timeout is cancelled and reported as 504, and the heavy timeout applies
+// when requested.
+func TestQueryTimeoutDeadline(t *testing.T) {
+	s := &Server{queryTimeoutDefault: 10 * time.Millisecond, queryTimeoutHeavy: time.Hour}
+	slowQuery := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+			writeServerError(w, fmt.Errorf("querying correlation: %w", r.Context().Err()))
+		case <-time.After(50 * time.Millisecond):
+			writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	s.queryTimeout(false)(slowQuery).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusGatewayTimeout || !strings.Contains(rec.Body.String(), CodeTimeout) {
+		t.Errorf("default: status = %d body = %s, want 504 timeout", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.queryTimeout(true)(slowQuery).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("heavy: status = %d, want 200", rec.Code)
+	}
+}
+
+// TestWriteServerErrorInternal verifies an ordinary query error is reported
+// as 500 with its message.
+func TestWriteServerErrorInternal(t *testing.T) {
+	rec := httptest.NewRecorder()
+	writeServerError(rec, errors.New("querying metrics: connection refused"))
+	if rec.Code != http.StatusInternalServerError || !strings.Contains(rec.Body.String(), "connection refused") {
+		t.Errorf("status = %d body = %s, want 500 with the error message", rec.Code, rec.Body.String())
+	}
+}
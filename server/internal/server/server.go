@@ -7,8 +7,10 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/claude/freereps/internal/ingest/alpha"
+	"github.com/claude/freereps/internal/ingest/fit"
 	"github.com/claude/freereps/internal/ingest/health"
 	freerepsmcp "github.com/claude/freereps/internal/mcp"
 	"github.com/claude/freereps/internal/oura"
@@ -23,6 +25,7 @@ type Server struct {
 	db     *storage.DB
 	health *health.Provider
 	alpha  *alpha.Provider
+	fit    *fit.Provider
 	log    *slog.Logger
 	lc     *local.Client
 	router chi.Router
@@ -31,9 +34,35 @@ type Server struct {
 	ouraTokenMgr *oura.TokenManager
 	ouraSyncer   *oura.Syncer
 
-	// HAE TCP import state (only one import at a time)
-	importMu     sync.Mutex
-	activeImport *haeImportState
+	// HAE TCP import state per user ID (one import per user at a time)
+	importMu sync.Mutex
+	imports  map[int]*haeImportState
+
+	// maxBodyBytes caps ingest/import bodies (0 = unlimited).
+	maxBodyBytes int64
+
+	// retentionPolicies are applied by the admin retention endpoint.
+	retentionPolicies []storage.RetentionPolicy
+
+	// maxImportChunks caps the date chunks one HAE TCP import may request.
+	maxImportChunks int
+
+	// importStallTimeout ends an HAE TCP import that makes no progress for
+	// this long (0 = never); see watchImport.
+	importStallTimeout time.Duration
+
+	// maxRawRows caps the unaggregated rows GET /api/v1/metrics returns.
+	maxRawRows int
+
+	// queryTimeoutDefault and queryTimeoutHeavy bound the request context of
+	// read endpoints (0 = unbounded); see queryTimeout.
+	queryTimeoutDefault time.Duration
+	queryTimeoutHeavy   time.Duration
+
+	// migrationDSN and migrationsPath let admins apply pending migrations
+	// without a restart (empty = not configured).
+	migrationDSN   string
+	migrationsPath string
 }
 
 // SetOura configures the Oura integration components.
@@ -47,18 +76,82 @@ func (s *Server) SetOura(tm *oura.TokenManager, syncer *oura.Syncer) {
 var Version = "dev"
 
 // New creates a new Server with all routes configured.
-func New(db *storage.DB, healthProvider *health.Provider, alphaProvider *alpha.Provider, log *slog.Logger) *Server {
+func New(db *storage.DB, healthProvider *health.Provider, alphaProvider *alpha.Provider, fitProvider *fit.Provider, log *slog.Logger) *Server {
 	s := &Server{
 		db:     db,
 		health: healthProvider,
 		alpha:  alphaProvider,
+		fit:    fitProvider,
 		log:    log,
 		router: chi.NewRouter(),
+
+		maxImportChunks: defaultMaxImportChunks,
+		maxRawRows:      defaultMaxRawRows,
+
+		queryTimeoutDefault: defaultQueryTimeout,
+		queryTimeoutHeavy:   defaultHeavyQueryTimeout,
 	}
 	s.routes()
 	return s
 }
 
+// SetMaxBodyBytes caps the size of ingest and import request bodies.
+// Must be called before the server starts handling requests.
+func (s *Server) SetMaxBodyBytes(n int64) {
+	s.maxBodyBytes = n
+}
+
+// SetRetentionPolicies configures the policies run by POST /api/v1/admin/retention.
+// Must be called before the server starts handling requests.
+func (s *Server) SetRetentionPolicies(policies []storage.RetentionPolicy) {
+	s.retentionPolicies = policies
+}
+
+// SetMaxImportChunks caps how many date chunks a single HAE TCP import may
+// request. Must be called before the server starts handling requests.
+func (s *Server) SetMaxImportChunks(n int) {
+	s.maxImportChunks = n
+}
+
+// SetImportStallTimeout sets how long an HAE TCP import may go without
+// starting a new chunk before it is ended as errored. 0 disables the
+// watchdog. Must be called before the server starts handling requests.
+func (s *Server) SetImportStallTimeout(d time.Duration) {
+	s.importStallTimeout = d
+}
+
+// SetMaxRawRows caps how many raw rows GET /api/v1/metrics returns before
+// falling back to bucketed aggregates. Must be called before the server
+// starts handling requests.
+func (s *Server) SetMaxRawRows(n int) {
+	s.maxRawRows = n
+}
+
+// SetQueryTimeouts bounds how long read endpoints may spend on database
+// queries: d for most, heavy for expensive aggregations such as correlations
+// and training summaries. Zero disables the bound. Must be called before the
+// server starts handling requests.
+func (s *Server) SetQueryTimeouts(d, heavy time.Duration) {
+	s.queryTimeoutDefault = d
+	s.queryTimeoutHeavy = heavy
+}
+
+// SetMigrations configures the database and directory the admin migrations
+// endpoint reports on and applies. Must be called before the server starts
+// handling requests.
+func (s *Server) SetMigrations(dsn, path string) {
+	s.migrationDSN = dsn
+	s.migrationsPath = path
+}
+
+// limitBody applies MaxBodySize with the configured limit. The limit is read
+// per request because routes are registered in New, before SetMaxBodyBytes.
+func (s *Server) limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		MaxBodySize(s.maxBodyBytes)(next).ServeHTTP(w, r)
+	})
+}
+
 // SetTailscale configures the Tailscale LocalClient for identity resolution.
 // Must be called before the server starts handling requests.
 // When set, all requests are authenticated via Tailscale identity.
@@ -103,11 +196,13 @@ func (s *Server) identityMiddleware() func(http.Handler) http.Handler {
 }
 
 func (s *Server) routes() {
+	s.router.Use(RequestID)
 	s.router.Use(RequestLogging(s.log))
 	s.router.Use(CORS)
 
-	// Public endpoint — no auth required.
+	// Public endpoints — no auth required.
 	s.router.Get("/api/v1/version", s.handleVersion)
+	s.router.Get("/healthz", s.handleHealthz)
 
 	// All routes require identity (Tailscale or dev fallback).
 	s.router.Group(func(r chi.Router) {
@@ -115,43 +210,98 @@ func (s *Server) routes() {
 
 		// Ingest endpoints
 		r.Route("/api/v1/ingest", func(r chi.Router) {
+			r.Use(GzipRequest, s.limitBody)
 			r.Post("/", s.handleIngest)
 			r.Post("/alpha", s.handleAlphaIngest)
+			r.Post("/fit", s.handleFITIngest)
 		})
 
 		// Unified import with auto-detection
-		r.Post("/api/v1/import", s.handleUnifiedImport)
+		r.With(GzipRequest, s.limitBody).Post("/api/v1/import", s.handleUnifiedImport)
 
 		// User identity
 		r.Get("/api/v1/me", s.handleMe)
+		r.Get("/api/v1/profile", s.handleGetProfile)
+		r.Put("/api/v1/profile", s.handleUpsertProfile)
+
+		// Read endpoints run under the default query timeout.
+		r.Group(func(r chi.Router) {
+			r.Use(s.queryTimeout(false))
+
+			// Dashboard API endpoints
+			r.Get("/api/v1/dashboard", s.handleDashboard)
+			r.Get("/api/v1/dashboard/init", s.handleDashboardInit)
+			r.Get("/api/v1/metrics/latest", s.handleLatestMetrics)
+			r.Get("/api/v1/metrics", s.handleQueryMetrics)
+			r.Get("/api/v1/sleep", s.handleQuerySleep)
+			r.Get("/api/v1/sleep/summary", s.handleSleepSummary)
+			r.Get("/api/v1/sleep/debt", s.handleSleepDebt)
+			r.Get("/api/v1/sleep/stages", s.handleSleepStages)
+			r.Get("/api/v1/sleep/{date}", s.handleSleepNight)
+			r.Get("/api/v1/sleep/{date}/architecture", s.handleSleepArchitecture)
+			r.Get("/api/v1/heart-rate/zones", s.handleHRZones)
+			r.Get("/api/v1/training/intensity-trend", s.handleIntensityTrend)
+			r.Get("/api/v1/training/best-efforts", s.handleBestEfforts)
+			r.Get("/api/v1/training/exercises", s.handleExercises)
+			r.Get("/api/v1/training/exercise-report", s.handleExerciseReport)
+			r.Get("/api/v1/reports/weekly", s.handleWeeklyReport)
+			r.Get("/api/v1/export/alpha", s.handleExportAlpha)
+			r.Get("/api/v1/training/calendar", s.handleActivityCalendar)
+			r.Get("/api/v1/training/workout-types", s.handleWorkoutTypes)
+			r.Get("/api/v1/changes", s.handleChanges)
+			r.Get("/api/v1/workouts", s.handleQueryWorkouts)
+			r.Get("/api/v1/workouts/{id}", s.handleGetWorkout)
+			r.Patch("/api/v1/workouts/{id}", s.handleUpdateWorkout)
+			r.Post("/api/v1/workouts/{id}/tags", s.handleAddWorkoutTag)
+			r.Delete("/api/v1/workouts/{id}/tags/{tag}", s.handleRemoveWorkoutTag)
+			r.Get("/api/v1/workouts/{id}/sets", s.handleWorkoutSets)
+			r.Get("/api/v1/workouts/{id}/combined", s.handleWorkoutCombined)
+			r.Get("/api/v1/workouts/{id}/intervals", s.handleWorkoutIntervals)
+			r.Get("/api/v1/metrics/stats", s.handleMetricStats)
+			r.Get("/api/v1/metrics/quality", s.handleDataQuality)
+			r.Get("/api/v1/metrics/steps", s.handleDailySteps)
+			r.Get("/api/v1/timeseries", s.handleTimeSeries)
+			r.Get("/api/v1/timeseries/bulk", s.handleBulkTimeSeries)
+			r.Get("/api/v1/aggregate", s.handleAggregate)
+			r.Get("/api/v1/allowlist", s.handleAllowlist)
+			r.Get("/api/v1/allowlist/rejected", s.handleRejectedMetrics)
+			r.Post("/api/v1/allowlist/rejected/{metric}/allow", s.handleAllowRejectedMetric)
+			r.Put("/api/v1/allowlist/{metric}", s.handleUpdateMetricDisplay)
+			r.Get("/api/v1/metric-aliases", s.handleListMetricAliases)
+			r.Put("/api/v1/metric-aliases", s.handleUpsertMetricAlias)
+			r.Get("/api/v1/metrics/available", s.handleAvailableMetrics)
+			r.Put("/api/v1/metrics/visibility", s.handleSaveMetricVisibility)
+
+			// Health data endpoints
+			r.Get("/api/v1/ecg", s.handleGetECGRecordings)
+			r.Get("/api/v1/audiograms", s.handleGetAudiograms)
+			r.Get("/api/v1/activity-summaries", s.handleGetActivitySummaries)
+			r.Get("/api/v1/medications", s.handleGetMedications)
+			r.Get("/api/v1/vision-prescriptions", s.handleGetVisionPrescriptions)
+			r.Get("/api/v1/state-of-mind", s.handleGetStateOfMind)
+			r.Get("/api/v1/category-samples", s.handleGetCategorySamples)
+			r.Get("/api/v1/symptoms", s.handleGetSymptoms)
+		})
 
-		// Dashboard API endpoints
-		r.Get("/api/v1/dashboard/init", s.handleDashboardInit)
-		r.Get("/api/v1/metrics/latest", s.handleLatestMetrics)
-		r.Get("/api/v1/metrics", s.handleQueryMetrics)
-		r.Get("/api/v1/sleep", s.handleQuerySleep)
-		r.Get("/api/v1/workouts", s.handleQueryWorkouts)
-		r.Get("/api/v1/workouts/{id}", s.handleGetWorkout)
-		r.Get("/api/v1/workouts/{id}/sets", s.handleWorkoutSets)
-		r.Get("/api/v1/metrics/stats", s.handleMetricStats)
-		r.Get("/api/v1/timeseries", s.handleTimeSeries)
-		r.Get("/api/v1/correlation", s.handleCorrelation)
-		r.Get("/api/v1/allowlist", s.handleAllowlist)
-		r.Get("/api/v1/metrics/available", s.handleAvailableMetrics)
-		r.Put("/api/v1/metrics/visibility", s.handleSaveMetricVisibility)
-
-		// Health data endpoints
-		r.Get("/api/v1/ecg", s.handleGetECGRecordings)
-		r.Get("/api/v1/audiograms", s.handleGetAudiograms)
-		r.Get("/api/v1/activity-summaries", s.handleGetActivitySummaries)
-		r.Get("/api/v1/medications", s.handleGetMedications)
-		r.Get("/api/v1/vision-prescriptions", s.handleGetVisionPrescriptions)
-		r.Get("/api/v1/state-of-mind", s.handleGetStateOfMind)
-		r.Get("/api/v1/category-samples", s.handleGetCategorySamples)
+		// Heavy aggregations get the longer timeout.
+		r.With(s.queryTimeout(true)).Get("/api/v1/training/summary", s.handleTrainingSummary)
+		r.With(s.queryTimeout(true)).Get("/api/v1/correlation", s.handleCorrelation)
+
+		// Streaming exports and bulk rewrites are left unbounded.
+		r.Get("/api/v1/export/metrics", s.handleExportMetric)
+		r.Post("/api/v1/workouts/moving-time/recompute", s.handleRecomputeMovingTime)
 
 		// Settings / admin endpoints
 		r.Get("/api/v1/stats", s.handleStats)
 		r.Get("/api/v1/import-logs", s.handleImportLogs)
+		r.Post("/api/v1/sleep/backfill", s.handleSleepBackfill)
+		r.Post("/api/v1/admin/retention", s.handleRetention)
+		r.Get("/api/v1/admin/migrations", s.handleMigrationStatus)
+		r.Post("/api/v1/admin/migrations", s.handleRunMigrations)
+		r.Get("/api/v1/admin/units", s.handleUnitConsistency)
+		r.Post("/api/v1/admin/units/repair", s.handleRepairUnits)
+		r.Get("/api/v1/admin/users", s.handleAdminUsers)
+		r.Delete("/api/v1/admin/users/{id}/data", s.handleDeleteUserData)
 
 		// Source priority configuration
 		r.Route("/api/v1/source-priority", func(r chi.Router) {
@@ -173,6 +323,7 @@ func (s *Server) routes() {
 		// HAE TCP import
 		r.Post("/api/v1/import/hae-tcp/check", s.handleCheckHAE)
 		r.Post("/api/v1/import/hae-tcp", s.handleStartHAEImport)
+		r.Post("/api/v1/import/hae-tcp/rerun/{logId}", s.handleRerunHAEImport)
 		r.Delete("/api/v1/import/hae-tcp", s.handleCancelHAEImport)
 		r.Get("/api/v1/import/hae-tcp/status", s.handleHAEImportStatus)
 		r.Get("/api/v1/import/hae-tcp/events", s.handleHAEImportEvents)
@@ -182,7 +333,24 @@ func (s *Server) routes() {
 // SetFrontend mounts the embedded SPA filesystem.
 // Unmatched routes serve index.html for client-side routing.
 // Hashed assets get long cache; index.html is never cached.
+// Without an index.html (a backend-only build) non-API paths get a JSON
+// "API-only mode" 404 instead of failing to serve a missing file.
 func (s *Server) SetFrontend(webFS fs.FS) {
+	if _, err := fs.Stat(webFS, "index.html"); err != nil {
+		s.log.Warn("no frontend embedded (web/dist is empty); running in API-only mode")
+		s.router.NotFound(func(w http.ResponseWriter, r *http.Request) {
+			writeAPIError(w, &APIError{
+				Code:    CodeNotFound,
+				Message: "not found",
+				Details: map[string]string{
+					"mode": "api-only",
+					"hint": "this build has no web frontend; use the /api/v1 endpoints",
+				},
+			})
+		})
+		return
+	}
+
 	fileServer := http.FileServerFS(webFS)
 
 	s.router.NotFound(func(w http.ResponseWriter, r *http.Request) {
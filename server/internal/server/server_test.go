@@ -0,0 +1,56 @@
+package server
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TestSetFrontendEmptyFS verifies a build without a frontend still serves API
+// routes and answers other paths with a JSON API-only response.
+func TestSetFrontendEmptyFS(t *testing.T) {
+	s := &Server{router: chi.NewRouter(), log: slog.New(slog.DiscardHandler)}
+	s.router.Get("/api/v1/version", s.handleVersion)
+	s.SetFrontend(fstest.MapFS{})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/version", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("API route status = %d, want 200", rec.Code)
+	}
+
+	for _, path := range []string{"/", "/workouts/abc", "/api/v1/nope"} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want 404", path, rec.Code)
+		}
+		if !strings.Contains(rec.Body.String(), `"mode":"api-only"`) {
+			t.Errorf("%s: body = %s, want API-only JSON", path, rec.Body.String())
+		}
+	}
+}
+
+// TestSetFrontendSPAFallback verifies unknown paths serve index.html when a
+// frontend is embedded, while unknown API paths still 404.
+func TestSetFrontendSPAFallback(t *testing.T) {
+	s := &Server{router: chi.NewRouter(), log: slog.New(slog.DiscardHandler)}
+	s.SetFrontend(fstest.MapFS{"index.html": {Data: []byte("<html>app</html>")}})
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/workouts/abc", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "app") {
+		t.Errorf("SPA fallback = %d %q, want index.html", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/nope", nil))
+	if rec.Code != http.StatusNotFound || strings.Contains(rec.Body.String(), "app") {
+		t.Errorf("unknown API path = %d %q, want plain 404", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/claude/freereps/internal/ingest"
+	"github.com/claude/freereps/internal/models"
+	"github.com/claude/freereps/internal/storage"
+)
+
+// Unit systems accepted by the units query parameter.
+const (
+	unitsMetric   = "metric"
+	unitsImperial = "imperial"
+)
+
+const (
+	feetPerMetre = 3.28084
+	mphPerMPS    = 2.236936
+)
+
+// quantity is a converted value with its display unit.
+type quantity struct {
+	Value float64 `json:"value"`
+	Unit  string  `json:"unit"`
+}
+
+// workoutUnits is a workout's distance, elevation and average speed in the
+// requested unit system. Speed is pace (min/km) for metric and mph for
+// imperial. Fields are omitted when the workout doesn't record them.
+type workoutUnits struct {
+	System        string    `json:"system"`
+	Distance      *quantity `json:"distance,omitempty"`
+	ElevationUp   *quantity `json:"elevation_up,omitempty"`
+	ElevationDown *quantity `json:"elevation_down,omitempty"`
+	Speed         *quantity `json:"speed,omitempty"`
+}
+
+// routePointUnits is one route point's altitude and speed in the requested
+// unit system, aligned by index with RouteData.
+type routePointUnits struct {
+	Altitude *quantity `json:"altitude,omitempty"`
+	Speed    *quantity `json:"speed,omitempty"`
+}
+
+// parseUnitSystem reads the units query parameter. It returns "" when the
+// request doesn't pick a system; see preferredUnits.
+func parseUnitSystem(r *http.Request) (string, error) {
+	switch u := r.URL.Query().Get("units"); u {
+	case "", unitsMetric, unitsImperial:
+		return u, nil
+	default:
+		return "", fmt.Errorf("invalid units %q: want metric or imperial", u)
+	}
+}
+
+// preferredUnits returns system if the request picked one, else the unit
+// system saved in the user's profile, else metric.
+func (s *Server) preferredUnits(ctx context.Context, userID int, system string) (string, error) {
+	if system != "" {
+		return system, nil
+	}
+	p, err := s.db.GetUserProfile(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if p != nil && p.UnitSystem != "" {
+		return p.UnitSystem, nil
+	}
+	return unitsMetric, nil
+}
+
+// distanceToKm converts a stored workout distance to kilometres with
+// ingest.ConvertUnit, the same factors workoutDistanceKmSQL uses in storage.
+// Unknown units are taken as km.
+func distanceToKm(d float64, units string) float64 {
+	if km, ok := ingest.ConvertUnit(d, units, "km"); ok {
+		return km
+	}
+	return d
+}
+
+// convertDistance converts kilometres to the unit system's distance unit.
+func convertDistance(km float64, system string) quantity {
+	if system == unitsImperial {
+		mi, _ := ingest.ConvertUnit(km, "km", "mi")
+		return quantity{mi, "mi"}
+	}
+	return quantity{km, "km"}
+}
+
+// convertElevation converts metres to the unit system's elevation unit.
+func convertElevation(m float64, system string) quantity {
+	if system == unitsImperial {
+		return quantity{m * feetPerMetre, "ft"}
+	}
+	return quantity{m, "m"}
+}
+
+// convertSpeed converts m/s to pace in min/km (metric) or mph (imperial).
+// Returns nil when not moving, where pace is undefined.
+func convertSpeed(mps float64, system string) *quantity {
+	if mps <= 0 {
+		return nil
+	}
+	if system == unitsImperial {
+		return &quantity{mps * mphPerMPS, "mph"}
+	}
+	return &quantity{1000 / mps / 60, "min/km"}
+}
+
+// convertWorkout returns the unit-converted view of a workout. Elevation is
+// stored as reported by Health Auto Export, which is metres. Speed uses the
+// moving time when the workout has one, so pauses don't slow the pace.
+func convertWorkout(w models.WorkoutRow, system string) workoutUnits {
+	u := workoutUnits{System: system}
+	if w.Distance != nil && *w.Distance > 0 {
+		km := distanceToKm(*w.Distance, w.DistanceUnits)
+		d := convertDistance(km, system)
+		u.Distance = &d
+		if sec := storage.PaceDurationSec(w); sec > 0 {
+			u.Speed = convertSpeed(km*1000/sec, system)
+		}
+	}
+	if w.ElevationUp != nil {
+		e := convertElevation(*w.ElevationUp, system)
+		u.ElevationUp = &e
+	}
+	if w.ElevationDown != nil {
+		e := convertElevation(*w.ElevationDown, system)
+		u.ElevationDown = &e
+	}
+	return u
+}
+
+// convertRoute returns the unit-converted altitude and speed of each route
+// point.
+func convertRoute(route []models.WorkoutRouteRow, system string) []routePointUnits {
+	out := make([]routePointUnits, len(route))
+	for i, p := range route {
+		if p.Altitude != nil {
+			a := convertElevation(*p.Altitude, system)
+			out[i].Altitude = &a
+		}
+		if p.Speed != nil {
+			out[i].Speed = convertSpeed(*p.Speed, system)
+		}
+	}
+	return out
+}
+
+// workoutWithUnits is a workout list entry with its converted units.
+type workoutWithUnits struct {
+	models.WorkoutRow
+	Units workoutUnits `json:"units"`
+}
+
+// workoutDetailWithUnits is a workout detail with converted units for the
+// workout and each route point.
+type workoutDetailWithUnits struct {
+	*storage.WorkoutDetail
+	Units      workoutUnits      `json:"units"`
+	RouteUnits []routePointUnits `json:"route_units"`
+}
+
+// withUnits adds converted units to a workout list.
+func withUnits(workouts []models.WorkoutRow, system string) []workoutWithUnits {
+	out := make([]workoutWithUnits, len(workouts))
+	for i, w := range workouts {
+		out[i] = workoutWithUnits{WorkoutRow: w, Units: convertWorkout(w, system)}
+	}
+	return out
+}
+
+// detailWithUnits adds converted units to a workout detail.
+func detailWithUnits(d *storage.WorkoutDetail, system string) workoutDetailWithUnits {
+	return workoutDetailWithUnits{
+		WorkoutDetail: d,
+		Units:         convertWorkout(d.WorkoutRow, system),
+		RouteUnits:    convertRoute(d.RouteData, system),
+	}
+}
@@ -0,0 +1,125 @@
+package server
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/claude/freereps/internal/models"
+	"github.com/claude/freereps/internal/storage"
+)
+
+func near(a, b float64) bool { return math.Abs(a-b) < 1e-3 }
+
+// TestConvertWorkout verifies a 10 km run in 50 minutes reads as 10 km at
+// 5:00 min/km in metric and 6.214 mi at 7.456 mph in imperial, whatever unit
+// the distance was stored in.
+func TestConvertWorkout(t *testing.T) {
+	dist, up := 10000.0, 100.0
+	w := models.WorkoutRow{Distance: &dist, DistanceUnits: "m", DurationSec: 3000, ElevationUp: &up}
+
+	m := convertWorkout(w, unitsMetric)
+	if m.Distance.Unit != "km" || !near(m.Distance.Value, 10) {
+		t.Errorf("metric distance = %+v", m.Distance)
+	}
+	if m.Speed.Unit != "min/km" || !near(m.Speed.Value, 5) {
+		t.Errorf("metric pace = %+v", m.Speed)
+	}
+	if m.ElevationUp.Unit != "m" || m.ElevationUp.Value != 100 || m.ElevationDown != nil {
+		t.Errorf("metric elevation = %+v / %+v", m.ElevationUp, m.ElevationDown)
+	}
+
+	i := convertWorkout(w, unitsImperial)
+	if i.Distance.Unit != "mi" || !near(i.Distance.Value, 6.2137) {
+		t.Errorf("imperial distance = %+v", i.Distance)
+	}
+	if i.Speed.Unit != "mph" || !near(i.Speed.Value, 7.4564) {
+		t.Errorf("imperial speed = %+v", i.Speed)
+	}
+	if i.ElevationUp.Unit != "ft" || !near(i.ElevationUp.Value, 328.084) {
+		t.Errorf("imperial elevation = %+v", i.ElevationUp)
+	}
+
+	if u := convertWorkout(models.WorkoutRow{DurationSec: 1800}, unitsMetric); u.Distance != nil || u.Speed != nil {
+		t.Errorf("strength workout = %+v, want no distance or speed", u)
+	}
+}
+
+// TestConvertWorkoutMovingTime verifies pace is computed from moving time when
+// the workout has one: 10 km in 45 moving minutes is 4:30 min/km even though
+// the elapsed time was 50 minutes.
+func TestConvertWorkoutMovingTime(t *testing.T) {
+	dist, moving := 10.0, 2700.0
+	w := models.WorkoutRow{Distance: &dist, DistanceUnits: "km", DurationSec: 3000, MovingTimeSec: &moving}
+	if m := convertWorkout(w, unitsMetric); !near(m.Speed.Value, 4.5) {
+		t.Errorf("metric pace = %+v, want 4.5 min/km", m.Speed)
+	}
+}
+
+// TestDistanceToKm verifies each stored distance unit converts to km.
+func TestDistanceToKm(t *testing.T) {
+	for _, tt := range []struct {
+		d     float64
+		units string
+		want  float64
+	}{
+		{5, "km", 5},
+		{5, "", 5},
+		{3.10686, "mi", 5},
+		{5000, "m", 5},
+		{5468.066, "yd", 5},
+	} {
+		if got := distanceToKm(tt.d, tt.units); !near(got, tt.want) {
+			t.Errorf("distanceToKm(%v, %q) = %v, want %v", tt.d, tt.units, got, tt.want)
+		}
+	}
+}
+
+// TestConvertRoute verifies route points get converted altitude and speed,
+// with no pace while standing still.
+func TestConvertRoute(t *testing.T) {
+	alt, fast, still := 500.0, 4.0, 0.0
+	got := convertRoute([]models.WorkoutRouteRow{
+		{Altitude: &alt, Speed: &fast},
+		{Speed: &still},
+	}, unitsMetric)
+	if len(got) != 2 || got[0].Altitude.Value != 500 || !near(got[0].Speed.Value, 4.1667) {
+		t.Errorf("moving point = %+v", got[0])
+	}
+	if got[1].Speed != nil || got[1].Altitude != nil {
+		t.Errorf("still point = %+v, want empty", got[1])
+	}
+}
+
+// TestParseUnitSystem verifies an unset parameter is left to the profile,
+// both systems are accepted, and anything else is rejected.
+func TestParseUnitSystem(t *testing.T) {
+	for q, want := range map[string]string{"": "", "?units=metric": unitsMetric, "?units=imperial": unitsImperial} {
+		got, err := parseUnitSystem(httptest.NewRequest(http.MethodGet, "/api/v1/workouts"+q, nil))
+		if err != nil || got != want {
+			t.Errorf("%q: got %q, %v; want %q", q, got, err, want)
+		}
+	}
+	if _, err := parseUnitSystem(httptest.NewRequest(http.MethodGet, "/api/v1/workouts?units=furlongs", nil)); err == nil {
+		t.Error("expected error for unknown units")
+	}
+}
+
+// TestDetailWithUnitsJSON verifies the converted units sit alongside the
+// unchanged stored fields in the workout detail response.
+func TestDetailWithUnitsJSON(t *testing.T) {
+	dist := 5.0
+	d := &storage.WorkoutDetail{WorkoutRow: models.WorkoutRow{Name: "Running", Distance: &dist, DistanceUnits: "km", DurationSec: 1500}}
+	b, err := json.Marshal(detailWithUnits(d, unitsImperial))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"Distance":5`, `"DistanceUnits":"km"`, `"units":{"system":"imperial"`, `"unit":"mi"`, `"route_units":[]`} {
+		if !strings.Contains(string(b), want) {
+			t.Errorf("response missing %s: %s", want, b)
+		}
+	}
+}
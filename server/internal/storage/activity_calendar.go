@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// maxCalendarDays bounds the range GetActivityCalendar will expand into rows.
+const maxCalendarDays = 3660
+
+// CalendarDay is one cell of the activity calendar. Days without workouts
+// are present with zero values.
+type CalendarDay struct {
+	Date          string  `json:"date"`
+	Workouts      int     `json:"workouts"`
+	ActiveMinutes float64 `json:"active_minutes"`
+	Calories      float64 `json:"calories"`
+}
+
+// activityCalendarSQL expands every UTC day from $1 to $2 (inclusive) and
+// left-joins workouts so empty days come back as zero rows, not gaps.
+var activityCalendarSQL = fmt.Sprintf(
+	`SELECT d.day,
+	        COUNT(w.id)::int,
+	        COALESCE(SUM(w.duration_sec), 0) / 60.0,
+	        COALESCE(SUM(%s), 0)
+	 FROM generate_series(($1::timestamptz AT TIME ZONE 'UTC')::date,
+	                      ($2::timestamptz AT TIME ZONE 'UTC')::date,
+	                      interval '1 day') AS d(day)
+	 LEFT JOIN workouts w
+	   ON w.user_id = $3
+	  AND w.start_time >= (d.day AT TIME ZONE 'UTC')
+	  AND w.start_time < ((d.day + interval '1 day') AT TIME ZONE 'UTC')
+	 GROUP BY d.day
+	 ORDER BY d.day`, workoutEnergyKcalSQL)
+
+// ValidateCalendarRange rejects ranges that are inverted or too long to expand.
+func ValidateCalendarRange(start, end time.Time) error {
+	if end.Before(start) {
+		return fmt.Errorf("end is before start")
+	}
+	if end.Sub(start) > maxCalendarDays*24*time.Hour {
+		return fmt.Errorf("range exceeds %d days", maxCalendarDays)
+	}
+	return nil
+}
+
+// GetActivityCalendar returns per-day workout count, active minutes, and
+// active calories (kcal) for every UTC day between start and end, inclusive.
+func (db *DB) GetActivityCalendar(ctx context.Context, start, end time.Time, userID int) ([]CalendarDay, error) {
+	if err := ValidateCalendarRange(start, end); err != nil {
+		return nil, err
+	}
+	rows, err := db.Pool.Query(ctx, activityCalendarSQL, start, end, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying activity calendar: %w", err)
+	}
+	defer rows.Close()
+
+	var result []CalendarDay
+	for rows.Next() {
+		var day time.Time
+		var c CalendarDay
+		if err := rows.Scan(&day, &c.Workouts, &c.ActiveMinutes, &c.Calories); err != nil {
+			return nil, fmt.Errorf("scanning activity calendar: %w", err)
+		}
+		c.Date = day.Format("2006-01-02")
+		result = append(result, c)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestActivityCalendarSQL verifies days come from generate_series with a LEFT
+// JOIN, so days without workouts are returned as zero rows instead of missing,
+// and calories are normalized to kcal.
+func TestActivityCalendarSQL(t *testing.T) {
+	for _, want := range []string{"generate_series(", "LEFT JOIN workouts w", "COUNT(w.id)", workoutEnergyKcalSQL} {
+		if !strings.Contains(activityCalendarSQL, want) {
+			t.Errorf("calendar SQL missing %q:\n%s", want, activityCalendarSQL)
+		}
+	}
+}
+
+// TestValidateCalendarRange verifies inverted and oversized ranges are rejected
+// before generate_series can expand them.
+func TestValidateCalendarRange(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := ValidateCalendarRange(start, start.AddDate(1, 0, 0)); err != nil {
+		t.Errorf("one year: unexpected error %v", err)
+	}
+	if err := ValidateCalendarRange(start, start.AddDate(0, 0, -1)); err == nil {
+		t.Error("expected error for end before start")
+	}
+	if err := ValidateCalendarRange(start, start.AddDate(20, 0, 0)); err == nil {
+		t.Error("expected error for 20-year range")
+	}
+}
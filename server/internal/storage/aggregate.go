@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnknownAggregate is returned for an aggregate function not in
+// aggregateExprs.
+var ErrUnknownAggregate = errors.New("fn must be one of avg, sum, min, max, count")
+
+// ErrDerivedAggregate is returned when GetAggregate is asked for a derived
+// metric, which only exists as computed time-series buckets.
+var ErrDerivedAggregate = errors.New("derived metrics can't be aggregated; use /api/v1/timeseries")
+
+// aggregateExprs maps the aggregate functions GetAggregate accepts to their
+// SQL over a metric row. min/max use the per-sample extremes of
+// min/avg/max-shaped metrics such as heart_rate.
+var aggregateExprs = map[string]string{
+	"avg":   "AVG(COALESCE(qty, avg_val))",
+	"sum":   "SUM(COALESCE(qty, avg_val))",
+	"min":   "MIN(COALESCE(qty, min_val))",
+	"max":   "MAX(COALESCE(qty, max_val))",
+	"count": "COUNT(*)",
+}
+
+// ValidAggregate reports whether fn is an aggregate function GetAggregate
+// accepts.
+func ValidAggregate(fn string) bool {
+	_, ok := aggregateExprs[fn]
+	return ok
+}
+
+// AggregatePoint is one bucket of a GetAggregate result.
+type AggregatePoint struct {
+	Time  time.Time `json:"time"`
+	Value *float64  `json:"value"`
+}
+
+// rollupAggregateExpr is fn over rollupSamplesSQL's combined CTE, where each
+// row stands for n samples with mean v (their sum for cumulative metrics).
+func rollupAggregateExpr(fn string, cumulative bool) string {
+	total := "SUM(v * n)"
+	if cumulative {
+		total = "SUM(v)"
+	}
+	switch fn {
+	case "avg":
+		return total + " / NULLIF(SUM(n), 0)"
+	case "sum":
+		return total
+	case "min":
+		return "MIN(lo)"
+	case "max":
+		return "MAX(hi)"
+	default:
+		return "SUM(n)"
+	}
+}
+
+// aggregateSQL builds the grouped query for fn. Parameters: $1 bucket
+// interval, $2 metric, $3 start, $4 end, $5 user_id, and with withRollup $6
+// the rollup watermark, before which rollups stand in for raw rows as in
+// timeSeriesSQL. fn is checked against aggregateExprs, so only fixed SQL is
+// interpolated.
+func aggregateSQL(priorities []string, fn string, cumulative, withRollup bool, loc *time.Location, dayStartHour int) (string, error) {
+	expr, ok := aggregateExprs[fn]
+	if !ok {
+		return "", ErrUnknownAggregate
+	}
+	cte := dedupCTE(priorities, "$2", "$3", "$4", "$5")
+	bucket := timeBucketSQL("$1::interval", "time", loc, dayStartHour)
+	if !withRollup {
+		return fmt.Sprintf(
+			`%sSELECT %s AS bucket, %s::double precision AS value
+			 FROM deduped WHERE rn = 1
+			 GROUP BY bucket
+			 ORDER BY bucket ASC`, cte, bucket, expr), nil
+	}
+	return fmt.Sprintf(
+		`%s, %s
+		SELECT %s AS bucket, (%s)::double precision AS value
+		FROM combined
+		GROUP BY bucket
+		ORDER BY bucket ASC`, cte, rollupSamplesSQL("", priorities, "$2", "$3", "$4", "$5", "$6"),
+		bucket, rollupAggregateExpr(fn, cumulative)), nil
+}
+
+// GetAggregate returns fn (see aggregateExprs) of a metric's deduplicated
+// values per bucket, a PostgreSQL interval like '1 day'. Buckets without data
+// are omitted. Like GetTimeSeries, ranges reaching back past the metric's
+// retention watermark read its rollups there.
+func (db *DB) GetAggregate(ctx context.Context, metricName, fn string, start, end time.Time, bucket string, userID int) ([]AggregatePoint, error) {
+	if db.derived[metricName] != nil {
+		return nil, ErrDerivedAggregate
+	}
+	priorities := db.ResolveSourcePriorityForMetric(ctx, userID, metricName)
+	watermark, _, err := db.rollupWatermark(ctx, metricName)
+	if err != nil {
+		return nil, err
+	}
+	args := []any{bucket, metricName, start, end, userID}
+	withRollup := watermark != nil && start.Before(*watermark)
+	if withRollup {
+		args = append(args, *watermark)
+	}
+	query, err := aggregateSQL(priorities, fn, cumulativeMetrics[metricName], withRollup, db.sleepLoc, db.dayStartHour)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying aggregate: %w", err)
+	}
+	defer rows.Close()
+
+	points := []AggregatePoint{}
+	for rows.Next() {
+		var p AggregatePoint
+		if err := rows.Scan(&p.Time, &p.Value); err != nil {
+			return nil, fmt.Errorf("scanning aggregate: %w", err)
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestAggregateSQL verifies each aggregate function selects its expression
+// over the deduplicated rows in day-start-aligned buckets.
+func TestAggregateSQL(t *testing.T) {
+	want := map[string]string{
+		"avg":   "AVG(COALESCE(qty, avg_val))",
+		"sum":   "SUM(COALESCE(qty, avg_val))",
+		"min":   "MIN(COALESCE(qty, min_val))",
+		"max":   "MAX(COALESCE(qty, max_val))",
+		"count": "COUNT(*)",
+	}
+	for fn, expr := range want {
+		query, err := aggregateSQL([]string{"Apple Watch"}, fn, false, false, nil, 4)
+		if err != nil {
+			t.Fatalf("%s: %v", fn, err)
+		}
+		for _, check := range []string{
+			expr + "::double precision AS value",
+			"WITH deduped AS",
+			"FROM deduped WHERE rn = 1",
+			"metric_name = $2",
+			"user_id = $5",
+			"interval '4 hours'",
+		} {
+			if !strings.Contains(query, check) {
+				t.Errorf("%s: query missing %q:\n%s", fn, check, query)
+			}
+		}
+	}
+}
+
+// TestAggregateSQLWithRollup verifies ranges before the rollup watermark
+// read rollups, weighting means by sample count and summing cumulative
+// rollups as they are.
+func TestAggregateSQLWithRollup(t *testing.T) {
+	for _, tt := range []struct {
+		fn         string
+		cumulative bool
+		want       string
+	}{
+		{"avg", false, "(SUM(v * n) / NULLIF(SUM(n), 0))::double precision AS value"},
+		{"sum", true, "(SUM(v))::double precision AS value"},
+		{"sum", false, "(SUM(v * n))::double precision AS value"},
+		{"min", false, "(MIN(lo))"},
+		{"max", false, "(MAX(hi))"},
+		{"count", false, "(SUM(n))"},
+	} {
+		query, err := aggregateSQL(nil, tt.fn, tt.cumulative, true, nil, 0)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.fn, err)
+		}
+		for _, check := range []string{tt.want, "FROM health_metric_rollups", "FROM combined", "LEAST($4, $6)"} {
+			if !strings.Contains(query, check) {
+				t.Errorf("%s cumulative=%v: query missing %q:\n%s", tt.fn, tt.cumulative, check, query)
+			}
+		}
+	}
+}
+
+// TestAggregateSQLRejectsUnknownFn verifies functions outside the allowlist,
+// including SQL fragments, are refused instead of interpolated.
+func TestAggregateSQLRejectsUnknownFn(t *testing.T) {
+	for _, fn := range []string{"", "median", "AVG", "avg(qty)); DROP TABLE health_metrics; --"} {
+		if _, err := aggregateSQL(nil, fn, false, true, nil, 0); !errors.Is(err, ErrUnknownAggregate) {
+			t.Errorf("%q: err = %v, want ErrUnknownAggregate", fn, err)
+		}
+	}
+}
+
+// TestValidAggregate verifies ValidAggregate matches the aggregateSQL
+// allowlist.
+func TestValidAggregate(t *testing.T) {
+	for _, fn := range []string{"avg", "sum", "min", "max", "count"} {
+		if !ValidAggregate(fn) {
+			t.Errorf("ValidAggregate(%q) = false, want true", fn)
+		}
+	}
+	for _, fn := range []string{"", "median", "Max"} {
+		if ValidAggregate(fn) {
+			t.Errorf("ValidAggregate(%q) = true, want false", fn)
+		}
+	}
+}
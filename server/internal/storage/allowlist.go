@@ -2,12 +2,56 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
+// ErrMetricNotAllowlisted is returned when editing a metric that has no
+// allowlist row.
+var ErrMetricNotAllowlisted = errors.New("metric not in allowlist")
+
 // IsMetricAllowed checks if a metric name is in the allowlist and enabled.
+// The full allowlist is cached for the configured TTL so a multi-metric ingest
+// payload issues one query instead of one per metric.
 func (db *DB) IsMetricAllowed(ctx context.Context, metricName string) (bool, error) {
+	db.allowlistMu.RLock()
+	if db.allowlistCache != nil && time.Since(db.allowlistFetchedAt) < db.allowlistCacheTTL {
+		enabled := db.allowlistCache[metricName]
+		db.allowlistMu.RUnlock()
+		return enabled, nil
+	}
+	ttl := db.allowlistCacheTTL
+	gen := db.allowlistGen
+	db.allowlistMu.RUnlock()
+
+	if ttl <= 0 {
+		return db.isMetricAllowedFromDB(ctx, metricName)
+	}
+
+	allowlist, err := db.loadAllowlist(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	db.storeAllowlist(gen, allowlist)
+	return allowlist[metricName], nil
+}
+
+// storeAllowlist caches allowlist unless InvalidateAllowlist ran since gen was
+// read; the loaded map may predate that change, so the next lookup reloads.
+func (db *DB) storeAllowlist(gen uint64, allowlist map[string]bool) {
+	db.allowlistMu.Lock()
+	if db.allowlistGen == gen {
+		db.allowlistCache = allowlist
+		db.allowlistFetchedAt = time.Now()
+	}
+	db.allowlistMu.Unlock()
+}
+
+// isMetricAllowedFromDB looks up a single allowlist entry (uncached).
+func (db *DB) isMetricAllowedFromDB(ctx context.Context, metricName string) (bool, error) {
 	var enabled bool
 	err := db.Pool.QueryRow(ctx,
 		`SELECT enabled FROM metric_allowlist WHERE metric_name = $1`,
@@ -21,6 +65,96 @@ func (db *DB) IsMetricAllowed(ctx context.Context, metricName string) (bool, err
 	return enabled, nil
 }
 
+// loadAllowlist fetches every allowlist entry as metric_name → enabled.
+func (db *DB) loadAllowlist(ctx context.Context) (map[string]bool, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT metric_name, enabled FROM metric_allowlist`)
+	if err != nil {
+		return nil, fmt.Errorf("checking metric allowlist: %w", err)
+	}
+	defer rows.Close()
+
+	allowlist := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var enabled bool
+		if err := rows.Scan(&name, &enabled); err != nil {
+			return nil, fmt.Errorf("scanning metric allowlist: %w", err)
+		}
+		allowlist[name] = enabled
+	}
+	return allowlist, rows.Err()
+}
+
+// UpdateMetricAllowed enables or disables a metric in the allowlist and
+// invalidates the allowlist and available-metrics caches.
+func (db *DB) UpdateMetricAllowed(ctx context.Context, metricName string, enabled bool) error {
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE metric_allowlist SET enabled = $2 WHERE metric_name = $1`,
+		metricName, enabled)
+	if err != nil {
+		return fmt.Errorf("updating metric allowlist: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMetricNotAllowlisted
+	}
+	db.InvalidateAllowlist()
+	db.InvalidateAllAvailableMetrics()
+	return nil
+}
+
+// AllowlistSeed is one allowlist row to create at startup.
+type AllowlistSeed struct {
+	MetricName   string
+	Category     string
+	Enabled      bool
+	DisplayLabel string
+	DisplayUnit  string
+	IsCumulative bool
+}
+
+// allowlistSeedQuery builds a single multi-row INSERT for seeds that skips
+// metrics already in the allowlist.
+func allowlistSeedQuery(seeds []AllowlistSeed) (string, []any) {
+	values := make([]string, len(seeds))
+	args := make([]any, 0, len(seeds)*6)
+	for i, sd := range seeds {
+		n := i * 6
+		values[i] = fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6)
+		args = append(args, sd.MetricName, sd.Category, sd.Enabled, sd.DisplayLabel, sd.DisplayUnit, sd.IsCumulative)
+	}
+	query := `INSERT INTO metric_allowlist (metric_name, category, enabled, display_label, display_unit, is_cumulative)
+		 VALUES ` + strings.Join(values, ", ") + `
+		 ON CONFLICT (metric_name) DO NOTHING`
+	return query, args
+}
+
+// SeedAllowlist adds configured allowlist entries for metrics not yet in the
+// allowlist. It is idempotent, so it runs on every startup; rows that already
+// exist, whether from migrations, earlier seeds or the UI, keep their
+// category, toggle and display settings.
+func (db *DB) SeedAllowlist(ctx context.Context, seeds []AllowlistSeed) error {
+	if len(seeds) == 0 {
+		return nil
+	}
+	query, args := allowlistSeedQuery(seeds)
+	if _, err := db.Pool.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("seeding metric allowlist: %w", err)
+	}
+	db.InvalidateAllowlist()
+	db.InvalidateAllAvailableMetrics()
+	return nil
+}
+
+// InvalidateAllowlist clears the cached allowlist and metric aliases so the
+// next lookup re-reads them.
+func (db *DB) InvalidateAllowlist() {
+	db.allowlistMu.Lock()
+	db.allowlistCache = nil
+	db.aliasCache = nil
+	db.allowlistGen++
+	db.allowlistMu.Unlock()
+}
+
 // AllowedMetric represents an entry in the metric allowlist with display metadata.
 type AllowedMetric struct {
 	MetricName        string  `json:"metric_name"`
@@ -33,6 +167,39 @@ type AllowedMetric struct {
 	Visible           bool    `json:"visible"`
 }
 
+// metricDisplayLabel returns label, or when it is empty a title-cased form
+// of the metric name ("heart_rate_variability" → "Heart Rate Variability"),
+// matching the web UI's fallback so API and MCP clients see the same names.
+func metricDisplayLabel(metricName, label string) string {
+	if label != "" {
+		return label
+	}
+	words := strings.Fields(strings.ReplaceAll(strings.TrimPrefix(metricName, "oura_"), "_", " "))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// UpdateMetricDisplay edits a metric's display label and/or unit. Nil fields
+// are left unchanged; an empty label reverts to the name-derived default.
+func (db *DB) UpdateMetricDisplay(ctx context.Context, metricName string, label, unit *string) error {
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE metric_allowlist
+		 SET display_label = COALESCE($2, display_label),
+		     display_unit = COALESCE($3, display_unit)
+		 WHERE metric_name = $1`,
+		metricName, label, unit)
+	if err != nil {
+		return fmt.Errorf("updating metric display: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMetricNotAllowlisted
+	}
+	db.InvalidateAllAvailableMetrics()
+	return nil
+}
+
 // defaultVisibleMetrics is the starter set for users who haven't customized visibility.
 var defaultVisibleMetrics = map[string]bool{
 	"heart_rate":              true,
@@ -72,6 +239,7 @@ func (db *DB) GetAllowedMetrics(ctx context.Context) ([]AllowedMetric, error) {
 			&m.DisplayLabel, &m.DisplayUnit, &m.IsCumulative, &m.DisplayMultiplier); err != nil {
 			return nil, fmt.Errorf("scanning allowlist: %w", err)
 		}
+		m.DisplayLabel = metricDisplayLabel(m.MetricName, m.DisplayLabel)
 		result = append(result, m)
 	}
 	return result, rows.Err()
@@ -137,6 +305,7 @@ func (db *DB) getAvailableMetricsFromDB(ctx context.Context, userID int) ([]Allo
 			&visOverride); err != nil {
 			return nil, fmt.Errorf("scanning available metric: %w", err)
 		}
+		m.DisplayLabel = metricDisplayLabel(m.MetricName, m.DisplayLabel)
 		if visOverride != nil {
 			m.Visible = *visOverride
 		} else {
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 )
@@ -199,3 +200,130 @@ func TestAvailableMetricsCacheBoundedSize(t *testing.T) {
 		t.Fatalf("expected 'new', got %q", got[0].MetricName)
 	}
 }
+
+// TestIsMetricAllowedCacheHit verifies that allowlist lookups within the TTL are
+// served from memory, so ingest doesn't query once per metric.
+func TestIsMetricAllowedCacheHit(t *testing.T) {
+	db := &DB{allowlistCacheTTL: time.Minute}
+	db.allowlistCache = map[string]bool{"heart_rate": true, "disabled_metric": false}
+	db.allowlistFetchedAt = time.Now()
+
+	tests := map[string]bool{"heart_rate": true, "disabled_metric": false, "unknown": false}
+	for name, want := range tests {
+		got, err := db.IsMetricAllowed(context.Background(), name)
+		if err != nil {
+			t.Fatalf("IsMetricAllowed(%q): %v", name, err)
+		}
+		if got != want {
+			t.Errorf("IsMetricAllowed(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+// TestInvalidateAllowlistWithinTTL verifies that toggling a metric drops the
+// cached allowlist immediately rather than waiting for the TTL to expire.
+func TestInvalidateAllowlistWithinTTL(t *testing.T) {
+	db := &DB{allowlistCacheTTL: time.Hour}
+	db.allowlistCache = map[string]bool{"heart_rate": true}
+	db.allowlistFetchedAt = time.Now()
+
+	db.InvalidateAllowlist()
+
+	db.allowlistMu.RLock()
+	defer db.allowlistMu.RUnlock()
+	if db.allowlistCache != nil {
+		t.Fatal("expected allowlist cache to be cleared")
+	}
+}
+
+// TestStoreAllowlistAfterInvalidate verifies a load that began before an
+// invalidation doesn't repopulate the cache with its now-stale map.
+func TestStoreAllowlistAfterInvalidate(t *testing.T) {
+	db := &DB{allowlistCacheTTL: time.Hour}
+	gen := db.allowlistGen
+
+	db.InvalidateAllowlist()
+	db.storeAllowlist(gen, map[string]bool{"heart_rate": true})
+	if db.allowlistCache != nil {
+		t.Fatal("stale load was cached after invalidation")
+	}
+
+	db.storeAllowlist(db.allowlistGen, map[string]bool{"heart_rate": true})
+	if !db.allowlistCache["heart_rate"] {
+		t.Error("current load was not cached")
+	}
+}
+
+// TestSetAllowlistCacheTTL verifies that reconfiguring the TTL discards any
+// cache filled under the previous setting.
+func TestSetAllowlistCacheTTL(t *testing.T) {
+	db := &DB{}
+	db.allowlistCache = map[string]bool{"heart_rate": true}
+
+	db.SetAllowlistCacheTTL(30 * time.Second)
+
+	if db.allowlistCacheTTL != 30*time.Second {
+		t.Errorf("ttl = %v, want 30s", db.allowlistCacheTTL)
+	}
+	if db.allowlistCache != nil {
+		t.Error("expected cache to be cleared on TTL change")
+	}
+}
+
+// TestAllowlistSeedQuery verifies seeds become one parameterized INSERT that
+// adds new metrics and leaves existing rows, including their category and
+// is_cumulative, untouched on every restart.
+func TestAllowlistSeedQuery(t *testing.T) {
+	query, args := allowlistSeedQuery([]AllowlistSeed{
+		{MetricName: "time_in_daylight", Category: "activity", Enabled: true, DisplayUnit: "min", IsCumulative: true},
+		{MetricName: "cycling_cadence", Category: "fitness"},
+	})
+	for _, want := range []string{
+		"($1, $2, $3, $4, $5, $6), ($7, $8, $9, $10, $11, $12)",
+		"ON CONFLICT (metric_name) DO NOTHING",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("seed query missing %q:\n%s", want, query)
+		}
+	}
+	if len(args) != 12 || args[0] != "time_in_daylight" || args[2] != true || args[8] != false {
+		t.Errorf("args = %v", args)
+	}
+	if strings.Contains(query, "DO UPDATE") {
+		t.Errorf("seeding must not overwrite existing rows:\n%s", query)
+	}
+	if strings.Contains(query, "time_in_daylight") {
+		t.Error("metric names must be bound, not interpolated")
+	}
+}
+
+// TestMetricDisplayLabel verifies configured labels win and unlabeled
+// metrics get the same title-cased fallback the web UI uses.
+func TestMetricDisplayLabel(t *testing.T) {
+	tests := []struct{ name, label, want string }{
+		{"heart_rate_variability", "", "Heart Rate Variability"},
+		{"heart_rate_variability", "HRV", "HRV"},
+		{"oura_readiness_score", "", "Readiness Score"},
+		{"vo2_max", "", "Vo2 Max"},
+	}
+	for _, tt := range tests {
+		if got := metricDisplayLabel(tt.name, tt.label); got != tt.want {
+			t.Errorf("metricDisplayLabel(%q, %q) = %q, want %q", tt.name, tt.label, got, tt.want)
+		}
+	}
+}
+
+// TestCanonicalMetricName verifies aliases resolve to their canonical metric
+// and unknown names pass through unchanged.
+func TestCanonicalMetricName(t *testing.T) {
+	aliases := map[string]string{"vo2max": "vo2_max"}
+	if got := CanonicalMetricName(aliases, "vo2max"); got != "vo2_max" {
+		t.Errorf("alias = %q, want vo2_max", got)
+	}
+	if got := CanonicalMetricName(aliases, "step_count"); got != "step_count" {
+		t.Errorf("unaliased = %q, want step_count", got)
+	}
+	if got := CanonicalMetricName(nil, "vo2max"); got != "vo2max" {
+		t.Errorf("nil aliases = %q, want vo2max", got)
+	}
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+	"github.com/google/uuid"
+)
+
+// DefaultBestEffortDistances are the target distances (metres) used when the
+// caller doesn't ask for specific ones.
+var DefaultBestEffortDistances = []float64{1000, 5000, 10000, 21097.5, 42195}
+
+// BestEffort is the fastest time any single workout covered a target distance.
+// Results are kept per workout type so cycling never beats a running PR.
+type BestEffort struct {
+	WorkoutName  string    `json:"workout_name"`
+	DistanceM    float64   `json:"distance_m"`
+	DurationSec  float64   `json:"duration_sec"`
+	PacePerKmSec float64   `json:"pace_per_km_sec"`
+	WorkoutID    uuid.UUID `json:"workout_id"`
+	Date         time.Time `json:"date"`
+}
+
+const earthRadiusM = 6371008.8
+
+// haversineM returns the great-circle distance between two points in metres.
+func haversineM(lat1, lon1, lat2, lon2 float64) float64 {
+	rad1, rad2 := lat1*math.Pi/180, lat2*math.Pi/180
+	dLat := (lat2 - lat1) * math.Pi / 180
+	dLon := (lon2 - lon1) * math.Pi / 180
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(rad1)*math.Cos(rad2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusM * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// routeBestEfforts returns, for each target distance, the fastest time (in
+// seconds) to cover it anywhere along the route, or 0 if the route is shorter.
+// points must be in time order. For each end point a trailing pointer marks the
+// last point at least the target distance behind; the start time is then
+// interpolated so every effort measures exactly the target distance.
+func routeBestEfforts(points []models.WorkoutRouteRow, distances []float64) []float64 {
+	best := make([]float64, len(distances))
+	if len(points) < 2 {
+		return best
+	}
+
+	cum := make([]float64, len(points))
+	for i := 1; i < len(points); i++ {
+		cum[i] = cum[i-1] + haversineM(points[i-1].Latitude, points[i-1].Longitude, points[i].Latitude, points[i].Longitude)
+	}
+	secs := func(i int) float64 { return points[i].Time.Sub(points[0].Time).Seconds() }
+
+	for di, d := range distances {
+		if d <= 0 || cum[len(cum)-1] < d {
+			continue
+		}
+		i := 0
+		for j := 1; j < len(points); j++ {
+			if cum[j] < d {
+				continue
+			}
+			for i+1 < j && cum[j]-cum[i+1] >= d {
+				i++
+			}
+			// Interpolate where between points i and i+1 the effort starts.
+			startDist := cum[j] - d
+			startT := secs(i)
+			if seg := cum[i+1] - cum[i]; seg > 0 {
+				startT += (secs(i+1) - secs(i)) * (startDist - cum[i]) / seg
+			}
+			if elapsed := secs(j) - startT; elapsed > 0 && (best[di] == 0 || elapsed < best[di]) {
+				best[di] = elapsed
+			}
+		}
+	}
+	return best
+}
+
+// ComputeBestEfforts scans every GPS route the user has recorded and returns
+// the all-time fastest effort per workout type and target distance (metres).
+func (db *DB) ComputeBestEfforts(ctx context.Context, userID int, distances []float64) ([]BestEffort, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT w.id, w.name, w.start_time, r.time, r.latitude, r.longitude
+		 FROM workout_routes r
+		 JOIN workouts w ON w.id = r.workout_id AND w.user_id = r.user_id
+		 WHERE r.user_id = $1
+		 ORDER BY w.id, r.time`,
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying workout routes: %w", err)
+	}
+	defer rows.Close()
+
+	type key struct {
+		name string
+		di   int
+	}
+	bests := make(map[key]BestEffort)
+
+	var (
+		curID    uuid.UUID
+		curName  string
+		curStart time.Time
+		points   []models.WorkoutRouteRow
+	)
+	flush := func() {
+		for di, sec := range routeBestEfforts(points, distances) {
+			if sec == 0 {
+				continue
+			}
+			k := key{curName, di}
+			if prev, ok := bests[k]; ok && prev.DurationSec <= sec {
+				continue
+			}
+			bests[k] = BestEffort{
+				WorkoutName:  curName,
+				DistanceM:    distances[di],
+				DurationSec:  sec,
+				PacePerKmSec: sec / distances[di] * 1000,
+				WorkoutID:    curID,
+				Date:         curStart,
+			}
+		}
+		points = points[:0]
+	}
+
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		var start time.Time
+		var p models.WorkoutRouteRow
+		if err := rows.Scan(&id, &name, &start, &p.Time, &p.Latitude, &p.Longitude); err != nil {
+			return nil, fmt.Errorf("scanning route point: %w", err)
+		}
+		if id != curID && len(points) > 0 {
+			flush()
+		}
+		curID, curName, curStart = id, name, start
+		points = append(points, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating route points: %w", err)
+	}
+	if len(points) > 0 {
+		flush()
+	}
+
+	result := make([]BestEffort, 0, len(bests))
+	for _, b := range bests {
+		result = append(result, b)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].WorkoutName != result[j].WorkoutName {
+			return result[i].WorkoutName < result[j].WorkoutName
+		}
+		return result[i].DistanceM < result[j].DistanceM
+	})
+	return result, nil
+}
@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// TestHaversineM verifies the distance math against a known reference: one
+// degree of longitude on the equator is ~111.2 km.
+func TestHaversineM(t *testing.T) {
+	if d := haversineM(0, 0, 0, 1); math.Abs(d-111195) > 5 {
+		t.Errorf("1° lon at equator = %.0f m, want ~111195", d)
+	}
+	if d := haversineM(52.5, 13.4, 52.5, 13.4); d != 0 {
+		t.Errorf("same point = %f, want 0", d)
+	}
+}
+
+// TestRouteBestEfforts verifies the sliding window finds the fastest stretch
+// on a synthetic track: 300 slow steps (2s each) then 300 fast steps (1s
+// each), each step ~11 m along the equator.
+func TestRouteBestEfforts(t *testing.T) {
+	const stepDeg = 0.0001
+	step := haversineM(0, 0, 0, stepDeg)
+
+	base := time.Date(2025, 5, 1, 7, 0, 0, 0, time.UTC)
+	var pts []models.WorkoutRouteRow
+	elapsed := 0
+	for i := 0; i <= 600; i++ {
+		pts = append(pts, models.WorkoutRouteRow{
+			Time:      base.Add(time.Duration(elapsed) * time.Second),
+			Longitude: float64(i) * stepDeg,
+		})
+		if i < 300 {
+			elapsed += 2
+		} else {
+			elapsed++
+		}
+	}
+
+	got := routeBestEfforts(pts, []float64{1000, 5000, 100000})
+
+	// 1 km fits entirely in the fast half.
+	if want := 1000 / step; math.Abs(got[0]-want) > 0.01 {
+		t.Errorf("1k = %.2fs, want %.2fs", got[0], want)
+	}
+	// 5 km needs all of the fast half plus the tail of the slow half.
+	fast := 300 * step
+	if want := 300 + 2*(5000-fast)/step; math.Abs(got[1]-want) > 0.01 {
+		t.Errorf("5k = %.2fs, want %.2fs", got[1], want)
+	}
+	// Longer than the route: no effort.
+	if got[2] != 0 {
+		t.Errorf("100k = %v, want 0", got[2])
+	}
+}
+
+// TestRouteBestEffortsShortRoute verifies degenerate routes yield no efforts
+// rather than panicking.
+func TestRouteBestEffortsShortRoute(t *testing.T) {
+	got := routeBestEfforts([]models.WorkoutRouteRow{{Time: time.Now()}}, []float64{1000})
+	if len(got) != 1 || got[0] != 0 {
+		t.Errorf("got %v, want [0]", got)
+	}
+}
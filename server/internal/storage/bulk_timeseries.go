@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MaxBulkMetrics caps how many metrics one GetMultipleTimeSeries call reads.
+const MaxBulkMetrics = 50
+
+// GetMultipleTimeSeries returns GetTimeSeries results for several metrics at
+// once, keyed by metric name. Every requested metric has a key; metrics with
+// no data in the range map to an empty slice. Metrics are read in a single
+// query grouped by metric and bucket, except derived metrics and ones whose
+// range reaches back past their rollup watermark, which fall back to
+// GetTimeSeries.
+func (db *DB) GetMultipleTimeSeries(ctx context.Context, metrics []string, start, end time.Time, bucketSize string, userID int) (map[string][]TimeSeriesPoint, error) {
+	metrics = uniqueMetrics(metrics)
+	out := make(map[string][]TimeSeriesPoint, len(metrics))
+	for _, m := range metrics {
+		out[m] = []TimeSeriesPoint{}
+	}
+	if len(metrics) == 0 {
+		return out, nil
+	}
+
+	rolled, err := db.rolledUpSince(ctx, metrics, start)
+	if err != nil {
+		return nil, err
+	}
+	var bulk []string
+	for _, m := range metrics {
+		if !rolled[m] && db.derived[m] == nil {
+			bulk = append(bulk, m)
+			continue
+		}
+		points, err := db.GetTimeSeries(ctx, m, start, end, bucketSize, userID)
+		if err != nil {
+			return nil, err
+		}
+		if points != nil {
+			out[m] = points
+		}
+	}
+	if len(bulk) == 0 {
+		return out, nil
+	}
+
+	pr := db.NewPriorityResolver(ctx, userID)
+	priorities, groups := groupByPriority(bulk, pr.ForMetric)
+	var cumulative []string
+	for _, m := range bulk {
+		if cumulativeMetrics[m] {
+			cumulative = append(cumulative, m)
+		}
+	}
+	args := []any{bucketSize, bulk, start, end, userID, cumulative}
+	if len(groups) > 1 {
+		for _, g := range groups[:len(groups)-1] {
+			args = append(args, g)
+		}
+	}
+
+	rows, err := db.Pool.Query(ctx, bulkTimeSeriesSQL(priorities, db.sleepLoc, db.dayStartHour), args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying bulk time series: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var p TimeSeriesPoint
+		if err := rows.Scan(&name, &p.Time, &p.Avg, &p.Min, &p.Max, &p.Count); err != nil {
+			return nil, fmt.Errorf("scanning bulk time series: %w", err)
+		}
+		out[name] = append(out[name], p)
+	}
+	return out, rows.Err()
+}
+
+// rolledUpSince reports which metrics have rollups covering data after
+// start, so their time series must include health_metric_rollups.
+func (db *DB) rolledUpSince(ctx context.Context, metrics []string, start time.Time) (map[string]bool, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT metric_name FROM metric_rollup_watermarks
+		 WHERE metric_name = ANY($1) AND covered_until > $2`, metrics, start)
+	if err != nil {
+		return nil, fmt.Errorf("querying rollup watermarks: %w", err)
+	}
+	defer rows.Close()
+	rolled := map[string]bool{}
+	for rows.Next() {
+		var m string
+		if err := rows.Scan(&m); err != nil {
+			return nil, fmt.Errorf("scanning rollup watermark: %w", err)
+		}
+		rolled[m] = true
+	}
+	return rolled, rows.Err()
+}
+
+// uniqueMetrics drops blank and repeated names, keeping first-seen order.
+func uniqueMetrics(metrics []string) []string {
+	seen := make(map[string]bool, len(metrics))
+	out := make([]string, 0, len(metrics))
+	for _, m := range metrics {
+		if m == "" || seen[m] {
+			continue
+		}
+		seen[m] = true
+		out = append(out, m)
+	}
+	return out
+}
+
+// groupByPriority groups metrics sharing a source priority list, in
+// first-seen order, so one query can dedup each group by its own priority.
+func groupByPriority(metrics []string, forMetric func(string) []string) ([][]string, [][]string) {
+	var priorities, groups [][]string
+	index := map[string]int{}
+	for _, m := range metrics {
+		p := forMetric(m)
+		key := strings.Join(p, "\x00")
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			priorities = append(priorities, p)
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], m)
+	}
+	return priorities, groups
+}
+
+// bulkTimeSeriesSQL builds the multi-metric time-series query. Parameters:
+// $1 bucket interval, $2 metric names, $3 start, $4 end, $5 user_id, $6
+// cumulative metric names (summed rather than averaged). With more than one
+// priority group, $7 onward hold each group's metric names except the last,
+// which is the fallback.
+func bulkTimeSeriesSQL(priorities [][]string, loc *time.Location, dayStartHour int) string {
+	priorityExpr := "1"
+	switch {
+	case len(priorities) == 1:
+		priorityExpr = sourcePriorityCaseSQL(priorities[0])
+	case len(priorities) > 1:
+		var b strings.Builder
+		b.WriteString("CASE ")
+		for i, p := range priorities[:len(priorities)-1] {
+			fmt.Fprintf(&b, "WHEN metric_name = ANY($%d) THEN %s ", i+7, sourcePriorityCaseSQL(p))
+		}
+		fmt.Fprintf(&b, "ELSE %s END", sourcePriorityCaseSQL(priorities[len(priorities)-1]))
+		priorityExpr = b.String()
+	}
+	return fmt.Sprintf(
+		`WITH deduped AS (
+			SELECT *, ROW_NUMBER() OVER (
+				PARTITION BY metric_name, time_bucket('5 minutes', time)
+				ORDER BY %s
+			) AS rn
+			FROM health_metrics
+			WHERE metric_name = ANY($2) AND time >= $3 AND time < $4 AND user_id = $5
+			  AND %s
+		)
+		SELECT metric_name, %s AS bucket,
+		       CASE WHEN metric_name = ANY($6) THEN SUM(COALESCE(qty, avg_val))
+		            ELSE AVG(COALESCE(qty, avg_val)) END AS avg_val,
+		       MIN(COALESCE(qty, min_val)) AS min_val,
+		       MAX(COALESCE(qty, max_val)) AS max_val,
+		       COUNT(*) AS count
+		FROM deduped WHERE rn = 1
+		GROUP BY metric_name, bucket
+		ORDER BY metric_name, bucket ASC`,
+		priorityExpr, uncoveredRowsSQL, timeBucketSQL("$1::interval", "time", loc, dayStartHour))
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestUniqueMetrics verifies blank and repeated metric names are dropped
+// while first-seen order is kept.
+func TestUniqueMetrics(t *testing.T) {
+	got := uniqueMetrics([]string{"heart_rate", "", "step_count", "heart_rate"})
+	if want := []string{"heart_rate", "step_count"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("uniqueMetrics = %v, want %v", got, want)
+	}
+}
+
+// TestGroupByPriority verifies metrics sharing a priority list land in one
+// group, in first-seen order.
+func TestGroupByPriority(t *testing.T) {
+	watch := []string{"Apple Watch"}
+	ring := []string{"Oura", "Apple Watch"}
+	forMetric := func(m string) []string {
+		if m == "sleep_analysis" {
+			return ring
+		}
+		return watch
+	}
+	priorities, groups := groupByPriority([]string{"heart_rate", "sleep_analysis", "step_count"}, forMetric)
+	if !reflect.DeepEqual(priorities, [][]string{watch, ring}) {
+		t.Errorf("priorities = %v", priorities)
+	}
+	if want := [][]string{{"heart_rate", "step_count"}, {"sleep_analysis"}}; !reflect.DeepEqual(groups, want) {
+		t.Errorf("groups = %v, want %v", groups, want)
+	}
+}
+
+// TestBulkTimeSeriesSQL verifies the bulk query groups by metric and bucket,
+// sums cumulative metrics, and switches priority per group by parameter.
+func TestBulkTimeSeriesSQL(t *testing.T) {
+	single := bulkTimeSeriesSQL([][]string{{"Apple Watch"}}, nil, 0)
+	for _, want := range []string{
+		"PARTITION BY metric_name, time_bucket('5 minutes', time)",
+		"metric_name = ANY($2)",
+		"WHEN metric_name = ANY($6) THEN SUM",
+		"GROUP BY metric_name, bucket",
+		"WHEN source LIKE 'Apple Watch%' THEN 1",
+	} {
+		if !strings.Contains(single, want) {
+			t.Errorf("single-group query missing %q:\n%s", want, single)
+		}
+	}
+	if strings.Contains(single, "$7") {
+		t.Errorf("single-group query should not bind group parameters:\n%s", single)
+	}
+
+	multi := bulkTimeSeriesSQL([][]string{{"Apple Watch"}, {"Oura"}, {"Garmin"}}, nil, 4)
+	for _, want := range []string{
+		"WHEN metric_name = ANY($7) THEN CASE WHEN source LIKE 'Apple Watch%'",
+		"WHEN metric_name = ANY($8) THEN CASE WHEN source LIKE 'Oura%'",
+		"ELSE CASE WHEN source LIKE 'Garmin%'",
+		"interval '4 hours'",
+	} {
+		if !strings.Contains(multi, want) {
+			t.Errorf("multi-group query missing %q:\n%s", want, multi)
+		}
+	}
+	if strings.Contains(multi, "$9") {
+		t.Errorf("fallback group should not be bound:\n%s", multi)
+	}
+}
@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChangesPageSize caps how many rows per table one GetChanges call returns,
+// give or take rows sharing the last updated_at.
+const ChangesPageSize = 5000
+
+// MetricChange identifies a created or updated health_metrics row by its
+// dedup key.
+type MetricChange struct {
+	Metric    string    `json:"metric"`
+	Source    string    `json:"source"`
+	Time      time.Time `json:"time"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// WorkoutChange identifies a created or updated workout.
+type WorkoutChange struct {
+	ID        uuid.UUID `json:"id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SleepSessionChange identifies a created or updated nightly sleep session.
+type SleepSessionChange struct {
+	Date      string    `json:"date"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Changes lists the rows created or updated after After. NextAfter is the
+// continue token for the next poll: the change cursor (see changesCursorSQL)
+// when everything fit, otherwise the earliest point a truncated table stopped
+// at (Truncated is then true and the client should poll again straight
+// away). Rows can repeat across polls, so clients should apply them
+// idempotently. Deletions are not reported.
+type Changes struct {
+	After         time.Time            `json:"after"`
+	NextAfter     time.Time            `json:"next_after"`
+	Truncated     bool                 `json:"truncated"`
+	HealthMetrics []MetricChange       `json:"health_metrics"`
+	Workouts      []WorkoutChange      `json:"workouts"`
+	SleepSessions []SleepSessionChange `json:"sleep_sessions"`
+}
+
+// changesSQL selects cols from table for rows updated after $2, up to and
+// including the updated_at of the row at offset $3 (see pageCutoffSQL), so
+// rows written by one batch, which share NOW(), stay on the same page. The
+// last column is true when rows remain past the page.
+func changesSQL(table, cols string) string {
+	return pageCutoffSQL(table, "updated_at", "user_id = $1 AND updated_at > $2", 3) + fmt.Sprintf(`
+		SELECT %[2]s, updated_at,
+		       EXISTS (SELECT 1 FROM %[1]s t, cutoff c WHERE t.user_id = $1 AND t.updated_at > c.updated_at)
+		FROM %[1]s
+		WHERE user_id = $1 AND updated_at > $2
+		  AND (NOT EXISTS (SELECT 1 FROM cutoff) OR updated_at <= (SELECT updated_at FROM cutoff))
+		ORDER BY updated_at`, table, cols)
+}
+
+// changesCursorSQL reads the resume point for a poll that returned
+// everything. Rows are stamped with their transaction's start time, so a
+// write still in progress commits rows dated before now; the cursor
+// therefore stays just before the oldest open transaction, which may
+// repeat rows on the next poll but never skips them.
+const changesCursorSQL = `SELECT LEAST(NOW(), COALESCE(MIN(xact_start) - interval '1 microsecond', NOW()))
+	FROM pg_stat_activity
+	WHERE datname = current_database() AND xact_start IS NOT NULL AND pid <> pg_backend_pid()`
+
+// nextAfter picks the cursor for the next poll: the earliest last-returned
+// updated_at among truncated tables, or now, the change cursor, when no
+// table was truncated.
+func nextAfter(now time.Time, truncatedAt []time.Time) (time.Time, bool) {
+	if len(truncatedAt) == 0 {
+		return now, false
+	}
+	next := truncatedAt[0]
+	for _, t := range truncatedAt[1:] {
+		if t.Before(next) {
+			next = t
+		}
+	}
+	return next, true
+}
+
+// GetChanges returns the health metrics, workouts, and sleep sessions created
+// or updated after after, oldest change first.
+func (db *DB) GetChanges(ctx context.Context, after time.Time, userID int) (*Changes, error) {
+	// Read the cursor before the rows, so anything committed in between is
+	// returned now and again on the next poll rather than not at all.
+	var now time.Time
+	if err := db.Pool.QueryRow(ctx, changesCursorSQL).Scan(&now); err != nil {
+		return nil, fmt.Errorf("reading change cursor: %w", err)
+	}
+
+	c := &Changes{
+		After:         after,
+		HealthMetrics: []MetricChange{},
+		Workouts:      []WorkoutChange{},
+		SleepSessions: []SleepSessionChange{},
+	}
+	var truncatedAt []time.Time
+
+	rows, err := db.Pool.Query(ctx, changesSQL("health_metrics", "metric_name, source, time"), userID, after, ChangesPageSize-1)
+	if err != nil {
+		return nil, fmt.Errorf("querying metric changes: %w", err)
+	}
+	more := false
+	for rows.Next() {
+		var m MetricChange
+		if err := rows.Scan(&m.Metric, &m.Source, &m.Time, &m.UpdatedAt, &more); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning metric change: %w", err)
+		}
+		c.HealthMetrics = append(c.HealthMetrics, m)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying metric changes: %w", err)
+	}
+	if more {
+		truncatedAt = append(truncatedAt, c.HealthMetrics[len(c.HealthMetrics)-1].UpdatedAt)
+	}
+
+	rows, err = db.Pool.Query(ctx, changesSQL("workouts", "id"), userID, after, ChangesPageSize-1)
+	if err != nil {
+		return nil, fmt.Errorf("querying workout changes: %w", err)
+	}
+	more = false
+	for rows.Next() {
+		var w WorkoutChange
+		if err := rows.Scan(&w.ID, &w.UpdatedAt, &more); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning workout change: %w", err)
+		}
+		c.Workouts = append(c.Workouts, w)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying workout changes: %w", err)
+	}
+	if more {
+		truncatedAt = append(truncatedAt, c.Workouts[len(c.Workouts)-1].UpdatedAt)
+	}
+
+	rows, err = db.Pool.Query(ctx, changesSQL("sleep_sessions", "to_char(date, 'YYYY-MM-DD')"), userID, after, ChangesPageSize-1)
+	if err != nil {
+		return nil, fmt.Errorf("querying sleep session changes: %w", err)
+	}
+	more = false
+	for rows.Next() {
+		var s SleepSessionChange
+		if err := rows.Scan(&s.Date, &s.UpdatedAt, &more); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning sleep session change: %w", err)
+		}
+		c.SleepSessions = append(c.SleepSessions, s)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("querying sleep session changes: %w", err)
+	}
+	if more {
+		truncatedAt = append(truncatedAt, c.SleepSessions[len(c.SleepSessions)-1].UpdatedAt)
+	}
+
+	c.NextAfter, c.Truncated = nextAfter(now, truncatedAt)
+	return c, nil
+}
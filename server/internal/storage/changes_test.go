@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestNextAfter verifies the next poll resumes from the earliest truncated
+// table and from the query time once every table fit.
+func TestNextAfter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	next, truncated := nextAfter(now, nil)
+	if !next.Equal(now) || truncated {
+		t.Errorf("complete = %v %v, want now and not truncated", next, truncated)
+	}
+
+	metrics := now.Add(-time.Hour)
+	workouts := now.Add(-2 * time.Hour)
+	next, truncated = nextAfter(now, []time.Time{metrics, workouts})
+	if !next.Equal(workouts) || !truncated {
+		t.Errorf("truncated = %v %v, want %v", next, truncated, workouts)
+	}
+}
+
+// TestChangesCursorSQL verifies a complete poll resumes before the oldest
+// transaction still open, whose rows carry its start time and could commit
+// after the poll, rather than at the query time.
+func TestChangesCursorSQL(t *testing.T) {
+	for _, want := range []string{
+		"FROM pg_stat_activity",
+		"MIN(xact_start) - interval '1 microsecond'",
+		"LEAST(NOW(),",
+		"datname = current_database()",
+		"pid <> pg_backend_pid()",
+	} {
+		if !strings.Contains(changesCursorSQL, want) {
+			t.Errorf("changesCursorSQL missing %q:\n%s", want, changesCursorSQL)
+		}
+	}
+}
+
+// TestChangesSQLCutoff verifies change pages filter strictly after the cursor
+// and end on the cutoff timestamp inclusively, so a batch sharing one
+// updated_at is never split between polls.
+func TestChangesSQLCutoff(t *testing.T) {
+	sql := changesSQL("workouts", "id")
+	for _, want := range []string{"updated_at > $2", "updated_at <= (SELECT updated_at FROM cutoff)", "OFFSET $3 LIMIT 1", "SELECT id, updated_at"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("changesSQL missing %q:\n%s", want, sql)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// minCorrelationPoints is the fewest paired buckets a correlation can be
+// computed from at all; the configured minimum applies on top.
+const minCorrelationPoints = 3
+
+// DefaultMinCorrelationPoints is the configured minimum when none is set.
+// Three or four points readily give r = ±1 by chance.
+const DefaultMinCorrelationPoints = 10
+
+// correlationAlpha is the significance level: correlations with a p-value at
+// or above it are flagged low confidence.
+const correlationAlpha = 0.05
+
+// SetMinCorrelationPoints sets the fewest paired buckets a correlation
+// coefficient is reported for. Values below 3 are raised to 3.
+func (db *DB) SetMinCorrelationPoints(n int) {
+	db.minCorrelationPoints = max(n, minCorrelationPoints)
+}
+
+// correlationMinPoints returns the configured minimum, defaulting to
+// DefaultMinCorrelationPoints.
+func (db *DB) correlationMinPoints() int {
+	if db.minCorrelationPoints == 0 {
+		return DefaultMinCorrelationPoints
+	}
+	return db.minCorrelationPoints
+}
+
+// correlate returns the Pearson r of paired samples and its two-tailed
+// p-value, or nils with fewer than minPoints pairs or a constant side.
+func correlate(xs, ys []float64, minPoints int) (r, p *float64) {
+	if len(xs) < minPoints {
+		return nil, nil
+	}
+	r = pearsonR(xs, ys)
+	if r == nil {
+		return nil, nil
+	}
+	pv := pearsonPValue(*r, len(xs))
+	return r, &pv
+}
+
+// lowConfidence reports whether a correlation is missing or not significant
+// at correlationAlpha.
+func lowConfidence(p *float64) bool {
+	return p == nil || *p >= correlationAlpha
+}
+
+// pearsonPValue returns the two-tailed p-value of r over n pairs under the
+// null hypothesis of no correlation, from t = r·√((n−2)/(1−r²)) with n−2
+// degrees of freedom.
+func pearsonPValue(r float64, n int) float64 {
+	df := float64(n - 2)
+	if df <= 0 {
+		return 1
+	}
+	if 1-r*r <= 0 {
+		return 0
+	}
+	t2 := r * r * df / (1 - r*r)
+	// P(|T| > t) for Student's t is the regularized incomplete beta
+	// I_{df/(df+t²)}(df/2, 1/2).
+	return regIncBeta(df/2, 0.5, df/(df+t2))
+}
+
+// regIncBeta returns the regularized incomplete beta function I_x(a, b),
+// evaluated by continued fraction (Numerical Recipes, betai).
+func regIncBeta(a, b, x float64) float64 {
+	if x <= 0 {
+		return 0
+	}
+	if x >= 1 {
+		return 1
+	}
+	la, _ := math.Lgamma(a)
+	lb, _ := math.Lgamma(b)
+	lab, _ := math.Lgamma(a + b)
+	front := math.Exp(lab - la - lb + a*math.Log(x) + b*math.Log(1-x))
+	if x < (a+1)/(a+b+2) {
+		return front * betaCF(a, b, x) / a
+	}
+	return 1 - front*betaCF(b, a, 1-x)/b
+}
+
+// betaCF evaluates the continued fraction for the incomplete beta function
+// with the modified Lentz method.
+func betaCF(a, b, x float64) float64 {
+	const (
+		maxIter = 200
+		eps     = 1e-14
+		tiny    = 1e-300
+	)
+	qab, qap, qam := a+b, a+1, a-1
+	c, d := 1.0, 1-qab*x/qap
+	if math.Abs(d) < tiny {
+		d = tiny
+	}
+	d = 1 / d
+	h := d
+	for m := 1; m <= maxIter; m++ {
+		fm := float64(m)
+		m2 := 2 * fm
+		aa := fm * (b - fm) * x / ((qam + m2) * (a + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		h *= d * c
+		aa = -(a + fm) * (qab + fm) * x / ((a + m2) * (qap + m2))
+		d = 1 + aa*d
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = 1 + aa/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < eps {
+			break
+		}
+	}
+	return h
+}
+
+// pearsonR returns the Pearson correlation of paired samples, or nil with
+// fewer than minCorrelationPoints pairs or when either side is constant.
+func pearsonR(xs, ys []float64) *float64 {
+	n := float64(len(xs))
+	if len(xs) < minCorrelationPoints || len(xs) != len(ys) {
+		return nil
+	}
+	var sumX, sumY, sumXY, sumX2, sumY2 float64
+	for i := range xs {
+		x, y := xs[i], ys[i]
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumX2 += x * x
+		sumY2 += y * y
+	}
+	denom := (n*sumX2 - sumX*sumX) * (n*sumY2 - sumY*sumY)
+	if denom <= 0 {
+		return nil
+	}
+	r := (n*sumXY - sumX*sumY) / math.Sqrt(denom)
+	return &r
+}
+
+// AnchorCorrelation is one candidate metric's correlation with an anchor
+// metric over Count shared buckets. LowConfidence is set when PValue is not
+// below 0.05.
+type AnchorCorrelation struct {
+	Metric        string  `json:"metric"`
+	PearsonR      float64 `json:"pearson_r"`
+	PValue        float64 `json:"p_value"`
+	LowConfidence bool    `json:"low_confidence"`
+	Count         int     `json:"count"`
+}
+
+// GetCorrelationsAgainst correlates anchorMetric with each candidate over
+// time buckets and returns them strongest first (by |r|). Without candidates
+// every metric available to the user is tried. Candidates sharing fewer than
+// the configured minimum of buckets with the anchor (see
+// SetMinCorrelationPoints), or with no variance, are left out.
+func (db *DB) GetCorrelationsAgainst(ctx context.Context, anchorMetric string, candidateMetrics []string, start, end time.Time, bucket string, userID int) ([]AnchorCorrelation, error) {
+	if len(candidateMetrics) == 0 {
+		available, err := db.GetAvailableMetrics(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range available {
+			candidateMetrics = append(candidateMetrics, m.MetricName)
+		}
+	}
+
+	anchor, err := db.GetTimeSeries(ctx, anchorMetric, start, end, bucket, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying anchor %s: %w", anchorMetric, err)
+	}
+	candidates := make(map[string][]TimeSeriesPoint, len(candidateMetrics))
+	for _, m := range candidateMetrics {
+		if m == anchorMetric {
+			continue
+		}
+		points, err := db.GetTimeSeries(ctx, m, start, end, bucket, userID)
+		if err != nil {
+			return nil, fmt.Errorf("querying candidate %s: %w", m, err)
+		}
+		candidates[m] = points
+	}
+	return correlateAgainst(anchor, candidates, db.correlationMinPoints()), nil
+}
+
+// correlateAgainst pairs each candidate's buckets with the anchor's by time
+// and ranks the resulting correlations by strength, skipping candidates with
+// fewer than minPoints shared buckets.
+func correlateAgainst(anchor []TimeSeriesPoint, candidates map[string][]TimeSeriesPoint, minPoints int) []AnchorCorrelation {
+	byTime := make(map[time.Time]float64, len(anchor))
+	for _, p := range anchor {
+		if p.Avg != nil {
+			byTime[p.Time.UTC()] = *p.Avg
+		}
+	}
+
+	out := []AnchorCorrelation{}
+	for metric, points := range candidates {
+		var xs, ys []float64
+		for _, p := range points {
+			if a, ok := byTime[p.Time.UTC()]; ok && p.Avg != nil {
+				xs = append(xs, a)
+				ys = append(ys, *p.Avg)
+			}
+		}
+		if r, p := correlate(xs, ys, minPoints); r != nil {
+			out = append(out, AnchorCorrelation{
+				Metric: metric, PearsonR: *r, PValue: *p, LowConfidence: lowConfidence(p), Count: len(xs),
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		ai, aj := math.Abs(out[i].PearsonR), math.Abs(out[j].PearsonR)
+		if ai != aj {
+			return ai > aj
+		}
+		return out[i].Metric < out[j].Metric
+	})
+	return out
+}
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// dailyPoints builds one point per day from day0 with the given values; NaN
+// leaves a gap.
+func dailyPoints(day0 time.Time, vals ...float64) []TimeSeriesPoint {
+	var out []TimeSeriesPoint
+	for i, v := range vals {
+		if math.IsNaN(v) {
+			continue
+		}
+		v := v
+		out = append(out, TimeSeriesPoint{Time: day0.AddDate(0, 0, i), Avg: &v})
+	}
+	return out
+}
+
+// TestPearsonR verifies perfect positive and negative correlation, and nil
+// for too few pairs or a constant series.
+func TestPearsonR(t *testing.T) {
+	if r := pearsonR([]float64{1, 2, 3, 4}, []float64{2, 4, 6, 8}); r == nil || math.Abs(*r-1) > 1e-9 {
+		t.Errorf("positive r = %v, want 1", r)
+	}
+	if r := pearsonR([]float64{1, 2, 3, 4}, []float64{8, 6, 4, 2}); r == nil || math.Abs(*r+1) > 1e-9 {
+		t.Errorf("negative r = %v, want -1", r)
+	}
+	if r := pearsonR([]float64{1, 2}, []float64{1, 2}); r != nil {
+		t.Errorf("two pairs r = %v, want nil", *r)
+	}
+	if r := pearsonR([]float64{1, 2, 3}, []float64{5, 5, 5}); r != nil {
+		t.Errorf("constant r = %v, want nil", *r)
+	}
+}
+
+// TestCorrelateAgainst verifies candidates are aligned on the anchor's
+// buckets, ranked by absolute strength, and dropped without enough overlap.
+func TestCorrelateAgainst(t *testing.T) {
+	day0 := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	nan := math.NaN()
+	anchor := dailyPoints(day0, 50, 55, 60, 65, 70)
+	got := correlateAgainst(anchor, map[string][]TimeSeriesPoint{
+		"resting_heart_rate": dailyPoints(day0, 62, 60, 58, 56, 54),            // r = -1
+		"step_count":         dailyPoints(day0, 8000, 9500, 8200, 11000, 9800), // weaker
+		"vo2_max":            dailyPoints(day0, nan, nan, nan, 45, 46),         // 2 shared buckets
+		"sleep_analysis":     dailyPoints(day0.AddDate(0, 0, 10), 7, 8, 6),     // no overlap
+	}, 3)
+	if len(got) != 2 {
+		t.Fatalf("got %d correlations, want 2: %+v", len(got), got)
+	}
+	if got[0].Metric != "resting_heart_rate" || math.Abs(got[0].PearsonR+1) > 1e-9 || got[0].Count != 5 {
+		t.Errorf("strongest = %+v, want resting_heart_rate r=-1 over 5", got[0])
+	}
+	if got[1].Metric != "step_count" || math.Abs(got[1].PearsonR) >= 1 {
+		t.Errorf("second = %+v", got[1])
+	}
+}
+
+// TestPearsonPValue verifies two-tailed p-values against Student's t
+// reference values, and the perfect and null correlation edge cases.
+func TestPearsonPValue(t *testing.T) {
+	tests := []struct {
+		r    float64
+		n    int
+		want float64
+	}{
+		{0.5, 10, 0.14111},
+		{0.3, 30, 0.10725},
+		{-0.8, 12, 0.0017818},
+		{0.632, 10, 0.049951}, // critical r for df = 8 at 5%
+		{0, 20, 1},
+		{1, 5, 0},
+	}
+	for _, tt := range tests {
+		if got := pearsonPValue(tt.r, tt.n); math.Abs(got-tt.want) > 1e-4 {
+			t.Errorf("p(r=%v, n=%d) = %v, want %v", tt.r, tt.n, got, tt.want)
+		}
+	}
+}
+
+// TestCorrelateMinPoints verifies the configured minimum suppresses the
+// coefficient, and that a weak correlation is flagged low confidence while
+// a strong one is not.
+func TestCorrelateMinPoints(t *testing.T) {
+	xs := []float64{1, 2, 3, 4}
+	ys := []float64{2, 4, 6, 8}
+	if r, p := correlate(xs, ys, 10); r != nil || p != nil {
+		t.Errorf("4 pairs with min 10 = %v, %v, want nil", r, p)
+	}
+	if !lowConfidence(nil) {
+		t.Error("missing correlation should be low confidence")
+	}
+
+	strong := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	noisy := []float64{1.2, 1.9, 3.3, 3.8, 5.1, 6.2, 6.8, 8.1, 9.2, 9.9}
+	r, p := correlate(strong, noisy, 10)
+	if r == nil || p == nil || lowConfidence(p) {
+		t.Errorf("strong = %v, %v, want significant", r, p)
+	}
+
+	weak := []float64{5, 3, 8, 1, 9, 2, 7, 4, 6, 5}
+	r, p = correlate(strong, weak, 10)
+	if r == nil || p == nil || !lowConfidence(p) {
+		t.Errorf("weak = %v, %v, want low confidence", r, p)
+	}
+}
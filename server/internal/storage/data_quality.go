@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Weights of the two data-quality score components; they sum to 100.
+const (
+	qualityCoverageWeight = 70.0
+	qualityGapWeight      = 30.0
+)
+
+// DataQuality describes how completely a metric is recorded over a range.
+// Coverage is the fraction of days in the range with at least one sample;
+// LongestGapDays is the longest run of consecutive days without one.
+type DataQuality struct {
+	Metric         string   `json:"metric"`
+	ExpectedDays   int      `json:"expected_days"`
+	CoveredDays    int      `json:"covered_days"`
+	Coverage       float64  `json:"coverage"`
+	LongestGapDays int      `json:"longest_gap_days"`
+	SampleCount    int64    `json:"sample_count"`
+	Sources        []string `json:"sources"`
+	Score          int      `json:"score"`
+}
+
+// GetDataQuality scores how trustworthy a metric's trend over [start, end) is
+// from its daily coverage and longest gap. The score is 70 × coverage plus
+// 30 × the share of the range not taken up by the longest gap, so a metric
+// recorded daily scores 100 and one with a single month-long hole in a
+// two-month range scores about half.
+func (db *DB) GetDataQuality(ctx context.Context, metricName string, start, end time.Time, userID int) (*DataQuality, error) {
+	points, err := db.GetTimeSeries(ctx, metricName, start, end, "1 day", userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying data quality: %w", err)
+	}
+
+	q := dataQuality(points, start, end)
+	q.Metric = metricName
+
+	rows, err := db.Pool.Query(ctx,
+		`SELECT DISTINCT source FROM (
+			SELECT source FROM health_metrics
+			WHERE metric_name = $1 AND user_id = $2 AND time >= $3 AND time < $4
+			  AND `+uncoveredRowsSQL+`
+			UNION ALL
+			SELECT source FROM health_metric_rollups
+			WHERE metric_name = $1 AND user_id = $2 AND time >= $3 AND time < $4
+		) s WHERE source <> '' ORDER BY source`,
+		metricName, userID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("querying metric sources: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var src string
+		if err := rows.Scan(&src); err != nil {
+			return nil, fmt.Errorf("scanning metric source: %w", err)
+		}
+		q.Sources = append(q.Sources, src)
+	}
+	return q, rows.Err()
+}
+
+// dataQuality computes coverage, the longest gap, and the score from daily
+// buckets. Days are UTC calendar days overlapping [start, end).
+func dataQuality(points []TimeSeriesPoint, start, end time.Time) *DataQuality {
+	q := &DataQuality{Sources: []string{}}
+	first := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	if !end.After(start) {
+		return q
+	}
+	q.ExpectedDays = int(math.Ceil(end.Sub(first).Hours() / 24))
+
+	covered := make(map[int]bool, len(points))
+	for _, p := range points {
+		if p.Count == 0 {
+			continue
+		}
+		q.SampleCount += p.Count
+		day := int(p.Time.UTC().Sub(first).Hours() / 24)
+		if day >= 0 && day < q.ExpectedDays {
+			covered[day] = true
+		}
+	}
+	q.CoveredDays = len(covered)
+
+	run := 0
+	for d := 0; d < q.ExpectedDays; d++ {
+		if covered[d] {
+			run = 0
+			continue
+		}
+		run++
+		if run > q.LongestGapDays {
+			q.LongestGapDays = run
+		}
+	}
+
+	if q.CoveredDays == 0 {
+		return q
+	}
+	expected := float64(q.ExpectedDays)
+	q.Coverage = float64(q.CoveredDays) / expected
+	q.Score = int(math.Round(qualityCoverageWeight*q.Coverage +
+		qualityGapWeight*(1-float64(q.LongestGapDays)/expected)))
+	return q
+}
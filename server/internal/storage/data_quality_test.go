@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDataQuality verifies coverage, the longest gap, and the score for a
+// range with missing days, and that no data scores zero.
+func TestDataQuality(t *testing.T) {
+	start := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 10)
+	day := func(d int, n int64) TimeSeriesPoint {
+		return TimeSeriesPoint{Time: start.AddDate(0, 0, d), Count: n}
+	}
+	// Days 0-3 and 8-9 covered; 4-7 missing (a 4-day gap); day 5 has an
+	// empty bucket that must not count.
+	points := []TimeSeriesPoint{day(0, 3), day(1, 2), day(2, 1), day(3, 4), day(5, 0), day(8, 2), day(9, 1)}
+
+	q := dataQuality(points, start, end)
+	if q.ExpectedDays != 10 || q.CoveredDays != 6 || q.LongestGapDays != 4 || q.SampleCount != 13 {
+		t.Fatalf("quality = %+v", q)
+	}
+	if q.Coverage != 0.6 {
+		t.Errorf("coverage = %v, want 0.6", q.Coverage)
+	}
+	// 70*0.6 + 30*(1-4/10) = 42 + 18
+	if q.Score != 60 {
+		t.Errorf("score = %d, want 60", q.Score)
+	}
+
+	full := dataQuality([]TimeSeriesPoint{day(0, 1), day(1, 1)}, start, start.AddDate(0, 0, 2))
+	if full.Score != 100 || full.LongestGapDays != 0 {
+		t.Errorf("full coverage = %+v, want score 100", full)
+	}
+
+	empty := dataQuality(nil, start, end)
+	if empty.Score != 0 || empty.Coverage != 0 || empty.LongestGapDays != 10 {
+		t.Errorf("empty = %+v, want score 0 and a 10-day gap", empty)
+	}
+}
@@ -3,6 +3,9 @@ package storage
 import (
 	"context"
 	"fmt"
+	"os"
+	"regexp"
+	"strconv"
 	"sync"
 	"time"
 
@@ -20,11 +23,68 @@ type DB struct {
 	// Available metrics cache (per user_id, bounded).
 	availMetricsMu    sync.RWMutex
 	availMetricsCache map[int]*availMetricsCacheEntry
+
+	// Allowlist cache (metric_name → enabled), shared across users.
+	allowlistMu        sync.RWMutex
+	allowlistCache     map[string]bool
+	allowlistFetchedAt time.Time
+	allowlistCacheTTL  time.Duration
+	// allowlistGen is bumped on every invalidation; a load that started
+	// under an older generation is dropped instead of cached.
+	allowlistGen uint64
+
+	// Metric alias cache, refreshed on the allowlist TTL; see MetricAliases.
+	aliasCache     map[string]string
+	aliasFetchedAt time.Time
+
+	// sleepLoc is the zone sleep nights are dated in (nil = UTC). It is also
+	// the user's calendar day for daily buckets and DailySumsToday.
+	sleepLoc *time.Location
+	// sleepDay assigns nights to days; see SetSleepDayBoundary.
+	sleepDay SleepDayBoundary
+
+	// dayStartHour shifts day boundaries; see SetDayStartHour.
+	dayStartHour int
+
+	// alphaLinkWindow pairs unlinked Alpha sessions with workouts; see
+	// SetAlphaLinkWindow.
+	alphaLinkWindow time.Duration
+
+	// Sleep debt defaults; see SetSleepDebtPolicy.
+	sleepTargetHours       float64
+	sleepMissingNightsZero bool
+
+	// rirBands classify strength sets by reps in reserve; see SetRIRBands.
+	rirBands []RIRBandDef
+
+	// Strength calorie estimate; see SetStrengthCalories.
+	strengthMET         float64
+	defaultBodyweightKg float64
+
+	// volumeLandmarks override DefaultVolumeLandmarks; see SetVolumeLandmarks.
+	volumeLandmarks map[string]VolumeLandmark
+
+	// illness configures DetectIllnessSignals; see SetIllnessThresholds.
+	illness IllnessThresholds
+
+	// workoutTypes override DefaultWorkoutTypes; see SetWorkoutTypes.
+	workoutTypes map[string]string
+
+	// minCorrelationPoints gates correlation coefficients; see
+	// SetMinCorrelationPoints.
+	minCorrelationPoints int
+
+	// derived maps derived metric names to their definitions; see
+	// SetDerivedMetrics.
+	derived map[string]*DerivedMetric
 }
 
 const (
 	availMetricsCacheTTL     = 5 * time.Minute
 	availMetricsCacheMaxSize = 64
+
+	// DefaultAllowlistCacheTTL is used when no TTL has been configured.
+	DefaultAllowlistCacheTTL = 60 * time.Second
 )
 
 type availMetricsCacheEntry struct {
@@ -39,14 +99,103 @@ func (db *DB) SetSourcePriority(priorities []string) {
 	db.SourcePriority = priorities
 }
 
-// New creates a new DB with a connection pool.
-func New(ctx context.Context, dsn string) (*DB, error) {
+// SetAllowlistCacheTTL configures how long allowlist lookups are cached.
+// A zero or negative TTL disables caching.
+func (db *DB) SetAllowlistCacheTTL(ttl time.Duration) {
+	db.allowlistMu.Lock()
+	db.allowlistCacheTTL = ttl
+	db.allowlistCache = nil
+	db.allowlistGen++
+	db.allowlistMu.Unlock()
+}
+
+// SetSleepTimezone sets the zone used to assign synthesized sleep sessions
+// to calendar dates, so backfilled nights match the user's wake-up date. Daily
+// time-series buckets and daily sums start at midnight in the same zone.
+func (db *DB) SetSleepTimezone(loc *time.Location) {
+	db.sleepLoc = loc
+}
+
+// SetSleepDayBoundary sets how nights are assigned to days: SleepDayNoon
+// files them under the noon-to-noon sleep day they start in, anything else
+// under the wake-up date. It applies to sessions stored afterwards and to
+// GetSleepSummary periods; existing sessions keep their dates.
+func (db *DB) SetSleepDayBoundary(b SleepDayBoundary) {
+	db.sleepDay = b
+}
+
+// SetDayStartHour sets the hour days start at for daily time-series buckets
+// and daily sums, so a night owl's 1am samples count toward the day before.
+// Zero keeps midnight boundaries.
+func (db *DB) SetDayStartHour(h int) {
+	db.dayStartHour = h
+}
+
+// SetAlphaLinkWindow sets how far an unlinked Alpha Progression session may
+// start from a workout for QueryWorkoutsMerged to pair them. It should match
+// the window sessions are linked with (ingest.alpha_link_window); zero pairs
+// only stored links.
+func (db *DB) SetAlphaLinkWindow(d time.Duration) {
+	db.alphaLinkWindow = d
+}
+
+// SetSleepDebtPolicy sets the nightly target used when GetSleepDebt is called
+// without one, and whether nights without a session count as zero sleep
+// rather than being left out.
+func (db *DB) SetSleepDebtPolicy(targetHours float64, missingAsZero bool) {
+	db.sleepTargetHours = targetHours
+	db.sleepMissingNightsZero = missingAsZero
+}
+
+// PoolConfig sizes the connection pool. Zero fields keep DefaultPoolConfig's
+// value.
+type PoolConfig struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	HealthCheckPeriod time.Duration
+}
+
+// DefaultPoolConfig suits a single-user instance against a stock Postgres
+// (max_connections 100), leaving room for migrations and psql sessions.
+var DefaultPoolConfig = PoolConfig{
+	MaxConns:          16,
+	MinConns:          2,
+	MaxConnLifetime:   time.Hour,
+	HealthCheckPeriod: time.Minute,
+}
+
+// poolConfig parses dsn and applies the pool sizing on top.
+func poolConfig(dsn string, pc PoolConfig) (*pgxpool.Config, error) {
 	cfg, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("parsing pool config: %w", err)
 	}
-	cfg.MaxConns = 16
-	cfg.MinConns = 2
+	pick := func(v, def int32) int32 {
+		if v == 0 {
+			return def
+		}
+		return v
+	}
+	pickDur := func(v, def time.Duration) time.Duration {
+		if v == 0 {
+			return def
+		}
+		return v
+	}
+	cfg.MaxConns = pick(pc.MaxConns, DefaultPoolConfig.MaxConns)
+	cfg.MinConns = pick(pc.MinConns, DefaultPoolConfig.MinConns)
+	cfg.MaxConnLifetime = pickDur(pc.MaxConnLifetime, DefaultPoolConfig.MaxConnLifetime)
+	cfg.HealthCheckPeriod = pickDur(pc.HealthCheckPeriod, DefaultPoolConfig.HealthCheckPeriod)
+	return cfg, nil
+}
+
+// New creates a new DB with a connection pool sized by pc.
+func New(ctx context.Context, dsn string, pc PoolConfig) (*DB, error) {
+	cfg, err := poolConfig(dsn, pc)
+	if err != nil {
+		return nil, err
+	}
 
 	pool, err := pgxpool.NewWithConfig(ctx, cfg)
 	if err != nil {
@@ -56,7 +205,13 @@ func New(ctx context.Context, dsn string) (*DB, error) {
 		pool.Close()
 		return nil, fmt.Errorf("pinging database: %w", err)
 	}
-	return &DB{Pool: pool}, nil
+	return &DB{
+		Pool:                pool,
+		allowlistCacheTTL:   DefaultAllowlistCacheTTL,
+		strengthMET:         DefaultStrengthMET,
+		defaultBodyweightKg: DefaultBodyweightKg,
+		illness:             DefaultIllnessThresholds,
+	}, nil
 }
 
 // Close closes the connection pool.
@@ -64,6 +219,45 @@ func (db *DB) Close() {
 	db.Pool.Close()
 }
 
+// SchemaVersion returns the applied migration version and whether the last
+// migration left the schema dirty.
+func (db *DB) SchemaVersion(ctx context.Context) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := db.Pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		return 0, false, fmt.Errorf("querying schema version: %w", err)
+	}
+	return uint(version), dirty, nil
+}
+
+// migrationFileRe matches an up migration file name and captures its version.
+var migrationFileRe = regexp.MustCompile(`^(\d+)_.*\.up\.sql$`)
+
+// LatestMigrationVersion returns the highest up-migration version in
+// migrationsPath, i.e. the version RunMigrations would bring the schema to.
+func LatestMigrationVersion(migrationsPath string) (uint, error) {
+	entries, err := os.ReadDir(migrationsPath)
+	if err != nil {
+		return 0, fmt.Errorf("reading migrations: %w", err)
+	}
+	var latest uint
+	for _, e := range entries {
+		m := migrationFileRe.FindStringSubmatch(e.Name())
+		if m == nil {
+			continue
+		}
+		v, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		if uint(v) > latest {
+			latest = uint(v)
+		}
+	}
+	return latest, nil
+}
+
 // RunMigrations applies all pending migrations from the given directory.
 func RunMigrations(dsn, migrationsPath string) error {
 	m, err := migrate.New("file://"+migrationsPath, dsn)
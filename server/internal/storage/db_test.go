@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestPoolConfig verifies the pool sizing is applied on top of the parsed DSN
+// and that zero fields fall back to DefaultPoolConfig.
+func TestPoolConfig(t *testing.T) {
+	dsn := "postgres://u:p@localhost:5432/freereps?sslmode=disable"
+	cfg, err := poolConfig(dsn, PoolConfig{MaxConns: 40, MinConns: 5, MaxConnLifetime: 30 * time.Minute, HealthCheckPeriod: 15 * time.Second})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.MaxConns != 40 || cfg.MinConns != 5 || cfg.MaxConnLifetime != 30*time.Minute || cfg.HealthCheckPeriod != 15*time.Second {
+		t.Errorf("pool config = max %d min %d lifetime %v health %v", cfg.MaxConns, cfg.MinConns, cfg.MaxConnLifetime, cfg.HealthCheckPeriod)
+	}
+	if cfg.ConnConfig.Database != "freereps" {
+		t.Errorf("database = %q, want freereps", cfg.ConnConfig.Database)
+	}
+
+	cfg, err = poolConfig(dsn, PoolConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	d := DefaultPoolConfig
+	if cfg.MaxConns != d.MaxConns || cfg.MinConns != d.MinConns || cfg.MaxConnLifetime != d.MaxConnLifetime || cfg.HealthCheckPeriod != d.HealthCheckPeriod {
+		t.Errorf("zero config = max %d min %d lifetime %v health %v, want defaults", cfg.MaxConns, cfg.MinConns, cfg.MaxConnLifetime, cfg.HealthCheckPeriod)
+	}
+
+	if _, err := poolConfig("postgres://%zz", PoolConfig{}); err == nil {
+		t.Error("expected error for malformed DSN")
+	}
+}
+
+// TestLatestMigrationVersion verifies the highest up-migration number is
+// found and that down files and unrelated files are ignored.
+func TestLatestMigrationVersion(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"000001_init.up.sql", "000001_init.down.sql",
+		"000012_rollups.up.sql", "000013_next.down.sql", "README.md",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v, err := LatestMigrationVersion(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if v != 12 {
+		t.Errorf("latest = %d, want 12", v)
+	}
+
+	if _, err := LatestMigrationVersion(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing directory")
+	}
+}
@@ -0,0 +1,297 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+	"unicode"
+)
+
+// DerivedMetric is a metric computed on the fly from other metrics with an
+// arithmetic expression, e.g. "active_energy + basal_energy_burned".
+type DerivedMetric struct {
+	Name       string
+	Expression string
+	Inputs     []string // metric names the expression reads, sorted
+
+	eval derivedExpr
+}
+
+// derivedExpr evaluates an expression over one bucket's input values. ok is
+// false when the result is undefined (division by zero).
+type derivedExpr func(vals map[string]float64) (v float64, ok bool)
+
+// ParseDerivedMetric parses expr, which may use metric names, numbers,
+// + - * /, unary minus and parentheses.
+func ParseDerivedMetric(name, expr string) (*DerivedMetric, error) {
+	p := &exprParser{src: []rune(expr), inputs: map[string]bool{}}
+	eval, err := p.parseSum()
+	if err == nil && p.peek() != 0 {
+		err = fmt.Errorf("unexpected %q at position %d", p.peek(), p.pos+1)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("derived metric %s: %w", name, err)
+	}
+	if len(p.inputs) == 0 {
+		return nil, fmt.Errorf("derived metric %s: expression uses no metrics", name)
+	}
+	d := &DerivedMetric{Name: name, Expression: expr, eval: eval}
+	for in := range p.inputs {
+		d.Inputs = append(d.Inputs, in)
+	}
+	sort.Strings(d.Inputs)
+	return d, nil
+}
+
+// SetDerivedMetrics parses and registers derived metrics (name → expression).
+// GetTimeSeries and GetMetricStats then answer for their names. An input may
+// not itself be a derived metric.
+func (db *DB) SetDerivedMetrics(defs map[string]string) error {
+	derived := make(map[string]*DerivedMetric, len(defs))
+	for name, expr := range defs {
+		d, err := ParseDerivedMetric(name, expr)
+		if err != nil {
+			return err
+		}
+		derived[name] = d
+	}
+	for _, d := range derived {
+		for _, in := range d.Inputs {
+			if derived[in] != nil {
+				return fmt.Errorf("derived metric %s: input %s is itself derived", d.Name, in)
+			}
+		}
+	}
+	db.derived = derived
+	return nil
+}
+
+// DerivedMetric returns the derived metric registered under name, or nil.
+func (db *DB) DerivedMetric(name string) *DerivedMetric {
+	return db.derived[name]
+}
+
+// GetDerivedMetric returns a derived metric's time series: each input is
+// bucketed as GetTimeSeries would, then the expression is applied to the
+// inputs' bucket averages. Buckets missing any input are left out.
+func (db *DB) GetDerivedMetric(ctx context.Context, d *DerivedMetric, start, end time.Time, bucketSize string, userID int) ([]TimeSeriesPoint, error) {
+	inputs := make(map[string][]TimeSeriesPoint, len(d.Inputs))
+	for _, in := range d.Inputs {
+		points, err := db.GetTimeSeries(ctx, in, start, end, bucketSize, userID)
+		if err != nil {
+			return nil, fmt.Errorf("derived metric %s: %w", d.Name, err)
+		}
+		inputs[in] = points
+	}
+	return alignDerived(d, inputs), nil
+}
+
+// getDerivedStats summarizes a derived metric's daily values.
+func (db *DB) getDerivedStats(ctx context.Context, d *DerivedMetric, start, end time.Time, userID int) (*MetricStats, error) {
+	points, err := db.GetDerivedMetric(ctx, d, start, end, "1 day", userID)
+	if err != nil {
+		return nil, err
+	}
+	return derivedStats(d.Name, points), nil
+}
+
+// alignDerived joins the inputs' points on bucket time and evaluates the
+// expression per bucket. Count is the smallest input count in the bucket.
+func alignDerived(d *DerivedMetric, inputs map[string][]TimeSeriesPoint) []TimeSeriesPoint {
+	type bucket struct {
+		vals  map[string]float64
+		count int64
+	}
+	buckets := map[time.Time]*bucket{}
+	for name, points := range inputs {
+		for _, p := range points {
+			if p.Avg == nil {
+				continue
+			}
+			b := buckets[p.Time]
+			if b == nil {
+				b = &bucket{vals: map[string]float64{}, count: p.Count}
+				buckets[p.Time] = b
+			}
+			b.vals[name] = *p.Avg
+			b.count = min(b.count, p.Count)
+		}
+	}
+
+	out := []TimeSeriesPoint{}
+	for t, b := range buckets {
+		if len(b.vals) < len(d.Inputs) {
+			continue
+		}
+		v, ok := d.eval(b.vals)
+		if !ok {
+			continue
+		}
+		out = append(out, TimeSeriesPoint{Time: t, Avg: &v, Min: &v, Max: &v, Count: b.count})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Time.Before(out[j].Time) })
+	return out
+}
+
+// derivedStats computes MetricStats over a derived series, one value per
+// point.
+func derivedStats(name string, points []TimeSeriesPoint) *MetricStats {
+	stats := &MetricStats{Metric: name, Basis: StatsBasisDerived, Count: int64(len(points))}
+	if len(points) == 0 {
+		return stats
+	}
+	lo, hi, sum := math.Inf(1), math.Inf(-1), 0.0
+	for _, p := range points {
+		lo, hi = min(lo, *p.Avg), max(hi, *p.Avg)
+		sum += *p.Avg
+	}
+	mean := sum / float64(len(points))
+	var ss float64
+	for _, p := range points {
+		ss += (*p.Avg - mean) * (*p.Avg - mean)
+	}
+	sd := math.Sqrt(ss / float64(len(points)))
+	stats.Avg, stats.Min, stats.Max, stats.StdDev = &mean, &lo, &hi, &sd
+	return stats
+}
+
+// exprParser is a recursive-descent parser for derived metric expressions:
+//
+//	sum     = product { ("+" | "-") product }
+//	product = unary { ("*" | "/") unary }
+//	unary   = "-" unary | number | name | "(" sum ")"
+type exprParser struct {
+	src    []rune
+	pos    int
+	inputs map[string]bool
+}
+
+// peek returns the next non-space rune, or 0 at the end.
+func (p *exprParser) peek() rune {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+	if p.pos == len(p.src) {
+		return 0
+	}
+	return p.src[p.pos]
+}
+
+func (p *exprParser) parseSum() (derivedExpr, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '+' && op != '-' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseProduct()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == '+' {
+			left = func(v map[string]float64) (float64, bool) {
+				a, ok1 := l(v)
+				b, ok2 := right(v)
+				return a + b, ok1 && ok2
+			}
+		} else {
+			left = func(v map[string]float64) (float64, bool) {
+				a, ok1 := l(v)
+				b, ok2 := right(v)
+				return a - b, ok1 && ok2
+			}
+		}
+	}
+}
+
+func (p *exprParser) parseProduct() (derivedExpr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		if op != '*' && op != '/' {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		if op == '*' {
+			left = func(v map[string]float64) (float64, bool) {
+				a, ok1 := l(v)
+				b, ok2 := right(v)
+				return a * b, ok1 && ok2
+			}
+		} else {
+			left = func(v map[string]float64) (float64, bool) {
+				a, ok1 := l(v)
+				b, ok2 := right(v)
+				return a / b, ok1 && ok2 && b != 0
+			}
+		}
+	}
+}
+
+func (p *exprParser) parseUnary() (derivedExpr, error) {
+	c := p.peek()
+	switch {
+	case c == 0:
+		return nil, fmt.Errorf("unexpected end of expression")
+	case c == '-':
+		p.pos++
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(v map[string]float64) (float64, bool) {
+			a, ok := inner(v)
+			return -a, ok
+		}, nil
+	case c == '(':
+		p.pos++
+		inner, err := p.parseSum()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("missing ) at position %d", p.pos+1)
+		}
+		p.pos++
+		return inner, nil
+	case unicode.IsDigit(c) || c == '.':
+		start := p.pos
+		for p.pos < len(p.src) && (unicode.IsDigit(p.src[p.pos]) || p.src[p.pos] == '.') {
+			p.pos++
+		}
+		n, err := strconv.ParseFloat(string(p.src[start:p.pos]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", string(p.src[start:p.pos]))
+		}
+		return func(map[string]float64) (float64, bool) { return n, true }, nil
+	case unicode.IsLetter(c) || c == '_':
+		start := p.pos
+		for p.pos < len(p.src) && (unicode.IsLetter(p.src[p.pos]) || unicode.IsDigit(p.src[p.pos]) || p.src[p.pos] == '_') {
+			p.pos++
+		}
+		name := string(p.src[start:p.pos])
+		p.inputs[name] = true
+		return func(v map[string]float64) (float64, bool) {
+			x, ok := v[name]
+			return x, ok
+		}, nil
+	default:
+		return nil, fmt.Errorf("unexpected %q at position %d", c, p.pos+1)
+	}
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestParseDerivedMetric verifies expressions are evaluated with the usual
+// precedence and that their inputs are collected.
+func TestParseDerivedMetric(t *testing.T) {
+	vals := map[string]float64{"a": 2, "b": 3, "c": 4}
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		{"a + b", 5},
+		{"a * b", 6},
+		{"a + b * c", 14},
+		{"(a + b) * c", 20},
+		{"a - b - c", -5},
+		{"c / a / 2", 1},
+		{"-a * 1.5", -3},
+		{"a * (1 - c / 100)", 1.92},
+	}
+	for _, tt := range tests {
+		d, err := ParseDerivedMetric("x", tt.expr)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.expr, err)
+		}
+		got, ok := d.eval(vals)
+		if !ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%q = %v (ok=%v), want %v", tt.expr, got, ok, tt.want)
+		}
+	}
+
+	d, _ := ParseDerivedMetric("x", "b * a + b")
+	if len(d.Inputs) != 2 || d.Inputs[0] != "a" || d.Inputs[1] != "b" {
+		t.Errorf("inputs = %v, want [a b]", d.Inputs)
+	}
+	if _, ok := d.eval(map[string]float64{"a": 1}); ok {
+		t.Error("expected missing input to be undefined")
+	}
+	d, _ = ParseDerivedMetric("x", "a / b")
+	if _, ok := d.eval(map[string]float64{"a": 1, "b": 0}); ok {
+		t.Error("expected division by zero to be undefined")
+	}
+}
+
+// TestParseDerivedMetricErrors verifies malformed expressions are rejected.
+func TestParseDerivedMetricErrors(t *testing.T) {
+	for _, expr := range []string{"", "a +", "(a + b", "a b", "a $ b", "1 + 2", "1..2 * a"} {
+		if _, err := ParseDerivedMetric("x", expr); err == nil {
+			t.Errorf("%q: expected error", expr)
+		}
+	}
+}
+
+// TestSetDerivedMetrics verifies definitions are registered by name and that
+// derived inputs are rejected.
+func TestSetDerivedMetrics(t *testing.T) {
+	db := &DB{}
+	if err := db.SetDerivedMetrics(map[string]string{"total_energy": "active_energy + basal_energy_burned"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if db.DerivedMetric("total_energy") == nil || db.DerivedMetric("active_energy") != nil {
+		t.Error("expected only total_energy to be derived")
+	}
+	err := db.SetDerivedMetrics(map[string]string{"a2": "a * 2", "a4": "a2 * 2"})
+	if err == nil {
+		t.Error("expected error for derived input")
+	}
+}
+
+// TestAlignDerivedAddition verifies inputs are summed per bucket and buckets
+// missing an input are dropped.
+func TestAlignDerivedAddition(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	d, _ := ParseDerivedMetric("total_energy", "active_energy + basal_energy_burned")
+	day1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2, day3 := day1.AddDate(0, 0, 1), day1.AddDate(0, 0, 2)
+	got := alignDerived(d, map[string][]TimeSeriesPoint{
+		"active_energy": {
+			{Time: day2, Avg: f(500), Count: 40},
+			{Time: day1, Avg: f(400), Count: 30},
+			{Time: day3, Avg: f(450), Count: 20},
+		},
+		"basal_energy_burned": {
+			{Time: day1, Avg: f(1700), Count: 50},
+			{Time: day2, Avg: f(1750), Count: 10},
+		},
+	})
+	if len(got) != 2 {
+		t.Fatalf("got %d points, want 2", len(got))
+	}
+	if !got[0].Time.Equal(day1) || *got[0].Avg != 2100 || got[0].Count != 30 {
+		t.Errorf("day 1 = %+v, want 2100 from min count 30", got[0])
+	}
+	if !got[1].Time.Equal(day2) || *got[1].Avg != 2250 || *got[1].Min != 2250 || *got[1].Max != 2250 || got[1].Count != 10 {
+		t.Errorf("day 2 = %+v, want 2250 from min count 10", got[1])
+	}
+}
+
+// TestAlignDerivedMultiplication verifies a product with a constant factor is
+// computed per bucket and division by zero skips the bucket.
+func TestAlignDerivedMultiplication(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	d, _ := ParseDerivedMetric("lean_mass", "weight_body_mass * (1 - body_fat_percentage / 100) / scale")
+	day1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	got := alignDerived(d, map[string][]TimeSeriesPoint{
+		"weight_body_mass":    {{Time: day1, Avg: f(80), Count: 1}, {Time: day2, Avg: f(81), Count: 1}},
+		"body_fat_percentage": {{Time: day1, Avg: f(20), Count: 1}, {Time: day2, Avg: f(20), Count: 1}},
+		"scale":               {{Time: day1, Avg: f(1), Count: 1}, {Time: day2, Avg: f(0), Count: 1}},
+	})
+	if len(got) != 1 {
+		t.Fatalf("got %d points, want 1", len(got))
+	}
+	if math.Abs(*got[0].Avg-64) > 1e-9 {
+		t.Errorf("lean mass = %v, want 64", *got[0].Avg)
+	}
+}
+
+// TestDerivedStats verifies stats are computed over the derived values.
+func TestDerivedStats(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	stats := derivedStats("x", []TimeSeriesPoint{
+		{Time: day, Avg: f(2)}, {Time: day.AddDate(0, 0, 1), Avg: f(4)}, {Time: day.AddDate(0, 0, 2), Avg: f(6)},
+	})
+	if stats.Count != 3 || *stats.Avg != 4 || *stats.Min != 2 || *stats.Max != 6 || stats.Basis != StatsBasisDerived {
+		t.Errorf("stats = %+v", stats)
+	}
+	if math.Abs(*stats.StdDev-math.Sqrt(8.0/3)) > 1e-9 {
+		t.Errorf("stddev = %v", *stats.StdDev)
+	}
+	if empty := derivedStats("x", nil); empty.Count != 0 || empty.Avg != nil {
+		t.Errorf("empty stats = %+v", empty)
+	}
+}
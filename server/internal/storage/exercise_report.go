@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// maxE1RMReps is the most reps to failure a set may have for its estimated
+// 1RM to count; the Epley formula overestimates beyond this.
+const maxE1RMReps = 12
+
+// ExerciseReportSession combines volume and intensity for one exercise in one
+// session. Warm-up sets are excluded. AvgRIR is nil when no set tracked RIR;
+// EstimatedOneRMKg is nil when no set qualified for an estimate.
+type ExerciseReportSession struct {
+	Date             string   `json:"date"`
+	Exercise         string   `json:"exercise"`
+	Sets             int      `json:"sets"`
+	Reps             int      `json:"reps"`
+	TonnageKg        float64  `json:"tonnage_kg"`
+	MaxWeightKg      float64  `json:"max_weight_kg"`
+	AvgRIR           *float64 `json:"avg_rir"`
+	EstimatedOneRMKg *float64 `json:"estimated_1rm_kg"`
+}
+
+// GetExerciseReport returns per-session tonnage, max weight, average RIR and
+// estimated 1RM for exercises matching exerciseFilter (partial,
+// case-insensitive), sorted by date and then exercise name.
+func (db *DB) GetExerciseReport(ctx context.Context, exerciseFilter string, start, end time.Time, userID int) ([]ExerciseReportSession, error) {
+	sets, err := db.QueryWorkoutSets(ctx, start, end, userID, exerciseFilter)
+	if err != nil {
+		return nil, err
+	}
+	return exerciseReport(sets), nil
+}
+
+// exerciseReport groups working sets by session and exercise.
+func exerciseReport(sets []models.WorkoutSetRow) []ExerciseReportSession {
+	type key struct {
+		date     time.Time
+		exercise string
+	}
+	type acc struct {
+		ExerciseReportSession
+		rirSum float64
+		rirN   int
+	}
+	groups := map[key]*acc{}
+	for _, s := range sets {
+		if s.IsWarmup {
+			continue
+		}
+		k := key{s.SessionDate, s.ExerciseName}
+		a := groups[k]
+		if a == nil {
+			a = &acc{ExerciseReportSession: ExerciseReportSession{
+				Date: s.SessionDate.Format("2006-01-02"), Exercise: s.ExerciseName,
+			}}
+			groups[k] = a
+		}
+		a.Sets++
+		a.Reps += s.Reps
+		a.TonnageKg += s.WeightKg * float64(s.Reps)
+		a.MaxWeightKg = max(a.MaxWeightKg, s.WeightKg)
+		if s.RIR >= 0 {
+			a.rirSum += s.RIR
+			a.rirN++
+		}
+		if e, ok := estimatedOneRM(s.WeightKg, s.Reps, s.RIR); ok && (a.EstimatedOneRMKg == nil || e > *a.EstimatedOneRMKg) {
+			a.EstimatedOneRMKg = &e
+		}
+	}
+
+	keys := make([]key, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].date.Equal(keys[j].date) {
+			return keys[i].date.Before(keys[j].date)
+		}
+		return keys[i].exercise < keys[j].exercise
+	})
+
+	out := make([]ExerciseReportSession, len(keys))
+	for i, k := range keys {
+		a := groups[k]
+		if a.rirN > 0 {
+			avg := a.rirSum / float64(a.rirN)
+			a.AvgRIR = &avg
+		}
+		out[i] = a.ExerciseReportSession
+	}
+	return out
+}
+
+// estimatedOneRM applies the Epley formula to a set, counting reps left in
+// reserve as reps the lifter could still have done. rir is -1 when
+// untracked. Sets without load, or with more than maxE1RMReps reps to
+// failure, give no estimate.
+func estimatedOneRM(weightKg float64, reps int, rir float64) (float64, bool) {
+	toFailure := float64(reps)
+	if rir > 0 {
+		toFailure += rir
+	}
+	if weightKg <= 0 || reps <= 0 || toFailure > maxE1RMReps {
+		return 0, false
+	}
+	if toFailure == 1 {
+		return weightKg, true
+	}
+	return weightKg * (1 + toFailure/30), true
+}
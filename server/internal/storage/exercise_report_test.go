@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// TestEstimatedOneRM verifies the Epley estimate counts reps in reserve and
+// skips unloaded and high-rep sets.
+func TestEstimatedOneRM(t *testing.T) {
+	tests := []struct {
+		weight float64
+		reps   int
+		rir    float64
+		want   float64
+		ok     bool
+	}{
+		{100, 1, 0, 100, true},
+		{100, 5, -1, 100 * (1 + 5.0/30), true},
+		{100, 5, 2, 100 * (1 + 7.0/30), true},
+		{100, 10, 3, 0, false},
+		{0, 5, 1, 0, false},
+		{100, 0, 1, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := estimatedOneRM(tt.weight, tt.reps, tt.rir)
+		if ok != tt.ok || math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("estimatedOneRM(%v, %d, %v) = %v, %v; want %v, %v", tt.weight, tt.reps, tt.rir, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+// TestExerciseReport verifies working sets are combined per session and
+// exercise, sorted by date then name, with warm-ups and untracked RIR left
+// out of the averages.
+func TestExerciseReport(t *testing.T) {
+	day1 := time.Date(2025, 4, 1, 18, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 3)
+	set := func(day time.Time, name string, warmup bool, kg float64, reps int, rir float64) models.WorkoutSetRow {
+		return models.WorkoutSetRow{SessionDate: day, ExerciseName: name, IsWarmup: warmup, WeightKg: kg, Reps: reps, RIR: rir}
+	}
+	// QueryWorkoutSets returns newest sessions first.
+	report := exerciseReport([]models.WorkoutSetRow{
+		set(day2, "Bench Press", false, 82.5, 5, 1),
+		set(day1, "Overhead Press", false, 50, 8, -1),
+		set(day1, "Bench Press", true, 40, 10, -1),
+		set(day1, "Bench Press", false, 80, 5, 2),
+		set(day1, "Bench Press", false, 80, 5, 0),
+		set(day1, "Bench Press", false, 75, 8, -1),
+	})
+
+	if len(report) != 3 {
+		t.Fatalf("got %d sessions, want 3", len(report))
+	}
+	if report[0].Exercise != "Bench Press" || report[1].Exercise != "Overhead Press" || report[2].Date != "2025-04-04" {
+		t.Fatalf("order = %+v", report)
+	}
+
+	bench := report[0]
+	if bench.Sets != 3 || bench.Reps != 18 || bench.TonnageKg != 1400 || bench.MaxWeightKg != 80 {
+		t.Errorf("bench volume = %+v, want 3 sets, 18 reps, 1400 kg, max 80", bench)
+	}
+	if bench.AvgRIR == nil || *bench.AvgRIR != 1 {
+		t.Errorf("bench avg RIR = %v, want 1", bench.AvgRIR)
+	}
+	// Best estimate: 80 kg × 5 reps + 2 in reserve beats 75 × 8.
+	if want := 80 * (1 + 7.0/30); bench.EstimatedOneRMKg == nil || math.Abs(*bench.EstimatedOneRMKg-want) > 1e-9 {
+		t.Errorf("bench e1RM = %v, want %v", bench.EstimatedOneRMKg, want)
+	}
+	if report[1].AvgRIR != nil {
+		t.Errorf("overhead press avg RIR = %v, want nil without tracked sets", *report[1].AvgRIR)
+	}
+}
@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ExerciseName is one distinct exercise in the user's logged sets.
+type ExerciseName struct {
+	Name          string    `json:"name"`
+	Sets          int       `json:"sets"` // working sets, warm-ups excluded
+	LastPerformed time.Time `json:"last_performed"`
+}
+
+// ListExercises returns the user's distinct exercise names, most trained
+// first, for autocomplete. A non-empty search keeps names containing every
+// whitespace-separated word, case-insensitively ("press" matches "Bench
+// Press" and "Leg Press"; "db press" matches "DB Shoulder Press").
+func (db *DB) ListExercises(ctx context.Context, search string, limit, userID int) ([]ExerciseName, error) {
+	query, args := exerciseListQuery(search, limit, userID)
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying exercises: %w", err)
+	}
+	defer rows.Close()
+
+	result := []ExerciseName{}
+	for rows.Next() {
+		var e ExerciseName
+		if err := rows.Scan(&e.Name, &e.Sets, &e.LastPerformed); err != nil {
+			return nil, fmt.Errorf("scanning exercise: %w", err)
+		}
+		result = append(result, e)
+	}
+	return result, rows.Err()
+}
+
+// exerciseListQuery builds the ListExercises query: one ILIKE condition per
+// search word, ordered by working set count.
+func exerciseListQuery(search string, limit, userID int) (string, []any) {
+	args := []any{userID}
+	var where strings.Builder
+	for _, p := range exerciseSearchPatterns(search) {
+		args = append(args, p)
+		fmt.Fprintf(&where, ` AND exercise_name ILIKE $%d ESCAPE '\'`, len(args))
+	}
+	args = append(args, limit)
+	query := fmt.Sprintf(
+		`SELECT exercise_name, COUNT(*) FILTER (WHERE NOT is_warmup)::int, MAX(session_date)
+		 FROM workout_sets
+		 WHERE user_id = $1%s
+		 GROUP BY exercise_name
+		 ORDER BY 2 DESC, exercise_name ASC
+		 LIMIT $%d`, where.String(), len(args))
+	return query, args
+}
+
+// exerciseSearchPatterns turns a search string into ILIKE patterns, one per
+// word, with LIKE wildcards in the input escaped.
+func exerciseSearchPatterns(search string) []string {
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	var patterns []string
+	for _, word := range strings.Fields(search) {
+		patterns = append(patterns, "%"+escaper.Replace(word)+"%")
+	}
+	return patterns
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestExerciseSearchPatterns verifies each search word becomes a substring
+// pattern with LIKE wildcards escaped.
+func TestExerciseSearchPatterns(t *testing.T) {
+	tests := []struct {
+		search string
+		want   []string
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"press", []string{"%press%"}},
+		{"  db   Press ", []string{"%db%", "%Press%"}},
+		{"100%_a\\b", []string{`%100\%\_a\\b%`}},
+	}
+	for _, tt := range tests {
+		if got := exerciseSearchPatterns(tt.search); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.search, got, tt.want)
+		}
+	}
+}
+
+// TestExerciseListQuery verifies the search filter adds one ILIKE condition
+// per word and the limit is bound after them.
+func TestExerciseListQuery(t *testing.T) {
+	query, args := exerciseListQuery("", 20, 7)
+	if strings.Contains(query, "ILIKE") || !reflect.DeepEqual(args, []any{7, 20}) {
+		t.Errorf("unfiltered: args = %v, query:\n%s", args, query)
+	}
+	if !strings.Contains(query, "LIMIT $2") {
+		t.Errorf("unfiltered query should bind the limit as $2:\n%s", query)
+	}
+
+	query, args = exerciseListQuery("bench press", 5, 7)
+	if !reflect.DeepEqual(args, []any{7, "%bench%", "%press%", 5}) {
+		t.Errorf("filtered args = %v", args)
+	}
+	for _, want := range []string{"exercise_name ILIKE $2", "exercise_name ILIKE $3", "LIMIT $4"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("filtered query missing %q:\n%s", want, query)
+		}
+	}
+}
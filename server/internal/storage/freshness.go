@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DataTable identifies a group of tables whose freshness is reported to
+// clients and whose version backs an HTTP cache validator (ETag /
+// Last-Modified).
+type DataTable string
+
+const (
+	DataHealthMetrics DataTable = "health_metrics"
+	DataSleep         DataTable = "sleep"
+	DataTraining      DataTable = "training"
+)
+
+// latestDataSQL maps each DataTable to a query returning the newest data
+// timestamp for user $1. The latest import_logs entry is folded in so that
+// backfills of older data still change the validator.
+var latestDataSQL = map[DataTable]string{
+	DataHealthMetrics: `SELECT GREATEST(
+		(SELECT max(time) FROM health_metrics WHERE user_id = $1),
+		(SELECT max(created_at) FROM import_logs WHERE user_id = $1))`,
+	DataSleep: `SELECT GREATEST(
+		(SELECT max(date)::timestamptz FROM sleep_sessions WHERE user_id = $1),
+		(SELECT max(start_time) FROM sleep_stages WHERE user_id = $1),
+		(SELECT max(created_at) FROM import_logs WHERE user_id = $1))`,
+	DataTraining: `SELECT GREATEST(
+		(SELECT max(start_time) FROM workouts WHERE user_id = $1),
+		(SELECT max(session_date) FROM workout_sets WHERE user_id = $1),
+		(SELECT max(created_at) FROM import_logs WHERE user_id = $1))`,
+}
+
+// dataVersionSQL maps each DataTable to a query returning when user $1's
+// data in the group last changed: rows created or updated (updated_at),
+// imports logged, and deletions or other edits recorded in data_versions
+// by markDataChanged. Tables without updated_at fall back to their newest
+// data time.
+var dataVersionSQL = map[DataTable]string{
+	DataHealthMetrics: `SELECT GREATEST(
+		(SELECT max(updated_at) FROM health_metrics WHERE user_id = $1),
+		(SELECT max(created_at) FROM import_logs WHERE user_id = $1),
+		(SELECT max(changed_at) FROM data_versions WHERE user_id IN ($1, 0) AND data_table = 'health_metrics'))`,
+	DataSleep: `SELECT GREATEST(
+		(SELECT max(updated_at) FROM sleep_sessions WHERE user_id = $1),
+		(SELECT max(start_time) FROM sleep_stages WHERE user_id = $1),
+		(SELECT max(created_at) FROM import_logs WHERE user_id = $1),
+		(SELECT max(changed_at) FROM data_versions WHERE user_id IN ($1, 0) AND data_table = 'sleep'))`,
+	DataTraining: `SELECT GREATEST(
+		(SELECT max(updated_at) FROM workouts WHERE user_id = $1),
+		(SELECT max(session_date) FROM workout_sets WHERE user_id = $1),
+		(SELECT max(created_at) FROM import_logs WHERE user_id = $1),
+		(SELECT max(changed_at) FROM data_versions WHERE user_id IN ($1, 0) AND data_table = 'training'))`,
+}
+
+// execer runs a statement on a pool or inside a transaction.
+type execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// markDataChanged records that userID's data in tables changed in a way
+// their updated_at columns don't show, such as a delete, so cache
+// validators built by GetDataVersion move on. userID 0 marks every user.
+func markDataChanged(ctx context.Context, q execer, userID int, tables ...DataTable) error {
+	for _, table := range tables {
+		if _, err := q.Exec(ctx,
+			`INSERT INTO data_versions (user_id, data_table, changed_at) VALUES ($1, $2, clock_timestamp())
+			 ON CONFLICT (user_id, data_table) DO UPDATE SET changed_at = EXCLUDED.changed_at`,
+			userID, string(table)); err != nil {
+			return fmt.Errorf("recording %s change: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// GetDataVersion returns when the user's data in the given table group last
+// changed, including updates and deletions, for HTTP cache validators.
+// Returns the zero time when the user has no data yet.
+func (db *DB) GetDataVersion(ctx context.Context, table DataTable, userID int) (time.Time, error) {
+	query, ok := dataVersionSQL[table]
+	if !ok {
+		return time.Time{}, fmt.Errorf("unknown data table %q", table)
+	}
+
+	var version *time.Time
+	if err := db.Pool.QueryRow(ctx, query, userID).Scan(&version); err != nil {
+		return time.Time{}, fmt.Errorf("querying %s data version: %w", table, err)
+	}
+	if version == nil {
+		return time.Time{}, nil
+	}
+	return *version, nil
+}
+
+// GetLatestDataTime returns the newest data timestamp for the given table group.
+// Returns the zero time when the user has no data yet.
+func (db *DB) GetLatestDataTime(ctx context.Context, table DataTable, userID int) (time.Time, error) {
+	query, ok := latestDataSQL[table]
+	if !ok {
+		return time.Time{}, fmt.Errorf("unknown data table %q", table)
+	}
+
+	var latest *time.Time
+	if err := db.Pool.QueryRow(ctx, query, userID).Scan(&latest); err != nil {
+		return time.Time{}, fmt.Errorf("querying latest %s time: %w", table, err)
+	}
+	if latest == nil {
+		return time.Time{}, nil
+	}
+	return *latest, nil
+}
+
+// freshnessTables lists the groups reported by GetDataFreshness, in a stable order.
+var freshnessTables = []DataTable{DataHealthMetrics, DataSleep, DataTraining}
+
+// GetDataFreshness returns the newest data timestamp for every table group,
+// keyed by group name. Groups without data map to nil.
+func (db *DB) GetDataFreshness(ctx context.Context, userID int) (map[DataTable]*time.Time, error) {
+	out := make(map[DataTable]*time.Time, len(freshnessTables))
+	for _, table := range freshnessTables {
+		latest, err := db.GetLatestDataTime(ctx, table, userID)
+		if err != nil {
+			return nil, err
+		}
+		if latest.IsZero() {
+			out[table] = nil
+			continue
+		}
+		out[table] = &latest
+	}
+	return out, nil
+}
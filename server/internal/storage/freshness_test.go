@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestGetLatestDataTimeUnknownTable verifies that an unmapped table group is
+// rejected before any query is built, so callers can't inject table names.
+func TestGetLatestDataTimeUnknownTable(t *testing.T) {
+	db := &DB{}
+	if _, err := db.GetLatestDataTime(context.Background(), DataTable("users"), 1); err == nil {
+		t.Fatal("expected error for unknown table")
+	}
+}
+
+// TestLatestDataSQLCoversAllTables verifies every declared DataTable has a query.
+func TestLatestDataSQLCoversAllTables(t *testing.T) {
+	for _, tbl := range []DataTable{DataHealthMetrics, DataSleep, DataTraining} {
+		if latestDataSQL[tbl] == "" {
+			t.Errorf("no latest-data query for %q", tbl)
+		}
+	}
+}
+
+// TestFreshnessTablesHaveQueries verifies every group reported by
+// GetDataFreshness is backed by a latest-data query.
+func TestFreshnessTablesHaveQueries(t *testing.T) {
+	for _, tbl := range freshnessTables {
+		if _, ok := latestDataSQL[tbl]; !ok {
+			t.Errorf("freshness table %q has no query", tbl)
+		}
+	}
+}
+
+// TestDataVersionSQL verifies every group's version moves on updates and on
+// recorded deletions, including changes marked for all users, not just on
+// newly inserted data.
+func TestDataVersionSQL(t *testing.T) {
+	for _, tbl := range freshnessTables {
+		sql, ok := dataVersionSQL[tbl]
+		if !ok {
+			t.Fatalf("no data version query for %q", tbl)
+		}
+		for _, want := range []string{
+			"max(updated_at)",
+			"FROM data_versions WHERE user_id IN ($1, 0) AND data_table = '" + string(tbl) + "'",
+			"FROM import_logs",
+		} {
+			if !strings.Contains(sql, want) {
+				t.Errorf("%s version query missing %q:\n%s", tbl, want, sql)
+			}
+		}
+	}
+}
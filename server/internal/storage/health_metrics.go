@@ -3,7 +3,6 @@ package storage
 import (
 	"context"
 	"fmt"
-	"math"
 	"strings"
 	"time"
 
@@ -13,8 +12,7 @@ import (
 
 // sourcePriorityCaseSQL generates a SQL CASE expression that maps source values
 // to priority numbers. Lower numbers = higher priority. Sources not in the list
-// get the lowest priority. Named sources use prefix matching (e.g. "Apple Watch"
-// matches "Apple Watch Series 9"); empty string uses exact match.
+// get the lowest priority. Entries match as in SourceRank (see sourceRule).
 // Returns "1" if no priorities are configured (all sources equal = no-op dedup).
 func sourcePriorityCaseSQL(priorities []string) string {
 	if len(priorities) == 0 {
@@ -23,16 +21,22 @@ func sourcePriorityCaseSQL(priorities []string) string {
 	var b strings.Builder
 	b.WriteString("CASE ")
 	for i, src := range priorities {
-		if src == "" {
-			fmt.Fprintf(&b, "WHEN source = '' THEN %d ", i+1)
-		} else {
-			fmt.Fprintf(&b, "WHEN source LIKE '%s%%' THEN %d ", src, i+1)
-		}
+		fmt.Fprintf(&b, "WHEN %s THEN %d ", sourceRule(src).conditionSQL(), i+1)
 	}
 	fmt.Fprintf(&b, "ELSE %d END", len(priorities)+1)
 	return b.String()
 }
 
+// uncoveredRowsSQL restricts a scan of health_metrics to raw rows plus
+// pre-aggregated daily rows whose day has no raw rows of the same metric, so
+// a daily total isn't counted alongside the samples it summarizes. Daily rows
+// are stamped at the start of their local day.
+const uncoveredRowsSQL = `(health_metrics.agg_level = 'raw' OR NOT EXISTS (
+				SELECT 1 FROM health_metrics r
+				WHERE r.user_id = health_metrics.user_id AND r.metric_name = health_metrics.metric_name
+				  AND r.agg_level = 'raw'
+				  AND r.time >= health_metrics.time AND r.time < health_metrics.time + interval '1 day'))`
+
 // dedupCTE returns a WITH clause that deduplicates health_metrics at a fixed
 // 5-minute granularity using source priority. The CTE selects all columns plus
 // a row number (rn) partitioned by 5-minute time buckets. Callers should filter
@@ -47,7 +51,8 @@ func dedupCTE(priorities []string, metricParam, startParam, endParam, userIDPara
 			) AS rn
 			FROM health_metrics
 			WHERE metric_name = %s AND time >= %s AND time < %s AND user_id = %s
-		) `, priorityExpr, metricParam, startParam, endParam, userIDParam)
+			  AND %s
+		) `, priorityExpr, metricParam, startParam, endParam, userIDParam, uncoveredRowsSQL)
 }
 
 // dedupCTEMultiMetric returns a dedup CTE for queries that span multiple metrics
@@ -62,7 +67,8 @@ func dedupCTEMultiMetric(priorities []string, userIDParam, inClause string) stri
 			) AS rn
 			FROM health_metrics
 			WHERE user_id = %s AND metric_name IN (%s)
-		) `, priorityExpr, userIDParam, inClause)
+			  AND %s
+		) `, priorityExpr, userIDParam, inClause, uncoveredRowsSQL)
 }
 
 // cumulativeMetrics are metrics that should be summed (not averaged) when aggregating.
@@ -83,9 +89,21 @@ var cumulativeMetrics = map[string]bool{
 	"distance_downhill_snow_sports": true,
 }
 
+// IsCumulativeMetric reports whether metricName is summed rather than
+// averaged when aggregated.
+func IsCumulativeMetric(metricName string) bool {
+	return cumulativeMetrics[metricName]
+}
+
+// minAvgMaxMetrics are stored as per-sample min_val/avg_val/max_val with no qty
+// (see health.DetectMetricShape).
+var minAvgMaxMetrics = map[string]bool{
+	"heart_rate": true,
+}
+
 // maxParamsPerBatch is the PostgreSQL extended protocol parameter limit (65535)
-// divided by 12 parameters per row, with headroom.
-const maxRowsPerBatch = 5000
+// divided by 15 parameters per row, with headroom.
+const maxRowsPerBatch = 4000
 
 // InsertHealthMetrics batch-inserts health metric rows. Returns the number actually inserted
 // (skipped duplicates via ON CONFLICT DO NOTHING).
@@ -110,19 +128,19 @@ func (db *DB) InsertHealthMetrics(ctx context.Context, rows []models.HealthMetri
 }
 
 func (db *DB) insertHealthMetricsBatch(ctx context.Context, rows []models.HealthMetricRow) (int64, error) {
-	query := `INSERT INTO health_metrics (time, user_id, metric_name, source, units, qty, min_val, avg_val, max_val, systolic, diastolic, source_uuid)
+	query := `INSERT INTO health_metrics (time, user_id, metric_name, source, units, qty, min_val, avg_val, max_val, systolic, diastolic, source_uuid, raw_units, raw_qty, agg_level)
 VALUES `
-	args := make([]any, 0, len(rows)*12)
+	args := make([]any, 0, len(rows)*15)
 	valueStrings := make([]string, 0, len(rows))
 
 	for i, r := range rows {
-		base := i * 12
+		base := i * 15
 		valueStrings = append(valueStrings, fmt.Sprintf(
-			"($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d)",
-			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9, base+10, base+11, base+12,
+			"($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,NULLIF($%d,''),$%d,COALESCE(NULLIF($%d,''),'raw'))",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9, base+10, base+11, base+12, base+13, base+14, base+15,
 		))
 		args = append(args, r.Time, r.UserID, r.MetricName, r.Source, r.Units,
-			r.Qty, r.MinVal, r.AvgVal, r.MaxVal, r.Systolic, r.Diastolic, r.SourceUUID)
+			r.Qty, r.MinVal, r.AvgVal, r.MaxVal, r.Systolic, r.Diastolic, r.SourceUUID, r.RawUnits, r.RawQty, r.AggLevel)
 	}
 
 	query += strings.Join(valueStrings, ",") + " ON CONFLICT DO NOTHING"
@@ -140,6 +158,7 @@ func (db *DB) QueryHealthMetrics(ctx context.Context, metricName string, start,
 		`SELECT time, user_id, metric_name, source, units, qty, min_val, avg_val, max_val, systolic, diastolic, source_uuid
 		 FROM health_metrics
 		 WHERE metric_name = $1 AND time >= $2 AND time < $3 AND user_id = $4
+		   AND `+uncoveredRowsSQL+`
 		 ORDER BY time ASC`,
 		metricName, start, end, userID)
 	if err != nil {
@@ -150,12 +169,53 @@ func (db *DB) QueryHealthMetrics(ctx context.Context, metricName string, start,
 	return scanHealthMetricRows(rows)
 }
 
+// CountHealthMetrics returns how many rows QueryHealthMetrics would return.
+func (db *DB) CountHealthMetrics(ctx context.Context, metricName string, start, end time.Time, userID int) (int64, error) {
+	var n int64
+	err := db.Pool.QueryRow(ctx,
+		`SELECT COUNT(*) FROM health_metrics
+		 WHERE metric_name = $1 AND time >= $2 AND time < $3 AND user_id = $4
+		   AND `+uncoveredRowsSQL,
+		metricName, start, end, userID).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("counting health metrics: %w", err)
+	}
+	return n, nil
+}
+
+// DownsampleBuckets are the bucket sizes raw queries fall back to, finest
+// first, with their lengths.
+var DownsampleBuckets = []struct {
+	Interval string
+	Length   time.Duration
+}{
+	{"1 minute", time.Minute},
+	{"5 minutes", 5 * time.Minute},
+	{"15 minutes", 15 * time.Minute},
+	{"1 hour", time.Hour},
+	{"6 hours", 6 * time.Hour},
+	{"1 day", 24 * time.Hour},
+	{"1 week", 7 * 24 * time.Hour},
+}
+
+// DownsampleBucket returns the finest bucket that splits [start, end) into
+// at most maxPoints buckets, or the coarsest one if none does.
+func DownsampleBucket(start, end time.Time, maxPoints int) string {
+	span := end.Sub(start)
+	for _, b := range DownsampleBuckets {
+		if maxPoints > 0 && int64(span/b.Length) <= int64(maxPoints) {
+			return b.Interval
+		}
+	}
+	return DownsampleBuckets[len(DownsampleBuckets)-1].Interval
+}
+
 // GetLatestMetrics returns the most recent data point for each metric.
 func (db *DB) GetLatestMetrics(ctx context.Context, userID int) ([]models.HealthMetricRow, error) {
 	rows, err := db.Pool.Query(ctx,
 		`SELECT DISTINCT ON (metric_name) time, user_id, metric_name, source, units, qty, min_val, avg_val, max_val, systolic, diastolic, source_uuid
 		 FROM health_metrics
-		 WHERE user_id = $1
+		 WHERE user_id = $1 AND `+uncoveredRowsSQL+`
 		 ORDER BY metric_name, time DESC`,
 		userID)
 	if err != nil {
@@ -166,28 +226,126 @@ func (db *DB) GetLatestMetrics(ctx context.Context, userID int) ([]models.Health
 	return scanHealthMetricRows(rows)
 }
 
+// timeSeriesSQL builds the GetTimeSeries query. Parameters: $1 bucket size,
+// $2 metric, $3 start, $4 end, $5 user_id, and with withRollup also $6, the
+// metric's rollup watermark. In that case raw rows are read only from $6 on
+// and health_metric_rollups supply the older range; rollup means are
+// re-weighted by sample_count so mixed buckets average correctly. Buckets
+// start at dayStartHour in loc (see timeBucketSQL).
+func timeSeriesSQL(priorities []string, cumulative, withRollup bool, loc *time.Location, dayStartHour int) string {
+	cte := dedupCTE(priorities, "$2", "$3", "$4", "$5")
+	bucket := timeBucketSQL("$1::interval", "time", loc, dayStartHour)
+	if !withRollup {
+		aggFunc := "AVG"
+		if cumulative {
+			aggFunc = "SUM"
+		}
+		return fmt.Sprintf(
+			`%sSELECT %s AS bucket,
+			        %s(COALESCE(qty, avg_val)) AS avg_val,
+			        MIN(COALESCE(qty, min_val)) AS min_val,
+			        MAX(COALESCE(qty, max_val)) AS max_val,
+			        COUNT(*) AS count
+			 FROM deduped WHERE rn = 1
+			 GROUP BY bucket
+			 ORDER BY bucket ASC`, cte, bucket, aggFunc)
+	}
+
+	return fmt.Sprintf(
+		`%s, %s
+		SELECT %s AS bucket,
+		       %s AS avg_val,
+		       MIN(lo) AS min_val,
+		       MAX(hi) AS max_val,
+		       SUM(n) AS count
+		FROM combined
+		GROUP BY bucket
+		ORDER BY bucket ASC`, cte, rollupSamplesSQL("", priorities, "$2", "$3", "$4", "$5", "$6"), bucket, rollupValueSQL(cumulative))
+}
+
+// rollupValueSQL aggregates the v column of rollupSamplesSQL's combined
+// CTE: a sum for cumulative metrics, otherwise a mean re-weighted by each
+// row's sample count.
+func rollupValueSQL(cumulative bool) string {
+	if cumulative {
+		return "SUM(v)"
+	}
+	return "SUM(v * n) / NULLIF(SUM(n), 0)"
+}
+
+// rollupSamplesSQL returns the CTEs <prefix>rolled and <prefix>combined,
+// which extend the deduplicated raw rows in <prefix>deduped with the
+// metric's rollups before the watermark parameter. combined yields (time,
+// v, lo, hi, n): raw rows from the watermark on, one sample each, and
+// rollup buckets before it with n their sample count and v their mean, or
+// their sum for cumulative metrics.
+func rollupSamplesSQL(prefix string, priorities []string, metricParam, startParam, endParam, userIDParam, watermarkParam string) string {
+	return fmt.Sprintf(
+		`%[1]srolled AS (
+			SELECT *, ROW_NUMBER() OVER (PARTITION BY time ORDER BY %[2]s) AS rn
+			FROM health_metric_rollups
+			WHERE metric_name = %[3]s AND user_id = %[6]s AND time >= %[4]s AND time < LEAST(%[5]s, %[7]s)
+		), %[1]scombined AS (
+			SELECT time, COALESCE(qty, avg_val) AS v, COALESCE(qty, min_val) AS lo, COALESCE(qty, max_val) AS hi, 1 AS n
+			FROM %[1]sdeduped WHERE rn = 1 AND time >= %[7]s
+			UNION ALL
+			SELECT time, qty, min_val, max_val, sample_count
+			FROM %[1]srolled WHERE rn = 1
+		)`, prefix, sourcePriorityCaseSQL(priorities), metricParam, startParam, endParam, userIDParam, watermarkParam)
+}
+
+// timeBucketSQL returns a time_bucket expression over col. Buckets start at
+// midnight in loc (UTC when nil), the same days dayStart uses. A non-zero
+// dayStartHour moves bucket boundaries that many hours past midnight, so
+// daily (and longer) buckets run e.g. 04:00–04:00 and late-night samples count
+// toward the day before. Sub-day buckets that divide evenly are unaffected.
+func timeBucketSQL(width, col string, loc *time.Location, dayStartHour int) string {
+	if loc == nil || loc == time.UTC {
+		if dayStartHour == 0 {
+			return fmt.Sprintf("time_bucket(%s, %s)", width, col)
+		}
+		return fmt.Sprintf("time_bucket(%s, %s, interval '%d hours')", width, col, dayStartHour)
+	}
+	tz := strings.ReplaceAll(loc.String(), "'", "''")
+	if dayStartHour == 0 {
+		return fmt.Sprintf("time_bucket(%s, %s, '%s')", width, col, tz)
+	}
+	return fmt.Sprintf(`time_bucket(%s, %s, '%s', "offset" => interval '%d hours')`, width, col, tz, dayStartHour)
+}
+
+// dayStart returns the start of the day containing t when days begin at
+// dayStartHour in loc (UTC when nil); before that hour t belongs to the
+// previous day.
+func dayStart(t time.Time, loc *time.Location, dayStartHour int) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	shifted := t.In(loc).Add(-time.Duration(dayStartHour) * time.Hour)
+	return time.Date(shifted.Year(), shifted.Month(), shifted.Day(), dayStartHour, 0, 0, 0, loc)
+}
+
 // GetTimeSeries returns aggregated time-series data using time_bucket.
-// bucketSize should be a PostgreSQL interval like '1 day', '1 hour'.
+// bucketSize should be a PostgreSQL interval like '1 day', '1 hour'. Day
+// buckets start at the configured day start hour (see SetDayStartHour).
 // Cumulative metrics (active_energy, basal_energy_burned, apple_exercise_time)
-// use SUM; all others use AVG.
+// use SUM; all others use AVG. Ranges reaching back past the metric's
+// retention watermark transparently include downsampled rollups.
 func (db *DB) GetTimeSeries(ctx context.Context, metricName string, start, end time.Time, bucketSize string, userID int) ([]TimeSeriesPoint, error) {
-	aggFunc := "AVG"
-	if cumulativeMetrics[metricName] {
-		aggFunc = "SUM"
+	if d := db.derived[metricName]; d != nil {
+		return db.GetDerivedMetric(ctx, d, start, end, bucketSize, userID)
 	}
 	priorities := db.ResolveSourcePriorityForMetric(ctx, userID, metricName)
-	cte := dedupCTE(priorities, "$2", "$3", "$4", "$5")
-	query := fmt.Sprintf(
-		`%sSELECT time_bucket($1::interval, time) AS bucket,
-		        %s(COALESCE(qty, avg_val)) AS avg_val,
-		        MIN(COALESCE(qty, min_val)) AS min_val,
-		        MAX(COALESCE(qty, max_val)) AS max_val,
-		        COUNT(*) AS count
-		 FROM deduped WHERE rn = 1
-		 GROUP BY bucket
-		 ORDER BY bucket ASC`, cte, aggFunc)
-	rows, err := db.Pool.Query(ctx, query,
-		bucketSize, metricName, start, end, userID)
+	watermark, _, err := db.rollupWatermark(ctx, metricName)
+	if err != nil {
+		return nil, err
+	}
+	args := []any{bucketSize, metricName, start, end, userID}
+	withRollup := watermark != nil && start.Before(*watermark)
+	if withRollup {
+		args = append(args, *watermark)
+	}
+	query := timeSeriesSQL(priorities, cumulativeMetrics[metricName], withRollup, db.sleepLoc, db.dayStartHour)
+	rows, err := db.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying time series: %w", err)
 	}
@@ -220,16 +378,82 @@ type DailySum struct {
 	Total      float64 `json:"Total"`
 }
 
-// GetDailySums returns summed values for the most recent day with data for cumulative metrics.
-// Uses the latest available data day rather than today, so historical data still shows values.
-func (db *DB) GetDailySums(ctx context.Context, userID int, metricNames []string) ([]DailySum, error) {
+// Days GetDailySums can sum over.
+const (
+	// DailySumsLatest sums the most recent day with data, so historical
+	// imports still show values. The default.
+	DailySumsLatest = "latest"
+	// DailySumsToday sums the current calendar day in the user's zone, even
+	// when it has little or no data yet, for live progress views.
+	DailySumsToday = "today"
+)
+
+// ValidDailySumsDay reports whether day is DailySumsLatest or DailySumsToday.
+func ValidDailySumsDay(day string) bool {
+	return day == DailySumsLatest || day == DailySumsToday
+}
+
+// dailySumsCutoff returns the SQL condition selecting the summed day and its
+// arguments, numbered from $argN. Today starts at dayStartHour of now's day
+// in loc (UTC when nil); the latest data day is found in SQL, with days
+// starting dayStartHour hours past UTC midnight like time-series buckets.
+func dailySumsCutoff(day string, now time.Time, loc *time.Location, dayStartHour, argN int) (string, []any) {
+	if day != DailySumsToday {
+		latest := timeBucketSQL("interval '1 day'", "MAX(time)", loc, dayStartHour)
+		return fmt.Sprintf("time >= (SELECT %s FROM combined)", latest), nil
+	}
+	return fmt.Sprintf("time >= $%d", argN), []any{dayStart(now, loc, dayStartHour)}
+}
+
+// rollupCoveredSQL is the rollup watermark of the metric in the enclosing
+// row, or -infinity for metrics that were never rolled up.
+const rollupCoveredSQL = `COALESCE((SELECT w.covered_until FROM metric_rollup_watermarks w
+			WHERE w.metric_name = %s.metric_name), '-infinity')`
+
+// dailySumsSQL builds the GetDailySums query over the metrics in inClause
+// for user $1, keeping rows that satisfy cutoff (see dailySumsCutoff). Like
+// GetTimeSeries, each metric's raw rows are read from its rollup watermark
+// on and its rollups before it, so a day that was downsampled still sums.
+func dailySumsSQL(priorities []string, inClause, cutoff string) string {
+	return fmt.Sprintf(
+		`%s, rolled AS (
+			SELECT *, ROW_NUMBER() OVER (
+				PARTITION BY metric_name, time
+				ORDER BY %s
+			) AS rn
+			FROM health_metric_rollups
+			WHERE user_id = $1 AND metric_name IN (%s)
+			  AND time < %s
+		), combined AS (
+			SELECT metric_name, time, units, COALESCE(qty, avg_val, 0) AS v
+			FROM deduped
+			WHERE rn = 1 AND time >= %s
+			UNION ALL
+			SELECT metric_name, time, units, COALESCE(qty, 0)
+			FROM rolled WHERE rn = 1
+		)
+		SELECT metric_name,
+		        COALESCE(MAX(units), '') as units,
+		        COALESCE(SUM(v), 0) as total
+		 FROM combined
+		 WHERE %s
+		 GROUP BY metric_name`,
+		dedupCTEMultiMetric(priorities, "$1", inClause), sourcePriorityCaseSQL(priorities), inClause,
+		fmt.Sprintf(rollupCoveredSQL, "health_metric_rollups"),
+		fmt.Sprintf(rollupCoveredSQL, "deduped"), cutoff)
+}
+
+// GetDailySums returns summed values of cumulative metrics for one day: the
+// most recent day with data (DailySumsLatest) or the current day in the
+// configured timezone (DailySumsToday).
+func (db *DB) GetDailySums(ctx context.Context, userID int, metricNames []string, day string) ([]DailySum, error) {
 	if len(metricNames) == 0 {
 		return nil, nil
 	}
 
 	// Build IN clause
 	params := make([]string, len(metricNames))
-	args := make([]any, 0, len(metricNames)+1)
+	args := make([]any, 0, len(metricNames)+2)
 	args = append(args, userID)
 	for i, name := range metricNames {
 		params[i] = fmt.Sprintf("$%d", i+2)
@@ -240,17 +464,9 @@ func (db *DB) GetDailySums(ctx context.Context, userID int, metricNames []string
 	// DailySums spans multiple metrics (potentially different categories).
 	// Use the user's _default priority.
 	priorities := db.ResolveSourcePriority(ctx, userID, "_default")
-	cte := dedupCTEMultiMetric(priorities, "$1", inClause)
-
-	query := fmt.Sprintf(
-		`%sSELECT metric_name,
-		        COALESCE(MAX(units), '') as units,
-		        COALESCE(SUM(COALESCE(qty, avg_val, 0)), 0) as total
-		 FROM deduped
-		 WHERE rn = 1
-		   AND time >= (SELECT date_trunc('day', MAX(time)) FROM deduped WHERE rn = 1)
-		 GROUP BY metric_name`,
-		cte)
+	cutoff, cutoffArgs := dailySumsCutoff(day, time.Now(), db.sleepLoc, db.dayStartHour, len(args)+1)
+	args = append(args, cutoffArgs...)
+	query := dailySumsSQL(priorities, inClause, cutoff)
 
 	rows, err := db.Pool.Query(ctx, query, args...)
 	if err != nil {
@@ -269,6 +485,17 @@ func (db *DB) GetDailySums(ctx context.Context, userID int, metricNames []string
 	return result, rows.Err()
 }
 
+// Stats bases describe which columns MetricStats values were computed from.
+const (
+	// StatsBasisQty: all values are over qty (or avg_val where qty is absent).
+	StatsBasisQty = "qty"
+	// StatsBasisMinAvgMax: avg/stddev are over each sample's avg_val, while
+	// min/max are the observed extremes MIN(min_val)/MAX(max_val).
+	StatsBasisMinAvgMax = "min_avg_max"
+	// StatsBasisDerived: all values are over a derived metric's daily values.
+	StatsBasisDerived = "derived_daily"
+)
+
 // MetricStats holds aggregate statistics for a single metric over a time range.
 type MetricStats struct {
 	Metric string   `json:"metric"`
@@ -277,28 +504,128 @@ type MetricStats struct {
 	Max    *float64 `json:"max"`
 	StdDev *float64 `json:"stddev"`
 	Count  int64    `json:"count"`
+	Basis  string   `json:"basis"`
+}
+
+// metricStatsSelect returns the aggregate SELECT list for a metric's stats and
+// the basis label describing it. Min/avg/max-shaped metrics get a dedicated
+// path so min/max reflect true observed extremes rather than sample averages;
+// rows holding a single qty sample (individual readings) fall back to it.
+func metricStatsSelect(metricName string) (string, string) {
+	if minAvgMaxMetrics[metricName] {
+		return `AVG(COALESCE(avg_val, qty)),
+		        MIN(COALESCE(min_val, qty)),
+		        MAX(COALESCE(max_val, qty)),
+		        STDDEV_POP(COALESCE(avg_val, qty)),
+		        COUNT(*)`, StatsBasisMinAvgMax
+	}
+	return `AVG(COALESCE(qty, avg_val)),
+		        MIN(COALESCE(qty, min_val)),
+		        MAX(COALESCE(qty, max_val)),
+		        STDDEV_POP(COALESCE(qty, avg_val)),
+		        COUNT(*)`, StatsBasisQty
+}
+
+// metricStatsRollupSQL builds the GetMetricStats query for ranges reaching
+// past the metric's rollup watermark. Parameters: $1 metric, $2 start, $3 end,
+// $4 user_id, $5 watermark. Statistics are over individual samples, with
+// each rollup bucket standing in for sample_count samples at its mean, so
+// the standard deviation before the watermark omits within-bucket spread.
+func metricStatsRollupSQL(priorities []string, cumulative bool) string {
+	mean := "v"
+	if cumulative {
+		mean = "v / NULLIF(n, 0)"
+	}
+	return fmt.Sprintf(
+		`%s, %s
+		SELECT SUM(m * n) / NULLIF(SUM(n), 0),
+		       MIN(lo),
+		       MAX(hi),
+		       SQRT(GREATEST(SUM(m * m * n) / NULLIF(SUM(n), 0) - POWER(SUM(m * n) / NULLIF(SUM(n), 0), 2), 0)),
+		       COALESCE(SUM(n), 0)
+		FROM (SELECT lo, hi, n, %s AS m FROM combined) s`,
+		dedupCTE(priorities, "$1", "$2", "$3", "$4"),
+		rollupSamplesSQL("", priorities, "$1", "$2", "$3", "$4", "$5"), mean)
 }
 
 // GetMetricStats returns aggregate statistics for a metric over a time range.
+// Ranges reaching back past the metric's retention watermark include
+// downsampled rollups, as in GetTimeSeries.
 func (db *DB) GetMetricStats(ctx context.Context, metricName string, start, end time.Time, userID int) (*MetricStats, error) {
+	if d := db.derived[metricName]; d != nil {
+		return db.getDerivedStats(ctx, d, start, end, userID)
+	}
 	priorities := db.ResolveSourcePriorityForMetric(ctx, userID, metricName)
-	cte := dedupCTE(priorities, "$1", "$2", "$3", "$4")
+	watermark, _, err := db.rollupWatermark(ctx, metricName)
+	if err != nil {
+		return nil, err
+	}
+	selectList, basis := metricStatsSelect(metricName)
 	query := fmt.Sprintf(
-		`%sSELECT AVG(COALESCE(qty, avg_val)),
-		        MIN(COALESCE(qty, min_val)),
-		        MAX(COALESCE(qty, max_val)),
-		        STDDEV_POP(COALESCE(qty, avg_val)),
-		        COUNT(*)
-		 FROM deduped WHERE rn = 1`, cte)
-	row := db.Pool.QueryRow(ctx, query, metricName, start, end, userID)
+		`%sSELECT %s
+		 FROM deduped WHERE rn = 1`, dedupCTE(priorities, "$1", "$2", "$3", "$4"), selectList)
+	args := []any{metricName, start, end, userID}
+	if watermark != nil && start.Before(*watermark) {
+		query = metricStatsRollupSQL(priorities, cumulativeMetrics[metricName])
+		args = append(args, *watermark)
+	}
+	row := db.Pool.QueryRow(ctx, query, args...)
 
-	stats := &MetricStats{Metric: metricName}
+	stats := &MetricStats{Metric: metricName, Basis: basis}
 	if err := row.Scan(&stats.Avg, &stats.Min, &stats.Max, &stats.StdDev, &stats.Count); err != nil {
 		return nil, fmt.Errorf("querying metric stats: %w", err)
 	}
 	return stats, nil
 }
 
+// MetricInfo describes what data exists for a metric: its time span, sample
+// count, and the units and sources it was recorded with.
+type MetricInfo struct {
+	Metric   string     `json:"metric"`
+	HasData  bool       `json:"has_data"`
+	Earliest *time.Time `json:"earliest"`
+	Latest   *time.Time `json:"latest"`
+	Count    int64      `json:"count"`
+	Units    []string   `json:"units"`
+	Sources  []string   `json:"sources"`
+}
+
+// metricInfoSQL aggregates raw samples and downsampled rollups together so a
+// metric's span and count survive retention deleting the raw rows. Only
+// rollups from before the earliest raw row count: later ones summarize raw
+// rows that were kept, which are already counted. Daily aggregates are
+// skipped on days with raw samples, as everywhere else.
+const metricInfoSQL = `WITH raw AS (
+		SELECT time, units, source
+		FROM health_metrics WHERE metric_name = $1 AND user_id = $2
+		  AND ` + uncoveredRowsSQL + `
+	)
+	SELECT MIN(time), MAX(time), COALESCE(SUM(n), 0),
+	       COALESCE(ARRAY_AGG(DISTINCT units ORDER BY units) FILTER (WHERE units <> ''), '{}'),
+	       COALESCE(ARRAY_AGG(DISTINCT source ORDER BY source) FILTER (WHERE source <> ''), '{}')
+	FROM (
+		SELECT time, units, source, 1 AS n
+		FROM raw
+		UNION ALL
+		SELECT time, units, source, sample_count
+		FROM health_metric_rollups
+		WHERE metric_name = $1 AND user_id = $2
+		  AND time < COALESCE((SELECT MIN(time) FROM raw), 'infinity')
+	) m`
+
+// GetMetricInfo reports whether a metric has any data for the user and over
+// what span, without fetching the data itself.
+func (db *DB) GetMetricInfo(ctx context.Context, metricName string, userID int) (*MetricInfo, error) {
+	info := &MetricInfo{Metric: metricName}
+	err := db.Pool.QueryRow(ctx, metricInfoSQL, metricName, userID).
+		Scan(&info.Earliest, &info.Latest, &info.Count, &info.Units, &info.Sources)
+	if err != nil {
+		return nil, fmt.Errorf("querying metric info: %w", err)
+	}
+	info.HasData = info.Count > 0
+	return info, nil
+}
+
 // CorrelationPoint is a time-aligned pair of metric values.
 type CorrelationPoint struct {
 	Time time.Time `json:"time"`
@@ -306,58 +633,86 @@ type CorrelationPoint struct {
 	Y    *float64  `json:"y"`
 }
 
-// CorrelationResult holds paired data and a Pearson correlation coefficient.
+// CorrelationResult holds paired data and a Pearson correlation coefficient
+// with its two-tailed p-value. PearsonR and PValue are nil with fewer pairs
+// than the configured minimum; LowConfidence is set then and whenever the
+// p-value is not below 0.05.
 type CorrelationResult struct {
-	Points   []CorrelationPoint `json:"points"`
-	PearsonR *float64           `json:"pearson_r"`
-	Count    int64              `json:"count"`
+	Points        []CorrelationPoint `json:"points"`
+	PearsonR      *float64           `json:"pearson_r"`
+	PValue        *float64           `json:"p_value"`
+	LowConfidence bool               `json:"low_confidence"`
+	Count         int64              `json:"count"`
 }
 
-// GetCorrelation joins two metrics on time buckets and computes their Pearson correlation.
-// Uses SUM for cumulative metrics, AVG for all others.
-func (db *DB) GetCorrelation(ctx context.Context, xMetric, yMetric string, start, end time.Time, bucket string, userID int) (*CorrelationResult, error) {
-	xAgg := "AVG"
-	if cumulativeMetrics[xMetric] {
-		xAgg = "SUM"
-	}
-	yAgg := "AVG"
-	if cumulativeMetrics[yMetric] {
-		yAgg = "SUM"
-	}
-	// For correlation, use the priority for the X metric's category.
-	priorities := db.ResolveSourcePriorityForMetric(ctx, userID, xMetric)
-	priorityExpr := sourcePriorityCaseSQL(priorities)
-	query := fmt.Sprintf(
-		`WITH x_deduped AS (
-			SELECT *, ROW_NUMBER() OVER (
-				PARTITION BY time_bucket('5 minutes', time)
-				ORDER BY %s
-			) AS rn
-			FROM health_metrics
-			WHERE metric_name = $2 AND time >= $4 AND time < $5 AND user_id = $6
-		), y_deduped AS (
+// correlationSQL builds the GetCorrelation query. Parameters: $1 bucket
+// size, $2 x metric, $3 y metric, $4 start, $5 end, $6 user_id. A non-empty
+// xWatermarkParam or yWatermarkParam names that metric's rollup watermark
+// parameter; its buckets before the watermark then come from rollups, as
+// in GetTimeSeries.
+func correlationSQL(xPriorities, yPriorities []string, xCumulative, yCumulative bool, xWatermarkParam, yWatermarkParam string) string {
+	series := func(name string, priorities []string, metricParam string, cumulative bool, watermarkParam string) string {
+		cte := fmt.Sprintf(
+			`%[1]s_deduped AS (
 			SELECT *, ROW_NUMBER() OVER (
 				PARTITION BY time_bucket('5 minutes', time)
-				ORDER BY %s
+				ORDER BY %[2]s
 			) AS rn
 			FROM health_metrics
-			WHERE metric_name = $3 AND time >= $4 AND time < $5 AND user_id = $6
-		), x AS (
+			WHERE metric_name = %[3]s AND time >= $4 AND time < $5 AND user_id = $6
+			  AND %[4]s
+		), `, name, sourcePriorityCaseSQL(priorities), metricParam, uncoveredRowsSQL)
+		if watermarkParam == "" {
+			agg := "AVG"
+			if cumulative {
+				agg = "SUM"
+			}
+			return cte + fmt.Sprintf(
+				`%[1]s AS (
 			SELECT time_bucket($1::interval, time) AS bucket,
-			       %s(COALESCE(qty, avg_val)) AS val
-			FROM x_deduped WHERE rn = 1
+			       %[2]s(COALESCE(qty, avg_val)) AS val
+			FROM %[1]s_deduped WHERE rn = 1
 			GROUP BY bucket
-		), y AS (
+		)`, name, agg)
+		}
+		return cte + rollupSamplesSQL(name+"_", priorities, metricParam, "$4", "$5", "$6", watermarkParam) + fmt.Sprintf(
+			`, %[1]s AS (
 			SELECT time_bucket($1::interval, time) AS bucket,
-			       %s(COALESCE(qty, avg_val)) AS val
-			FROM y_deduped WHERE rn = 1
+			       %[2]s AS val
+			FROM %[1]s_combined
 			GROUP BY bucket
-		)
+		)`, name, rollupValueSQL(cumulative))
+	}
+	return fmt.Sprintf(
+		`WITH %s, %s
 		SELECT x.bucket, x.val, y.val
 		FROM x JOIN y ON x.bucket = y.bucket
-		ORDER BY x.bucket ASC`, priorityExpr, priorityExpr, xAgg, yAgg)
-	rows, err := db.Pool.Query(ctx, query,
-		bucket, xMetric, yMetric, start, end, userID)
+		ORDER BY x.bucket ASC`,
+		series("x", xPriorities, "$2", xCumulative, xWatermarkParam),
+		series("y", yPriorities, "$3", yCumulative, yWatermarkParam))
+}
+
+// GetCorrelation joins two metrics on time buckets and computes their Pearson correlation.
+// Uses SUM for cumulative metrics, AVG for all others. Ranges reaching back
+// past either metric's retention watermark include its rollups.
+func (db *DB) GetCorrelation(ctx context.Context, xMetric, yMetric string, start, end time.Time, bucket string, userID int) (*CorrelationResult, error) {
+	// For correlation, use the priority for the X metric's category.
+	priorities := db.ResolveSourcePriorityForMetric(ctx, userID, xMetric)
+	args := []any{bucket, xMetric, yMetric, start, end, userID}
+	watermarkParams := make([]string, 2)
+	for i, metric := range []string{xMetric, yMetric} {
+		watermark, _, err := db.rollupWatermark(ctx, metric)
+		if err != nil {
+			return nil, err
+		}
+		if watermark != nil && start.Before(*watermark) {
+			args = append(args, *watermark)
+			watermarkParams[i] = fmt.Sprintf("$%d", len(args))
+		}
+	}
+	query := correlationSQL(priorities, priorities, cumulativeMetrics[xMetric], cumulativeMetrics[yMetric],
+		watermarkParams[0], watermarkParams[1])
+	rows, err := db.Pool.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying correlation: %w", err)
 	}
@@ -381,28 +736,15 @@ func (db *DB) GetCorrelation(ctx context.Context, xMetric, yMetric string, start
 	}
 
 	// Compute Pearson R
-	if len(points) >= 3 {
-		var sumX, sumY, sumXY, sumX2, sumY2 float64
-		var n float64
-		for _, p := range points {
-			if p.X != nil && p.Y != nil {
-				x, y := *p.X, *p.Y
-				sumX += x
-				sumY += y
-				sumXY += x * y
-				sumX2 += x * x
-				sumY2 += y * y
-				n++
-			}
-		}
-		if n >= 3 {
-			denom := (n*sumX2 - sumX*sumX) * (n*sumY2 - sumY*sumY)
-			if denom > 0 {
-				r := (n*sumXY - sumX*sumY) / math.Sqrt(denom)
-				result.PearsonR = &r
-			}
+	var xs, ys []float64
+	for _, p := range points {
+		if p.X != nil && p.Y != nil {
+			xs = append(xs, *p.X)
+			ys = append(ys, *p.Y)
 		}
 	}
+	result.PearsonR, result.PValue = correlate(xs, ys, db.correlationMinPoints())
+	result.LowConfidence = lowConfidence(result.PValue)
 
 	return result, nil
 }
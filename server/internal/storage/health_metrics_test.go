@@ -3,6 +3,7 @@ package storage
 import (
 	"strings"
 	"testing"
+	"time"
 )
 
 // TestSourcePriorityCaseSQL verifies that the SQL CASE expression correctly
@@ -34,6 +35,11 @@ func TestSourcePriorityCaseSQL(t *testing.T) {
 			priorities: []string{"Oura", "Apple Watch", ""},
 			wantSQL:    "CASE WHEN source LIKE 'Oura%' THEN 1 WHEN source LIKE 'Apple Watch%' THEN 2 WHEN source = '' THEN 3 ELSE 4 END",
 		},
+		{
+			name:       "quotes and wildcards match literally, as in SourceRank",
+			priorities: []string{"Ada's 100%_Watch"},
+			wantSQL:    `CASE WHEN source LIKE 'Ada''s 100\%\_Watch%' THEN 1 ELSE 2 END`,
+		},
 	}
 
 	for _, tt := range tests {
@@ -46,6 +52,53 @@ func TestSourcePriorityCaseSQL(t *testing.T) {
 	}
 }
 
+// TestSourceRank verifies Go-side ranking matches the SQL CASE semantics:
+// prefix matches for named sources, exact match for "", unlisted last.
+func TestSourceRank(t *testing.T) {
+	priorities := []string{"Apple Watch", "iPhone", ""}
+	tests := []struct {
+		source string
+		want   int
+	}{
+		{"Apple Watch Series 9", 1},
+		{"iPhone 15", 2},
+		{"", 3},
+		{"Oura", 4},
+	}
+	for _, tt := range tests {
+		if got := SourceRank(priorities, tt.source); got != tt.want {
+			t.Errorf("SourceRank(%q) = %d, want %d", tt.source, got, tt.want)
+		}
+	}
+	if got := SourceRank(nil, "Oura"); got != 1 {
+		t.Errorf("SourceRank with no priorities = %d, want 1", got)
+	}
+}
+
+// TestCanonicalSource verifies the highest-ranked source wins regardless of
+// order, ties go to prefer (or the first row without one), and no rows
+// yields -1.
+func TestCanonicalSource(t *testing.T) {
+	sources := []string{"Garmin", "iPhone", "Apple Watch Ultra", "Pixel"}
+	src := func(i int) string { return sources[i] }
+	if got := CanonicalSource([]string{"Apple Watch", "iPhone"}, len(sources), src, nil); got != 2 {
+		t.Errorf("got %d, want 2 (Apple Watch)", got)
+	}
+
+	// Neither Garmin nor Pixel is listed: prefer decides, else the first.
+	unlisted := []string{"Garmin", "Pixel"}
+	usrc := func(i int) string { return unlisted[i] }
+	if got := CanonicalSource([]string{"Oura"}, 2, usrc, nil); got != 0 {
+		t.Errorf("no prefer: got %d, want 0", got)
+	}
+	if got := CanonicalSource([]string{"Oura"}, 2, usrc, func(i, j int) bool { return i > j }); got != 1 {
+		t.Errorf("with prefer: got %d, want 1", got)
+	}
+	if got := CanonicalSource(nil, 0, src, nil); got != -1 {
+		t.Errorf("empty: got %d, want -1", got)
+	}
+}
+
 // TestDedupCTE verifies that the generated CTE has the correct structure:
 // a WITH clause using time_bucket, ROW_NUMBER, and the right parameter placeholders.
 func TestDedupCTE(t *testing.T) {
@@ -70,6 +123,33 @@ func TestDedupCTE(t *testing.T) {
 	}
 }
 
+// TestDedupCTESkipsCoveredDailyRows verifies every dedup CTE, including the
+// multi-metric one GetDailySums totals active_energy from, drops daily
+// aggregates on days that also have raw rows, so a day mixing raw samples
+// and a "Summarize Data" total isn't counted twice.
+func TestDedupCTESkipsCoveredDailyRows(t *testing.T) {
+	ctes := map[string]string{
+		"dedupCTE":            dedupCTE(nil, "$2", "$3", "$4", "$5"),
+		"dedupCTEMultiMetric": dedupCTEMultiMetric(nil, "$1", "$2"),
+		"bulkTimeSeriesSQL":   bulkTimeSeriesSQL(nil, nil, 0),
+	}
+	for name, cte := range ctes {
+		if !strings.Contains(cte, uncoveredRowsSQL) {
+			t.Errorf("%s does not filter covered daily rows:\n%s", name, cte)
+		}
+	}
+	for _, check := range []string{
+		"health_metrics.agg_level = 'raw' OR NOT EXISTS",
+		"r.agg_level = 'raw'",
+		"r.metric_name = health_metrics.metric_name",
+		"r.time < health_metrics.time + interval '1 day'",
+	} {
+		if !strings.Contains(uncoveredRowsSQL, check) {
+			t.Errorf("uncoveredRowsSQL missing %q", check)
+		}
+	}
+}
+
 // TestDedupCTEMultiMetric verifies the multi-metric CTE partitions by both
 // metric_name and time bucket, preventing cross-metric deduplication.
 func TestDedupCTEMultiMetric(t *testing.T) {
@@ -88,3 +168,268 @@ func TestDedupCTEMultiMetric(t *testing.T) {
 		}
 	}
 }
+
+// TestMetricStatsSelectHeartRate verifies heart_rate stats take min from
+// min_val and max from max_val (true observed extremes), not from avg_val,
+// while avg/stddev stay on avg_val; that rows with only a qty sample still
+// count; and that the basis is labelled.
+func TestMetricStatsSelectHeartRate(t *testing.T) {
+	sql, basis := metricStatsSelect("heart_rate")
+	if basis != StatsBasisMinAvgMax {
+		t.Errorf("basis = %q, want %q", basis, StatsBasisMinAvgMax)
+	}
+	for _, want := range []string{"MIN(COALESCE(min_val, qty))", "MAX(COALESCE(max_val, qty))", "AVG(COALESCE(avg_val, qty))", "STDDEV_POP(COALESCE(avg_val, qty))"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("heart_rate stats SQL missing %q:\n%s", want, sql)
+		}
+	}
+	// Rows holding only qty (individual readings) fall back to it, but
+	// aggregated rows keep their per-sample extremes: the reverse of the qty
+	// path's order.
+	if strings.Contains(sql, "COALESCE(qty,") {
+		t.Errorf("heart_rate stats SQL should prefer min/avg/max over qty:\n%s", sql)
+	}
+}
+
+// TestMetricStatsSelectQty verifies qty metrics keep the COALESCE path.
+func TestMetricStatsSelectQty(t *testing.T) {
+	sql, basis := metricStatsSelect("resting_heart_rate")
+	if basis != StatsBasisQty {
+		t.Errorf("basis = %q, want %q", basis, StatsBasisQty)
+	}
+	if !strings.Contains(sql, "MIN(COALESCE(qty, min_val))") {
+		t.Errorf("qty stats SQL unexpected:\n%s", sql)
+	}
+}
+
+// TestTimeSeriesSQLRollupUnion verifies ranges reaching past the retention
+// watermark read raw rows only from the watermark on and fill the older part
+// from rollups, weighting rollup means by their sample count.
+func TestTimeSeriesSQLRollupUnion(t *testing.T) {
+	plain := timeSeriesSQL(nil, false, false, nil, 0)
+	if strings.Contains(plain, "health_metric_rollups") || strings.Contains(plain, "$6") {
+		t.Errorf("raw-only query should not touch rollups:\n%s", plain)
+	}
+
+	sql := timeSeriesSQL([]string{"Apple Watch"}, false, true, nil, 0)
+	for _, want := range []string{
+		"FROM health_metric_rollups",
+		"time < LEAST($4, $6)",
+		"rn = 1 AND time >= $6",
+		"UNION ALL",
+		"SUM(v * n) / NULLIF(SUM(n), 0)",
+		"SUM(n) AS count",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("rollup query missing %q:\n%s", want, sql)
+		}
+	}
+
+	if sql := timeSeriesSQL(nil, true, true, nil, 0); !strings.Contains(sql, "SUM(v) AS avg_val") {
+		t.Errorf("cumulative rollup query should sum values:\n%s", sql)
+	}
+}
+
+// TestMetricStatsRollupSQL verifies stats over a range reaching past the
+// watermark read rollups before it, weighting each bucket by its sample count
+// and dividing cumulative sums back into per-sample means.
+func TestMetricStatsRollupSQL(t *testing.T) {
+	sql := metricStatsRollupSQL(nil, false)
+	for _, want := range []string{
+		"FROM health_metric_rollups",
+		"time < LEAST($3, $5)",
+		"rn = 1 AND time >= $5",
+		"SUM(m * n) / NULLIF(SUM(n), 0)",
+		"COALESCE(SUM(n), 0)",
+		"v AS m",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("stats rollup query missing %q:\n%s", want, sql)
+		}
+	}
+	if sql := metricStatsRollupSQL(nil, true); !strings.Contains(sql, "v / NULLIF(n, 0) AS m") {
+		t.Errorf("cumulative stats should use per-sample means:\n%s", sql)
+	}
+}
+
+// TestDailySumsSQLRollups verifies daily sums take each metric's raw rows
+// from its own watermark on and its rollups before it, and that the latest
+// day is found over both.
+func TestDailySumsSQLRollups(t *testing.T) {
+	cutoff, _ := dailySumsCutoff(DailySumsLatest, time.Now(), nil, 0, 4)
+	sql := dailySumsSQL(nil, "$2,$3", cutoff)
+	for _, want := range []string{
+		"FROM health_metric_rollups",
+		"metric_name IN ($2,$3)",
+		"WHERE w.metric_name = health_metric_rollups.metric_name), '-infinity')",
+		"WHERE w.metric_name = deduped.metric_name), '-infinity')",
+		"PARTITION BY metric_name, time",
+		"FROM combined",
+		"(SELECT time_bucket(interval '1 day', MAX(time)) FROM combined)",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("daily sums query missing %q:\n%s", want, sql)
+		}
+	}
+}
+
+// TestCorrelationSQLRollups verifies each side of a correlation reads its own
+// rollups only when it has a watermark parameter.
+func TestCorrelationSQLRollups(t *testing.T) {
+	plain := correlationSQL(nil, nil, false, true, "", "")
+	if strings.Contains(plain, "health_metric_rollups") {
+		t.Errorf("raw-only correlation should not touch rollups:\n%s", plain)
+	}
+	for _, want := range []string{"AVG(COALESCE(qty, avg_val))", "SUM(COALESCE(qty, avg_val))", "FROM x JOIN y"} {
+		if !strings.Contains(plain, want) {
+			t.Errorf("correlation query missing %q:\n%s", want, plain)
+		}
+	}
+
+	sql := correlationSQL(nil, nil, false, false, "", "$7")
+	for _, want := range []string{
+		"y_rolled AS",
+		"metric_name = $3 AND user_id = $6 AND time >= $4 AND time < LEAST($5, $7)",
+		"FROM y_deduped WHERE rn = 1 AND time >= $7",
+		"FROM y_combined",
+		"FROM x_deduped WHERE rn = 1\n",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("y-rollup correlation missing %q:\n%s", want, sql)
+		}
+	}
+	if strings.Contains(sql, "x_rolled") {
+		t.Errorf("x has no watermark but reads rollups:\n%s", sql)
+	}
+}
+
+// TestTimeBucketSQLDayStart verifies a day start hour offsets time_bucket
+// origins in both the raw and rollup time-series queries.
+func TestTimeBucketSQLDayStart(t *testing.T) {
+	if got := timeBucketSQL("$1::interval", "time", nil, 0); got != "time_bucket($1::interval, time)" {
+		t.Errorf("no offset = %q", got)
+	}
+	want := "time_bucket($1::interval, time, interval '4 hours')"
+	if got := timeBucketSQL("$1::interval", "time", nil, 4); got != want {
+		t.Errorf("4h offset = %q, want %q", got, want)
+	}
+	for _, rollup := range []bool{false, true} {
+		if sql := timeSeriesSQL(nil, false, rollup, nil, 4); !strings.Contains(sql, want) {
+			t.Errorf("rollup=%v query missing offset bucket:\n%s", rollup, sql)
+		}
+	}
+}
+
+// TestDayStart verifies that with days starting at 04:00, 1am data belongs
+// to the previous day while 5am data starts a new one.
+func TestDayStart(t *testing.T) {
+	oneAM := time.Date(2025, 3, 2, 1, 0, 0, 0, time.UTC)
+	if got, want := dayStart(oneAM, nil, 4), time.Date(2025, 3, 1, 4, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("01:00 day start = %v, want %v", got, want)
+	}
+	fiveAM := time.Date(2025, 3, 2, 5, 0, 0, 0, time.UTC)
+	if got, want := dayStart(fiveAM, nil, 4), time.Date(2025, 3, 2, 4, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("05:00 day start = %v, want %v", got, want)
+	}
+	if got, want := dayStart(oneAM, nil, 0), time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("midnight day start = %v, want %v", got, want)
+	}
+}
+
+// TestMetricInfoSQLIncludesRollups verifies metric info counts rollup samples
+// too, so a metric whose old raw rows were deleted doesn't look newer than it
+// is, but only those before the raw data, so kept raw rows aren't counted
+// twice. Daily aggregates covered by raw samples are skipped too.
+func TestMetricInfoSQLIncludesRollups(t *testing.T) {
+	for _, want := range []string{"FROM health_metrics", "FROM health_metric_rollups", "SUM(n)", "sample_count",
+		"time < COALESCE((SELECT MIN(time) FROM raw), 'infinity')", uncoveredRowsSQL} {
+		if !strings.Contains(metricInfoSQL, want) {
+			t.Errorf("metric info SQL missing %q:\n%s", want, metricInfoSQL)
+		}
+	}
+}
+
+// TestDownsampleBucket verifies the finest bucket that keeps a range under
+// the point cap is chosen, stepping up as the range crosses the threshold,
+// and that the coarsest bucket is the fallback.
+func TestDownsampleBucket(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		days      int
+		maxPoints int
+		want      string
+	}{
+		{30, 50000, "1 minute"},  // 43,200 minutes fits
+		{60, 50000, "5 minutes"}, // 86,400 minutes doesn't
+		{365, 50000, "15 minutes"},
+		{365, 1000, "1 day"},
+		{3650, 10, "1 week"}, // nothing fits
+	}
+	for _, tt := range tests {
+		if got := DownsampleBucket(start, start.AddDate(0, 0, tt.days), tt.maxPoints); got != tt.want {
+			t.Errorf("%d days, max %d: bucket = %q, want %q", tt.days, tt.maxPoints, got, tt.want)
+		}
+	}
+}
+
+// TestDailySumsCutoff verifies the latest mode finds the day in SQL while the
+// today mode cuts off at the local day start in the user's zone.
+func TestDailySumsCutoff(t *testing.T) {
+	now := time.Date(2025, 3, 2, 3, 30, 0, 0, time.UTC)
+
+	clause, args := dailySumsCutoff(DailySumsLatest, now, nil, 0, 5)
+	if !strings.Contains(clause, "MAX(time)") || len(args) != 0 {
+		t.Errorf("latest = %q %v", clause, args)
+	}
+
+	clause, args = dailySumsCutoff(DailySumsToday, now, nil, 0, 5)
+	if clause != "time >= $5" || len(args) != 1 || !args[0].(time.Time).Equal(time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("today UTC = %q %v", clause, args)
+	}
+
+	// With a 04:00 day start, 03:30 still belongs to March 1st; the latest
+	// data day is bucketed with the same offset.
+	_, args = dailySumsCutoff(DailySumsToday, now, nil, 4, 5)
+	if want := time.Date(2025, 3, 1, 4, 0, 0, 0, time.UTC); !args[0].(time.Time).Equal(want) {
+		t.Errorf("today 4am start = %v, want %v", args[0], want)
+	}
+	if clause, _ = dailySumsCutoff(DailySumsLatest, now, nil, 4, 5); !strings.Contains(clause, "interval '4 hours'") {
+		t.Errorf("latest 4am start = %q", clause)
+	}
+
+	// 03:30 UTC is still March 1st in New York.
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata unavailable")
+	}
+	_, args = dailySumsCutoff(DailySumsToday, now, ny, 0, 5)
+	if want := time.Date(2025, 3, 1, 0, 0, 0, 0, ny); !args[0].(time.Time).Equal(want) {
+		t.Errorf("today New York = %v, want %v", args[0], want)
+	}
+	// The latest data day is bucketed in the same zone as today's start.
+	if clause, _ = dailySumsCutoff(DailySumsLatest, now, ny, 0, 5); !strings.Contains(clause, "'America/New_York'") {
+		t.Errorf("latest New York = %q", clause)
+	}
+}
+
+// TestTimeBucketSQLTimezone verifies buckets follow the configured zone, with
+// the day start hour as an offset from local midnight.
+func TestTimeBucketSQLTimezone(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata unavailable")
+	}
+	if got, want := timeBucketSQL("$1::interval", "time", ny, 0), "time_bucket($1::interval, time, 'America/New_York')"; got != want {
+		t.Errorf("zone = %q, want %q", got, want)
+	}
+	want := `time_bucket($1::interval, time, 'America/New_York', "offset" => interval '4 hours')`
+	if got := timeBucketSQL("$1::interval", "time", ny, 4); got != want {
+		t.Errorf("zone + 4h = %q, want %q", got, want)
+	}
+	if sql := timeSeriesSQL(nil, false, true, ny, 4); !strings.Contains(sql, want) {
+		t.Errorf("time series missing zoned bucket:\n%s", sql)
+	}
+	if got := timeBucketSQL("$1::interval", "time", time.UTC, 0); got != "time_bucket($1::interval, time)" {
+		t.Errorf("UTC = %q", got)
+	}
+}
@@ -0,0 +1,85 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// hrRecoveryTolerance is how far a recovery sample may sit from the 1- or
+// 2-minute mark and still count for it.
+const hrRecoveryTolerance = 20 * time.Second
+
+// HRRecovery is the heart-rate drop after a workout ends, a marker of
+// cardiovascular fitness: larger drops mean faster recovery. A field is nil
+// when no sample lands near its mark.
+type HRRecovery struct {
+	EndBPM     float64  `json:"end_bpm"`
+	OneMinBPM  *float64 `json:"one_min_bpm"`
+	TwoMinBPM  *float64 `json:"two_min_bpm"`
+	OneMinDrop *float64 `json:"one_min_drop"`
+	TwoMinDrop *float64 `json:"two_min_drop"`
+}
+
+// ComputeHRRecovery derives 1- and 2-minute HR recovery for a workout ending
+// at end. The reference is the last in-workout sample, falling back to the
+// first recovery sample. Returns nil without recovery samples or a reference.
+func ComputeHRRecovery(end time.Time, workoutHR, recovery []models.WorkoutHRRow) *HRRecovery {
+	if len(recovery) == 0 {
+		return nil
+	}
+
+	var ref *float64
+	for i := len(workoutHR) - 1; i >= 0; i-- {
+		if !workoutHR[i].Time.After(end) {
+			ref = hrRowBPM(workoutHR[i])
+			break
+		}
+	}
+	if ref == nil {
+		ref = hrRowBPM(recovery[0])
+	}
+	if ref == nil {
+		return nil
+	}
+
+	out := &HRRecovery{EndBPM: *ref}
+	out.OneMinBPM = hrNear(recovery, end.Add(time.Minute))
+	out.TwoMinBPM = hrNear(recovery, end.Add(2*time.Minute))
+	if out.OneMinBPM != nil {
+		d := *ref - *out.OneMinBPM
+		out.OneMinDrop = &d
+	}
+	if out.TwoMinBPM != nil {
+		d := *ref - *out.TwoMinBPM
+		out.TwoMinDrop = &d
+	}
+	return out
+}
+
+// hrNear returns the BPM of the sample closest to t within
+// hrRecoveryTolerance, or nil if there is none.
+func hrNear(rows []models.WorkoutHRRow, t time.Time) *float64 {
+	var best *float64
+	bestGap := hrRecoveryTolerance + 1
+	for _, r := range rows {
+		gap := r.Time.Sub(t).Abs()
+		if gap < bestGap {
+			if bpm := hrRowBPM(r); bpm != nil {
+				best, bestGap = bpm, gap
+			}
+		}
+	}
+	return best
+}
+
+// hrRowBPM returns a sample's average BPM, or its max when HAE sent only that.
+func hrRowBPM(r models.WorkoutHRRow) *float64 {
+	if r.AvgBPM != nil && *r.AvgBPM > 0 {
+		return r.AvgBPM
+	}
+	if r.MaxBPM != nil && *r.MaxBPM > 0 {
+		return r.MaxBPM
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+func hrRow(t time.Time, avg float64) models.WorkoutHRRow {
+	return models.WorkoutHRRow{Time: t, AvgBPM: &avg}
+}
+
+// TestComputeHRRecovery verifies the 1- and 2-minute drops are measured from
+// the last in-workout sample using the recovery samples nearest each mark.
+func TestComputeHRRecovery(t *testing.T) {
+	end := time.Date(2025, 4, 9, 18, 0, 0, 0, time.UTC)
+	workout := []models.WorkoutHRRow{hrRow(end.Add(-time.Minute), 160), hrRow(end, 172)}
+	recovery := []models.WorkoutHRRow{
+		hrRow(end.Add(10*time.Second), 170),
+		hrRow(end.Add(55*time.Second), 150),
+		hrRow(end.Add(2*time.Minute+5*time.Second), 128),
+	}
+
+	got := ComputeHRRecovery(end, workout, recovery)
+	if got == nil {
+		t.Fatal("expected recovery summary")
+	}
+	if got.EndBPM != 172 {
+		t.Errorf("end = %v, want 172", got.EndBPM)
+	}
+	if got.OneMinDrop == nil || *got.OneMinDrop != 22 {
+		t.Errorf("1-min drop = %v, want 22", got.OneMinDrop)
+	}
+	if got.TwoMinDrop == nil || *got.TwoMinDrop != 44 {
+		t.Errorf("2-min drop = %v, want 44", got.TwoMinDrop)
+	}
+}
+
+// TestComputeHRRecoveryGaps verifies a missing mark stays nil, the first
+// recovery sample is the reference without in-workout HR, and no recovery
+// samples means no summary.
+func TestComputeHRRecoveryGaps(t *testing.T) {
+	end := time.Date(2025, 4, 9, 18, 0, 0, 0, time.UTC)
+	recovery := []models.WorkoutHRRow{hrRow(end, 165), hrRow(end.Add(time.Minute), 140)}
+
+	got := ComputeHRRecovery(end, nil, recovery)
+	if got == nil || got.EndBPM != 165 || got.OneMinDrop == nil || *got.OneMinDrop != 25 {
+		t.Fatalf("got %+v, want end 165 and 1-min drop 25", got)
+	}
+	if got.TwoMinBPM != nil || got.TwoMinDrop != nil {
+		t.Errorf("2-min mark has no sample, got %v", *got.TwoMinBPM)
+	}
+
+	if ComputeHRRecovery(end, recovery, nil) != nil {
+		t.Error("expected nil without recovery samples")
+	}
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// IllnessThresholds control DetectIllnessSignals. A signal triggers when the
+// day's value moves past its baseline by the threshold in the unhealthy
+// direction.
+type IllnessThresholds struct {
+	BaselineDays     int     // trailing days averaged into the baseline
+	MinBaselineDays  int     // days with data required for a usable baseline
+	RestingHRRiseBPM float64 // resting HR above baseline
+	HRVDropPct       float64 // HRV below baseline, percent
+	RespRateRise     float64 // respiratory rate above baseline, breaths/min
+	WristTempRiseC   float64 // sleeping wrist temperature above baseline, °C
+	MinSignals       int     // triggered signals needed to flag likely illness
+}
+
+// DefaultIllnessThresholds are used until SetIllnessThresholds is called.
+var DefaultIllnessThresholds = IllnessThresholds{
+	BaselineDays:     28,
+	MinBaselineDays:  14,
+	RestingHRRiseBPM: 5,
+	HRVDropPct:       20,
+	RespRateRise:     1,
+	WristTempRiseC:   0.5,
+	MinSignals:       2,
+}
+
+// SetIllnessThresholds replaces the thresholds used by DetectIllnessSignals.
+func (db *DB) SetIllnessThresholds(t IllnessThresholds) {
+	db.illness = t
+}
+
+// IllnessSignal is one vital compared with its trailing baseline. Status is
+// "triggered", "normal", or "insufficient_data" when the day has no value or
+// the baseline has too few days.
+type IllnessSignal struct {
+	Metric       string   `json:"metric"`
+	Value        *float64 `json:"value"`
+	Baseline     *float64 `json:"baseline"`
+	BaselineDays int      `json:"baseline_days"`
+	Change       *float64 `json:"change"`
+	Threshold    float64  `json:"threshold"`
+	Unit         string   `json:"unit"`
+	Status       string   `json:"status"`
+}
+
+// IllnessAssessment is the composite verdict for one day. Status is
+// "likely_illness" when at least MinSignals signals trigger, "watch" for a
+// single triggered signal, "normal", or "insufficient_data" when fewer than
+// two signals could be evaluated.
+type IllnessAssessment struct {
+	Date      string          `json:"date"`
+	Status    string          `json:"status"`
+	Flagged   bool            `json:"flagged"`
+	Triggered []string        `json:"triggered"`
+	Signals   []IllnessSignal `json:"signals"`
+}
+
+// illnessVital describes how one metric signals illness.
+type illnessVital struct {
+	metric string
+	unit   string
+	// change returns the unhealthy-direction change from baseline (positive
+	// = worse) and the threshold it's compared against.
+	change func(value, baseline float64, t IllnessThresholds) (float64, float64)
+}
+
+var illnessVitals = []illnessVital{
+	{"resting_heart_rate", "bpm", func(v, b float64, t IllnessThresholds) (float64, float64) {
+		return v - b, t.RestingHRRiseBPM
+	}},
+	{"heart_rate_variability", "%", func(v, b float64, t IllnessThresholds) (float64, float64) {
+		if b == 0 {
+			return 0, t.HRVDropPct
+		}
+		return (b - v) / b * 100, t.HRVDropPct
+	}},
+	{"respiratory_rate", "breaths/min", func(v, b float64, t IllnessThresholds) (float64, float64) {
+		return v - b, t.RespRateRise
+	}},
+	{"apple_sleeping_wrist_temperature", "°C", func(v, b float64, t IllnessThresholds) (float64, float64) {
+		return v - b, t.WristTempRiseC
+	}},
+}
+
+// DetectIllnessSignals compares the day's resting HR, HRV, respiratory rate
+// and sleeping wrist temperature with their trailing baselines and flags
+// likely illness when enough of them move the wrong way together.
+func (db *DB) DetectIllnessSignals(ctx context.Context, date time.Time, userID int) (*IllnessAssessment, error) {
+	t := db.illness
+	if t.BaselineDays == 0 {
+		t = DefaultIllnessThresholds
+	}
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	start := day.AddDate(0, 0, -t.BaselineDays)
+
+	series := make(map[string][]TimeSeriesPoint, len(illnessVitals))
+	for _, v := range illnessVitals {
+		points, err := db.GetTimeSeries(ctx, v.metric, start, day.AddDate(0, 0, 1), "1 day", userID)
+		if err != nil {
+			return nil, fmt.Errorf("querying %s for illness signals: %w", v.metric, err)
+		}
+		series[v.metric] = points
+	}
+	return assessIllness(day, series, t), nil
+}
+
+// assessIllness evaluates each vital's value on day against the mean of its
+// daily values before day.
+func assessIllness(day time.Time, series map[string][]TimeSeriesPoint, t IllnessThresholds) *IllnessAssessment {
+	a := &IllnessAssessment{Date: day.Format("2006-01-02"), Triggered: []string{}}
+	evaluated := 0
+	for _, v := range illnessVitals {
+		sig := IllnessSignal{Metric: v.metric, Unit: v.unit, Status: "insufficient_data"}
+		var sum float64
+		for _, p := range series[v.metric] {
+			if p.Avg == nil {
+				continue
+			}
+			pDay := p.Time.UTC().Truncate(24 * time.Hour)
+			switch {
+			case pDay.Equal(day):
+				val := *p.Avg
+				sig.Value = &val
+			case pDay.Before(day):
+				sum += *p.Avg
+				sig.BaselineDays++
+			}
+		}
+		if sig.BaselineDays > 0 {
+			b := sum / float64(sig.BaselineDays)
+			sig.Baseline = &b
+		}
+		_, sig.Threshold = v.change(0, 0, t)
+		if sig.Value != nil && sig.Baseline != nil && sig.BaselineDays >= t.MinBaselineDays {
+			change, _ := v.change(*sig.Value, *sig.Baseline, t)
+			sig.Change = &change
+			sig.Status = "normal"
+			if change >= sig.Threshold {
+				sig.Status = "triggered"
+				a.Triggered = append(a.Triggered, v.metric)
+			}
+			evaluated++
+		}
+		a.Signals = append(a.Signals, sig)
+	}
+
+	switch {
+	case len(a.Triggered) >= t.MinSignals:
+		a.Status, a.Flagged = "likely_illness", true
+	case evaluated < 2:
+		a.Status = "insufficient_data"
+	case len(a.Triggered) > 0:
+		a.Status = "watch"
+	default:
+		a.Status = "normal"
+	}
+	return a
+}
@@ -0,0 +1,70 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// illnessSeries builds daily points ending at day: n baseline days at base
+// followed by today on day itself.
+func illnessSeries(day time.Time, n int, base, today float64) []TimeSeriesPoint {
+	var points []TimeSeriesPoint
+	for i := n; i >= 1; i-- {
+		v := base
+		points = append(points, TimeSeriesPoint{Time: day.AddDate(0, 0, -i), Avg: &v, Count: 1})
+	}
+	t := today
+	return append(points, TimeSeriesPoint{Time: day, Avg: &t, Count: 1})
+}
+
+// TestAssessIllness verifies the composite status across triggered, normal
+// and insufficient-data combinations.
+func TestAssessIllness(t *testing.T) {
+	day := time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)
+	th := DefaultIllnessThresholds
+
+	sick := map[string][]TimeSeriesPoint{
+		"resting_heart_rate":               illnessSeries(day, 20, 55, 62),
+		"heart_rate_variability":           illnessSeries(day, 20, 60, 42),
+		"respiratory_rate":                 illnessSeries(day, 20, 14, 14.3),
+		"apple_sleeping_wrist_temperature": illnessSeries(day, 20, 0, 0.1),
+	}
+	a := assessIllness(day, sick, th)
+	if a.Status != "likely_illness" || !a.Flagged {
+		t.Fatalf("status = %q flagged = %v, want likely_illness", a.Status, a.Flagged)
+	}
+	if len(a.Triggered) != 2 || a.Triggered[0] != "resting_heart_rate" || a.Triggered[1] != "heart_rate_variability" {
+		t.Errorf("triggered = %v", a.Triggered)
+	}
+	if c := a.Signals[1].Change; c == nil || *c != 30 {
+		t.Errorf("hrv change = %v, want 30", c)
+	}
+
+	watch := map[string][]TimeSeriesPoint{
+		"resting_heart_rate": illnessSeries(day, 20, 55, 61),
+		"respiratory_rate":   illnessSeries(day, 20, 14, 14),
+	}
+	if a := assessIllness(day, watch, th); a.Status != "watch" || a.Flagged {
+		t.Errorf("status = %q, want watch", a.Status)
+	}
+
+	normal := map[string][]TimeSeriesPoint{
+		"resting_heart_rate": illnessSeries(day, 20, 55, 56),
+		"respiratory_rate":   illnessSeries(day, 20, 14, 14.2),
+	}
+	if a := assessIllness(day, normal, th); a.Status != "normal" {
+		t.Errorf("status = %q, want normal", a.Status)
+	}
+
+	short := map[string][]TimeSeriesPoint{
+		"resting_heart_rate": illnessSeries(day, 5, 55, 70),
+		"respiratory_rate":   illnessSeries(day, 20, 14, 14),
+	}
+	a = assessIllness(day, short, th)
+	if a.Status != "insufficient_data" {
+		t.Errorf("status = %q, want insufficient_data", a.Status)
+	}
+	if a.Signals[0].Status != "insufficient_data" || a.Signals[0].BaselineDays != 5 {
+		t.Errorf("resting hr signal = %+v", a.Signals[0])
+	}
+}
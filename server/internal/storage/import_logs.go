@@ -3,8 +3,11 @@ package storage
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/jackc/pgx/v5"
 )
 
 // ImportLog represents a single import operation's outcome.
@@ -95,3 +98,25 @@ func (db *DB) QueryImportLogs(ctx context.Context, userID, limit int) ([]ImportL
 	}
 	return result, rows.Err()
 }
+
+// GetImportLog returns one of the user's import logs, or nil if no log with
+// that id belongs to the user.
+func (db *DB) GetImportLog(ctx context.Context, id int64, userID int) (*ImportLog, error) {
+	var l ImportLog
+	err := db.Pool.QueryRow(ctx,
+		`SELECT id, user_id, created_at, source, status, metrics_received, metrics_inserted,
+		 workouts_received, workouts_inserted, sleep_sessions, sets_received, sets_inserted,
+		 duration_ms, error_message, metadata
+		 FROM import_logs
+		 WHERE id = $1 AND user_id = $2`,
+		id, userID).Scan(&l.ID, &l.UserID, &l.CreatedAt, &l.Source, &l.Status,
+		&l.MetricsReceived, &l.MetricsInserted, &l.WorkoutsReceived, &l.WorkoutsInserted,
+		&l.SleepSessions, &l.SetsReceived, &l.SetsInserted, &l.DurationMs, &l.ErrorMessage, &l.Metadata)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying import log %d: %w", id, err)
+	}
+	return &l, nil
+}
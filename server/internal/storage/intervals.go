@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+	"github.com/google/uuid"
+)
+
+// DefaultIntervalMinDuration is the shortest interval DetectIntervals keeps;
+// shorter segments are absorbed by their neighbors so HR noise around the
+// threshold doesn't split an effort.
+const DefaultIntervalMinDuration = 30 * time.Second
+
+// IntervalOptions tune DetectIntervals. ThresholdBPM 0 uses the midpoint of
+// the workout's lowest and highest HR samples; MinDuration 0 uses
+// DefaultIntervalMinDuration.
+type IntervalOptions struct {
+	ThresholdBPM float64
+	MinDuration  time.Duration
+}
+
+// WorkoutInterval is one high- or low-effort segment of a workout. AvgSpeed
+// (m/s) is nil when the workout has no route speed samples in the segment.
+type WorkoutInterval struct {
+	Effort      string    `json:"effort"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	DurationSec float64   `json:"duration_sec"`
+	AvgBPM      float64   `json:"avg_bpm"`
+	MaxBPM      float64   `json:"max_bpm"`
+	AvgSpeed    *float64  `json:"avg_speed"`
+}
+
+// WorkoutIntervals is the segmentation of one workout.
+type WorkoutIntervals struct {
+	ThresholdBPM float64           `json:"threshold_bpm"`
+	Intervals    []WorkoutInterval `json:"intervals"`
+}
+
+// DetectIntervals splits a workout's heart-rate stream into alternating
+// high- and low-effort intervals around a threshold.
+func (db *DB) DetectIntervals(ctx context.Context, workoutID uuid.UUID, userID int, opts IntervalOptions) (*WorkoutIntervals, error) {
+	detail, err := db.GetWorkout(ctx, workoutID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return segmentIntervals(detail.HeartRateData, detail.RouteData, opts), nil
+}
+
+// hrSample is a usable HR reading and how long it stands for.
+type hrSample struct {
+	time time.Time
+	bpm  float64
+	dur  time.Duration
+}
+
+// segmentIntervals classifies each HR sample against the threshold, groups
+// consecutive samples of equal effort, and folds segments shorter than the
+// minimum duration into their neighbors.
+func segmentIntervals(hr []models.WorkoutHRRow, route []models.WorkoutRouteRow, opts IntervalOptions) *WorkoutIntervals {
+	var samples []hrSample
+	for _, r := range hr {
+		if bpm := hrRowBPM(r); bpm != nil {
+			samples = append(samples, hrSample{time: r.Time, bpm: *bpm})
+		}
+	}
+	out := &WorkoutIntervals{ThresholdBPM: opts.ThresholdBPM, Intervals: []WorkoutInterval{}}
+	if len(samples) == 0 {
+		return out
+	}
+
+	// Each sample lasts until the next; the last one repeats the previous gap.
+	for i := range samples {
+		switch {
+		case i+1 < len(samples):
+			samples[i].dur = samples[i+1].time.Sub(samples[i].time)
+		case i > 0:
+			samples[i].dur = samples[i-1].dur
+		}
+	}
+	if out.ThresholdBPM == 0 {
+		lo, hi := samples[0].bpm, samples[0].bpm
+		for _, s := range samples {
+			lo, hi = min(lo, s.bpm), max(hi, s.bpm)
+		}
+		out.ThresholdBPM = (lo + hi) / 2
+	}
+	minDur := opts.MinDuration
+	if minDur == 0 {
+		minDur = DefaultIntervalMinDuration
+	}
+
+	type segment struct {
+		high     bool
+		from, to int // sample index range [from, to)
+		dur      time.Duration
+	}
+	var raw []segment
+	for i, s := range samples {
+		high := s.bpm >= out.ThresholdBPM
+		if n := len(raw); n > 0 && raw[n-1].high == high {
+			raw[n-1].to, raw[n-1].dur = i+1, raw[n-1].dur+s.dur
+			continue
+		}
+		raw = append(raw, segment{high: high, from: i, to: i + 1, dur: s.dur})
+	}
+
+	// Repeatedly flip the shortest too-short segment so it merges into its
+	// neighbors; shortest first keeps a brief spike from swallowing a real
+	// recovery next to it.
+	segs := raw
+	for len(segs) > 1 {
+		shortest := -1
+		for i, seg := range segs {
+			if seg.dur < minDur && (shortest < 0 || seg.dur < segs[shortest].dur) {
+				shortest = i
+			}
+		}
+		if shortest < 0 {
+			break
+		}
+		segs[shortest].high = !segs[shortest].high
+		merged := segs[:1]
+		for _, seg := range segs[1:] {
+			if n := len(merged); merged[n-1].high == seg.high {
+				merged[n-1].to, merged[n-1].dur = seg.to, merged[n-1].dur+seg.dur
+				continue
+			}
+			merged = append(merged, seg)
+		}
+		segs = merged
+	}
+
+	for _, seg := range segs {
+		iv := WorkoutInterval{
+			Effort:      "low",
+			Start:       samples[seg.from].time,
+			End:         samples[seg.to-1].time.Add(samples[seg.to-1].dur),
+			DurationSec: seg.dur.Seconds(),
+		}
+		if seg.high {
+			iv.Effort = "high"
+		}
+		var sum float64
+		for _, s := range samples[seg.from:seg.to] {
+			sum += s.bpm
+			iv.MaxBPM = max(iv.MaxBPM, s.bpm)
+		}
+		iv.AvgBPM = sum / float64(seg.to-seg.from)
+		iv.AvgSpeed = avgRouteSpeed(route, iv.Start, iv.End)
+		out.Intervals = append(out.Intervals, iv)
+	}
+	return out
+}
+
+// avgRouteSpeed averages route speed samples in [start, end).
+func avgRouteSpeed(route []models.WorkoutRouteRow, start, end time.Time) *float64 {
+	var sum float64
+	var n int
+	for _, r := range route {
+		if r.Speed != nil && *r.Speed >= 0 && !r.Time.Before(start) && r.Time.Before(end) {
+			sum += *r.Speed
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	avg := sum / float64(n)
+	return &avg
+}
@@ -0,0 +1,82 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// hrStream builds one HR sample every 10 seconds from t0 with the given bpm.
+func hrStream(t0 time.Time, bpms ...float64) []models.WorkoutHRRow {
+	rows := make([]models.WorkoutHRRow, len(bpms))
+	for i, b := range bpms {
+		bpm := b
+		rows[i] = models.WorkoutHRRow{Time: t0.Add(time.Duration(i) * 10 * time.Second), AvgBPM: &bpm}
+	}
+	return rows
+}
+
+// repeat returns n copies of v.
+func repeat(v float64, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = v
+	}
+	return out
+}
+
+// TestSegmentIntervals verifies a synthetic 2x(60s hard / 60s easy) session
+// splits into four intervals around the auto threshold, and that a 10s spike
+// inside an easy block is absorbed rather than creating its own interval.
+func TestSegmentIntervals(t *testing.T) {
+	t0 := time.Date(2026, 4, 1, 7, 0, 0, 0, time.UTC)
+	var bpms []float64
+	bpms = append(bpms, repeat(170, 6)...)
+	bpms = append(bpms, repeat(120, 2)...)
+	bpms = append(bpms, 165) // spike
+	bpms = append(bpms, repeat(120, 3)...)
+	bpms = append(bpms, repeat(172, 6)...)
+	bpms = append(bpms, repeat(118, 6)...)
+
+	speed := 4.0
+	route := []models.WorkoutRouteRow{{Time: t0.Add(20 * time.Second), Speed: &speed}}
+
+	got := segmentIntervals(hrStream(t0, bpms...), route, IntervalOptions{})
+	if got.ThresholdBPM != 145 {
+		t.Errorf("threshold = %v, want 145", got.ThresholdBPM)
+	}
+	want := []string{"high", "low", "high", "low"}
+	if len(got.Intervals) != len(want) {
+		t.Fatalf("got %d intervals, want %d: %+v", len(got.Intervals), len(want), got.Intervals)
+	}
+	for i, iv := range got.Intervals {
+		if iv.Effort != want[i] || iv.DurationSec != 60 {
+			t.Errorf("interval %d = %s %.0fs, want %s 60s", i, iv.Effort, iv.DurationSec, want[i])
+		}
+	}
+	if iv := got.Intervals[0]; iv.AvgBPM != 170 || iv.MaxBPM != 170 || iv.AvgSpeed == nil || *iv.AvgSpeed != 4 {
+		t.Errorf("first interval = %+v", iv)
+	}
+	if got.Intervals[1].MaxBPM != 165 || got.Intervals[1].AvgSpeed != nil {
+		t.Errorf("easy interval with spike = %+v", got.Intervals[1])
+	}
+}
+
+// TestSegmentIntervalsThreshold verifies an explicit threshold and minimum
+// duration are honored, and that no HR data yields no intervals.
+func TestSegmentIntervalsThreshold(t *testing.T) {
+	t0 := time.Date(2026, 4, 1, 7, 0, 0, 0, time.UTC)
+	hr := hrStream(t0, 150, 150, 130, 150, 150)
+
+	got := segmentIntervals(hr, nil, IntervalOptions{ThresholdBPM: 140, MinDuration: 5 * time.Second})
+	if got.ThresholdBPM != 140 || len(got.Intervals) != 3 {
+		t.Fatalf("got %+v, want 3 intervals at 140", got)
+	}
+	if got := segmentIntervals(hr, nil, IntervalOptions{ThresholdBPM: 160}); len(got.Intervals) != 1 || got.Intervals[0].Effort != "low" {
+		t.Errorf("all-below threshold = %+v", got.Intervals)
+	}
+	if got := segmentIntervals(nil, nil, IntervalOptions{}); len(got.Intervals) != 0 {
+		t.Errorf("no HR = %+v", got.Intervals)
+	}
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Bases an estimated max heart rate can come from.
+const (
+	// MaxHRBasisProfile: the Tanaka formula (208 − 0.7 × age) on the
+	// profile's birth date.
+	MaxHRBasisProfile = "profile"
+	// MaxHRBasisObserved: the highest heart_rate reading over the trailing
+	// year plus observedMaxHRBuffer.
+	MaxHRBasisObserved = "observed"
+)
+
+const (
+	// observedMaxHRBuffer is added to the observed maximum, since few people
+	// reach their true max outside a dedicated test.
+	observedMaxHRBuffer = 3
+	// maxPlausibleHR drops optical sensor spikes from the observed maximum.
+	maxPlausibleHR = 230
+)
+
+// hrZoneBounds are the lower bounds of zones 2–5 as fractions of max HR.
+// Zone 1 covers everything below zone 2.
+var hrZoneBounds = []float64{0.6, 0.7, 0.8, 0.9}
+
+// HRZone is a heart rate band in bpm. Max is nil for the top zone.
+type HRZone struct {
+	Zone int      `json:"zone"`
+	Min  float64  `json:"min_bpm"`
+	Max  *float64 `json:"max_bpm"`
+}
+
+// EstimatedMaxHR is a max heart rate estimate, how it was obtained, and the
+// zones derived from it.
+type EstimatedMaxHR struct {
+	MaxHR float64  `json:"max_hr"`
+	Basis string   `json:"basis"`
+	Zones []HRZone `json:"zones"`
+}
+
+// GetEstimatedMaxHR estimates the user's max heart rate from their profile
+// age, falling back to the heart_rate data of the trailing year when no birth
+// date is saved. Returns nil when neither is available.
+func (db *DB) GetEstimatedMaxHR(ctx context.Context, userID int) (*EstimatedMaxHR, error) {
+	profile, err := db.GetUserProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if _, ok := profile.Age(now); ok {
+		return estimateMaxHR(profile, nil, now), nil
+	}
+
+	var observed *float64
+	err = db.Pool.QueryRow(ctx,
+		`SELECT MAX(COALESCE(max_val, qty))
+		 FROM health_metrics
+		 WHERE metric_name = 'heart_rate' AND user_id = $1 AND time >= $2
+		   AND COALESCE(max_val, qty) <= $3
+		   AND `+uncoveredRowsSQL,
+		userID, now.AddDate(-1, 0, 0), maxPlausibleHR).Scan(&observed)
+	if err != nil {
+		return nil, fmt.Errorf("querying observed max heart rate: %w", err)
+	}
+	return estimateMaxHR(profile, observed, now), nil
+}
+
+// estimateMaxHR prefers the profile's age-based estimate and otherwise uses
+// the observed maximum plus a buffer. Returns nil without either.
+func estimateMaxHR(profile *UserProfile, observed *float64, now time.Time) *EstimatedMaxHR {
+	var est EstimatedMaxHR
+	if age, ok := profile.Age(now); ok {
+		est = EstimatedMaxHR{MaxHR: math.Round(208 - 0.7*float64(age)), Basis: MaxHRBasisProfile}
+	} else if observed != nil && *observed > 0 {
+		est = EstimatedMaxHR{MaxHR: math.Round(*observed) + observedMaxHRBuffer, Basis: MaxHRBasisObserved}
+	} else {
+		return nil
+	}
+	est.Zones = hrZones(est.MaxHR)
+	return &est
+}
+
+// hrZones splits 0–maxHR into five zones at hrZoneBounds, rounded to whole
+// bpm.
+func hrZones(maxHR float64) []HRZone {
+	zones := make([]HRZone, 0, len(hrZoneBounds)+1)
+	lo := 0.0
+	for i, f := range hrZoneBounds {
+		hi := math.Round(maxHR * f)
+		zones = append(zones, HRZone{Zone: i + 1, Min: lo, Max: &hi})
+		lo = hi
+	}
+	return append(zones, HRZone{Zone: len(hrZoneBounds) + 1, Min: lo})
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// TestEstimateMaxHR verifies the observed maximum plus buffer is used without
+// a birth date, and that a profile age overrides it.
+func TestEstimateMaxHR(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	observed := 187.4
+
+	got := estimateMaxHR(&UserProfile{Sex: "female"}, &observed, now)
+	if got == nil || got.Basis != MaxHRBasisObserved || got.MaxHR != 190 {
+		t.Fatalf("observed estimate = %+v, want 190 bpm from observed", got)
+	}
+
+	birth := time.Date(1985, 7, 1, 0, 0, 0, 0, time.UTC) // 39 on now
+	got = estimateMaxHR(&UserProfile{BirthDate: &birth}, &observed, now)
+	if got == nil || got.Basis != MaxHRBasisProfile || got.MaxHR != 181 {
+		t.Fatalf("profile estimate = %+v, want 181 bpm from profile", got)
+	}
+
+	if got := estimateMaxHR(nil, nil, now); got != nil {
+		t.Errorf("estimate without data = %+v, want nil", got)
+	}
+}
+
+// TestHRZones verifies zones are contiguous, start at 0, and leave the top
+// zone open-ended.
+func TestHRZones(t *testing.T) {
+	zones := hrZones(190)
+	wantMax := []float64{114, 133, 152, 171}
+	if len(zones) != 5 {
+		t.Fatalf("got %d zones, want 5", len(zones))
+	}
+	if zones[0].Min != 0 || zones[4].Max != nil || zones[4].Min != 171 {
+		t.Errorf("zone ends = %+v .. %+v", zones[0], zones[4])
+	}
+	for i, want := range wantMax {
+		if zones[i].Max == nil || *zones[i].Max != want {
+			t.Errorf("zone %d max = %v, want %v", i+1, zones[i].Max, want)
+		}
+		if zones[i+1].Min != want {
+			t.Errorf("zone %d min = %v, want %v", i+2, zones[i+1].Min, want)
+		}
+	}
+}
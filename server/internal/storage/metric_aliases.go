@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrAliasIsMetric is returned when an alias would shadow an allowlisted
+// metric name.
+var ErrAliasIsMetric = errors.New("alias is already an allowlisted metric name")
+
+// MetricAlias maps an incoming metric name onto a canonical allowlist name.
+type MetricAlias struct {
+	Alias      string `json:"alias"`
+	MetricName string `json:"metric_name"`
+}
+
+// MetricAliases returns alias → canonical metric name. It shares the
+// allowlist cache TTL and is cleared by InvalidateAllowlist.
+func (db *DB) MetricAliases(ctx context.Context) (map[string]string, error) {
+	db.allowlistMu.RLock()
+	if db.aliasCache != nil && time.Since(db.aliasFetchedAt) < db.allowlistCacheTTL {
+		aliases := db.aliasCache
+		db.allowlistMu.RUnlock()
+		return aliases, nil
+	}
+	gen := db.allowlistGen
+	db.allowlistMu.RUnlock()
+
+	list, err := db.ListMetricAliases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	aliases := make(map[string]string, len(list))
+	for _, a := range list {
+		aliases[a.Alias] = a.MetricName
+	}
+
+	db.allowlistMu.Lock()
+	if db.allowlistGen == gen {
+		db.aliasCache = aliases
+		db.aliasFetchedAt = time.Now()
+	}
+	db.allowlistMu.Unlock()
+	return aliases, nil
+}
+
+// CanonicalMetricName returns the metric name aliases maps name onto, or name
+// itself when it has no alias.
+func CanonicalMetricName(aliases map[string]string, name string) string {
+	if canonical, ok := aliases[name]; ok {
+		return canonical
+	}
+	return name
+}
+
+// ListMetricAliases returns every alias, ordered by canonical name.
+func (db *DB) ListMetricAliases(ctx context.Context) ([]MetricAlias, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT alias, metric_name FROM metric_aliases ORDER BY metric_name, alias`)
+	if err != nil {
+		return nil, fmt.Errorf("querying metric aliases: %w", err)
+	}
+	defer rows.Close()
+
+	aliases := []MetricAlias{}
+	for rows.Next() {
+		var a MetricAlias
+		if err := rows.Scan(&a.Alias, &a.MetricName); err != nil {
+			return nil, fmt.Errorf("scanning metric alias: %w", err)
+		}
+		aliases = append(aliases, a)
+	}
+	return aliases, rows.Err()
+}
+
+// UpsertMetricAlias maps alias onto metricName, replacing any earlier target.
+// Returns ErrMetricNotAllowlisted when metricName has no allowlist row and
+// ErrAliasIsMetric when alias is itself an allowlisted metric.
+func (db *DB) UpsertMetricAlias(ctx context.Context, alias, metricName string) error {
+	var targetExists, aliasIsMetric bool
+	err := db.Pool.QueryRow(ctx,
+		`SELECT EXISTS (SELECT 1 FROM metric_allowlist WHERE metric_name = $2),
+		        EXISTS (SELECT 1 FROM metric_allowlist WHERE metric_name = $1)`,
+		alias, metricName).Scan(&targetExists, &aliasIsMetric)
+	if err != nil {
+		return fmt.Errorf("checking metric alias: %w", err)
+	}
+	if !targetExists {
+		return ErrMetricNotAllowlisted
+	}
+	if aliasIsMetric {
+		return ErrAliasIsMetric
+	}
+
+	_, err = db.Pool.Exec(ctx,
+		`INSERT INTO metric_aliases (alias, metric_name) VALUES ($1, $2)
+		 ON CONFLICT (alias) DO UPDATE SET metric_name = EXCLUDED.metric_name`,
+		alias, metricName)
+	if err != nil {
+		return fmt.Errorf("upserting metric alias: %w", err)
+	}
+	db.InvalidateAllowlist()
+	return nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// MetricExportPage bounds one page of a raw metric export. Through is the
+// timestamp of the page's last row; it is nil when the rest of the range fits
+// in the page. More reports whether rows remain after Through, in which case
+// Through is the continue token for the next page.
+type MetricExportPage struct {
+	Through *time.Time
+	More    bool
+}
+
+// exportWhere builds the filter for a metric export page: rows of metric $1
+// for user $2 in [$3, $4), after the previous page's last timestamp when
+// after is set and up to this page's last timestamp when through is set.
+// Placeholders for after and through follow the four fixed ones.
+func exportWhere(after, through *time.Time) (string, []any) {
+	where := "metric_name = $1 AND user_id = $2 AND time >= $3 AND time < $4"
+	var args []any
+	if after != nil {
+		args = append(args, *after)
+		where += fmt.Sprintf(" AND time > $%d", 4+len(args))
+	}
+	if through != nil {
+		args = append(args, *through)
+		where += fmt.Sprintf(" AND time <= $%d", 4+len(args))
+	}
+	return where, args
+}
+
+// PlanMetricExportPage finds where a page of at most limit rows starting
+// after the given timestamp ends. Pages end on a timestamp boundary (see
+// pageCutoffSQL) so rows sharing a timestamp, one per source, are never split.
+func (db *DB) PlanMetricExportPage(ctx context.Context, metricName string, start, end time.Time, after *time.Time, limit, userID int) (MetricExportPage, error) {
+	var page MetricExportPage
+	where, extra := exportWhere(after, nil)
+	args := append([]any{metricName, userID, start, end}, extra...)
+	args = append(args, limit-1)
+
+	var through time.Time
+	var more bool
+	err := db.Pool.QueryRow(ctx, pageCutoffSQL("health_metrics", "time", where, len(args))+`
+		SELECT c.time, EXISTS (
+			SELECT 1 FROM health_metrics
+			WHERE metric_name = $1 AND user_id = $2 AND time > c.time AND time < $4
+		) FROM cutoff c`, args...).Scan(&through, &more)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return page, nil
+		}
+		return page, fmt.Errorf("planning metric export page: %w", err)
+	}
+	if more {
+		page.Through, page.More = &through, true
+	}
+	return page, nil
+}
+
+// StreamHealthMetrics calls fn for each raw row of a metric export page in
+// time order without buffering the page; rows are read straight off the
+// connection as fn consumes them. It returns the number of rows passed to fn.
+func (db *DB) StreamHealthMetrics(ctx context.Context, metricName string, start, end time.Time, after, through *time.Time, userID int, fn func(models.HealthMetricRow) error) (int, error) {
+	where, extra := exportWhere(after, through)
+	args := append([]any{metricName, userID, start, end}, extra...)
+	rows, err := db.Pool.Query(ctx,
+		`SELECT time, user_id, metric_name, source, units, qty, min_val, avg_val, max_val, systolic, diastolic, source_uuid
+		 FROM health_metrics WHERE `+where+`
+		 ORDER BY time, source`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("querying metric export: %w", err)
+	}
+	defer rows.Close()
+
+	n := 0
+	for rows.Next() {
+		var r models.HealthMetricRow
+		if err := rows.Scan(&r.Time, &r.UserID, &r.MetricName, &r.Source, &r.Units,
+			&r.Qty, &r.MinVal, &r.AvgVal, &r.MaxVal, &r.Systolic, &r.Diastolic, &r.SourceUUID); err != nil {
+			return n, fmt.Errorf("scanning metric export row: %w", err)
+		}
+		if err := fn(r); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestExportWhereResumesAfterToken verifies the first page is bounded by its
+// last timestamp and the second page resumes strictly after it, so no row is
+// repeated or skipped at the boundary.
+func TestExportWhereResumesAfterToken(t *testing.T) {
+	through := time.Date(2025, 3, 1, 12, 0, 0, 123000, time.UTC)
+
+	first, args := exportWhere(nil, &through)
+	if !strings.HasSuffix(first, "AND time <= $5") || len(args) != 1 || args[0] != through {
+		t.Errorf("first page = %q %v", first, args)
+	}
+
+	second, args := exportWhere(&through, nil)
+	if !strings.HasSuffix(second, "AND time > $5") || len(args) != 1 || args[0] != through {
+		t.Errorf("second page = %q %v", second, args)
+	}
+	if strings.Contains(second, "<=") {
+		t.Errorf("second page %q should not be bounded", second)
+	}
+
+	next := through.Add(time.Hour)
+	middle, args := exportWhere(&through, &next)
+	if !strings.HasSuffix(middle, "AND time > $5 AND time <= $6") || len(args) != 2 {
+		t.Errorf("middle page = %q %v", middle, args)
+	}
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/claude/freereps/internal/models"
+	"github.com/google/uuid"
+)
+
+// MovingSpeedThresholdMPS is the speed above which a route segment counts as
+// moving. It sits below a slow walk but above typical GPS drift while
+// standing still.
+const MovingSpeedThresholdMPS = 0.5
+
+// movingTimeSec sums the time between consecutive route points where the
+// athlete was moving. A segment's speed is the later point's reported speed
+// when present, otherwise the distance covered over the time taken. points
+// must be in time order.
+func movingTimeSec(points []models.WorkoutRouteRow) float64 {
+	var moving float64
+	for i := 1; i < len(points); i++ {
+		prev, cur := points[i-1], points[i]
+		dt := cur.Time.Sub(prev.Time).Seconds()
+		if dt <= 0 {
+			continue
+		}
+		speed := haversineM(prev.Latitude, prev.Longitude, cur.Latitude, cur.Longitude) / dt
+		if cur.Speed != nil && *cur.Speed >= 0 {
+			speed = *cur.Speed
+		}
+		if speed > MovingSpeedThresholdMPS {
+			moving += dt
+		}
+	}
+	return moving
+}
+
+// PaceDurationSec is the duration pace and speed should be computed from:
+// the moving time when the workout has one, otherwise the elapsed duration.
+func PaceDurationSec(w models.WorkoutRow) float64 {
+	if w.MovingTimeSec != nil && *w.MovingTimeSec > 0 {
+		return *w.MovingTimeSec
+	}
+	return w.DurationSec
+}
+
+// UpdateMovingTime recomputes a workout's moving time from its stored route
+// and saves it. It returns false when the workout has fewer than two route
+// points, leaving moving_time_sec unchanged.
+func (db *DB) UpdateMovingTime(ctx context.Context, workoutID uuid.UUID, userID int) (bool, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT time, latitude, longitude, speed
+		 FROM workout_routes
+		 WHERE workout_id = $1 AND user_id = $2
+		 ORDER BY time`,
+		workoutID, userID)
+	if err != nil {
+		return false, fmt.Errorf("querying workout route: %w", err)
+	}
+	var points []models.WorkoutRouteRow
+	for rows.Next() {
+		var p models.WorkoutRouteRow
+		if err := rows.Scan(&p.Time, &p.Latitude, &p.Longitude, &p.Speed); err != nil {
+			rows.Close()
+			return false, fmt.Errorf("scanning route point: %w", err)
+		}
+		points = append(points, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return false, fmt.Errorf("iterating route points: %w", err)
+	}
+	if len(points) < 2 {
+		return false, nil
+	}
+
+	if _, err := db.Pool.Exec(ctx,
+		`UPDATE workouts SET moving_time_sec = $3 WHERE id = $1 AND user_id = $2`,
+		workoutID, userID, movingTimeSec(points)); err != nil {
+		return false, fmt.Errorf("updating moving time: %w", err)
+	}
+	return true, nil
+}
+
+// RecomputeMovingTimes recomputes moving time for every workout of the user
+// that has a route and returns how many were updated.
+func (db *DB) RecomputeMovingTimes(ctx context.Context, userID int) (int, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT DISTINCT workout_id FROM workout_routes WHERE user_id = $1`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("querying routed workouts: %w", err)
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scanning workout id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("iterating routed workouts: %w", err)
+	}
+
+	updated := 0
+	for _, id := range ids {
+		ok, err := db.UpdateMovingTime(ctx, id, userID)
+		if err != nil {
+			return updated, err
+		}
+		if ok {
+			updated++
+		}
+	}
+	return updated, nil
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// TestMovingTimeSecPause verifies a stationary pause in the middle of a track
+// is left out of moving time, so moving time is less than elapsed time.
+func TestMovingTimeSecPause(t *testing.T) {
+	t0 := time.Date(2025, 5, 1, 7, 0, 0, 0, time.UTC)
+	// ~0.0001° latitude ≈ 11 m; 10 s per step ≈ 1.1 m/s.
+	pt := func(sec int, lat float64) models.WorkoutRouteRow {
+		return models.WorkoutRouteRow{Time: t0.Add(time.Duration(sec) * time.Second), Latitude: lat, Longitude: 13.4}
+	}
+	points := []models.WorkoutRouteRow{
+		pt(0, 52.5000), pt(10, 52.5001), pt(20, 52.5002),
+		// 5-minute stop at a crossing with ~1 m of GPS drift.
+		pt(320, 52.50021),
+		pt(330, 52.50031), pt(340, 52.50041),
+	}
+
+	moving := movingTimeSec(points)
+	elapsed := points[len(points)-1].Time.Sub(points[0].Time).Seconds()
+	if moving >= elapsed {
+		t.Fatalf("moving = %v, want less than elapsed %v", moving, elapsed)
+	}
+	if math.Abs(moving-40) > 0.01 {
+		t.Errorf("moving = %v, want 40", moving)
+	}
+}
+
+// TestMovingTimeSecReportedSpeed verifies a point's reported speed wins over
+// the distance-derived one and that a single point has no moving time.
+func TestMovingTimeSecReportedSpeed(t *testing.T) {
+	t0 := time.Date(2025, 5, 1, 7, 0, 0, 0, time.UTC)
+	still, fast := 0.0, 3.0
+	points := []models.WorkoutRouteRow{
+		{Time: t0, Latitude: 52.5, Longitude: 13.4},
+		{Time: t0.Add(10 * time.Second), Latitude: 52.5001, Longitude: 13.4, Speed: &still},
+		{Time: t0.Add(20 * time.Second), Latitude: 52.5001, Longitude: 13.4, Speed: &fast},
+	}
+	if got := movingTimeSec(points); got != 10 {
+		t.Errorf("moving = %v, want 10", got)
+	}
+	if got := movingTimeSec(points[:1]); got != 0 {
+		t.Errorf("single point moving = %v, want 0", got)
+	}
+}
+
+// TestPaceDurationSec verifies moving time is preferred over elapsed duration
+// when recorded.
+func TestPaceDurationSec(t *testing.T) {
+	moving := 1500.0
+	if got := PaceDurationSec(models.WorkoutRow{DurationSec: 1800, MovingTimeSec: &moving}); got != 1500 {
+		t.Errorf("with moving time = %v, want 1500", got)
+	}
+	if got := PaceDurationSec(models.WorkoutRow{DurationSec: 1800}); got != 1800 {
+		t.Errorf("without moving time = %v, want 1800", got)
+	}
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// muscleGroupKeywords maps exercise-name fragments to the primary muscle
+// group trained. Earlier entries win, so specific names ("leg curl", "romanian")
+// come before generic ones ("curl", "deadlift").
+var muscleGroupKeywords = []struct{ keyword, group string }{
+	{"calf", "calves"},
+	{"leg curl", "hamstrings"},
+	{"romanian", "hamstrings"},
+	{"rdl", "hamstrings"},
+	{"stiff", "hamstrings"},
+	{"good morning", "hamstrings"},
+	{"hip thrust", "glutes"},
+	{"glute", "glutes"},
+	{"leg extension", "quads"},
+	{"leg press", "quads"},
+	{"squat", "quads"},
+	{"lunge", "quads"},
+	{"step up", "quads"},
+	{"face pull", "shoulders"},
+	{"lateral raise", "shoulders"},
+	{"rear delt", "shoulders"},
+	{"overhead press", "shoulders"},
+	{"shoulder press", "shoulders"},
+	{"military press", "shoulders"},
+	{"tricep", "triceps"},
+	{"pushdown", "triceps"},
+	{"skull", "triceps"},
+	{"close grip bench", "triceps"},
+	{"bench", "chest"},
+	{"chest", "chest"},
+	{"fly", "chest"},
+	{"push-up", "chest"},
+	{"push up", "chest"},
+	{"dip", "chest"},
+	{"pull-up", "back"},
+	{"pull up", "back"},
+	{"chin-up", "back"},
+	{"chin up", "back"},
+	{"pulldown", "back"},
+	{"row", "back"},
+	{"deadlift", "back"},
+	{"curl", "biceps"},
+	{"crunch", "abs"},
+	{"plank", "abs"},
+	{"sit-up", "abs"},
+	{"leg raise", "abs"},
+}
+
+// MuscleGroupOf returns the primary muscle group for an exercise name, or
+// "other" when no keyword matches.
+func MuscleGroupOf(exercise string) string {
+	name := strings.ToLower(exercise)
+	for _, k := range muscleGroupKeywords {
+		if strings.Contains(name, k.keyword) {
+			return k.group
+		}
+	}
+	return "other"
+}
+
+// VolumeLandmark bounds productive weekly volume for a muscle group, in
+// working sets: MEV is the minimum effective volume, MRV the maximum
+// recoverable volume.
+type VolumeLandmark struct {
+	MEV int `json:"mev"`
+	MRV int `json:"mrv"`
+}
+
+// DefaultVolumeLandmarks are typical intermediate-lifter landmarks, used for
+// any group SetVolumeLandmarks doesn't override.
+var DefaultVolumeLandmarks = map[string]VolumeLandmark{
+	"chest":      {MEV: 8, MRV: 22},
+	"back":       {MEV: 10, MRV: 25},
+	"shoulders":  {MEV: 8, MRV: 26},
+	"quads":      {MEV: 8, MRV: 20},
+	"hamstrings": {MEV: 6, MRV: 20},
+	"glutes":     {MEV: 0, MRV: 16},
+	"biceps":     {MEV: 8, MRV: 26},
+	"triceps":    {MEV: 6, MRV: 18},
+	"calves":     {MEV: 8, MRV: 20},
+	"abs":        {MEV: 0, MRV: 25},
+}
+
+// SetVolumeLandmarks overrides the default landmarks per muscle group.
+func (db *DB) SetVolumeLandmarks(overrides map[string]VolumeLandmark) {
+	db.volumeLandmarks = overrides
+}
+
+// landmark returns a group's landmarks: the user's profile overrides win
+// over the configured ones, which win over the defaults.
+func (db *DB) landmark(group string, profile map[string]VolumeLandmark) (VolumeLandmark, bool) {
+	if l, ok := profile[group]; ok {
+		return l, true
+	}
+	if l, ok := db.volumeLandmarks[group]; ok {
+		return l, true
+	}
+	l, ok := DefaultVolumeLandmarks[group]
+	return l, ok
+}
+
+// MuscleGroupVolume is the average weekly working-set count for one muscle
+// group and how it compares to the group's landmarks. Status is "below_mev",
+// "productive", "above_mrv", or "unrated" for groups without landmarks.
+type MuscleGroupVolume struct {
+	Group      string          `json:"group"`
+	TotalSets  int             `json:"total_sets"`
+	WeeklySets float64         `json:"weekly_sets"`
+	Landmarks  *VolumeLandmark `json:"landmarks"`
+	Status     string          `json:"status"`
+	Exercises  []string        `json:"exercises"`
+}
+
+// volumeStatus rates weekly sets against a landmark.
+func volumeStatus(weekly float64, l VolumeLandmark) string {
+	switch {
+	case weekly < float64(l.MEV):
+		return "below_mev"
+	case weekly > float64(l.MRV):
+		return "above_mrv"
+	default:
+		return "productive"
+	}
+}
+
+// rangeWeeks returns the length of [start, end) in weeks, at least one so a
+// partial week isn't inflated into a weekly rate.
+func rangeWeeks(start, end time.Time) float64 {
+	return math.Max(end.Sub(start).Hours()/(24*7), 1)
+}
+
+// GetMuscleGroupVolume counts working sets per muscle group between start and
+// end and rates each group's weekly average against its volume landmarks,
+// including any overrides saved in the user's profile. Every landmarked
+// group is listed, including those with no sets.
+func (db *DB) GetMuscleGroupVolume(ctx context.Context, start, end time.Time, userID int) ([]MuscleGroupVolume, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT exercise_name, COUNT(*)::int
+		 FROM workout_sets
+		 WHERE session_date >= $1 AND session_date < $2
+		   AND user_id = $3
+		   AND NOT is_warmup
+		 GROUP BY exercise_name`,
+		start, end, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying muscle group volume: %w", err)
+	}
+	defer rows.Close()
+
+	setsByExercise := make(map[string]int)
+	for rows.Next() {
+		var name string
+		var sets int
+		if err := rows.Scan(&name, &sets); err != nil {
+			return nil, fmt.Errorf("scanning muscle group volume: %w", err)
+		}
+		setsByExercise[name] = sets
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	profile, err := db.GetUserProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var overrides map[string]VolumeLandmark
+	if profile != nil {
+		overrides = profile.VolumeLandmarks
+	}
+	return db.muscleGroupVolume(setsByExercise, rangeWeeks(start, end), overrides), nil
+}
+
+// muscleGroupVolume groups per-exercise set counts by muscle group and rates
+// them, with profile the user's landmark overrides; split out of
+// GetMuscleGroupVolume for testing.
+func (db *DB) muscleGroupVolume(setsByExercise map[string]int, weeks float64, profile map[string]VolumeLandmark) []MuscleGroupVolume {
+	byGroup := make(map[string]*MuscleGroupVolume)
+	get := func(group string) *MuscleGroupVolume {
+		if v, ok := byGroup[group]; ok {
+			return v
+		}
+		v := &MuscleGroupVolume{Group: group, Exercises: []string{}}
+		byGroup[group] = v
+		return v
+	}
+	for group := range DefaultVolumeLandmarks {
+		get(group)
+	}
+	for group := range db.volumeLandmarks {
+		get(group)
+	}
+	for group := range profile {
+		get(group)
+	}
+	for name, sets := range setsByExercise {
+		v := get(MuscleGroupOf(name))
+		v.TotalSets += sets
+		v.Exercises = append(v.Exercises, name)
+	}
+
+	out := make([]MuscleGroupVolume, 0, len(byGroup))
+	for group, v := range byGroup {
+		v.WeeklySets = math.Round(float64(v.TotalSets)/weeks*10) / 10
+		v.Status = "unrated"
+		if l, ok := db.landmark(group, profile); ok {
+			v.Landmarks = &l
+			v.Status = volumeStatus(v.WeeklySets, l)
+		}
+		sort.Strings(v.Exercises)
+		out = append(out, *v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Group < out[j].Group })
+	return out
+}
@@ -0,0 +1,65 @@
+package storage
+
+import "testing"
+
+// TestMuscleGroupOf verifies specific exercise names win over generic
+// keywords (a leg curl is hamstrings, not biceps) and unknown names fall
+// into "other".
+func TestMuscleGroupOf(t *testing.T) {
+	tests := map[string]string{
+		"Barbell Bench Press":    "chest",
+		"Close Grip Bench Press": "triceps",
+		"Lying Leg Curl":         "hamstrings",
+		"EZ Bar Curl":            "biceps",
+		"Romanian Deadlift":      "hamstrings",
+		"Conventional Deadlift":  "back",
+		"Bulgarian Split Squat":  "quads",
+		"Seated Calf Raise":      "calves",
+		"Dumbbell Lateral Raise": "shoulders",
+		"Cable Tricep Pushdown":  "triceps",
+		"Lat Pulldown":           "back",
+		"Farmer's Walk":          "other",
+	}
+	for name, want := range tests {
+		if got := MuscleGroupOf(name); got != want {
+			t.Errorf("MuscleGroupOf(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+// TestMuscleGroupVolumeStatus verifies weekly sets are averaged over the
+// range and rated against default, configured and profile landmarks, with
+// the profile winning, and that landmarked groups without sets are reported
+// below MEV.
+func TestMuscleGroupVolumeStatus(t *testing.T) {
+	db := &DB{}
+	db.SetVolumeLandmarks(map[string]VolumeLandmark{"biceps": {MEV: 2, MRV: 4}, "chest": {MEV: 20, MRV: 30}})
+	profile := map[string]VolumeLandmark{"chest": {MEV: 8, MRV: 22}, "forearms": {MEV: 2, MRV: 10}}
+
+	got := db.muscleGroupVolume(map[string]int{
+		"Bench Press":   20, // 10/week: productive for chest (8–22)
+		"Incline Fly":   4,
+		"Barbell Curl":  12, // 6/week: above the overridden biceps MRV of 4
+		"Farmer's Walk": 6,
+	}, 2, profile)
+
+	byGroup := make(map[string]MuscleGroupVolume)
+	for _, v := range got {
+		byGroup[v.Group] = v
+	}
+	if c := byGroup["chest"]; c.WeeklySets != 12 || c.Status != "productive" || len(c.Exercises) != 2 {
+		t.Errorf("chest = %+v, want 12 sets/week, productive, 2 exercises", c)
+	}
+	if b := byGroup["biceps"]; b.Status != "above_mrv" || b.Landmarks.MRV != 4 {
+		t.Errorf("biceps = %+v, want above_mrv against override", b)
+	}
+	if q := byGroup["quads"]; q.TotalSets != 0 || q.Status != "below_mev" {
+		t.Errorf("quads = %+v, want 0 sets, below_mev", q)
+	}
+	if f := byGroup["forearms"]; f.Status != "below_mev" || f.Landmarks == nil || f.Landmarks.MRV != 10 {
+		t.Errorf("forearms = %+v, want below_mev against the profile landmark", f)
+	}
+	if o := byGroup["other"]; o.Status != "unrated" || o.Landmarks != nil {
+		t.Errorf("other = %+v, want unrated", o)
+	}
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Labels comparing a value against a population norm range.
+const (
+	NormBelow   = "below"
+	NormWithin  = "within"
+	NormAbove   = "above"
+	NormUnknown = "unknown"
+)
+
+// normRow is the typical range [Low, High] for people aged MinAge–MaxAge
+// (inclusive). An empty Sex applies to everyone.
+type normRow struct {
+	MinAge, MaxAge int
+	Sex            string
+	Low, High      float64
+}
+
+// normTable is one metric's published reference ranges.
+type normTable struct {
+	Unit   string
+	Source string
+	Rows   []normRow
+}
+
+// populationNorms holds static reference ranges for the headline recovery and
+// fitness metrics, keyed by the metric names used in weekly reports. Values
+// are rounded from the cited publications; they describe typical healthy
+// adults, not clinical cut-offs.
+var populationNorms = map[string]normTable{
+	// Ostchega Y, Porter KS, Hughes J, Dillon CF, Nwankwo T. Resting pulse
+	// rate reference data for children, adolescents, and adults: United
+	// States, 1999–2008. National Health Statistics Reports no. 41, 2011.
+	// Approximate 25th–75th percentiles for adults.
+	"resting_heart_rate": {
+		Unit:   "bpm",
+		Source: "Ostchega et al., NHSR no. 41 (2011), adult 25th–75th percentile",
+		Rows: []normRow{
+			{18, 120, "male", 63, 77},
+			{18, 120, "female", 66, 80},
+		},
+	},
+	// Nunan D, Sandercock GRH, Brodie DA. A quantitative systematic review of
+	// normal values for short-term heart rate variability in healthy adults.
+	// Pacing Clin Electrophysiol 2010;33(11):1407–1417. SDNN (the measure
+	// Apple Health records) mean 50 ± 16 ms; the range is mean ± 1 SD.
+	"heart_rate_variability": {
+		Unit:   "ms",
+		Source: "Nunan et al., PACE 33:1407 (2010), short-term SDNN mean ± 1 SD",
+		Rows: []normRow{
+			{18, 120, "", 34, 66},
+		},
+	},
+	// Kaminsky LA, Arena R, Myers J. Reference standards for cardiorespiratory
+	// fitness measured with cardiopulmonary exercise testing: data from the
+	// Fitness Registry and the Importance of Exercise National Database
+	// (FRIEND). Mayo Clin Proc 2015;90(11):1515–1523. Treadmill 25th–75th
+	// percentiles, mL/kg/min.
+	"vo2_max": {
+		Unit:   "mL/kg/min",
+		Source: "Kaminsky et al., Mayo Clin Proc 90:1515 (2015), FRIEND treadmill 25th–75th percentile",
+		Rows: []normRow{
+			{20, 29, "male", 40.1, 55.2},
+			{30, 39, "male", 35.9, 49.2},
+			{40, 49, "male", 31.9, 45.0},
+			{50, 59, "male", 27.1, 39.7},
+			{60, 69, "male", 23.7, 34.5},
+			{70, 79, "male", 20.4, 30.4},
+			{20, 29, "female", 30.5, 44.7},
+			{30, 39, "female", 25.3, 36.1},
+			{40, 49, "female", 22.1, 32.4},
+			{50, 59, "female", 19.9, 27.6},
+			{60, 69, "female", 17.2, 23.8},
+			{70, 79, "female", 15.6, 20.8},
+		},
+	},
+	// Hirshkowitz M et al. National Sleep Foundation's sleep time duration
+	// recommendations: methodology and results summary. Sleep Health
+	// 2015;1(1):40–43. Recommended nightly sleep in hours.
+	"sleep": {
+		Unit:   "h",
+		Source: "Hirshkowitz et al., Sleep Health 1:40 (2015), recommended sleep duration",
+		Rows: []normRow{
+			{18, 64, "", 7, 9},
+			{65, 120, "", 7, 8},
+		},
+	},
+}
+
+// NormContext places a value against the population norm for the user's age
+// and sex. Label is NormUnknown, with no range, when there is no value, no
+// profile, or no norm row matching the profile.
+type NormContext struct {
+	Metric      string   `json:"metric"`
+	Value       *float64 `json:"value"`
+	Unit        string   `json:"unit"`
+	Label       string   `json:"label"`
+	TypicalLow  *float64 `json:"typical_low,omitempty"`
+	TypicalHigh *float64 `json:"typical_high,omitempty"`
+	Source      string   `json:"source,omitempty"`
+}
+
+// normContext looks up metric's norm row for profile as of at and labels value
+// against it.
+func normContext(metric string, value *float64, profile *UserProfile, at time.Time) NormContext {
+	table := populationNorms[metric]
+	nc := NormContext{Metric: metric, Value: value, Unit: table.Unit, Label: NormUnknown}
+	age, ok := profile.Age(at)
+	if value == nil || !ok {
+		return nc
+	}
+	for _, row := range table.Rows {
+		if age < row.MinAge || age > row.MaxAge || (row.Sex != "" && row.Sex != profile.Sex) {
+			continue
+		}
+		low, high := row.Low, row.High
+		nc.TypicalLow, nc.TypicalHigh, nc.Source = &low, &high, table.Source
+		switch {
+		case *value < low:
+			nc.Label = NormBelow
+		case *value > high:
+			nc.Label = NormAbove
+		default:
+			nc.Label = NormWithin
+		}
+		break
+	}
+	return nc
+}
+
+// normMetrics is the order GetNormContext reports metrics in.
+var normMetrics = []string{"resting_heart_rate", "heart_rate_variability", "vo2_max", "sleep"}
+
+// GetNormContext averages each norm-backed metric over [start, end) and
+// compares it against the population norm for the user's profile.
+func (db *DB) GetNormContext(ctx context.Context, start, end time.Time, userID int) ([]NormContext, error) {
+	profile, err := db.GetUserProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]NormContext, 0, len(normMetrics))
+	for _, metric := range normMetrics {
+		var vals []*float64
+		if metric == "sleep" {
+			sessions, err := db.QuerySleepSessions(ctx, start, end, userID)
+			if err != nil {
+				return nil, fmt.Errorf("querying sleep for norms: %w", err)
+			}
+			for _, s := range sessions {
+				v := s.TotalSleep
+				vals = append(vals, &v)
+			}
+		} else {
+			points, err := db.GetTimeSeries(ctx, metric, start, end, "1 day", userID)
+			if err != nil {
+				return nil, fmt.Errorf("querying %s for norms: %w", metric, err)
+			}
+			for _, p := range points {
+				vals = append(vals, p.Avg)
+			}
+		}
+		out = append(out, normContext(metric, meanOf(vals), profile, end))
+	}
+	return out, nil
+}
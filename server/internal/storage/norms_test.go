@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// TestNormContext verifies values are labeled against the age/sex row of the
+// norm table and that a missing profile, value, or matching row is unknown.
+func TestNormContext(t *testing.T) {
+	at := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	born := time.Date(1990, 7, 1, 0, 0, 0, 0, time.UTC) // 34 on 2025-06-01
+	male := &UserProfile{BirthDate: &born, Sex: "male"}
+	f := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name    string
+		metric  string
+		value   *float64
+		profile *UserProfile
+		want    string
+	}{
+		{"vo2 within", "vo2_max", f(45), male, NormWithin},
+		{"vo2 above", "vo2_max", f(52), male, NormAbove},
+		{"vo2 below female row", "vo2_max", f(24), &UserProfile{BirthDate: &born, Sex: "female"}, NormBelow},
+		{"rhr above", "resting_heart_rate", f(82), male, NormAbove},
+		{"hrv ignores sex", "heart_rate_variability", f(50), &UserProfile{BirthDate: &born}, NormWithin},
+		{"sleep below", "sleep", f(6.2), male, NormBelow},
+		{"no profile", "vo2_max", f(45), nil, NormUnknown},
+		{"no birth date", "vo2_max", f(45), &UserProfile{Sex: "male"}, NormUnknown},
+		{"sex required", "vo2_max", f(45), &UserProfile{BirthDate: &born}, NormUnknown},
+		{"no value", "vo2_max", nil, male, NormUnknown},
+	}
+	for _, tt := range tests {
+		nc := normContext(tt.metric, tt.value, tt.profile, at)
+		if nc.Label != tt.want {
+			t.Errorf("%s: label = %q, want %q", tt.name, nc.Label, tt.want)
+		}
+		if (nc.Label == NormUnknown) != (nc.TypicalLow == nil) {
+			t.Errorf("%s: range = %v, want set only when labeled", tt.name, nc.TypicalLow)
+		}
+	}
+
+	old := time.Date(1940, 1, 1, 0, 0, 0, 0, time.UTC)
+	if nc := normContext("vo2_max", f(30), &UserProfile{BirthDate: &old, Sex: "male"}, at); nc.Label != NormUnknown {
+		t.Errorf("age outside table: label = %q, want unknown", nc.Label)
+	}
+}
+
+// TestUserProfileAge verifies age counts whole years and only increments on
+// the birthday.
+func TestUserProfileAge(t *testing.T) {
+	born := time.Date(1990, 7, 1, 0, 0, 0, 0, time.UTC)
+	p := &UserProfile{BirthDate: &born}
+	if age, _ := p.Age(time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)); age != 34 {
+		t.Errorf("day before birthday: age = %d, want 34", age)
+	}
+	if age, _ := p.Age(time.Date(2025, 7, 1, 0, 0, 0, 0, time.UTC)); age != 35 {
+		t.Errorf("on birthday: age = %d, want 35", age)
+	}
+	var none *UserProfile
+	if _, ok := none.Age(time.Now()); ok {
+		t.Error("nil profile should have no age")
+	}
+}
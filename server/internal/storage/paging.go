@@ -0,0 +1,17 @@
+package storage
+
+import "fmt"
+
+// pageCutoffSQL returns a WITH clause defining cutoff, the col value of the
+// row at offset $offsetArg among the table rows matching where, in col order.
+// Keyset pages (raw metric export, changes, sleep stages) select rows up to
+// and including that value, so pages end on a col boundary and rows sharing
+// it are never split; the next page resumes strictly after it. A page can
+// therefore run a few rows over its limit. cutoff is empty when fewer rows
+// remain than the offset.
+func pageCutoffSQL(table, col, where string, offsetArg int) string {
+	return fmt.Sprintf(`WITH cutoff AS (
+			SELECT %[2]s FROM %[1]s WHERE %[3]s
+			ORDER BY %[2]s OFFSET $%[4]d LIMIT 1
+		)`, table, col, where, offsetArg)
+}
@@ -0,0 +1,17 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestPageCutoffSQL verifies the cutoff CTE orders and offsets by the page
+// column with the caller's filter and placeholder.
+func TestPageCutoffSQL(t *testing.T) {
+	sql := pageCutoffSQL("sleep_stages", "start_time", "user_id = $1", 4)
+	for _, want := range []string{"WITH cutoff AS (", "SELECT start_time FROM sleep_stages WHERE user_id = $1", "ORDER BY start_time OFFSET $4 LIMIT 1"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("pageCutoffSQL missing %q:\n%s", want, sql)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultRejectedCategory is the allowlist category given to a rejected
+// metric enabled without one.
+const DefaultRejectedCategory = "other"
+
+// RejectedMetric is a metric name ingest turned away because it is not
+// enabled in the allowlist. Rejections counts payloads that carried it;
+// Points counts the data points dropped.
+type RejectedMetric struct {
+	MetricName string    `json:"metric_name"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	Rejections int64     `json:"rejections"`
+	Points     int64     `json:"points"`
+}
+
+// rejectedMetricsUpsert builds a single multi-row UPSERT adding one rejection
+// and the given point count per metric name for user $1. Names are sorted so
+// concurrent ingests lock rows in the same order.
+func rejectedMetricsUpsert(userID int, points map[string]int) (string, []any) {
+	names := make([]string, 0, len(points))
+	for name := range points {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]string, len(names))
+	args := make([]any, 0, len(names)*2+1)
+	args = append(args, userID)
+	for i, name := range names {
+		values[i] = fmt.Sprintf("($1, $%d, 1, $%d)", i*2+2, i*2+3)
+		args = append(args, name, points[name])
+	}
+	query := `INSERT INTO rejected_metrics (user_id, metric_name, rejections, points)
+		 VALUES ` + strings.Join(values, ", ") + `
+		 ON CONFLICT (user_id, metric_name) DO UPDATE SET
+		   last_seen = now(),
+		   rejections = rejected_metrics.rejections + 1,
+		   points = rejected_metrics.points + EXCLUDED.points`
+	return query, args
+}
+
+// RecordRejectedMetrics notes one rejection of each metric in points for the
+// user, keyed by name with the number of data points dropped.
+func (db *DB) RecordRejectedMetrics(ctx context.Context, userID int, points map[string]int) error {
+	if len(points) == 0 {
+		return nil
+	}
+	query, args := rejectedMetricsUpsert(userID, points)
+	if _, err := db.Pool.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("recording rejected metrics: %w", err)
+	}
+	return nil
+}
+
+// ListRejectedMetrics returns the metrics recorded as rejected for the user,
+// most recently seen first.
+func (db *DB) ListRejectedMetrics(ctx context.Context, userID int) ([]RejectedMetric, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT metric_name, first_seen, last_seen, rejections, points
+		 FROM rejected_metrics
+		 WHERE user_id = $1
+		 ORDER BY last_seen DESC, metric_name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying rejected metrics: %w", err)
+	}
+	defer rows.Close()
+
+	metrics := []RejectedMetric{}
+	for rows.Next() {
+		var m RejectedMetric
+		if err := rows.Scan(&m.MetricName, &m.FirstSeen, &m.LastSeen, &m.Rejections, &m.Points); err != nil {
+			return nil, fmt.Errorf("scanning rejected metric: %w", err)
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, rows.Err()
+}
+
+// AllowRejectedMetric enables metricName in the allowlist under category,
+// adding it if missing, and forgets every user's recorded rejections of it.
+// Later payloads carrying it are stored. isCumulative, when set, says whether
+// the metric is summed rather than averaged; otherwise a new row takes the
+// built-in default for the name (see IsCumulativeMetric). An existing
+// allowlist row keeps its category, and its is_cumulative unless set.
+func (db *DB) AllowRejectedMetric(ctx context.Context, metricName, category string, isCumulative *bool) error {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning allowlist tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO metric_allowlist (metric_name, category, enabled, is_cumulative) VALUES ($1, $2, TRUE, COALESCE($3, $4))
+		 ON CONFLICT (metric_name) DO UPDATE SET
+		   enabled = TRUE,
+		   is_cumulative = COALESCE($3, metric_allowlist.is_cumulative)`,
+		metricName, category, isCumulative, IsCumulativeMetric(metricName)); err != nil {
+		return fmt.Errorf("enabling rejected metric: %w", err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM rejected_metrics WHERE metric_name = $1`, metricName); err != nil {
+		return fmt.Errorf("clearing rejected metric: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing allowlist tx: %w", err)
+	}
+	db.InvalidateAllowlist()
+	db.InvalidateAllAvailableMetrics()
+	return nil
+}
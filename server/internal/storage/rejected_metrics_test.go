@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestRejectedMetricsUpsert verifies one row per metric for the user, sorted
+// by name, that adds a rejection and the dropped points to that user's
+// existing counts.
+func TestRejectedMetricsUpsert(t *testing.T) {
+	query, args := rejectedMetricsUpsert(7, map[string]int{"walking_asymmetry": 4, "body_temperature": 2})
+
+	if !strings.Contains(query, "($1, $2, 1, $3), ($1, $4, 1, $5)") {
+		t.Errorf("unexpected VALUES in:\n%s", query)
+	}
+	for _, check := range []string{
+		"(user_id, metric_name, rejections, points)",
+		"ON CONFLICT (user_id, metric_name) DO UPDATE",
+		"rejections = rejected_metrics.rejections + 1",
+		"points = rejected_metrics.points + EXCLUDED.points",
+		"last_seen = now()",
+	} {
+		if !strings.Contains(query, check) {
+			t.Errorf("query missing %q", check)
+		}
+	}
+	want := []any{7, "body_temperature", 2, "walking_asymmetry", 4}
+	if len(args) != len(want) {
+		t.Fatalf("args = %v, want %v", args, want)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Errorf("args[%d] = %v, want %v", i, args[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Rollup resolutions supported by ApplyRetention.
+const (
+	ResolutionHour = "1 hour"
+	ResolutionDay  = "1 day"
+)
+
+// resolutionDurations maps rollup resolutions to their bucket width.
+var resolutionDurations = map[string]time.Duration{
+	ResolutionHour: time.Hour,
+	ResolutionDay:  24 * time.Hour,
+}
+
+// RetentionPolicy downsamples one metric's raw rows once they are older than
+// OlderThan into Resolution-sized buckets, optionally deleting the raw rows.
+type RetentionPolicy struct {
+	Metric     string
+	OlderThan  time.Duration
+	Resolution string
+	DeleteRaw  bool
+}
+
+// DownsampleReport describes what one policy run did (or, for a dry run,
+// would do). From/Until bound the raw window that was rolled up; Backfilled
+// counts rows before From that arrived since the previous run.
+type DownsampleReport struct {
+	Metric     string    `json:"metric"`
+	Resolution string    `json:"resolution"`
+	From       time.Time `json:"from"`
+	Until      time.Time `json:"until"`
+	RawRows    int64     `json:"raw_rows"`
+	Backfilled int64     `json:"backfilled"`
+	Buckets    int64     `json:"buckets"`
+	Deleted    int64     `json:"deleted"`
+	DryRun     bool      `json:"dry_run"`
+}
+
+// retentionCutoff returns the exclusive end of the window a policy may roll
+// up at now, aligned down to a bucket boundary so no bucket is split between
+// rolled-up and raw data.
+func retentionCutoff(now time.Time, p RetentionPolicy) (time.Time, error) {
+	width, ok := resolutionDurations[p.Resolution]
+	if !ok {
+		return time.Time{}, fmt.Errorf("unsupported rollup resolution %q", p.Resolution)
+	}
+	return now.Add(-p.OlderThan).UTC().Truncate(width), nil
+}
+
+// rollupWatermark returns how far metricName has been rolled up and at what
+// resolution, or nil if it never has.
+func (db *DB) rollupWatermark(ctx context.Context, metricName string) (*time.Time, string, error) {
+	var until time.Time
+	var res string
+	err := db.Pool.QueryRow(ctx,
+		`SELECT covered_until, resolution FROM metric_rollup_watermarks WHERE metric_name = $1`,
+		metricName).Scan(&until, &res)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("querying rollup watermark: %w", err)
+	}
+	return &until, res, nil
+}
+
+// rollupInsertSQL builds the statement that rolls up p.Metric's raw rows
+// matching where into health_metric_rollups, with $1 the metric and $4 the
+// resolution. With merge, buckets that already exist absorb the new rows,
+// re-weighting means by sample count; otherwise they are left alone.
+func rollupInsertSQL(cumulative bool, where string, merge bool) string {
+	qtyAgg := "AVG(COALESCE(qty, avg_val))"
+	qtyMerge := `(health_metric_rollups.qty * health_metric_rollups.sample_count + EXCLUDED.qty * EXCLUDED.sample_count)
+		                / (health_metric_rollups.sample_count + EXCLUDED.sample_count)`
+	if cumulative {
+		qtyAgg = "SUM(COALESCE(qty, avg_val))"
+		qtyMerge = "health_metric_rollups.qty + EXCLUDED.qty"
+	}
+	conflict := "ON CONFLICT DO NOTHING"
+	if merge {
+		conflict = fmt.Sprintf(`ON CONFLICT (user_id, metric_name, source, resolution, time) DO UPDATE SET
+		     units        = COALESCE(health_metric_rollups.units, EXCLUDED.units),
+		     qty          = COALESCE(%s, health_metric_rollups.qty, EXCLUDED.qty),
+		     min_val      = LEAST(health_metric_rollups.min_val, EXCLUDED.min_val),
+		     max_val      = GREATEST(health_metric_rollups.max_val, EXCLUDED.max_val),
+		     sample_count = health_metric_rollups.sample_count + EXCLUDED.sample_count`, qtyMerge)
+	}
+	return fmt.Sprintf(
+		`INSERT INTO health_metric_rollups (time, user_id, metric_name, source, resolution, units, qty, min_val, max_val, sample_count)
+		 SELECT time_bucket($4::interval, time), user_id, metric_name, source, $4, MAX(units),
+		        %s, MIN(COALESCE(qty, min_val)), MAX(COALESCE(qty, max_val)), COUNT(*)
+		 FROM health_metrics
+		 WHERE metric_name = $1 AND %s
+		   AND %s
+		 GROUP BY 1, user_id, metric_name, source
+		 %s`, qtyAgg, where, uncoveredRowsSQL, conflict)
+}
+
+// Raw row windows ApplyRetention rolls up, with $1 the metric. The new
+// window runs from the previous watermark ($2) to the cutoff ($3); backfilled
+// rows are dated before the watermark ($2) but written since the previous
+// run ($3), so reads, which take raw rows only from the watermark on, would
+// otherwise never see them.
+const (
+	retentionWindowSQL   = "time >= $2 AND time < $3"
+	retentionBackfillSQL = "time < $2 AND updated_at >= $3"
+)
+
+// ApplyRetention rolls up p.Metric's raw rows between the previous watermark
+// and the policy cutoff, for all users, and merges rows backfilled behind the
+// watermark since the previous run into their buckets. Each run only touches
+// rows it has not seen, so it is safe to schedule repeatedly. With dryRun,
+// nothing is written and the report shows what a real run would affect.
+func (db *DB) ApplyRetention(ctx context.Context, p RetentionPolicy, now time.Time, dryRun bool) (*DownsampleReport, error) {
+	cutoff, err := retentionCutoff(now, p)
+	if err != nil {
+		return nil, err
+	}
+	watermark, res, err := db.rollupWatermark(ctx, p.Metric)
+	if err != nil {
+		return nil, err
+	}
+	if watermark != nil && res != p.Resolution {
+		return nil, fmt.Errorf("%s already rolled up at %q; changing resolution to %q is not supported", p.Metric, res, p.Resolution)
+	}
+
+	report := &DownsampleReport{Metric: p.Metric, Resolution: p.Resolution, Until: cutoff, DryRun: dryRun}
+	var rolledAt time.Time
+	if watermark != nil {
+		report.From = *watermark
+		err := db.Pool.QueryRow(ctx,
+			`SELECT rolled_at FROM metric_rollup_watermarks WHERE metric_name = $1`, p.Metric).Scan(&rolledAt)
+		if err != nil {
+			return nil, fmt.Errorf("querying rollup time: %w", err)
+		}
+		err = db.Pool.QueryRow(ctx,
+			`SELECT COUNT(*) FROM health_metrics WHERE metric_name = $1 AND `+retentionBackfillSQL,
+			p.Metric, report.From, rolledAt).Scan(&report.Backfilled)
+		if err != nil {
+			return nil, fmt.Errorf("counting backfilled rows: %w", err)
+		}
+	}
+	if !report.From.Before(cutoff) && report.Backfilled == 0 {
+		return report, nil
+	}
+	if report.From.After(cutoff) {
+		report.Until = report.From
+	}
+
+	err = db.Pool.QueryRow(ctx,
+		`SELECT COUNT(*), COUNT(DISTINCT (user_id, source, time_bucket($4::interval, time)))
+		 FROM health_metrics
+		 WHERE metric_name = $1 AND `+retentionWindowSQL,
+		p.Metric, report.From, report.Until, p.Resolution).Scan(&report.RawRows, &report.Buckets)
+	if err != nil {
+		return nil, fmt.Errorf("counting rows to downsample: %w", err)
+	}
+	if dryRun {
+		if p.DeleteRaw {
+			report.Deleted = report.RawRows + report.Backfilled
+		}
+		return report, nil
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning retention tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	cumulative := cumulativeMetrics[p.Metric]
+	tag, err := tx.Exec(ctx, rollupInsertSQL(cumulative, retentionWindowSQL, false),
+		p.Metric, report.From, report.Until, p.Resolution)
+	if err != nil {
+		return nil, fmt.Errorf("inserting rollups: %w", err)
+	}
+	report.Buckets = tag.RowsAffected()
+	if report.Backfilled > 0 {
+		if _, err := tx.Exec(ctx, rollupInsertSQL(cumulative, retentionBackfillSQL, true),
+			p.Metric, report.From, rolledAt, p.Resolution); err != nil {
+			return nil, fmt.Errorf("merging backfilled rows into rollups: %w", err)
+		}
+	}
+
+	if p.DeleteRaw {
+		tag, err := tx.Exec(ctx,
+			`DELETE FROM health_metrics WHERE metric_name = $1 AND `+retentionWindowSQL,
+			p.Metric, report.From, report.Until)
+		if err != nil {
+			return nil, fmt.Errorf("deleting downsampled rows: %w", err)
+		}
+		report.Deleted = tag.RowsAffected()
+		if report.Backfilled > 0 {
+			tag, err := tx.Exec(ctx,
+				`DELETE FROM health_metrics WHERE metric_name = $1 AND `+retentionBackfillSQL,
+				p.Metric, report.From, rolledAt)
+			if err != nil {
+				return nil, fmt.Errorf("deleting backfilled rows: %w", err)
+			}
+			report.Deleted += tag.RowsAffected()
+		}
+	}
+
+	if err := markDataChanged(ctx, tx, 0, DataHealthMetrics); err != nil {
+		return nil, err
+	}
+	_, err = tx.Exec(ctx,
+		`INSERT INTO metric_rollup_watermarks (metric_name, resolution, covered_until, rolled_at)
+		 VALUES ($1, $2, $3, NOW())
+		 ON CONFLICT (metric_name) DO UPDATE SET covered_until = EXCLUDED.covered_until, rolled_at = EXCLUDED.rolled_at`,
+		p.Metric, p.Resolution, report.Until)
+	if err != nil {
+		return nil, fmt.Errorf("updating rollup watermark: %w", err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing retention tx: %w", err)
+	}
+	return report, nil
+}
+
+// RunRetention applies every policy in order, stopping at the first error.
+func (db *DB) RunRetention(ctx context.Context, policies []RetentionPolicy, now time.Time, dryRun bool) ([]DownsampleReport, error) {
+	reports := make([]DownsampleReport, 0, len(policies))
+	for _, p := range policies {
+		r, err := db.ApplyRetention(ctx, p, now, dryRun)
+		if err != nil {
+			return reports, fmt.Errorf("retention for %s: %w", p.Metric, err)
+		}
+		reports = append(reports, *r)
+	}
+	return reports, nil
+}
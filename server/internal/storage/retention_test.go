@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRetentionCutoff verifies the cutoff lands on a bucket boundary, so a
+// bucket is never half rolled up and half raw, and that unknown resolutions
+// are rejected before any SQL runs.
+func TestRetentionCutoff(t *testing.T) {
+	now := time.Date(2025, 6, 10, 14, 37, 12, 0, time.UTC)
+	tests := []struct {
+		resolution string
+		want       time.Time
+	}{
+		{ResolutionHour, time.Date(2025, 3, 12, 14, 0, 0, 0, time.UTC)},
+		{ResolutionDay, time.Date(2025, 3, 12, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		p := RetentionPolicy{Metric: "heart_rate", OlderThan: 90 * 24 * time.Hour, Resolution: tt.resolution}
+		got, err := retentionCutoff(now, p)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.resolution, err)
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%s: cutoff = %v, want %v", tt.resolution, got, tt.want)
+		}
+	}
+
+	if _, err := retentionCutoff(now, RetentionPolicy{Resolution: "1 week"}); err == nil {
+		t.Error("expected error for unsupported resolution")
+	}
+}
+
+// TestRollupInsertSQL verifies the new window leaves existing buckets alone
+// while backfilled rows are merged into theirs, re-weighting means by sample
+// count and adding sums for cumulative metrics.
+func TestRollupInsertSQL(t *testing.T) {
+	window := rollupInsertSQL(false, retentionWindowSQL, false)
+	for _, want := range []string{"time >= $2 AND time < $3", "AVG(COALESCE(qty, avg_val))", "ON CONFLICT DO NOTHING", uncoveredRowsSQL} {
+		if !strings.Contains(window, want) {
+			t.Errorf("window insert missing %q:\n%s", want, window)
+		}
+	}
+
+	backfill := rollupInsertSQL(false, retentionBackfillSQL, true)
+	for _, want := range []string{
+		"time < $2 AND updated_at >= $3",
+		"DO UPDATE SET",
+		"health_metric_rollups.qty * health_metric_rollups.sample_count + EXCLUDED.qty * EXCLUDED.sample_count",
+		"LEAST(health_metric_rollups.min_val, EXCLUDED.min_val)",
+		"sample_count = health_metric_rollups.sample_count + EXCLUDED.sample_count",
+	} {
+		if !strings.Contains(backfill, want) {
+			t.Errorf("backfill insert missing %q:\n%s", want, backfill)
+		}
+	}
+
+	if sql := rollupInsertSQL(true, retentionBackfillSQL, true); !strings.Contains(sql, "COALESCE(health_metric_rollups.qty + EXCLUDED.qty, ") {
+		t.Errorf("cumulative backfill should add sums:\n%s", sql)
+	}
+}
@@ -11,9 +11,26 @@ import (
 	"github.com/claude/freereps/internal/models"
 )
 
-// InsertSleepSession upserts a sleep session (one per date per user).
+// supersededSleepSQL deletes the user's ($1) sessions filed under a date
+// other than $2 whose sleep overlaps [$3, $4): the same night stored under
+// the other day boundary before it was changed. Only adjacent dates are
+// searched, which is as far apart the two boundaries can file a night.
+const supersededSleepSQL = `DELETE FROM sleep_sessions
+	WHERE user_id = $1 AND date <> $2 AND date BETWEEN $2::date - 1 AND $2::date + 1
+	  AND sleep_start < $4 AND sleep_end > $3`
+
+// InsertSleepSession upserts a sleep session (one per date per user). The
+// date follows the configured sleep day boundary (see sleepSessionDate); a
+// copy of the same night filed under the other boundary's date is replaced.
 func (db *DB) InsertSleepSession(ctx context.Context, row models.SleepSessionRow) error {
-	_, err := db.Pool.Exec(ctx,
+	row.Date = sleepSessionDate(row, db.sleepLoc, db.sleepDay)
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning sleep session tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	_, err = tx.Exec(ctx,
 		`INSERT INTO sleep_sessions (user_id, date, total_sleep, asleep, core, deep, rem, in_bed, sleep_start, sleep_end, in_bed_start, in_bed_end)
 		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
 		 ON CONFLICT (user_id, date) DO UPDATE SET
@@ -32,6 +49,20 @@ func (db *DB) InsertSleepSession(ctx context.Context, row models.SleepSessionRow
 	if err != nil {
 		return fmt.Errorf("inserting sleep session: %w", err)
 	}
+	if !row.SleepStart.IsZero() && !row.SleepEnd.IsZero() {
+		tag, err := tx.Exec(ctx, supersededSleepSQL, row.UserID, row.Date, row.SleepStart, row.SleepEnd)
+		if err != nil {
+			return fmt.Errorf("deleting superseded sleep session: %w", err)
+		}
+		if tag.RowsAffected() > 0 {
+			if err := markDataChanged(ctx, tx, row.UserID, DataSleep); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing sleep session tx: %w", err)
+	}
 	return nil
 }
 
@@ -119,6 +150,78 @@ func (db *DB) QuerySleepStages(ctx context.Context, start, end time.Time, userID
 	return result, rows.Err()
 }
 
+// SleepStagePage is one page of raw sleep stages. NextAfter is the start time
+// of the page's last stage when more remain; pass it back as after to get the
+// next page.
+type SleepStagePage struct {
+	Stages    []models.SleepStageRow `json:"stages"`
+	NextAfter *time.Time             `json:"next_after,omitempty"`
+}
+
+// sleepStageWhere builds the filter for a sleep stage page: stages of user $1
+// starting in [$2, $3), limited to the given stage names when any are set and
+// starting after the previous page's last start time when after is set.
+// Placeholders for the filters follow the three fixed ones.
+func sleepStageWhere(stages []string, after *time.Time) (string, []any) {
+	where := "user_id = $1 AND start_time >= $2 AND start_time < $3"
+	var args []any
+	if len(stages) > 0 {
+		args = append(args, stages)
+		where += fmt.Sprintf(" AND stage = ANY($%d)", 3+len(args))
+	}
+	if after != nil {
+		args = append(args, *after)
+		where += fmt.Sprintf(" AND start_time > $%d", 3+len(args))
+	}
+	return where, args
+}
+
+// QuerySleepStagePage returns up to limit stages starting in [start, end) in
+// start time order, optionally only the named stages. Pages end on a start
+// time boundary (see pageCutoffSQL) so stages sharing a start time, one per
+// source, are never split.
+func (db *DB) QuerySleepStagePage(ctx context.Context, start, end time.Time, stages []string, after *time.Time, limit, userID int) (*SleepStagePage, error) {
+	where, extra := sleepStageWhere(stages, after)
+	args := append([]any{userID, start, end}, extra...)
+	args = append(args, limit-1)
+	rows, err := db.Pool.Query(ctx, pageCutoffSQL("sleep_stages", "start_time", where, len(args))+`
+		SELECT start_time, end_time, user_id, stage, duration_hr, source
+		FROM sleep_stages
+		WHERE `+where+` AND start_time <= COALESCE((SELECT start_time FROM cutoff), 'infinity')
+		ORDER BY start_time, stage, source`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying sleep stage page: %w", err)
+	}
+	defer rows.Close()
+
+	page := &SleepStagePage{Stages: []models.SleepStageRow{}}
+	for rows.Next() {
+		var r models.SleepStageRow
+		if err := rows.Scan(&r.StartTime, &r.EndTime, &r.UserID, &r.Stage, &r.DurationHr, &r.Source); err != nil {
+			return nil, fmt.Errorf("scanning sleep stage: %w", err)
+		}
+		page.Stages = append(page.Stages, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(page.Stages) < limit {
+		return page, nil
+	}
+
+	last := page.Stages[len(page.Stages)-1].StartTime
+	where, extra = sleepStageWhere(stages, &last)
+	var more bool
+	if err := db.Pool.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM sleep_stages WHERE `+where+`)`,
+		append([]any{userID, start, end}, extra...)...).Scan(&more); err != nil {
+		return nil, fmt.Errorf("checking for more sleep stages: %w", err)
+	}
+	if more {
+		page.NextAfter = &last
+	}
+	return page, nil
+}
+
 // SleepStageUserIDs returns distinct user IDs that have sleep stage data.
 func (db *DB) SleepStageUserIDs(ctx context.Context) ([]int, error) {
 	rows, err := db.Pool.Query(ctx, `SELECT DISTINCT user_id FROM sleep_stages ORDER BY user_id`)
@@ -138,11 +241,30 @@ func (db *DB) SleepStageUserIDs(ctx context.Context) ([]int, error) {
 	return ids, rows.Err()
 }
 
-// BackfillSleepSessions synthesizes sleep sessions from existing sleep stages
-// that don't yet have corresponding sessions. Called at server startup and
-// after each HAE TCP import. Idempotent (ON CONFLICT DO NOTHING).
+// sleepBackfillEpoch is the lower bound used when a user has no sessions yet.
+var sleepBackfillEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// BackfillSleepSessions synthesizes sleep sessions from sleep stages that
+// don't yet have corresponding sessions. Called at server startup and after
+// each HAE import. Incremental: only stages starting after each user's latest
+// session are read, so the cost tracks new data rather than all history.
+// Idempotent (ON CONFLICT DO NOTHING).
 func (db *DB) BackfillSleepSessions(ctx context.Context, log *slog.Logger) error {
-	userIDs, err := db.SleepStageUserIDs(ctx)
+	return backfillSleepSessions(ctx, db, log, db.sleepLoc, db.sleepDay)
+}
+
+// sleepBackfillStore abstracts the reads and writes of a sleep backfill for
+// testing.
+type sleepBackfillStore interface {
+	SleepStageUserIDs(ctx context.Context) ([]int, error)
+	latestSleepSessionEnd(ctx context.Context, userID int) (*time.Time, error)
+	QuerySleepStages(ctx context.Context, start, end time.Time, userID int) ([]models.SleepStageRow, error)
+	insertBackfillSleepSession(ctx context.Context, session models.SleepSessionRow) (bool, error)
+	InsertHealthMetrics(ctx context.Context, rows []models.HealthMetricRow) (int64, error)
+}
+
+func backfillSleepSessions(ctx context.Context, store sleepBackfillStore, log *slog.Logger, loc *time.Location, boundary SleepDayBoundary) error {
+	userIDs, err := store.SleepStageUserIDs(ctx)
 	if err != nil {
 		return fmt.Errorf("getting user IDs for backfill: %w", err)
 	}
@@ -152,7 +274,11 @@ func (db *DB) BackfillSleepSessions(ctx context.Context, log *slog.Logger) error
 
 	var totalCreated int
 	for _, userID := range userIDs {
-		created, err := db.backfillUserSleepSessions(ctx, log, userID)
+		latest, err := store.latestSleepSessionEnd(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("backfilling user %d: %w", userID, err)
+		}
+		created, err := backfillUserSleepSessions(ctx, store, log, userID, sleepBackfillStart(latest), loc, boundary)
 		if err != nil {
 			return fmt.Errorf("backfilling user %d: %w", userID, err)
 		}
@@ -163,23 +289,41 @@ func (db *DB) BackfillSleepSessions(ctx context.Context, log *slog.Logger) error
 	return nil
 }
 
-func (db *DB) backfillUserSleepSessions(ctx context.Context, log *slog.Logger, userID int) (int, error) {
-	stages, err := db.QuerySleepStages(ctx,
-		time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC),
-		time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC),
-		userID)
+// BackfillSleepSessionsFull regroups all of a user's sleep stages regardless
+// of existing sessions. Used to repair gaps the incremental pass can't see,
+// e.g. stages imported for nights older than the latest session.
+func (db *DB) BackfillSleepSessionsFull(ctx context.Context, log *slog.Logger, userID int) (int, error) {
+	return backfillUserSleepSessions(ctx, db, log, userID, sleepBackfillEpoch, db.sleepLoc, db.sleepDay)
+}
+
+// latestSleepSessionEnd returns the end of the user's most recent sleep
+// session, or nil if they have none.
+func (db *DB) latestSleepSessionEnd(ctx context.Context, userID int) (*time.Time, error) {
+	var latest *time.Time
+	err := db.Pool.QueryRow(ctx,
+		`SELECT MAX(sleep_end) FROM sleep_sessions WHERE user_id = $1`, userID).Scan(&latest)
 	if err != nil {
-		return 0, fmt.Errorf("querying stages: %w", err)
+		return nil, fmt.Errorf("querying latest sleep session: %w", err)
 	}
-	if len(stages) == 0 {
-		return 0, nil
+	return latest, nil
+}
+
+// sleepBackfillStart returns where an incremental backfill should begin
+// reading stages: just after the latest known session, or the epoch if none.
+func sleepBackfillStart(latestEnd *time.Time) time.Time {
+	if latestEnd == nil {
+		return sleepBackfillEpoch
 	}
+	return *latestEnd
+}
 
+// groupSleepNights sorts stages and splits them into nights wherever there
+// is a gap of more than 12h between one stage's end and the next's start.
+func groupSleepNights(stages []models.SleepStageRow) [][]models.SleepStageRow {
 	sort.Slice(stages, func(i, j int) bool {
 		return stages[i].StartTime.Before(stages[j].StartTime)
 	})
 
-	// Group into nights (gap > 12h = new night)
 	var nights [][]models.SleepStageRow
 	var currentNight []models.SleepStageRow
 
@@ -199,60 +343,106 @@ func (db *DB) backfillUserSleepSessions(ctx context.Context, log *slog.Logger, u
 	if len(currentNight) > 0 {
 		nights = append(nights, currentNight)
 	}
+	return nights
+}
 
-	var created int
-	for _, night := range nights {
-		sleepStart := night[0].StartTime
-		sleepEnd := night[len(night)-1].EndTime
-
-		var deep, core, rem, awake, inBedDur float64
-		for _, s := range night {
-			switch s.Stage {
-			case "Deep":
-				deep += s.DurationHr
-			case "Core":
-				core += s.DurationHr
-			case "REM":
-				rem += s.DurationHr
-			case "Awake":
-				awake += s.DurationHr
-			case "In Bed":
-				inBedDur += s.DurationHr
-			}
-		}
+// SleepDayBoundary selects how nights are assigned to calendar days; see
+// SetSleepDayBoundary.
+type SleepDayBoundary string
 
-		totalSleep := deep + core + rem
-		inBed := sleepEnd.Sub(sleepStart).Hours()
-		date := sleepEnd.Truncate(24 * time.Hour)
+const (
+	// SleepDayMidnight files a night under its wake-up date.
+	SleepDayMidnight SleepDayBoundary = "midnight"
+	// SleepDayNoon files a night under the noon-to-noon sleep day it falls in,
+	// named after the day it starts: the evening's date, so a 1am bedtime
+	// and an afternoon nap stay with the day before.
+	SleepDayNoon SleepDayBoundary = "noon"
+)
 
-		session := models.SleepSessionRow{
-			UserID:     userID,
-			Date:       date,
-			TotalSleep: totalSleep,
-			Asleep:     totalSleep,
-			Core:       core,
-			Deep:       deep,
-			REM:        rem,
-			InBed:      inBed,
-			SleepStart: sleepStart,
-			SleepEnd:   sleepEnd,
-			InBedStart: sleepStart,
-			InBedEnd:   sleepEnd,
+// sleepNightDate returns the calendar date a night is filed under: the local
+// date of the night's midpoint shifted forward 12h. For ordinary nights that
+// is the wake-up date, and because the midpoint sits hours away from the
+// noon cut-off, small shifts in stage boundaries between re-imports can't
+// move a night to the adjacent date the way truncating sleepEnd in UTC did.
+// With SleepDayNoon the midpoint is shifted back 12h instead, giving the date
+// the noon-to-noon sleep day began.
+func sleepNightDate(start, end time.Time, loc *time.Location, boundary SleepDayBoundary) time.Time {
+	if loc == nil {
+		loc = time.UTC
+	}
+	shift := 12 * time.Hour
+	if boundary == SleepDayNoon {
+		shift = -shift
+	}
+	anchor := start.Add(end.Sub(start) / 2).Add(shift).In(loc)
+	return time.Date(anchor.Year(), anchor.Month(), anchor.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// sleepSessionDate returns the date row is stored under. Under SleepDayNoon
+// sessions with known sleep times are re-dated by sleepNightDate, since HAE
+// and Oura date nights by wake-up day; otherwise the source's date is kept.
+func sleepSessionDate(row models.SleepSessionRow, loc *time.Location, boundary SleepDayBoundary) time.Time {
+	if boundary != SleepDayNoon || row.SleepStart.IsZero() || row.SleepEnd.IsZero() {
+		return row.Date
+	}
+	return sleepNightDate(row.SleepStart, row.SleepEnd, loc, boundary)
+}
+
+// sleepSessionFromNight summarizes one night of stages into a session row.
+func sleepSessionFromNight(userID int, night []models.SleepStageRow, loc *time.Location, boundary SleepDayBoundary) models.SleepSessionRow {
+	sleepStart := night[0].StartTime
+	sleepEnd := night[len(night)-1].EndTime
+
+	var deep, core, rem float64
+	for _, s := range night {
+		switch s.Stage {
+		case "Deep":
+			deep += s.DurationHr
+		case "Core":
+			core += s.DurationHr
+		case "REM":
+			rem += s.DurationHr
 		}
+	}
+
+	totalSleep := deep + core + rem
+	return models.SleepSessionRow{
+		UserID:     userID,
+		Date:       sleepNightDate(sleepStart, sleepEnd, loc, boundary),
+		TotalSleep: totalSleep,
+		Asleep:     totalSleep,
+		Core:       core,
+		Deep:       deep,
+		REM:        rem,
+		InBed:      sleepEnd.Sub(sleepStart).Hours(),
+		SleepStart: sleepStart,
+		SleepEnd:   sleepEnd,
+		InBedStart: sleepStart,
+		InBedEnd:   sleepEnd,
+	}
+}
 
-		// Use DO NOTHING: backfill is a fallback — don't overwrite sessions
-		// from direct sources (Oura, HAE) which have more accurate data.
-		tag, err := db.Pool.Exec(ctx,
-			`INSERT INTO sleep_sessions (user_id, date, total_sleep, asleep, core, deep, rem, in_bed, sleep_start, sleep_end, in_bed_start, in_bed_end)
-			 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
-			 ON CONFLICT (user_id, date) DO NOTHING`,
-			session.UserID, session.Date, session.TotalSleep, session.Asleep,
-			session.Core, session.Deep, session.REM, session.InBed,
-			session.SleepStart, session.SleepEnd, session.InBedStart, session.InBedEnd)
+func backfillUserSleepSessions(ctx context.Context, store sleepBackfillStore, log *slog.Logger, userID int, since time.Time, loc *time.Location, boundary SleepDayBoundary) (int, error) {
+	stages, err := store.QuerySleepStages(ctx, since,
+		time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC),
+		userID)
+	if err != nil {
+		return 0, fmt.Errorf("querying stages: %w", err)
+	}
+	if len(stages) == 0 {
+		return 0, nil
+	}
+
+	var created int
+	for _, night := range groupSleepNights(stages) {
+		session := sleepSessionFromNight(userID, night, loc, boundary)
+		totalSleep, date := session.TotalSleep, session.Date
+
+		inserted, err := store.insertBackfillSleepSession(ctx, session)
 		if err != nil {
-			return created, fmt.Errorf("inserting backfill session: %w", err)
+			return created, err
 		}
-		if tag.RowsAffected() == 0 {
+		if !inserted {
 			continue // session already exists from a direct source
 		}
 		created++
@@ -266,7 +456,7 @@ func (db *DB) backfillUserSleepSessions(ctx context.Context, log *slog.Logger, u
 			Units:      "hr",
 			Qty:        &qty,
 		}
-		if _, err := db.InsertHealthMetrics(ctx, []models.HealthMetricRow{sleepMetric}); err != nil {
+		if _, err := store.InsertHealthMetrics(ctx, []models.HealthMetricRow{sleepMetric}); err != nil {
 			return created, fmt.Errorf("inserting backfill sleep_analysis metric: %w", err)
 		}
 	}
@@ -276,3 +466,21 @@ func (db *DB) backfillUserSleepSessions(ctx context.Context, log *slog.Logger, u
 	}
 	return created, nil
 }
+
+// insertBackfillSleepSession stores a synthesized session unless one already
+// exists for its date, reporting whether it was inserted. DO NOTHING because
+// backfill is a fallback — sessions from direct sources (Oura, HAE) have more
+// accurate data and must not be overwritten.
+func (db *DB) insertBackfillSleepSession(ctx context.Context, session models.SleepSessionRow) (bool, error) {
+	tag, err := db.Pool.Exec(ctx,
+		`INSERT INTO sleep_sessions (user_id, date, total_sleep, asleep, core, deep, rem, in_bed, sleep_start, sleep_end, in_bed_start, in_bed_end)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+		 ON CONFLICT (user_id, date) DO NOTHING`,
+		session.UserID, session.Date, session.TotalSleep, session.Asleep,
+		session.Core, session.Deep, session.REM, session.InBed,
+		session.SleepStart, session.SleepEnd, session.InBedStart, session.InBedEnd)
+	if err != nil {
+		return false, fmt.Errorf("inserting backfill session: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
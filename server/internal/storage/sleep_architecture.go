@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// remCycleGap is the longest non-REM interruption that still counts as the
+// same REM period; REM split by a brief arousal is one cycle, not two.
+const remCycleGap = 15 * time.Minute
+
+// SleepArchitecture describes how a night moved between stages rather than
+// how long it spent in each. Latency and WASO are in minutes; SleepOnset,
+// FinalWake and OnsetLatencyMin are nil when the night has no sleep stage.
+type SleepArchitecture struct {
+	Date            time.Time  `json:"date"`
+	SleepOnset      *time.Time `json:"sleep_onset"`
+	FinalWake       *time.Time `json:"final_wake"`
+	OnsetLatencyMin *float64   `json:"onset_latency_min"`
+	Awakenings      int        `json:"awakenings"`
+	WASOMin         float64    `json:"waso_min"`
+	REMCycles       int        `json:"rem_cycles"`
+	LongestDeepMin  float64    `json:"longest_deep_min"`
+	Transitions     int        `json:"transitions"`
+}
+
+// GetSleepArchitecture returns the transition analysis of the night filed
+// under date, built from the same stages GetSleepNight returns. Returns nil
+// when the night has no stages.
+func (db *DB) GetSleepArchitecture(ctx context.Context, date time.Time, userID int) (*SleepArchitecture, error) {
+	night, err := db.GetSleepNight(ctx, date, userID)
+	if err != nil || night == nil || len(night.Stages) == 0 {
+		return nil, err
+	}
+	return sleepArchitecture(night.Date, night.Stages), nil
+}
+
+// sleepArchitecture walks a night's stages in time order. "In Bed" segments
+// only mark when the night began (Apple Health records one spanning the whole
+// night alongside the stages); every other segment is either sleep or Awake.
+//
+//   - Onset latency runs from the first segment's start to the first sleep.
+//   - Awakenings are Awake segments between onset and the final wake, i.e.
+//     ones followed by more sleep; WASO is their total length.
+//   - REM cycles count REM periods, merging REM separated by less than
+//     remCycleGap.
+//   - The longest deep block merges back-to-back Deep segments.
+//   - Transitions count changes of stage between consecutive segments.
+func sleepArchitecture(date time.Time, stages []HypnogramStage) *SleepArchitecture {
+	arch := &SleepArchitecture{Date: date}
+	if len(stages) == 0 {
+		return arch
+	}
+	bedStart := stages[0].Start
+	var walk []HypnogramStage
+	for _, s := range stages {
+		if s.Start.Before(bedStart) {
+			bedStart = s.Start
+		}
+		if s.Stage != models.SleepStageInBed {
+			walk = append(walk, s)
+		}
+	}
+	sort.SliceStable(walk, func(i, j int) bool { return walk[i].Start.Before(walk[j].Start) })
+
+	asleep := func(s HypnogramStage) bool { return s.Stage != models.SleepStageAwake }
+	first, last := -1, -1
+	for i, s := range walk {
+		if asleep(s) {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		return arch
+	}
+	onset, wake := walk[first].Start, walk[last].End
+	latency := onset.Sub(bedStart).Minutes()
+	arch.SleepOnset, arch.FinalWake, arch.OnsetLatencyMin = &onset, &wake, &latency
+
+	var remEnd, deepStart, deepEnd time.Time
+	for i := first; i <= last; i++ {
+		s := walk[i]
+		if i > first && s.Stage != walk[i-1].Stage {
+			arch.Transitions++
+		}
+		switch s.Stage {
+		case models.SleepStageAwake:
+			arch.Awakenings++
+			arch.WASOMin += s.End.Sub(s.Start).Minutes()
+		case models.SleepStageREM:
+			if remEnd.IsZero() || s.Start.Sub(remEnd) >= remCycleGap {
+				arch.REMCycles++
+			}
+			remEnd = s.End
+		case models.SleepStageDeep:
+			if deepEnd.IsZero() || s.Start.After(deepEnd) {
+				deepStart = s.Start
+			}
+			deepEnd = s.End
+			arch.LongestDeepMin = max(arch.LongestDeepMin, deepEnd.Sub(deepStart).Minutes())
+		}
+		if s.Stage != models.SleepStageDeep {
+			deepEnd = time.Time{}
+		}
+	}
+	return arch
+}
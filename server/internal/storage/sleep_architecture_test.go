@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// TestSleepArchitecture verifies onset latency, awakenings, WASO, REM cycles,
+// the longest deep block, and transitions on a crafted hypnogram.
+func TestSleepArchitecture(t *testing.T) {
+	bed := time.Date(2025, 3, 1, 22, 0, 0, 0, time.UTC)
+	var stages []HypnogramStage
+	at := bed
+	add := func(stage string, min int) {
+		end := at.Add(time.Duration(min) * time.Minute)
+		stages = append(stages, HypnogramStage{Start: at, End: end, Stage: stage})
+		at = end
+	}
+	add("Awake", 20) // falling asleep
+	add("Core", 30)
+	add("Deep", 40)
+	add("Deep", 25) // same deep block: 65 min
+	add("Core", 20)
+	add("REM", 15)  // cycle 1
+	add("Awake", 5) // awakening 1, within the REM merge gap
+	add("REM", 10)  // still cycle 1
+	add("Core", 60)
+	add("Deep", 30)
+	add("REM", 25)   // cycle 2
+	add("Awake", 10) // awakening 2
+	add("Core", 40)
+	add("REM", 20)   // cycle 3
+	add("Awake", 15) // final wake: not an awakening
+	// Apple Health's In Bed spans everything and starts earlier.
+	stages = append(stages, HypnogramStage{Start: bed.Add(-10 * time.Minute), End: at, Stage: "In Bed"})
+
+	a := sleepArchitecture(bed, stages)
+	if a.SleepOnset == nil || !a.SleepOnset.Equal(bed.Add(20*time.Minute)) {
+		t.Fatalf("onset = %v", a.SleepOnset)
+	}
+	if a.FinalWake == nil || !a.FinalWake.Equal(at.Add(-15*time.Minute)) {
+		t.Errorf("final wake = %v", a.FinalWake)
+	}
+	if a.OnsetLatencyMin == nil || *a.OnsetLatencyMin != 30 {
+		t.Errorf("latency = %v, want 30 (from In Bed start)", a.OnsetLatencyMin)
+	}
+	if a.Awakenings != 2 || a.WASOMin != 15 {
+		t.Errorf("awakenings = %d, WASO = %v; want 2, 15", a.Awakenings, a.WASOMin)
+	}
+	if a.REMCycles != 3 {
+		t.Errorf("REM cycles = %d, want 3", a.REMCycles)
+	}
+	if a.LongestDeepMin != 65 {
+		t.Errorf("longest deep = %v, want 65", a.LongestDeepMin)
+	}
+	// Core→Deep, Deep→Core, Core→REM, REM→Awake, Awake→REM, REM→Core,
+	// Core→Deep, Deep→REM, REM→Awake, Awake→Core, Core→REM.
+	if a.Transitions != 11 {
+		t.Errorf("transitions = %d, want 11", a.Transitions)
+	}
+
+	awake := sleepArchitecture(bed, []HypnogramStage{{Start: bed, End: bed.Add(time.Hour), Stage: "Awake"}})
+	if awake.SleepOnset != nil || awake.OnsetLatencyMin != nil || awake.Awakenings != 0 {
+		t.Errorf("awake-only night = %+v", awake)
+	}
+}
@@ -0,0 +1,100 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// DefaultSleepTargetHours is the nightly need assumed when none is configured.
+	DefaultSleepTargetHours = 8.0
+	// sleepDebtWindowDays is the trailing window sleep debt is measured over.
+	sleepDebtWindowDays = 14
+)
+
+// SleepDebtNight is one night in the debt window. TotalSleep is nil when the
+// night has no session and missing nights are not counted as zero.
+type SleepDebtNight struct {
+	Date       time.Time `json:"date"`
+	TotalSleep *float64  `json:"total_sleep"`
+	Shortfall  float64   `json:"shortfall"`
+}
+
+// SleepDebt summarizes sleep shortfall over the trailing window ending at End.
+// DebtHours sums each night's shortfall against the target; nights above
+// target don't pay debt back. AverageHours is over nights with data only.
+type SleepDebt struct {
+	End            time.Time        `json:"end"`
+	Days           int              `json:"days"`
+	TargetHours    float64          `json:"target_hours"`
+	DebtHours      float64          `json:"debt_hours"`
+	AverageHours   *float64         `json:"average_hours"`
+	NightsWithData int              `json:"nights_with_data"`
+	MissingNights  int              `json:"missing_nights"`
+	MissingAsZero  bool             `json:"missing_as_zero"`
+	Nights         []SleepDebtNight `json:"nights"`
+}
+
+// computeSleepDebt builds the debt summary for the days-long window ending on
+// end (inclusive) from total sleep per night date. A missing night is left
+// out of both debt and average — no data is not the same as no sleep, e.g.
+// when the watch was charging — unless missingAsZero is set.
+func computeSleepDebt(totals map[time.Time]float64, end time.Time, days int, target float64, missingAsZero bool) *SleepDebt {
+	debt := &SleepDebt{End: end, Days: days, TargetHours: target, MissingAsZero: missingAsZero, Nights: make([]SleepDebtNight, 0, days)}
+
+	var sum float64
+	for i := days - 1; i >= 0; i-- {
+		date := end.AddDate(0, 0, -i)
+		night := SleepDebtNight{Date: date}
+		total, ok := totals[date]
+		switch {
+		case ok:
+			debt.NightsWithData++
+		case missingAsZero:
+			debt.MissingNights++
+		default:
+			debt.MissingNights++
+			debt.Nights = append(debt.Nights, night)
+			continue
+		}
+		night.TotalSleep = &total
+		night.Shortfall = max(0, target-total)
+		debt.DebtHours += night.Shortfall
+		sum += total
+		debt.Nights = append(debt.Nights, night)
+	}
+
+	counted := debt.NightsWithData
+	if missingAsZero {
+		counted = days
+	}
+	if counted > 0 {
+		avg := sum / float64(counted)
+		debt.AverageHours = &avg
+	}
+	return debt
+}
+
+// GetSleepDebt returns sleep debt over the 14 nights ending on end's date.
+// targetHours <= 0 uses the configured target (8h by default).
+func (db *DB) GetSleepDebt(ctx context.Context, end time.Time, userID int, targetHours float64) (*SleepDebt, error) {
+	if targetHours <= 0 {
+		targetHours = db.sleepTargetHours
+	}
+	if targetHours <= 0 {
+		targetHours = DefaultSleepTargetHours
+	}
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(0, 0, -(sleepDebtWindowDays - 1))
+
+	sessions, err := db.QuerySleepSessions(ctx, start, end.AddDate(0, 0, 1), userID)
+	if err != nil {
+		return nil, err
+	}
+	totals := make(map[time.Time]float64, len(sessions))
+	for _, s := range sessions {
+		d := s.Date.UTC()
+		totals[time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)] = s.TotalSleep
+	}
+	return computeSleepDebt(totals, end, sleepDebtWindowDays, targetHours, db.sleepMissingNightsZero), nil
+}
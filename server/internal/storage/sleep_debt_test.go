@@ -0,0 +1,48 @@
+package storage
+
+import (
+	"testing"
+	"time"
+)
+
+// TestComputeSleepDebt verifies shortfalls accumulate, surplus nights don't
+// pay debt back, and missing nights are skipped rather than counted as zero.
+func TestComputeSleepDebt(t *testing.T) {
+	end := time.Date(2025, 5, 14, 0, 0, 0, 0, time.UTC)
+	totals := map[time.Time]float64{
+		end:                   6,   // 2h short
+		end.AddDate(0, 0, -1): 9,   // surplus, no payback
+		end.AddDate(0, 0, -2): 7.5, // 0.5h short
+	}
+
+	debt := computeSleepDebt(totals, end, 14, 8, false)
+	if debt.DebtHours != 2.5 {
+		t.Errorf("debt = %v, want 2.5", debt.DebtHours)
+	}
+	if debt.NightsWithData != 3 || debt.MissingNights != 11 {
+		t.Errorf("nights with data/missing = %d/%d, want 3/11", debt.NightsWithData, debt.MissingNights)
+	}
+	if debt.AverageHours == nil || *debt.AverageHours != 7.5 {
+		t.Errorf("average = %v, want 7.5", debt.AverageHours)
+	}
+	if len(debt.Nights) != 14 || !debt.Nights[13].Date.Equal(end) || debt.Nights[0].TotalSleep != nil {
+		t.Errorf("nights should cover the window oldest first with missing nights nil: %+v", debt.Nights[0])
+	}
+}
+
+// TestComputeSleepDebtMissingAsZero verifies the opt-in policy charges the
+// full target for every missing night.
+func TestComputeSleepDebtMissingAsZero(t *testing.T) {
+	end := time.Date(2025, 5, 14, 0, 0, 0, 0, time.UTC)
+	debt := computeSleepDebt(map[time.Time]float64{end: 8}, end, 3, 8, true)
+	if debt.DebtHours != 16 {
+		t.Errorf("debt = %v, want 16", debt.DebtHours)
+	}
+	if debt.AverageHours == nil || *debt.AverageHours != 8.0/3 {
+		t.Errorf("average = %v, want %v", debt.AverageHours, 8.0/3)
+	}
+
+	if none := computeSleepDebt(nil, end, 3, 8, false); none.AverageHours != nil || none.DebtHours != 0 {
+		t.Errorf("no data should mean no average and no debt, got %+v", none)
+	}
+}
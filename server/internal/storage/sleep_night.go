@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// HypnogramStage is one contiguous stage segment of a night, in time order.
+type HypnogramStage struct {
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Stage       string    `json:"stage"`
+	DurationMin float64   `json:"duration_min"`
+	Source      string    `json:"source"`
+}
+
+// SleepNight is a single night's session summary plus its ordered stages,
+// ready to render as a hypnogram. Session is nil when only stages exist.
+type SleepNight struct {
+	Date    time.Time           `json:"date"`
+	Session *SleepSessionResult `json:"session"`
+	Stages  []HypnogramStage    `json:"stages"`
+}
+
+// pickSleepNight returns the stages of the night filed under date, using the
+// same grouping and dating as session synthesis so the hypnogram always
+// matches the session. When several sources recorded the night, only the
+// source with the most staged time is kept; overlapping tracks from two
+// devices can't be drawn as one hypnogram.
+func pickSleepNight(stages []models.SleepStageRow, date time.Time, loc *time.Location, boundary SleepDayBoundary) []models.SleepStageRow {
+	var night []models.SleepStageRow
+	for _, n := range groupSleepNights(stages) {
+		if sleepNightDate(n[0].StartTime, n[len(n)-1].EndTime, loc, boundary).Equal(date) {
+			night = n
+			break
+		}
+	}
+	if len(night) == 0 {
+		return nil
+	}
+
+	bySource := make(map[string]float64)
+	for _, s := range night {
+		bySource[s.Source] += s.EndTime.Sub(s.StartTime).Hours()
+	}
+	if len(bySource) == 1 {
+		return night
+	}
+	best := night[0].Source
+	for src, hrs := range bySource {
+		if hrs > bySource[best] || (hrs == bySource[best] && src < best) {
+			best = src
+		}
+	}
+	out := make([]models.SleepStageRow, 0, len(night))
+	for _, s := range night {
+		if s.Source == best {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// GetSleepNight returns the session and hypnogram stages for the night filed
+// under date (a calendar date; the time of day is ignored). Returns nil when
+// there is neither a session nor stages for that night.
+func (db *DB) GetSleepNight(ctx context.Context, date time.Time, userID int) (*SleepNight, error) {
+	date = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+
+	sessions, err := db.QuerySleepSessions(ctx, date, date.AddDate(0, 0, 1), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// A night dated D has its midpoint within 12h of local midnight on D, so
+	// two days either side covers any night length and any UTC offset.
+	stages, err := db.QuerySleepStages(ctx, date.AddDate(0, 0, -2), date.AddDate(0, 0, 2), userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying night stages: %w", err)
+	}
+
+	night := &SleepNight{Date: date, Stages: []HypnogramStage{}}
+	if len(sessions) > 0 {
+		night.Session = &sessions[0]
+	}
+	for _, s := range pickSleepNight(stages, date, db.sleepLoc, db.sleepDay) {
+		night.Stages = append(night.Stages, HypnogramStage{
+			Start:       s.StartTime,
+			End:         s.EndTime,
+			Stage:       s.Stage,
+			DurationMin: s.EndTime.Sub(s.StartTime).Minutes(),
+			Source:      s.Source,
+		})
+	}
+	if night.Session == nil && len(night.Stages) == 0 {
+		return nil, nil
+	}
+	return night, nil
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// TestPickSleepNightSpansMidnight verifies a night that starts before and
+// ends after midnight is returned whole under its wake-up date, and that the
+// neighbouring nights in the query window are excluded.
+func TestPickSleepNightSpansMidnight(t *testing.T) {
+	day := time.Date(2025, 4, 9, 0, 0, 0, 0, time.UTC)
+	var stages []models.SleepStageRow
+	for d := -1; d <= 1; d++ {
+		stages = append(stages, nightStages(day.AddDate(0, 0, d))...)
+	}
+
+	got := pickSleepNight(stages, day.AddDate(0, 0, 1), nil, SleepDayMidnight)
+	if len(got) != 2 {
+		t.Fatalf("stages = %d, want 2", len(got))
+	}
+	if want := day.Add(23 * time.Hour); !got[0].StartTime.Equal(want) {
+		t.Errorf("night starts %v, want %v", got[0].StartTime, want)
+	}
+	if got[1].EndTime.Day() != 10 {
+		t.Errorf("night should end on the 10th, got %v", got[1].EndTime)
+	}
+
+	if got := pickSleepNight(stages, day.AddDate(0, 0, 5), nil, SleepDayMidnight); got != nil {
+		t.Errorf("date without a night = %v, want nil", got)
+	}
+}
+
+// TestPickSleepNightTimezone verifies the night is dated in the configured
+// zone, matching the date its synthesized session was stored under.
+func TestPickSleepNightTimezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skip("tzdata unavailable")
+	}
+	// A 11:00–15:00 Tokyo sleep (02:00–06:00 UTC) has its midpoint past local
+	// noon, so it files under the next local day but the same UTC day.
+	start := time.Date(2025, 4, 9, 2, 0, 0, 0, time.UTC)
+	stages := []models.SleepStageRow{
+		{StartTime: start, EndTime: start.Add(4 * time.Hour), Stage: "Core", DurationHr: 4},
+	}
+	next := time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC)
+	if got := pickSleepNight(stages, next, tokyo, SleepDayMidnight); len(got) != 1 {
+		t.Errorf("Tokyo sleep not found under its local date")
+	}
+	if got := pickSleepNight(stages, next, nil, SleepDayMidnight); got != nil {
+		t.Errorf("in UTC the sleep belongs to the 9th, got %v under the 10th", got)
+	}
+}
+
+// TestPickSleepNightSingleSource verifies overlapping tracks from two devices
+// are reduced to the one with the most staged time.
+func TestPickSleepNightSingleSource(t *testing.T) {
+	start := time.Date(2025, 4, 9, 23, 0, 0, 0, time.UTC)
+	stages := []models.SleepStageRow{
+		{StartTime: start, EndTime: start.Add(7 * time.Hour), Stage: "Core", Source: "Apple Watch"},
+		{StartTime: start.Add(30 * time.Minute), EndTime: start.Add(3 * time.Hour), Stage: "Deep", Source: "Oura"},
+		{StartTime: start.Add(3 * time.Hour), EndTime: start.Add(5 * time.Hour), Stage: "REM", Source: "Oura"},
+	}
+	got := pickSleepNight(stages, time.Date(2025, 4, 10, 0, 0, 0, 0, time.UTC), nil, SleepDayMidnight)
+	if len(got) != 1 || got[0].Source != "Apple Watch" {
+		t.Errorf("got %+v, want only the Apple Watch track", got)
+	}
+}
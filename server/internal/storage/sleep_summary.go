@@ -32,13 +32,54 @@ type sleepTimingRow struct {
 	sleepEnd   time.Time
 }
 
-// GetSleepSummary returns aggregated sleep stats per period with circular bedtime/waketime averages.
+// sleepDaySQL returns the SQL expression for the day a sleep_sessions row
+// belongs to. SleepDayMidnight uses the stored date; SleepDayNoon derives the
+// noon-to-noon sleep day from the sleep times like sleepNightDate, in the
+// zone given by tzParam, so a session stored before the boundary changed
+// lands on the same day as one stored after. Rows without sleep times keep
+// their date.
+func sleepDaySQL(boundary SleepDayBoundary, tzParam string) string {
+	if boundary != SleepDayNoon {
+		return "date"
+	}
+	return fmt.Sprintf(
+		"COALESCE(((sleep_start + (sleep_end - sleep_start) / 2) AT TIME ZONE %s - interval '12 hours')::date, date)",
+		tzParam)
+}
+
+// sleepDayRangeSQL returns the WHERE condition selecting rows whose
+// sleepDaySQL day is in [$2, $3). For SleepDayNoon the stored date, which
+// is at most a day from the derived one, is bounded first with a day's
+// slack either side for the zone, so the date index still narrows the scan.
+func sleepDayRangeSQL(boundary SleepDayBoundary, tzParam string) string {
+	day := sleepDaySQL(boundary, tzParam)
+	if boundary != SleepDayNoon {
+		return day + " >= $2 AND " + day + " < $3"
+	}
+	return "date >= $2::date - 2 AND date < $3::date + 2 AND " + day + " >= $2 AND " + day + " < $3"
+}
+
+// GetSleepSummary returns aggregated sleep stats per period with circular
+// bedtime/waketime averages. Nights are assigned to periods by the
+// configured sleep day boundary (see SetSleepDayBoundary). Each stored
+// session counts once; a night imported under both boundaries is only
+// deduplicated once it is re-imported (see InsertSleepSession).
 func (db *DB) GetSleepSummary(ctx context.Context, start, end time.Time, bucket string, userID int) ([]SleepSummaryPeriod, error) {
 	trunc := truncInterval(bucket)
+	day := sleepDaySQL(db.sleepDay, "$5")
+	inRange := sleepDayRangeSQL(db.sleepDay, "$5")
+	args := []any{trunc, start, end, userID}
+	if db.sleepDay == SleepDayNoon {
+		tz := "UTC"
+		if db.sleepLoc != nil {
+			tz = db.sleepLoc.String()
+		}
+		args = append(args, tz)
+	}
 
 	// Query 1: Aggregated duration/stage stats per period
 	aggRows, err := db.Pool.Query(ctx,
-		`SELECT date_trunc($1, date)::date AS period,
+		`SELECT date_trunc($1, `+day+`)::date AS period,
 		        COUNT(*)::int AS nights,
 		        AVG(total_sleep),
 		        AVG(deep),
@@ -49,10 +90,10 @@ func (db *DB) GetSleepSummary(ctx context.Context, start, end time.Time, bucket
 		        AVG(CASE WHEN total_sleep > 0 THEN deep / total_sleep * 100 ELSE 0 END),
 		        AVG(CASE WHEN total_sleep > 0 THEN rem / total_sleep * 100 ELSE 0 END)
 		 FROM sleep_sessions
-		 WHERE date >= $2 AND date < $3 AND user_id = $4
+		 WHERE `+inRange+` AND user_id = $4
 		 GROUP BY period
 		 ORDER BY period DESC`,
-		trunc, start, end, userID)
+		args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying sleep summary: %w", err)
 	}
@@ -79,11 +120,11 @@ func (db *DB) GetSleepSummary(ctx context.Context, start, end time.Time, bucket
 
 	// Query 2: Raw sleep_start/sleep_end for circular mean computation
 	timingRows, err := db.Pool.Query(ctx,
-		`SELECT date_trunc($1, date)::date AS period, sleep_start, sleep_end
+		`SELECT date_trunc($1, `+day+`)::date AS period, sleep_start, sleep_end
 		 FROM sleep_sessions
-		 WHERE date >= $2 AND date < $3 AND user_id = $4
-		 ORDER BY period, date`,
-		trunc, start, end, userID)
+		 WHERE `+inRange+` AND user_id = $4
+		 ORDER BY period, `+day,
+		args...)
 	if err != nil {
 		return nil, fmt.Errorf("querying sleep timing: %w", err)
 	}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"math"
+	"strings"
 	"testing"
 )
 
@@ -107,3 +108,33 @@ func TestTruncInterval(t *testing.T) {
 		}
 	}
 }
+
+// TestSleepDaySQL verifies the midnight boundary groups by the stored date
+// and the noon boundary by the zone-local sleep midpoint shifted back 12h.
+func TestSleepDaySQL(t *testing.T) {
+	if got := sleepDaySQL(SleepDayMidnight, "$5"); got != "date" {
+		t.Errorf("midnight = %q, want date", got)
+	}
+	got := sleepDaySQL(SleepDayNoon, "$5")
+	for _, want := range []string{"sleep_start + (sleep_end - sleep_start) / 2", "AT TIME ZONE $5", "- interval '12 hours'", ", date)"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("noon expression missing %q: %s", want, got)
+		}
+	}
+}
+
+// TestSleepDayRangeSQL verifies the noon boundary bounds the indexed date
+// column before filtering on the derived day, while midnight filters the
+// date directly.
+func TestSleepDayRangeSQL(t *testing.T) {
+	if got := sleepDayRangeSQL(SleepDayMidnight, "$5"); got != "date >= $2 AND date < $3" {
+		t.Errorf("midnight = %q", got)
+	}
+	got := sleepDayRangeSQL(SleepDayNoon, "$5")
+	if !strings.HasPrefix(got, "date >= $2::date - 2 AND date < $3::date + 2 AND ") {
+		t.Errorf("noon range should bound the stored date first: %s", got)
+	}
+	if !strings.Contains(got, sleepDaySQL(SleepDayNoon, "$5")+" >= $2") {
+		t.Errorf("noon range missing derived day filter: %s", got)
+	}
+}
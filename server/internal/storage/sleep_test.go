@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// nightStages returns a Core+Deep pair for a night starting at 23:00 UTC on day.
+func nightStages(day time.Time) []models.SleepStageRow {
+	start := day.Add(23 * time.Hour)
+	return []models.SleepStageRow{
+		{StartTime: start, EndTime: start.Add(4 * time.Hour), Stage: "Core", DurationHr: 4},
+		{StartTime: start.Add(4 * time.Hour), EndTime: start.Add(7 * time.Hour), Stage: "Deep", DurationHr: 3},
+	}
+}
+
+// fakeSleepBackfillStore holds one user's stages and sessions in memory,
+// recording where each stage scan started.
+type fakeSleepBackfillStore struct {
+	stages   []models.SleepStageRow
+	sessions map[time.Time]models.SleepSessionRow // keyed like (user_id, date)
+	metrics  []models.HealthMetricRow
+	scans    []time.Time
+}
+
+func (f *fakeSleepBackfillStore) SleepStageUserIDs(context.Context) ([]int, error) {
+	return []int{1}, nil
+}
+
+func (f *fakeSleepBackfillStore) latestSleepSessionEnd(context.Context, int) (*time.Time, error) {
+	var latest *time.Time
+	for _, s := range f.sessions {
+		if latest == nil || s.SleepEnd.After(*latest) {
+			end := s.SleepEnd
+			latest = &end
+		}
+	}
+	return latest, nil
+}
+
+func (f *fakeSleepBackfillStore) QuerySleepStages(_ context.Context, start, end time.Time, _ int) ([]models.SleepStageRow, error) {
+	f.scans = append(f.scans, start)
+	var out []models.SleepStageRow
+	for _, s := range f.stages {
+		if !s.StartTime.Before(start) && s.StartTime.Before(end) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeSleepBackfillStore) insertBackfillSleepSession(_ context.Context, session models.SleepSessionRow) (bool, error) {
+	if _, ok := f.sessions[session.Date]; ok {
+		return false, nil
+	}
+	f.sessions[session.Date] = session
+	return true, nil
+}
+
+func (f *fakeSleepBackfillStore) InsertHealthMetrics(_ context.Context, rows []models.HealthMetricRow) (int64, error) {
+	f.metrics = append(f.metrics, rows...)
+	return int64(len(rows)), nil
+}
+
+// TestIncrementalSleepBackfillOneNewNight verifies that after history has
+// been turned into sessions, adding one new night makes the incremental pass
+// scan from the latest session's end and create exactly one session, while a
+// night that already has a session from a direct source is left alone.
+func TestIncrementalSleepBackfillOneNewNight(t *testing.T) {
+	ctx := context.Background()
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	day0 := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	store := &fakeSleepBackfillStore{sessions: map[time.Time]models.SleepSessionRow{}}
+	for d := 0; d < 30; d++ {
+		store.stages = append(store.stages, nightStages(day0.AddDate(0, 0, d))...)
+	}
+
+	if err := backfillSleepSessions(ctx, store, log, nil, SleepDayMidnight); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.sessions) != 30 || len(store.metrics) != 30 {
+		t.Fatalf("history: sessions = %d, metrics = %d, want 30 each", len(store.sessions), len(store.metrics))
+	}
+	latest, _ := store.latestSleepSessionEnd(ctx, 1)
+
+	store.stages = append(store.stages, nightStages(day0.AddDate(0, 0, 30))...)
+	store.scans, store.metrics = nil, nil
+	if err := backfillSleepSessions(ctx, store, log, nil, SleepDayMidnight); err != nil {
+		t.Fatal(err)
+	}
+	if len(store.scans) != 1 || !store.scans[0].Equal(*latest) {
+		t.Fatalf("scans = %v, want one starting at %v", store.scans, *latest)
+	}
+	if len(store.sessions) != 31 || len(store.metrics) != 1 {
+		t.Fatalf("sessions = %d, metrics = %d, want 31 and 1 new", len(store.sessions), len(store.metrics))
+	}
+	if got := store.sessions[day0.AddDate(0, 0, 31)]; got.TotalSleep != 7 {
+		t.Errorf("new night total_sleep = %v, want 7", got.TotalSleep)
+	}
+
+	// A direct-source session already covers the next night: the backfill
+	// must not overwrite it or add a metric for it.
+	direct := models.SleepSessionRow{Date: day0.AddDate(0, 0, 32), TotalSleep: 6.5}
+	store.sessions[direct.Date] = direct
+	store.stages = append(store.stages, nightStages(day0.AddDate(0, 0, 31))...)
+	store.metrics = nil
+	if err := backfillSleepSessions(ctx, store, log, nil, SleepDayMidnight); err != nil {
+		t.Fatal(err)
+	}
+	if got := store.sessions[direct.Date]; got.TotalSleep != 6.5 || len(store.metrics) != 0 {
+		t.Errorf("direct session total_sleep = %v, metrics = %d; want 6.5 untouched and none added", got.TotalSleep, len(store.metrics))
+	}
+}
+
+// TestSleepBackfillStartNoSessions verifies a user with no sessions yet gets
+// a full scan from the epoch.
+func TestSleepBackfillStartNoSessions(t *testing.T) {
+	if got := sleepBackfillStart(nil); !got.Equal(sleepBackfillEpoch) {
+		t.Errorf("start = %v, want %v", got, sleepBackfillEpoch)
+	}
+}
+
+// TestSleepNightDateJitteredReimport verifies that importing the same night
+// twice with boundaries shifted across midnight UTC (a UTC+7 sleeper waking
+// at 07:00 local) files both under one date, so ON CONFLICT (user_id, date)
+// yields exactly one session instead of one per adjacent day.
+func TestSleepNightDateJitteredReimport(t *testing.T) {
+	bangkok := time.FixedZone("UTC+7", 7*3600)
+	bed := time.Date(2025, 6, 9, 23, 0, 0, 0, bangkok)
+	first := []models.SleepStageRow{
+		{StartTime: bed, EndTime: bed.Add(8*time.Hour + 10*time.Minute), Stage: "Core", DurationHr: 8.1},
+	}
+	second := []models.SleepStageRow{
+		{StartTime: bed.Add(-15 * time.Minute), EndTime: bed.Add(7*time.Hour + 50*time.Minute), Stage: "Core", DurationHr: 8.1},
+	}
+	if first[0].EndTime.UTC().Day() == second[0].EndTime.UTC().Day() {
+		t.Fatal("test setup: wake times should straddle midnight UTC")
+	}
+
+	want := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	for _, loc := range []*time.Location{bangkok, nil} {
+		sessions := map[time.Time]bool{} // keyed like the (user_id, date) constraint
+		for _, night := range [][]models.SleepStageRow{first, second} {
+			sessions[sleepSessionFromNight(1, night, loc, SleepDayMidnight).Date] = true
+		}
+		if len(sessions) != 1 {
+			t.Errorf("loc=%v: sessions = %v, want exactly one", loc, sessions)
+		}
+		if loc != nil && !sessions[want] {
+			t.Errorf("loc=%v: sessions = %v, want wake date %s", loc, sessions, want.Format("2006-01-02"))
+		}
+	}
+
+	// Both imports' stages end up in sleep_stages; regrouped they are one night.
+	if nights := groupSleepNights(append(append([]models.SleepStageRow(nil), first...), second...)); len(nights) != 1 {
+		t.Errorf("regrouped nights = %d, want 1", len(nights))
+	}
+}
+
+// TestSleepNightDateNoonBoundary verifies a 1am bedtime is filed under its
+// wake-up date by default but under the prior sleep day with the noon
+// boundary, and that an afternoon nap joins the sleep day that follows it.
+func TestSleepNightDateNoonBoundary(t *testing.T) {
+	berlin := time.FixedZone("UTC+1", 3600)
+	bed := time.Date(2025, 2, 7, 1, 0, 0, 0, berlin)
+	wake := bed.Add(7 * time.Hour)
+	feb6 := time.Date(2025, 2, 6, 0, 0, 0, 0, time.UTC)
+	feb7 := feb6.AddDate(0, 0, 1)
+
+	if got := sleepNightDate(bed, wake, berlin, SleepDayMidnight); !got.Equal(feb7) {
+		t.Errorf("midnight boundary = %s, want 2025-02-07", got.Format("2006-01-02"))
+	}
+	if got := sleepNightDate(bed, wake, berlin, SleepDayNoon); !got.Equal(feb6) {
+		t.Errorf("noon boundary = %s, want 2025-02-06", got.Format("2006-01-02"))
+	}
+
+	nap := time.Date(2025, 2, 6, 15, 0, 0, 0, berlin)
+	if got := sleepNightDate(nap, nap.Add(time.Hour), berlin, SleepDayNoon); !got.Equal(feb6) {
+		t.Errorf("nap = %s, want 2025-02-06", got.Format("2006-01-02"))
+	}
+
+	night := []models.SleepStageRow{{StartTime: bed, EndTime: wake, Stage: "Core", DurationHr: 7}}
+	if got := sleepSessionFromNight(1, night, berlin, SleepDayNoon).Date; !got.Equal(feb6) {
+		t.Errorf("synthesized session = %s, want 2025-02-06", got.Format("2006-01-02"))
+	}
+}
+
+// TestSleepSessionDate verifies source-dated sessions are re-dated only under
+// the noon boundary and only when their sleep times are known.
+func TestSleepSessionDate(t *testing.T) {
+	bed := time.Date(2025, 2, 7, 1, 0, 0, 0, time.UTC)
+	feb6 := time.Date(2025, 2, 6, 0, 0, 0, 0, time.UTC)
+	feb7 := feb6.AddDate(0, 0, 1)
+	row := models.SleepSessionRow{Date: feb7, SleepStart: bed, SleepEnd: bed.Add(7 * time.Hour)}
+
+	if got := sleepSessionDate(row, nil, SleepDayMidnight); !got.Equal(feb7) {
+		t.Errorf("midnight boundary = %s, want the source date", got.Format("2006-01-02"))
+	}
+	if got := sleepSessionDate(row, nil, SleepDayNoon); !got.Equal(feb6) {
+		t.Errorf("noon boundary = %s, want 2025-02-06", got.Format("2006-01-02"))
+	}
+	if got := sleepSessionDate(models.SleepSessionRow{Date: feb7}, nil, SleepDayNoon); !got.Equal(feb7) {
+		t.Errorf("without sleep times = %s, want the source date", got.Format("2006-01-02"))
+	}
+}
+
+// TestSleepStageWhere verifies the stage filter and the pagination cursor
+// each add a bound condition after the fixed user and range placeholders.
+func TestSleepStageWhere(t *testing.T) {
+	base := "user_id = $1 AND start_time >= $2 AND start_time < $3"
+	after := time.Date(2025, 3, 2, 1, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		stages    []string
+		after     *time.Time
+		wantWhere string
+		wantArgs  []any
+	}{
+		{"unfiltered", nil, nil, base, nil},
+		{"filtered", []string{"Deep", "REM"}, nil, base + " AND stage = ANY($4)", []any{[]string{"Deep", "REM"}}},
+		{"paginated", nil, &after, base + " AND start_time > $4", []any{after}},
+		{"filtered and paginated", []string{"Awake"}, &after,
+			base + " AND stage = ANY($4) AND start_time > $5", []any{[]string{"Awake"}, after}},
+	}
+	for _, tt := range tests {
+		where, args := sleepStageWhere(tt.stages, tt.after)
+		if where != tt.wantWhere {
+			t.Errorf("%s: where = %q, want %q", tt.name, where, tt.wantWhere)
+		}
+		if !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("%s: args = %v, want %v", tt.name, args, tt.wantArgs)
+		}
+	}
+}
+
+// TestSupersededSleepSQL verifies storing a night removes only the same
+// user's copy of it under an adjacent date, matched by overlapping sleep.
+func TestSupersededSleepSQL(t *testing.T) {
+	for _, want := range []string{
+		"DELETE FROM sleep_sessions",
+		"user_id = $1 AND date <> $2",
+		"date BETWEEN $2::date - 1 AND $2::date + 1",
+		"sleep_start < $4 AND sleep_end > $3",
+	} {
+		if !strings.Contains(supersededSleepSQL, want) {
+			t.Errorf("supersededSleepSQL missing %q:\n%s", want, supersededSleepSQL)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 )
 
@@ -45,7 +46,8 @@ func (db *DB) UpsertSourcePriority(ctx context.Context, userID int, category str
 	if err != nil {
 		return fmt.Errorf("upserting source priority: %w", err)
 	}
-	return nil
+	// Priorities pick which source's rows queries keep.
+	return markDataChanged(ctx, db.Pool, userID, freshnessTables...)
 }
 
 // DeleteSourcePriority removes a category override for a user (falls back to _default).
@@ -56,7 +58,7 @@ func (db *DB) DeleteSourcePriority(ctx context.Context, userID int, category str
 	if err != nil {
 		return fmt.Errorf("deleting source priority: %w", err)
 	}
-	return nil
+	return markDataChanged(ctx, db.Pool, userID, freshnessTables...)
 }
 
 // GetDistinctSources returns all distinct source values from health_metrics for a user.
@@ -80,6 +82,62 @@ func (db *DB) GetDistinctSources(ctx context.Context, userID int) ([]string, err
 	return result, rows.Err()
 }
 
+// --- Canonical source selection ---
+
+// sourceRule is one source priority entry. A named entry matches sources
+// starting with it ("Apple Watch" covers "Apple Watch Series 9"); "" only
+// matches an empty source. SourceRank and sourcePriorityCaseSQL both match
+// through it, so Go and SQL dedup rank sources the same way.
+type sourceRule string
+
+// matches reports whether source falls under the rule.
+func (r sourceRule) matches(source string) bool {
+	if r == "" {
+		return source == ""
+	}
+	return strings.HasPrefix(source, string(r))
+}
+
+// likeEscaper quotes a rule for a LIKE pattern: wildcards are escaped so
+// they match literally, as in matches, and quotes are doubled.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `'`, `''`)
+
+// conditionSQL returns the rule as a SQL condition on the source column.
+func (r sourceRule) conditionSQL() string {
+	if r == "" {
+		return "source = ''"
+	}
+	return "source LIKE '" + likeEscaper.Replace(string(r)) + "%'"
+}
+
+// SourceRank returns a source's 1-based rank under priorities, as the SQL
+// built by sourcePriorityCaseSQL ranks it. Unlisted sources rank last
+// (len(priorities)+1). With no priorities every source ranks 1.
+func SourceRank(priorities []string, source string) int {
+	for i, p := range priorities {
+		if sourceRule(p).matches(source) {
+			return i + 1
+		}
+	}
+	return len(priorities) + 1
+}
+
+// CanonicalSource picks which of n rows from one time window to keep: the
+// one whose source ranks highest under priorities. prefer(i, j) breaks ties
+// between equally ranked rows (true keeps i over j); nil keeps the first.
+// Returns -1 for n == 0. Go-side dedup should use this so it resolves sources
+// the same way as the SQL dedup.
+func CanonicalSource(priorities []string, n int, source func(i int) string, prefer func(i, j int) bool) int {
+	best, bestRank := -1, 0
+	for i := 0; i < n; i++ {
+		rank := SourceRank(priorities, source(i))
+		if best < 0 || rank < bestRank || (rank == bestRank && prefer != nil && prefer(i, best)) {
+			best, bestRank = i, rank
+		}
+	}
+	return best
+}
+
 // --- Priority resolver with caching ---
 
 // metricCategoryCache caches the metric_name → category mapping from the allowlist.
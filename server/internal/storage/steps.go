@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DailySteps is the deduplicated step total for one day, with days starting
+// at the configured day start hour in the configured timezone.
+type DailySteps struct {
+	Date  string  `json:"date"`
+	Steps float64 `json:"steps"`
+}
+
+// hourlySourceSteps is one source's step sum for one hour.
+type hourlySourceSteps struct {
+	hour   time.Time
+	source string
+	steps  float64
+}
+
+// GetDailySteps returns per-day step totals without double-counting devices
+// that track the same walk. iPhone and Apple Watch both record step_count for
+// overlapping intervals, so summing every row inflates totals. Instead, steps
+// are summed per source per hour, one source is kept for each hour, and those
+// hourly sums are added up per day. The kept source is the highest under the
+// user's source priority; among equally ranked sources the one with the most
+// steps wins (whichever device was actually carried). Ranges reaching back
+// past step_count's retention watermark read its rollups there, as
+// GetTimeSeries does.
+func (db *DB) GetDailySteps(ctx context.Context, start, end time.Time, userID int) ([]DailySteps, error) {
+	priorities := db.ResolveSourcePriorityForMetric(ctx, userID, "step_count")
+	watermark, _, err := db.rollupWatermark(ctx, "step_count")
+	if err != nil {
+		return nil, err
+	}
+	args := []any{userID, start, end}
+	withRollup := watermark != nil && start.Before(*watermark)
+	if withRollup {
+		args = append(args, *watermark)
+	}
+	rows, err := db.Pool.Query(ctx, hourlyStepsSQL(withRollup, db.sleepLoc), args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying daily steps: %w", err)
+	}
+	defer rows.Close()
+
+	var hourly []hourlySourceSteps
+	for rows.Next() {
+		var h hourlySourceSteps
+		if err := rows.Scan(&h.hour, &h.source, &h.steps); err != nil {
+			return nil, fmt.Errorf("scanning hourly steps: %w", err)
+		}
+		hourly = append(hourly, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating hourly steps: %w", err)
+	}
+	return dailyStepsFromHourly(hourly, priorities, db.sleepLoc, db.dayStartHour), nil
+}
+
+// hourlyStepsSQL sums step_count per source per hour for user $1 in
+// [$2, $3). With withRollup, raw rows are read from the watermark $4 on and
+// health_metric_rollups supply the range before it, split like
+// rollupSamplesSQL but keeping every source so dailyStepsFromHourly can pick
+// one per hour. Rollups coarser than an hour land in their bucket's first
+// hour. Hours are aligned in loc, so half-hour zones split days cleanly.
+func hourlyStepsSQL(withRollup bool, loc *time.Location) string {
+	raw := `SELECT time, source, COALESCE(qty, 0) AS steps
+			FROM health_metrics
+			WHERE metric_name = 'step_count' AND user_id = $1 AND time >= $2 AND time < $3
+			  AND ` + uncoveredRowsSQL
+	if withRollup {
+		raw += ` AND time >= $4
+			UNION ALL
+			SELECT time, source, COALESCE(qty, 0)
+			FROM health_metric_rollups
+			WHERE metric_name = 'step_count' AND user_id = $1 AND time >= $2 AND time < LEAST($3, $4)`
+	}
+	return `SELECT ` + timeBucketSQL("interval '1 hour'", "time", loc, 0) + ` AS hour, source, SUM(steps)
+		 FROM (` + raw + `) samples
+		 GROUP BY hour, source`
+}
+
+// dailyStepsFromHourly keeps the canonical source per hour and sums hours
+// per day, assigning hours to days with dayStart like daily buckets.
+func dailyStepsFromHourly(hourly []hourlySourceSteps, priorities []string, loc *time.Location, dayStartHour int) []DailySteps {
+	byHour := make(map[time.Time][]hourlySourceSteps)
+	for _, h := range hourly {
+		byHour[h.hour] = append(byHour[h.hour], h)
+	}
+
+	days := make(map[string]float64)
+	for hour, rows := range byHour {
+		i := CanonicalSource(priorities, len(rows),
+			func(i int) string { return rows[i].source },
+			func(i, j int) bool { return rows[i].steps > rows[j].steps })
+		days[dayStart(hour, loc, dayStartHour).Format("2006-01-02")] += rows[i].steps
+	}
+
+	result := make([]DailySteps, 0, len(days))
+	for date, steps := range days {
+		result = append(result, DailySteps{Date: date, Steps: steps})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Date < result[j].Date })
+	return result
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestDailyStepsFromHourlyOverlappingSources verifies that an iPhone and an
+// Apple Watch reporting the same hour count once (the higher of the two),
+// while hours only one device saw are still added.
+func TestDailyStepsFromHourlyOverlappingSources(t *testing.T) {
+	h9 := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	h10 := h9.Add(time.Hour)
+	next := h9.AddDate(0, 0, 1)
+
+	got := dailyStepsFromHourly([]hourlySourceSteps{
+		{hour: h9, source: "iPhone", steps: 1200},
+		{hour: h9, source: "Apple Watch", steps: 1500},
+		{hour: h10, source: "iPhone", steps: 300},
+		{hour: next, source: "Apple Watch", steps: 800},
+	}, nil, nil, 0)
+
+	if len(got) != 2 {
+		t.Fatalf("days = %d, want 2: %+v", len(got), got)
+	}
+	if got[0].Date != "2025-04-02" || got[0].Steps != 1800 {
+		t.Errorf("day 1 = %+v, want 2025-04-02 / 1800 (1500 + 300, not 3000)", got[0])
+	}
+	if got[1].Date != "2025-04-03" || got[1].Steps != 800 {
+		t.Errorf("day 2 = %+v, want 2025-04-03 / 800", got[1])
+	}
+}
+
+// TestDailyStepsFromHourlySourcePriority verifies a configured priority
+// overrides the most-steps rule, and that sources the priority doesn't list
+// still fall back to the most steps.
+func TestDailyStepsFromHourlySourcePriority(t *testing.T) {
+	h9 := time.Date(2025, 4, 2, 9, 0, 0, 0, time.UTC)
+	hourly := []hourlySourceSteps{
+		{hour: h9, source: "iPhone", steps: 1200},
+		{hour: h9, source: "Apple Watch Series 9", steps: 1000},
+		{hour: h9.Add(time.Hour), source: "Pixel", steps: 400},
+		{hour: h9.Add(time.Hour), source: "Garmin", steps: 600},
+	}
+
+	got := dailyStepsFromHourly(hourly, []string{"Apple Watch", "iPhone"}, nil, 0)
+	if len(got) != 1 || got[0].Steps != 1600 {
+		t.Errorf("got %+v, want 1600 (watch 1000 + max unlisted 600)", got)
+	}
+}
+
+// TestHourlyStepsSQLWithWatermark verifies that once step_count is rolled up,
+// raw rows are read from the watermark on and rollups, per source, before
+// it, so days whose raw rows were deleted still have a total.
+func TestHourlyStepsSQLWithWatermark(t *testing.T) {
+	plain := hourlyStepsSQL(false, nil)
+	if strings.Contains(plain, "health_metric_rollups") || strings.Contains(plain, "$4") {
+		t.Errorf("no watermark but reads rollups:\n%s", plain)
+	}
+
+	sql := hourlyStepsSQL(true, nil)
+	for _, want := range []string{
+		"AND time >= $4",
+		"UNION ALL",
+		"FROM health_metric_rollups",
+		"time < LEAST($3, $4)",
+		"GROUP BY hour, source",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("query missing %q:\n%s", want, sql)
+		}
+	}
+}
+
+// TestDailyStepsFromHourlyDayBoundary verifies hours are filed under the day
+// they belong to in the configured zone and day start, not the UTC date.
+func TestDailyStepsFromHourlyDayBoundary(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata unavailable")
+	}
+	// 02:00 UTC on April 3rd is 22:00 on April 2nd in New York.
+	late := time.Date(2025, 4, 3, 2, 0, 0, 0, time.UTC)
+	hourly := []hourlySourceSteps{{hour: late, source: "iPhone", steps: 500}}
+
+	if got := dailyStepsFromHourly(hourly, nil, ny, 0); len(got) != 1 || got[0].Date != "2025-04-02" {
+		t.Errorf("New York = %+v, want 2025-04-02", got)
+	}
+	// With days starting at 04:00 UTC, 02:00 still counts toward April 2nd.
+	if got := dailyStepsFromHourly(hourly, nil, nil, 4); len(got) != 1 || got[0].Date != "2025-04-02" {
+		t.Errorf("4am start = %+v, want 2025-04-02", got)
+	}
+	if got := dailyStepsFromHourly(hourly, nil, nil, 0); len(got) != 1 || got[0].Date != "2025-04-03" {
+		t.Errorf("UTC = %+v, want 2025-04-03", got)
+	}
+}
@@ -0,0 +1,112 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/claude/freereps/internal/ingest"
+	"github.com/google/uuid"
+)
+
+// SwimWorkout is one swim with distance normalized to metres.
+type SwimWorkout struct {
+	ID           uuid.UUID `json:"id"`
+	StartTime    time.Time `json:"start_time"`
+	DurationSec  float64   `json:"duration_sec"`
+	DistanceM    *float64  `json:"distance_m"`
+	PoolLengthM  *float64  `json:"pool_length_m"`
+	TotalStrokes *int      `json:"total_strokes"`
+	LapCount     *int      `json:"lap_count"`
+	PacePer100m  *float64  `json:"pace_per_100m_sec"`
+	SWOLF        *float64  `json:"swolf"`
+}
+
+// SwimStats summarizes swims in a time range.
+type SwimStats struct {
+	Workouts       int           `json:"workouts"`
+	TotalDistanceM float64       `json:"total_distance_m"`
+	TotalDuration  float64       `json:"total_duration_sec"`
+	PacePer100m    *float64      `json:"avg_pace_per_100m_sec"`
+	AvgSWOLF       *float64      `json:"avg_swolf"`
+	Swims          []SwimWorkout `json:"swims"`
+}
+
+// GetSwimStats returns per-swim pace and SWOLF plus range totals. Swims are
+// picked with ingest.IsSwimWorkout, the same check that decides at ingest
+// whether swim fields are parsed, so the two can't disagree on a name.
+func (db *DB) GetSwimStats(ctx context.Context, start, end time.Time, userID int) (*SwimStats, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id, name, start_time, duration_sec, distance, COALESCE(distance_units, ''),
+		        pool_length, total_strokes, lap_count
+		 FROM workouts
+		 WHERE user_id = $1 AND start_time >= $2 AND start_time < $3
+		 ORDER BY start_time DESC`,
+		userID, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("querying swims: %w", err)
+	}
+	defer rows.Close()
+
+	var swims []SwimWorkout
+	for rows.Next() {
+		var s SwimWorkout
+		var name, units string
+		var distance *float64
+		if err := rows.Scan(&s.ID, &name, &s.StartTime, &s.DurationSec, &distance, &units,
+			&s.PoolLengthM, &s.TotalStrokes, &s.LapCount); err != nil {
+			return nil, fmt.Errorf("scanning swim: %w", err)
+		}
+		if !ingest.IsSwimWorkout(name) {
+			continue
+		}
+		if distance != nil {
+			if m, ok := ingest.ConvertUnit(*distance, units, "m"); ok {
+				s.DistanceM = &m
+			}
+		}
+		swims = append(swims, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating swims: %w", err)
+	}
+
+	stats := summarizeSwims(swims)
+	return &stats, nil
+}
+
+// summarizeSwims fills per-swim pace/SWOLF and aggregates totals. SWOLF
+// (seconds + strokes per length) needs both lap and stroke counts, so it is
+// left nil for open-water swims and pools without stroke data.
+func summarizeSwims(swims []SwimWorkout) SwimStats {
+	stats := SwimStats{Workouts: len(swims), Swims: swims}
+	var pacedDist, pacedDur, swolfSum float64
+	var swolfN int
+	for i := range swims {
+		s := &swims[i]
+		if s.DistanceM != nil && *s.DistanceM > 0 {
+			pace := s.DurationSec / *s.DistanceM * 100
+			s.PacePer100m = &pace
+			stats.TotalDistanceM += *s.DistanceM
+			pacedDist += *s.DistanceM
+			pacedDur += s.DurationSec
+		}
+		stats.TotalDuration += s.DurationSec
+		if s.LapCount != nil && *s.LapCount > 0 && s.TotalStrokes != nil && *s.TotalStrokes > 0 {
+			laps := float64(*s.LapCount)
+			swolf := s.DurationSec/laps + float64(*s.TotalStrokes)/laps
+			s.SWOLF = &swolf
+			swolfSum += swolf
+			swolfN++
+		}
+	}
+	if pacedDist > 0 {
+		pace := pacedDur / pacedDist * 100
+		stats.PacePer100m = &pace
+	}
+	if swolfN > 0 {
+		avg := swolfSum / float64(swolfN)
+		stats.AvgSWOLF = &avg
+	}
+	return stats
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"math"
+	"testing"
+)
+
+// TestSummarizeSwims verifies pace is weighted by distance across swims and
+// SWOLF is only computed (and averaged) for swims with lap and stroke counts.
+func TestSummarizeSwims(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	n := func(v int) *int { return &v }
+	swims := []SwimWorkout{
+		// Pool: 1000m in 20min, 40 laps, 800 strokes → SWOLF 30 + 20 = 50.
+		{DurationSec: 1200, DistanceM: f(1000), LapCount: n(40), TotalStrokes: n(800)},
+		// Open water: no laps, so no SWOLF.
+		{DurationSec: 1800, DistanceM: f(1500)},
+	}
+
+	stats := summarizeSwims(swims)
+	if stats.Workouts != 2 || stats.TotalDistanceM != 2500 || stats.TotalDuration != 3000 {
+		t.Errorf("totals = %+v", stats)
+	}
+	if stats.PacePer100m == nil || math.Abs(*stats.PacePer100m-120) > 1e-9 {
+		t.Errorf("pace = %v, want 120 s/100m", stats.PacePer100m)
+	}
+	if stats.AvgSWOLF == nil || *stats.AvgSWOLF != 50 {
+		t.Errorf("avg swolf = %v, want 50", stats.AvgSWOLF)
+	}
+	if stats.Swims[1].SWOLF != nil {
+		t.Errorf("open-water swim SWOLF = %v, want nil", *stats.Swims[1].SWOLF)
+	}
+	if stats.Swims[0].PacePer100m == nil || *stats.Swims[0].PacePer100m != 120 {
+		t.Errorf("per-swim pace = %v, want 120", stats.Swims[0].PacePer100m)
+	}
+}
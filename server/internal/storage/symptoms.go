@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// InsertSymptoms batch-inserts symptom rows. Returns count inserted.
+// Uses ON CONFLICT DO NOTHING on (user_id, name, start_time, source).
+func (db *DB) InsertSymptoms(ctx context.Context, rows []models.SymptomRow) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	query := `INSERT INTO symptoms (user_id, name, severity, start_time, end_time, user_entered, source) VALUES `
+	args := make([]any, 0, len(rows)*7)
+	valueStrings := make([]string, 0, len(rows))
+
+	for i, r := range rows {
+		base := i * 7
+		valueStrings = append(valueStrings, fmt.Sprintf(
+			"($%d,$%d,$%d,$%d,$%d,$%d,$%d)",
+			base+1, base+2, base+3, base+4, base+5, base+6, base+7,
+		))
+		args = append(args, r.UserID, r.Name, r.Severity, r.StartTime, r.EndTime,
+			r.UserEntered, r.Source)
+	}
+
+	query += strings.Join(valueStrings, ",") + " ON CONFLICT DO NOTHING"
+
+	tag, err := db.Pool.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("inserting symptoms: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// GetSymptoms retrieves symptoms starting in [start, end) for a user,
+// optionally filtered by name (case-insensitive).
+func (db *DB) GetSymptoms(ctx context.Context, start, end time.Time, userID int, name string) ([]models.SymptomRow, error) {
+	query := `SELECT user_id, name, severity, start_time, end_time, user_entered, source
+		 FROM symptoms
+		 WHERE start_time >= $1 AND start_time < $2 AND user_id = $3`
+	args := []any{start, end, userID}
+	if name != "" {
+		query += ` AND lower(name) = lower($4)`
+		args = append(args, name)
+	}
+	query += ` ORDER BY start_time DESC`
+
+	rows, err := db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("querying symptoms: %w", err)
+	}
+	defer rows.Close()
+
+	var result []models.SymptomRow
+	for rows.Next() {
+		var r models.SymptomRow
+		if err := rows.Scan(&r.UserID, &r.Name, &r.Severity, &r.StartTime, &r.EndTime,
+			&r.UserEntered, &r.Source); err != nil {
+			return nil, fmt.Errorf("scanning symptom: %w", err)
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// SymptomCorrelation compares a metric's daily average on days a symptom was
+// logged with the other days in the range. LagDays shifts the metric back,
+// e.g. 1 compares each symptom day with the previous day's value. Means are
+// nil when no day of that kind has a metric value.
+type SymptomCorrelation struct {
+	Symptom        string   `json:"symptom"`
+	Metric         string   `json:"metric"`
+	LagDays        int      `json:"lag_days"`
+	SymptomDays    int      `json:"symptom_days"`
+	OtherDays      int      `json:"other_days"`
+	SymptomDayMean *float64 `json:"symptom_day_mean"`
+	OtherDayMean   *float64 `json:"other_day_mean"`
+	Difference     *float64 `json:"difference"`
+}
+
+// CorrelateSymptoms aligns each symptom logged in [start, end) against the
+// daily values of metric, one SymptomCorrelation per symptom name.
+func (db *DB) CorrelateSymptoms(ctx context.Context, start, end time.Time, metric string, lagDays int, userID int) ([]SymptomCorrelation, error) {
+	symptoms, err := db.GetSymptoms(ctx, start, end, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	points, err := db.GetTimeSeries(ctx, metric, start.AddDate(0, 0, -lagDays), end, "1 day", userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying %s for symptom correlation: %w", metric, err)
+	}
+	return correlateSymptoms(symptoms, points, start, end, metric, lagDays), nil
+}
+
+// correlateSymptoms splits the metric's days in [start, end) into symptom
+// and other days per symptom name. Days are UTC dates; a day's value is the
+// one lagDays earlier.
+func correlateSymptoms(symptoms []models.SymptomRow, points []TimeSeriesPoint, start, end time.Time, metric string, lagDays int) []SymptomCorrelation {
+	values := make(map[string]float64, len(points))
+	for _, p := range points {
+		day := p.Time.UTC().AddDate(0, 0, lagDays)
+		if p.Avg != nil && !day.Before(start) && day.Before(end) {
+			values[day.Format("2006-01-02")] = *p.Avg
+		}
+	}
+
+	var names []string
+	days := map[string]map[string]bool{}
+	for _, s := range symptoms {
+		key := strings.ToLower(s.Name)
+		if days[key] == nil {
+			days[key] = map[string]bool{}
+			names = append(names, s.Name)
+		}
+		days[key][s.StartTime.UTC().Format("2006-01-02")] = true
+	}
+
+	out := []SymptomCorrelation{}
+	for _, name := range names {
+		c := SymptomCorrelation{Symptom: name, Metric: metric, LagDays: lagDays}
+		var symSum, otherSum float64
+		for day, v := range values {
+			if days[strings.ToLower(name)][day] {
+				symSum += v
+				c.SymptomDays++
+			} else {
+				otherSum += v
+				c.OtherDays++
+			}
+		}
+		if c.SymptomDays > 0 {
+			m := symSum / float64(c.SymptomDays)
+			c.SymptomDayMean = &m
+		}
+		if c.OtherDays > 0 {
+			m := otherSum / float64(c.OtherDays)
+			c.OtherDayMean = &m
+		}
+		if c.SymptomDayMean != nil && c.OtherDayMean != nil {
+			d := *c.SymptomDayMean - *c.OtherDayMean
+			c.Difference = &d
+		}
+		out = append(out, c)
+	}
+	return out
+}
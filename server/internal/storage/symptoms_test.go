@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// TestCorrelateSymptoms verifies symptom days are split from other days per
+// symptom name (case-insensitively) and that the lag compares each day with
+// the metric value from the day before.
+func TestCorrelateSymptoms(t *testing.T) {
+	day0 := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+	sleep := []float64{8, 5, 8, 8, 5, 8}
+	var points []TimeSeriesPoint
+	for i, v := range sleep {
+		v := v
+		points = append(points, TimeSeriesPoint{Time: day0.AddDate(0, 0, i), Avg: &v})
+	}
+	symptoms := []models.SymptomRow{
+		{Name: "Headache", StartTime: day0.AddDate(0, 0, 2).Add(9 * time.Hour)},
+		{Name: "headache", StartTime: day0.AddDate(0, 0, 5).Add(14 * time.Hour)},
+		{Name: "Fatigue", StartTime: day0.AddDate(0, 0, 1).Add(8 * time.Hour)},
+	}
+	end := day0.AddDate(0, 0, 6)
+
+	// Lag 1: headaches on days 2 and 5 follow the 5h nights on days 1 and 4.
+	got := correlateSymptoms(symptoms, points, day0, end, "sleep_analysis", 1)
+	if len(got) != 2 {
+		t.Fatalf("got %d correlations, want 2: %+v", len(got), got)
+	}
+	h := got[0]
+	if h.Symptom != "Headache" || h.SymptomDays != 2 || h.OtherDays != 3 {
+		t.Fatalf("headache = %+v", h)
+	}
+	if *h.SymptomDayMean != 5 || *h.OtherDayMean != 8 || *h.Difference != -3 {
+		t.Errorf("headache means = %v/%v diff %v, want 5/8 -3", *h.SymptomDayMean, *h.OtherDayMean, *h.Difference)
+	}
+
+	// Without lag the same headaches land on 8h days.
+	got = correlateSymptoms(symptoms, points, day0, end, "sleep_analysis", 0)
+	if *got[0].SymptomDayMean != 8 || got[0].OtherDays != 4 {
+		t.Errorf("unlagged headache = %+v", got[0])
+	}
+	if f := got[1]; f.Symptom != "Fatigue" || *f.SymptomDayMean != 5 {
+		t.Errorf("fatigue = %+v", f)
+	}
+
+	if got := correlateSymptoms(nil, points, day0, end, "sleep_analysis", 0); len(got) != 0 {
+		t.Errorf("no symptoms = %+v", got)
+	}
+}
@@ -3,9 +3,102 @@ package storage
 import (
 	"context"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
+// RIRBandDef defines one intensity band: sets with RIR <= MaxRIR that no
+// earlier band claimed. The last band has a nil MaxRIR and takes every
+// remaining tracked set. Failure bands count toward the failure rate.
+type RIRBandDef struct {
+	Name     string
+	RIRRange string
+	MaxRIR   *float64
+	Failure  bool
+}
+
+func rirCutoff(v float64) *float64 { return &v }
+
+// DefaultRIRBands are used until SetRIRBands configures others.
+var DefaultRIRBands = []RIRBandDef{
+	{Name: "failure", RIRRange: "0", MaxRIR: rirCutoff(0), Failure: true},
+	{Name: "near_failure", RIRRange: "0.5-1", MaxRIR: rirCutoff(1), Failure: true},
+	{Name: "moderate", RIRRange: "1.5-2", MaxRIR: rirCutoff(2)},
+	{Name: "easy", RIRRange: "2.5-3", MaxRIR: rirCutoff(3)},
+	{Name: "very_easy", RIRRange: ">3"},
+}
+
+// SetRIRBands replaces the bands used by GetTrainingIntensity and the failure
+// cutoff used by GetIntensityTrend. Bands must be in ascending MaxRIR order
+// with only the last one open-ended; nil or empty restores the defaults.
+func (db *DB) SetRIRBands(bands []RIRBandDef) {
+	db.rirBands = bands
+}
+
+func (db *DB) bands() []RIRBandDef {
+	if len(db.rirBands) == 0 {
+		return DefaultRIRBands
+	}
+	return db.rirBands
+}
+
+// rirDistributionSQL builds the RIR distribution query for bands. Cutoffs are
+// inlined as numeric literals; names and ranges are bound from $4 on, and the
+// output follows the configured band order with "untracked" last.
+func rirDistributionSQL(bands []RIRBandDef) (string, []any) {
+	var args []any
+	param := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(3+len(args))
+	}
+
+	var band, rng strings.Builder
+	band.WriteString("CASE WHEN rir = -1 THEN 'untracked'")
+	rng.WriteString("CASE WHEN rir = -1 THEN 'untracked'")
+	order := make([]string, 0, len(bands)+1)
+	for _, b := range bands {
+		if b.MaxRIR == nil {
+			fmt.Fprintf(&band, " ELSE %s::text", param(b.Name))
+			fmt.Fprintf(&rng, " ELSE %s::text", param(b.RIRRange))
+		} else {
+			cutoff := strconv.FormatFloat(*b.MaxRIR, 'f', -1, 64)
+			fmt.Fprintf(&band, " WHEN rir <= %s THEN %s::text", cutoff, param(b.Name))
+			fmt.Fprintf(&rng, " WHEN rir <= %s THEN %s::text", cutoff, param(b.RIRRange))
+		}
+		order = append(order, b.Name)
+	}
+	band.WriteString(" END")
+	rng.WriteString(" END")
+	order = append(order, "untracked")
+
+	query := `SELECT band, rir_range, sets FROM (
+			SELECT
+				` + band.String() + ` AS band,
+				` + rng.String() + ` AS rir_range,
+				COUNT(*)::int AS sets
+			FROM workout_sets
+			WHERE session_date >= $1 AND session_date < $2
+				AND user_id = $3
+				AND NOT is_warmup
+			GROUP BY band, rir_range
+		) sub
+		ORDER BY array_position(` + param(order) + `::text[], band)`
+	return query, args
+}
+
+// failureCutoff returns the highest RIR still counted as failure: the MaxRIR
+// of the last failure band. Returns -1 (nothing counts) without failure bands.
+func failureCutoff(bands []RIRBandDef) float64 {
+	cutoff := -1.0
+	for _, b := range bands {
+		if b.Failure && b.MaxRIR != nil {
+			cutoff = *b.MaxRIR
+		}
+	}
+	return cutoff
+}
+
 // RIRBand holds the count and percentage of sets in a specific RIR range.
 type RIRBand struct {
 	Band     string  `json:"band"`
@@ -33,63 +126,64 @@ type ExerciseProgression struct {
 	AvgRIR         *float64 `json:"avg_rir,omitempty"`
 }
 
+// ExerciseProgressionSeries is the session-by-session progression of one
+// exercise, returned for each of the top exercises by volume.
+type ExerciseProgressionSeries struct {
+	Name        string                `json:"name"`
+	Progression []ExerciseProgression `json:"progression"`
+}
+
 // TrainingIntensityResult holds the complete intensity analysis.
 type TrainingIntensityResult struct {
-	RIRDistribution []RIRBand             `json:"rir_distribution"`
-	FailureRatePct  float64               `json:"failure_rate_pct"`
-	TotalSets       int                   `json:"total_sets"`
-	TrackedSets     int                   `json:"tracked_sets"`
-	Exercises       []ExerciseSummary     `json:"exercises"`
-	Progression     []ExerciseProgression `json:"progression,omitempty"`
+	RIRDistribution      []RIRBand                   `json:"rir_distribution"`
+	FailureRatePct       float64                     `json:"failure_rate_pct"`
+	TotalSets            int                         `json:"total_sets"`
+	TrackedSets          int                         `json:"tracked_sets"`
+	Exercises            []ExerciseSummary           `json:"exercises"`
+	Progression          []ExerciseProgression       `json:"progression,omitempty"`
+	ExerciseProgressions []ExerciseProgressionSeries `json:"exercise_progressions,omitempty"`
+}
+
+// MaxIntensityTop caps how many exercises GetTrainingIntensity returns
+// progression for in one call, bounding the per-session query.
+const MaxIntensityTop = 10
+
+// topExerciseNames returns the names of the first n exercises, which the
+// summary query has already ordered by tonnage. n is capped at MaxIntensityTop.
+func topExerciseNames(exercises []ExerciseSummary, n int) []string {
+	n = min(n, MaxIntensityTop, len(exercises))
+	if n <= 0 {
+		return nil
+	}
+	names := make([]string, n)
+	for i := range names {
+		names[i] = exercises[i].Name
+	}
+	return names
 }
 
 // GetTrainingIntensity returns RIR distribution, failure rate, per-exercise stats,
-// and optional exercise progression for strength training.
+// and optional exercise progression for strength training. With an exercise
+// filter, Progression holds that exercise's sessions; otherwise top > 0 fills
+// ExerciseProgressions for the top exercises by tonnage (at most MaxIntensityTop).
 // RIR value of -1 is treated as untracked (Alpha Progression sentinel).
-func (db *DB) GetTrainingIntensity(ctx context.Context, start, end time.Time, userID int, exerciseFilter string) (*TrainingIntensityResult, error) {
+func (db *DB) GetTrainingIntensity(ctx context.Context, start, end time.Time, userID int, exerciseFilter string, top int) (*TrainingIntensityResult, error) {
 	result := &TrainingIntensityResult{}
 
 	// Query 1: RIR distribution
-	rirRows, err := db.Pool.Query(ctx,
-		`SELECT band, rir_range, sets FROM (
-			SELECT
-				CASE
-					WHEN rir = -1 THEN 'untracked'
-					WHEN rir <= 0 THEN 'failure'
-					WHEN rir <= 1 THEN 'near_failure'
-					WHEN rir <= 2 THEN 'moderate'
-					WHEN rir <= 3 THEN 'easy'
-					ELSE 'very_easy'
-				END AS band,
-				CASE
-					WHEN rir = -1 THEN 'untracked'
-					WHEN rir <= 0 THEN '0'
-					WHEN rir <= 1 THEN '0.5-1'
-					WHEN rir <= 2 THEN '1.5-2'
-					WHEN rir <= 3 THEN '2.5-3'
-					ELSE '>3'
-				END AS rir_range,
-				COUNT(*)::int AS sets
-			FROM workout_sets
-			WHERE session_date >= $1 AND session_date < $2
-				AND user_id = $3
-				AND NOT is_warmup
-			GROUP BY band, rir_range
-		) sub
-		ORDER BY CASE band
-			WHEN 'failure' THEN 1
-			WHEN 'near_failure' THEN 2
-			WHEN 'moderate' THEN 3
-			WHEN 'easy' THEN 4
-			WHEN 'very_easy' THEN 5
-			WHEN 'untracked' THEN 6
-		END`,
-		start, end, userID)
+	bands := db.bands()
+	rirSQL, rirArgs := rirDistributionSQL(bands)
+	rirRows, err := db.Pool.Query(ctx, rirSQL, append([]any{start, end, userID}, rirArgs...)...)
 	if err != nil {
 		return nil, fmt.Errorf("querying RIR distribution: %w", err)
 	}
 	defer rirRows.Close()
 
+	failureBand := make(map[string]bool, len(bands))
+	for _, b := range bands {
+		failureBand[b.Name] = b.Failure
+	}
+
 	var totalSets, trackedSets, failureSets int
 	for rirRows.Next() {
 		var b RIRBand
@@ -100,7 +194,7 @@ func (db *DB) GetTrainingIntensity(ctx context.Context, start, end time.Time, us
 		if b.Band != "untracked" {
 			trackedSets += b.Sets
 		}
-		if b.Band == "failure" || b.Band == "near_failure" {
+		if failureBand[b.Band] {
 			failureSets += b.Sets
 		}
 		result.RIRDistribution = append(result.RIRDistribution, b)
@@ -187,7 +281,113 @@ func (db *DB) GetTrainingIntensity(ctx context.Context, start, end time.Time, us
 		if err := progRows.Err(); err != nil {
 			return nil, err
 		}
+	} else if names := topExerciseNames(result.Exercises, top); len(names) > 0 {
+		// Query 4: Progression for the top exercises by volume
+		series, err := db.queryTopProgressions(ctx, start, end, userID, names)
+		if err != nil {
+			return nil, err
+		}
+		result.ExerciseProgressions = series
 	}
 
 	return result, nil
 }
+
+// queryTopProgressions returns per-session progression for each named
+// exercise, in the order the names are given.
+func (db *DB) queryTopProgressions(ctx context.Context, start, end time.Time, userID int, names []string) ([]ExerciseProgressionSeries, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT exercise_name,
+		        session_date,
+		        COALESCE(MAX(weight_kg), 0),
+		        COALESCE(SUM(weight_kg * reps), 0),
+		        COUNT(*)::int,
+		        AVG(NULLIF(rir, -1))
+		 FROM workout_sets
+		 WHERE session_date >= $1 AND session_date < $2
+		   AND user_id = $3
+		   AND exercise_name = ANY($4)
+		   AND NOT is_warmup
+		 GROUP BY exercise_name, session_date
+		 ORDER BY session_date ASC`,
+		start, end, userID, names)
+	if err != nil {
+		return nil, fmt.Errorf("querying top exercise progression: %w", err)
+	}
+	defer rows.Close()
+
+	series := make([]ExerciseProgressionSeries, len(names))
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		series[i] = ExerciseProgressionSeries{Name: name, Progression: []ExerciseProgression{}}
+		index[name] = i
+	}
+	for rows.Next() {
+		var name string
+		var p ExerciseProgression
+		var d time.Time
+		if err := rows.Scan(&name, &d, &p.MaxWeight, &p.SessionTonnage, &p.Sets, &p.AvgRIR); err != nil {
+			return nil, fmt.Errorf("scanning top exercise progression: %w", err)
+		}
+		p.Date = d.Format("2006-01-02")
+		i := index[name]
+		series[i].Progression = append(series[i].Progression, p)
+	}
+	return series, rows.Err()
+}
+
+// IntensityTrendPeriod holds average RIR and failure rate for one period.
+// AvgRIR and FailureRatePct are nil when the period has no RIR-tracked sets.
+type IntensityTrendPeriod struct {
+	Period         string   `json:"period"`
+	WorkingSets    int      `json:"working_sets"`
+	TrackedSets    int      `json:"tracked_sets"`
+	AvgRIR         *float64 `json:"avg_rir"`
+	FailureRatePct *float64 `json:"failure_rate_pct"`
+}
+
+// intensityTrendSQL expands every period ($1 = date_trunc unit) between $2
+// and $3 and left-joins working sets, so periods without training come back
+// as rows with nulls rather than gaps. Failure matches GetTrainingIntensity:
+// RIR <= $5 (the failure bands' cutoff) among tracked sets, with -1 as the
+// untracked sentinel.
+const intensityTrendSQL = `
+	SELECT p.period::date,
+	       COUNT(ws.id)::int,
+	       COUNT(NULLIF(ws.rir, -1))::int,
+	       AVG(NULLIF(ws.rir, -1)),
+	       100.0 * COUNT(*) FILTER (WHERE ws.rir <> -1 AND ws.rir <= $5)
+	             / NULLIF(COUNT(NULLIF(ws.rir, -1)), 0)
+	FROM generate_series(date_trunc($1, $2::timestamptz), $3::timestamptz,
+	                     ('1 ' || $1)::interval) AS p(period)
+	LEFT JOIN workout_sets ws
+	  ON ws.user_id = $4
+	 AND NOT ws.is_warmup
+	 AND ws.session_date >= $2 AND ws.session_date < $3
+	 AND date_trunc($1, ws.session_date) = p.period
+	WHERE p.period < $3
+	GROUP BY p.period
+	ORDER BY p.period ASC`
+
+// GetIntensityTrend returns average RIR and failure rate per period (bucket
+// "1 week" or "1 month") so users can see whether they train closer to
+// failure over time. Warmups are excluded.
+func (db *DB) GetIntensityTrend(ctx context.Context, start, end time.Time, bucket string, userID int) ([]IntensityTrendPeriod, error) {
+	rows, err := db.Pool.Query(ctx, intensityTrendSQL, truncInterval(bucket), start, end, userID, failureCutoff(db.bands()))
+	if err != nil {
+		return nil, fmt.Errorf("querying intensity trend: %w", err)
+	}
+	defer rows.Close()
+
+	result := []IntensityTrendPeriod{}
+	for rows.Next() {
+		var p IntensityTrendPeriod
+		var d time.Time
+		if err := rows.Scan(&d, &p.WorkingSets, &p.TrackedSets, &p.AvgRIR, &p.FailureRatePct); err != nil {
+			return nil, fmt.Errorf("scanning intensity trend: %w", err)
+		}
+		p.Period = d.Format("2006-01-02")
+		result = append(result, p)
+	}
+	return result, rows.Err()
+}
@@ -0,0 +1,99 @@
+package storage
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestTopExerciseNames verifies the top-N selection keeps the tonnage order
+// from the summary query and is capped at MaxIntensityTop.
+func TestTopExerciseNames(t *testing.T) {
+	var exercises []ExerciseSummary
+	for _, name := range []string{"Squat", "Bench", "Row", "Curl"} {
+		exercises = append(exercises, ExerciseSummary{Name: name})
+	}
+
+	if got := topExerciseNames(exercises, 2); len(got) != 2 || got[0] != "Squat" || got[1] != "Bench" {
+		t.Errorf("top 2 = %v, want [Squat Bench]", got)
+	}
+	if got := topExerciseNames(exercises, 0); got != nil {
+		t.Errorf("top 0 = %v, want nil", got)
+	}
+	if got := topExerciseNames(exercises, 9); len(got) != 4 {
+		t.Errorf("top 9 of 4 = %v, want all 4", got)
+	}
+
+	many := make([]ExerciseSummary, MaxIntensityTop+5)
+	if got := topExerciseNames(many, 50); len(got) != MaxIntensityTop {
+		t.Errorf("top 50 = %d names, want cap %d", len(got), MaxIntensityTop)
+	}
+}
+
+// TestIntensityTrendSQL verifies the trend excludes warmups, treats RIR -1 as
+// untracked in both the average and the failure rate, and keeps empty periods.
+func TestIntensityTrendSQL(t *testing.T) {
+	for _, want := range []string{
+		"NOT ws.is_warmup",
+		"AVG(NULLIF(ws.rir, -1))",
+		"ws.rir <> -1 AND ws.rir <= $5",
+		"NULLIF(COUNT(NULLIF(ws.rir, -1)), 0)",
+		"LEFT JOIN workout_sets ws",
+	} {
+		if !strings.Contains(intensityTrendSQL, want) {
+			t.Errorf("intensityTrendSQL missing %q", want)
+		}
+	}
+}
+
+// TestRIRDistributionSQLCustomBands verifies configured bands become the CASE
+// cutoffs in order, that names are bound rather than inlined, and that the
+// output order follows the configuration with untracked last.
+func TestRIRDistributionSQLCustomBands(t *testing.T) {
+	bands := []RIRBandDef{
+		{Name: "max_effort", RIRRange: "0-0.5", MaxRIR: rirCutoff(0.5), Failure: true},
+		{Name: "productive", RIRRange: "1-2.5", MaxRIR: rirCutoff(2.5)},
+		{Name: "junk", RIRRange: ">2.5"},
+	}
+	query, args := rirDistributionSQL(bands)
+
+	first := strings.Index(query, "WHEN rir <= 0.5 THEN $4::text")
+	second := strings.Index(query, "WHEN rir <= 2.5 THEN $6::text")
+	if first < 0 || second < first {
+		t.Errorf("band cutoffs missing or out of order:\n%s", query)
+	}
+	if !strings.Contains(query, "ELSE $8::text") {
+		t.Error("open-ended band should be the ELSE branch")
+	}
+	if strings.Contains(query, "max_effort") {
+		t.Error("band names must be bound parameters, not inlined")
+	}
+
+	if len(args) != 7 {
+		t.Fatalf("args = %d, want 3 names + 3 ranges + order", len(args))
+	}
+	order, ok := args[6].([]string)
+	if !ok || strings.Join(order, ",") != "max_effort,productive,junk,untracked" {
+		t.Errorf("order = %v, want configured order then untracked", args[6])
+	}
+	if !strings.Contains(query, "array_position($10::text[], band)") {
+		t.Errorf("ORDER BY should use the bound order array:\n%s", query)
+	}
+}
+
+// TestFailureCutoff verifies the failure rate threshold follows the last
+// failure band, and that the defaults keep RIR <= 1 as failure.
+func TestFailureCutoff(t *testing.T) {
+	if got := failureCutoff(DefaultRIRBands); got != 1 {
+		t.Errorf("default cutoff = %v, want 1", got)
+	}
+	custom := []RIRBandDef{
+		{Name: "max_effort", MaxRIR: rirCutoff(0.5), Failure: true},
+		{Name: "rest"},
+	}
+	if got := failureCutoff(custom); got != 0.5 {
+		t.Errorf("custom cutoff = %v, want 0.5", got)
+	}
+	if got := failureCutoff([]RIRBandDef{{Name: "all"}}); got != -1 {
+		t.Errorf("no failure bands: cutoff = %v, want -1", got)
+	}
+}
@@ -2,8 +2,12 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/claude/freereps/internal/ingest"
+	"github.com/jackc/pgx/v5"
 )
 
 // WorkoutTypePeriodSummary holds aggregated workout stats for one type within a period.
@@ -13,15 +17,66 @@ type WorkoutTypePeriodSummary struct {
 	AvgDuration  float64  `json:"avg_duration_sec"`
 	TotalCalories float64 `json:"total_calories"`
 	AvgHeartRate *float64 `json:"avg_heart_rate,omitempty"`
+	RawNames     []string `json:"raw_names,omitempty"` // workout names grouped into Type
 }
 
 // StrengthVolumeSummary holds aggregated strength training stats for a period.
+// EstimatedCalories is nil when the sessions carry no duration or the
+// estimate is disabled.
 type StrengthVolumeSummary struct {
-	WorkingSets    int     `json:"working_sets"`
-	TotalReps      int     `json:"total_reps"`
-	TonnageKg      float64 `json:"tonnage_kg"`
-	Sessions       int     `json:"sessions"`
-	AvgSetsPerSession float64 `json:"avg_sets_per_session"`
+	WorkingSets       int      `json:"working_sets"`
+	TotalReps         int      `json:"total_reps"`
+	TonnageKg         float64  `json:"tonnage_kg"`
+	Sessions          int      `json:"sessions"`
+	AvgSetsPerSession float64  `json:"avg_sets_per_session"`
+	DurationMin       float64  `json:"duration_min"`
+	EstimatedCalories *float64 `json:"estimated_calories,omitempty"`
+}
+
+// Strength calorie estimate defaults; see SetStrengthCalories.
+const (
+	DefaultStrengthMET  = 5.0
+	DefaultBodyweightKg = 75.0
+)
+
+// SetStrengthCalories configures the strength session calorie estimate:
+// kcal = met × bodyweight (kg) × hours. The bodyweight is the user's latest
+// weight_body_mass sample, or defaultBodyweightKg when none is recorded.
+// A zero MET disables the estimate.
+func (db *DB) SetStrengthCalories(met, defaultBodyweightKg float64) {
+	db.strengthMET = met
+	db.defaultBodyweightKg = defaultBodyweightKg
+}
+
+// estimateStrengthKcal applies the MET formula: kcal = MET × kg × hours.
+func estimateStrengthKcal(met, bodyweightKg float64, d time.Duration) float64 {
+	return met * bodyweightKg * d.Hours()
+}
+
+// latestBodyweightKg returns the user's most recent body mass before end in
+// kg, or 0 if none is recorded.
+func (db *DB) latestBodyweightKg(ctx context.Context, end time.Time, userID int) (float64, error) {
+	var qty float64
+	var units *string
+	err := db.Pool.QueryRow(ctx,
+		`SELECT COALESCE(qty, avg_val), units FROM health_metrics
+		 WHERE user_id = $1 AND metric_name = 'weight_body_mass' AND time < $2
+		   AND COALESCE(qty, avg_val) IS NOT NULL
+		   AND `+uncoveredRowsSQL+`
+		 ORDER BY time DESC
+		 LIMIT 1`, userID, end).Scan(&qty, &units)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("querying bodyweight: %w", err)
+	}
+	if units != nil {
+		if kg, ok := ingest.ConvertUnit(qty, *units, "kg"); ok {
+			qty = kg
+		}
+	}
+	return qty, nil
 }
 
 // TrainingSummaryPeriod holds combined workout + strength data for one time period.
@@ -31,7 +86,8 @@ type TrainingSummaryPeriod struct {
 	Strength  *StrengthVolumeSummary     `json:"strength,omitempty"`
 }
 
-// GetTrainingSummary returns aggregated workout and strength volume stats per period.
+// GetTrainingSummary returns aggregated workout and strength volume stats per
+// period. Workouts are grouped by canonical type (see WorkoutType).
 func (db *DB) GetTrainingSummary(ctx context.Context, start, end time.Time, bucket string, userID int) ([]TrainingSummaryPeriod, error) {
 	// Query 1: Workout stats grouped by period + type
 	workoutRows, err := db.Pool.Query(ctx,
@@ -39,7 +95,7 @@ func (db *DB) GetTrainingSummary(ctx context.Context, start, end time.Time, buck
 		        name,
 		        COUNT(*)::int,
 		        AVG(duration_sec),
-		        COALESCE(SUM(active_energy_burned), 0),
+		        COALESCE(SUM(`+workoutEnergyKcalSQL+`), 0),
 		        AVG(avg_heart_rate)
 		 FROM workouts
 		 WHERE start_time >= $2 AND start_time < $3 AND user_id = $4
@@ -71,6 +127,9 @@ func (db *DB) GetTrainingSummary(ctx context.Context, start, end time.Time, buck
 	if err := workoutRows.Err(); err != nil {
 		return nil, err
 	}
+	for _, p := range periodMap {
+		p.Workouts = groupWorkoutTypes(p.Workouts, db.workoutTypes)
+	}
 
 	// Query 2: Strength set volume grouped by period
 	strengthRows, err := db.Pool.Query(ctx,
@@ -109,6 +168,11 @@ func (db *DB) GetTrainingSummary(ctx context.Context, start, end time.Time, buck
 		return nil, err
 	}
 
+	// Query 3: Session durations per period, for the calorie estimate
+	if err := db.addStrengthCalories(ctx, start, end, bucket, userID, periodMap); err != nil {
+		return nil, err
+	}
+
 	// Assemble result in order
 	result := make([]TrainingSummaryPeriod, 0, len(periodOrder))
 	for _, key := range periodOrder {
@@ -117,6 +181,57 @@ func (db *DB) GetTrainingSummary(ctx context.Context, start, end time.Time, buck
 	return result, nil
 }
 
+// addStrengthCalories fills DurationMin and EstimatedCalories on the strength
+// summaries in periods from the Alpha sessions' recorded durations.
+func (db *DB) addStrengthCalories(ctx context.Context, start, end time.Time, bucket string, userID int, periods map[string]*TrainingSummaryPeriod) error {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT DISTINCT date_trunc($1, session_date)::date AS period, session_date, session_duration
+		 FROM workout_sets
+		 WHERE session_date >= $2 AND session_date < $3 AND user_id = $4`,
+		truncInterval(bucket), start, end, userID)
+	if err != nil {
+		return fmt.Errorf("querying strength session durations: %w", err)
+	}
+	defer rows.Close()
+
+	durations := make(map[string]time.Duration)
+	for rows.Next() {
+		var periodTime, sessionDate time.Time
+		var dur *string
+		if err := rows.Scan(&periodTime, &sessionDate, &dur); err != nil {
+			return fmt.Errorf("scanning strength session duration: %w", err)
+		}
+		if dur != nil {
+			durations[periodTime.Format("2006-01-02")] += parseAlphaDuration(*dur)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var bodyweight float64
+	if db.strengthMET > 0 && len(durations) > 0 {
+		if bodyweight, err = db.latestBodyweightKg(ctx, end, userID); err != nil {
+			return err
+		}
+		if bodyweight <= 0 {
+			bodyweight = db.defaultBodyweightKg
+		}
+	}
+	for key, d := range durations {
+		p, ok := periods[key]
+		if !ok || p.Strength == nil {
+			continue
+		}
+		p.Strength.DurationMin = d.Minutes()
+		if db.strengthMET > 0 && bodyweight > 0 && d > 0 {
+			kcal := estimateStrengthKcal(db.strengthMET, bodyweight, d)
+			p.Strength.EstimatedCalories = &kcal
+		}
+	}
+	return nil
+}
+
 // truncInterval converts bucket strings like "1 month" to the interval name
 // that date_trunc expects (e.g. "month", "week").
 func truncInterval(bucket string) string {
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestEstimateStrengthKcal verifies the MET estimate scales linearly with
+// both session duration and bodyweight.
+func TestEstimateStrengthKcal(t *testing.T) {
+	base := estimateStrengthKcal(DefaultStrengthMET, 80, time.Hour)
+	if math.Abs(base-400) > 1e-9 {
+		t.Errorf("5 MET × 80 kg × 1h = %v, want 400", base)
+	}
+	if got := estimateStrengthKcal(DefaultStrengthMET, 80, 90*time.Minute); math.Abs(got-1.5*base) > 1e-9 {
+		t.Errorf("90 min = %v, want %v", got, 1.5*base)
+	}
+	if got := estimateStrengthKcal(DefaultStrengthMET, 100, time.Hour); math.Abs(got-1.25*base) > 1e-9 {
+		t.Errorf("100 kg = %v, want %v", got, 1.25*base)
+	}
+	if got := estimateStrengthKcal(0, 80, time.Hour); got != 0 {
+		t.Errorf("0 MET = %v, want 0", got)
+	}
+}
@@ -0,0 +1,243 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// UnitCount is how many stored rows of a metric use one unit.
+type UnitCount struct {
+	Unit string `json:"unit"`
+	Rows int64  `json:"rows"`
+}
+
+// MetricUnitReport lists the distinct units a metric is stored in, most
+// common first. A metric is consistent when all its rows share one unit.
+type MetricUnitReport struct {
+	Metric       string      `json:"metric"`
+	MajorityUnit string      `json:"majority_unit"`
+	Units        []UnitCount `json:"units"`
+	Consistent   bool        `json:"consistent"`
+}
+
+// UnitRepair converts one metric's rows from a minority unit to its majority
+// unit. Convertible is false when no conversion between the two is known;
+// those rows are left alone. RollupsUpdated counts the converted
+// health_metric_rollups buckets.
+type UnitRepair struct {
+	Metric         string `json:"metric"`
+	FromUnit       string `json:"from_unit"`
+	ToUnit         string `json:"to_unit"`
+	Rows           int64  `json:"rows"`
+	Convertible    bool   `json:"convertible"`
+	Updated        int64  `json:"updated"`
+	RollupsUpdated int64  `json:"rollups_updated"`
+}
+
+// UnitConverter converts v between two units, reporting false when it can't.
+// ingest.ConvertUnit satisfies it.
+type UnitConverter func(v float64, from, to string) (float64, bool)
+
+// metricUnitRow is one (metric, unit, count) group from health_metrics.
+type metricUnitRow struct {
+	Metric string
+	Unit   string
+	Rows   int64
+}
+
+// GetUnitConsistency reports, for every metric in health_metrics, the units
+// its rows are stored in and how many rows use each. Rows with no unit are
+// left out since they carry nothing to convert.
+func (db *DB) GetUnitConsistency(ctx context.Context) ([]MetricUnitReport, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT metric_name, units, COUNT(*)
+		 FROM health_metrics
+		 WHERE COALESCE(units, '') <> ''
+		 GROUP BY metric_name, units`)
+	if err != nil {
+		return nil, fmt.Errorf("querying metric units: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []metricUnitRow
+	for rows.Next() {
+		var g metricUnitRow
+		if err := rows.Scan(&g.Metric, &g.Unit, &g.Rows); err != nil {
+			return nil, fmt.Errorf("scanning metric units: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating metric units: %w", err)
+	}
+	return unitReports(groups), nil
+}
+
+// unitReports groups unit counts by metric, ordering metrics by name and
+// units by row count (ties by name) so the first unit is the majority.
+func unitReports(groups []metricUnitRow) []MetricUnitReport {
+	byMetric := map[string][]UnitCount{}
+	for _, g := range groups {
+		byMetric[g.Metric] = append(byMetric[g.Metric], UnitCount{Unit: g.Unit, Rows: g.Rows})
+	}
+
+	out := make([]MetricUnitReport, 0, len(byMetric))
+	for metric, units := range byMetric {
+		sort.Slice(units, func(i, j int) bool {
+			if units[i].Rows != units[j].Rows {
+				return units[i].Rows > units[j].Rows
+			}
+			return units[i].Unit < units[j].Unit
+		})
+		out = append(out, MetricUnitReport{
+			Metric:       metric,
+			MajorityUnit: units[0].Unit,
+			Units:        units,
+			Consistent:   len(units) == 1,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Metric < out[j].Metric })
+	return out
+}
+
+// unitRepairPlan lists the conversions needed to bring every inconsistent
+// metric onto its majority unit.
+func unitRepairPlan(reports []MetricUnitReport, convert UnitConverter) []UnitRepair {
+	plan := []UnitRepair{}
+	for _, r := range reports {
+		for _, u := range r.Units[1:] {
+			_, ok := convert(0, u.Unit, r.MajorityUnit)
+			plan = append(plan, UnitRepair{
+				Metric:      r.Metric,
+				FromUnit:    u.Unit,
+				ToUnit:      r.MajorityUnit,
+				Rows:        u.Rows,
+				Convertible: ok,
+			})
+		}
+	}
+	return plan
+}
+
+// linearCoefficients expresses the conversion from → to as v*scale + offset
+// so it can run as a single UPDATE. Every known unit conversion is affine
+// (temperatures have an offset, the rest only a scale), so two samples
+// determine it exactly.
+func linearCoefficients(convert UnitConverter, from, to string) (scale, offset float64, ok bool) {
+	offset, ok = convert(0, from, to)
+	if !ok {
+		return 0, 0, false
+	}
+	one, _ := convert(1, from, to)
+	return one - offset, offset, true
+}
+
+// unitRepairSQL converts metric $1's raw rows in unit $2 matching where,
+// as v*$3 + $4, into unit $5.
+func unitRepairSQL(where string) string {
+	return `UPDATE health_metrics SET
+				raw_units = COALESCE(NULLIF(raw_units, ''), units),
+				raw_qty   = COALESCE(raw_qty, qty),
+				qty       = qty * $3 + $4,
+				min_val   = min_val * $3 + $4,
+				avg_val   = avg_val * $3 + $4,
+				max_val   = max_val * $3 + $4,
+				units     = $5
+			 WHERE metric_name = $1 AND units = $2 AND ` + where
+}
+
+// rollupUnitRepairSQL converts metric $1's rollups in unit $2 like
+// unitRepairSQL. A cumulative rollup's qty is a sum of sample_count
+// samples, so it takes the offset once per sample.
+func rollupUnitRepairSQL(cumulative bool) string {
+	qtyOffset := "$4"
+	if cumulative {
+		qtyOffset = "$4 * sample_count"
+	}
+	return `UPDATE health_metric_rollups SET
+				qty     = qty * $3 + ` + qtyOffset + `,
+				min_val = min_val * $3 + $4,
+				max_val = max_val * $3 + $4,
+				units   = $5
+			 WHERE metric_name = $1 AND units = $2`
+}
+
+// keepUpdatedAtSQL switches the touch_updated_at trigger off (true) or back
+// on for the rest of the transaction.
+const keepUpdatedAtSQL = `SELECT set_config('freereps.keep_updated_at', CASE WHEN $1 THEN 'on' ELSE 'off' END, true)`
+
+// RepairMetricUnits converts rows stored in a minority unit to their metric's
+// majority unit, keeping the original unit and qty in raw_units/raw_qty as
+// ingest normalization does, and converts the metric's rollups in that unit
+// along with them. Raw rows behind the rollup watermark keep their
+// updated_at, so retention doesn't take them for backfill and merge them into
+// their buckets a second time. With dryRun nothing is written and the plan
+// shows what would change.
+func (db *DB) RepairMetricUnits(ctx context.Context, convert UnitConverter, dryRun bool) ([]UnitRepair, error) {
+	reports, err := db.GetUnitConsistency(ctx)
+	if err != nil {
+		return nil, err
+	}
+	plan := unitRepairPlan(reports, convert)
+	if dryRun {
+		return plan, nil
+	}
+
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning unit repair tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	for i, p := range plan {
+		if !p.Convertible {
+			continue
+		}
+		scale, offset, _ := linearCoefficients(convert, p.FromUnit, p.ToUnit)
+		watermark, _, err := db.rollupWatermark(ctx, p.Metric)
+		if err != nil {
+			return plan, err
+		}
+		args := []any{p.Metric, p.FromUnit, scale, offset, p.ToUnit}
+		if watermark == nil {
+			tag, err := tx.Exec(ctx, unitRepairSQL("TRUE"), args...)
+			if err != nil {
+				return plan, fmt.Errorf("converting %s from %s to %s: %w", p.Metric, p.FromUnit, p.ToUnit, err)
+			}
+			plan[i].Updated = tag.RowsAffected()
+			continue
+		}
+
+		args = append(args, *watermark)
+		tag, err := tx.Exec(ctx, unitRepairSQL("time >= $6"), args...)
+		if err != nil {
+			return plan, fmt.Errorf("converting %s from %s to %s: %w", p.Metric, p.FromUnit, p.ToUnit, err)
+		}
+		plan[i].Updated = tag.RowsAffected()
+		if _, err := tx.Exec(ctx, keepUpdatedAtSQL, true); err != nil {
+			return plan, fmt.Errorf("pausing updated_at: %w", err)
+		}
+		tag, err = tx.Exec(ctx, unitRepairSQL("time < $6"), args...)
+		if err != nil {
+			return plan, fmt.Errorf("converting rolled-up %s from %s to %s: %w", p.Metric, p.FromUnit, p.ToUnit, err)
+		}
+		plan[i].Updated += tag.RowsAffected()
+		if _, err := tx.Exec(ctx, keepUpdatedAtSQL, false); err != nil {
+			return plan, fmt.Errorf("resuming updated_at: %w", err)
+		}
+		tag, err = tx.Exec(ctx, rollupUnitRepairSQL(cumulativeMetrics[p.Metric]), args[:5]...)
+		if err != nil {
+			return plan, fmt.Errorf("converting %s rollups from %s to %s: %w", p.Metric, p.FromUnit, p.ToUnit, err)
+		}
+		plan[i].RollupsUpdated = tag.RowsAffected()
+	}
+
+	if err := markDataChanged(ctx, tx, 0, DataHealthMetrics); err != nil {
+		return plan, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return plan, fmt.Errorf("committing unit repair tx: %w", err)
+	}
+	return plan, nil
+}
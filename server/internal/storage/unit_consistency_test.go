@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// testConverter knows lb→kg and degF→degC, like ingest.ConvertUnit.
+func testConverter(v float64, from, to string) (float64, bool) {
+	switch {
+	case from == to:
+		return v, true
+	case from == "lb" && to == "kg":
+		return v * 0.45359237, true
+	case from == "degF" && to == "degC":
+		return (v - 32) * 5 / 9, true
+	}
+	return 0, false
+}
+
+// TestUnitReports verifies units are grouped per metric with the most common
+// unit first and single-unit metrics marked consistent.
+func TestUnitReports(t *testing.T) {
+	reports := unitReports([]metricUnitRow{
+		{"weight_body_mass", "lb", 12},
+		{"weight_body_mass", "kg", 340},
+		{"step_count", "count", 9000},
+	})
+	if len(reports) != 2 || reports[0].Metric != "step_count" || !reports[0].Consistent {
+		t.Fatalf("reports = %+v", reports)
+	}
+	w := reports[1]
+	if w.Consistent || w.MajorityUnit != "kg" || len(w.Units) != 2 || w.Units[1] != (UnitCount{"lb", 12}) {
+		t.Errorf("weight report = %+v", w)
+	}
+}
+
+// TestUnitRepairPlan verifies each minority unit becomes one repair onto the
+// majority unit, flagged unconvertible when no conversion exists.
+func TestUnitRepairPlan(t *testing.T) {
+	reports := unitReports([]metricUnitRow{
+		{"weight_body_mass", "kg", 340},
+		{"weight_body_mass", "lb", 12},
+		{"weight_body_mass", "stone", 2},
+		{"step_count", "count", 9000},
+	})
+	plan := unitRepairPlan(reports, testConverter)
+	if len(plan) != 2 {
+		t.Fatalf("plan = %+v, want 2 repairs", plan)
+	}
+	if p := plan[0]; p.FromUnit != "lb" || p.ToUnit != "kg" || p.Rows != 12 || !p.Convertible {
+		t.Errorf("lb repair = %+v", p)
+	}
+	if p := plan[1]; p.FromUnit != "stone" || p.Convertible {
+		t.Errorf("stone repair = %+v, want unconvertible", p)
+	}
+}
+
+// TestLinearCoefficients verifies the SQL conversion matches the converter
+// for a pure scale (lb→kg) and an affine one (degF→degC).
+func TestLinearCoefficients(t *testing.T) {
+	for _, c := range []struct {
+		from, to string
+		in, want float64
+	}{
+		{"lb", "kg", 220, 99.79032},
+		{"degF", "degC", 98.6, 37},
+	} {
+		scale, offset, ok := linearCoefficients(testConverter, c.from, c.to)
+		if !ok {
+			t.Fatalf("%s→%s: not convertible", c.from, c.to)
+		}
+		if got := c.in*scale + offset; math.Abs(got-c.want) > 1e-4 {
+			t.Errorf("%s→%s: %v → %v, want %v", c.from, c.to, c.in, got, c.want)
+		}
+	}
+	if _, _, ok := linearCoefficients(testConverter, "stone", "kg"); ok {
+		t.Error("stone→kg should not be convertible")
+	}
+}
+
+// TestUnitRepairSQL verifies raw rows are split at the watermark and that
+// cumulative rollup sums take the offset once per sample.
+func TestUnitRepairSQL(t *testing.T) {
+	if sql := unitRepairSQL("time < $6"); !strings.Contains(sql, "units = $2 AND time < $6") || !strings.Contains(sql, "raw_qty   = COALESCE(raw_qty, qty)") {
+		t.Errorf("raw repair:\n%s", sql)
+	}
+	if sql := rollupUnitRepairSQL(false); !strings.Contains(sql, "qty     = qty * $3 + $4,") || !strings.Contains(sql, "UPDATE health_metric_rollups") {
+		t.Errorf("rollup repair:\n%s", sql)
+	}
+	if sql := rollupUnitRepairSQL(true); !strings.Contains(sql, "qty * $3 + $4 * sample_count") {
+		t.Errorf("cumulative rollup repair:\n%s", sql)
+	}
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// UserProfile holds the optional demographics used to pick population norms,
+// the functional threshold power used for cycling intensity factor, weekly
+// volume landmark overrides per muscle group, and the preferred unit system
+// ("metric" or "imperial") for workout responses. Any field may be unset.
+type UserProfile struct {
+	BirthDate       *time.Time                `json:"birth_date"`
+	Sex             string                    `json:"sex"`
+	FTPWatts        *int                      `json:"ftp_watts"`
+	VolumeLandmarks map[string]VolumeLandmark `json:"volume_landmarks"`
+	UnitSystem      string                    `json:"unit_system"`
+}
+
+// Age returns the profile's age in whole years on day at, or false when the
+// birth date is unknown.
+func (p *UserProfile) Age(at time.Time) (int, bool) {
+	if p == nil || p.BirthDate == nil {
+		return 0, false
+	}
+	b := *p.BirthDate
+	age := at.Year() - b.Year()
+	if at.Month() < b.Month() || (at.Month() == b.Month() && at.Day() < b.Day()) {
+		age--
+	}
+	return age, true
+}
+
+// GetUserProfile returns the user's profile, or nil if none has been saved.
+func (db *DB) GetUserProfile(ctx context.Context, userID int) (*UserProfile, error) {
+	var p UserProfile
+	var sex, units *string
+	err := db.Pool.QueryRow(ctx,
+		`SELECT birth_date, sex, ftp_watts, volume_landmarks, unit_system FROM user_profiles WHERE user_id = $1`, userID,
+	).Scan(&p.BirthDate, &sex, &p.FTPWatts, &p.VolumeLandmarks, &units)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying user profile: %w", err)
+	}
+	if sex != nil {
+		p.Sex = *sex
+	}
+	if units != nil {
+		p.UnitSystem = *units
+	}
+	return &p, nil
+}
+
+// UpsertUserProfile saves the user's profile, replacing any previous one.
+func (db *DB) UpsertUserProfile(ctx context.Context, userID int, p UserProfile) error {
+	var sex, units *string
+	if p.Sex != "" {
+		sex = &p.Sex
+	}
+	if p.UnitSystem != "" {
+		units = &p.UnitSystem
+	}
+	var landmarks []byte
+	if len(p.VolumeLandmarks) > 0 {
+		var err error
+		if landmarks, err = json.Marshal(p.VolumeLandmarks); err != nil {
+			return fmt.Errorf("encoding volume landmarks: %w", err)
+		}
+	}
+	_, err := db.Pool.Exec(ctx,
+		`INSERT INTO user_profiles (user_id, birth_date, sex, ftp_watts, volume_landmarks, unit_system)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (user_id) DO UPDATE SET birth_date = EXCLUDED.birth_date, sex = EXCLUDED.sex,
+		   ftp_watts = EXCLUDED.ftp_watts, volume_landmarks = EXCLUDED.volume_landmarks,
+		   unit_system = EXCLUDED.unit_system`,
+		userID, p.BirthDate, sex, p.FTPWatts, landmarks, units)
+	if err != nil {
+		return fmt.Errorf("upserting user profile: %w", err)
+	}
+	return nil
+}
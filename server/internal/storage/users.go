@@ -2,6 +2,8 @@ package storage
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 )
@@ -34,3 +36,114 @@ func (db *DB) GetPrimaryUser(ctx context.Context) (id int, login string, err err
 	}
 	return
 }
+
+// UserSummary describes a user for the admin listing, with row counts of the
+// data they have stored.
+type UserSummary struct {
+	ID          int       `json:"id"`
+	Login       string    `json:"login"`
+	DisplayName *string   `json:"display_name"`
+	CreatedAt   time.Time `json:"created_at"`
+	LastSeen    time.Time `json:"last_seen"`
+	MetricRows  int64     `json:"metric_rows"`
+	Workouts    int64     `json:"workouts"`
+	SleepNights int64     `json:"sleep_nights"`
+	Sets        int64     `json:"sets"`
+}
+
+// usersSQL lists every user with per-user row counts, oldest account first.
+const usersSQL = `
+	SELECT u.id, u.login, u.display_name, u.created_at, u.last_seen,
+		(SELECT COUNT(*) FROM health_metrics WHERE user_id = u.id),
+		(SELECT COUNT(*) FROM workouts WHERE user_id = u.id),
+		(SELECT COUNT(*) FROM sleep_sessions WHERE user_id = u.id),
+		(SELECT COUNT(*) FROM workout_sets WHERE user_id = u.id)
+	FROM users u
+	ORDER BY u.created_at ASC, u.id ASC`
+
+// GetUsers returns all users with their stored row counts.
+func (db *DB) GetUsers(ctx context.Context) ([]UserSummary, error) {
+	rows, err := db.Pool.Query(ctx, usersSQL)
+	if err != nil {
+		return nil, fmt.Errorf("querying users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []UserSummary{}
+	for rows.Next() {
+		var u UserSummary
+		if err := rows.Scan(&u.ID, &u.Login, &u.DisplayName, &u.CreatedAt, &u.LastSeen,
+			&u.MetricRows, &u.Workouts, &u.SleepNights, &u.Sets); err != nil {
+			return nil, fmt.Errorf("scanning user: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// userDataTables lists every table holding per-user rows, children before
+// their parents so each delete can report its own row count rather than
+// relying on ON DELETE CASCADE.
+var userDataTables = []string{
+	"workout_heart_rate",
+	"workout_hr_recovery",
+	"workout_power",
+	"workout_cadence",
+	"workout_routes",
+	"workout_tags",
+	"workouts",
+	"workout_sets",
+	"health_metrics",
+	"health_metric_rollups",
+	"sleep_stages",
+	"sleep_sessions",
+	"ecg_recordings",
+	"audiograms",
+	"activity_summaries",
+	"medications",
+	"vision_prescriptions",
+	"state_of_mind",
+	"category_samples",
+	"symptoms",
+	"oura_sync_state",
+	"oura_tokens",
+	"source_priority",
+	"user_metric_visibility",
+	"user_profiles",
+	"rejected_metrics",
+	"data_versions",
+	"import_logs",
+}
+
+// DeleteUserData erases all of a user's stored data in one transaction and
+// returns the number of rows deleted per table. With removeUser the users row
+// goes too (reported as "users"); otherwise the account stays and the user can
+// keep uploading.
+func (db *DB) DeleteUserData(ctx context.Context, userID int, removeUser bool) (map[string]int64, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning user delete tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	deleted := make(map[string]int64, len(userDataTables)+1)
+	for _, table := range userDataTables {
+		tag, err := tx.Exec(ctx, "DELETE FROM "+table+" WHERE user_id = $1", userID)
+		if err != nil {
+			return nil, fmt.Errorf("deleting %s: %w", table, err)
+		}
+		deleted[table] = tag.RowsAffected()
+	}
+	if removeUser {
+		tag, err := tx.Exec(ctx, `DELETE FROM users WHERE id = $1`, userID)
+		if err != nil {
+			return nil, fmt.Errorf("deleting user: %w", err)
+		}
+		deleted["users"] = tag.RowsAffected()
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing user delete: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestUsersSQLCountsPerUser verifies every row count in the admin listing is
+// scoped to the listed user rather than counting the whole table.
+func TestUsersSQLCountsPerUser(t *testing.T) {
+	for _, table := range []string{"health_metrics", "workouts", "sleep_sessions", "workout_sets"} {
+		if !strings.Contains(usersSQL, "FROM "+table+" WHERE user_id = u.id") {
+			t.Errorf("usersSQL does not count %s per user", table)
+		}
+	}
+	if !strings.Contains(usersSQL, "ORDER BY u.created_at ASC") {
+		t.Error("usersSQL should list the oldest account (the primary user) first")
+	}
+}
+
+// TestUserDataTablesCoverSchema verifies the erase covers every table with a
+// user_id column in the migrations, and that child tables are emptied before
+// the tables they reference.
+func TestUserDataTablesCoverSchema(t *testing.T) {
+	files, err := filepath.Glob("../../migrations/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+	createRe := regexp.MustCompile(`(?s)CREATE TABLE (?:IF NOT EXISTS )?(\w+) \((.*?)\n\);`)
+
+	listed := make(map[string]int)
+	for i, table := range userDataTables {
+		listed[table] = i
+	}
+	for _, f := range files {
+		sql, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range createRe.FindAllStringSubmatch(string(sql), -1) {
+			if m[1] == "users" || !strings.Contains(m[2], "user_id") {
+				continue
+			}
+			if _, ok := listed[m[1]]; !ok {
+				t.Errorf("table %s (%s) has user_id but is not erased by DeleteUserData", m[1], filepath.Base(f))
+			}
+		}
+	}
+
+	for _, child := range []string{"workout_heart_rate", "workout_routes"} {
+		if listed[child] > listed["workouts"] {
+			t.Errorf("%s must be deleted before workouts", child)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/claude/freereps/internal/ingest"
+	"github.com/claude/freereps/internal/models"
+)
+
+// DefaultTemperatureBandC is the width of each temperature band when the
+// caller doesn't choose one.
+const DefaultTemperatureBandC = 5.0
+
+// TemperatureBand summarizes the workouts recorded in one temperature range
+// [MinC, MaxC). AvgPaceSecPerKm is total moving time (elapsed duration for
+// workouts without a route) over total distance, so longer workouts weigh
+// more; AvgHumidity is nil when no workout in the band recorded humidity.
+type TemperatureBand struct {
+	MinC            float64  `json:"min_c"`
+	MaxC            float64  `json:"max_c"`
+	Workouts        int      `json:"workouts"`
+	DistanceKm      float64  `json:"distance_km"`
+	AvgPaceSecPerKm float64  `json:"avg_pace_sec_per_km"`
+	AvgHumidity     *float64 `json:"avg_humidity"`
+}
+
+// GetPaceByTemperature groups workouts in [start, end) that recorded both a
+// distance and a temperature into bands of bandC degrees Celsius and returns
+// the average pace per band, coldest first. name narrows to one workout type.
+func (db *DB) GetPaceByTemperature(ctx context.Context, start, end time.Time, name string, bandC float64, userID int) ([]TemperatureBand, error) {
+	rows, err := db.QueryWorkouts(ctx, start, end, userID, WorkoutFilter{Name: name})
+	if err != nil {
+		return nil, fmt.Errorf("querying pace by temperature: %w", err)
+	}
+	return paceByTemperature(rows, bandC), nil
+}
+
+// paceByTemperature buckets workouts by temperature, converting Fahrenheit
+// readings and non-km distances first. Workouts missing either value, or
+// with no distance or duration, are skipped.
+func paceByTemperature(rows []models.WorkoutRow, bandC float64) []TemperatureBand {
+	if bandC <= 0 {
+		bandC = DefaultTemperatureBandC
+	}
+	type acc struct {
+		workouts int
+		km, sec  float64
+		humSum   float64
+		humN     int
+	}
+	bands := map[int]*acc{}
+	for _, w := range rows {
+		if w.Temperature == nil || w.Distance == nil || w.DurationSec <= 0 {
+			continue
+		}
+		km, ok := ingest.ConvertUnit(*w.Distance, w.DistanceUnits, "km")
+		if !ok {
+			km = *w.Distance
+		}
+		if km <= 0 {
+			continue
+		}
+		tempC, ok := ingest.ConvertUnit(*w.Temperature, w.TemperatureUnits, "degC")
+		if !ok {
+			tempC = *w.Temperature
+		}
+		key := int(math.Floor(tempC / bandC))
+		a := bands[key]
+		if a == nil {
+			a = &acc{}
+			bands[key] = a
+		}
+		a.workouts++
+		a.km += km
+		a.sec += PaceDurationSec(w)
+		if w.Humidity != nil {
+			a.humSum += *w.Humidity
+			a.humN++
+		}
+	}
+
+	keys := make([]int, 0, len(bands))
+	for k := range bands {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	out := make([]TemperatureBand, 0, len(keys))
+	for _, k := range keys {
+		a := bands[k]
+		b := TemperatureBand{
+			MinC:            float64(k) * bandC,
+			MaxC:            float64(k+1) * bandC,
+			Workouts:        a.workouts,
+			DistanceKm:      a.km,
+			AvgPaceSecPerKm: a.sec / a.km,
+		}
+		if a.humN > 0 {
+			h := a.humSum / float64(a.humN)
+			b.AvgHumidity = &h
+		}
+		out = append(out, b)
+	}
+	return out
+}
@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"math"
+	"testing"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// TestPaceByTemperature verifies workouts are banded by Celsius temperature,
+// Fahrenheit and mile readings are converted, and pace is duration-weighted.
+func TestPaceByTemperature(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	rows := []models.WorkoutRow{
+		{DurationSec: 1800, Distance: f(5), DistanceUnits: "km", Temperature: f(8.2), TemperatureUnits: "degC", Humidity: f(90)},
+		{DurationSec: 3000, Distance: f(10), DistanceUnits: "km", Temperature: f(6), TemperatureUnits: "degC"},
+		// 77°F = 25°C, 3.106856 mi ≈ 5 km
+		{DurationSec: 2000, Distance: f(3.106856), DistanceUnits: "mi", Temperature: f(77), TemperatureUnits: "degF", Humidity: f(40)},
+		{DurationSec: 1800, Distance: f(5), DistanceUnits: "km"},          // no temperature
+		{DurationSec: 1800, Temperature: f(12), TemperatureUnits: "degC"}, // no distance
+	}
+
+	bands := paceByTemperature(rows, 5)
+	if len(bands) != 2 {
+		t.Fatalf("got %d bands, want 2: %+v", len(bands), bands)
+	}
+
+	cold := bands[0]
+	if cold.MinC != 5 || cold.MaxC != 10 || cold.Workouts != 2 {
+		t.Errorf("cold band = %+v", cold)
+	}
+	if math.Abs(cold.AvgPaceSecPerKm-320) > 0.01 {
+		t.Errorf("cold pace = %f, want 320", cold.AvgPaceSecPerKm)
+	}
+	if cold.AvgHumidity == nil || *cold.AvgHumidity != 90 {
+		t.Errorf("cold humidity = %v, want 90", cold.AvgHumidity)
+	}
+
+	warm := bands[1]
+	if warm.MinC != 25 || warm.MaxC != 30 || warm.Workouts != 1 {
+		t.Errorf("warm band = %+v", warm)
+	}
+	if math.Abs(warm.AvgPaceSecPerKm-400) > 0.1 {
+		t.Errorf("warm pace = %f, want 400", warm.AvgPaceSecPerKm)
+	}
+}
+
+// TestPaceByTemperatureNegative verifies sub-zero temperatures floor into the
+// band below zero and a non-positive width falls back to the default.
+func TestPaceByTemperatureNegative(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	rows := []models.WorkoutRow{
+		{DurationSec: 1500, Distance: f(5), DistanceUnits: "km", Temperature: f(-2), TemperatureUnits: "degC"},
+	}
+	bands := paceByTemperature(rows, 0)
+	if len(bands) != 1 || bands[0].MinC != -5 || bands[0].MaxC != 0 {
+		t.Errorf("bands = %+v, want one [-5, 0) band", bands)
+	}
+}
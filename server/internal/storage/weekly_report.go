@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/claude/freereps/internal/ingest"
+	"github.com/claude/freereps/internal/models"
+)
+
+// ReportMetric is one headline figure in a weekly report: a value for each
+// day of the week (nil when there's no data) plus this week's and last week's
+// daily averages.
+type ReportMetric struct {
+	Name    string     `json:"name"`
+	Label   string     `json:"label"`
+	Unit    string     `json:"unit"`
+	Daily   []*float64 `json:"daily"`
+	Avg     *float64   `json:"avg"`
+	PrevAvg *float64   `json:"prev_avg"`
+
+	// LowerIsBetter marks metrics, like resting heart rate, where a drop is
+	// an improvement.
+	LowerIsBetter bool `json:"lower_is_better,omitempty"`
+
+	// Norm compares Avg against the population norm for the user's age and
+	// sex; set only for metrics with a norm table.
+	Norm *NormContext `json:"norm,omitempty"`
+}
+
+// ReportWorkouts totals the week's workouts alongside last week's.
+type ReportWorkouts struct {
+	Count          int     `json:"count"`
+	PrevCount      int     `json:"prev_count"`
+	Minutes        float64 `json:"minutes"`
+	PrevMinutes    float64 `json:"prev_minutes"`
+	ActiveKcal     float64 `json:"active_kcal"`
+	PrevActiveKcal float64 `json:"prev_active_kcal"`
+}
+
+// WeeklyReport summarizes the seven days ending WeekEnd (inclusive) against
+// the seven days before.
+type WeeklyReport struct {
+	WeekStart string         `json:"week_start"`
+	WeekEnd   string         `json:"week_end"`
+	Metrics   []ReportMetric `json:"metrics"`
+	Workouts  ReportWorkouts `json:"workouts"`
+}
+
+// reportDays is the length of a report week.
+const reportDays = 7
+
+// GetWeeklyReport builds the report for the week ending the day before end
+// (end is truncated to a UTC date and is exclusive).
+func (db *DB) GetWeeklyReport(ctx context.Context, end time.Time, userID int) (*WeeklyReport, error) {
+	end = time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(0, 0, -reportDays)
+	prevStart := start.AddDate(0, 0, -reportDays)
+
+	report := &WeeklyReport{
+		WeekStart: start.Format("2006-01-02"),
+		WeekEnd:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+	}
+
+	steps, err := db.GetDailySteps(ctx, prevStart, end, userID)
+	if err != nil {
+		return nil, err
+	}
+	byDay := make(map[string]float64, len(steps))
+	for _, s := range steps {
+		byDay[s.Date] = s.Steps
+	}
+	report.Metrics = append(report.Metrics, reportMetric("steps", "Steps", "steps", byDay, start))
+
+	sessions, err := db.QuerySleepSessions(ctx, prevStart, end, userID)
+	if err != nil {
+		return nil, err
+	}
+	byDay = make(map[string]float64, len(sessions))
+	for _, s := range sessions {
+		// Naps and split nights are separate sessions on the same date.
+		byDay[s.Date.UTC().Format("2006-01-02")] += s.TotalSleep
+	}
+	report.Metrics = append(report.Metrics, reportMetric("sleep", "Sleep", "h", byDay, start))
+
+	for _, m := range []struct {
+		name, label, unit string
+		lowerIsBetter     bool
+	}{
+		{"resting_heart_rate", "Resting HR", "bpm", true},
+		{"heart_rate_variability", "HRV", "ms", false},
+	} {
+		points, err := db.GetTimeSeries(ctx, m.name, prevStart, end, "1 day", userID)
+		if err != nil {
+			return nil, err
+		}
+		byDay = make(map[string]float64, len(points))
+		for _, p := range points {
+			if p.Avg != nil {
+				byDay[p.Time.UTC().Format("2006-01-02")] = *p.Avg
+			}
+		}
+		rm := reportMetric(m.name, m.label, m.unit, byDay, start)
+		rm.LowerIsBetter = m.lowerIsBetter
+		report.Metrics = append(report.Metrics, rm)
+	}
+
+	profile, err := db.GetUserProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range report.Metrics {
+		m := &report.Metrics[i]
+		if _, ok := populationNorms[m.Name]; ok {
+			nc := normContext(m.Name, m.Avg, profile, end)
+			m.Norm = &nc
+		}
+	}
+
+	workouts, err := db.QueryWorkoutsMerged(ctx, prevStart, end, userID, WorkoutFilter{})
+	if err != nil {
+		return nil, err
+	}
+	w := &report.Workouts
+	for _, wo := range workouts {
+		kcal := workoutKcal(wo)
+		if wo.StartTime.Before(start) {
+			w.PrevCount++
+			w.PrevMinutes += wo.DurationSec / 60
+			w.PrevActiveKcal += kcal
+		} else {
+			w.Count++
+			w.Minutes += wo.DurationSec / 60
+			w.ActiveKcal += kcal
+		}
+	}
+	return report, nil
+}
+
+// workoutKcal returns a workout's active energy in kcal, converting kJ, or 0
+// when it has none.
+func workoutKcal(w models.WorkoutRow) float64 {
+	if w.ActiveEnergyBurned == nil {
+		return 0
+	}
+	if kcal, ok := ingest.ConvertUnit(*w.ActiveEnergyBurned, w.ActiveEnergyUnits, "kcal"); ok {
+		return kcal
+	}
+	return *w.ActiveEnergyBurned
+}
+
+// reportMetric lays byDay (keyed YYYY-MM-DD) out as the week starting at
+// start and averages it and the preceding week over days with data.
+func reportMetric(name, label, unit string, byDay map[string]float64, start time.Time) ReportMetric {
+	m := ReportMetric{Name: name, Label: label, Unit: unit, Daily: make([]*float64, reportDays)}
+	var prev []*float64
+	for i := 0; i < reportDays; i++ {
+		if v, ok := byDay[start.AddDate(0, 0, i).Format("2006-01-02")]; ok {
+			m.Daily[i] = &v
+		}
+		if v, ok := byDay[start.AddDate(0, 0, i-reportDays).Format("2006-01-02")]; ok {
+			prev = append(prev, &v)
+		}
+	}
+	m.Avg = meanOf(m.Daily)
+	m.PrevAvg = meanOf(prev)
+	return m
+}
+
+// meanOf averages the non-nil values, or returns nil if there are none.
+func meanOf(vals []*float64) *float64 {
+	var sum float64
+	var n int
+	for _, v := range vals {
+		if v != nil {
+			sum += *v
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	avg := sum / float64(n)
+	return &avg
+}
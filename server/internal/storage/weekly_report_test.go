@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// TestReportMetric verifies daily values are placed on their weekday, missing
+// days stay nil, and both weeks are averaged over days with data only.
+func TestReportMetric(t *testing.T) {
+	start := time.Date(2025, 4, 7, 0, 0, 0, 0, time.UTC)
+	byDay := map[string]float64{
+		"2025-03-31": 6000, // previous week
+		"2025-04-02": 8000,
+		"2025-04-07": 10000,
+		"2025-04-09": 6000,
+		"2025-04-14": 99999, // after the week; ignored
+	}
+
+	m := reportMetric("steps", "Steps", "steps", byDay, start)
+	if len(m.Daily) != 7 || m.Daily[0] == nil || *m.Daily[0] != 10000 || m.Daily[1] != nil || *m.Daily[2] != 6000 {
+		t.Fatalf("daily = %v", m.Daily)
+	}
+	if m.Avg == nil || *m.Avg != 8000 {
+		t.Errorf("avg = %v, want 8000", m.Avg)
+	}
+	if m.PrevAvg == nil || *m.PrevAvg != 7000 {
+		t.Errorf("prev avg = %v, want 7000", m.PrevAvg)
+	}
+
+	if empty := reportMetric("hrv", "HRV", "ms", nil, start); empty.Avg != nil || empty.PrevAvg != nil {
+		t.Errorf("empty metric averages = %v/%v, want nil", empty.Avg, empty.PrevAvg)
+	}
+}
+
+// TestWorkoutKcal verifies kJ workouts are counted in kcal and workouts
+// without energy count as zero.
+func TestWorkoutKcal(t *testing.T) {
+	kj, kcal := 4184.0, 500.0
+	if got := workoutKcal(models.WorkoutRow{ActiveEnergyBurned: &kj, ActiveEnergyUnits: "kJ"}); got != 1000 {
+		t.Errorf("kJ = %v, want 1000", got)
+	}
+	if got := workoutKcal(models.WorkoutRow{ActiveEnergyBurned: &kcal, ActiveEnergyUnits: "kcal"}); got != 500 {
+		t.Errorf("kcal = %v, want 500", got)
+	}
+	if got := workoutKcal(models.WorkoutRow{}); got != 0 {
+		t.Errorf("none = %v, want 0", got)
+	}
+}
@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+	"github.com/google/uuid"
+)
+
+// cadenceBandSPM is the width of the cadence distribution bands, in steps
+// per minute.
+const cadenceBandSPM = 10
+
+// MaxCadenceInterval is the longest gap between in-workout step count
+// samples treated as the interval the steps were counted over. Longer gaps
+// are pauses, so the sample before one reuses the previous interval.
+const MaxCadenceInterval = 2 * time.Minute
+
+// CadenceIntervals returns the interval each in-workout step count sample
+// covers: the gap to the next sample. The last sample, or one followed by a
+// pause, reuses the previous interval, or HAE's one-minute default when there
+// is none.
+func CadenceIntervals(times []time.Time) []time.Duration {
+	out := make([]time.Duration, len(times))
+	interval := time.Minute
+	for i, t := range times {
+		if i+1 < len(times) {
+			if gap := times[i+1].Sub(t); gap > 0 && gap <= MaxCadenceInterval {
+				interval = gap
+			}
+		}
+		out[i] = interval
+	}
+	return out
+}
+
+// WorkoutCadenceStats summarizes a workout's cadence samples. Stopped
+// samples (0 spm) count towards Samples but not towards the average or the
+// distribution.
+type WorkoutCadenceStats struct {
+	Samples      int           `json:"samples"`
+	AvgSPM       float64       `json:"avg_spm"`
+	MaxSPM       float64       `json:"max_spm"`
+	Distribution []CadenceBand `json:"distribution"`
+}
+
+// CadenceBand is one cadenceBandSPM-wide band of the cadence distribution.
+// Seconds is the time spent in the band (see CadenceIntervals) and Share
+// that time as a fraction of the moving time.
+type CadenceBand struct {
+	FromSPM int     `json:"from_spm"`
+	ToSPM   int     `json:"to_spm"`
+	Samples int     `json:"samples"`
+	Seconds float64 `json:"seconds"`
+	Share   float64 `json:"share"`
+}
+
+// InsertWorkoutCadence batch-inserts workout cadence samples. Returns count inserted.
+func (db *DB) InsertWorkoutCadence(ctx context.Context, rows []models.WorkoutCadenceRow) (int64, error) {
+	const batchSize = 1000
+	var total int64
+	for i := 0; i < len(rows); i += batchSize {
+		batch := rows[i:min(i+batchSize, len(rows))]
+
+		query := `INSERT INTO workout_cadence (time, workout_id, user_id, spm, source) VALUES `
+		args := make([]any, 0, len(batch)*5)
+		valueStrings := make([]string, 0, len(batch))
+		for j, r := range batch {
+			base := j * 5
+			valueStrings = append(valueStrings, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d)",
+				base+1, base+2, base+3, base+4, base+5))
+			args = append(args, r.Time, r.WorkoutID, r.UserID, r.SPM, r.Source)
+		}
+		query += strings.Join(valueStrings, ",") + " ON CONFLICT DO NOTHING"
+
+		tag, err := db.Pool.Exec(ctx, query, args...)
+		if err != nil {
+			return total, fmt.Errorf("inserting workout cadence: %w", err)
+		}
+		total += tag.RowsAffected()
+	}
+	return total, nil
+}
+
+// queryWorkoutCadence returns a workout's cadence samples in time order.
+func (db *DB) queryWorkoutCadence(ctx context.Context, workoutID uuid.UUID, userID int) ([]models.WorkoutCadenceRow, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT time, workout_id, user_id, spm, source
+		 FROM workout_cadence
+		 WHERE workout_id = $1 AND user_id = $2
+		 ORDER BY time ASC`,
+		workoutID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying workout cadence: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.WorkoutCadenceRow
+	for rows.Next() {
+		var c models.WorkoutCadenceRow
+		if err := rows.Scan(&c.Time, &c.WorkoutID, &c.UserID, &c.SPM, &c.Source); err != nil {
+			return nil, fmt.Errorf("scanning workout cadence: %w", err)
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// GetWorkoutCadence returns average, maximum and the distribution of running
+// cadence for a workout. Returns nil for workouts without cadence data.
+func (db *DB) GetWorkoutCadence(ctx context.Context, workoutID uuid.UUID, userID int) (*WorkoutCadenceStats, error) {
+	points, err := db.queryWorkoutCadence(ctx, workoutID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return ComputeWorkoutCadenceStats(points), nil
+}
+
+// ComputeWorkoutCadenceStats derives cadence stats from samples in time
+// order. Bands are weighted by each sample's interval, so Share is the share
+// of moving time rather than of samples. Returns nil without samples.
+func ComputeWorkoutCadenceStats(points []models.WorkoutCadenceRow) *WorkoutCadenceStats {
+	if len(points) == 0 {
+		return nil
+	}
+	times := make([]time.Time, len(points))
+	for i, p := range points {
+		times[i] = p.Time
+	}
+	intervals := CadenceIntervals(times)
+
+	stats := &WorkoutCadenceStats{Samples: len(points), Distribution: []CadenceBand{}}
+	bands := map[int]*CadenceBand{}
+	var sum, movingSec float64
+	moving := 0
+	for i, p := range points {
+		stats.MaxSPM = max(stats.MaxSPM, p.SPM)
+		if p.SPM <= 0 {
+			continue
+		}
+		sum += p.SPM
+		moving++
+		sec := intervals[i].Seconds()
+		movingSec += sec
+		from := int(math.Floor(p.SPM/cadenceBandSPM)) * cadenceBandSPM
+		b := bands[from]
+		if b == nil {
+			b = &CadenceBand{FromSPM: from, ToSPM: from + cadenceBandSPM}
+			bands[from] = b
+		}
+		b.Samples++
+		b.Seconds += sec
+	}
+	if moving == 0 {
+		return stats
+	}
+	stats.AvgSPM = sum / float64(moving)
+
+	for _, b := range bands {
+		b.Share = b.Seconds / movingSec
+		stats.Distribution = append(stats.Distribution, *b)
+	}
+	sort.Slice(stats.Distribution, func(i, j int) bool {
+		return stats.Distribution[i].FromSPM < stats.Distribution[j].FromSPM
+	})
+	return stats
+}
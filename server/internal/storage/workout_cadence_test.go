@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// TestComputeWorkoutCadenceStats verifies average and max cadence, that
+// stopped samples are left out of the average, and the banded distribution.
+func TestComputeWorkoutCadenceStats(t *testing.T) {
+	start := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	var rows []models.WorkoutCadenceRow
+	for i, spm := range []float64{162, 168, 171, 0, 175, 179, 158} {
+		rows = append(rows, models.WorkoutCadenceRow{Time: start.Add(time.Duration(i) * time.Minute), SPM: spm})
+	}
+
+	stats := ComputeWorkoutCadenceStats(rows)
+	if stats.Samples != 7 || stats.MaxSPM != 179 {
+		t.Errorf("samples/max = %d/%v, want 7/179", stats.Samples, stats.MaxSPM)
+	}
+	if want := (162.0 + 168 + 171 + 175 + 179 + 158) / 6; math.Abs(stats.AvgSPM-want) > 1e-9 {
+		t.Errorf("avg = %v, want %v", stats.AvgSPM, want)
+	}
+
+	want := []CadenceBand{
+		{FromSPM: 150, ToSPM: 160, Samples: 1},
+		{FromSPM: 160, ToSPM: 170, Samples: 2},
+		{FromSPM: 170, ToSPM: 180, Samples: 3},
+	}
+	if len(stats.Distribution) != len(want) {
+		t.Fatalf("distribution = %+v, want %d bands", stats.Distribution, len(want))
+	}
+	for i, w := range want {
+		got := stats.Distribution[i]
+		if got.FromSPM != w.FromSPM || got.ToSPM != w.ToSPM || got.Samples != w.Samples {
+			t.Errorf("band %d = %+v, want %+v", i, got, w)
+		}
+		if share := float64(w.Samples) / 6; math.Abs(got.Share-share) > 1e-9 {
+			t.Errorf("band %d share = %v, want %v", i, got.Share, share)
+		}
+	}
+
+	if ComputeWorkoutCadenceStats(nil) != nil {
+		t.Error("no samples should give nil stats")
+	}
+	stopped := ComputeWorkoutCadenceStats(rows[3:4])
+	if stopped.AvgSPM != 0 || len(stopped.Distribution) != 0 {
+		t.Errorf("all-stopped stats = %+v, want zero average and no bands", stopped)
+	}
+}
+
+// TestCadenceShareIsTimeWeighted verifies bands are weighted by the interval
+// each sample covers: one 2-minute sample outweighs two 30-second ones, and
+// a pause reuses the previous interval instead of counting the gap.
+func TestCadenceShareIsTimeWeighted(t *testing.T) {
+	start := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	rows := []models.WorkoutCadenceRow{
+		{Time: start, SPM: 165},
+		{Time: start.Add(30 * time.Second), SPM: 165},
+		{Time: start.Add(time.Minute), SPM: 175},
+		{Time: start.Add(3 * time.Minute), SPM: 175}, // paused for 10 minutes after
+		{Time: start.Add(13 * time.Minute), SPM: 175},
+	}
+	stats := ComputeWorkoutCadenceStats(rows)
+	if len(stats.Distribution) != 2 {
+		t.Fatalf("distribution = %+v, want 2 bands", stats.Distribution)
+	}
+	// 160s: 30+30. 170s: 120+120+120 (the last two reuse 2 minutes).
+	low, high := stats.Distribution[0], stats.Distribution[1]
+	if low.Seconds != 60 || high.Seconds != 360 {
+		t.Errorf("seconds = %v/%v, want 60/360", low.Seconds, high.Seconds)
+	}
+	if math.Abs(low.Share-60.0/420) > 1e-9 || math.Abs(high.Share-360.0/420) > 1e-9 {
+		t.Errorf("shares = %v/%v, want time-weighted", low.Share, high.Share)
+	}
+}
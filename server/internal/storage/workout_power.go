@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+	"github.com/google/uuid"
+)
+
+// npWindowSec is the rolling window of normalized power, in seconds.
+const npWindowSec = 30
+
+// maxPowerGapSec is the longest gap between power samples that is filled by
+// holding the previous value. Longer gaps are treated as pauses and left out.
+const maxPowerGapSec = 10
+
+// WorkoutPowerStats summarizes a workout's power meter stream.
+// NormalizedWatts is nil for streams shorter than the 30-second window;
+// IntensityFactor is nil without it or without an FTP in the user profile.
+type WorkoutPowerStats struct {
+	Samples         int      `json:"samples"`
+	AvgWatts        float64  `json:"avg_watts"`
+	MaxWatts        float64  `json:"max_watts"`
+	NormalizedWatts *float64 `json:"normalized_watts"`
+	FTPWatts        *int     `json:"ftp_watts"`
+	IntensityFactor *float64 `json:"intensity_factor"`
+}
+
+// InsertWorkoutPower batch-inserts workout power samples. Returns count inserted.
+func (db *DB) InsertWorkoutPower(ctx context.Context, rows []models.WorkoutPowerRow) (int64, error) {
+	const batchSize = 1000
+	var total int64
+	for i := 0; i < len(rows); i += batchSize {
+		batch := rows[i:min(i+batchSize, len(rows))]
+
+		query := `INSERT INTO workout_power (time, workout_id, user_id, watts, source) VALUES `
+		args := make([]any, 0, len(batch)*5)
+		valueStrings := make([]string, 0, len(batch))
+		for j, r := range batch {
+			base := j * 5
+			valueStrings = append(valueStrings, fmt.Sprintf("($%d,$%d,$%d,$%d,$%d)",
+				base+1, base+2, base+3, base+4, base+5))
+			args = append(args, r.Time, r.WorkoutID, r.UserID, r.Watts, r.Source)
+		}
+		query += strings.Join(valueStrings, ",") + " ON CONFLICT DO NOTHING"
+
+		tag, err := db.Pool.Exec(ctx, query, args...)
+		if err != nil {
+			return total, fmt.Errorf("inserting workout power: %w", err)
+		}
+		total += tag.RowsAffected()
+	}
+	return total, nil
+}
+
+// queryWorkoutPower returns a workout's power samples in time order.
+func (db *DB) queryWorkoutPower(ctx context.Context, workoutID uuid.UUID, userID int) ([]models.WorkoutPowerRow, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT time, workout_id, user_id, watts, source
+		 FROM workout_power
+		 WHERE workout_id = $1 AND user_id = $2
+		 ORDER BY time ASC`,
+		workoutID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying workout power: %w", err)
+	}
+	defer rows.Close()
+
+	var out []models.WorkoutPowerRow
+	for rows.Next() {
+		var p models.WorkoutPowerRow
+		if err := rows.Scan(&p.Time, &p.WorkoutID, &p.UserID, &p.Watts, &p.Source); err != nil {
+			return nil, fmt.Errorf("scanning workout power: %w", err)
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+// GetWorkoutPowerStats returns average, maximum and normalized power for a
+// workout, plus intensity factor when the user profile has an FTP. Returns
+// nil for workouts without power data.
+func (db *DB) GetWorkoutPowerStats(ctx context.Context, workoutID uuid.UUID, userID int) (*WorkoutPowerStats, error) {
+	points, err := db.queryWorkoutPower(ctx, workoutID, userID)
+	if err != nil || len(points) == 0 {
+		return nil, err
+	}
+	profile, err := db.GetUserProfile(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var ftp *int
+	if profile != nil {
+		ftp = profile.FTPWatts
+	}
+	return ComputeWorkoutPowerStats(points, ftp), nil
+}
+
+// ComputeWorkoutPowerStats derives power stats from time-ordered samples.
+// The stream is resampled to one value per second first, so average and
+// normalized power weigh each second equally regardless of how often the
+// device recorded. Returns nil without samples.
+func ComputeWorkoutPowerStats(points []models.WorkoutPowerRow, ftp *int) *WorkoutPowerStats {
+	if len(points) == 0 {
+		return nil
+	}
+	stats := &WorkoutPowerStats{Samples: len(points), FTPWatts: ftp}
+	for _, p := range points {
+		stats.MaxWatts = max(stats.MaxWatts, p.Watts)
+	}
+
+	series := powerSeries(points)
+	var sum float64
+	for _, w := range series {
+		sum += w
+	}
+	if len(series) > 0 {
+		stats.AvgWatts = sum / float64(len(series))
+	}
+
+	stats.NormalizedWatts = normalizedPower(series)
+	if stats.NormalizedWatts != nil && ftp != nil && *ftp > 0 {
+		ifactor := *stats.NormalizedWatts / float64(*ftp)
+		stats.IntensityFactor = &ifactor
+	}
+	return stats
+}
+
+// powerSeries expands samples into a 1-second series, holding each sample
+// until the next one. Samples sharing a second keep only the last; gaps
+// longer than maxPowerGapSec contribute a single second.
+func powerSeries(points []models.WorkoutPowerRow) []float64 {
+	var out []float64
+	for i, p := range points {
+		n := 1
+		if i+1 < len(points) {
+			dt := int(points[i+1].Time.Sub(p.Time).Round(time.Second) / time.Second)
+			switch {
+			case dt < 1:
+				n = 0
+			case dt <= maxPowerGapSec:
+				n = dt
+			}
+		}
+		for range n {
+			out = append(out, p.Watts)
+		}
+	}
+	return out
+}
+
+// normalizedPower is the fourth root of the mean fourth power of the
+// 30-second rolling average, or nil for series shorter than the window.
+func normalizedPower(series []float64) *float64 {
+	if len(series) < npWindowSec {
+		return nil
+	}
+	var window, sum4 float64
+	n := 0
+	for i, w := range series {
+		window += w
+		if i >= npWindowSec {
+			window -= series[i-npWindowSec]
+		}
+		if i >= npWindowSec-1 {
+			avg := window / npWindowSec
+			sum4 += avg * avg * avg * avg
+			n++
+		}
+	}
+	np := math.Pow(sum4/float64(n), 0.25)
+	return &np
+}
@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// powerStream builds 1 Hz samples from per-second watts.
+func powerStream(start time.Time, watts ...float64) []models.WorkoutPowerRow {
+	rows := make([]models.WorkoutPowerRow, len(watts))
+	for i, w := range watts {
+		rows[i] = models.WorkoutPowerRow{Time: start.Add(time.Duration(i) * time.Second), Watts: w}
+	}
+	return rows
+}
+
+// TestComputeWorkoutPowerStats verifies average, max, normalized power and
+// intensity factor on synthetic power streams.
+func TestComputeWorkoutPowerStats(t *testing.T) {
+	start := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	ftp := 250
+
+	// A steady effort: NP equals average power.
+	steady := ComputeWorkoutPowerStats(powerStream(start, repeat(200, 600)...), &ftp)
+	if steady.Samples != 600 || steady.AvgWatts != 200 || steady.MaxWatts != 200 {
+		t.Fatalf("steady = %+v", steady)
+	}
+	if steady.NormalizedWatts == nil || math.Abs(*steady.NormalizedWatts-200) > 1e-9 {
+		t.Errorf("steady NP = %v, want 200", steady.NormalizedWatts)
+	}
+	if steady.IntensityFactor == nil || math.Abs(*steady.IntensityFactor-0.8) > 1e-9 {
+		t.Errorf("steady IF = %v, want 0.8", steady.IntensityFactor)
+	}
+
+	// Alternating 2-minute blocks at 100 W and 300 W: same average, but the
+	// surges push NP well above it.
+	var blocks []float64
+	for range 5 {
+		blocks = append(blocks, repeat(100, 120)...)
+		blocks = append(blocks, repeat(300, 120)...)
+	}
+	varied := ComputeWorkoutPowerStats(powerStream(start, blocks...), nil)
+	if varied.AvgWatts != 200 || varied.MaxWatts != 300 {
+		t.Fatalf("varied = %+v", varied)
+	}
+	if varied.NormalizedWatts == nil || *varied.NormalizedWatts < 230 || *varied.NormalizedWatts > 250 {
+		t.Errorf("varied NP = %v, want ~240", varied.NormalizedWatts)
+	}
+	if varied.IntensityFactor != nil {
+		t.Errorf("IF without FTP = %v, want nil", *varied.IntensityFactor)
+	}
+
+	// Shorter than the 30 s window: no NP or IF.
+	short := ComputeWorkoutPowerStats(powerStream(start, repeat(150, 20)...), &ftp)
+	if short.NormalizedWatts != nil || short.IntensityFactor != nil || short.AvgWatts != 150 {
+		t.Errorf("short = %+v", short)
+	}
+
+	if ComputeWorkoutPowerStats(nil, &ftp) != nil {
+		t.Error("no samples should give nil stats")
+	}
+}
+
+// TestPowerSeries verifies sparse samples are held until the next one,
+// duplicate seconds collapse, and long gaps count as pauses.
+func TestPowerSeries(t *testing.T) {
+	start := time.Date(2025, 6, 1, 8, 0, 0, 0, time.UTC)
+	at := func(sec int, w float64) models.WorkoutPowerRow {
+		return models.WorkoutPowerRow{Time: start.Add(time.Duration(sec) * time.Second), Watts: w}
+	}
+	got := powerSeries([]models.WorkoutPowerRow{
+		at(0, 100), at(3, 200), at(3, 210), at(4, 300), at(60, 400),
+	})
+	want := []float64{100, 100, 100, 210, 300, 400}
+	if len(got) != len(want) {
+		t.Fatalf("series = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("series = %v, want %v", got, want)
+		}
+	}
+}
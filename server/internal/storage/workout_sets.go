@@ -7,38 +7,49 @@ import (
 	"time"
 
 	"github.com/claude/freereps/internal/models"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 // DeleteWorkoutSets removes all sets for a given session date and user, enabling clean re-imports.
 func (db *DB) DeleteWorkoutSets(ctx context.Context, sessionDate time.Time, userID int) error {
-	_, err := db.Pool.Exec(ctx,
+	tag, err := db.Pool.Exec(ctx,
 		`DELETE FROM workout_sets WHERE user_id = $1 AND session_date = $2`,
 		userID, sessionDate)
-	return err
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		return markDataChanged(ctx, db.Pool, userID, DataTraining)
+	}
+	return nil
 }
 
-// InsertWorkoutSets batch-inserts Alpha Progression set data. Returns count inserted.
+// InsertWorkoutSets batch-inserts Alpha Progression set data. Returns count
+// inserted; sets already stored under the same session key are skipped.
 func (db *DB) InsertWorkoutSets(ctx context.Context, rows []models.WorkoutSetRow) (int64, error) {
 	if len(rows) == 0 {
 		return 0, nil
 	}
 
-	query := `INSERT INTO workout_sets (user_id, session_name, session_date, session_duration,
+	query := `INSERT INTO workout_sets (user_id, session_key, session_name, session_date, session_duration,
 		exercise_number, exercise_name, equipment, target_reps, is_warmup, set_number,
-		weight_kg, is_bodyweight_plus, reps, rir) VALUES `
-	args := make([]any, 0, len(rows)*14)
+		weight_kg, is_bodyweight_plus, reps, rir, session_notes, rest_seconds) VALUES `
+	args := make([]any, 0, len(rows)*17)
 	valueStrings := make([]string, 0, len(rows))
 
 	for i, r := range rows {
-		base := i * 14
+		base := i * 17
 		valueStrings = append(valueStrings, fmt.Sprintf(
-			"($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d)",
+			"($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d)",
 			base+1, base+2, base+3, base+4, base+5, base+6, base+7,
-			base+8, base+9, base+10, base+11, base+12, base+13, base+14,
+			base+8, base+9, base+10, base+11, base+12, base+13, base+14, base+15,
+			base+16, base+17,
 		))
-		args = append(args, r.UserID, r.SessionName, r.SessionDate, r.SessionDuration,
+		args = append(args, r.UserID, r.SessionKey, r.SessionName, r.SessionDate, r.SessionDuration,
 			r.ExerciseNumber, r.ExerciseName, r.Equipment, r.TargetReps,
-			r.IsWarmup, r.SetNumber, r.WeightKg, r.IsBodyweightPlus, r.Reps, r.RIR)
+			r.IsWarmup, r.SetNumber, r.WeightKg, r.IsBodyweightPlus, r.Reps, r.RIR,
+			r.SessionNotes, r.RestSeconds)
 	}
 
 	query += strings.Join(valueStrings, ",") + " ON CONFLICT DO NOTHING"
@@ -52,9 +63,7 @@ func (db *DB) InsertWorkoutSets(ctx context.Context, rows []models.WorkoutSetRow
 
 // QueryWorkoutSets retrieves workout sets in a date range, optionally filtered by exercise name.
 func (db *DB) QueryWorkoutSets(ctx context.Context, start, end time.Time, userID int, exerciseFilter string) ([]models.WorkoutSetRow, error) {
-	query := `SELECT user_id, session_name, session_date, session_duration,
-		 exercise_number, exercise_name, equipment, target_reps,
-		 is_warmup, set_number, weight_kg, is_bodyweight_plus, reps, rir
+	query := `SELECT ` + workoutSetColumns + `
 		 FROM workout_sets
 		 WHERE session_date >= $1 AND session_date < $2 AND user_id = $3`
 	args := []any{start, end, userID}
@@ -68,13 +77,23 @@ func (db *DB) QueryWorkoutSets(ctx context.Context, start, end time.Time, userID
 		return nil, fmt.Errorf("querying workout sets: %w", err)
 	}
 	defer rows.Close()
+	return scanWorkoutSetRows(rows)
+}
 
+// workoutSetColumns is the select list read by scanWorkoutSetRows.
+const workoutSetColumns = `user_id, workout_id, session_name, session_date, session_duration,
+		 exercise_number, exercise_name, equipment, target_reps,
+		 is_warmup, set_number, weight_kg, is_bodyweight_plus, reps, rir,
+		 session_notes, rest_seconds`
+
+func scanWorkoutSetRows(rows pgx.Rows) ([]models.WorkoutSetRow, error) {
 	var result []models.WorkoutSetRow
 	for rows.Next() {
 		var r models.WorkoutSetRow
-		if err := rows.Scan(&r.UserID, &r.SessionName, &r.SessionDate, &r.SessionDuration,
+		if err := rows.Scan(&r.UserID, &r.WorkoutID, &r.SessionName, &r.SessionDate, &r.SessionDuration,
 			&r.ExerciseNumber, &r.ExerciseName, &r.Equipment, &r.TargetReps,
-			&r.IsWarmup, &r.SetNumber, &r.WeightKg, &r.IsBodyweightPlus, &r.Reps, &r.RIR); err != nil {
+			&r.IsWarmup, &r.SetNumber, &r.WeightKg, &r.IsBodyweightPlus, &r.Reps, &r.RIR,
+			&r.SessionNotes, &r.RestSeconds); err != nil {
 			return nil, fmt.Errorf("scanning workout set: %w", err)
 		}
 		result = append(result, r)
@@ -82,17 +101,66 @@ func (db *DB) QueryWorkoutSets(ctx context.Context, start, end time.Time, userID
 	return result, rows.Err()
 }
 
+// linkWorkoutSetsSQL links each unlinked session of user $1 to the workout
+// whose start time is nearest the session date, within $2 seconds either
+// side. Sessions without a candidate keep a NULL workout_id.
+const linkWorkoutSetsSQL = `
+	UPDATE workout_sets s SET workout_id = m.workout_id
+	FROM (
+		SELECT DISTINCT ON (a.session_key) a.session_key, w.id AS workout_id
+		FROM (SELECT DISTINCT session_key, session_date
+		      FROM workout_sets
+		      WHERE user_id = $1 AND workout_id IS NULL) a
+		JOIN workouts w ON w.user_id = $1
+			AND w.start_time BETWEEN a.session_date - $2 * interval '1 second'
+			                     AND a.session_date + $2 * interval '1 second'
+		ORDER BY a.session_key, abs(extract(epoch FROM w.start_time - a.session_date)), w.id
+	) m
+	WHERE s.user_id = $1 AND s.session_key = m.session_key AND s.workout_id IS NULL`
+
+// LinkWorkoutSets links the user's unlinked Alpha sessions to the nearest
+// workout starting within window of the session. Returns the number of sets
+// linked. Already-linked sets are left alone, so a session uploaded before its
+// Apple workout gets linked on the next Alpha upload.
+func (db *DB) LinkWorkoutSets(ctx context.Context, userID int, window time.Duration) (int64, error) {
+	if window <= 0 {
+		return 0, nil
+	}
+	tag, err := db.Pool.Exec(ctx, linkWorkoutSetsSQL, userID, window.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("linking workout sets: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// QueryLinkedWorkoutSets returns the sets linked to a workout, in session order.
+func (db *DB) QueryLinkedWorkoutSets(ctx context.Context, workoutID uuid.UUID, userID int) ([]models.WorkoutSetRow, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+workoutSetColumns+`
+		 FROM workout_sets
+		 WHERE workout_id = $1 AND user_id = $2
+		 ORDER BY exercise_number ASC, is_warmup DESC, set_number ASC`,
+		workoutID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying linked workout sets: %w", err)
+	}
+	defer rows.Close()
+	return scanWorkoutSetRows(rows)
+}
+
 // AlphaSessionInfo summarizes a distinct Alpha Progression session.
+// WorkoutID is the workout LinkWorkoutSets linked it to, if any.
 type AlphaSessionInfo struct {
 	SessionName     string
 	SessionDate     time.Time
 	SessionDuration string
+	WorkoutID       *uuid.UUID
 }
 
 // QueryAlphaSessions returns one row per distinct Alpha Progression session in a time range.
 func (db *DB) QueryAlphaSessions(ctx context.Context, start, end time.Time, userID int) ([]AlphaSessionInfo, error) {
 	rows, err := db.Pool.Query(ctx,
-		`SELECT DISTINCT session_name, session_date, session_duration
+		`SELECT DISTINCT session_name, session_date, session_duration, workout_id
 		 FROM workout_sets
 		 WHERE session_date >= $1 AND session_date < $2 AND user_id = $3
 		 ORDER BY session_date DESC`,
@@ -105,7 +173,7 @@ func (db *DB) QueryAlphaSessions(ctx context.Context, start, end time.Time, user
 	var result []AlphaSessionInfo
 	for rows.Next() {
 		var s AlphaSessionInfo
-		if err := rows.Scan(&s.SessionName, &s.SessionDate, &s.SessionDuration); err != nil {
+		if err := rows.Scan(&s.SessionName, &s.SessionDate, &s.SessionDuration, &s.WorkoutID); err != nil {
 			return nil, fmt.Errorf("scanning alpha session: %w", err)
 		}
 		result = append(result, s)
@@ -0,0 +1,34 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestLinkWorkoutSetsSQL verifies linking only touches the user's unlinked
+// sets and picks the nearest workout inside the window.
+func TestLinkWorkoutSetsSQL(t *testing.T) {
+	for _, want := range []string{
+		"WHERE user_id = $1 AND workout_id IS NULL",
+		"w.user_id = $1",
+		"a.session_date - $2 * interval '1 second'",
+		"a.session_date + $2 * interval '1 second'",
+		"ORDER BY a.session_key, abs(extract(epoch FROM w.start_time - a.session_date))",
+		"s.workout_id IS NULL",
+	} {
+		if !strings.Contains(linkWorkoutSetsSQL, want) {
+			t.Errorf("linkWorkoutSetsSQL missing %q", want)
+		}
+	}
+}
+
+// TestLinkWorkoutSetsDisabled verifies a zero window skips linking without
+// touching the database.
+func TestLinkWorkoutSetsDisabled(t *testing.T) {
+	db := &DB{}
+	n, err := db.LinkWorkoutSets(context.Background(), 1, 0)
+	if err != nil || n != 0 {
+		t.Errorf("LinkWorkoutSets(0) = %d, %v; want 0, nil", n, err)
+	}
+}
@@ -0,0 +1,65 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxTagLength caps a workout tag's length in characters.
+const maxTagLength = 32
+
+// ErrInvalidTag is returned for tags that are empty, too long, or contain
+// characters other than letters, digits, '-', and '_'.
+var ErrInvalidTag = errors.New("tag must be 1-32 letters, digits, '-' or '_'")
+
+// NormalizeTag trims and lower-cases tag and rejects anything that isn't a
+// short slug, so "Race" and " race " land on the same label.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || len([]rune(tag)) > maxTagLength {
+		return "", ErrInvalidTag
+	}
+	for _, r := range tag {
+		if r != '-' && r != '_' && !('a' <= r && r <= 'z') && !('0' <= r && r <= '9') {
+			return "", ErrInvalidTag
+		}
+	}
+	return tag, nil
+}
+
+// AddWorkoutTag labels one of the user's workouts with tag, which must
+// already be normalized. Adding an existing tag is a no-op. It returns false
+// when the user has no such workout.
+func (db *DB) AddWorkoutTag(ctx context.Context, workoutID uuid.UUID, userID int, tag string) (bool, error) {
+	res, err := db.Pool.Exec(ctx,
+		`INSERT INTO workout_tags (user_id, workout_id, tag)
+		 SELECT user_id, id, $3 FROM workouts WHERE id = $1 AND user_id = $2
+		 ON CONFLICT (user_id, workout_id, tag) DO UPDATE SET tag = EXCLUDED.tag`,
+		workoutID, userID, tag)
+	if err != nil {
+		return false, fmt.Errorf("adding workout tag: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return false, nil
+	}
+	return true, markDataChanged(ctx, db.Pool, userID, DataTraining)
+}
+
+// RemoveWorkoutTag removes tag from one of the user's workouts. It returns
+// false when the workout didn't have the tag.
+func (db *DB) RemoveWorkoutTag(ctx context.Context, workoutID uuid.UUID, userID int, tag string) (bool, error) {
+	res, err := db.Pool.Exec(ctx,
+		`DELETE FROM workout_tags WHERE workout_id = $1 AND user_id = $2 AND tag = $3`,
+		workoutID, userID, tag)
+	if err != nil {
+		return false, fmt.Errorf("removing workout tag: %w", err)
+	}
+	if res.RowsAffected() == 0 {
+		return false, nil
+	}
+	return true, markDataChanged(ctx, db.Pool, userID, DataTraining)
+}
@@ -0,0 +1,29 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestNormalizeTag verifies tags are trimmed and lower-cased, and that empty,
+// overlong, or punctuated tags are rejected.
+func TestNormalizeTag(t *testing.T) {
+	for in, want := range map[string]string{
+		"race":      "race",
+		"  Race ":   "race",
+		"long-run":  "long-run",
+		"zone_2":    "zone_2",
+		"MARATHON1": "marathon1",
+	} {
+		got, err := NormalizeTag(in)
+		if err != nil || got != want {
+			t.Errorf("NormalizeTag(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "   ", "two words", "race!", "a,b", strings.Repeat("x", 33)} {
+		if _, err := NormalizeTag(in); !errors.Is(err, ErrInvalidTag) {
+			t.Errorf("NormalizeTag(%q) err = %v, want ErrInvalidTag", in, err)
+		}
+	}
+}
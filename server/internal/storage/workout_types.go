@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultWorkoutTypes maps raw workout names (as stored after ingest name
+// normalization) onto the canonical types summaries group by. Names not
+// listed are their own type.
+var DefaultWorkoutTypes = map[string]string{
+	"Traditional Strength Training":    "Strength",
+	"Functional Strength Training":     "Strength",
+	"Strength Training":                "Strength",
+	"Strength":                         "Strength",
+	"Core Training":                    "Strength",
+	"Running":                          "Running",
+	"Trail Running":                    "Running",
+	"Track Running":                    "Running",
+	"Cycling":                          "Cycling",
+	"Hand Cycling":                     "Cycling",
+	"Walking":                          "Walking",
+	"Hiking":                           "Hiking",
+	"Swimming":                         "Swimming",
+	"Pool Swim":                        "Swimming",
+	"Open Water Swim":                  "Swimming",
+	"High Intensity Interval Training": "HIIT",
+	"Cross Training":                   "HIIT",
+	"Mixed Cardio":                     "Cardio",
+	"Elliptical":                       "Cardio",
+	"Stair Climbing":                   "Cardio",
+	"Stairs":                           "Cardio",
+	"Rowing":                           "Rowing",
+	"Yoga":                             "Mind & Body",
+	"Pilates":                          "Mind & Body",
+	"Flexibility":                      "Mind & Body",
+	"Mind and Body":                    "Mind & Body",
+	"Cooldown":                         "Mind & Body",
+}
+
+// SetWorkoutTypes overrides or extends DefaultWorkoutTypes.
+func (db *DB) SetWorkoutTypes(overrides map[string]string) {
+	db.workoutTypes = overrides
+}
+
+// WorkoutType returns the canonical type for a raw workout name.
+func (db *DB) WorkoutType(name string) string {
+	return workoutType(name, db.workoutTypes)
+}
+
+// workoutType looks name up in overrides, then DefaultWorkoutTypes, and
+// falls back to name itself.
+func workoutType(name string, overrides map[string]string) string {
+	if t, ok := overrides[name]; ok {
+		return t
+	}
+	if t, ok := DefaultWorkoutTypes[name]; ok {
+		return t
+	}
+	return name
+}
+
+// groupWorkoutTypes merges per-name summaries into per-type summaries, most
+// frequent first. Averages are re-weighted by workout count, and the raw
+// names behind each type are kept in RawNames.
+func groupWorkoutTypes(rows []WorkoutTypePeriodSummary, overrides map[string]string) []WorkoutTypePeriodSummary {
+	type acc struct {
+		sum    WorkoutTypePeriodSummary
+		durSum float64
+		hrSum  float64
+		hrN    int
+	}
+	byType := map[string]*acc{}
+	var order []string
+	for _, r := range rows {
+		t := workoutType(r.Type, overrides)
+		a := byType[t]
+		if a == nil {
+			a = &acc{sum: WorkoutTypePeriodSummary{Type: t}}
+			byType[t] = a
+			order = append(order, t)
+		}
+		a.sum.Count += r.Count
+		a.sum.TotalCalories += r.TotalCalories
+		a.sum.RawNames = append(a.sum.RawNames, r.Type)
+		a.durSum += r.AvgDuration * float64(r.Count)
+		if r.AvgHeartRate != nil {
+			a.hrSum += *r.AvgHeartRate * float64(r.Count)
+			a.hrN += r.Count
+		}
+	}
+
+	out := make([]WorkoutTypePeriodSummary, 0, len(order))
+	for _, t := range order {
+		a := byType[t]
+		if a.sum.Count > 0 {
+			a.sum.AvgDuration = a.durSum / float64(a.sum.Count)
+		}
+		if a.hrN > 0 {
+			hr := a.hrSum / float64(a.hrN)
+			a.sum.AvgHeartRate = &hr
+		}
+		sort.Strings(a.sum.RawNames)
+		out = append(out, a.sum)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	return out
+}
+
+// WorkoutTypeShare is one canonical type's share of the workouts in a range.
+type WorkoutTypeShare struct {
+	Type        string   `json:"type"`
+	Count       int      `json:"count"`
+	DurationSec float64  `json:"duration_sec"`
+	Pct         float64  `json:"pct"`
+	RawNames    []string `json:"raw_names"`
+}
+
+// GetWorkoutTypeDistribution returns how the workouts in [start, end) split
+// across canonical types, by count, most frequent first.
+func (db *DB) GetWorkoutTypeDistribution(ctx context.Context, start, end time.Time, userID int) ([]WorkoutTypeShare, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT name, COUNT(*)::int, COALESCE(SUM(duration_sec), 0)
+		 FROM workouts
+		 WHERE start_time >= $1 AND start_time < $2 AND user_id = $3
+		 GROUP BY name`,
+		start, end, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying workout types: %w", err)
+	}
+	defer rows.Close()
+
+	var names []WorkoutTypeShare
+	for rows.Next() {
+		var s WorkoutTypeShare
+		if err := rows.Scan(&s.Type, &s.Count, &s.DurationSec); err != nil {
+			return nil, fmt.Errorf("scanning workout type: %w", err)
+		}
+		names = append(names, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return workoutTypeDistribution(names, db.workoutTypes), nil
+}
+
+// workoutTypeDistribution merges per-name counts into canonical types and
+// computes each type's percentage of all workouts.
+func workoutTypeDistribution(names []WorkoutTypeShare, overrides map[string]string) []WorkoutTypeShare {
+	byType := map[string]*WorkoutTypeShare{}
+	total := 0
+	for _, n := range names {
+		t := workoutType(n.Type, overrides)
+		s := byType[t]
+		if s == nil {
+			s = &WorkoutTypeShare{Type: t}
+			byType[t] = s
+		}
+		s.Count += n.Count
+		s.DurationSec += n.DurationSec
+		s.RawNames = append(s.RawNames, n.Type)
+		total += n.Count
+	}
+
+	out := make([]WorkoutTypeShare, 0, len(byType))
+	for _, s := range byType {
+		if total > 0 {
+			s.Pct = float64(s.Count) / float64(total) * 100
+		}
+		sort.Strings(s.RawNames)
+		out = append(out, *s)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		return out[i].Type < out[j].Type
+	})
+	return out
+}
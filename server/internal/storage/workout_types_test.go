@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestGroupWorkoutTypesStrength verifies the Apple strength variants collapse
+// into one Strength group with count-weighted averages and their raw names.
+func TestGroupWorkoutTypesStrength(t *testing.T) {
+	hr := func(v float64) *float64 { return &v }
+	rows := []WorkoutTypePeriodSummary{
+		{Type: "Traditional Strength Training", Count: 3, AvgDuration: 3600, TotalCalories: 900, AvgHeartRate: hr(110)},
+		{Type: "Functional Strength Training", Count: 1, AvgDuration: 2400, TotalCalories: 200, AvgHeartRate: hr(130)},
+		{Type: "Strength", Count: 2, AvgDuration: 1800, TotalCalories: 300},
+		{Type: "Running", Count: 4, AvgDuration: 1800, TotalCalories: 1200},
+	}
+
+	got := groupWorkoutTypes(rows, nil)
+	if len(got) != 2 {
+		t.Fatalf("got %d groups, want 2: %+v", len(got), got)
+	}
+	st := got[0]
+	if st.Type != "Strength" || st.Count != 6 || st.TotalCalories != 1400 {
+		t.Errorf("strength = %+v", st)
+	}
+	// (3*3600 + 2400 + 2*1800) / 6
+	if st.AvgDuration != 2800 {
+		t.Errorf("avg duration = %v, want 2800", st.AvgDuration)
+	}
+	// HR only from the 4 workouts that recorded it: (3*110 + 130) / 4
+	if st.AvgHeartRate == nil || *st.AvgHeartRate != 115 {
+		t.Errorf("avg heart rate = %v, want 115", st.AvgHeartRate)
+	}
+	want := []string{"Functional Strength Training", "Strength", "Traditional Strength Training"}
+	if !reflect.DeepEqual(st.RawNames, want) {
+		t.Errorf("raw names = %v, want %v", st.RawNames, want)
+	}
+}
+
+// TestWorkoutTypeDistributionOverrides verifies configured mappings take
+// precedence over the defaults and shares are computed over all workouts.
+func TestWorkoutTypeDistributionOverrides(t *testing.T) {
+	names := []WorkoutTypeShare{
+		{Type: "Traditional Strength Training", Count: 2, DurationSec: 7200},
+		{Type: "Core Training", Count: 1, DurationSec: 900},
+		{Type: "Kickboxing", Count: 1, DurationSec: 3600},
+	}
+	got := workoutTypeDistribution(names, map[string]string{"Core Training": "Core", "Kickboxing": "HIIT"})
+	if len(got) != 3 {
+		t.Fatalf("got %d types, want 3: %+v", len(got), got)
+	}
+	if got[0].Type != "Strength" || got[0].Pct != 50 {
+		t.Errorf("first = %+v, want Strength at 50%%", got[0])
+	}
+	if got[1].Type != "Core" || got[2].Type != "HIIT" {
+		t.Errorf("order = %s, %s, want Core, HIIT", got[1].Type, got[2].Type)
+	}
+}
@@ -2,46 +2,152 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/claude/freereps/internal/ingest"
 	"github.com/claude/freereps/internal/models"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 // alphaWorkoutNamespace is the UUID namespace for deterministic synthetic Alpha workout IDs.
 var alphaWorkoutNamespace = uuid.MustParse("7ba7b810-9dad-11d1-80b4-00c04fd430c8")
 
-// InsertWorkout inserts a workout row. Returns true if inserted, false if duplicate.
-func (db *DB) InsertWorkout(ctx context.Context, row models.WorkoutRow) (bool, error) {
-	tag, err := db.Pool.Exec(ctx,
-		`INSERT INTO workouts (id, user_id, name, source, start_time, end_time, duration_sec, location, is_indoor,
+// insertWorkoutSQL inserts one workout row; callers append the conflict clause.
+const insertWorkoutSQL = `INSERT INTO workouts (id, user_id, name, source, start_time, end_time, duration_sec, location, is_indoor,
 		 active_energy_burned, active_energy_units, total_energy, total_energy_units,
 		 distance, distance_units, avg_heart_rate, max_heart_rate, min_heart_rate,
-		 elevation_up, elevation_down, raw_json)
-		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21)
-		 ON CONFLICT DO NOTHING`,
-		row.ID, row.UserID, row.Name, row.Source, row.StartTime, row.EndTime, row.DurationSec,
+		 elevation_up, elevation_down, temperature, temperature_units, humidity,
+		 pool_length, total_strokes, lap_count, raw_json)
+		 VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15,$16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26,$27)`
+
+// upsertMergedWorkoutSQL stores a merged workout over a previously synced
+// first segment of the same user, replacing its imported summary. Reports
+// whether the row was newly inserted.
+const upsertMergedWorkoutSQL = insertWorkoutSQL + `
+		 ON CONFLICT (id) DO UPDATE SET
+		     name = EXCLUDED.name, source = EXCLUDED.source,
+		     start_time = EXCLUDED.start_time, end_time = EXCLUDED.end_time, duration_sec = EXCLUDED.duration_sec,
+		     location = EXCLUDED.location, is_indoor = EXCLUDED.is_indoor,
+		     active_energy_burned = EXCLUDED.active_energy_burned, active_energy_units = EXCLUDED.active_energy_units,
+		     total_energy = EXCLUDED.total_energy, total_energy_units = EXCLUDED.total_energy_units,
+		     distance = EXCLUDED.distance, distance_units = EXCLUDED.distance_units,
+		     avg_heart_rate = EXCLUDED.avg_heart_rate, max_heart_rate = EXCLUDED.max_heart_rate,
+		     min_heart_rate = EXCLUDED.min_heart_rate,
+		     elevation_up = EXCLUDED.elevation_up, elevation_down = EXCLUDED.elevation_down,
+		     temperature = EXCLUDED.temperature, temperature_units = EXCLUDED.temperature_units,
+		     humidity = EXCLUDED.humidity,
+		     pool_length = EXCLUDED.pool_length, total_strokes = EXCLUDED.total_strokes,
+		     lap_count = EXCLUDED.lap_count, raw_json = EXCLUDED.raw_json
+		 WHERE workouts.user_id = EXCLUDED.user_id
+		 RETURNING (xmax = 0)`
+
+// workoutArgs returns row's values in insertWorkoutSQL parameter order.
+func workoutArgs(row models.WorkoutRow) []any {
+	return []any{row.ID, row.UserID, row.Name, row.Source, row.StartTime, row.EndTime, row.DurationSec,
 		row.Location, row.IsIndoor,
 		row.ActiveEnergyBurned, row.ActiveEnergyUnits, row.TotalEnergy, row.TotalEnergyUnits,
 		row.Distance, row.DistanceUnits, row.AvgHeartRate, row.MaxHeartRate, row.MinHeartRate,
-		row.ElevationUp, row.ElevationDown, row.RawJSON)
+		row.ElevationUp, row.ElevationDown, row.Temperature, row.TemperatureUnits, row.Humidity,
+		row.PoolLength, row.TotalStrokes, row.LapCount, row.RawJSON}
+}
+
+// InsertWorkout inserts a workout row. Returns true if inserted, false if duplicate.
+func (db *DB) InsertWorkout(ctx context.Context, row models.WorkoutRow) (bool, error) {
+	tag, err := db.Pool.Exec(ctx, insertWorkoutSQL+" ON CONFLICT DO NOTHING", workoutArgs(row)...)
 	if err != nil {
 		return false, fmt.Errorf("inserting workout: %w", err)
 	}
 	return tag.RowsAffected() > 0, nil
 }
 
+// UpsertMergedWorkout stores a workout merged from several segments under
+// row.ID, its first segment's ID. A first segment synced earlier on its own
+// is overwritten with the merged summary, and the absorbed segments' own
+// rows, if an earlier sync stored them, are deleted along with their samples
+// once their tags and set links have moved to row.ID. Returns true if row
+// was newly inserted.
+func (db *DB) UpsertMergedWorkout(ctx context.Context, row models.WorkoutRow, absorbed []uuid.UUID) (bool, error) {
+	tx, err := db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("beginning merged workout tx: %w", err)
+	}
+	defer tx.Rollback(ctx) //nolint:errcheck
+
+	var inserted bool
+	err = tx.QueryRow(ctx, upsertMergedWorkoutSQL, workoutArgs(row)...).Scan(&inserted)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The ID belongs to another user's workout; leave it alone.
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("upserting merged workout: %w", err)
+	}
+
+	if len(absorbed) > 0 {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO workout_tags (user_id, workout_id, tag)
+			 SELECT user_id, $1, tag FROM workout_tags WHERE user_id = $2 AND workout_id = ANY($3)
+			 ON CONFLICT DO NOTHING`,
+			row.ID, row.UserID, absorbed); err != nil {
+			return false, fmt.Errorf("moving absorbed segment tags: %w", err)
+		}
+		if _, err := tx.Exec(ctx,
+			`UPDATE workout_sets SET workout_id = $1 WHERE user_id = $2 AND workout_id = ANY($3)`,
+			row.ID, row.UserID, absorbed); err != nil {
+			return false, fmt.Errorf("moving absorbed segment sets: %w", err)
+		}
+		tag, err := tx.Exec(ctx,
+			`DELETE FROM workouts WHERE user_id = $1 AND id = ANY($2)`,
+			row.UserID, absorbed)
+		if err != nil {
+			return false, fmt.Errorf("deleting absorbed segments: %w", err)
+		}
+		if tag.RowsAffected() > 0 {
+			if err := markDataChanged(ctx, tx, row.UserID, DataTraining); err != nil {
+				return false, err
+			}
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("committing merged workout tx: %w", err)
+	}
+	return inserted, nil
+}
+
 // InsertWorkoutHeartRate batch-inserts workout HR data points. Returns count inserted.
 func (db *DB) InsertWorkoutHeartRate(ctx context.Context, rows []models.WorkoutHRRow) (int64, error) {
+	n, err := db.insertWorkoutHRRows(ctx, "workout_heart_rate", rows)
+	if err != nil {
+		return 0, fmt.Errorf("inserting workout heart rate: %w", err)
+	}
+	return n, nil
+}
+
+// InsertWorkoutHRRecovery batch-inserts post-workout HR recovery points.
+// Returns count inserted.
+func (db *DB) InsertWorkoutHRRecovery(ctx context.Context, rows []models.WorkoutHRRow) (int64, error) {
+	n, err := db.insertWorkoutHRRows(ctx, "workout_hr_recovery", rows)
+	if err != nil {
+		return 0, fmt.Errorf("inserting workout HR recovery: %w", err)
+	}
+	return n, nil
+}
+
+// insertWorkoutHRRows batch-inserts HR rows into table, which must have the
+// workout_heart_rate column layout.
+func (db *DB) insertWorkoutHRRows(ctx context.Context, table string, rows []models.WorkoutHRRow) (int64, error) {
 	if len(rows) == 0 {
 		return 0, nil
 	}
 
-	query := `INSERT INTO workout_heart_rate (time, workout_id, user_id, min_bpm, avg_bpm, max_bpm, source) VALUES `
+	query := `INSERT INTO ` + table + ` (time, workout_id, user_id, min_bpm, avg_bpm, max_bpm, source) VALUES `
 	args := make([]any, 0, len(rows)*7)
 	valueStrings := make([]string, 0, len(rows))
 
@@ -58,19 +164,41 @@ func (db *DB) InsertWorkoutHeartRate(ctx context.Context, rows []models.WorkoutH
 
 	tag, err := db.Pool.Exec(ctx, query, args...)
 	if err != nil {
-		return 0, fmt.Errorf("inserting workout heart rate: %w", err)
+		return 0, err
 	}
 	return tag.RowsAffected(), nil
 }
 
+// workoutRouteColumns lists workout_routes columns in the order used by
+// routeRowArgs and routeRowDest.
+const workoutRouteColumns = `time, workout_id, user_id, latitude, longitude, altitude, speed, course,
+	horizontal_accuracy, vertical_accuracy, course_accuracy, speed_accuracy`
+
+// routeColumnCount is the number of columns in workoutRouteColumns.
+const routeColumnCount = 12
+
+// routeRowArgs returns r's values in workoutRouteColumns order.
+func routeRowArgs(r models.WorkoutRouteRow) []any {
+	return []any{r.Time, r.WorkoutID, r.UserID, r.Latitude, r.Longitude,
+		r.Altitude, r.Speed, r.Course, r.HorizontalAccuracy, r.VerticalAccuracy,
+		r.CourseAccuracy, r.SpeedAccuracy}
+}
+
+// routeRowDest returns scan targets into r in workoutRouteColumns order.
+func routeRowDest(r *models.WorkoutRouteRow) []any {
+	return []any{&r.Time, &r.WorkoutID, &r.UserID, &r.Latitude, &r.Longitude,
+		&r.Altitude, &r.Speed, &r.Course, &r.HorizontalAccuracy, &r.VerticalAccuracy,
+		&r.CourseAccuracy, &r.SpeedAccuracy}
+}
+
 // InsertWorkoutRoutes batch-inserts workout route points. Returns count inserted.
 func (db *DB) InsertWorkoutRoutes(ctx context.Context, rows []models.WorkoutRouteRow) (int64, error) {
 	if len(rows) == 0 {
 		return 0, nil
 	}
 
-	// 10 params per row; PostgreSQL extended protocol limited to 65535 params.
-	const batchSize = 6000
+	// 12 params per row; PostgreSQL extended protocol limited to 65535 params.
+	const batchSize = 5000
 	var total int64
 
 	for start := 0; start < len(rows); start += batchSize {
@@ -80,18 +208,18 @@ func (db *DB) InsertWorkoutRoutes(ctx context.Context, rows []models.WorkoutRout
 		}
 		batch := rows[start:end]
 
-		query := `INSERT INTO workout_routes (time, workout_id, user_id, latitude, longitude, altitude, speed, course, horizontal_accuracy, vertical_accuracy) VALUES `
-		args := make([]any, 0, len(batch)*10)
+		query := `INSERT INTO workout_routes (` + workoutRouteColumns + `) VALUES `
+		args := make([]any, 0, len(batch)*routeColumnCount)
 		valueStrings := make([]string, 0, len(batch))
 
 		for i, r := range batch {
-			base := i * 10
-			valueStrings = append(valueStrings, fmt.Sprintf(
-				"($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d)",
-				base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8, base+9, base+10,
-			))
-			args = append(args, r.Time, r.WorkoutID, r.UserID, r.Latitude, r.Longitude,
-				r.Altitude, r.Speed, r.Course, r.HorizontalAccuracy, r.VerticalAccuracy)
+			base := i * routeColumnCount
+			placeholders := make([]string, routeColumnCount)
+			for j := range placeholders {
+				placeholders[j] = fmt.Sprintf("$%d", base+j+1)
+			}
+			valueStrings = append(valueStrings, "("+strings.Join(placeholders, ",")+")")
+			args = append(args, routeRowArgs(r)...)
 		}
 
 		query += strings.Join(valueStrings, ",") + " ON CONFLICT DO NOTHING"
@@ -110,21 +238,186 @@ type WorkoutDetail struct {
 	models.WorkoutRow
 	HeartRateData []models.WorkoutHRRow
 	RouteData     []models.WorkoutRouteRow
+
+	// HeartRateRecovery holds the samples recorded after the workout ended;
+	// HRRecovery summarizes them. HRRecovery is nil without recovery data.
+	HeartRateRecovery []models.WorkoutHRRow
+	HRRecovery        *HRRecovery `json:"hr_recovery,omitempty"`
+
+	// PowerData holds power meter samples; Power summarizes them and is nil
+	// for workouts recorded without a power meter.
+	PowerData []models.WorkoutPowerRow
+	Power     *WorkoutPowerStats `json:"power,omitempty"`
+
+	// CadenceData holds running cadence samples; Cadence summarizes them and
+	// is nil for workouts without in-workout step data.
+	CadenceData []models.WorkoutCadenceRow
+	Cadence     *WorkoutCadenceStats `json:"cadence,omitempty"`
+
+	// Raw holds raw_json fields not modeled as columns. Only populated on
+	// request (see WorkoutRawExtras).
+	Raw map[string]json.RawMessage `json:"raw,omitempty"`
+}
+
+// workoutModeledKeys are raw_json keys already exposed through columns or
+// child tables; WorkoutRawExtras omits them.
+var workoutModeledKeys = map[string]bool{
+	"id": true, "name": true, "start": true, "end": true, "duration": true,
+	"location": true, "isIndoor": true,
+	"activeEnergyBurned": true, "totalEnergy": true, "distance": true,
+	"elevationUp": true, "elevationDown": true,
+	"temperature": true, "humidity": true,
+	"lapLength": true, "totalSwimmingStrokeCount": true,
+	"heartRate": true, "avgHeartRate": true, "maxHeartRate": true,
+	"heartRateData": true, "heartRateRecovery": true, "route": true,
+	"stepCount": true,
 }
 
-// QueryWorkouts retrieves workouts in a time range, optionally filtered by type name.
+// WorkoutRawExtras returns the raw_json fields not otherwise modeled, optionally
+// restricted to the given keys. Returns nil for empty or non-object raw JSON.
+func WorkoutRawExtras(raw []byte, only []string) map[string]json.RawMessage {
+	if len(raw) == 0 {
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+	want := make(map[string]bool, len(only))
+	for _, k := range only {
+		want[k] = true
+	}
+	extras := make(map[string]json.RawMessage)
+	for k, v := range fields {
+		if len(want) > 0 && !want[k] {
+			continue
+		}
+		if len(want) == 0 && workoutModeledKeys[k] {
+			continue
+		}
+		extras[k] = v
+	}
+	if len(extras) == 0 {
+		return nil
+	}
+	return extras
+}
+
+// WorkoutFilter narrows QueryWorkouts beyond the time range. Zero-value
+// fields are ignored. Distance and energy bounds are compared after
+// converting the stored value to km / kcal, so mixed-unit sources filter
+// consistently.
+type WorkoutFilter struct {
+	Name           string
+	Tag            string
+	MinDurationSec *float64
+	MaxDurationSec *float64
+	MinDistanceKm  *float64
+	MaxDistanceKm  *float64
+	MinEnergyKcal  *float64
+	MaxEnergyKcal  *float64
+}
+
+// matchesAlphaSession applies the filter to a synthetic Alpha workout, which
+// is always "Traditional Strength Training" and has no distance or energy —
+// so, as with NULL columns in SQL, any distance/energy bound excludes it.
+func (f WorkoutFilter) matchesAlphaSession(durationSec float64) bool {
+	if f.Name != "" && f.Name != "Traditional Strength Training" {
+		return false
+	}
+	// Synthetic workouts have no row to attach tags to.
+	if f.Tag != "" {
+		return false
+	}
+	if f.MinDistanceKm != nil || f.MaxDistanceKm != nil || f.MinEnergyKcal != nil || f.MaxEnergyKcal != nil {
+		return false
+	}
+	if f.MinDurationSec != nil && durationSec < *f.MinDurationSec {
+		return false
+	}
+	if f.MaxDurationSec != nil && durationSec > *f.MaxDurationSec {
+		return false
+	}
+	return true
+}
+
+// workoutDistanceKmSQL and workoutEnergyKcalSQL normalize the per-row units
+// so range filters don't have to care which source wrote the workout.
+var (
+	workoutDistanceKmSQL = unitCaseSQL("distance", "distance_units", "km", "mi", "m", "yd")
+	workoutEnergyKcalSQL = unitCaseSQL("active_energy_burned", "active_energy_units", "kcal", "kJ")
+)
+
+// unitCaseSQL returns a CASE expression converting valueCol from each of the
+// from units to unit to, using the same factors as ingest.ConvertUnit. Rows
+// in any other unit are taken to already be in to. Only linear conversions
+// are supported; it panics on a pair ConvertUnit doesn't know.
+func unitCaseSQL(valueCol, unitCol, to string, from ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "(CASE %s", unitCol)
+	for _, u := range from {
+		f, ok := ingest.ConvertUnit(1, u, to)
+		if !ok {
+			panic(fmt.Sprintf("no conversion from %q to %q", u, to))
+		}
+		fmt.Fprintf(&b, " WHEN '%s' THEN %s * %s", u, valueCol, strconv.FormatFloat(f, 'g', -1, 64))
+	}
+	fmt.Fprintf(&b, " ELSE %s END)", valueCol)
+	return b.String()
+}
+
+// workoutTagsSQL selects the tags of the workout row named rel (a table or CTE
+// exposing id and user_id) as a sorted array. Both columns are qualified so a
+// tag row can never match another user's workout or an outer column by accident.
+func workoutTagsSQL(rel string) string {
+	return fmt.Sprintf(`ARRAY(SELECT t.tag FROM workout_tags t WHERE t.workout_id = %[1]s.id AND t.user_id = %[1]s.user_id ORDER BY t.tag)`, rel)
+}
+
+// workoutFilterSQL appends the filter's conditions to where. All values are
+// bound as positional parameters following the existing args; only fixed
+// column expressions are ever interpolated into the SQL text.
+func workoutFilterSQL(f WorkoutFilter, where string, args []any) (string, []any) {
+	add := func(cond string, v any) {
+		args = append(args, v)
+		where += fmt.Sprintf(" AND %s $%d", cond, len(args))
+	}
+	if f.Name != "" {
+		add("name =", f.Name)
+	}
+	if f.Tag != "" {
+		args = append(args, f.Tag)
+		where += fmt.Sprintf(" AND EXISTS (SELECT 1 FROM workout_tags t WHERE t.workout_id = workouts.id AND t.tag = $%d)", len(args))
+	}
+	bounds := []struct {
+		expr string
+		min  *float64
+		max  *float64
+	}{
+		{"duration_sec", f.MinDurationSec, f.MaxDurationSec},
+		{workoutDistanceKmSQL, f.MinDistanceKm, f.MaxDistanceKm},
+		{workoutEnergyKcalSQL, f.MinEnergyKcal, f.MaxEnergyKcal},
+	}
+	for _, b := range bounds {
+		if b.min != nil {
+			add(b.expr+" >=", *b.min)
+		}
+		if b.max != nil {
+			add(b.expr+" <=", *b.max)
+		}
+	}
+	return where, args
+}
+
+// QueryWorkouts retrieves workouts in a time range, optionally narrowed by filter.
 // Deduplicates overlapping workouts from different sources using source priority:
 // when two workouts start within the same 5-minute window, only the highest-priority
 // source's workout is returned. Excludes raw_json to keep the list payload small.
-func (db *DB) QueryWorkouts(ctx context.Context, start, end time.Time, userID int, nameFilter string) ([]models.WorkoutRow, error) {
+func (db *DB) QueryWorkouts(ctx context.Context, start, end time.Time, userID int, filter WorkoutFilter) ([]models.WorkoutRow, error) {
 	priorities := db.ResolveSourcePriority(ctx, userID, "activity")
 	priorityExpr := sourcePriorityCaseSQL(priorities)
-	where := `start_time >= $1 AND start_time < $2 AND user_id = $3`
-	args := []any{start, end, userID}
-	if nameFilter != "" {
-		where += ` AND name = $4`
-		args = append(args, nameFilter)
-	}
+	where, args := workoutFilterSQL(filter,
+		`start_time >= $1 AND start_time < $2 AND user_id = $3`,
+		[]any{start, end, userID})
 	query := fmt.Sprintf(
 		`WITH ranked AS (
 			SELECT *, ROW_NUMBER() OVER (
@@ -137,7 +430,8 @@ func (db *DB) QueryWorkouts(ctx context.Context, start, end time.Time, userID in
 		SELECT id, user_id, name, source, start_time, end_time, duration_sec, location, is_indoor,
 			active_energy_burned, active_energy_units, total_energy, total_energy_units,
 			distance, distance_units, avg_heart_rate, max_heart_rate, min_heart_rate,
-			elevation_up, elevation_down
+			elevation_up, elevation_down, temperature, COALESCE(temperature_units, ''), humidity,
+			pool_length, total_strokes, lap_count, moving_time_sec, notes, `+workoutTagsSQL("ranked")+`
 		FROM ranked WHERE rn = 1
 		ORDER BY start_time DESC`, priorityExpr, where)
 	rows, err := db.Pool.Query(ctx, query, args...)
@@ -149,13 +443,93 @@ func (db *DB) QueryWorkouts(ctx context.Context, start, end time.Time, userID in
 	return scanWorkoutListRows(rows)
 }
 
+// WorkoutUpdate holds the user-editable workout fields; nil fields are left
+// unchanged.
+type WorkoutUpdate struct {
+	Name     *string `json:"name"`
+	Location *string `json:"location"`
+	IsIndoor *bool   `json:"is_indoor"`
+	Notes    *string `json:"notes"`
+}
+
+// Empty reports whether the update changes nothing.
+func (u WorkoutUpdate) Empty() bool {
+	return u.Name == nil && u.Location == nil && u.IsIndoor == nil && u.Notes == nil
+}
+
+// workoutUpdateSQL builds the SET clause for u, numbering placeholders after
+// the id and user_id arguments.
+func workoutUpdateSQL(u WorkoutUpdate) (string, []any) {
+	var sets []string
+	var args []any
+	add := func(col string, v any) {
+		args = append(args, v)
+		sets = append(sets, fmt.Sprintf("%s = $%d", col, len(args)+2))
+	}
+	if u.Name != nil {
+		add("name", *u.Name)
+	}
+	if u.Location != nil {
+		add("location", *u.Location)
+	}
+	if u.IsIndoor != nil {
+		add("is_indoor", *u.IsIndoor)
+	}
+	if u.Notes != nil {
+		add("notes", *u.Notes)
+	}
+	return strings.Join(sets, ", "), args
+}
+
+// UpdateWorkoutFields applies u to one of the user's workouts, touching only
+// the provided columns. It returns false when no such workout exists.
+func (db *DB) UpdateWorkoutFields(ctx context.Context, workoutID uuid.UUID, userID int, u WorkoutUpdate) (bool, error) {
+	set, args := workoutUpdateSQL(u)
+	if set == "" {
+		return false, nil
+	}
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE workouts SET `+set+` WHERE id = $1 AND user_id = $2`,
+		append([]any{workoutID, userID}, args...)...)
+	if err != nil {
+		return false, fmt.Errorf("updating workout: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// queryWorkoutHRRows returns a workout's rows from an HR table with the
+// workout_heart_rate layout, in time order.
+func (db *DB) queryWorkoutHRRows(ctx context.Context, table string, workoutID uuid.UUID, userID int) ([]models.WorkoutHRRow, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT time, workout_id, user_id, min_bpm, avg_bpm, max_bpm, source
+		 FROM `+table+`
+		 WHERE workout_id = $1 AND user_id = $2
+		 ORDER BY time ASC`,
+		workoutID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.WorkoutHRRow
+	for rows.Next() {
+		var hr models.WorkoutHRRow
+		if err := rows.Scan(&hr.Time, &hr.WorkoutID, &hr.UserID, &hr.MinBPM, &hr.AvgBPM, &hr.MaxBPM, &hr.Source); err != nil {
+			return nil, fmt.Errorf("scanning %s: %w", table, err)
+		}
+		out = append(out, hr)
+	}
+	return out, rows.Err()
+}
+
 // GetWorkout retrieves a single workout by ID with all associated data.
 func (db *DB) GetWorkout(ctx context.Context, workoutID uuid.UUID, userID int) (*WorkoutDetail, error) {
 	row := db.Pool.QueryRow(ctx,
 		`SELECT id, user_id, name, start_time, end_time, duration_sec, location, is_indoor,
 		 active_energy_burned, active_energy_units, total_energy, total_energy_units,
 		 distance, distance_units, avg_heart_rate, max_heart_rate, min_heart_rate,
-		 elevation_up, elevation_down, raw_json
+		 elevation_up, elevation_down, temperature, COALESCE(temperature_units, ''), humidity,
+		 pool_length, total_strokes, lap_count, moving_time_sec, notes, `+workoutTagsSQL("workouts")+`, raw_json
 		 FROM workouts
 		 WHERE id = $1 AND user_id = $2`,
 		workoutID, userID)
@@ -165,7 +539,8 @@ func (db *DB) GetWorkout(ctx context.Context, workoutID uuid.UUID, userID int) (
 		&w.Location, &w.IsIndoor,
 		&w.ActiveEnergyBurned, &w.ActiveEnergyUnits, &w.TotalEnergy, &w.TotalEnergyUnits,
 		&w.Distance, &w.DistanceUnits, &w.AvgHeartRate, &w.MaxHeartRate, &w.MinHeartRate,
-		&w.ElevationUp, &w.ElevationDown, &w.RawJSON)
+		&w.ElevationUp, &w.ElevationDown, &w.Temperature, &w.TemperatureUnits, &w.Humidity,
+		&w.PoolLength, &w.TotalStrokes, &w.LapCount, &w.MovingTimeSec, &w.Notes, &w.Tags, &w.RawJSON)
 	if err != nil {
 		return nil, fmt.Errorf("querying workout: %w", err)
 	}
@@ -173,31 +548,41 @@ func (db *DB) GetWorkout(ctx context.Context, workoutID uuid.UUID, userID int) (
 	detail := &WorkoutDetail{WorkoutRow: w}
 
 	// Get HR data
-	hrRows, err := db.Pool.Query(ctx,
-		`SELECT time, workout_id, user_id, min_bpm, avg_bpm, max_bpm, source
-		 FROM workout_heart_rate
-		 WHERE workout_id = $1 AND user_id = $2
-		 ORDER BY time ASC`,
-		workoutID, userID)
+	detail.HeartRateData, err = db.queryWorkoutHRRows(ctx, "workout_heart_rate", workoutID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("querying workout HR: %w", err)
 	}
-	defer hrRows.Close()
+	detail.HeartRateRecovery, err = db.queryWorkoutHRRows(ctx, "workout_hr_recovery", workoutID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying workout HR recovery: %w", err)
+	}
+	detail.HRRecovery = ComputeHRRecovery(w.EndTime, detail.HeartRateData, detail.HeartRateRecovery)
 
-	for hrRows.Next() {
-		var hr models.WorkoutHRRow
-		if err := hrRows.Scan(&hr.Time, &hr.WorkoutID, &hr.UserID, &hr.MinBPM, &hr.AvgBPM, &hr.MaxBPM, &hr.Source); err != nil {
-			return nil, fmt.Errorf("scanning workout HR: %w", err)
+	detail.PowerData, err = db.queryWorkoutPower(ctx, workoutID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(detail.PowerData) > 0 {
+		profile, err := db.GetUserProfile(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		var ftp *int
+		if profile != nil {
+			ftp = profile.FTPWatts
 		}
-		detail.HeartRateData = append(detail.HeartRateData, hr)
+		detail.Power = ComputeWorkoutPowerStats(detail.PowerData, ftp)
 	}
-	if err := hrRows.Err(); err != nil {
+
+	detail.CadenceData, err = db.queryWorkoutCadence(ctx, workoutID, userID)
+	if err != nil {
 		return nil, err
 	}
+	detail.Cadence = ComputeWorkoutCadenceStats(detail.CadenceData)
 
 	// Get route data
 	routeRows, err := db.Pool.Query(ctx,
-		`SELECT time, workout_id, user_id, latitude, longitude, altitude, speed, course, horizontal_accuracy, vertical_accuracy
+		`SELECT `+workoutRouteColumns+`
 		 FROM workout_routes
 		 WHERE workout_id = $1 AND user_id = $2
 		 ORDER BY time ASC`,
@@ -209,8 +594,7 @@ func (db *DB) GetWorkout(ctx context.Context, workoutID uuid.UUID, userID int) (
 
 	for routeRows.Next() {
 		var r models.WorkoutRouteRow
-		if err := routeRows.Scan(&r.Time, &r.WorkoutID, &r.UserID, &r.Latitude, &r.Longitude,
-			&r.Altitude, &r.Speed, &r.Course, &r.HorizontalAccuracy, &r.VerticalAccuracy); err != nil {
+		if err := routeRows.Scan(routeRowDest(&r)...); err != nil {
 			return nil, fmt.Errorf("scanning workout route: %w", err)
 		}
 		detail.RouteData = append(detail.RouteData, r)
@@ -232,7 +616,8 @@ func scanWorkoutListRows(rows interface {
 			&w.Location, &w.IsIndoor,
 			&w.ActiveEnergyBurned, &w.ActiveEnergyUnits, &w.TotalEnergy, &w.TotalEnergyUnits,
 			&w.Distance, &w.DistanceUnits, &w.AvgHeartRate, &w.MaxHeartRate, &w.MinHeartRate,
-			&w.ElevationUp, &w.ElevationDown); err != nil {
+			&w.ElevationUp, &w.ElevationDown, &w.Temperature, &w.TemperatureUnits, &w.Humidity,
+			&w.PoolLength, &w.TotalStrokes, &w.LapCount, &w.MovingTimeSec, &w.Notes, &w.Tags); err != nil {
 			return nil, fmt.Errorf("scanning workout: %w", err)
 		}
 		result = append(result, w)
@@ -241,16 +626,19 @@ func scanWorkoutListRows(rows interface {
 }
 
 // QueryWorkoutsMerged returns workouts enriched with Alpha Progression session names.
-// Apple/Oura workouts near an Alpha session get the session name for display.
-// Alpha sessions with no nearby workout get a synthetic workout entry.
-func (db *DB) QueryWorkoutsMerged(ctx context.Context, start, end time.Time, userID int, nameFilter string) ([]models.WorkoutRow, error) {
-	workouts, err := db.QueryWorkouts(ctx, start, end, userID, nameFilter)
+// A session's stored link (see LinkWorkoutSets) decides its workout; unlinked
+// sessions fall back to the nearest workout within the Alpha link window
+// (see SetAlphaLinkWindow). Alpha sessions with neither get a synthetic
+// workout entry.
+func (db *DB) QueryWorkoutsMerged(ctx context.Context, start, end time.Time, userID int, filter WorkoutFilter) ([]models.WorkoutRow, error) {
+	workouts, err := db.QueryWorkouts(ctx, start, end, userID, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	// Fetch Alpha sessions with 2h padding to catch sessions just outside the range.
-	alphaSessions, err := db.QueryAlphaSessions(ctx, start.Add(-2*time.Hour), end.Add(2*time.Hour), userID)
+	// Pad by the link window to catch sessions just outside the range.
+	window := db.alphaLinkWindow
+	alphaSessions, err := db.QueryAlphaSessions(ctx, start.Add(-window), end.Add(window), userID)
 	if err != nil {
 		return nil, err
 	}
@@ -258,36 +646,7 @@ func (db *DB) QueryWorkoutsMerged(ctx context.Context, start, end time.Time, use
 		return workouts, nil
 	}
 
-	// Match Alpha sessions to workouts by nearest time within ±2h.
-	matched := make(map[int]bool)    // index into workouts
-	alphaUsed := make(map[int]bool)  // index into alphaSessions
-
-	type pair struct {
-		wi, ai int
-		dist   time.Duration
-	}
-	var pairs []pair
-	for wi, w := range workouts {
-		for ai, a := range alphaSessions {
-			dist := w.StartTime.Sub(a.SessionDate)
-			if dist < 0 {
-				dist = -dist
-			}
-			if dist <= 2*time.Hour {
-				pairs = append(pairs, pair{wi, ai, dist})
-			}
-		}
-	}
-	sort.Slice(pairs, func(i, j int) bool { return pairs[i].dist < pairs[j].dist })
-
-	for _, p := range pairs {
-		if matched[p.wi] || alphaUsed[p.ai] {
-			continue
-		}
-		workouts[p.wi].AlphaSessionName = alphaSessions[p.ai].SessionName
-		matched[p.wi] = true
-		alphaUsed[p.ai] = true
-	}
+	alphaUsed := pairAlphaSessions(workouts, alphaSessions, window)
 
 	// Create synthetic workouts for unmatched Alpha sessions.
 	for ai, a := range alphaSessions {
@@ -298,11 +657,10 @@ func (db *DB) QueryWorkoutsMerged(ctx context.Context, start, end time.Time, use
 		if a.SessionDate.Before(start) || !a.SessionDate.Before(end) {
 			continue
 		}
-		// Skip if name filter is set and doesn't match the synthetic base name.
-		if nameFilter != "" && nameFilter != "Traditional Strength Training" {
+		dur := parseAlphaDuration(a.SessionDuration)
+		if !filter.matchesAlphaSession(dur.Seconds()) {
 			continue
 		}
-		dur := parseAlphaDuration(a.SessionDuration)
 		workouts = append(workouts, models.WorkoutRow{
 			ID:               uuid.NewSHA1(alphaWorkoutNamespace, []byte("alpha:"+a.SessionDate.Format(time.RFC3339)+":"+a.SessionName)),
 			UserID:           userID,
@@ -321,6 +679,65 @@ func (db *DB) QueryWorkoutsMerged(ctx context.Context, start, end time.Time, use
 	return workouts, nil
 }
 
+// pairAlphaSessions sets AlphaSessionName on the workouts the sessions belong
+// to and reports which sessions were accounted for. A linked session pairs
+// with its stored workout, and counts as accounted for even when that workout
+// is outside the list, so it never becomes a synthetic duplicate. Unlinked
+// sessions then take the nearest remaining workout starting within window.
+func pairAlphaSessions(workouts []models.WorkoutRow, sessions []AlphaSessionInfo, window time.Duration) map[int]bool {
+	matched := make(map[int]bool)   // index into workouts
+	alphaUsed := make(map[int]bool) // index into sessions
+
+	byID := make(map[uuid.UUID]int, len(workouts))
+	for wi, w := range workouts {
+		byID[w.ID] = wi
+	}
+	for ai, a := range sessions {
+		if a.WorkoutID == nil {
+			continue
+		}
+		alphaUsed[ai] = true
+		if wi, ok := byID[*a.WorkoutID]; ok && !matched[wi] {
+			workouts[wi].AlphaSessionName = a.SessionName
+			matched[wi] = true
+		}
+	}
+
+	type pair struct {
+		wi, ai int
+		dist   time.Duration
+	}
+	var pairs []pair
+	for wi, w := range workouts {
+		if matched[wi] {
+			continue
+		}
+		for ai, a := range sessions {
+			if alphaUsed[ai] {
+				continue
+			}
+			dist := w.StartTime.Sub(a.SessionDate)
+			if dist < 0 {
+				dist = -dist
+			}
+			if window > 0 && dist <= window {
+				pairs = append(pairs, pair{wi, ai, dist})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].dist < pairs[j].dist })
+
+	for _, p := range pairs {
+		if matched[p.wi] || alphaUsed[p.ai] {
+			continue
+		}
+		workouts[p.wi].AlphaSessionName = sessions[p.ai].SessionName
+		matched[p.wi] = true
+		alphaUsed[p.ai] = true
+	}
+	return alphaUsed
+}
+
 // parseAlphaDuration parses Alpha Progression duration strings like "1:02 hr".
 func parseAlphaDuration(s string) time.Duration {
 	s = strings.TrimSpace(strings.TrimSuffix(s, "hr"))
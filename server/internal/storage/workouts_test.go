@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+	"github.com/google/uuid"
+)
+
+// TestWorkoutRawExtras verifies that include=raw returns only fields without
+// a dedicated column, and that an explicit key list overrides that filter.
+func TestWorkoutRawExtras(t *testing.T) {
+	raw := []byte(`{"name":"Outdoor Run","distance":{"qty":5,"units":"km"},"weather":"Rain","intensity":{"qty":9}}`)
+
+	extras := WorkoutRawExtras(raw, nil)
+	if len(extras) != 2 || extras["weather"] == nil || extras["intensity"] == nil {
+		t.Errorf("extras = %v, want weather+intensity", extras)
+	}
+
+	only := WorkoutRawExtras(raw, []string{"distance"})
+	if len(only) != 1 || only["distance"] == nil {
+		t.Errorf("filtered extras = %v, want distance only", only)
+	}
+
+	if got := WorkoutRawExtras(nil, nil); got != nil {
+		t.Errorf("empty raw = %v, want nil", got)
+	}
+	if got := WorkoutRawExtras([]byte(`[1,2]`), nil); got != nil {
+		t.Errorf("non-object raw = %v, want nil", got)
+	}
+}
+
+// TestWorkoutFilterSQLMultiple verifies several range filters combine into one
+// WHERE clause with placeholders numbered after the base args, so "runs over
+// 10km and 30min" hits the right columns with the right values.
+func TestWorkoutFilterSQLMultiple(t *testing.T) {
+	minDur, minDist, maxEnergy := 1800.0, 10.0, 900.0
+	where, args := workoutFilterSQL(WorkoutFilter{
+		Name:           "Running",
+		MinDurationSec: &minDur,
+		MinDistanceKm:  &minDist,
+		MaxEnergyKcal:  &maxEnergy,
+	}, "user_id = $1", []any{7})
+
+	for _, want := range []string{
+		"name = $2",
+		"duration_sec >= $3",
+		workoutDistanceKmSQL + " >= $4",
+		workoutEnergyKcalSQL + " <= $5",
+	} {
+		if !strings.Contains(where, want) {
+			t.Errorf("where missing %q:\n%s", want, where)
+		}
+	}
+	if len(args) != 5 || args[1] != "Running" || args[2] != 1800.0 || args[3] != 10.0 || args[4] != 900.0 {
+		t.Errorf("args = %v", args)
+	}
+}
+
+// TestWorkoutUnitSQL verifies the unit CASE expressions use the ingest
+// conversion factors, so SQL filters and Go-side conversions agree.
+func TestWorkoutUnitSQL(t *testing.T) {
+	want := `(CASE distance_units WHEN 'mi' THEN distance * 1.609344 WHEN 'm' THEN distance * 0.001 WHEN 'yd' THEN distance * 0.0009144 ELSE distance END)`
+	if workoutDistanceKmSQL != want {
+		t.Errorf("workoutDistanceKmSQL = %s, want %s", workoutDistanceKmSQL, want)
+	}
+	if !strings.Contains(workoutEnergyKcalSQL, "WHEN 'kJ' THEN active_energy_burned * 0.2390") {
+		t.Errorf("workoutEnergyKcalSQL = %s", workoutEnergyKcalSQL)
+	}
+}
+
+// TestWorkoutFilterSQLInjection verifies user-supplied values only ever
+// reach the query as bound args, never as SQL text.
+func TestWorkoutFilterSQLInjection(t *testing.T) {
+	evil := "Running'; DROP TABLE workouts; --"
+	where, args := workoutFilterSQL(WorkoutFilter{Name: evil}, "user_id = $1", []any{1})
+	if strings.Contains(where, "DROP") || strings.Contains(where, "'") {
+		t.Errorf("user input leaked into SQL: %s", where)
+	}
+	if args[len(args)-1] != evil {
+		t.Errorf("name not bound as arg: %v", args)
+	}
+
+	if where, args := workoutFilterSQL(WorkoutFilter{}, "user_id = $1", []any{1}); where != "user_id = $1" || len(args) != 1 {
+		t.Errorf("empty filter changed query: %q %v", where, args)
+	}
+}
+
+// TestWorkoutUpdateSQLNameOnly verifies a name-only update sets just the
+// name column, leaving location, is_indoor, and notes untouched.
+func TestWorkoutUpdateSQLNameOnly(t *testing.T) {
+	name := "Trail Run"
+	set, args := workoutUpdateSQL(WorkoutUpdate{Name: &name})
+	if set != "name = $3" {
+		t.Errorf("set = %q, want only name", set)
+	}
+	if len(args) != 1 || args[0] != name {
+		t.Errorf("args = %v", args)
+	}
+
+	indoor, notes := true, "treadmill, HR strap"
+	set, args = workoutUpdateSQL(WorkoutUpdate{IsIndoor: &indoor, Notes: &notes})
+	if set != "is_indoor = $3, notes = $4" || len(args) != 2 {
+		t.Errorf("set = %q args = %v", set, args)
+	}
+
+	if (WorkoutUpdate{}).Empty() != true || (WorkoutUpdate{Name: &name}).Empty() {
+		t.Error("Empty() wrong")
+	}
+}
+
+// TestWorkoutFilterAlphaSession verifies synthetic Alpha workouts honor the
+// duration bounds and are dropped by distance/energy bounds they can't meet.
+func TestWorkoutFilterAlphaSession(t *testing.T) {
+	minDur, minDist := 3600.0, 5.0
+	if !(WorkoutFilter{}).matchesAlphaSession(1800) {
+		t.Error("empty filter should match")
+	}
+	if (WorkoutFilter{MinDurationSec: &minDur}).matchesAlphaSession(1800) {
+		t.Error("30min session should fail min_duration 1h")
+	}
+	if (WorkoutFilter{MinDistanceKm: &minDist}).matchesAlphaSession(7200) {
+		t.Error("distance bound should exclude session without distance")
+	}
+	if (WorkoutFilter{Name: "Running"}).matchesAlphaSession(1800) {
+		t.Error("name filter should exclude strength session")
+	}
+	if (WorkoutFilter{Tag: "race"}).matchesAlphaSession(1800) {
+		t.Error("tag filter should exclude untaggable synthetic session")
+	}
+}
+
+// TestWorkoutFilterSQLTag verifies a tag filter becomes an EXISTS check on
+// workout_tags with the tag bound as the next placeholder.
+func TestWorkoutFilterSQLTag(t *testing.T) {
+	where, args := workoutFilterSQL(WorkoutFilter{Name: "Running", Tag: "race"}, "user_id = $1", []any{7})
+	want := "EXISTS (SELECT 1 FROM workout_tags t WHERE t.workout_id = workouts.id AND t.tag = $3)"
+	if !strings.Contains(where, want) {
+		t.Errorf("where missing %q:\n%s", want, where)
+	}
+	if len(args) != 3 || args[2] != "race" {
+		t.Errorf("args = %v", args)
+	}
+}
+
+// TestRouteRowRoundTrip verifies a route row's values, including course and
+// speed accuracy, land back in the same fields when scanned in column order,
+// and that the column list matches the per-row argument count.
+func TestWorkoutTagsSQLQualified(t *testing.T) {
+	sql := workoutTagsSQL("ranked")
+	for _, want := range []string{"t.workout_id = ranked.id", "t.user_id = ranked.user_id"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("tags SQL missing %q: %s", want, sql)
+		}
+	}
+}
+
+func TestRouteRowRoundTrip(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	in := models.WorkoutRouteRow{
+		Time: time.Date(2025, 4, 9, 7, 0, 0, 0, time.UTC), WorkoutID: uuid.New(), UserID: 3,
+		Latitude: 48.1, Longitude: 11.5, Altitude: f(500), Speed: f(3.5), Course: f(90),
+		HorizontalAccuracy: f(5), VerticalAccuracy: f(3), CourseAccuracy: f(12.5), SpeedAccuracy: f(0.4),
+	}
+
+	args := routeRowArgs(in)
+	if n := len(strings.Split(workoutRouteColumns, ",")); n != routeColumnCount || len(args) != n {
+		t.Fatalf("columns = %d, args = %d, want %d", n, len(args), routeColumnCount)
+	}
+
+	// Simulate the driver scanning each column into its destination.
+	var out models.WorkoutRouteRow
+	for i, dest := range routeRowDest(&out) {
+		reflect.ValueOf(dest).Elem().Set(reflect.ValueOf(args[i]))
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+	if *out.CourseAccuracy != 12.5 || *out.SpeedAccuracy != 0.4 {
+		t.Errorf("accuracies = %v/%v, want 12.5/0.4", *out.CourseAccuracy, *out.SpeedAccuracy)
+	}
+}
+
+// TestUpsertMergedWorkoutSQL verifies a merged workout overwrites a first
+// segment synced earlier instead of being skipped as a duplicate, only for
+// the same user, and reports whether it was new.
+func TestUpsertMergedWorkoutSQL(t *testing.T) {
+	for _, want := range []string{
+		"ON CONFLICT (id) DO UPDATE SET",
+		"end_time = EXCLUDED.end_time",
+		"duration_sec = EXCLUDED.duration_sec",
+		"distance = EXCLUDED.distance",
+		"raw_json = EXCLUDED.raw_json",
+		"WHERE workouts.user_id = EXCLUDED.user_id",
+		"RETURNING (xmax = 0)",
+	} {
+		if !strings.Contains(upsertMergedWorkoutSQL, want) {
+			t.Errorf("merged workout upsert missing %q:\n%s", want, upsertMergedWorkoutSQL)
+		}
+	}
+	if n := strings.Count(insertWorkoutSQL, "$"); n != len(workoutArgs(models.WorkoutRow{})) {
+		t.Errorf("insert has %d placeholders, workoutArgs gives %d values", n, len(workoutArgs(models.WorkoutRow{})))
+	}
+}
+
+// TestPairAlphaSessions verifies a stored link wins over a nearer workout,
+// a session linked to a workout outside the list isn't left for a synthetic
+// entry, and unlinked sessions pair by proximity within the window only.
+func TestPairAlphaSessions(t *testing.T) {
+	t0 := time.Date(2025, 5, 1, 18, 0, 0, 0, time.UTC)
+	linked, near, far := uuid.New(), uuid.New(), uuid.New()
+	workouts := []models.WorkoutRow{
+		{ID: near, StartTime: t0.Add(5 * time.Minute)},
+		{ID: linked, StartTime: t0.Add(150 * time.Minute)},
+		{ID: far, StartTime: t0.Add(26 * time.Hour)},
+	}
+	elsewhere := uuid.New()
+	sessions := []AlphaSessionInfo{
+		{SessionName: "Push", SessionDate: t0, WorkoutID: &linked},
+		{SessionName: "Pull", SessionDate: t0.Add(24 * time.Hour), WorkoutID: &elsewhere},
+		{SessionName: "Legs", SessionDate: t0.Add(10 * time.Minute)},
+		{SessionName: "Arms", SessionDate: t0.Add(72 * time.Hour)},
+	}
+
+	used := pairAlphaSessions(workouts, sessions, time.Hour)
+	if workouts[1].AlphaSessionName != "Push" {
+		t.Errorf("linked workout = %q, want Push", workouts[1].AlphaSessionName)
+	}
+	if workouts[0].AlphaSessionName != "Legs" {
+		t.Errorf("nearby workout = %q, want Legs", workouts[0].AlphaSessionName)
+	}
+	if workouts[2].AlphaSessionName != "" {
+		t.Errorf("far workout = %q, want unpaired (Pull is linked elsewhere)", workouts[2].AlphaSessionName)
+	}
+	if !used[0] || !used[1] || !used[2] || used[3] {
+		t.Errorf("used = %v, want Push, Pull and Legs accounted for", used)
+	}
+
+	for i := range workouts {
+		workouts[i].AlphaSessionName = ""
+	}
+	if used := pairAlphaSessions(workouts, sessions[2:3], 0); used[0] || workouts[0].AlphaSessionName != "" {
+		t.Errorf("zero window paired by proximity: %v", used)
+	}
+}
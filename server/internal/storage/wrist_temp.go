@@ -0,0 +1,69 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+const (
+	// wristTempBaselineNights is how many prior recorded nights make up the
+	// baseline a night's wrist temperature is compared against.
+	wristTempBaselineNights = 30
+	// wristTempLookbackDays bounds how far before the range the baseline
+	// nights are searched for, so gaps in wear don't void the baseline.
+	wristTempLookbackDays = 90
+)
+
+// WristTempNight is one night's sleeping wrist temperature and its deviation
+// from the user's trailing baseline. Baseline and Deviation are nil until
+// wristTempBaselineNights earlier nights have been recorded.
+type WristTempNight struct {
+	Date      string   `json:"date"`
+	Value     float64  `json:"value"`
+	Baseline  *float64 `json:"baseline"`
+	Deviation *float64 `json:"deviation"`
+}
+
+// GetWristTempDeviation returns each night in [start, end) with its sleeping
+// wrist temperature's deviation from the mean of the previous 30 recorded
+// nights.
+func (db *DB) GetWristTempDeviation(ctx context.Context, start, end time.Time, userID int) ([]WristTempNight, error) {
+	points, err := db.GetTimeSeries(ctx, "apple_sleeping_wrist_temperature",
+		start.AddDate(0, 0, -wristTempLookbackDays), end, "1 day", userID)
+	if err != nil {
+		return nil, fmt.Errorf("querying wrist temperature: %w", err)
+	}
+	return wristTempDeviation(points, start), nil
+}
+
+// wristTempDeviation computes deviations for the daily points at or after
+// start, using earlier points only as baseline history.
+func wristTempDeviation(points []TimeSeriesPoint, start time.Time) []WristTempNight {
+	nights := []WristTempNight{}
+	var history []float64
+	for _, p := range points {
+		if p.Avg == nil {
+			continue
+		}
+		if !p.Time.Before(start) {
+			n := WristTempNight{Date: p.Time.UTC().Format("2006-01-02"), Value: *p.Avg}
+			if len(history) >= wristTempBaselineNights {
+				b := meanFloat(history[len(history)-wristTempBaselineNights:])
+				d := *p.Avg - b
+				n.Baseline, n.Deviation = &b, &d
+			}
+			nights = append(nights, n)
+		}
+		history = append(history, *p.Avg)
+	}
+	return nights
+}
+
+func meanFloat(vs []float64) float64 {
+	var sum float64
+	for _, v := range vs {
+		sum += v
+	}
+	return sum / float64(len(vs))
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// TestWristTempDeviation verifies nights before a full 30-night baseline get
+// nulls, later nights deviate from the mean of the previous 30, and points
+// before start only feed the baseline.
+func TestWristTempDeviation(t *testing.T) {
+	day0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var points []TimeSeriesPoint
+	for i := 0; i < 35; i++ {
+		v := 34.0
+		if i >= 30 {
+			v = 34.6
+		}
+		points = append(points, TimeSeriesPoint{Time: day0.AddDate(0, 0, i), Avg: &v})
+	}
+	points = append(points, TimeSeriesPoint{Time: day0.AddDate(0, 0, 35)}) // no sample
+
+	nights := wristTempDeviation(points, day0)
+	if len(nights) != 35 {
+		t.Fatalf("got %d nights, want 35", len(nights))
+	}
+	for _, n := range nights[:30] {
+		if n.Baseline != nil || n.Deviation != nil {
+			t.Fatalf("night %s has a baseline before 30 nights: %+v", n.Date, n)
+		}
+	}
+	if d := nights[30].Deviation; d == nil || math.Abs(*d-0.6) > 1e-9 {
+		t.Errorf("night 30 deviation = %v, want 0.6", d)
+	}
+	// Night 31's baseline includes night 30's raised value.
+	if b := nights[31].Baseline; b == nil || math.Abs(*b-(29*34.0+34.6)/30) > 1e-9 {
+		t.Errorf("night 31 baseline = %v", b)
+	}
+
+	later := wristTempDeviation(points, day0.AddDate(0, 0, 32))
+	if len(later) != 3 || later[0].Date != "2026-02-02" || later[0].Deviation == nil {
+		t.Errorf("range after history = %+v", later)
+	}
+}
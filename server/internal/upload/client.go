@@ -2,12 +2,14 @@ package upload
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"time"
 
+	"github.com/claude/freereps/internal/ingest"
 	"github.com/claude/freereps/internal/models"
 )
 
@@ -22,21 +24,118 @@ type allowedMetric struct {
 type Client struct {
 	serverURL  string
 	httpClient *http.Client
+	gzip       bool // compress ingest bodies with Content-Encoding: gzip
+	attempts   int
+	backoff    time.Duration
+}
+
+// ClientOptions tunes request timeouts and retries. Zero fields take the
+// NewClient defaults.
+type ClientOptions struct {
+	Timeout  time.Duration // per request, including reading the body (default 60s)
+	Attempts int           // tries per request, including the first (default 3)
+	Backoff  time.Duration // wait before the first retry, doubling after each (default 1s)
 }
 
 // NewClient creates a new HTTP client for the FreeReps server.
 func NewClient(serverURL string) *Client {
+	return NewClientWithOptions(serverURL, ClientOptions{})
+}
+
+// NewClientWithOptions creates a client with custom timeout and retry settings.
+func NewClientWithOptions(serverURL string, opts ClientOptions) *Client {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 60 * time.Second
+	}
+	if opts.Attempts <= 0 {
+		opts.Attempts = 3
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = time.Second
+	}
 	return &Client{
 		serverURL: serverURL,
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
+			Timeout: opts.Timeout,
 		},
+		attempts: opts.Attempts,
+		backoff:  opts.Backoff,
+	}
+}
+
+// retryDelay returns how long to wait before the given (1-based) retry.
+func (c *Client) retryDelay(retry int) time.Duration {
+	return c.backoff << uint(retry-1)
+}
+
+// get issues a GET against the server, retrying connection errors and 5xx
+// responses so a server restart mid-run doesn't abort the upload. 4xx
+// responses are returned as-is since retrying won't change them.
+func (c *Client) get(path string) (*http.Response, error) {
+	var lastErr error
+	for attempt := range c.attempts {
+		if attempt > 0 {
+			time.Sleep(c.retryDelay(attempt))
+		}
+
+		resp, err := c.httpClient.Get(c.serverURL + path)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode >= 500 {
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close() //nolint:errcheck
+			lastErr = fmt.Errorf("status %d: %s", resp.StatusCode, body)
+			continue
+		}
+		return resp, nil
+	}
+	return nil, fmt.Errorf("after %d attempts: %w", c.attempts, lastErr)
+}
+
+// SetGzip enables or disables gzip compression of ingest request bodies.
+// Route-heavy workout batches shrink considerably, which matters over Tailscale.
+func (c *Client) SetGzip(enabled bool) {
+	c.gzip = enabled
+}
+
+// postIngest POSTs a JSON body to the ingest endpoint, gzip-compressing it
+// first when compression is enabled.
+func (c *Client) postIngest(data []byte) (*http.Response, error) {
+	return c.post("/api/v1/ingest/", "application/json", data)
+}
+
+// post POSTs a body to an ingest path, gzip-compressing it first when
+// compression is enabled.
+func (c *Client) post(path, contentType string, data []byte) (*http.Response, error) {
+	body := data
+	if c.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(data); err != nil {
+			return nil, fmt.Errorf("compressing payload: %w", err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, fmt.Errorf("compressing payload: %w", err)
+		}
+		body = buf.Bytes()
 	}
+
+	req, err := http.NewRequest(http.MethodPost, c.serverURL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if c.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	return c.httpClient.Do(req)
 }
 
 // FetchAllowlist retrieves the enabled metric names from the server.
 func (c *Client) FetchAllowlist() (map[string]bool, error) {
-	resp, err := c.httpClient.Get(c.serverURL + "/api/v1/allowlist")
+	resp, err := c.get("/api/v1/allowlist")
 	if err != nil {
 		return nil, fmt.Errorf("fetching allowlist: %w", err)
 	}
@@ -66,16 +165,12 @@ func (c *Client) FetchAllowlist() (map[string]bool, error) {
 // that are already in the correct format.
 func (c *Client) SendRawJSON(data []byte) error {
 	var lastErr error
-	for attempt := range 3 {
+	for attempt := range c.attempts {
 		if attempt > 0 {
-			time.Sleep(time.Duration(1<<uint(attempt-1)) * time.Second)
+			time.Sleep(c.retryDelay(attempt))
 		}
 
-		resp, err := c.httpClient.Post(
-			c.serverURL+"/api/v1/ingest/",
-			"application/json",
-			bytes.NewReader(data),
-		)
+		resp, err := c.postIngest(data)
 		if err != nil {
 			lastErr = err
 			continue
@@ -90,28 +185,72 @@ func (c *Client) SendRawJSON(data []byte) error {
 		lastErr = fmt.Errorf("ingest failed (status %d): %s", resp.StatusCode, body)
 	}
 
-	return fmt.Errorf("after 3 attempts: %w", lastErr)
+	return fmt.Errorf("after %d attempts: %w", c.attempts, lastErr)
+}
+
+// SendFIT POSTs the raw bytes of a Garmin FIT file to the FIT ingest
+// endpoint, retrying like SendRawJSON.
+func (c *Client) SendFIT(data []byte) error {
+	var lastErr error
+	for attempt := range c.attempts {
+		if attempt > 0 {
+			time.Sleep(c.retryDelay(attempt))
+		}
+
+		resp, err := c.post("/api/v1/ingest/fit", "application/octet-stream", data)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close() //nolint:errcheck
+
+		if resp.StatusCode == http.StatusOK {
+			return nil
+		}
+		lastErr = fmt.Errorf("FIT ingest failed (status %d): %s", resp.StatusCode, body)
+	}
+
+	return fmt.Errorf("after %d attempts: %w", c.attempts, lastErr)
 }
 
 // SendPayload POSTs an HealthPayload to the server's ingest endpoint.
-// Retries up to 3 times with exponential backoff on failure.
+// Retries with exponential backoff on failure (3 attempts by default).
 func (c *Client) SendPayload(payload models.HealthPayload) error {
+	_, err := c.sendPayload(payload)
+	return err
+}
+
+// SendPayloadResult sends payload like SendPayload and returns the server's
+// ingest result, e.g. to confirm which workouts were stored.
+func (c *Client) SendPayloadResult(payload models.HealthPayload) (*ingest.Result, error) {
+	body, err := c.sendPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	var result ingest.Result
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("decoding ingest result: %w", err)
+	}
+	return &result, nil
+}
+
+// sendPayload POSTs payload with retries and returns the response body of
+// the successful attempt.
+func (c *Client) sendPayload(payload models.HealthPayload) ([]byte, error) {
 	data, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("marshaling payload: %w", err)
+		return nil, fmt.Errorf("marshaling payload: %w", err)
 	}
 
 	var lastErr error
-	for attempt := range 3 {
+	for attempt := range c.attempts {
 		if attempt > 0 {
-			time.Sleep(time.Duration(1<<uint(attempt-1)) * time.Second)
+			time.Sleep(c.retryDelay(attempt))
 		}
 
-		resp, err := c.httpClient.Post(
-			c.serverURL+"/api/v1/ingest/",
-			"application/json",
-			bytes.NewReader(data),
-		)
+		resp, err := c.postIngest(data)
 		if err != nil {
 			lastErr = err
 			continue
@@ -121,10 +260,10 @@ func (c *Client) SendPayload(payload models.HealthPayload) error {
 		resp.Body.Close() //nolint:errcheck
 
 		if resp.StatusCode == http.StatusOK {
-			return nil
+			return body, nil
 		}
 		lastErr = fmt.Errorf("ingest failed (status %d): %s", resp.StatusCode, body)
 	}
 
-	return fmt.Errorf("after 3 attempts: %w", lastErr)
+	return nil, fmt.Errorf("after %d attempts: %w", c.attempts, lastErr)
 }
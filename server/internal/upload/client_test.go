@@ -0,0 +1,167 @@
+package upload
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// TestSendRawJSONGzip verifies that enabling gzip compresses the ingest body
+// and sets Content-Encoding, and that the server sees the original JSON.
+func TestSendRawJSONGzip(t *testing.T) {
+	want := `{"data":{"metrics":[]}}`
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if enc := r.Header.Get("Content-Encoding"); enc != "gzip" {
+			t.Errorf("Content-Encoding = %q, want gzip", enc)
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Fatalf("gzip reader: %v", err)
+		}
+		b, _ := io.ReadAll(zr)
+		got = string(b)
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	c.SetGzip(true)
+	if err := c.SendRawJSON([]byte(want)); err != nil {
+		t.Fatalf("SendRawJSON: %v", err)
+	}
+	if got != want {
+		t.Errorf("body = %q, want %q", got, want)
+	}
+}
+
+// TestSendRawJSONUncompressed verifies the default client sends plain JSON
+// so older servers without gzip support keep working.
+func TestSendRawJSONUncompressed(t *testing.T) {
+	var enc, got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc = r.Header.Get("Content-Encoding")
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	}))
+	defer srv.Close()
+
+	if err := NewClient(srv.URL).SendRawJSON([]byte(`{}`)); err != nil {
+		t.Fatalf("SendRawJSON: %v", err)
+	}
+	if enc != "" {
+		t.Errorf("Content-Encoding = %q, want empty", enc)
+	}
+	if got != `{}` {
+		t.Errorf("body = %q, want %q", got, `{}`)
+	}
+}
+
+// TestFetchAllowlistRetries verifies a transient 5xx (e.g. the server
+// restarting) is retried instead of failing the whole upload.
+func TestFetchAllowlistRetries(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			http.Error(w, "restarting", http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"metric_name":"heart_rate","enabled":true}]`))
+	}))
+	defer srv.Close()
+
+	c := NewClientWithOptions(srv.URL, ClientOptions{Backoff: time.Millisecond})
+	allow, err := c.FetchAllowlist()
+	if err != nil {
+		t.Fatalf("FetchAllowlist: %v", err)
+	}
+	if calls != 2 || !allow["heart_rate"] {
+		t.Errorf("calls = %d, allowlist = %v; want 2 calls and heart_rate allowed", calls, allow)
+	}
+}
+
+// TestFetchAllowlistNoRetryOn4xx verifies client errors fail immediately,
+// since repeating the same request can't succeed.
+func TestFetchAllowlistNoRetryOn4xx(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	c := NewClientWithOptions(srv.URL, ClientOptions{Backoff: time.Millisecond})
+	if _, err := c.FetchAllowlist(); err == nil {
+		t.Fatal("expected error for 403")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+// TestClientTimeout verifies the configured timeout bounds each attempt and
+// that exhausting the attempts reports the count.
+func TestClientTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer srv.Close()
+
+	c := NewClientWithOptions(srv.URL, ClientOptions{Timeout: 20 * time.Millisecond, Attempts: 2, Backoff: time.Millisecond})
+	_, err := c.FetchAllowlist()
+	if err == nil || !strings.Contains(err.Error(), "after 2 attempts") {
+		t.Errorf("err = %v, want timeout after 2 attempts", err)
+	}
+}
+
+// TestSendFIT verifies FIT files are posted unchanged to the FIT ingest
+// endpoint as an octet stream.
+func TestSendFIT(t *testing.T) {
+	var path, ctype, got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, ctype = r.URL.Path, r.Header.Get("Content-Type")
+		b, _ := io.ReadAll(r.Body)
+		got = string(b)
+	}))
+	defer srv.Close()
+
+	if err := NewClient(srv.URL).SendFIT([]byte("\x0e\x20.FIT")); err != nil {
+		t.Fatalf("SendFIT: %v", err)
+	}
+	if path != "/api/v1/ingest/fit" || ctype != "application/octet-stream" {
+		t.Errorf("path = %q, content type = %q", path, ctype)
+	}
+	if got != "\x0e\x20.FIT" {
+		t.Errorf("body = %q", got)
+	}
+}
+
+// TestSendPayloadResult verifies the ingest result is decoded from the
+// response and that a non-JSON body is an error rather than an empty result.
+func TestSendPayloadResult(t *testing.T) {
+	body := `{"workouts_received":2,"workouts_inserted":1,"workout_ids":["a","b"]}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, body) //nolint:errcheck
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL)
+	result, err := c.SendPayloadResult(models.HealthPayload{})
+	if err != nil {
+		t.Fatalf("SendPayloadResult: %v", err)
+	}
+	if result.WorkoutsReceived != 2 || len(result.WorkoutIDs) != 2 || result.WorkoutIDs[1] != "b" {
+		t.Errorf("result = %+v, want 2 received, IDs [a b]", result)
+	}
+
+	body = "ok"
+	if _, err := c.SendPayloadResult(models.HealthPayload{}); err == nil {
+		t.Error("expected error for non-JSON response")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"sort"
 	"time"
 
+	"github.com/claude/freereps/internal/ingest"
 	"github.com/claude/freereps/internal/models"
 )
 
@@ -97,6 +98,19 @@ func convertMetric(file models.HAEFileMetric, metricName string) (models.HealthM
 	return metric, hrPoints, nil
 }
 
+// filterTimeBounds drops data points whose start time falls outside bounds,
+// returning the kept points and the number rejected.
+func filterTimeBounds(points []models.HAEFileDataPoint, bounds ingest.TimeBounds) ([]models.HAEFileDataPoint, int) {
+	kept := points[:0]
+	for _, dp := range points {
+		if bounds.Check(models.AppleTimestampToTime(dp.Start)) != nil {
+			continue
+		}
+		kept = append(kept, dp)
+	}
+	return kept, len(points) - len(kept)
+}
+
 // convertSleepStages converts .hae sleep data points to REST API unaggregated format.
 func convertSleepStages(dataPoints []models.HAEFileDataPoint) ([]json.RawMessage, error) {
 	var data []json.RawMessage
@@ -125,7 +139,7 @@ func convertSleepStages(dataPoints []models.HAEFileDataPoint) ([]json.RawMessage
 // convertWorkout converts an HAEFileWorkout to REST API HealthWorkout format.
 // Route data is embedded from a separate route file (if found).
 // Heart rate data is correlated from in-memory hrPoints collected during metric processing.
-func convertWorkout(file models.HAEFileWorkout, route *models.HAEFileRoute, hrPoints []hrDataPoint) models.HealthWorkout {
+func convertWorkout(file models.HAEFileWorkout, route *models.HAEFileRoute, hrPoints []hrDataPoint, hrTolerance time.Duration) models.HealthWorkout {
 	start := models.AppleTimestampToTime(file.Start)
 	end := models.AppleTimestampToTime(file.End)
 
@@ -147,6 +161,12 @@ func convertWorkout(file models.HAEFileWorkout, route *models.HAEFileRoute, hrPo
 	if file.ElevationUp != nil {
 		w.ElevationUp = &models.Quantity{Qty: *file.ElevationUp, Units: "m"}
 	}
+	if file.Temperature != nil {
+		w.Temperature = &models.Quantity{Qty: *file.Temperature, Units: "degC"}
+	}
+	if file.Humidity != nil {
+		w.Humidity = &models.Quantity{Qty: *file.Humidity, Units: "%"}
+	}
 
 	// Embed route data from separate .hae file
 	if route != nil && len(route.Locations) > 0 {
@@ -160,6 +180,8 @@ func convertWorkout(file models.HAEFileWorkout, route *models.HAEFileRoute, hrPo
 				Course:             loc.Course,
 				HorizontalAccuracy: loc.HAcc,
 				VerticalAccuracy:   loc.VAcc,
+				CourseAccuracy:     loc.CourseAcc,
+				SpeedAccuracy:      loc.SpeedAcc,
 				Timestamp:          models.HealthTime{Time: models.AppleTimestampToTime(loc.Time)},
 			}
 		}
@@ -168,7 +190,7 @@ func convertWorkout(file models.HAEFileWorkout, route *models.HAEFileRoute, hrPo
 
 	// Correlate HR data from overlapping heart_rate metrics
 	if len(hrPoints) > 0 {
-		correlatedHR := correlateWorkoutHR(hrPoints, start, end)
+		correlatedHR := correlateWorkoutHR(hrPoints, start, end, hrTolerance)
 		if len(correlatedHR) > 0 {
 			w.HeartRateData = correlatedHR
 
@@ -197,8 +219,13 @@ func convertWorkout(file models.HAEFileWorkout, route *models.HAEFileRoute, hrPo
 }
 
 // correlateWorkoutHR finds heart rate data points within the workout's time range
-// using binary search on the sorted hrPoints slice.
-func correlateWorkoutHR(hrPoints []hrDataPoint, start, end time.Time) []models.WorkoutHRPoint {
+// using binary search on the sorted hrPoints slice. The range is half-open,
+// [start, end), like the storage range queries. tolerance widens it on both
+// sides so samples the watch logged just before the start or just after the
+// end still count.
+func correlateWorkoutHR(hrPoints []hrDataPoint, start, end time.Time, tolerance time.Duration) []models.WorkoutHRPoint {
+	start, end = start.Add(-tolerance), end.Add(tolerance)
+
 	// Binary search for the first point >= start
 	lo := sort.Search(len(hrPoints), func(i int) bool {
 		return !hrPoints[i].Time.Before(start)
@@ -206,7 +233,7 @@ func correlateWorkoutHR(hrPoints []hrDataPoint, start, end time.Time) []models.W
 
 	var result []models.WorkoutHRPoint
 	for i := lo; i < len(hrPoints); i++ {
-		if hrPoints[i].Time.After(end) {
+		if !hrPoints[i].Time.Before(end) {
 			break
 		}
 		result = append(result, models.WorkoutHRPoint{
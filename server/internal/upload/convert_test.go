@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/claude/freereps/internal/ingest"
 	"github.com/claude/freereps/internal/models"
 )
 
@@ -223,12 +224,12 @@ func TestConvertWorkout(t *testing.T) {
 		ID:   "AAAAAAAA-BBBB-CCCC-DDDD-EEEEEEEEEEEE",
 		Name: "Running Route",
 		Locations: []models.HAEFileLocation{
-			{Latitude: 48.1, Longitude: 11.5, Elevation: 500, Speed: 3.5, Course: 90, Time: 730000000, HAcc: 5, VAcc: 3},
+			{Latitude: 48.1, Longitude: 11.5, Elevation: 500, Speed: 3.5, Course: 90, Time: 730000000, HAcc: 5, VAcc: 3, CourseAcc: 12.5, SpeedAcc: 0.4},
 			{Latitude: 48.2, Longitude: 11.6, Elevation: 510, Speed: 3.6, Course: 91, Time: 730000060, HAcc: 5, VAcc: 3},
 		},
 	}
 
-	workout := convertWorkout(fileWorkout, route, nil)
+	workout := convertWorkout(fileWorkout, route, nil, 0)
 
 	if workout.ID != "AAAAAAAA-BBBB-CCCC-DDDD-EEEEEEEEEEEE" {
 		t.Errorf("ID = %q", workout.ID)
@@ -264,6 +265,10 @@ func TestConvertWorkout(t *testing.T) {
 	if workout.Route[0].Altitude != 500 {
 		t.Errorf("Route[0].Altitude = %f, want 500", workout.Route[0].Altitude)
 	}
+	if workout.Route[0].CourseAccuracy != 12.5 || workout.Route[0].SpeedAccuracy != 0.4 {
+		t.Errorf("Route[0] accuracy = course %f speed %f, want 12.5, 0.4",
+			workout.Route[0].CourseAccuracy, workout.Route[0].SpeedAccuracy)
+	}
 }
 
 // TestConvertWorkoutOptionalFields verifies that nil optional fields
@@ -277,7 +282,7 @@ func TestConvertWorkoutOptionalFields(t *testing.T) {
 		Duration: 3600,
 	}
 
-	workout := convertWorkout(fileWorkout, nil, nil)
+	workout := convertWorkout(fileWorkout, nil, nil, 0)
 
 	if workout.ActiveEnergyBurned != nil {
 		t.Error("ActiveEnergyBurned should be nil")
@@ -288,6 +293,9 @@ func TestConvertWorkoutOptionalFields(t *testing.T) {
 	if workout.ElevationUp != nil {
 		t.Error("ElevationUp should be nil")
 	}
+	if workout.Temperature != nil || workout.Humidity != nil {
+		t.Error("Temperature and Humidity should be nil")
+	}
 	if len(workout.Route) != 0 {
 		t.Error("Route should be empty")
 	}
@@ -296,8 +304,36 @@ func TestConvertWorkoutOptionalFields(t *testing.T) {
 	}
 }
 
+// TestConvertWorkoutConditions verifies that temperature and humidity from the
+// hiking fixture carry through to the REST workout format.
+func TestConvertWorkoutConditions(t *testing.T) {
+	raw := `{
+		"id": "D39830A2-4724-4648-8F36-41D7511423B6",
+		"name": "Hiking",
+		"start": 787321422.438,
+		"end": 787324808.576,
+		"duration": 3386.138,
+		"totalDistance": 4.768,
+		"humidity": 90,
+		"temperature": 8.235
+	}`
+	var fileWorkout models.HAEFileWorkout
+	if err := json.Unmarshal([]byte(raw), &fileWorkout); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	workout := convertWorkout(fileWorkout, nil, nil, 0)
+
+	if workout.Temperature == nil || workout.Temperature.Qty != 8.235 || workout.Temperature.Units != "degC" {
+		t.Errorf("Temperature = %+v", workout.Temperature)
+	}
+	if workout.Humidity == nil || workout.Humidity.Qty != 90 || workout.Humidity.Units != "%" {
+		t.Errorf("Humidity = %+v", workout.Humidity)
+	}
+}
+
 // TestCorrelateWorkoutHR verifies that binary search correctly finds
-// heart rate data points within a workout's time range.
+// heart rate data points within a workout's half-open time range.
 func TestCorrelateWorkoutHR(t *testing.T) {
 	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
 	hrPoints := make([]hrDataPoint, 100)
@@ -316,11 +352,11 @@ func TestCorrelateWorkoutHR(t *testing.T) {
 	start := baseTime.Add(20 * time.Minute)
 	end := baseTime.Add(50 * time.Minute)
 
-	result := correlateWorkoutHR(hrPoints, start, end)
+	result := correlateWorkoutHR(hrPoints, start, end, 0)
 
-	// Should include points at minutes 20, 21, ..., 50 = 31 points
-	if len(result) != 31 {
-		t.Errorf("correlated %d HR points, want 31", len(result))
+	// Should include points at minutes 20, 21, ..., 49 = 30 points
+	if len(result) != 30 {
+		t.Errorf("correlated %d HR points, want 30", len(result))
 	}
 
 	// Verify first and last point values
@@ -328,12 +364,36 @@ func TestCorrelateWorkoutHR(t *testing.T) {
 		if result[0].Avg != 90 { // 70 + 20
 			t.Errorf("first HR point Avg = %f, want 90", result[0].Avg)
 		}
-		if result[len(result)-1].Avg != 120 { // 70 + 50
-			t.Errorf("last HR point Avg = %f, want 120", result[len(result)-1].Avg)
+		if result[len(result)-1].Avg != 119 { // 70 + 49
+			t.Errorf("last HR point Avg = %f, want 119", result[len(result)-1].Avg)
 		}
 	}
 }
 
+// TestCorrelateWorkoutHRBoundaries pins the half-open window: a sample at
+// the start is matched, one at the end is not, including when a tolerance
+// moves the bounds.
+func TestCorrelateWorkoutHRBoundaries(t *testing.T) {
+	start := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	end := start.Add(30 * time.Minute)
+	hrPoints := []hrDataPoint{
+		{Time: start.Add(-time.Minute), Avg: 90},
+		{Time: start, Avg: 100},
+		{Time: end, Avg: 110},
+		{Time: end.Add(time.Minute), Avg: 120},
+	}
+
+	strict := correlateWorkoutHR(hrPoints, start, end, 0)
+	if len(strict) != 1 || strict[0].Avg != 100 {
+		t.Errorf("strict = %+v, want only the sample at start", strict)
+	}
+
+	tolerant := correlateWorkoutHR(hrPoints, start, end, time.Minute)
+	if len(tolerant) != 3 || tolerant[0].Avg != 90 || tolerant[2].Avg != 110 {
+		t.Errorf("tolerant = %+v, want samples from start-1m up to but not end+1m", tolerant)
+	}
+}
+
 // TestCorrelateWorkoutHRNoOverlap verifies that no HR points are returned
 // when the workout time range doesn't overlap with any HR data.
 func TestCorrelateWorkoutHRNoOverlap(t *testing.T) {
@@ -347,12 +407,39 @@ func TestCorrelateWorkoutHRNoOverlap(t *testing.T) {
 	start := baseTime.Add(2 * time.Hour)
 	end := baseTime.Add(3 * time.Hour)
 
-	result := correlateWorkoutHR(hrPoints, start, end)
+	result := correlateWorkoutHR(hrPoints, start, end, 0)
 	if len(result) != 0 {
 		t.Errorf("correlated %d HR points, want 0", len(result))
 	}
 }
 
+// TestCorrelateWorkoutHRTolerance verifies samples just outside the workout
+// are matched within the tolerance and samples beyond it are not.
+func TestCorrelateWorkoutHRTolerance(t *testing.T) {
+	baseTime := time.Date(2024, 6, 15, 10, 0, 0, 0, time.UTC)
+	start := baseTime.Add(10 * time.Minute)
+	end := baseTime.Add(40 * time.Minute)
+	hrPoints := []hrDataPoint{
+		{Time: start.Add(-3 * time.Minute), Avg: 70}, // beyond tolerance
+		{Time: start.Add(-90 * time.Second), Avg: 80},
+		{Time: start.Add(15 * time.Minute), Avg: 140},
+		{Time: end.Add(90 * time.Second), Avg: 110},
+		{Time: end.Add(150 * time.Second), Avg: 100}, // beyond tolerance
+	}
+
+	if strict := correlateWorkoutHR(hrPoints, start, end, 0); len(strict) != 1 {
+		t.Errorf("strict window matched %d points, want 1", len(strict))
+	}
+
+	result := correlateWorkoutHR(hrPoints, start, end, 2*time.Minute)
+	if len(result) != 3 {
+		t.Fatalf("tolerant window matched %d points, want 3", len(result))
+	}
+	if result[0].Avg != 80 || result[2].Avg != 110 {
+		t.Errorf("matched %v..%v, want 80..110", result[0].Avg, result[2].Avg)
+	}
+}
+
 // TestConvertWorkoutWithHRCorrelation verifies that HR data is embedded
 // in the workout and a summary is computed from the correlated points.
 func TestConvertWorkoutWithHRCorrelation(t *testing.T) {
@@ -373,7 +460,7 @@ func TestConvertWorkoutWithHRCorrelation(t *testing.T) {
 		Duration: 1800,
 	}
 
-	workout := convertWorkout(fileWorkout, nil, hrPoints)
+	workout := convertWorkout(fileWorkout, nil, hrPoints, 0)
 
 	if len(workout.HeartRateData) != 3 {
 		t.Fatalf("HeartRateData length = %d, want 3", len(workout.HeartRateData))
@@ -416,3 +503,22 @@ func TestFormatHealthTime(t *testing.T) {
 		t.Errorf("formatHealthTime = %q, want %q", got, want)
 	}
 }
+
+// TestFilterTimeBounds verifies that the file importer drops a year-3000 point
+// and counts it, keeping valid points untouched.
+func TestFilterTimeBounds(t *testing.T) {
+	valid := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	future := time.Date(3000, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := []models.HAEFileDataPoint{
+		{Start: float64(valid.Unix() - models.AppleEpochOffset), Qty: floatPtr(1)},
+		{Start: float64(future.Unix() - models.AppleEpochOffset), Qty: floatPtr(2)},
+	}
+
+	kept, rejected := filterTimeBounds(points, ingest.DefaultTimeBounds)
+	if rejected != 1 {
+		t.Errorf("rejected = %d, want 1", rejected)
+	}
+	if len(kept) != 1 || *kept[0].Qty != 1 {
+		t.Errorf("kept = %+v, want only the 2025 point", kept)
+	}
+}
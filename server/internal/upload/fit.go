@@ -0,0 +1,105 @@
+package upload
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RunFIT uploads Garmin FIT files from path, which may be a single file or a
+// directory searched recursively. Files are parsed by the server, so each is
+// sent as-is and skipped on later runs while its size and hash are unchanged.
+func (u *Uploader) RunFIT(path string) (*Stats, error) {
+	files, err := findFITFiles(path)
+	if err != nil {
+		return &u.stats, fmt.Errorf("finding FIT files: %w", err)
+	}
+
+	for _, f := range files {
+		u.stats.FilesTotal++
+
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			abs = f
+		}
+		// Prefix the state key so FIT paths can't collide with AutoSync
+		// paths, which are stored relative to the AutoSync directory.
+		key := "fit:" + abs
+
+		info, err := os.Stat(f)
+		if err != nil {
+			u.log.Warn("stat failed", "file", f, "error", err)
+			u.stats.FilesErrored++
+			continue
+		}
+		hash, err := HashFile(f)
+		if err != nil {
+			u.log.Warn("hash failed", "file", f, "error", err)
+			u.stats.FilesErrored++
+			continue
+		}
+		uploaded, err := u.state.IsUploaded(key, info.Size(), hash)
+		if err != nil {
+			u.log.Warn("state check failed", "file", f, "error", err)
+			u.stats.FilesErrored++
+			continue
+		}
+		if uploaded {
+			u.stats.FilesSkipped++
+			continue
+		}
+
+		if u.dryRun {
+			u.log.Info("dry-run: would send FIT file", "file", f, "bytes", info.Size())
+		} else {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				u.log.Warn("read failed", "file", f, "error", err)
+				u.stats.FilesErrored++
+				continue
+			}
+			if err := u.client.SendFIT(data); err != nil {
+				u.log.Warn("upload failed", "file", f, "error", err)
+				u.stats.FilesErrored++
+				continue
+			}
+		}
+
+		if err := u.state.MarkUploaded(key, info.Size(), hash); err != nil {
+			u.log.Warn("failed to mark uploaded", "file", f, "error", err)
+		}
+		u.stats.FilesUploaded++
+	}
+	return &u.stats, nil
+}
+
+// findFITFiles returns path if it is a file, or every .fit file (any case)
+// under it if it is a directory, sorted by path.
+func findFITFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() && strings.EqualFold(filepath.Ext(p), ".fit") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,40 @@
+package upload
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// TestFindFITFiles verifies .fit files are found recursively regardless of
+// extension case, other files are ignored, and a file path is returned as-is.
+func TestFindFITFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.FIT", "a.fit", "notes.txt", "sub/c.fit"} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := findFITFiles(dir)
+	if err != nil {
+		t.Fatalf("findFITFiles: %v", err)
+	}
+	want := []string{filepath.Join(dir, "a.fit"), filepath.Join(dir, "b.FIT"), filepath.Join(dir, "sub/c.fit")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("files = %v, want %v", got, want)
+	}
+
+	single := filepath.Join(dir, "a.fit")
+	if got, err := findFITFiles(single); err != nil || !reflect.DeepEqual(got, []string{single}) {
+		t.Errorf("findFITFiles(file) = %v, %v", got, err)
+	}
+	if _, err := findFITFiles(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing path")
+	}
+}
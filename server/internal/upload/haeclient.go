@@ -2,19 +2,23 @@ package upload
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
+	"slices"
 	"time"
 )
 
 // HAEClient connects to the Health Auto Export TCP server (JSON-RPC 2.0).
-// Each method call opens a new TCP connection — the HAE server closes the
-// socket after sending the response.
+// Each method call opens a new TCP connection. The HAE server normally closes
+// the socket after sending the response, but the client stops reading as soon
+// as the matching response arrives, so a server that keeps it open works too.
 type HAEClient struct {
 	host    string
 	port    int
+	token   string
 	timeout time.Duration
 }
 
@@ -27,9 +31,11 @@ type jsonRPCRequest struct {
 }
 
 // callToolParams wraps the tool name and arguments for the callTool method.
+// Token authenticates the call to HAE servers that require one.
 type callToolParams struct {
 	Name      string         `json:"name"`
 	Arguments map[string]any `json:"arguments"`
+	Token     string         `json:"token,omitempty"`
 }
 
 // jsonRPCResponse is a JSON-RPC 2.0 response.
@@ -49,11 +55,13 @@ type jsonRPCError struct {
 // HAE date format: yyyy-MM-dd HH:mm:ss Z
 const haeDateFormat = "2006-01-02 15:04:05 -0700"
 
-// NewHAEClient creates a new client for the HAE TCP server.
-func NewHAEClient(host string, port int) *HAEClient {
+// NewHAEClient creates a new client for the HAE TCP server. token is sent in
+// the params of every request; leave it empty for servers without auth.
+func NewHAEClient(host string, port int, token string) *HAEClient {
 	return &HAEClient{
 		host:    host,
 		port:    port,
+		token:   token,
 		timeout: 120 * time.Second,
 	}
 }
@@ -86,14 +94,20 @@ func (c *HAEClient) QueryWorkouts(start, end time.Time) (json.RawMessage, error)
 
 // callTool sends a JSON-RPC callTool request and returns the result.
 func (c *HAEClient) callTool(toolName string, args map[string]any) (json.RawMessage, error) {
+	return c.call("callTool", callToolParams{
+		Name:      toolName,
+		Arguments: args,
+		Token:     c.token,
+	})
+}
+
+// call sends a single JSON-RPC request and returns the result.
+func (c *HAEClient) call(method string, params any) (json.RawMessage, error) {
 	req := jsonRPCRequest{
 		JSONRPC: "2.0",
 		ID:      1,
-		Method:  "callTool",
-		Params: callToolParams{
-			Name:      toolName,
-			Arguments: args,
-		},
+		Method:  method,
+		Params:  params,
 	}
 
 	reqData, err := json.Marshal(req)
@@ -119,19 +133,12 @@ func (c *HAEClient) callTool(toolName string, args map[string]any) (json.RawMess
 		return nil, fmt.Errorf("writing request: %w", err)
 	}
 
-	// HAE server closes the connection after sending the response, so read until EOF.
-	respData, err := io.ReadAll(conn)
-	if err != nil {
-		return nil, fmt.Errorf("reading response: %w", err)
-	}
-
-	if len(respData) == 0 {
+	resp, err := readResponse(conn, req.ID)
+	if errors.Is(err, errEmptyResponse) {
 		return nil, fmt.Errorf("empty response from %s", addr)
 	}
-
-	var resp jsonRPCResponse
-	if err := json.Unmarshal(respData, &resp); err != nil {
-		return nil, fmt.Errorf("parsing response: %w", err)
+	if err != nil {
+		return nil, err
 	}
 
 	if resp.Error != nil {
@@ -141,6 +148,126 @@ func (c *HAEClient) callTool(toolName string, args map[string]any) (json.RawMess
 	return resp.Result, nil
 }
 
+// errEmptyResponse is returned by readResponse when the server closes the
+// connection without sending anything.
+var errEmptyResponse = errors.New("empty response")
+
+// readResponse decodes JSON-RPC messages from r until the response to id
+// arrives. Messages are framed by the JSON values themselves, so both
+// newline-delimited streams and a single response followed by a close work.
+// Notifications and responses to other ids are skipped; the connection's
+// deadline bounds the wait.
+func readResponse(r io.Reader, id int) (*jsonRPCResponse, error) {
+	dec := json.NewDecoder(r)
+	for n := 0; ; n++ {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			switch {
+			case err == io.EOF && n == 0:
+				return nil, errEmptyResponse
+			case err == io.EOF:
+				return nil, fmt.Errorf("connection closed before response to request %d", id)
+			}
+			return nil, fmt.Errorf("reading response: %w", err)
+		}
+
+		var head struct {
+			ID     *int   `json:"id"`
+			Method string `json:"method"`
+		}
+		if err := json.Unmarshal(raw, &head); err != nil {
+			return nil, fmt.Errorf("parsing response: %w", err)
+		}
+		if head.Method != "" || head.ID == nil || *head.ID != id {
+			continue
+		}
+
+		var resp jsonRPCResponse
+		if err := json.Unmarshal(raw, &resp); err != nil {
+			return nil, fmt.Errorf("parsing response: %w", err)
+		}
+		return &resp, nil
+	}
+}
+
+// HAEServerInfo is what an HAE server reports about itself.
+type HAEServerInfo struct {
+	Version string   `json:"version,omitempty"`
+	Tools   []string `json:"tools"`
+}
+
+// RequiredHAETools are the tools an import calls; a server missing any of
+// them has an outdated or incomplete export plugin.
+var RequiredHAETools = []string{"health_metrics", "workouts"}
+
+// infoTimeout bounds ServerInfo so an introspection call the server ignores
+// doesn't stall the caller for the full query timeout.
+const infoTimeout = 10 * time.Second
+
+// ServerInfo asks the server for its tool list and version via the listTools
+// method. Older HAE builds don't support it and return an error.
+func (c *HAEClient) ServerInfo() (*HAEServerInfo, error) {
+	probe := *c
+	probe.timeout = min(c.timeout, infoTimeout)
+	params := map[string]any{}
+	if c.token != "" {
+		params["token"] = c.token
+	}
+	raw, err := probe.call("listTools", params)
+	if err != nil {
+		return nil, err
+	}
+	return parseServerInfo(raw)
+}
+
+// parseServerInfo decodes a listTools result. Tools may be listed as objects
+// with a name or as bare strings, and the version may sit at the top level
+// or under serverInfo.
+func parseServerInfo(raw json.RawMessage) (*HAEServerInfo, error) {
+	var res struct {
+		Tools      []json.RawMessage `json:"tools"`
+		Version    string            `json:"version"`
+		ServerInfo struct {
+			Version string `json:"version"`
+		} `json:"serverInfo"`
+	}
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, fmt.Errorf("parsing listTools result: %w", err)
+	}
+
+	info := &HAEServerInfo{Version: res.Version, Tools: []string{}}
+	if info.Version == "" {
+		info.Version = res.ServerInfo.Version
+	}
+	for _, t := range res.Tools {
+		var name string
+		if err := json.Unmarshal(t, &name); err != nil {
+			var obj struct {
+				Name string `json:"name"`
+			}
+			if err := json.Unmarshal(t, &obj); err != nil {
+				return nil, fmt.Errorf("parsing tool entry: %w", err)
+			}
+			name = obj.Name
+		}
+		if name != "" {
+			info.Tools = append(info.Tools, name)
+		}
+	}
+	return info, nil
+}
+
+// MissingTools returns the required tools the server didn't list.
+func (i *HAEServerInfo) MissingTools() []string {
+	missing := []string{}
+	for _, req := range RequiredHAETools {
+		if !slices.Contains(i.Tools, req) {
+			missing = append(missing, req)
+		}
+	}
+	return missing
+}
+
 // Ping checks if the HAE server is reachable with a short TCP dial.
 func (c *HAEClient) Ping() error {
 	addr := net.JoinHostPort(c.host, fmt.Sprintf("%d", c.port))
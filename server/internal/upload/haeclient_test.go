@@ -2,7 +2,9 @@ package upload
 
 import (
 	"encoding/json"
+	"errors"
 	"net"
+	"strings"
 	"testing"
 	"time"
 )
@@ -50,7 +52,7 @@ func TestCallTool(t *testing.T) {
 
 	port := startMockTCPServer(t, respBytes)
 
-	client := NewHAEClient("127.0.0.1", port)
+	client := NewHAEClient("127.0.0.1", port, "")
 	client.timeout = 5 * time.Second
 
 	result, err := client.callTool("health_metrics", map[string]any{
@@ -77,7 +79,7 @@ func TestCallToolError(t *testing.T) {
 
 	port := startMockTCPServer(t, respBytes)
 
-	client := NewHAEClient("127.0.0.1", port)
+	client := NewHAEClient("127.0.0.1", port, "")
 	client.timeout = 5 * time.Second
 
 	_, err := client.callTool("health_metrics", map[string]any{})
@@ -89,6 +91,53 @@ func TestCallToolError(t *testing.T) {
 	}
 }
 
+// TestCallToolSkipsNotifications verifies that notification lines and
+// responses to other ids before the real response are skipped, and that the
+// client returns without waiting for the server to close the connection.
+func TestCallToolSkipsNotifications(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() }) //nolint:errcheck
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close() //nolint:errcheck
+		buf := make([]byte, 4096)
+		conn.Read(buf) //nolint:errcheck
+		conn.Write([]byte(`{"jsonrpc":"2.0","method":"progress","params":{"pct":50}}` + "\n" + //nolint:errcheck
+			`{"jsonrpc":"2.0","id":7,"result":{"stale":true}}` + "\n" +
+			`{"jsonrpc":"2.0","id":1,"result":{"data":{"workouts":[]}}}` + "\n"))
+		<-done // keep the connection open
+	}()
+
+	client := NewHAEClient("127.0.0.1", ln.Addr().(*net.TCPAddr).Port, "")
+	client.timeout = 2 * time.Second
+
+	result, err := client.callTool("workouts", map[string]any{})
+	if err != nil {
+		t.Fatalf("callTool returned error: %v", err)
+	}
+	if string(result) != `{"data":{"workouts":[]}}` {
+		t.Errorf("unexpected result: %s", result)
+	}
+}
+
+// TestReadResponseClosedBeforeMatch verifies a stream that ends after only
+// unrelated messages is an error rather than an empty result.
+func TestReadResponseClosedBeforeMatch(t *testing.T) {
+	_, err := readResponse(strings.NewReader(`{"jsonrpc":"2.0","method":"progress"}`+"\n"), 1)
+	if err == nil || errors.Is(err, errEmptyResponse) {
+		t.Errorf("err = %v, want connection-closed error", err)
+	}
+}
+
 // TestQueryMetrics verifies the JSON-RPC request structure for health_metrics.
 func TestQueryMetrics(t *testing.T) {
 	// Server reads the request and echoes it back in the result for inspection.
@@ -126,7 +175,7 @@ func TestQueryMetrics(t *testing.T) {
 		conn.Write(respBytes) //nolint:errcheck
 	}()
 
-	client := NewHAEClient("127.0.0.1", port)
+	client := NewHAEClient("127.0.0.1", port, "")
 	client.timeout = 5 * time.Second
 
 	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
@@ -197,7 +246,7 @@ func TestQueryWorkouts(t *testing.T) {
 		conn.Write(respBytes) //nolint:errcheck
 	}()
 
-	client := NewHAEClient("127.0.0.1", port)
+	client := NewHAEClient("127.0.0.1", port, "")
 	client.timeout = 5 * time.Second
 
 	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
@@ -234,7 +283,7 @@ func TestQueryWorkouts(t *testing.T) {
 // TestConnectionRefused verifies that a connection error is returned gracefully.
 func TestConnectionRefused(t *testing.T) {
 	// Use a port that's guaranteed to be unused
-	client := NewHAEClient("127.0.0.1", 1)
+	client := NewHAEClient("127.0.0.1", 1, "")
 	client.timeout = 1 * time.Second
 
 	_, err := client.callTool("health_metrics", map[string]any{})
@@ -247,7 +296,7 @@ func TestConnectionRefused(t *testing.T) {
 func TestEmptyResponse(t *testing.T) {
 	port := startMockTCPServer(t, []byte{})
 
-	client := NewHAEClient("127.0.0.1", port)
+	client := NewHAEClient("127.0.0.1", port, "")
 	client.timeout = 5 * time.Second
 
 	_, err := client.callTool("health_metrics", map[string]any{})
@@ -301,3 +350,121 @@ func TestSyncState(t *testing.T) {
 	}
 }
 
+
+// TestServerInfo verifies listTools is called and an MCP-style result with
+// tool objects and a nested version is decoded.
+func TestServerInfo(t *testing.T) {
+	resp := jsonRPCResponse{
+		JSONRPC: "2.0",
+		ID:      1,
+		Result: json.RawMessage(`{"serverInfo":{"name":"HAE","version":"8.2.1"},
+			"tools":[{"name":"health_metrics","description":"..."},{"name":"workouts"}]}`),
+	}
+	respBytes, _ := json.Marshal(resp)
+	port := startMockTCPServer(t, respBytes)
+
+	info, err := NewHAEClient("127.0.0.1", port, "").ServerInfo()
+	if err != nil {
+		t.Fatalf("ServerInfo returned error: %v", err)
+	}
+	if info.Version != "8.2.1" {
+		t.Errorf("version = %q, want 8.2.1", info.Version)
+	}
+	if len(info.Tools) != 2 || info.Tools[0] != "health_metrics" || info.Tools[1] != "workouts" {
+		t.Errorf("tools = %v", info.Tools)
+	}
+	if m := info.MissingTools(); len(m) != 0 {
+		t.Errorf("missing = %v, want none", m)
+	}
+}
+
+// TestParseServerInfoStringTools verifies bare tool names and a top-level
+// version are accepted, and that a missing workouts tool is reported.
+func TestParseServerInfoStringTools(t *testing.T) {
+	info, err := parseServerInfo(json.RawMessage(`{"version":"7.0","tools":["health_metrics"]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.Version != "7.0" || len(info.Tools) != 1 {
+		t.Errorf("info = %+v", info)
+	}
+	if m := info.MissingTools(); len(m) != 1 || m[0] != "workouts" {
+		t.Errorf("missing = %v, want [workouts]", m)
+	}
+}
+
+// TestServerInfoUnsupported verifies a server without listTools surfaces an
+// error so callers can fall back to plain reachability.
+func TestServerInfoUnsupported(t *testing.T) {
+	resp := jsonRPCResponse{
+		JSONRPC: "2.0",
+		ID:      1,
+		Error:   &jsonRPCError{Code: -32601, Message: "Method not found"},
+	}
+	respBytes, _ := json.Marshal(resp)
+	port := startMockTCPServer(t, respBytes)
+
+	if _, err := NewHAEClient("127.0.0.1", port, "").ServerInfo(); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+// captureParams runs call against a one-shot mock server and returns the
+// params object of the request it received.
+func captureParams(t *testing.T, call func(port int)) map[string]any {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() }) //nolint:errcheck
+
+	var req struct {
+		Params map[string]any `json:"params"`
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close() //nolint:errcheck
+
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second)) //nolint:errcheck
+		json.NewDecoder(conn).Decode(&req)                    //nolint:errcheck
+
+		conn.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{"tools":[]}}`)) //nolint:errcheck
+	}()
+
+	call(ln.Addr().(*net.TCPAddr).Port)
+	<-done
+	return req.Params
+}
+
+// TestHAEClientToken verifies the token is sent in the params of callTool
+// and listTools requests, and omitted when the client has none.
+func TestHAEClientToken(t *testing.T) {
+	day := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	params := captureParams(t, func(port int) {
+		NewHAEClient("127.0.0.1", port, "s3cret").QueryWorkouts(day, day.AddDate(0, 0, 1)) //nolint:errcheck
+	})
+	if params["token"] != "s3cret" || params["name"] != "workouts" {
+		t.Errorf("callTool params = %v, want token s3cret", params)
+	}
+
+	params = captureParams(t, func(port int) {
+		NewHAEClient("127.0.0.1", port, "s3cret").ServerInfo() //nolint:errcheck
+	})
+	if params["token"] != "s3cret" {
+		t.Errorf("listTools params = %v, want token s3cret", params)
+	}
+
+	params = captureParams(t, func(port int) {
+		NewHAEClient("127.0.0.1", port, "").QueryWorkouts(day, day.AddDate(0, 0, 1)) //nolint:errcheck
+	})
+	if _, ok := params["token"]; ok {
+		t.Errorf("callTool params = %v, want no token", params)
+	}
+}
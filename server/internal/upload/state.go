@@ -49,6 +49,16 @@ func OpenStateDB(dir string) (*StateDB, error) {
 		return nil, fmt.Errorf("creating sync_state table: %w", err)
 	}
 
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS failed_attempts (
+		path     TEXT PRIMARY KEY,
+		hash     TEXT NOT NULL,
+		failures INTEGER NOT NULL
+	)`)
+	if err != nil {
+		db.Close() //nolint:errcheck
+		return nil, fmt.Errorf("creating failed_attempts table: %w", err)
+	}
+
 	return &StateDB{db: db}, nil
 }
 
@@ -74,6 +84,22 @@ func (s *StateDB) MarkUploaded(relPath string, size int64, hash string) error {
 	return err
 }
 
+// RecordFailedAttempt counts another failed upload of a file and returns how
+// many times this version of it (by hash) has failed. A changed file starts
+// again from one.
+func (s *StateDB) RecordFailedAttempt(relPath, hash string) (int, error) {
+	var failures int
+	err := s.db.QueryRow(
+		`INSERT INTO failed_attempts (path, hash, failures) VALUES (?, ?, 1)
+		 ON CONFLICT (path) DO UPDATE SET
+		   failures = CASE WHEN failed_attempts.hash = excluded.hash THEN failed_attempts.failures + 1 ELSE 1 END,
+		   hash     = excluded.hash
+		 RETURNING failures`,
+		relPath, hash,
+	).Scan(&failures)
+	return failures, err
+}
+
 // GetSyncState returns the value for a sync state key, or empty string if not found.
 func (s *StateDB) GetSyncState(key string) (string, error) {
 	var value string
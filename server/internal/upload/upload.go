@@ -9,9 +9,11 @@ import (
 	"os/exec"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/claude/freereps/internal/ingest"
 	"github.com/claude/freereps/internal/models"
 )
 
@@ -22,11 +24,15 @@ type Stats struct {
 	FilesSkipped  int
 	FilesErrored  int
 
-	MetricPointsSent   int
-	SleepStagesSent    int
-	WorkoutsSent       int
-	RoutePointsSent    int
-	HRPointsCorrelated int
+	MetricPointsSent       int
+	MetricPointsSkipped    int // malformed points dropped from partially-corrupt files
+	MetricPointsOutOfRange int // points with future or pre-2014 timestamps
+	SleepStagesSent        int
+	WorkoutsSent           int
+	WorkoutsUnconfirmed    int // sent but not confirmed stored; their files are retried next run
+	WorkoutsAbandoned      int // unconfirmed too many times; their files are no longer retried
+	RoutePointsSent        int
+	HRPointsCorrelated     int
 
 	RejectedMetrics []string
 
@@ -39,16 +45,26 @@ type Stats struct {
 // Uploader walks an AutoSync directory, converts .hae files to REST API format,
 // and POSTs them to the FreeReps server.
 type Uploader struct {
-	client    *Client
-	state     *StateDB
-	autoSync  string
-	dryRun    bool
-	batchSize int
-	log       *slog.Logger
-	stats     Stats
-	hrPoints  []hrDataPoint // collected during metric processing for workout HR correlation
+	client      *Client
+	state       *StateDB
+	autoSync    string
+	dryRun      bool
+	batchSize   int
+	log         *slog.Logger
+	stats       Stats
+	hrPoints    []hrDataPoint // collected during metric processing for workout HR correlation
+	hrTolerance time.Duration
+	bounds      ingest.TimeBounds
+
+	// maxWorkoutAttempts caps how often an unconfirmed workout is re-sent
+	// before its file is given up on (0 = retry forever).
+	maxWorkoutAttempts int
 }
 
+// DefaultMaxWorkoutAttempts is how many runs re-send a workout the server
+// does not confirm before giving up on it.
+const DefaultMaxWorkoutAttempts = 5
+
 // New creates a new Uploader.
 func New(client *Client, state *StateDB, autoSyncDir string, dryRun bool, batchSize int, log *slog.Logger) *Uploader {
 	return &Uploader{
@@ -58,9 +74,31 @@ func New(client *Client, state *StateDB, autoSyncDir string, dryRun bool, batchS
 		dryRun:    dryRun,
 		batchSize: batchSize,
 		log:       log,
+		bounds:    ingest.DefaultTimeBounds,
+
+		maxWorkoutAttempts: DefaultMaxWorkoutAttempts,
 	}
 }
 
+// SetTimeBounds configures the accepted timestamp range for metric data points.
+func (u *Uploader) SetTimeBounds(b ingest.TimeBounds) {
+	u.bounds = b
+}
+
+// SetHRTolerance widens the window used to match heart rate samples to a
+// workout by d on each side, catching samples the watch logged slightly
+// before the workout started or after it ended.
+func (u *Uploader) SetHRTolerance(d time.Duration) {
+	u.hrTolerance = d
+}
+
+// SetMaxWorkoutAttempts sets how many times a workout the server does not
+// confirm is sent before its file is marked uploaded anyway, so one workout
+// the server always skips isn't re-sent on every run. 0 retries forever.
+func (u *Uploader) SetMaxWorkoutAttempts(n int) {
+	u.maxWorkoutAttempts = n
+}
+
 // Run executes the upload pipeline.
 func (u *Uploader) Run() (*Stats, error) {
 	// Fetch allowlist from server (skip in dry-run — accept all metrics)
@@ -189,12 +227,23 @@ func (u *Uploader) processMetricDir(dir, metricName string) error {
 			continue
 		}
 
-		var file models.HAEFileMetric
-		if err := json.Unmarshal(data, &file); err != nil {
+		file, badPoints, err := parseMetricFile(data)
+		if err != nil {
 			u.log.Warn("parse failed", "file", f, "error", err)
 			u.stats.FilesErrored++
 			continue
 		}
+		if badPoints > 0 {
+			u.log.Warn("skipped malformed data points", "file", f, "skipped", badPoints, "kept", len(file.Data))
+			u.stats.MetricPointsSkipped += badPoints
+		}
+
+		var outOfRange int
+		file.Data, outOfRange = filterTimeBounds(file.Data, u.bounds)
+		if outOfRange > 0 {
+			u.log.Warn("rejected out-of-range data points", "file", f, "rejected", outOfRange)
+			u.stats.MetricPointsOutOfRange += outOfRange
+		}
 
 		if len(file.Data) == 0 {
 			u.stats.FilesSkipped++
@@ -280,6 +329,88 @@ func (u *Uploader) processMetricDir(dir, metricName string) error {
 	return nil
 }
 
+// parseMetricFile decodes a .hae metric file. If the file doesn't parse as a
+// whole (e.g. truncated by an interrupted sync, or one point with a bad field),
+// it falls back to decoding data points one at a time, keeping the good ones.
+// Returns the number of points that had to be dropped.
+func parseMetricFile(data []byte) (models.HAEFileMetric, int, error) {
+	var file models.HAEFileMetric
+	wholeErr := json.Unmarshal(data, &file)
+	if wholeErr == nil {
+		return file, 0, nil
+	}
+
+	file = models.HAEFileMetric{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return file, 0, wholeErr
+	}
+
+	skipped := 0
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch key, _ := tok.(string); key {
+		case "metric":
+			_ = dec.Decode(&file.Metric)
+		case "date":
+			_ = dec.Decode(&file.Date)
+		case "data":
+			n, err := decodeDataPoints(dec, &file.Data)
+			skipped += n
+			if err != nil {
+				// Syntax error: the rest of the stream is unusable.
+				if len(file.Data) == 0 {
+					return file, skipped, wholeErr
+				}
+				return file, skipped, nil
+			}
+		default:
+			var discard json.RawMessage
+			if err := dec.Decode(&discard); err != nil {
+				return file, skipped, wholeErr
+			}
+		}
+	}
+
+	if len(file.Data) == 0 && skipped == 0 {
+		return file, 0, wholeErr
+	}
+	return file, skipped, nil
+}
+
+// decodeDataPoints reads a JSON array of data points from dec, appending each
+// well-formed point to out. Points with type errors are skipped and counted.
+// A syntax error (truncation) stops decoding and counts one lost point.
+func decodeDataPoints(dec *json.Decoder, out *[]models.HAEFileDataPoint) (int, error) {
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		if err == nil {
+			err = fmt.Errorf("data is not an array")
+		}
+		return 0, err
+	}
+
+	skipped := 0
+	for dec.More() {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return skipped + 1, err
+		}
+		var dp models.HAEFileDataPoint
+		if err := json.Unmarshal(raw, &dp); err != nil {
+			skipped++
+			continue
+		}
+		*out = append(*out, dp)
+	}
+	if _, err := dec.Token(); err != nil { // closing ]
+		return skipped, err
+	}
+	return skipped, nil
+}
+
 // processWorkouts walks Workouts/ and Routes/, converts and uploads them.
 func (u *Uploader) processWorkouts(workoutDir, routeDir string) error {
 	files, err := filepath.Glob(filepath.Join(workoutDir, "*.hae"))
@@ -352,7 +483,7 @@ func (u *Uploader) processWorkouts(workoutDir, routeDir string) error {
 		}
 
 		// Convert with route + HR correlation
-		workout := convertWorkout(fileWorkout, route, u.hrPoints)
+		workout := convertWorkout(fileWorkout, route, u.hrPoints, u.hrTolerance)
 
 		if route != nil {
 			u.stats.RoutePointsSent += len(workout.Route)
@@ -382,7 +513,11 @@ func (u *Uploader) processWorkouts(workoutDir, routeDir string) error {
 	return nil
 }
 
-// sendWorkoutBatch sends a batch of workouts and marks their files as uploaded.
+// sendWorkoutBatch sends a batch of workouts and marks the files of those the
+// server confirms stored as uploaded. Unconfirmed workouts (e.g. skipped
+// server-side) keep their files pending, so the next run sends them again
+// instead of silently leaving them out, until they have failed
+// maxWorkoutAttempts times.
 func (u *Uploader) sendWorkoutBatch(workouts []models.HealthWorkout, files []fileInfo) error {
 	payload := models.HealthPayload{
 		Data: models.HealthData{
@@ -390,26 +525,77 @@ func (u *Uploader) sendWorkoutBatch(workouts []models.HealthWorkout, files []fil
 		},
 	}
 
+	confirmed := make(map[string]bool, len(workouts))
 	if u.dryRun {
 		u.log.Info("dry-run: would send workouts", "count", len(workouts))
+		for _, w := range workouts {
+			confirmed[w.ID] = true
+		}
 	} else {
-		if err := u.client.SendPayload(payload); err != nil {
+		result, err := u.client.SendPayloadResult(payload)
+		if err != nil {
 			return fmt.Errorf("sending workout batch: %w", err)
 		}
+		confirmed = confirmedWorkouts(workouts, result)
 	}
 
 	u.stats.WorkoutsSent += len(workouts)
 
-	for _, fi := range files {
+	for i, fi := range files {
+		if !confirmed[workouts[i].ID] {
+			if !u.giveUp(fi, workouts[i].ID) {
+				u.log.Warn("workout not confirmed by server, will retry", "file", fi.relPath, "workout", workouts[i].ID)
+				u.stats.WorkoutsUnconfirmed++
+				continue
+			}
+			u.log.Warn("workout not confirmed by server, giving up", "file", fi.relPath, "workout", workouts[i].ID,
+				"attempts", u.maxWorkoutAttempts)
+			u.stats.WorkoutsAbandoned++
+		} else {
+			u.stats.FilesUploaded++
+		}
 		if err := u.state.MarkUploaded(fi.relPath, fi.size, fi.hash); err != nil {
 			u.log.Warn("failed to mark uploaded", "file", fi.relPath, "error", err)
 		}
-		u.stats.FilesUploaded++
 	}
 
 	return nil
 }
 
+// confirmedWorkouts returns the IDs of workouts the server reports stored.
+// Servers predating workout_ids only report counts: the whole batch counts
+// as confirmed when every workout was received and inserted, and none
+// otherwise, since the counts don't say which ones were left out.
+func confirmedWorkouts(workouts []models.HealthWorkout, result *ingest.Result) map[string]bool {
+	confirmed := make(map[string]bool, len(workouts))
+	if len(result.WorkoutIDs) > 0 {
+		for _, id := range result.WorkoutIDs {
+			confirmed[id] = true
+		}
+		return confirmed
+	}
+	if result.WorkoutsReceived == len(workouts) && result.WorkoutsInserted == len(workouts) {
+		for _, w := range workouts {
+			confirmed[w.ID] = true
+		}
+	}
+	return confirmed
+}
+
+// giveUp records a failed attempt for an unconfirmed workout's file and
+// reports whether it has now failed maxWorkoutAttempts times.
+func (u *Uploader) giveUp(fi fileInfo, workoutID string) bool {
+	if u.maxWorkoutAttempts <= 0 {
+		return false
+	}
+	failures, err := u.state.RecordFailedAttempt(fi.relPath, fi.hash)
+	if err != nil {
+		u.log.Warn("failed to record failed attempt", "file", fi.relPath, "workout", workoutID, "error", err)
+		return false
+	}
+	return failures >= u.maxWorkoutAttempts
+}
+
 // TCPMetric defines a metric to query from the HAE server.
 type TCPMetric struct {
 	Name      string
@@ -435,24 +621,124 @@ var TCPMetrics = []TCPMetric{
 	{Name: "apple_exercise_time", Aggregate: true},
 }
 
-// RunTCP queries the HAE TCP server for health data and forwards it to FreeReps.
-// It processes metrics individually (one per request) and workouts in time-range chunks.
-func (u *Uploader) RunTCP(haeHost string, haePort int, start, end time.Time, chunkDays int) (*Stats, error) {
-	hae := NewHAEClient(haeHost, haePort)
-	chunkDur := time.Duration(chunkDays) * 24 * time.Hour
+// TCPOptions controls how a TCP import paces its queries, so users can tune
+// around a fragile HAE server.
+type TCPOptions struct {
+	ChunkDays int // days per query
+
+	// MetricChunkDays overrides ChunkDays per metric; some metrics (e.g.
+	// heart_rate) are heavy per day while daily aggregates are light.
+	MetricChunkDays map[string]int
+
+	// RequestDelay is waited between consecutive HAE queries.
+	RequestDelay time.Duration
+
+	// Token authenticates requests to an HAE server that requires one.
+	Token string
+}
+
+// ChunkDaysFor returns the chunk size for metric, honoring overrides.
+func (o TCPOptions) ChunkDaysFor(metric string) int {
+	if d, ok := o.MetricChunkDays[metric]; ok {
+		return d
+	}
+	return o.ChunkDays
+}
+
+// Validate rejects non-positive chunk sizes, overrides for metrics that
+// aren't queried, and a negative delay.
+func (o TCPOptions) Validate() error {
+	if o.ChunkDays <= 0 {
+		return fmt.Errorf("chunk days must be positive")
+	}
+	for name, d := range o.MetricChunkDays {
+		if !isTCPMetric(name) {
+			return fmt.Errorf("unknown metric %q in chunk overrides", name)
+		}
+		if d <= 0 {
+			return fmt.Errorf("chunk days for %s must be positive", name)
+		}
+	}
+	if o.RequestDelay < 0 {
+		return fmt.Errorf("request delay must not be negative")
+	}
+	return nil
+}
+
+// TotalRequests returns how many HAE queries an import of [start, end) makes:
+// one per metric chunk plus one per workout chunk.
+func (o TCPOptions) TotalRequests(start, end time.Time) int {
+	total := CountChunks(start, end, o.ChunkDays) // workouts
+	for _, m := range TCPMetrics {
+		total += CountChunks(start, end, o.ChunkDaysFor(m.Name))
+	}
+	return total
+}
 
-	// Count total chunks for progress display
-	numChunks := 0
+// CountChunks returns how many chunks of chunkDays cover [start, end).
+func CountChunks(start, end time.Time, chunkDays int) int {
+	chunkDur := time.Duration(chunkDays) * 24 * time.Hour
+	n := 0
 	for cs := start; cs.Before(end); cs = cs.Add(chunkDur) {
-		numChunks++
+		n++
 	}
-	totalSteps := len(TCPMetrics)*numChunks + numChunks // metrics + workouts
+	return n
+}
+
+// ParseMetricChunkDays parses a comma-separated list of metric=days pairs,
+// e.g. "heart_rate=1,weight_body_mass=30".
+func ParseMetricChunkDays(s string) (map[string]int, error) {
+	out := make(map[string]int)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, days, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid chunk override %q (want metric=days)", pair)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(days))
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk days in %q: %w", pair, err)
+		}
+		out[strings.TrimSpace(name)] = n
+	}
+	return out, nil
+}
+
+func isTCPMetric(name string) bool {
+	for _, m := range TCPMetrics {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// RunTCP queries the HAE TCP server for health data and forwards it to FreeReps.
+// It processes metrics individually (one per request) and workouts in time-range chunks.
+func (u *Uploader) RunTCP(haeHost string, haePort int, start, end time.Time, opts TCPOptions) (*Stats, error) {
+	if err := opts.Validate(); err != nil {
+		return &u.stats, err
+	}
+	hae := NewHAEClient(haeHost, haePort, opts.Token)
+
+	totalSteps := opts.TotalRequests(start, end)
 	currentStep := 0
 
+	// pace waits RequestDelay before every query but the first.
+	pace := func() {
+		if currentStep > 1 && opts.RequestDelay > 0 {
+			time.Sleep(opts.RequestDelay)
+		}
+	}
+
 	// Phase 1: Health metrics — query each metric individually
-	u.log.Info("querying health metrics", "start", start.Format("2006-01-02"), "end", end.Format("2006-01-02"), "chunk_days", chunkDays, "metrics", len(TCPMetrics), "total_requests", totalSteps)
+	u.log.Info("querying health metrics", "start", start.Format("2006-01-02"), "end", end.Format("2006-01-02"), "chunk_days", opts.ChunkDays, "metrics", len(TCPMetrics), "total_requests", totalSteps)
 
 	for _, m := range TCPMetrics {
+		chunkDur := time.Duration(opts.ChunkDaysFor(m.Name)) * 24 * time.Hour
 		for chunkStart := start; chunkStart.Before(end); chunkStart = chunkStart.Add(chunkDur) {
 			chunkEnd := chunkStart.Add(chunkDur)
 			if chunkEnd.After(end) {
@@ -464,7 +750,7 @@ func (u *Uploader) RunTCP(haeHost string, haePort int, start, end time.Time, chu
 				currentStep, totalSteps, m.Name,
 				chunkStart.Format("2006-01-02"), chunkEnd.Format("2006-01-02"))
 
-
+			pace()
 			result, err := hae.QueryMetricsWithRetry(chunkStart, chunkEnd, m.Name, m.Aggregate, u.log)
 			if err != nil {
 				u.log.Warn("failed to query metric, skipping",
@@ -495,6 +781,7 @@ func (u *Uploader) RunTCP(haeHost string, haePort int, start, end time.Time, chu
 	}
 
 	// Phase 2: Workouts
+	chunkDur := time.Duration(opts.ChunkDays) * 24 * time.Hour
 	for chunkStart := start; chunkStart.Before(end); chunkStart = chunkStart.Add(chunkDur) {
 		chunkEnd := chunkStart.Add(chunkDur)
 		if chunkEnd.After(end) {
@@ -507,7 +794,7 @@ func (u *Uploader) RunTCP(haeHost string, haePort int, start, end time.Time, chu
 			currentStep, totalSteps,
 			chunkStart.Format("2006-01-02"), chunkEnd.Format("2006-01-02"))
 
-
+		pace()
 		result, err := hae.QueryWorkoutsWithRetry(chunkStart, chunkEnd, u.log)
 		if err != nil {
 			u.log.Warn("failed to query workouts, skipping",
@@ -0,0 +1,239 @@
+package upload
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/claude/freereps/internal/models"
+)
+
+// TestParseMetricFileSkipsBadPoint verifies that a single malformed data point
+// is dropped and counted while the rest of the file is still uploaded.
+func TestParseMetricFileSkipsBadPoint(t *testing.T) {
+	data := []byte(`{"metric":"weight_body_mass","date":700000000,"data":[
+		{"start":700000000,"end":700000000,"qty":80.1},
+		{"start":"not-a-number","end":700000100,"qty":80.2},
+		{"start":700000200,"end":700000200,"qty":80.3}
+	]}`)
+
+	file, skipped, err := parseMetricFile(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if skipped != 1 {
+		t.Errorf("skipped = %d, want 1", skipped)
+	}
+	if len(file.Data) != 2 {
+		t.Fatalf("data points = %d, want 2", len(file.Data))
+	}
+	if *file.Data[1].Qty != 80.3 {
+		t.Errorf("second kept point qty = %v, want 80.3", *file.Data[1].Qty)
+	}
+	if file.Metric != "weight_body_mass" {
+		t.Errorf("metric = %q, want weight_body_mass", file.Metric)
+	}
+}
+
+// TestParseMetricFileTruncated verifies that points before a sync-interrupted
+// truncation are salvaged instead of failing the whole file.
+func TestParseMetricFileTruncated(t *testing.T) {
+	data := []byte(`{"metric":"step_count","data":[{"start":700000000,"end":700000060,"qty":10},{"start":700000060,"end":7000`)
+
+	file, skipped, err := parseMetricFile(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(file.Data) != 1 {
+		t.Fatalf("data points = %d, want 1", len(file.Data))
+	}
+	if skipped != 1 {
+		t.Errorf("skipped = %d, want 1", skipped)
+	}
+}
+
+// TestParseMetricFileValid verifies well-formed files take the fast path
+// with nothing skipped.
+func TestParseMetricFileValid(t *testing.T) {
+	file, skipped, err := parseMetricFile([]byte(`{"metric":"x","data":[{"start":1,"end":2,"qty":3}]}`))
+	if err != nil || skipped != 0 || len(file.Data) != 1 {
+		t.Errorf("got (%d points, %d skipped, %v), want (1, 0, nil)", len(file.Data), skipped, err)
+	}
+}
+
+// TestParseMetricFileGarbage verifies that a file with nothing salvageable
+// still reports an error so it's counted in FilesErrored.
+func TestParseMetricFileGarbage(t *testing.T) {
+	if _, _, err := parseMetricFile([]byte(`not json at all`)); err == nil {
+		t.Error("expected error for garbage input")
+	}
+	if _, _, err := parseMetricFile([]byte(`{"metric":"x","data":[{"sta`)); err == nil {
+		t.Error("expected error when no points could be salvaged")
+	}
+}
+
+// TestTCPOptions verifies per-metric chunk overrides change the request count
+// and that invalid overrides are rejected.
+func TestTCPOptions(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 14)
+
+	opts := TCPOptions{ChunkDays: 7}
+	if got, want := opts.TotalRequests(start, end), 2*(len(TCPMetrics)+1); got != want {
+		t.Errorf("requests = %d, want %d", got, want)
+	}
+
+	opts.MetricChunkDays = map[string]int{"heart_rate": 1}
+	if opts.ChunkDaysFor("heart_rate") != 1 || opts.ChunkDaysFor("vo2_max") != 7 {
+		t.Errorf("chunk days = %d/%d, want 1/7", opts.ChunkDaysFor("heart_rate"), opts.ChunkDaysFor("vo2_max"))
+	}
+	if got, want := opts.TotalRequests(start, end), 2*len(TCPMetrics)+14; got != want {
+		t.Errorf("requests with override = %d, want %d", got, want)
+	}
+	if err := opts.Validate(); err != nil {
+		t.Errorf("valid options: %v", err)
+	}
+
+	for _, bad := range []TCPOptions{
+		{ChunkDays: 0},
+		{ChunkDays: 7, MetricChunkDays: map[string]int{"no_such_metric": 1}},
+		{ChunkDays: 7, MetricChunkDays: map[string]int{"heart_rate": 0}},
+		{ChunkDays: 7, RequestDelay: -time.Second},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v: expected error", bad)
+		}
+	}
+}
+
+// TestParseMetricChunkDays verifies the -metric-chunk-days flag format.
+func TestParseMetricChunkDays(t *testing.T) {
+	got, err := ParseMetricChunkDays("heart_rate=1, weight_body_mass = 30,")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got["heart_rate"] != 1 || got["weight_body_mass"] != 30 {
+		t.Errorf("got %v", got)
+	}
+	if got, err := ParseMetricChunkDays(""); err != nil || len(got) != 0 {
+		t.Errorf("empty flag = %v, %v; want no overrides", got, err)
+	}
+	for _, bad := range []string{"heart_rate", "heart_rate=x"} {
+		if _, err := ParseMetricChunkDays(bad); err == nil {
+			t.Errorf("%q: expected error", bad)
+		}
+	}
+}
+
+// TestSendWorkoutBatchPartial verifies that when the server confirms only 3
+// of 5 workouts, only those files are marked uploaded and the other two stay
+// pending for the next run.
+func TestSendWorkoutBatchPartial(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload models.HealthPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("decoding payload: %v", err)
+		}
+		var ids []string
+		for _, wo := range payload.Data.Workouts[:3] {
+			ids = append(ids, wo.ID)
+		}
+		json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
+			"workouts_received": len(payload.Data.Workouts),
+			"workouts_inserted": 3,
+			"workout_ids":       ids,
+		})
+	}))
+	defer srv.Close()
+
+	state, err := OpenStateDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer state.Close() //nolint:errcheck
+
+	u := New(NewClient(srv.URL), state, t.TempDir(), false, 100, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	var workouts []models.HealthWorkout
+	var files []fileInfo
+	for i := range 5 {
+		workouts = append(workouts, models.HealthWorkout{ID: fmt.Sprintf("00000000-0000-0000-0000-00000000000%d", i)})
+		files = append(files, fileInfo{relPath: fmt.Sprintf("Workouts/%d.hae", i), size: 10, hash: fmt.Sprint(i)})
+	}
+	if err := u.sendWorkoutBatch(workouts, files); err != nil {
+		t.Fatalf("sendWorkoutBatch: %v", err)
+	}
+
+	for i, fi := range files {
+		uploaded, err := state.IsUploaded(fi.relPath, fi.size, fi.hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := i < 3; uploaded != want {
+			t.Errorf("%s uploaded = %v, want %v", fi.relPath, uploaded, want)
+		}
+	}
+	if u.stats.FilesUploaded != 3 || u.stats.WorkoutsUnconfirmed != 2 || u.stats.WorkoutsSent != 5 {
+		t.Errorf("stats = %d uploaded, %d unconfirmed, %d sent; want 3, 2, 5",
+			u.stats.FilesUploaded, u.stats.WorkoutsUnconfirmed, u.stats.WorkoutsSent)
+	}
+}
+
+// TestSendWorkoutBatchFallbackAndGiveUp verifies a response without
+// workout_ids confirms the batch only when every workout was inserted, and
+// that a workout left unconfirmed keeps its file pending until it has failed
+// maxWorkoutAttempts times.
+func TestSendWorkoutBatchFallbackAndGiveUp(t *testing.T) {
+	inserted := 2
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
+			"workouts_received": 2,
+			"workouts_inserted": inserted,
+		})
+	}))
+	defer srv.Close()
+
+	state, err := OpenStateDB(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer state.Close() //nolint:errcheck
+
+	u := New(NewClient(srv.URL), state, t.TempDir(), false, 100, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	u.SetMaxWorkoutAttempts(2)
+	workouts := []models.HealthWorkout{{ID: "a"}, {ID: "b"}}
+	files := []fileInfo{{relPath: "Workouts/a.hae", size: 1, hash: "a"}, {relPath: "Workouts/b.hae", size: 1, hash: "b"}}
+	uploaded := func(fi fileInfo) bool {
+		ok, err := state.IsUploaded(fi.relPath, fi.size, fi.hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ok
+	}
+
+	if err := u.sendWorkoutBatch(workouts, files); err != nil {
+		t.Fatalf("sendWorkoutBatch: %v", err)
+	}
+	if u.stats.FilesUploaded != 2 || !uploaded(files[0]) || !uploaded(files[1]) {
+		t.Fatalf("all inserted: %d files uploaded, want 2", u.stats.FilesUploaded)
+	}
+
+	inserted = 1
+	files = []fileInfo{{relPath: "Workouts/a.hae", size: 2, hash: "a2"}, {relPath: "Workouts/b.hae", size: 2, hash: "b2"}}
+	if err := u.sendWorkoutBatch(workouts, files); err != nil {
+		t.Fatalf("sendWorkoutBatch: %v", err)
+	}
+	if u.stats.WorkoutsUnconfirmed != 2 || uploaded(files[0]) {
+		t.Fatalf("partial insert: %d unconfirmed, want 2 pending", u.stats.WorkoutsUnconfirmed)
+	}
+	if err := u.sendWorkoutBatch(workouts, files); err != nil {
+		t.Fatalf("sendWorkoutBatch: %v", err)
+	}
+	if u.stats.WorkoutsAbandoned != 2 || !uploaded(files[0]) || !uploaded(files[1]) {
+		t.Errorf("second failure: %d abandoned, want 2 and both files marked", u.stats.WorkoutsAbandoned)
+	}
+}
@@ -0,0 +1,2 @@
+ALTER TABLE health_metrics DROP COLUMN IF EXISTS raw_qty;
+ALTER TABLE health_metrics DROP COLUMN IF EXISTS raw_units;
@@ -0,0 +1,4 @@
+-- Preserve the as-received unit and value when ingest converts a metric to
+-- its canonical unit (e.g. lb → kg). NULL when no conversion was applied.
+ALTER TABLE health_metrics ADD COLUMN IF NOT EXISTS raw_units TEXT;
+ALTER TABLE health_metrics ADD COLUMN IF NOT EXISTS raw_qty DOUBLE PRECISION;
@@ -0,0 +1,3 @@
+ALTER TABLE workouts DROP COLUMN IF EXISTS humidity;
+ALTER TABLE workouts DROP COLUMN IF EXISTS temperature_units;
+ALTER TABLE workouts DROP COLUMN IF EXISTS temperature;
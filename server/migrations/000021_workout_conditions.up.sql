@@ -0,0 +1,5 @@
+-- Environmental conditions reported with outdoor workouts. Previously these
+-- were dropped on ingest because the workout model didn't include them.
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS temperature DOUBLE PRECISION;
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS temperature_units TEXT;
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS humidity DOUBLE PRECISION;
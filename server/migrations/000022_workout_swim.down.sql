@@ -0,0 +1,3 @@
+ALTER TABLE workouts DROP COLUMN IF EXISTS lap_count;
+ALTER TABLE workouts DROP COLUMN IF EXISTS total_strokes;
+ALTER TABLE workouts DROP COLUMN IF EXISTS pool_length;
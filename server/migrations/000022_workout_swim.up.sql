@@ -0,0 +1,5 @@
+-- Swim-specific workout fields. Pool length is stored in metres regardless of
+-- the unit the pool was configured in, so pace/SWOLF compare across pools.
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS pool_length DOUBLE PRECISION;
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS total_strokes INTEGER;
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS lap_count INTEGER;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS metric_rollup_watermarks;
+DROP TABLE IF EXISTS health_metric_rollups;
@@ -0,0 +1,26 @@
+-- Downsampled health_metrics for data past its retention age (see
+-- storage.ApplyRetention). One row per user/metric/source/bucket; qty is the
+-- SUM for cumulative metrics and the mean otherwise, with sample_count kept so
+-- means can be re-weighted when buckets are combined.
+CREATE TABLE IF NOT EXISTS health_metric_rollups (
+    time         TIMESTAMPTZ      NOT NULL,
+    user_id      INTEGER          NOT NULL,
+    metric_name  TEXT             NOT NULL,
+    source       TEXT             NOT NULL DEFAULT '',
+    resolution   TEXT             NOT NULL,
+    units        TEXT,
+    qty          DOUBLE PRECISION,
+    min_val      DOUBLE PRECISION,
+    max_val      DOUBLE PRECISION,
+    sample_count INTEGER          NOT NULL,
+    PRIMARY KEY (user_id, metric_name, source, resolution, time)
+);
+
+-- How far each metric has been rolled up. Time-series queries read rollups
+-- before covered_until and raw rows from there on, so raw rows kept after
+-- downsampling are never double-counted.
+CREATE TABLE IF NOT EXISTS metric_rollup_watermarks (
+    metric_name   TEXT        PRIMARY KEY,
+    resolution    TEXT        NOT NULL,
+    covered_until TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,3 @@
+-- Revert 000024_workout_sets_session_key.
+DROP INDEX IF EXISTS idx_workout_sets_session_key;
+ALTER TABLE workout_sets DROP COLUMN IF EXISTS session_key;
@@ -0,0 +1,18 @@
+-- Deterministic key for an Alpha Progression session: hex SHA-256 of
+-- "name|date|duration" with the date in UTC RFC 3339 (see models.AlphaSession.Key).
+-- Re-uploading the same export then conflicts on every set instead of
+-- inserting a second copy of the session.
+ALTER TABLE workout_sets ADD COLUMN IF NOT EXISTS session_key TEXT;
+
+UPDATE workout_sets
+SET session_key = encode(sha256(convert_to(
+        session_name || '|' ||
+        to_char(session_date AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"') || '|' ||
+        COALESCE(session_duration, ''),
+    'UTF8')), 'hex')
+WHERE session_key IS NULL;
+
+ALTER TABLE workout_sets ALTER COLUMN session_key SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workout_sets_session_key
+    ON workout_sets (user_id, session_key, exercise_number, set_number, is_warmup);
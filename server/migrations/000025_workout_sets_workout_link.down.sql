@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_workout_sets_workout_id;
+ALTER TABLE workout_sets DROP COLUMN IF EXISTS workout_id;
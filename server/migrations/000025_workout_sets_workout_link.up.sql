@@ -0,0 +1,7 @@
+-- Link Alpha Progression sets to the Apple/Oura workout recorded for the same
+-- session (nearest start time within ingest.alpha_link_window). NULL when no
+-- workout was found; deleting the workout unlinks rather than deletes the sets.
+ALTER TABLE workout_sets ADD COLUMN IF NOT EXISTS workout_id UUID REFERENCES workouts(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_workout_sets_workout_id
+    ON workout_sets (workout_id) WHERE workout_id IS NOT NULL;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS workout_hr_recovery;
@@ -0,0 +1,17 @@
+-- Heart-rate recovery samples HAE reports for the minutes after a workout
+-- ends. Same shape as workout_heart_rate; kept separate so in-workout HR
+-- summaries aren't skewed by the cool-down.
+CREATE TABLE IF NOT EXISTS workout_hr_recovery (
+    time       TIMESTAMPTZ      NOT NULL,
+    workout_id UUID             NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+    user_id    INTEGER          NOT NULL,
+    min_bpm    DOUBLE PRECISION,
+    avg_bpm    DOUBLE PRECISION,
+    max_bpm    DOUBLE PRECISION,
+    source     TEXT             NOT NULL DEFAULT ''
+);
+
+SELECT create_hypertable('workout_hr_recovery', 'time', if_not_exists => TRUE);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workout_hr_recovery_dedup
+    ON workout_hr_recovery (time, workout_id, user_id);
@@ -0,0 +1,2 @@
+ALTER TABLE workout_routes DROP COLUMN IF EXISTS speed_accuracy;
+ALTER TABLE workout_routes DROP COLUMN IF EXISTS course_accuracy;
@@ -0,0 +1,4 @@
+-- Course and speed accuracy of each GPS point, for filtering bad points when
+-- recomputing distance and elevation. NULL for points imported before this.
+ALTER TABLE workout_routes ADD COLUMN IF NOT EXISTS course_accuracy DOUBLE PRECISION;
+ALTER TABLE workout_routes ADD COLUMN IF NOT EXISTS speed_accuracy DOUBLE PRECISION;
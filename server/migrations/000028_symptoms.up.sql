@@ -0,0 +1,15 @@
+-- Symptom logs (headache, fatigue, ...) from Health Auto Export's "symptoms"
+-- array. They carry a severity label rather than a quantity, so they don't
+-- fit health_metrics. HAE sends no sample ID; name + start + source is unique.
+CREATE TABLE IF NOT EXISTS symptoms (
+    user_id      INTEGER     NOT NULL,
+    name         TEXT        NOT NULL,
+    severity     TEXT        NOT NULL DEFAULT '',
+    start_time   TIMESTAMPTZ NOT NULL,
+    end_time     TIMESTAMPTZ,
+    user_entered BOOLEAN     NOT NULL DEFAULT false,
+    source       TEXT        NOT NULL DEFAULT '',
+    PRIMARY KEY (user_id, name, start_time, source)
+);
+
+CREATE INDEX IF NOT EXISTS idx_symptoms_user_time ON symptoms (user_id, start_time DESC);
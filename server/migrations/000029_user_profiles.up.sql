@@ -0,0 +1,7 @@
+-- Optional per-user demographics. Only used to pick the age/sex row of the
+-- population norm tables; nothing else depends on a profile existing.
+CREATE TABLE IF NOT EXISTS user_profiles (
+    user_id    INTEGER PRIMARY KEY,
+    birth_date DATE,
+    sex        TEXT CHECK (sex IN ('male', 'female'))
+);
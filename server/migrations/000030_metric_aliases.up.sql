@@ -0,0 +1,23 @@
+-- Alternate names for allowlisted metrics. Ingest rewrites an aliased name to
+-- its canonical metric before the allowlist check, so a source renaming a
+-- metric keeps landing in the existing series instead of being rejected.
+CREATE TABLE IF NOT EXISTS metric_aliases (
+    alias       TEXT PRIMARY KEY,
+    metric_name TEXT NOT NULL REFERENCES metric_allowlist (metric_name) ON DELETE CASCADE
+);
+
+-- Spellings seen from older Health Auto Export versions and HealthKit
+-- identifiers exported verbatim.
+INSERT INTO metric_aliases (alias, metric_name) VALUES
+    ('walking_heart_rate',              'walking_heart_rate_average'),
+    ('heart_rate_variability_sdnn',     'heart_rate_variability'),
+    ('oxygen_saturation',               'blood_oxygen_saturation'),
+    ('resting_energy',                  'basal_energy_burned'),
+    ('active_energy_burned',            'active_energy'),
+    ('body_mass',                       'weight_body_mass'),
+    ('walking_running_distance',        'distance_walking_running'),
+    ('sleeping_wrist_temperature',      'apple_sleeping_wrist_temperature'),
+    ('exercise_time',                   'apple_exercise_time'),
+    ('stand_time',                      'apple_stand_time'),
+    ('vo2max',                          'vo2_max')
+ON CONFLICT (alias) DO NOTHING;
@@ -0,0 +1,2 @@
+-- User annotations on workouts, set through PATCH /api/v1/workouts/{id}.
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS notes TEXT NOT NULL DEFAULT '';
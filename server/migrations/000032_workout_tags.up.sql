@@ -0,0 +1,9 @@
+-- User-defined labels on workouts (e.g. "race", "long-run"), independent of
+-- the imported workout type. Tags are stored normalized to lower case.
+CREATE TABLE IF NOT EXISTS workout_tags (
+    user_id    INTEGER NOT NULL,
+    workout_id UUID    NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+    tag        TEXT    NOT NULL,
+    PRIMARY KEY (user_id, workout_id, tag)
+);
+CREATE INDEX IF NOT EXISTS idx_workout_tags_tag ON workout_tags (user_id, tag);
@@ -0,0 +1 @@
+ALTER TABLE workouts DROP COLUMN IF EXISTS moving_time_sec;
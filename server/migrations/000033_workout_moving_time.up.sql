@@ -0,0 +1,3 @@
+-- Time spent moving during GPS workouts, computed from workout_routes. NULL
+-- for workouts without a route; pace falls back to duration_sec then.
+ALTER TABLE workouts ADD COLUMN IF NOT EXISTS moving_time_sec DOUBLE PRECISION;
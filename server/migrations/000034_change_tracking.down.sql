@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS health_metrics_touch ON health_metrics;
+DROP TRIGGER IF EXISTS workouts_touch ON workouts;
+DROP TRIGGER IF EXISTS sleep_sessions_touch ON sleep_sessions;
+DROP FUNCTION IF EXISTS touch_updated_at();
+ALTER TABLE health_metrics DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE workouts DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE sleep_sessions DROP COLUMN IF EXISTS updated_at;
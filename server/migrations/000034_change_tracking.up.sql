@@ -0,0 +1,23 @@
+-- Row change times for the /api/v1/changes sync feed. Existing rows get the
+-- migration time; inserts take NOW() and the trigger bumps it on update.
+ALTER TABLE health_metrics ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE workouts       ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+ALTER TABLE sleep_sessions ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX IF NOT EXISTS idx_health_metrics_updated ON health_metrics (user_id, updated_at);
+CREATE INDEX IF NOT EXISTS idx_workouts_updated       ON workouts (user_id, updated_at);
+CREATE INDEX IF NOT EXISTS idx_sleep_sessions_updated ON sleep_sessions (user_id, updated_at);
+
+CREATE OR REPLACE FUNCTION touch_updated_at() RETURNS trigger AS $$
+BEGIN
+    NEW.updated_at := NOW();
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER health_metrics_touch BEFORE UPDATE ON health_metrics
+    FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+CREATE TRIGGER workouts_touch BEFORE UPDATE ON workouts
+    FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
+CREATE TRIGGER sleep_sessions_touch BEFORE UPDATE ON sleep_sessions
+    FOR EACH ROW EXECUTE FUNCTION touch_updated_at();
@@ -0,0 +1 @@
+DELETE FROM metric_allowlist WHERE category = 'garmin';
@@ -0,0 +1,7 @@
+-- Garmin FIT import: stress samples from monitoring files. Resting heart
+-- rate reuses the existing resting_heart_rate metric.
+INSERT INTO metric_allowlist (metric_name, category) VALUES
+    ('garmin_stress_level', 'garmin')
+ON CONFLICT (metric_name) DO NOTHING;
+
+UPDATE metric_allowlist SET display_label = 'Stress', display_unit = 'score' WHERE metric_name = 'garmin_stress_level';
@@ -0,0 +1,2 @@
+ALTER TABLE user_profiles DROP COLUMN IF EXISTS ftp_watts;
+DROP TABLE IF EXISTS workout_power;
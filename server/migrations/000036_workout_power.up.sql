@@ -0,0 +1,16 @@
+-- Power meter samples recorded during workouts (watts), and the user's
+-- functional threshold power for intensity factor.
+CREATE TABLE IF NOT EXISTS workout_power (
+    time       TIMESTAMPTZ      NOT NULL,
+    workout_id UUID             NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+    user_id    INTEGER          NOT NULL,
+    watts      DOUBLE PRECISION NOT NULL,
+    source     TEXT             NOT NULL DEFAULT ''
+);
+
+SELECT create_hypertable('workout_power', 'time', if_not_exists => TRUE);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workout_power_dedup
+    ON workout_power (time, workout_id, user_id);
+
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS ftp_watts INTEGER CHECK (ftp_watts > 0);
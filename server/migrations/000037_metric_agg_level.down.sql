@@ -0,0 +1 @@
+ALTER TABLE health_metrics DROP COLUMN IF EXISTS agg_level;
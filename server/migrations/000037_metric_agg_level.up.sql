@@ -0,0 +1,6 @@
+-- Aggregation level of each metric row: 'raw' for individual samples, 'day'
+-- for daily totals/averages sent by Health Auto Export with "Summarize Data"
+-- on. Aggregation queries use daily rows only for days without raw rows of
+-- the same metric. Existing rows are assumed raw.
+ALTER TABLE health_metrics ADD COLUMN IF NOT EXISTS agg_level TEXT NOT NULL DEFAULT 'raw'
+    CHECK (agg_level IN ('raw', 'day'));
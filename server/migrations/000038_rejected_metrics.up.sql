@@ -0,0 +1,9 @@
+-- Metric names ingest rejected because they are missing from (or disabled
+-- in) the allowlist, so they can be reviewed and enabled later.
+CREATE TABLE IF NOT EXISTS rejected_metrics (
+    metric_name TEXT        PRIMARY KEY,
+    first_seen  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_seen   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    rejections  BIGINT      NOT NULL DEFAULT 0,
+    points      BIGINT      NOT NULL DEFAULT 0
+);
@@ -0,0 +1,2 @@
+ALTER TABLE workout_sets DROP COLUMN IF EXISTS rest_seconds;
+ALTER TABLE workout_sets DROP COLUMN IF EXISTS session_notes;
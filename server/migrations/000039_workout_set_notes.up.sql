@@ -0,0 +1,4 @@
+-- Alpha Progression session notes (repeated on each set row, like the other
+-- session fields) and the rest taken after a set, when the export has them.
+ALTER TABLE workout_sets ADD COLUMN IF NOT EXISTS session_notes TEXT NOT NULL DEFAULT '';
+ALTER TABLE workout_sets ADD COLUMN IF NOT EXISTS rest_seconds INTEGER CHECK (rest_seconds >= 0);
@@ -0,0 +1,14 @@
+-- Running cadence samples recorded during workouts (steps per minute),
+-- derived from Health Auto Export's in-workout step counts.
+CREATE TABLE IF NOT EXISTS workout_cadence (
+    time       TIMESTAMPTZ      NOT NULL,
+    workout_id UUID             NOT NULL REFERENCES workouts(id) ON DELETE CASCADE,
+    user_id    INTEGER          NOT NULL,
+    spm        DOUBLE PRECISION NOT NULL,
+    source     TEXT             NOT NULL DEFAULT ''
+);
+
+SELECT create_hypertable('workout_cadence', 'time', if_not_exists => TRUE);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workout_cadence_dedup
+    ON workout_cadence (time, workout_id, user_id);
@@ -0,0 +1 @@
+ALTER TABLE metric_rollup_watermarks DROP COLUMN IF EXISTS rolled_at;
@@ -0,0 +1,4 @@
+-- When each metric was last rolled up. Raw rows written since then but dated
+-- before covered_until arrived late (backfills) and are merged into the
+-- existing rollups on the next retention run.
+ALTER TABLE metric_rollup_watermarks ADD COLUMN IF NOT EXISTS rolled_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
@@ -0,0 +1,4 @@
+TRUNCATE rejected_metrics;
+ALTER TABLE rejected_metrics DROP CONSTRAINT IF EXISTS rejected_metrics_pkey;
+ALTER TABLE rejected_metrics DROP COLUMN IF EXISTS user_id;
+ALTER TABLE rejected_metrics ADD PRIMARY KEY (metric_name);
@@ -0,0 +1,7 @@
+-- Rejected metric names are per user, so users don't see each other's. Rows
+-- recorded before this weren't attributed to anyone; they are dropped and
+-- come back with the next ingest that carries them.
+TRUNCATE rejected_metrics;
+ALTER TABLE rejected_metrics ADD COLUMN IF NOT EXISTS user_id INTEGER NOT NULL;
+ALTER TABLE rejected_metrics DROP CONSTRAINT IF EXISTS rejected_metrics_pkey;
+ALTER TABLE rejected_metrics ADD PRIMARY KEY (user_id, metric_name);
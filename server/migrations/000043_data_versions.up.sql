@@ -0,0 +1,10 @@
+-- When a user's data last changed in a way updated_at columns can't show,
+-- e.g. rows deleted or a source priority edited, per DataTable group. HTTP
+-- cache validators fold it in. user_id 0 marks changes to every user's data
+-- (retention).
+CREATE TABLE IF NOT EXISTS data_versions (
+    user_id    INTEGER     NOT NULL,
+    data_table TEXT        NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, data_table)
+);
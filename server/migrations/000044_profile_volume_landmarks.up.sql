@@ -0,0 +1,3 @@
+-- Per-user weekly volume landmark overrides, {"group": {"mev": n, "mrv": n}}.
+-- They win over training.volume_landmarks and the built-in defaults.
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS volume_landmarks JSONB;
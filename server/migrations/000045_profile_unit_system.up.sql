@@ -0,0 +1,2 @@
+-- Preferred unit system for workout responses that don't pass ?units=.
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS unit_system TEXT CHECK (unit_system IN ('metric', 'imperial'));
@@ -141,14 +141,41 @@ export interface SleepResponse {
   stages: SleepStage[];
 }
 
+export interface SleepStagePage {
+  stages: SleepStage[];
+  next_after?: string;
+}
+
+export async function fetchSleepStages(
+  start: string,
+  end: string
+): Promise<SleepStage[]> {
+  const stages: SleepStage[] = [];
+  let after: string | undefined;
+  do {
+    const params = new URLSearchParams({ start, end, limit: "10000" });
+    if (after) params.set("after", after);
+    const res = await fetch(`${BASE}/sleep/stages?${params}`);
+    if (!res.ok) throw new Error(`${res.status}: ${res.statusText}`);
+    const page: SleepStagePage = await res.json();
+    stages.push(...page.stages);
+    after = page.next_after;
+  } while (after);
+  return stages;
+}
+
 export async function fetchSleep(
   start: string,
   end: string
 ): Promise<SleepResponse> {
   const params = new URLSearchParams({ start, end });
-  const res = await fetch(`${BASE}/sleep?${params}`);
+  const [res, stages] = await Promise.all([
+    fetch(`${BASE}/sleep?${params}`),
+    fetchSleepStages(start, end),
+  ]);
   if (!res.ok) throw new Error(`${res.status}: ${res.statusText}`);
-  return res.json();
+  const { sessions } = await res.json();
+  return { sessions, stages };
 }
 
 // --- Workouts ---
@@ -192,9 +219,24 @@ export interface WorkoutRoute {
   Speed: number | null;
 }
 
+export interface CadenceBand {
+  from_spm: number;
+  to_spm: number;
+  samples: number;
+  share: number;
+}
+
+export interface WorkoutCadence {
+  samples: number;
+  avg_spm: number;
+  max_spm: number;
+  distribution: CadenceBand[];
+}
+
 export interface WorkoutDetail extends Workout {
   HeartRateData: WorkoutHR[] | null;
   RouteData: WorkoutRoute[] | null;
+  cadence?: WorkoutCadence;
 }
 
 export async function fetchWorkouts(
@@ -215,6 +257,29 @@ export async function fetchWorkoutDetail(id: string): Promise<WorkoutDetail> {
   return res.json();
 }
 
+// --- Heart Rate Zones ---
+
+export interface HRZone {
+  zone: number;
+  min_bpm: number;
+  max_bpm: number | null;
+}
+
+export interface HRZones {
+  max_hr: number;
+  basis: "profile" | "observed";
+  zones: HRZone[];
+}
+
+// Returns null when the server has no birth date or heart rate data to
+// estimate max HR from.
+export async function fetchHRZones(): Promise<HRZones | null> {
+  const res = await fetch(`${BASE}/heart-rate/zones`);
+  if (res.status === 404) return null;
+  if (!res.ok) throw new Error(`${res.status}: ${res.statusText}`);
+  return res.json();
+}
+
 // --- Workout Sets ---
 
 export interface WorkoutSet {
@@ -231,6 +296,8 @@ export interface WorkoutSet {
   IsBodyweightPlus: boolean;
   Reps: number;
   RIR: number;
+  SessionNotes: string;
+  RestSeconds: number | null;
 }
 
 export async function fetchWorkoutSets(
@@ -258,6 +325,8 @@ export interface CorrelationPoint {
 export interface CorrelationResponse {
   points: CorrelationPoint[];
   pearson_r: number | null;
+  p_value: number | null;
+  low_confidence: boolean;
   count: number;
 }
 
@@ -1,6 +1,9 @@
-import { WorkoutHR } from "../../api";
+import { HRZones, WorkoutHR } from "../../api";
 
-const ZONES = [
+const ZONE_COLORS = ["#22d3ee", "#4ade80", "#facc15", "#fb923c", "#f87171"];
+
+// Fixed bounds used until the server can estimate max HR.
+const DEFAULT_ZONES = [
   { name: "Z1", label: "Zone 1", min: 0, max: 120, color: "#22d3ee" },
   { name: "Z2", label: "Zone 2", min: 120, max: 140, color: "#4ade80" },
   { name: "Z3", label: "Zone 3", min: 140, max: 155, color: "#facc15" },
@@ -10,9 +13,20 @@ const ZONES = [
 
 interface Props {
   hrData: WorkoutHR[];
+  zones?: HRZones | null;
 }
 
-export default function HRZoneBars({ hrData }: Props) {
+export default function HRZoneBars({ hrData, zones }: Props) {
+  const ZONES = zones
+    ? zones.zones.map((z, i) => ({
+        name: `Z${z.zone}`,
+        label: `Zone ${z.zone}`,
+        min: z.min_bpm,
+        max: z.max_bpm ?? 999,
+        color: ZONE_COLORS[i % ZONE_COLORS.length],
+      }))
+    : DEFAULT_ZONES;
+
   if (!hrData || hrData.length < 5) {
     return (
       <div className="bg-zinc-900 border border-zinc-800 rounded-lg p-4">
@@ -58,6 +72,11 @@ export default function HRZoneBars({ hrData }: Props) {
     <div className="bg-zinc-900 border border-zinc-800 rounded-lg p-4">
       <h3 className="text-sm font-medium text-zinc-400 mb-3">
         Time in HR Zones
+        {zones && (
+          <span className="ml-2 text-xs text-zinc-500">
+            max {zones.max_hr} bpm ({zones.basis === "profile" ? "from age" : "observed"})
+          </span>
+        )}
       </h3>
       <div className="space-y-2">
         {ZONES.map((zone, i) => {
@@ -61,11 +61,17 @@ export default function WorkoutSets({
     exercises[exerciseMap.get(key)!].sets.push(set);
   }
 
+  const notes = data[0].SessionNotes;
+  const hasRest = data.some((set) => set.RestSeconds != null);
+
   return (
     <div className="bg-zinc-900 border border-zinc-800 rounded-lg p-4">
       <h3 className="text-sm font-medium text-zinc-400 mb-4">
         Exercises
       </h3>
+      {notes && (
+        <p className="text-sm text-zinc-400 whitespace-pre-line mb-4">{notes}</p>
+      )}
       <div className="space-y-4">
         {exercises.map((ex) => (
           <div key={ex.name}>
@@ -84,6 +90,7 @@ export default function WorkoutSets({
                   <th className="text-right py-1">Weight</th>
                   <th className="text-right py-1">Reps</th>
                   <th className="text-right py-1">RIR</th>
+                  {hasRest && <th className="text-right py-1">Rest</th>}
                 </tr>
               </thead>
               <tbody>
@@ -108,6 +115,13 @@ export default function WorkoutSets({
                     <td className="text-right py-1 tabular-nums">
                       {set.RIR >= 0 ? set.RIR.toFixed(1) : "-"}
                     </td>
+                    {hasRest && (
+                      <td className="text-right py-1 tabular-nums">
+                        {set.RestSeconds != null
+                          ? `${Math.floor(set.RestSeconds / 60)}:${String(set.RestSeconds % 60).padStart(2, "0")}`
+                          : "-"}
+                      </td>
+                    )}
                   </tr>
                 ))}
               </tbody>
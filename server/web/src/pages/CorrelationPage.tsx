@@ -184,16 +184,23 @@ export default function CorrelationPage() {
                   >
                     {data.pearson_r.toFixed(3)}
                   </span>
+                  {data.p_value != null && (
+                    <span className="font-mono text-zinc-500">
+                      p = {data.p_value < 0.001 ? "<0.001" : data.p_value.toFixed(3)}
+                    </span>
+                  )}
                   <span className="text-zinc-600">
                     ({data.count} data points)
                   </span>
                 </div>
               )}
 
-              {/* Low data warning */}
-              {data.count < 7 && (
+              {/* Low confidence warning */}
+              {data.low_confidence && (
                 <div className="text-amber-500 text-sm p-3 bg-amber-500/5 border border-amber-500/20 rounded-lg">
-                  Low data overlap — correlation may not be reliable.
+                  {data.pearson_r == null
+                    ? "Too little data overlap to compute a correlation."
+                    : "Not statistically significant (p ≥ 0.05) — correlation may not be reliable."}
                 </div>
               )}
 
@@ -1,6 +1,6 @@
 import { useQuery } from "@tanstack/react-query";
 import { useParams, Link, useLocation } from "react-router-dom";
-import { fetchWorkoutDetail, type Workout } from "../api";
+import { fetchHRZones, fetchWorkoutDetail, type Workout } from "../api";
 import { getWorkoutDisplayName } from "../components/workouts/workoutNames";
 import HRTimelineChart from "../components/workouts/HRTimelineChart";
 import HRZoneBars from "../components/workouts/HRZoneBars";
@@ -26,8 +26,16 @@ export default function WorkoutDetailPage() {
     enabled: !!id && !isSynthetic,
   });
 
+  const { data: hrZones } = useQuery({
+    queryKey: ["hr-zones"],
+    queryFn: fetchHRZones,
+    enabled: !isSynthetic,
+    staleTime: 60 * 60 * 1000,
+  });
+
   // For synthetic workouts, use the route state directly.
   const w = isSynthetic ? routeWorkout! : data;
+  const cadence = data?.cadence;
 
   if (!isSynthetic && isLoading) {
     return (
@@ -106,6 +114,13 @@ export default function WorkoutDetailPage() {
             unit={w.DistanceUnits}
           />
         )}
+        {cadence != null && cadence.avg_spm > 0 && (
+          <StatCard
+            label="Cadence"
+            value={`${Math.round(cadence.avg_spm)}`}
+            unit="spm"
+          />
+        )}
         {w.ElevationUp != null && w.ElevationUp > 0 && (
           <StatCard
             label="Elev. Gain"
@@ -128,7 +143,7 @@ export default function WorkoutDetailPage() {
       {hasHR && <HRTimelineChart hrData={data!.HeartRateData!} />}
 
       {/* HR Zones */}
-      {hasHR && <HRZoneBars hrData={data!.HeartRateData!} />}
+      {hasHR && <HRZoneBars hrData={data!.HeartRateData!} zones={hrZones} />}
 
       {/* Route Map — hidden for indoor or zero-distance workouts */}
       {hasRoute && !w.IsIndoor && (w.Distance ?? 0) > 0.1 && (